@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/demo"
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/testscenarios"
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/tools"
+	edserver "github.com/edgedelta/edgedelta-mcp-server/server"
+
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/spf13/cobra"
+)
+
+var rootCmd = &cobra.Command{
+	Use:   "mcp-scenarios [scenario.yaml...]",
+	Short: "Run agent workflow scenarios against the demo API",
+	Long: `Runs declarative scenarios of multi-tool agent workflows against the tools serving the built-in
+demo data, and fails if any assertion fails. Without arguments the builtin scenarios of
+pkg/testscenarios/scenarios are run.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		if !verbose {
+			slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+		}
+
+		scenarios, err := loadScenarios(args)
+		if err != nil {
+			return err
+		}
+
+		s := server.NewMCPServer("edgedelta-mcp-scenarios", edserver.BuildInfo().Version)
+		edserver.AddCustomTools(s, demo.NewClient(), tools.ErrorCodes())
+		caller := &testscenarios.ServerCaller{Server: s}
+		ctx := context.WithValue(cmd.Context(), tools.OrgIDKey, demo.OrgID)
+
+		failed := 0
+		for _, scenario := range scenarios {
+			result := testscenarios.Run(ctx, caller, scenario)
+			status := "PASS"
+			if !result.Passed() {
+				status = "FAIL"
+				failed++
+			}
+			fmt.Printf("%s %s\n", status, result.Name)
+			for _, step := range result.Steps {
+				if verbose || len(step.Failures) > 0 {
+					fmt.Printf("    %s (%s) %s\n", step.Name, step.Tool, step.Duration.Round(time.Microsecond))
+				}
+				for _, failure := range step.Failures {
+					fmt.Printf("        %s\n", failure)
+				}
+			}
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d of %d scenarios failed", failed, len(scenarios))
+		}
+		fmt.Printf("%d scenarios passed\n", len(scenarios))
+		return nil
+	},
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func loadScenarios(files []string) ([]*testscenarios.Scenario, error) {
+	if len(files) == 0 {
+		return testscenarios.Load(testscenarios.Scenarios, "scenarios")
+	}
+
+	scenarios := make([]*testscenarios.Scenario, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		scenario, err := testscenarios.Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		scenarios = append(scenarios, scenario)
+	}
+	return scenarios, nil
+}
+
+func init() {
+	rootCmd.Flags().BoolP("verbose", "v", false, "Print every step and the server logs")
+}
+
+func main() {
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
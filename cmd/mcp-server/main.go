@@ -2,31 +2,34 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/demo"
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/storage"
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/tools"
 	"github.com/edgedelta/edgedelta-mcp-server/server"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	stdlog "log"
 )
 
-var (
-	version = "version"
-	commit  = "commit"
-	date    = "date"
-)
-
 var (
 	rootCmd = &cobra.Command{
 		Use:     "server",
 		Short:   "Edge Delta MCP Server",
 		Long:    `A Edge Delta MCP server that handles various tools and resources.`,
-		Version: fmt.Sprintf("%s (%s) %s", version, commit, date),
+		Version: server.BuildInfo().String(),
 	}
 
 	stdioCmd = &cobra.Command{
@@ -70,8 +73,29 @@ var (
 			}
 		},
 	}
+
+	selfTestCmd = &cobra.Command{
+		Use:   "selftest",
+		Short: "Call every read-only tool against the configured org",
+		Long: `Call every read-only tool with minimal safe arguments against the org of ED_ORG_ID with ED_API_TOKEN,
+and report pass, fail or skip and the latency of each, to verify the token scopes, the org access and the API
+compatibility of a deployment before pointing agents at it. Exits with an error if any tool fails.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			logger, err := initLogger(viper.GetString("log-file"))
+			if err != nil {
+				return fmt.Errorf("failed to initialize logger: %w", err)
+			}
+			jsonOutput, _ := cmd.Flags().GetBool("json")
+			return runServer(runConfig{logger: logger, selfTest: true, jsonOutput: jsonOutput})
+		},
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
 )
 
+// logLevel is the level of the log file, which the config file can change at runtime
+var logLevel = new(slog.LevelVar)
+
 func initLogger(outPath string) (*slog.Logger, error) {
 	if outPath == "" {
 		return slog.Default(), nil
@@ -82,8 +106,9 @@ func initLogger(outPath string) (*slog.Logger, error) {
 		return nil, fmt.Errorf("failed to open log file: %w", err)
 	}
 
+	logLevel.Set(slog.LevelDebug)
 	logger := slog.New(slog.NewJSONHandler(file, &slog.HandlerOptions{
-		Level: slog.LevelDebug,
+		Level: logLevel,
 	}))
 
 	return logger, nil
@@ -92,18 +117,30 @@ func initLogger(outPath string) (*slog.Logger, error) {
 func init() {
 	// Add global flags that will be shared by all commands
 	rootCmd.PersistentFlags().String("log-file", "", "Path to log file")
+	rootCmd.PersistentFlags().Bool("demo", false, "Serve built-in synthetic data instead of calling the Edge Delta API")
+	rootCmd.PersistentFlags().String("config", "", "Path to a config file with settings reloaded while the server runs: log_level, schema_cache_ttl, http_rps, http_burst, http_endpoint_rate_limits and disabled_tools")
+	rootCmd.PersistentFlags().String("openapi-file", "", "Path to a local copy of the Edge Delta API OpenAPI spec, for the API drift check without network access")
 
 	// Bind flags to viper
 	_ = viper.BindPFlag("log-file", rootCmd.PersistentFlags().Lookup("log-file"))
+	_ = viper.BindPFlag("demo", rootCmd.PersistentFlags().Lookup("demo"))
+	_ = viper.BindPFlag("config", rootCmd.PersistentFlags().Lookup("config"))
+	_ = viper.BindPFlag("openapi-file", rootCmd.PersistentFlags().Lookup("openapi-file"))
 
 	// Add subcommands
 	rootCmd.AddCommand(stdioCmd)
 	rootCmd.AddCommand(httpCmd)
+	rootCmd.AddCommand(selfTestCmd)
+
+	selfTestCmd.Flags().Bool("json", false, "Print the results as JSON")
 }
 
 type runConfig struct {
 	logger     *slog.Logger
 	serverType server.ServerType
+	// selfTest runs the self test with the server options instead of the server
+	selfTest   bool
+	jsonOutput bool
 }
 
 func runServer(cfg runConfig) error {
@@ -119,11 +156,349 @@ func runServer(cfg runConfig) error {
 		}
 	}
 
+	var limits tools.LookbackLimits
+	if maxLookback := os.Getenv("ED_MAX_LOOKBACK"); maxLookback != "" {
+		d, err := tools.ParseLookback(maxLookback)
+		if err != nil {
+			return fmt.Errorf("invalid ED_MAX_LOOKBACK, err: %w", err)
+		}
+		limits.Default = d
+	}
+
+	if perOrg := os.Getenv("ED_MAX_LOOKBACK_PER_ORG"); perOrg != "" {
+		perOrgLimits, err := tools.ParseLookbackLimits(perOrg)
+		if err != nil {
+			return fmt.Errorf("invalid ED_MAX_LOOKBACK_PER_ORG, err: %w", err)
+		}
+		limits.PerOrg = perOrgLimits
+	}
+
+	opts = append(opts, server.WithLookbackLimits(limits))
+
+	if featureFlags := os.Getenv("ED_FEATURE_FLAGS"); featureFlags != "" {
+		enabled, err := strconv.ParseBool(featureFlags)
+		if err != nil {
+			return fmt.Errorf("invalid ED_FEATURE_FLAGS, err: %w", err)
+		}
+		opts = append(opts, server.WithFeatureFlags(enabled))
+	}
+
+	if compact := os.Getenv("ED_COMPACT_DESCRIPTIONS"); compact != "" {
+		enabled, err := strconv.ParseBool(compact)
+		if err != nil {
+			return fmt.Errorf("invalid ED_COMPACT_DESCRIPTIONS, err: %w", err)
+		}
+		opts = append(opts, server.WithCompactDescriptions(enabled))
+	}
+
+	if multiOrg := os.Getenv("ED_MULTI_ORG"); multiOrg != "" {
+		enabled, err := strconv.ParseBool(multiOrg)
+		if err != nil {
+			return fmt.Errorf("invalid ED_MULTI_ORG, err: %w", err)
+		}
+		opts = append(opts, server.WithMultiOrg(enabled))
+	}
+
+	if maxResultBytes := os.Getenv("ED_MAX_RESULT_BYTES"); maxResultBytes != "" {
+		maxBytes, err := strconv.ParseUint(maxResultBytes, 10, 31)
+		if err != nil {
+			return fmt.Errorf("invalid ED_MAX_RESULT_BYTES, err: %w", err)
+		}
+		opts = append(opts, server.WithMaxResultBytes(int(maxBytes)))
+	}
+
+	if toolBudgets := os.Getenv("ED_TOOL_BUDGETS"); toolBudgets != "" {
+		budgets, err := tools.ParseToolBudgets(toolBudgets)
+		if err != nil {
+			return fmt.Errorf("invalid ED_TOOL_BUDGETS, err: %w", err)
+		}
+		for toolName, budget := range budgets {
+			opts = append(opts, server.WithToolBudget(toolName, budget.Calls, budget.Per))
+		}
+	}
+
+	if requestTimeout := os.Getenv("ED_REQUEST_TIMEOUT"); requestTimeout != "" {
+		timeout, err := time.ParseDuration(requestTimeout)
+		if err != nil {
+			return fmt.Errorf("invalid ED_REQUEST_TIMEOUT, err: %w", err)
+		}
+		opts = append(opts, server.WithRequestTimeout(timeout))
+	}
+
+	if toolTimeouts := os.Getenv("ED_TOOL_TIMEOUTS"); toolTimeouts != "" {
+		timeouts, err := tools.ParseToolTimeouts(toolTimeouts)
+		if err != nil {
+			return fmt.Errorf("invalid ED_TOOL_TIMEOUTS, err: %w", err)
+		}
+		for toolName, timeout := range timeouts {
+			opts = append(opts, server.WithToolTimeout(toolName, timeout))
+		}
+	}
+
+	retryPolicy := tools.DefaultRetryPolicy
+	if maxRetries := os.Getenv("ED_HTTP_MAX_RETRIES"); maxRetries != "" {
+		retries, err := strconv.ParseUint(maxRetries, 10, 8)
+		if err != nil {
+			return fmt.Errorf("invalid ED_HTTP_MAX_RETRIES, err: %w", err)
+		}
+		retryPolicy.MaxRetries = int(retries)
+	}
+
+	if maxDelay := os.Getenv("ED_HTTP_RETRY_MAX_DELAY"); maxDelay != "" {
+		delay, err := time.ParseDuration(maxDelay)
+		if err != nil {
+			return fmt.Errorf("invalid ED_HTTP_RETRY_MAX_DELAY, err: %w", err)
+		}
+		retryPolicy.MaxDelay = delay
+	}
+	opts = append(opts, server.WithRetryPolicy(retryPolicy))
+
+	rateLimits := tools.DefaultRateLimits
+	if rps := os.Getenv("ED_HTTP_RPS"); rps != "" {
+		r, err := strconv.ParseFloat(rps, 64)
+		if err != nil {
+			return fmt.Errorf("invalid ED_HTTP_RPS, err: %w", err)
+		}
+		rateLimits.Default.RPS = r
+	}
+
+	if burst := os.Getenv("ED_HTTP_BURST"); burst != "" {
+		b, err := strconv.Atoi(burst)
+		if err != nil {
+			return fmt.Errorf("invalid ED_HTTP_BURST, err: %w", err)
+		}
+		rateLimits.Default.Burst = b
+	}
+
+	if endpointLimits := os.Getenv("ED_HTTP_ENDPOINT_RATE_LIMITS"); endpointLimits != "" {
+		limits, err := tools.ParseEndpointRateLimits(endpointLimits)
+		if err != nil {
+			return fmt.Errorf("invalid ED_HTTP_ENDPOINT_RATE_LIMITS, err: %w", err)
+		}
+		rateLimits.Endpoints = limits
+	}
+	opts = append(opts, server.WithRateLimits(rateLimits))
+
+	var httpOptions []tools.HTTPClientOption
+	if httpTimeout := os.Getenv("ED_HTTP_TIMEOUT"); httpTimeout != "" {
+		timeout, err := time.ParseDuration(httpTimeout)
+		if err != nil {
+			return fmt.Errorf("invalid ED_HTTP_TIMEOUT, err: %w", err)
+		}
+		httpOptions = append(httpOptions, tools.WithHTTPTimeout(timeout))
+	}
+
+	if maxIdleConns := os.Getenv("ED_HTTP_MAX_IDLE_CONNS"); maxIdleConns != "" {
+		n, err := strconv.ParseUint(maxIdleConns, 10, 31)
+		if err != nil {
+			return fmt.Errorf("invalid ED_HTTP_MAX_IDLE_CONNS, err: %w", err)
+		}
+		httpOptions = append(httpOptions, tools.WithMaxIdleConns(int(n)))
+	}
+
+	if tlsMinVersion := os.Getenv("ED_HTTP_TLS_MIN_VERSION"); tlsMinVersion != "" {
+		version, err := tools.ParseTLSVersion(tlsMinVersion)
+		if err != nil {
+			return fmt.Errorf("invalid ED_HTTP_TLS_MIN_VERSION, err: %w", err)
+		}
+		httpOptions = append(httpOptions, tools.WithTransport(func(t *http.Transport) {
+			t.TLSClientConfig.MinVersion = version
+		}))
+	}
+	opts = append(opts, server.WithHTTPClientOptions(httpOptions...))
+
+	if configFile := viper.GetString("config"); configFile != "" {
+		reloads, err := watchConfig(configFile, rateLimits, cfg.logger)
+		if err != nil {
+			return fmt.Errorf("invalid config file %s, err: %w", configFile, err)
+		}
+		opts = append(opts, server.WithConfigReloads(reloads))
+	}
+
+	if issuerURL := os.Getenv("ED_OAUTH_ISSUER_URL"); issuerURL != "" {
+		audience := os.Getenv("ED_OAUTH_AUDIENCE")
+		if audience == "" {
+			return fmt.Errorf("ED_OAUTH_AUDIENCE is required with ED_OAUTH_ISSUER_URL")
+		}
+		opts = append(opts, server.WithOAuth(issuerURL, audience))
+		if orgClaim := os.Getenv("ED_OAUTH_ORG_CLAIM"); orgClaim != "" {
+			opts = append(opts, server.WithOAuthOrgClaim(orgClaim))
+		}
+	}
+
+	if warmupOrgID := os.Getenv("ED_WARMUP_ORG_ID"); warmupOrgID != "" {
+		opts = append(opts, server.WithWarmup(warmupOrgID, os.Getenv("ED_WARMUP_API_TOKEN")))
+	}
+
+	if signingKey := os.Getenv("ED_RESULT_SIGNING_KEY"); signingKey != "" {
+		keyID := os.Getenv("ED_RESULT_SIGNING_KEY_ID")
+		if keyID == "" {
+			keyID = "default"
+		}
+		signer, err := tools.NewResultSigner(keyID, []byte(signingKey))
+		if err != nil {
+			return fmt.Errorf("invalid ED_RESULT_SIGNING_KEY, err: %w", err)
+		}
+		opts = append(opts, server.WithResultSigner(signer))
+	}
+
+	tracer, err := newTracer()
+	if err != nil {
+		return err
+	}
+	if tracer != nil {
+		opts = append(opts, server.WithTracing(tracer))
+	}
+
+	if metrics := os.Getenv("ED_METRICS"); metrics != "" {
+		enabled, err := strconv.ParseBool(metrics)
+		if err != nil {
+			return fmt.Errorf("invalid ED_METRICS, err: %w", err)
+		}
+		if enabled {
+			opts = append(opts, server.WithMetrics())
+		}
+	}
+
+	redactSecrets := true
+	if redact := os.Getenv("ED_REDACT_SECRETS"); redact != "" {
+		redactSecrets, err = strconv.ParseBool(redact)
+		if err != nil {
+			return fmt.Errorf("invalid ED_REDACT_SECRETS, err: %w", err)
+		}
+	}
+	if redactSecrets {
+		patterns := tools.DefaultRedactionPatterns
+		if patternsFile := os.Getenv("ED_REDACTION_PATTERNS_FILE"); patternsFile != "" {
+			filePatterns, err := tools.LoadRedactionPatterns(patternsFile)
+			if err != nil {
+				return fmt.Errorf("invalid ED_REDACTION_PATTERNS_FILE, err: %w", err)
+			}
+			patterns = append(append([]string{}, patterns...), filePatterns...)
+		}
+		redactor, err := tools.NewRedactor(patterns)
+		if err != nil {
+			return fmt.Errorf("invalid ED_REDACTION_PATTERNS_FILE, err: %w", err)
+		}
+		opts = append(opts, server.WithSecretRedaction(redactor))
+	}
+
+	if auditLogFile := os.Getenv("ED_AUDIT_LOG_FILE"); auditLogFile != "" {
+		auditLogger, err := tools.NewFileAuditLogger(auditLogFile)
+		if err != nil {
+			return fmt.Errorf("invalid ED_AUDIT_LOG_FILE, err: %w", err)
+		}
+		defer auditLogger.Close()
+		opts = append(opts, server.WithAuditLogger(auditLogger))
+	}
+
+	if auditIngestionURL := os.Getenv("ED_AUDIT_INGESTION_URL"); auditIngestionURL != "" {
+		opts = append(opts, server.WithAuditLogger(tools.NewIngestionAuditLogger(auditIngestionURL)))
+	}
+
+	if pseudonymize := os.Getenv("ED_PSEUDONYMIZE"); pseudonymize != "" {
+		enabled, err := strconv.ParseBool(pseudonymize)
+		if err != nil {
+			return fmt.Errorf("invalid ED_PSEUDONYMIZE, err: %w", err)
+		}
+		if enabled {
+			opts = append(opts, server.WithPseudonyms([]byte(os.Getenv("ED_PSEUDONYM_KEY"))))
+		}
+	}
+
+	if ticketURL := os.Getenv("ED_TICKET_WEBHOOK_URL"); ticketURL != "" {
+		var headers map[string]string
+		if value := os.Getenv("ED_TICKET_WEBHOOK_HEADERS"); value != "" {
+			if headers, err = tools.ParseOTLPHeaders(value); err != nil {
+				return fmt.Errorf("invalid ED_TICKET_WEBHOOK_HEADERS, err: %w", err)
+			}
+		}
+		var bodyTemplate string
+		if templateFile := os.Getenv("ED_TICKET_TEMPLATE_FILE"); templateFile != "" {
+			if bodyTemplate, err = tools.LoadTicketTemplate(templateFile); err != nil {
+				return fmt.Errorf("invalid ED_TICKET_TEMPLATE_FILE, err: %w", err)
+			}
+		}
+		webhook, err := tools.NewTicketWebhook(ticketURL, headers, bodyTemplate)
+		if err != nil {
+			return fmt.Errorf("invalid ED_TICKET_TEMPLATE_FILE, err: %w", err)
+		}
+		opts = append(opts, server.WithTicketWebhook(webhook))
+	}
+
+	if specURL := os.Getenv("ED_OPENAPI_SPEC_URL"); specURL != "" {
+		opts = append(opts, server.WithAPISpecCheck(specURL))
+	}
+
+	if specFile := viper.GetString("openapi-file"); specFile != "" {
+		opts = append(opts, server.WithOpenAPISpecFile(specFile))
+	}
+
+	if checkInterval := os.Getenv("ED_OPENAPI_SPEC_CHECK_INTERVAL"); checkInterval != "" {
+		interval, err := time.ParseDuration(checkInterval)
+		if err != nil {
+			return fmt.Errorf("invalid ED_OPENAPI_SPEC_CHECK_INTERVAL, err: %w", err)
+		}
+		opts = append(opts, server.WithAPISpecCheckInterval(interval))
+	}
+
+	if schemaCacheTTL := os.Getenv("ED_SCHEMA_CACHE_TTL"); schemaCacheTTL != "" {
+		ttl, err := time.ParseDuration(schemaCacheTTL)
+		if err != nil {
+			return fmt.Errorf("invalid ED_SCHEMA_CACHE_TTL, err: %w", err)
+		}
+		opts = append(opts, server.WithSchemaCacheTTL(ttl))
+	}
+
+	if apiRegions := os.Getenv("ED_API_REGIONS"); apiRegions != "" {
+		regions, err := tools.ParseRegions(apiRegions)
+		if err != nil {
+			return fmt.Errorf("invalid ED_API_REGIONS, err: %w", err)
+		}
+		opts = append(opts, server.WithRegions(regions))
+	}
+
+	if locale := os.Getenv("ED_LOCALE"); locale != "" {
+		opts = append(opts, server.WithLocale(locale))
+	}
+
+	if aliasesFile := os.Getenv("ED_SERVICE_ALIASES_FILE"); aliasesFile != "" {
+		aliases, err := tools.LoadServiceAliases(aliasesFile)
+		if err != nil {
+			return fmt.Errorf("failed to load service aliases, err: %w", err)
+		}
+		opts = append(opts, server.WithServiceAliases(aliases))
+	}
+
+	store, err := storage.Open(os.Getenv("ED_STORAGE_URL"))
+	if err != nil {
+		return fmt.Errorf("invalid ED_STORAGE_URL, err: %w", err)
+	}
+	opts = append(opts, server.WithStorage(store))
+
+	// The query history file takes precedence over the storage
+	if historyFile := os.Getenv("ED_QUERY_HISTORY_FILE"); historyFile != "" {
+		history, err := tools.NewQueryHistory(historyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load query history, err: %w", err)
+		}
+		opts = append(opts, server.WithQueryHistory(history))
+	}
 	opts = append(opts, server.WithLogger(cfg.logger))
 
 	apiToken := os.Getenv("ED_API_TOKEN")
 	orgID := os.Getenv("ED_ORG_ID")
 
+	if viper.GetBool("demo") {
+		cfg.logger.Info("Demo mode enabled, serving synthetic data")
+		orgID, apiToken = demo.OrgID, demo.APIToken
+		opts = append(opts, server.WithClient(demo.NewClient()), server.WithDefaultOrgID(demo.OrgID))
+	}
+
+	if cfg.selfTest {
+		return runSelfTest(orgID, apiToken, cfg.jsonOutput, opts...)
+	}
+
 	mcpServer, err := server.CreateServer(cfg.serverType, orgID, apiToken, opts...)
 	if err != nil {
 		return fmt.Errorf("failed to create server, err: %w", err)
@@ -138,6 +513,177 @@ func runServer(cfg runConfig) error {
 	return nil
 }
 
+// runSelfTest runs the self test and prints a line per tool, or the results as JSON.
+func runSelfTest(orgID, apiToken string, jsonOutput bool, opts ...server.ServerOption) error {
+	results, err := server.SelfTest(context.Background(), orgID, apiToken, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to run self test, err: %w", err)
+	}
+
+	counts := make(map[string]int)
+	for _, result := range results {
+		counts[result.Status]++
+	}
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, result := range results {
+			duration := ""
+			if result.Status != server.SelfTestSkipped {
+				duration = (time.Duration(result.DurationMs) * time.Millisecond).String()
+			}
+			// Errors are often whole API responses, their first line is enough here
+			message, _, _ := strings.Cut(result.Error, "\n")
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", strings.ToUpper(result.Status), result.Tool, duration, message)
+		}
+		w.Flush()
+		fmt.Printf("%d passed, %d failed, %d skipped\n", counts[server.SelfTestPassed], counts[server.SelfTestFailed], counts[server.SelfTestSkipped])
+	}
+
+	if counts[server.SelfTestFailed] > 0 {
+		return fmt.Errorf("%d tools failed the self test", counts[server.SelfTestFailed])
+	}
+	return nil
+}
+
+// newTracer creates the tracer exporting to the OTLP endpoint of the standard OpenTelemetry
+// environment variables, or nil if no endpoint is set.
+func newTracer() (*tools.Tracer, error) {
+	if disabled, _ := strconv.ParseBool(os.Getenv("OTEL_SDK_DISABLED")); disabled {
+		return nil, nil
+	}
+
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		if base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); base != "" {
+			endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+		}
+	}
+	if endpoint == "" {
+		return nil, nil
+	}
+
+	for _, name := range []string{"OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "OTEL_EXPORTER_OTLP_PROTOCOL"} {
+		if protocol := os.Getenv(name); protocol != "" && protocol != "http/json" {
+			return nil, fmt.Errorf("invalid %s %q, only http/json is supported", name, protocol)
+		}
+	}
+
+	config := tools.TracerConfig{Endpoint: endpoint, ServiceName: os.Getenv("OTEL_SERVICE_NAME")}
+	if config.ServiceName == "" {
+		config.ServiceName = "edgedelta-mcp-server"
+	}
+	for _, name := range []string{"OTEL_EXPORTER_OTLP_HEADERS", "OTEL_EXPORTER_OTLP_TRACES_HEADERS"} {
+		if value := os.Getenv(name); value != "" {
+			headers, err := tools.ParseOTLPHeaders(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s, err: %w", name, err)
+			}
+			if config.Headers == nil {
+				config.Headers = make(map[string]string)
+			}
+			for key, v := range headers {
+				config.Headers[key] = v
+			}
+		}
+	}
+	return tools.NewTracer(config), nil
+}
+
+// watchConfig reads the config file and watches it for changes, sending the runtime config of
+// the file on every change. Invalid changes are logged and ignored. rateLimits are the limits the
+// rate settings of the file apply to.
+func watchConfig(path string, rateLimits tools.RateLimits, logger *slog.Logger) (<-chan server.RuntimeConfig, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	rc, err := runtimeConfig(v, rateLimits)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the last config matters, so a pending one is replaced by a newer one
+	reloads := make(chan server.RuntimeConfig, 1)
+	reloads <- rc
+	v.OnConfigChange(func(_ fsnotify.Event) {
+		rc, err := runtimeConfig(v, rateLimits)
+		if err != nil {
+			logger.Warn("Ignoring invalid config file change", "path", path, "error", err)
+			return
+		}
+		logger.Info("Config file changed, applying it", "path", path)
+		select {
+		case <-reloads:
+		default:
+		}
+		reloads <- rc
+	})
+	v.WatchConfig()
+	return reloads, nil
+}
+
+// runtimeConfig reads the settings of the config file that can change while the server runs, and
+// applies the log level.
+func runtimeConfig(v *viper.Viper, rateLimits tools.RateLimits) (server.RuntimeConfig, error) {
+	var rc server.RuntimeConfig
+
+	level := logLevel.Level()
+	if v.IsSet("log_level") {
+		if err := level.UnmarshalText([]byte(v.GetString("log_level"))); err != nil {
+			return rc, fmt.Errorf("invalid log_level, err: %w", err)
+		}
+	}
+
+	if v.IsSet("schema_cache_ttl") {
+		ttl, err := time.ParseDuration(v.GetString("schema_cache_ttl"))
+		if err != nil {
+			return rc, fmt.Errorf("invalid schema_cache_ttl, err: %w", err)
+		}
+		rc.SchemaCacheTTL = &ttl
+	}
+
+	if v.IsSet("http_rps") || v.IsSet("http_burst") || v.IsSet("http_endpoint_rate_limits") {
+		if v.IsSet("http_rps") {
+			r, err := strconv.ParseFloat(v.GetString("http_rps"), 64)
+			if err != nil {
+				return rc, fmt.Errorf("invalid http_rps, err: %w", err)
+			}
+			rateLimits.Default.RPS = r
+		}
+		if v.IsSet("http_burst") {
+			b, err := strconv.Atoi(v.GetString("http_burst"))
+			if err != nil {
+				return rc, fmt.Errorf("invalid http_burst, err: %w", err)
+			}
+			rateLimits.Default.Burst = b
+		}
+		if v.IsSet("http_endpoint_rate_limits") {
+			limits, err := tools.ParseEndpointRateLimits(v.GetString("http_endpoint_rate_limits"))
+			if err != nil {
+				return rc, fmt.Errorf("invalid http_endpoint_rate_limits, err: %w", err)
+			}
+			rateLimits.Endpoints = limits
+		}
+		rc.RateLimits = &rateLimits
+	}
+
+	// Removing disabled_tools from the file enables the tools again
+	rc.DisabledTools = append([]string{}, v.GetStringSlice("disabled_tools")...)
+
+	if v.IsSet("log_level") {
+		logLevel.Set(level)
+		slog.SetLogLoggerLevel(level)
+	}
+	return rc, nil
+}
+
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -0,0 +1,118 @@
+// Package demo provides a tools.Client backed by a built-in synthetic data generator so the
+// server can be tried without an Edge Delta account. All data is deterministic for a given
+// request and time window.
+package demo
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// OrgID and APIToken are placeholder credentials used when running in demo mode.
+	OrgID    = "demo-org"
+	APIToken = "demo-token"
+
+	apiURL = "https://api.demo.edgedelta.local"
+)
+
+// Client serves Edge Delta API requests from synthetic data instead of the network.
+type Client struct {
+	router *mux.Router
+}
+
+func NewClient() *Client {
+	return &Client{router: newRouter()}
+}
+
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	c.router.ServeHTTP(rec, req)
+	return rec.Result(), nil
+}
+
+func (c *Client) Get(url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+func (c *Client) APIURL() string {
+	return apiURL
+}
+
+func newRouter() *mux.Router {
+	r := mux.NewRouter()
+	r.HandleFunc("/v1/orgs", handleOrgs).Methods(http.MethodGet)
+	org := r.PathPrefix("/v1/orgs/{org_id}").Subrouter()
+
+	// Search
+	org.HandleFunc("/logs/log_search/search", handleLogSearch).Methods(http.MethodGet)
+	org.HandleFunc("/logs/log_search/graph", handleServicesGraph).Methods(http.MethodGet)
+	org.HandleFunc("/events/search", handleEventSearch).Methods(http.MethodGet)
+	org.HandleFunc("/clustering/stats", handlePatternStats).Methods(http.MethodGet)
+	org.HandleFunc("/traces", handleTraces).Methods(http.MethodGet)
+	org.HandleFunc("/graph", handleGraph).Methods(http.MethodPost)
+
+	// Schema
+	org.HandleFunc("/facets", handleFacets).Methods(http.MethodGet)
+	org.HandleFunc("/facets", handleCreateFacet).Methods(http.MethodPost)
+	org.HandleFunc("/facets", handleNoContent).Methods(http.MethodDelete)
+	org.HandleFunc("/facet_options", handleFacetOptions).Methods(http.MethodGet)
+	org.HandleFunc("/facet_keys", handleFacetKeys).Methods(http.MethodGet)
+
+	// Pipelines
+	org.HandleFunc("/pipelines", handlePipelines).Methods(http.MethodGet)
+	org.HandleFunc("/pipelines/{conf_id}/history", handlePipelineHistory).Methods(http.MethodGet)
+	org.HandleFunc("/pipelines/{conf_id}/agents", handlePipelineAgents).Methods(http.MethodGet)
+	org.HandleFunc("/pipelines/{conf_id}/deploy/{version}", handleAccepted).Methods(http.MethodPost)
+	org.HandleFunc("/pipelines/{conf_id}/add_source", handleAccepted).Methods(http.MethodPost)
+	org.HandleFunc("/pipelines/{conf_id}/remove_node", handleAccepted).Methods(http.MethodPost)
+	org.HandleFunc("/pipelines/{conf_id}/update_node", handleAccepted).Methods(http.MethodPost)
+	org.HandleFunc("/pipelines/{conf_id}/connect_nodes", handleAccepted).Methods(http.MethodPost)
+	org.HandleFunc("/pipelines/{conf_id}/disconnect_nodes", handleAccepted).Methods(http.MethodPost)
+	org.HandleFunc("/pipelines/{conf_id}/save", handleAccepted).Methods(http.MethodPost)
+	org.HandleFunc("/pipelines/{conf_id}/validate", handleValidatePipeline).Methods(http.MethodPost)
+	org.HandleFunc("/confs", handleConfs).Methods(http.MethodGet)
+	org.HandleFunc("/confs/{conf_id}", handleConf).Methods(http.MethodGet)
+
+	// Ingestion
+	org.HandleFunc("/ingestion_endpoints", handleIngestionEndpoints).Methods(http.MethodGet)
+	org.HandleFunc("/ingestion_token", handleIngestionToken).Methods(http.MethodGet)
+	r.HandleFunc("/v1/logs", handleAccepted).Methods(http.MethodPost)
+
+	// Org settings
+	org.HandleFunc("/features", handleFeatures).Methods(http.MethodGet)
+	org.HandleFunc("/settings/service_aliases", handleServiceAliases).Methods(http.MethodGet)
+	org.HandleFunc("/child_orgs", handleChildOrgs).Methods(http.MethodGet)
+	org.HandleFunc("/members", handleOrgMembers).Methods(http.MethodGet)
+	org.HandleFunc("/api_keys", handleAPIKeys).Methods(http.MethodGet)
+
+	// Dashboards
+	org.HandleFunc("/dashboards", handleDashboards).Methods(http.MethodGet)
+	org.HandleFunc("/dashboards", handleDashboardChange).Methods(http.MethodPost)
+	org.HandleFunc("/dashboards/{dashboard_id}", handleDashboard).Methods(http.MethodGet)
+	org.HandleFunc("/dashboards/{dashboard_id}", handleDashboardChange).Methods(http.MethodPut)
+
+	// Monitors
+	org.HandleFunc("/monitors", handleMonitors).Methods(http.MethodGet)
+	org.HandleFunc("/monitors", handleMonitorChange).Methods(http.MethodPost)
+	org.HandleFunc("/monitors/{monitor_id}", handleMonitor).Methods(http.MethodGet)
+	org.HandleFunc("/monitors/{monitor_id}", handleMonitorChange).Methods(http.MethodPut)
+	org.HandleFunc("/monitors/{monitor_id}/mute", handleMonitorChange).Methods(http.MethodPost)
+	org.HandleFunc("/monitors/{monitor_id}/unmute", handleMonitorChange).Methods(http.MethodPost)
+
+	// Notifications
+	org.HandleFunc("/notifications/integrations", handleNotificationIntegrations).Methods(http.MethodGet)
+	org.HandleFunc("/notifications/routing_rules", handleNotificationRoutes).Methods(http.MethodGet)
+
+	// Archives
+	org.HandleFunc("/archives", handleArchives).Methods(http.MethodGet)
+	org.HandleFunc("/archives/{destination_id}/objects", handleArchiveObjects).Methods(http.MethodGet)
+
+	return r
+}
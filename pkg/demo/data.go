@@ -0,0 +1,460 @@
+package demo
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	logInterval   = 3 * time.Second
+	eventInterval = 4 * time.Minute
+	traceInterval = 20 * time.Second
+
+	// maxScan bounds how many synthetic records a single request walks through.
+	maxScan = 50000
+)
+
+var (
+	services = []string{"frontend", "checkout", "payments", "inventory", "auth"}
+	hosts    = []string{"ip-10-0-1-12", "ip-10-0-1-47", "ip-10-0-2-3", "ip-10-0-2-91"}
+	envTags  = []string{"prod-us-east", "prod-eu-west"}
+	pods     = []string{"frontend-7d9f8b6c5d-x2kqp", "checkout-5c6b7f9d8-m4zlr", "payments-6f7c8d9b4-q8wvn", "inventory-58d7c6b9f-t3hjs", "auth-7b8c9d6f5-k9pxd"}
+
+	severities = []struct {
+		name   string
+		weight uint64
+	}{
+		{"INFO", 70},
+		{"WARN", 15},
+		{"ERROR", 10},
+		{"DEBUG", 5},
+	}
+
+	logTemplates = map[string][]string{
+		"INFO": {
+			"GET /api/%s 200 %dms",
+			"request completed user_id=%d duration=%dms",
+			"cache hit ratio=0.%d entries=%d",
+		},
+		"WARN": {
+			"slow query detected table=orders duration=%dms rows=%d",
+			"retrying upstream call attempt=%d backoff=%dms",
+		},
+		"ERROR": {
+			"connection refused to payments-db:5432 after %d retries (%dms)",
+			"upstream timeout calling %s after %dms",
+			"failed to process order order_id=%d: insufficient stock (%d)",
+		},
+		"DEBUG": {
+			"span exported trace_id=%d spans=%d",
+		},
+	}
+
+	metricNames = []string{
+		"http.server.request.duration",
+		"http.server.request.count",
+		"system.cpu.utilization",
+		"system.memory.usage",
+		"k8s.pod.restarts",
+		"db.client.connections.usage",
+	}
+
+	eventTypes = []struct {
+		eventType string
+		domain    string
+		body      string
+	}{
+		{"pattern_anomaly", "Monitor Alerts", `{"pattern":"connection refused to payments-db:5432 after * retries","score":%d,"monitor_name":"Payments anomaly"}`},
+		{"metric_threshold", "Monitor Alerts", `{"metric":"system.cpu.utilization","value":0.%d,"threshold":0.85,"monitor_name":"High CPU"}`},
+		{"log_threshold", "Monitor Alerts", `{"query":"severity_text:\"ERROR\"","count":%d,"threshold":100,"monitor_name":"Error burst"}`},
+		{"k8s_event", "K8s", `{"reason":"BackOff","message":"Back-off restarting failed container (%d)"}`},
+	}
+
+	patterns = []struct {
+		service   string
+		pattern   string
+		sentiment string
+		weight    int
+	}{
+		{"frontend", "GET /api/* 200 *ms", "neutral", 400},
+		{"checkout", "request completed user_id=* duration=*ms", "neutral", 250},
+		{"checkout", "failed to process order order_id=*: insufficient stock (*)", "negative", 30},
+		{"payments", "connection refused to payments-db:5432 after * retries (*ms)", "negative", 45},
+		{"payments", "upstream timeout calling * after *ms", "negative", 20},
+		{"inventory", "slow query detected table=orders duration=*ms rows=*", "negative", 60},
+		{"inventory", "cache hit ratio=0.* entries=*", "positive", 120},
+		{"auth", "retrying upstream call attempt=* backoff=*ms", "neutral", 40},
+	}
+
+	fieldConditionPattern = regexp.MustCompile(`(-?)(@?[A-Za-z_][A-Za-z0-9_.]*)\s*:\s*(?:"([^"]*)"|\(([^)]*)\)|([^\s()]+))`)
+	groupByPattern        = regexp.MustCompile(`\bby\s*\{([^}]*)\}`)
+	rollupPattern         = regexp.MustCompile(`\.rollup\((\d+)\)`)
+	metricNamePattern     = regexp.MustCompile(`^\s*[a-z]+:([^{\s]+)\{`)
+)
+
+// mix is a splitmix64 step, used to derive deterministic attributes from a record index.
+func mix(i uint64) uint64 {
+	z := i + 0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// timeWindow resolves the lookback/from/to query parameters the API accepts.
+func timeWindow(q url.Values) (time.Time, time.Time) {
+	to := time.Now().UTC()
+	if t, err := time.Parse(time.RFC3339Nano, q.Get("to")); err == nil {
+		to = t
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, q.Get("from")); err == nil && q.Get("lookback") == "" {
+		return t, to
+	}
+
+	lookback := time.Hour
+	if d, err := parseDuration(q.Get("lookback")); err == nil && d > 0 {
+		lookback = d
+	}
+
+	return to.Add(-lookback), to
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	switch {
+	case strings.HasSuffix(s, "d"):
+		n, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		return time.Duration(n) * 24 * time.Hour, err
+	case strings.HasSuffix(s, "w"):
+		n, err := strconv.Atoi(strings.TrimSuffix(s, "w"))
+		return time.Duration(n) * 7 * 24 * time.Hour, err
+	default:
+		return time.ParseDuration(s)
+	}
+}
+
+type record map[string]any
+
+// field returns the value of a flattened field, looking at the record, its resource and attributes.
+func (r record) field(name string) (string, bool) {
+	if strings.HasPrefix(name, "@") {
+		if attrs, ok := r["attributes"].(map[string]any); ok {
+			v, ok := attrs[strings.TrimPrefix(name, "@")]
+			return fmt.Sprint(v), ok
+		}
+		return "", false
+	}
+
+	if v, ok := r[name]; ok {
+		return fmt.Sprint(v), true
+	}
+
+	if res, ok := r["resource"].(map[string]any); ok {
+		if v, ok := res[name]; ok {
+			return fmt.Sprint(v), true
+		}
+	}
+
+	return "", false
+}
+
+func logAt(i uint64) record {
+	h := mix(i)
+	service := services[h%uint64(len(services))]
+	host := hosts[(h>>8)%uint64(len(hosts))]
+	env := envTags[(h>>12)%uint64(len(envTags))]
+
+	severity := severities[0].name
+	roll := (h >> 16) % 100
+	for _, s := range severities {
+		if roll < s.weight {
+			severity = s.name
+			break
+		}
+		roll -= s.weight
+	}
+
+	templates := logTemplates[severity]
+	template := templates[(h>>24)%uint64(len(templates))]
+	var body string
+	if strings.Count(template, "%") == 2 && strings.Contains(template, "%s") {
+		body = fmt.Sprintf(template, services[(h>>32)%uint64(len(services))], (h>>40)%5000)
+	} else {
+		body = fmt.Sprintf(template, (h>>32)%100000, (h>>48)%900+10)
+	}
+
+	return record{
+		"timestamp":     time.Unix(0, int64(i)*int64(logInterval)).UTC().Format(time.RFC3339Nano),
+		"body":          body,
+		"severity_text": severity,
+		"resource": map[string]any{
+			"service.name":  service,
+			"host.name":     host,
+			"ed.tag":        env,
+			"k8s.pod.name":  pods[indexOf(services, service)],
+			"k8s.namespace": "shop",
+		},
+		"attributes": map[string]any{
+			"http.status_code": statusCodeFor(severity, h),
+		},
+	}
+}
+
+func eventAt(i uint64) record {
+	h := mix(i ^ 0xe7e7)
+	ev := eventTypes[h%uint64(len(eventTypes))]
+	service := services[(h>>8)%uint64(len(services))]
+	return record{
+		"timestamp":       time.Unix(0, int64(i)*int64(eventInterval)).UTC().Format(time.RFC3339Nano),
+		"event.type":      ev.eventType,
+		"event.domain":    ev.domain,
+		"ed.monitor.type": ev.eventType,
+		"body":            fmt.Sprintf(ev.body, (h>>16)%90+10),
+		"resource": map[string]any{
+			"service.name": service,
+			"ed.tag":       envTags[(h>>24)%uint64(len(envTags))],
+		},
+	}
+}
+
+// traceAt returns the spans of the synthetic trace with the given index:
+// frontend -> checkout -> (payments, inventory), with occasional payment failures.
+func traceAt(i uint64) []record {
+	h := mix(i ^ 0x7ace)
+	start := time.Unix(0, int64(i)*int64(traceInterval)).UTC()
+	traceID := fmt.Sprintf("%016x%016x", h, mix(h))
+	failed := h%10 == 0
+
+	type spanDef struct {
+		id, parent, service, name, kind string
+		offset, duration                time.Duration
+		err                             string
+	}
+
+	paymentsErr := ""
+	checkoutErr := ""
+	frontendErr := ""
+	if failed {
+		paymentsErr = "connection refused to payments-db:5432"
+		checkoutErr = "payment authorization failed"
+		frontendErr = "HTTP 502 from checkout"
+	}
+
+	base := time.Duration(h>>20%80+20) * time.Millisecond
+	defs := []spanDef{
+		{"0001", "", "frontend", "GET /api/checkout", "server", 0, 4*base + 12*time.Millisecond, frontendErr},
+		{"0002", "0001", "checkout", "POST /orders", "server", 5 * time.Millisecond, 4 * base, checkoutErr},
+		{"0003", "0002", "inventory", "SELECT orders", "client", 8 * time.Millisecond, base, ""},
+		{"0004", "0002", "payments", "AuthorizePayment", "server", base + 10*time.Millisecond, 2 * base, paymentsErr},
+	}
+
+	spans := make([]record, 0, len(defs))
+	for _, d := range defs {
+		status := "OK"
+		if d.err != "" {
+			status = "ERROR"
+		}
+
+		span := record{
+			"trace_id":       traceID,
+			"span_id":        fmt.Sprintf("%012x%s", h>>16, d.id),
+			"parent_span_id": "",
+			"name":           d.name,
+			"span.kind":      d.kind,
+			"status.code":    status,
+			"status.message": d.err,
+			"timestamp":      start.Add(d.offset).Format(time.RFC3339Nano),
+			"duration_ns":    d.duration.Nanoseconds(),
+			"resource": map[string]any{
+				"service.name": d.service,
+				"ed.tag":       envTags[(h>>8)%uint64(len(envTags))],
+			},
+			"events": []any{},
+		}
+		if d.parent != "" {
+			span["parent_span_id"] = fmt.Sprintf("%012x%s", h>>16, d.parent)
+		}
+		if d.err != "" {
+			span["events"] = []any{map[string]any{
+				"name":      "exception",
+				"timestamp": start.Add(d.offset + d.duration/2).Format(time.RFC3339Nano),
+				"attributes": map[string]any{
+					"exception.type":    "ConnectionError",
+					"exception.message": d.err,
+				},
+			}}
+		}
+		spans = append(spans, span)
+	}
+	return spans
+}
+
+func statusCodeFor(severity string, h uint64) int {
+	switch severity {
+	case "ERROR":
+		return []int{500, 502, 503}[h%3]
+	case "WARN":
+		return 429
+	default:
+		return 200
+	}
+}
+
+func indexOf(values []string, v string) int {
+	for i, s := range values {
+		if s == v {
+			return i
+		}
+	}
+	return 0
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// condition is a single field:value term of a CQL query.
+type condition struct {
+	negate bool
+	field  string
+	values []string
+}
+
+// matcher implements the subset of CQL the demo data needs: field:"value", field:("a" OR "b"),
+// -field:"value" and full-text terms, all ANDed together.
+type matcher struct {
+	conditions []condition
+	terms      []string
+}
+
+func newMatcher(query string) matcher {
+	var m matcher
+	for _, match := range fieldConditionPattern.FindAllStringSubmatch(query, -1) {
+		c := condition{negate: match[1] == "-", field: match[2]}
+		switch {
+		case match[3] != "":
+			c.values = []string{match[3]}
+		case match[4] != "":
+			for _, v := range strings.Split(match[4], " OR ") {
+				c.values = append(c.values, strings.Trim(strings.TrimSpace(v), `"`))
+			}
+		default:
+			c.values = []string{match[5]}
+		}
+		m.conditions = append(m.conditions, c)
+	}
+
+	rest := fieldConditionPattern.ReplaceAllString(query, " ")
+	for _, term := range strings.Fields(strings.NewReplacer("(", " ", ")", " ", `"`, " ").Replace(rest)) {
+		switch term {
+		case "AND", "OR", "NOT", "*", "{*}":
+			continue
+		}
+		m.terms = append(m.terms, strings.ToLower(term))
+	}
+	return m
+}
+
+func (m matcher) match(r record) bool {
+	for _, c := range m.conditions {
+		v, _ := r.field(c.field)
+		if matchesAny(v, c.values) == c.negate {
+			return false
+		}
+	}
+
+	if len(m.terms) == 0 {
+		return true
+	}
+
+	body, _ := r.field("body")
+	body = strings.ToLower(body)
+	for _, t := range m.terms {
+		if strings.Contains(body, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// valuesFor returns the values the query allows for a field, or nil when the field is unconstrained.
+func (m matcher) valuesFor(field string, candidates []string) []string {
+	out := candidates
+	for _, c := range m.conditions {
+		if c.field != field {
+			continue
+		}
+		filtered := make([]string, 0, len(out))
+		for _, v := range out {
+			if matchesAny(v, c.values) != c.negate {
+				filtered = append(filtered, v)
+			}
+		}
+		out = filtered
+	}
+	return out
+}
+
+func matchesAny(v string, values []string) bool {
+	for _, want := range values {
+		switch {
+		case want == "*":
+			return true
+		case strings.HasPrefix(want, "*") && strings.HasSuffix(want, "*") && len(want) > 1:
+			if strings.Contains(v, strings.Trim(want, "*")) {
+				return true
+			}
+		case strings.HasSuffix(want, "*"):
+			if strings.HasPrefix(v, strings.TrimSuffix(want, "*")) {
+				return true
+			}
+		case strings.HasPrefix(want, "*"):
+			if strings.HasSuffix(v, strings.TrimPrefix(want, "*")) {
+				return true
+			}
+		case strings.EqualFold(v, want):
+			return true
+		}
+	}
+	return false
+}
+
+// scan walks the records produced by gen at the given interval within [from, to] in the
+// requested order and returns up to limit records accepted by m.
+func scan(gen func(uint64) record, interval time.Duration, from, to time.Time, desc bool, limit int, m matcher) []record {
+	first := uint64(from.UnixNano()/int64(interval)) + 1
+	last := uint64(to.UnixNano() / int64(interval))
+	if last < first {
+		return nil
+	}
+
+	out := make([]record, 0, limit)
+	for n := uint64(0); n <= last-first && n < maxScan && len(out) < limit; n++ {
+		i := first + n
+		if desc {
+			i = last - n
+		}
+		if r := gen(i); m.match(r) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// bucketSize picks a graph bucket so a window renders in at most ~60 points.
+func bucketSize(window time.Duration) time.Duration {
+	for _, d := range []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour, 6 * time.Hour, 24 * time.Hour} {
+		if window/d <= 60 {
+			return d
+		}
+	}
+	return 7 * 24 * time.Hour
+}
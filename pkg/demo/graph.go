@@ -0,0 +1,459 @@
+package demo
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	maxSeries = 50
+
+	// graphVolume scales the sampled record streams up to the volumes graphs report, as a
+	// search only ever shows a sample of what is ingested.
+	graphVolume = 50
+)
+
+// series holds one group of a graph query, one value per bucket.
+type series struct {
+	values []string
+	points []float64
+	gauge  bool
+}
+
+// graphResult is the evaluated result of a formula.
+type graphResult struct {
+	keys   []string
+	bucket time.Duration
+	series map[string]*series
+}
+
+func (g graphResult) render(from, to time.Time, table bool, limit int) map[string]any {
+	records := make([]map[string]any, 0, len(g.series))
+	for _, s := range g.series {
+		total := 0.0
+		for _, p := range s.points {
+			total += p
+		}
+		if s.gauge && len(s.points) > 0 {
+			total /= float64(len(s.points))
+		}
+
+		rec := map[string]any{
+			"values":    s.values,
+			"aggregate": map[string]any{"value": int(math.Round(total))},
+		}
+		if !table {
+			points := make([]map[string]any, 0, len(s.points))
+			for i, p := range s.points {
+				points = append(points, map[string]any{
+					"timestamp": bucketStart(from, g.bucket).Add(time.Duration(i) * g.bucket).UnixMilli(),
+					"value":     math.Round(p*100) / 100,
+				})
+			}
+			rec["timeseries"] = points
+		}
+		records = append(records, rec)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i]["aggregate"].(map[string]any)["value"].(int) > records[j]["aggregate"].(map[string]any)["value"].(int)
+	})
+	if len(records) > limit {
+		records = records[:limit]
+	}
+
+	return map[string]any{
+		"from":    from.Format(time.RFC3339),
+		"to":      to.Format(time.RFC3339),
+		"window":  g.bucket.String(),
+		"keys":    g.keys,
+		"records": records,
+	}
+}
+
+func bucketStart(from time.Time, bucket time.Duration) time.Time {
+	return from.Truncate(bucket)
+}
+
+// evaluateFormula computes the series of every query the formula references and combines
+// them bucket by bucket.
+func evaluateFormula(queries map[string]map[string]any, formula string, from, to time.Time, window string) (graphResult, error) {
+	expr, err := parseExpr(formula)
+	if err != nil {
+		return graphResult{}, err
+	}
+
+	bucket := bucketSize(to.Sub(from))
+	if d, err := time.ParseDuration(window); err == nil && d >= time.Minute && to.Sub(from)/d <= 10000 {
+		bucket = d
+	}
+	evaluated := make(map[string]graphResult)
+	for _, ref := range expr.refs(nil) {
+		q, ok := queries[ref]
+		if !ok {
+			return graphResult{}, fmt.Errorf("formula references unknown query %q", ref)
+		}
+		result, err := querySeries(q, from, to, bucket)
+		if err != nil {
+			return graphResult{}, fmt.Errorf("query %s: %w", ref, err)
+		}
+		evaluated[ref] = result
+	}
+
+	// The result is grouped like the query with the most groups; queries without the group
+	// (a single series) are broadcast to every group.
+	var out graphResult
+	for _, r := range evaluated {
+		if len(r.series) > len(out.series) {
+			out = graphResult{keys: r.keys, bucket: bucket, series: make(map[string]*series, len(r.series))}
+			for k, s := range r.series {
+				out.series[k] = &series{values: s.values, gauge: s.gauge}
+			}
+		}
+	}
+
+	n := int(to.Sub(bucketStart(from, bucket))/bucket) + 1
+	for key, s := range out.series {
+		s.points = make([]float64, n)
+		for i := range s.points {
+			s.points[i] = expr.eval(func(name string) float64 {
+				r := evaluated[name]
+				if src, ok := r.series[key]; ok {
+					return src.points[i]
+				}
+				if len(r.series) == 1 {
+					for _, src := range r.series {
+						return src.points[i]
+					}
+				}
+				return 0
+			})
+		}
+		if len(evaluated) > 1 {
+			// ratios and differences are not additive
+			s.gauge = true
+		}
+	}
+
+	return out, nil
+}
+
+// querySeries generates the series of a single graph query.
+func querySeries(q map[string]any, from, to time.Time, bucket time.Duration) (graphResult, error) {
+	scope, _ := q["scope"].(string)
+	query, _ := q["query"].(string)
+	dataType, _ := q["dataType"].(string)
+
+	var groupBy []string
+	if match := groupByPattern.FindStringSubmatch(query); match != nil {
+		for _, k := range strings.Split(match[1], ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				groupBy = append(groupBy, k)
+			}
+		}
+	}
+
+	if match := rollupPattern.FindStringSubmatch(query); match != nil {
+		if sec, err := strconv.Atoi(match[1]); err == nil && sec > 0 {
+			if d := time.Duration(sec) * time.Second; to.Sub(from)/d <= 1000 {
+				bucket = d
+			}
+		}
+	}
+
+	filter := rollupPattern.ReplaceAllString(groupByPattern.ReplaceAllString(query, ""), "")
+	base, gauge := 0.0, false
+	switch scope {
+	case "log", "pattern":
+		base = graphVolume * bucket.Seconds() / logInterval.Seconds()
+	case "event":
+		base = bucket.Seconds() / eventInterval.Seconds()
+	case "trace":
+		switch strings.ToLower(dataType) {
+		case "latency", "duration":
+			base, gauge = 180, true
+		default:
+			base = bucket.Seconds() / traceInterval.Seconds() * 4
+		}
+	case "metric":
+		match := metricNamePattern.FindStringSubmatch(filter)
+		if match == nil {
+			return graphResult{}, fmt.Errorf("invalid metric query %q, expected <aggregation>:<name>{<filter>}", query)
+		}
+		if !contains(metricNames, match[1]) {
+			return graphResult{}, fmt.Errorf("metric %q not found", match[1])
+		}
+		base, gauge = metricBaseline(match[1], bucket)
+		if agg := strings.SplitN(strings.TrimSpace(filter), ":", 2)[0]; agg != "sum" && agg != "count" {
+			gauge = true
+		}
+		if start := strings.Index(filter, "{"); start >= 0 {
+			filter = strings.TrimSuffix(strings.TrimSpace(filter[start+1:]), "}")
+		}
+	default:
+		return graphResult{}, fmt.Errorf("unsupported scope %q", scope)
+	}
+
+	m := newMatcher(strings.Trim(strings.TrimSpace(filter), "{}"))
+
+	// Dimensions that are filtered but not grouped on scale the series down by the share of
+	// the values the filter keeps.
+	for _, dim := range []string{"service.name", "severity_text", "host.name", "ed.tag"} {
+		if contains(groupBy, dim) {
+			continue
+		}
+		all := facetValues(dim)
+		if !gauge {
+			base *= share(dim, m.valuesFor(dim, all), all)
+		}
+	}
+
+	result := graphResult{keys: groupBy, bucket: bucket, series: make(map[string]*series)}
+	n := int(to.Sub(bucketStart(from, bucket))/bucket) + 1
+	for _, values := range groupCombinations(groupBy, m) {
+		scale := base
+		if !gauge {
+			for i, dim := range groupBy {
+				scale *= share(dim, []string{values[i]}, facetValues(dim))
+			}
+		}
+
+		key := strings.Join(values, "\x00")
+		s := &series{values: values, points: make([]float64, n), gauge: gauge}
+		for i := range s.points {
+			at := bucketStart(from, bucket).Add(time.Duration(i) * bucket)
+			s.points[i] = scale * shape(key+query, at, values)
+			if !gauge {
+				s.points[i] = math.Round(s.points[i])
+			}
+		}
+		result.series[key] = s
+	}
+
+	return result, nil
+}
+
+// share returns the fraction of the total volume the given values of a dimension account for.
+func share(dim string, kept, all []string) float64 {
+	if len(all) == 0 {
+		return 1
+	}
+	if dim != "severity_text" {
+		return float64(len(kept)) / float64(len(all))
+	}
+
+	total := 0.0
+	for _, s := range severities {
+		if contains(kept, s.name) {
+			total += float64(s.weight)
+		}
+	}
+	return total / 100
+}
+
+func groupCombinations(groupBy []string, m matcher) [][]string {
+	combos := [][]string{{}}
+	for _, dim := range groupBy {
+		values := facetValues(dim)
+		if values == nil {
+			values = []string{dim + "-1", dim + "-2"}
+		}
+		values = m.valuesFor(dim, values)
+
+		next := make([][]string, 0, len(combos)*len(values))
+		for _, c := range combos {
+			for _, v := range values {
+				if len(next) >= maxSeries {
+					break
+				}
+				next = append(next, append(append([]string{}, c...), v))
+			}
+		}
+		combos = next
+	}
+	return combos
+}
+
+// shape adds a daily wave, jitter and a recurring payments incident to a baseline.
+func shape(key string, at time.Time, values []string) float64 {
+	h := mix(uint64(at.Unix()) ^ hashString(key))
+	jitter := 0.9 + float64(h%200)/1000
+	wave := 1 + 0.3*math.Sin(2*math.Pi*float64(at.Unix()%86400)/86400)
+
+	incident := 1.0
+	if at.Unix()%(6*3600) < 20*60 {
+		for _, v := range values {
+			if v == "payments" || v == "ERROR" {
+				incident = 4
+			}
+		}
+	}
+	return wave * jitter * incident
+}
+
+func hashString(s string) uint64 {
+	var h uint64 = 14695981039346656037
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= 1099511628211
+	}
+	return h
+}
+
+func metricBaseline(name string, bucket time.Duration) (float64, bool) {
+	switch name {
+	case "http.server.request.duration":
+		return 120, true
+	case "http.server.request.count":
+		return bucket.Seconds() * 5, false
+	case "system.cpu.utilization":
+		return 0.45, true
+	case "system.memory.usage":
+		return 2.1e9, true
+	case "k8s.pod.restarts":
+		return 0.2, false
+	default:
+		return 40, true
+	}
+}
+
+// expr is a parsed formula: query references, numbers and + - * / with parentheses.
+type expr interface {
+	eval(lookup func(string) float64) float64
+	refs(acc []string) []string
+}
+
+type numberExpr float64
+type refExpr string
+type binaryExpr struct {
+	op          byte
+	left, right expr
+}
+
+func (e numberExpr) eval(func(string) float64) float64 { return float64(e) }
+func (e numberExpr) refs(acc []string) []string        { return acc }
+
+func (e refExpr) eval(lookup func(string) float64) float64 { return lookup(string(e)) }
+func (e refExpr) refs(acc []string) []string {
+	for _, r := range acc {
+		if r == string(e) {
+			return acc
+		}
+	}
+	return append(acc, string(e))
+}
+
+func (e binaryExpr) eval(lookup func(string) float64) float64 {
+	l, r := e.left.eval(lookup), e.right.eval(lookup)
+	switch e.op {
+	case '+':
+		return l + r
+	case '-':
+		return l - r
+	case '*':
+		return l * r
+	default:
+		if r == 0 {
+			return 0
+		}
+		return l / r
+	}
+}
+
+func (e binaryExpr) refs(acc []string) []string { return e.right.refs(e.left.refs(acc)) }
+
+type exprParser struct {
+	s   string
+	pos int
+}
+
+func parseExpr(s string) (expr, error) {
+	p := &exprParser{s: s}
+	e, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	if p.skip(); p.pos < len(p.s) {
+		return nil, fmt.Errorf("unexpected %q in formula %q", p.s[p.pos:], s)
+	}
+	return e, nil
+}
+
+func (p *exprParser) skip() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *exprParser) sum() (expr, error) {
+	left, err := p.product()
+	for err == nil {
+		if p.skip(); p.pos >= len(p.s) || (p.s[p.pos] != '+' && p.s[p.pos] != '-') {
+			return left, nil
+		}
+		op := p.s[p.pos]
+		p.pos++
+		var right expr
+		if right, err = p.product(); err == nil {
+			left = binaryExpr{op: op, left: left, right: right}
+		}
+	}
+	return nil, err
+}
+
+func (p *exprParser) product() (expr, error) {
+	left, err := p.operand()
+	for err == nil {
+		if p.skip(); p.pos >= len(p.s) || (p.s[p.pos] != '*' && p.s[p.pos] != '/') {
+			return left, nil
+		}
+		op := p.s[p.pos]
+		p.pos++
+		var right expr
+		if right, err = p.operand(); err == nil {
+			left = binaryExpr{op: op, left: left, right: right}
+		}
+	}
+	return nil, err
+}
+
+func (p *exprParser) operand() (expr, error) {
+	p.skip()
+	if p.pos >= len(p.s) {
+		return nil, fmt.Errorf("unexpected end of formula %q", p.s)
+	}
+
+	switch c := rune(p.s[p.pos]); {
+	case c == '(':
+		p.pos++
+		e, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		if p.skip(); p.pos >= len(p.s) || p.s[p.pos] != ')' {
+			return nil, fmt.Errorf("missing ) in formula %q", p.s)
+		}
+		p.pos++
+		return e, nil
+	case unicode.IsDigit(c) || c == '.':
+		start := p.pos
+		for p.pos < len(p.s) && (unicode.IsDigit(rune(p.s[p.pos])) || p.s[p.pos] == '.') {
+			p.pos++
+		}
+		v, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+		return numberExpr(v), err
+	case unicode.IsLetter(c) || c == '_':
+		start := p.pos
+		for p.pos < len(p.s) && (unicode.IsLetter(rune(p.s[p.pos])) || unicode.IsDigit(rune(p.s[p.pos])) || p.s[p.pos] == '_') {
+			p.pos++
+		}
+		return refExpr(p.s[start:p.pos]), nil
+	default:
+		return nil, fmt.Errorf("unexpected %q in formula %q", string(c), p.s)
+	}
+}
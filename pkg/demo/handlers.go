@@ -0,0 +1,923 @@
+package demo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/tools"
+	"github.com/gorilla/mux"
+	"gopkg.in/yaml.v3"
+)
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...any) {
+	writeJSON(w, status, map[string]string{"error": fmt.Sprintf(format, args...)})
+}
+
+func limitParam(r *http.Request, def int) int {
+	if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && n > 0 {
+		return n
+	}
+	return def
+}
+
+// handleRecordSearch serves a cursor paginated search over a synthetic record stream.
+// The cursor is the index of the next record to scan.
+func handleRecordSearch(w http.ResponseWriter, r *http.Request, gen func(uint64) record, interval time.Duration) {
+	q := r.URL.Query()
+	from, to := timeWindow(q)
+	desc := q.Get("order") != "asc"
+	limit := limitParam(r, 20)
+
+	if cursor, err := strconv.ParseUint(q.Get("cursor"), 10, 64); err == nil {
+		at := time.Unix(0, int64(cursor)*int64(interval))
+		if desc {
+			to = at
+		} else {
+			from = at.Add(-interval)
+		}
+	}
+
+	items := scan(gen, interval, from, to, desc, limit+1, newMatcher(q.Get("query")))
+
+	response := map[string]any{"items": items}
+	if len(items) > limit {
+		response["items"] = items[:limit]
+		ts, _ := time.Parse(time.RFC3339Nano, items[limit]["timestamp"].(string))
+		response["next_cursor"] = strconv.FormatInt(ts.UnixNano()/int64(interval), 10)
+	}
+	response["query_stats"] = queryStats(from, to, interval)
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// queryStats makes up the execution stats of a search over the records of the window, as if every
+// record was evaluated.
+func queryStats(from, to time.Time, interval time.Duration) map[string]any {
+	records := int64(to.Sub(from) / interval)
+	return map[string]any{
+		"scanned_bytes":     records * 320,
+		"evaluated_records": records,
+		"execution_ms":      12 + records/2000,
+		"shards":            1,
+	}
+}
+
+func handleLogSearch(w http.ResponseWriter, r *http.Request) {
+	handleRecordSearch(w, r, logAt, logInterval)
+}
+
+func handleEventSearch(w http.ResponseWriter, r *http.Request) {
+	handleRecordSearch(w, r, eventAt, eventInterval)
+}
+
+func handleTraces(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	from, to := timeWindow(q)
+	limit := limitParam(r, 20)
+	m := newMatcher(q.Get("query"))
+	withChildren := q.Get("include_child_spans") == "true"
+
+	first := uint64(from.UnixNano()/int64(traceInterval)) + 1
+	last := uint64(to.UnixNano() / int64(traceInterval))
+
+	items := make([]record, 0, limit)
+	for n := uint64(0); last >= first && n <= last-first && n < maxScan && len(items) < limit; n++ {
+		spans := traceAt(last - n)
+		for _, span := range spans {
+			if !m.match(span) {
+				continue
+			}
+			if withChildren {
+				items = append(items, spans...)
+				break
+			}
+			items = append(items, span)
+			if len(items) >= limit {
+				break
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+func handlePatternStats(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	from, to := timeWindow(q)
+	m := newMatcher(q.Get("query"))
+	negative := q.Get("negative") == "true"
+	limit := limitParam(r, 50)
+	hours := to.Sub(from).Hours()
+
+	type stat struct {
+		Pattern    string  `json:"pattern"`
+		Count      int     `json:"count"`
+		Proportion float64 `json:"proportion"`
+		Sentiment  string  `json:"sentiment"`
+		Delta      float64 `json:"delta"`
+		Service    string  `json:"service"`
+	}
+
+	var stats []stat
+	total := 0
+	for i, p := range patterns {
+		if negative && p.sentiment != "negative" {
+			continue
+		}
+		if len(m.valuesFor("service.name", []string{p.service})) == 0 {
+			continue
+		}
+		if len(m.terms) > 0 && !m.match(record{"body": p.pattern}) {
+			continue
+		}
+
+		count := int(float64(p.weight)*hours*12) + 1
+		stats = append(stats, stat{
+			Pattern:   p.pattern,
+			Count:     count,
+			Sentiment: p.sentiment,
+			Delta:     float64(int64(mix(uint64(i))%400)-100) / 10,
+			Service:   p.service,
+		})
+		total += count
+	}
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Count > stats[j].Count })
+	if len(stats) > limit {
+		stats = stats[:limit]
+	}
+	for i := range stats {
+		stats[i].Proportion = float64(stats[i].Count) / float64(total)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"stats": stats})
+}
+
+func handleServicesGraph(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	from, to := timeWindow(q)
+	m := newMatcher(q.Get("query"))
+
+	response := tools.GraphResponse{
+		From:   from.Format(time.RFC3339),
+		To:     to.Format(time.RFC3339),
+		Window: bucketSize(to.Sub(from)).String(),
+		Keys:   []string{"service.name"},
+	}
+	for _, s := range m.valuesFor("service.name", services) {
+		record := tools.GraphRecord{Values: []string{s}}
+		record.Aggregate.Value = int(to.Sub(from)/logInterval) / len(services)
+		response.Records = append(response.Records, record)
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func handleGraph(w http.ResponseWriter, r *http.Request) {
+	var payload tools.GraphPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid graph payload: %v", err)
+		return
+	}
+
+	q := r.URL.Query()
+	from, to := timeWindow(q)
+	table := q.Get("graph_type") == "table"
+	limit := limitParam(r, 100)
+
+	results := make(map[string]any, len(payload.Formulas))
+	for name, formula := range payload.Formulas {
+		result, err := evaluateFormula(payload.Queries, formula.Formula, from, to, q.Get("window"))
+		if err != nil {
+			results[name] = map[string]any{"status_code": http.StatusBadRequest, "error": err.Error()}
+			continue
+		}
+		results[name] = result.render(from, to, table, limit)
+	}
+
+	writeJSON(w, http.StatusMultiStatus, results)
+}
+
+var facetsByScope = map[string][]tools.Facet{
+	"log": {
+		{Name: "Service", Path: "service.name"},
+		{Name: "Severity", Path: "severity_text"},
+		{Name: "Host", Path: "host.name"},
+		{Name: "Tag", Path: "ed.tag"},
+		{Name: "Pod", Path: "k8s.pod.name"},
+		{Name: "Namespace", Path: "k8s.namespace"},
+		{Name: "HTTP Status", Path: "@http.status_code"},
+	},
+	"metric": {
+		{Name: "Metric Name", Path: "name"},
+		{Name: "Service", Path: "service.name"},
+		{Name: "Host", Path: "host.name"},
+	},
+	"trace": {
+		{Name: "Service", Path: "service.name"},
+		{Name: "Span Name", Path: "name"},
+		{Name: "Span Kind", Path: "span.kind"},
+		{Name: "Status", Path: "status.code"},
+	},
+	"pattern": {
+		{Name: "Service", Path: "service.name"},
+		{Name: "Sentiment", Path: "sentiment"},
+	},
+	"event": {
+		{Name: "Event Type", Path: "event.type"},
+		{Name: "Event Domain", Path: "event.domain"},
+		{Name: "Service", Path: "service.name"},
+		{Name: "Monitor Type", Path: "ed.monitor.type"},
+	},
+}
+
+func handleFacets(w http.ResponseWriter, r *http.Request) {
+	scope := r.URL.Query().Get("scope")
+	if scope == "" {
+		scope = "log"
+	}
+
+	builtin := make([]tools.Facet, 0, len(facetsByScope[scope]))
+	for _, f := range facetsByScope[scope] {
+		f.Scope = scope
+		builtin = append(builtin, f)
+	}
+
+	writeJSON(w, http.StatusOK, tools.FacetsResponse{Builtin: builtin, UserDefined: []tools.Facet{}})
+}
+
+func handleCreateFacet(w http.ResponseWriter, r *http.Request) {
+	var facet tools.Facet
+	if err := json.NewDecoder(r.Body).Decode(&facet); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid facet: %v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, facet)
+}
+
+func handleNoContent(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleAccepted(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"conf_id": mux.Vars(r)["conf_id"],
+		"status":  "ok",
+		"message": "demo mode: no changes were made",
+	})
+}
+
+func facetValues(path string) []string {
+	switch strings.TrimPrefix(path, "@") {
+	case "service.name":
+		return services
+	case "severity_text":
+		out := make([]string, 0, len(severities))
+		for _, s := range severities {
+			out = append(out, s.name)
+		}
+		return out
+	case "host.name":
+		return hosts
+	case "ed.tag":
+		return envTags
+	case "k8s.pod.name":
+		return pods
+	case "k8s.namespace":
+		return []string{"shop"}
+	case "http.status_code":
+		return []string{"200", "429", "500", "502", "503"}
+	case "name":
+		return metricNames
+	case "span.kind":
+		return []string{"server", "client"}
+	case "status.code":
+		return []string{"OK", "ERROR"}
+	case "sentiment":
+		return []string{"positive", "neutral", "negative"}
+	case "event.type", "ed.monitor.type":
+		out := make([]string, 0, len(eventTypes))
+		for _, e := range eventTypes {
+			out = append(out, e.eventType)
+		}
+		return out
+	case "event.domain":
+		return []string{"Monitor Alerts", "K8s"}
+	default:
+		return nil
+	}
+}
+
+func handleFacetOptions(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	path := q.Get("facet_path")
+	values := newMatcher(q.Get("query")).valuesFor(path, facetValues(path))
+
+	facet := tools.Facet{Name: path, Path: path, Scope: q.Get("scope")}
+	for i, v := range values {
+		facet.Options = append(facet.Options, tools.FacetOption{Name: v, Count: int(mix(uint64(i))%5000) + 100})
+	}
+
+	writeJSON(w, http.StatusOK, facet)
+}
+
+func handleFacetKeys(w http.ResponseWriter, r *http.Request) {
+	scope := r.URL.Query().Get("scope")
+	keys := make([]tools.FacetKey, 0, len(facetsByScope[scope]))
+	for _, f := range facetsByScope[scope] {
+		keys = append(keys, tools.FacetKey{Key: f.Path})
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+type demoPipeline struct {
+	summary tools.PipelineSummary
+	content string
+}
+
+var pipelines = []demoPipeline{
+	{
+		summary: tools.PipelineSummary{
+			ID: "demo-conf-k8s-prod", Tag: "prod-us-east", ClusterName: "shop-us-east",
+			Creator: "demo@edgedelta.com", Created: "2025-01-10T09:00:00Z",
+			Updater: "demo@edgedelta.com", Updated: "2025-01-13T09:00:00Z",
+			Environment: tools.KubernetesEnvironmentType, FleetType: tools.EdgeFleetType, Status: tools.FleetRunning,
+		},
+		content: `version: v3
+settings:
+  tag: prod-us-east
+nodes:
+  - name: k8s_logs
+    type: kubernetes_input
+  - name: error_filter
+    type: ottl_transform
+    statements: |
+      set(attributes["level"], "error") where IsMatch(body, "(?i)error|exception")
+      replace_pattern(body, "user_id=\\d+", "user_id=***")
+  - name: ed_output
+    type: ed_output
+links:
+  - from: k8s_logs
+    to: error_filter
+  - from: error_filter
+    to: ed_output
+`,
+	},
+	{
+		summary: tools.PipelineSummary{
+			ID: "demo-conf-k8s-eu", Tag: "prod-eu-west", ClusterName: "shop-eu-west",
+			Creator: "demo@edgedelta.com", Created: "2025-02-03T14:30:00Z",
+			Updater: "demo@edgedelta.com", Updated: "2025-02-04T10:15:00Z",
+			Environment: tools.HelmEnvironmentType, FleetType: tools.EdgeFleetType, Status: tools.FleetRunning,
+		},
+		content: `version: v3
+settings:
+  tag: prod-eu-west
+nodes:
+  - name: k8s_logs
+    type: kubernetes_input
+  - name: ed_output
+    type: ed_output
+links:
+  - from: k8s_logs
+    to: ed_output
+`,
+	},
+	{
+		summary: tools.PipelineSummary{
+			ID: "demo-conf-ingestion", Tag: "AI-Connector-Telemetry-Pipeline",
+			Creator: "demo@edgedelta.com", Created: "2025-01-10T09:00:00Z",
+			Updater: "demo@edgedelta.com", Updated: "2025-01-10T09:00:00Z",
+			FleetType: tools.IngestionPipelineFleetType, Status: tools.FleetRunning,
+		},
+		content: `version: v3
+settings:
+  tag: AI-Connector-Telemetry-Pipeline
+nodes:
+  - name: http_ingest
+    type: http_ingestion_input
+  - name: ed_output
+    type: ed_output
+links:
+  - from: http_ingest
+    to: ed_output
+`,
+	},
+}
+
+func findPipeline(confID string) (demoPipeline, bool) {
+	for _, p := range pipelines {
+		if p.summary.ID == confID {
+			return p, true
+		}
+	}
+	return demoPipeline{}, false
+}
+
+func handlePipelines(w http.ResponseWriter, _ *http.Request) {
+	out := make([]tools.PipelineSummary, 0, len(pipelines))
+	for _, p := range pipelines {
+		out = append(out, p.summary)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func handlePipelineHistory(w http.ResponseWriter, r *http.Request) {
+	p, ok := findPipeline(mux.Vars(r)["conf_id"])
+	if !ok {
+		writeError(w, http.StatusNotFound, "pipeline not found")
+		return
+	}
+
+	created, _ := time.Parse(time.RFC3339, p.summary.Created)
+	history := []map[string]any{
+		{"timestamp": created.Add(72 * time.Hour).UnixMilli(), "author": "demo@edgedelta.com", "description": "Route errors to a dedicated filter", "status": "deployed", "content": p.content},
+		{"timestamp": created.Add(24 * time.Hour).UnixMilli(), "author": "demo@edgedelta.com", "description": "Add kubernetes input", "status": "saved", "content": p.content},
+		{"timestamp": created.UnixMilli(), "author": "demo@edgedelta.com", "description": "Initial version", "status": "saved", "content": p.content},
+	}
+	writeJSON(w, http.StatusOK, history)
+}
+
+func (p demoPipeline) conf(withContent bool) tools.ConfSummary {
+	conf := tools.ConfSummary{ID: p.summary.ID, Tag: p.summary.Tag, FleetType: p.summary.FleetType}
+	if withContent {
+		conf.Content = p.content
+	}
+	return conf
+}
+
+func handleConfs(w http.ResponseWriter, r *http.Request) {
+	withContent := r.URL.Query().Get("empty_contents") != "true"
+	out := make([]tools.ConfSummary, 0, len(pipelines))
+	for _, p := range pipelines {
+		out = append(out, p.conf(withContent))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func handleConf(w http.ResponseWriter, r *http.Request) {
+	p, ok := findPipeline(mux.Vars(r)["conf_id"])
+	if !ok {
+		writeError(w, http.StatusNotFound, "conf not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, p.conf(true))
+}
+
+// handleValidatePipeline checks the YAML syntax, the version and that links reference existing
+// nodes, which is enough to show the validation flow.
+func handleValidatePipeline(w http.ResponseWriter, r *http.Request) {
+	if _, ok := findPipeline(mux.Vars(r)["conf_id"]); !ok {
+		writeError(w, http.StatusNotFound, "conf not found")
+		return
+	}
+
+	var body struct {
+		Content string `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: %v", err)
+		return
+	}
+
+	result := tools.PipelineValidationResult{Errors: []tools.PipelineValidationIssue{}, Warnings: []tools.PipelineValidationIssue{}}
+	var conf struct {
+		Version string `yaml:"version"`
+		Nodes   []struct {
+			Name string `yaml:"name"`
+			Type string `yaml:"type"`
+		} `yaml:"nodes"`
+		Links []struct {
+			From string `yaml:"from"`
+			To   string `yaml:"to"`
+		} `yaml:"links"`
+	}
+	if err := yaml.Unmarshal([]byte(body.Content), &conf); err != nil {
+		result.Errors = append(result.Errors, tools.PipelineValidationIssue{Message: err.Error()})
+		writeJSON(w, http.StatusUnprocessableEntity, result)
+		return
+	}
+
+	if conf.Version == "" {
+		result.Warnings = append(result.Warnings, tools.PipelineValidationIssue{Path: "version", Message: "version is missing, v3 is assumed"})
+	}
+	nodes := make(map[string]bool, len(conf.Nodes))
+	for i, n := range conf.Nodes {
+		if n.Name == "" || n.Type == "" {
+			result.Errors = append(result.Errors, tools.PipelineValidationIssue{Path: fmt.Sprintf("nodes[%d]", i), Message: "node must have a name and a type"})
+		}
+		nodes[n.Name] = true
+	}
+	linked := make(map[string]bool)
+	for i, l := range conf.Links {
+		for _, end := range [][2]string{{"from", l.From}, {"to", l.To}} {
+			field, name := end[0], end[1]
+			if !nodes[name] {
+				result.Errors = append(result.Errors, tools.PipelineValidationIssue{Path: fmt.Sprintf("links[%d].%s", i, field), Node: name, Message: fmt.Sprintf("link references unknown node %q", name)})
+			}
+			linked[name] = true
+		}
+	}
+	for _, n := range conf.Nodes {
+		if n.Name != "" && !linked[n.Name] {
+			result.Warnings = append(result.Warnings, tools.PipelineValidationIssue{Node: n.Name, Message: fmt.Sprintf("node %q is not linked to any other node", n.Name)})
+		}
+	}
+
+	result.Valid = len(result.Errors) == 0
+	status := http.StatusOK
+	if !result.Valid {
+		status = http.StatusUnprocessableEntity
+	}
+	writeJSON(w, status, result)
+}
+
+func handleFeatures(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, tools.OrgFeaturesResponse{
+		Features: map[string]bool{
+			tools.FeatureMetrics:  true,
+			tools.FeatureTraces:   true,
+			tools.FeaturePatterns: true,
+			tools.FeatureEvents:   true,
+		},
+	})
+}
+
+func handleServiceAliases(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, tools.ServiceAliasesResponse{
+		Aliases: map[string][]string{
+			"storefront": {"frontend"},
+			"orders":     {"checkout", "inventory"},
+		},
+	})
+}
+
+func handleOrgs(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, tools.OrgsResponse{
+		Orgs: []tools.Org{
+			{ID: OrgID, Name: "Demo", Role: "admin"},
+			{ID: "demo-acme", Name: "Acme Corp", Role: "admin"},
+			{ID: "demo-globex", Name: "Globex", Role: "read_only"},
+		},
+	})
+}
+
+func handleChildOrgs(w http.ResponseWriter, r *http.Request) {
+	if mux.Vars(r)["org_id"] != OrgID {
+		writeJSON(w, http.StatusOK, tools.ChildOrgsResponse{Orgs: []tools.Org{}})
+		return
+	}
+	writeJSON(w, http.StatusOK, tools.ChildOrgsResponse{
+		Orgs: []tools.Org{
+			{ID: "demo-acme", Name: "Acme Corp"},
+			{ID: "demo-globex", Name: "Globex"},
+		},
+	})
+}
+
+func handleOrgMembers(w http.ResponseWriter, _ *http.Request) {
+	now := time.Now().UTC()
+	ago := func(d time.Duration) *time.Time {
+		t := now.Add(-d)
+		return &t
+	}
+	writeJSON(w, http.StatusOK, tools.OrgMembersResponse{
+		Members: []tools.OrgMember{
+			{UserID: "demo-user-1", Email: "alice@demo.edgedelta.local", Name: "Alice", Role: "admin", LastLoginAt: ago(2 * time.Hour)},
+			{UserID: "demo-user-2", Email: "bob@demo.edgedelta.local", Name: "Bob", Role: "editor", LastLoginAt: ago(3 * 24 * time.Hour)},
+			{UserID: "demo-user-3", Email: "carol@demo.edgedelta.local", Name: "Carol", Role: "read_only"},
+		},
+	})
+}
+
+func handleAPIKeys(w http.ResponseWriter, _ *http.Request) {
+	now := time.Now().UTC()
+	ago := func(d time.Duration) *time.Time {
+		t := now.Add(-d)
+		return &t
+	}
+	writeJSON(w, http.StatusOK, tools.APIKeysResponse{
+		APIKeys: []tools.APIKey{
+			{KeyID: "demo-key-ci", Name: "CI deploys", Permissions: []string{"pipelines:write"}, CreatedBy: "alice@demo.edgedelta.local", CreatedAt: ago(400 * 24 * time.Hour), LastUsedAt: ago(time.Hour)},
+			{KeyID: "demo-key-grafana", Name: "Grafana", Permissions: []string{"logs:read", "metrics:read"}, CreatedBy: "bob@demo.edgedelta.local", CreatedAt: ago(300 * 24 * time.Hour), LastUsedAt: ago(120 * 24 * time.Hour)},
+			{KeyID: "demo-key-test", Name: "test", Permissions: []string{"admin"}, CreatedBy: "alice@demo.edgedelta.local", CreatedAt: ago(200 * 24 * time.Hour)},
+		},
+	})
+}
+
+func handlePipelineAgents(w http.ResponseWriter, r *http.Request) {
+	p, ok := findPipeline(mux.Vars(r)["conf_id"])
+	if !ok {
+		writeError(w, http.StatusNotFound, "pipeline %s not found", mux.Vars(r)["conf_id"])
+		return
+	}
+
+	now := time.Now().UTC()
+	agents := []tools.Agent{}
+	if p.summary.ClusterName != "" {
+		for i, age := range []time.Duration{20 * time.Second, 40 * time.Second, 3 * time.Hour} {
+			agents = append(agents, tools.Agent{
+				AgentID:  fmt.Sprintf("%s-agent-%d", p.summary.ID, i+1),
+				Host:     fmt.Sprintf("%s-node-%d", p.summary.ClusterName, i+1),
+				Version:  "v1.30.0",
+				Health:   "healthy",
+				LastSeen: now.Add(-age),
+			})
+		}
+		agents[1].Health = "degraded"
+		agents[1].Message = "output edgedelta: 12 retries in the last 5m"
+		agents[2].Version = "v1.28.2"
+	}
+
+	if host := r.URL.Query().Get("host"); host != "" {
+		agents = slices.DeleteFunc(agents, func(a tools.Agent) bool { return !strings.EqualFold(a.Host, host) })
+	}
+	writeJSON(w, http.StatusOK, tools.AgentsResponse{Agents: agents})
+}
+
+func handleIngestionEndpoints(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, tools.IngestionEndpointsResponse{
+		HTTPS: &tools.HTTPSIngestionEndpoints{
+			BaseURL: "https://ingest.demo.edgedelta.local",
+			PathForDataType: map[string]string{
+				"logs":    "/v1/logs",
+				"metrics": "/v1/metrics",
+				"traces":  "/v1/traces",
+			},
+			SampleData: map[string]string{
+				"logs": `{"body":"hello from demo","severity_text":"INFO"}`,
+			},
+			TestCommands: map[string]string{
+				"logs": `curl -X POST "https://ingest.demo.edgedelta.local/v1/logs?token={TOKEN}" -d '{"body":"hello from demo"}'`,
+			},
+		},
+	})
+}
+
+func handleIngestionToken(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	writeJSON(w, http.StatusOK, tools.IngestionTokenResponse{
+		RawToken: "demo-ingestion-token",
+		TokenID:  "demo-token-id",
+		OrgID:    mux.Vars(r)["org_id"],
+		ConfID:   q.Get("conf_id"),
+		NodeName: q.Get("node_name"),
+	})
+}
+
+var dashboards = []map[string]any{
+	{
+		"dashboard_id": "demo-dashboard-overview",
+		"name":         "Shop Overview",
+		"description":  "Request volume, errors and latency across shop services",
+		"tags":         []string{"overview"},
+		"definition": map[string]any{
+			"widgets": []map[string]any{
+				{"id": "w1", "title": "Log volume by service", "type": "timeseries", "query": map[string]any{"scope": "log", "query": "{*} by {service.name}"}},
+				{"id": "w2", "title": "Errors by service", "type": "timeseries", "query": map[string]any{"scope": "log", "query": `{severity_text:"ERROR"} by {service.name}`}},
+				{"id": "w3", "title": "Request latency", "type": "timeseries", "query": map[string]any{"scope": "metric", "query": "avg:http.server.request.duration{*} by {service.name}"}},
+			},
+		},
+	},
+	{
+		"dashboard_id": "demo-dashboard-payments",
+		"name":         "Payments",
+		"description":  "Payments service health",
+		"tags":         []string{"payments"},
+		"definition": map[string]any{
+			"widgets": []map[string]any{
+				{"id": "w1", "title": "Payment errors", "type": "timeseries", "query": map[string]any{"scope": "log", "query": `service.name:"payments" AND severity_text:"ERROR"`}},
+				{"id": "w2", "title": "DB connections", "type": "timeseries", "query": map[string]any{"scope": "metric", "query": `avg:db.client.connections.usage{service.name:"payments"}`}},
+			},
+		},
+	},
+}
+
+func handleDashboards(w http.ResponseWriter, r *http.Request) {
+	withDefinition := r.URL.Query().Get("include_definitions") == "true"
+	out := make([]map[string]any, 0, len(dashboards))
+	for _, d := range dashboards {
+		summary := make(map[string]any, len(d))
+		for k, v := range d {
+			if k == "definition" && !withDefinition {
+				continue
+			}
+			summary[k] = v
+		}
+		out = append(out, summary)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func handleDashboard(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["dashboard_id"]
+	for _, d := range dashboards {
+		if d["dashboard_id"] == id {
+			writeJSON(w, http.StatusOK, d)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "dashboard %s not found", id)
+}
+
+func handleDashboardChange(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["dashboard_id"]
+	if id == "" {
+		id = "demo-dashboard-new"
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"dashboard_id": id,
+		"status":       "ok",
+		"message":      "demo mode: no changes were made",
+	})
+}
+
+var monitors = []map[string]any{
+	{
+		"monitor_id": "demo-monitor-checkout-errors",
+		"name":       "Checkout error rate",
+		"type":       "log_threshold",
+		"query":      `service.name:"checkout" AND severity_text:"ERROR"`,
+		"threshold":  map[string]any{"operator": ">", "value": 50},
+		"window":     "5m",
+		"state":      "alerting",
+		"muted":      false,
+	},
+	{
+		"monitor_id": "demo-monitor-payment-latency",
+		"name":       "Payment p95 latency",
+		"type":       "metric_threshold",
+		"query":      `service.name:"payment"`,
+		"threshold":  map[string]any{"operator": ">", "value": 800},
+		"window":     "10m",
+		"state":      "ok",
+		"muted":      false,
+	},
+}
+
+func handleMonitors(w http.ResponseWriter, r *http.Request) {
+	keyword := strings.ToLower(r.URL.Query().Get("keyword"))
+	out := make([]map[string]any, 0, len(monitors))
+	for _, m := range monitors {
+		if name, _ := m["name"].(string); strings.Contains(strings.ToLower(name), keyword) {
+			out = append(out, m)
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func handleMonitor(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["monitor_id"]
+	for _, m := range monitors {
+		if m["monitor_id"] == id {
+			writeJSON(w, http.StatusOK, m)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "monitor %s not found", id)
+}
+
+func handleMonitorChange(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["monitor_id"]
+	if id == "" {
+		id = "demo-monitor-new"
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"monitor_id": id,
+		"status":     "ok",
+		"message":    "demo mode: no changes were made",
+	})
+}
+
+var notificationIntegrations = []map[string]any{
+	{
+		"integration_id": "demo-slack-oncall",
+		"type":           "slack",
+		"target":         "#oncall",
+		"enabled":        true,
+		"last_delivery":  map[string]any{"status": "success", "timestamp": "2024-01-01T00:00:00Z"},
+	},
+	{
+		"integration_id": "demo-pagerduty-payments",
+		"type":           "pagerduty",
+		"target":         "Payments",
+		"enabled":        false,
+		"last_delivery":  map[string]any{"status": "failed", "error": "integration key revoked", "timestamp": "2024-01-01T00:00:00Z"},
+	},
+}
+
+var notificationRoutes = []map[string]any{
+	{
+		"rule_id":         "demo-route-checkout",
+		"monitor_ids":     []string{"demo-monitor-checkout-errors"},
+		"integration_ids": []string{"demo-slack-oncall"},
+	},
+	{
+		"rule_id":         "demo-route-payment",
+		"monitor_ids":     []string{"demo-monitor-payment-latency"},
+		"integration_ids": []string{"demo-pagerduty-payments"},
+	},
+}
+
+func handleNotificationIntegrations(w http.ResponseWriter, r *http.Request) {
+	integrationType := r.URL.Query().Get("type")
+	out := make([]map[string]any, 0, len(notificationIntegrations))
+	for _, i := range notificationIntegrations {
+		if integrationType == "" || i["type"] == integrationType {
+			out = append(out, i)
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func handleNotificationRoutes(w http.ResponseWriter, r *http.Request) {
+	monitorID := r.URL.Query().Get("monitor_id")
+	out := make([]map[string]any, 0, len(notificationRoutes))
+	for _, route := range notificationRoutes {
+		ids, _ := route["monitor_ids"].([]string)
+		if monitorID == "" || slices.Contains(ids, monitorID) {
+			out = append(out, route)
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+var archiveDestinations = []tools.ArchiveDestination{
+	{DestinationID: "demo-archive-s3", Name: "logs-archive", Type: "s3", Bucket: "demo-edgedelta-archive", Prefix: "edgedelta/", Region: "us-west-2"},
+}
+
+// archiveRetention is how far back the demo archive goes.
+const archiveRetention = 7 * 24 * time.Hour
+
+func handleArchives(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, tools.ArchiveDestinationsResponse{Destinations: archiveDestinations})
+}
+
+// handleArchiveObjects serves hourly objects of the last week. The 03:00 UTC hour of every day is
+// missing so that coverage gaps show. The cursor is the index of the next object.
+func handleArchiveObjects(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["destination_id"]
+	if !slices.ContainsFunc(archiveDestinations, func(d tools.ArchiveDestination) bool { return d.DestinationID == id }) {
+		writeError(w, http.StatusNotFound, "archive destination %s not found", id)
+		return
+	}
+
+	q := r.URL.Query()
+	from, to := timeWindow(q)
+	now := time.Now().UTC()
+	from = maxTime(from, now.Add(-archiveRetention)).Truncate(time.Hour)
+	// The current hour is not archived yet
+	to = minTime(to, now.Truncate(time.Hour))
+
+	var objects []tools.ArchiveObject
+	for start := from; start.Before(to); start = start.Add(time.Hour) {
+		if start.Hour() == 3 {
+			continue
+		}
+		object := tools.ArchiveObject{
+			Key:       "edgedelta/logs/" + start.Format("2006/01/02/15") + "/part-0000.json.gz",
+			SizeBytes: 40<<20 + int64(start.Hour())<<20,
+			Start:     start,
+			End:       start.Add(time.Hour),
+			Records:   120000 + int64(start.Hour())*3000,
+		}
+		if strings.HasPrefix(object.Key, q.Get("prefix")) {
+			objects = append(objects, object)
+		}
+	}
+
+	offset, _ := strconv.Atoi(q.Get("cursor"))
+	offset = min(max(offset, 0), len(objects))
+	end := min(offset+limitParam(r, 100), len(objects))
+	out := tools.ArchiveObjectsResponse{Objects: objects[offset:end]}
+	if end < len(objects) {
+		out.NextCursor = strconv.Itoa(end)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,282 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRedisReply(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    any
+		wantErr error
+	}{
+		{"simple string", "+OK\r\n", []byte("OK"), nil},
+		{"integer", ":42\r\n", int64(42), nil},
+		{"bulk string", "$5\r\nhello\r\n", []byte("hello"), nil},
+		{"bulk string with CRLF", "$4\r\na\r\nb\r\n", []byte("a\r\nb"), nil},
+		{"empty bulk string", "$0\r\n\r\n", []byte{}, nil},
+		{"nil bulk string", "$-1\r\n", nil, nil},
+		{"error", "-WRONGTYPE wrong kind of value\r\n", nil, redisError("WRONGTYPE wrong kind of value")},
+		{"array", "*2\r\n$1\r\na\r\n:1\r\n", []any{[]byte("a"), int64(1)}, nil},
+		{"nested array", "*2\r\n$1\r\n0\r\n*1\r\n$3\r\nkey\r\n", []any{[]byte("0"), []any{[]byte("key")}}, nil},
+		{"array with an error", "*2\r\n-ERR failed\r\n$1\r\nb\r\n", []any{redisError("ERR failed"), []byte("b")}, nil},
+		{"nil array", "*-1\r\n", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &redisConn{r: bufio.NewReader(strings.NewReader(tt.raw))}
+			got, err := c.reply()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error is %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("reply is %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+
+	for _, raw := range []string{"?what\r\n", "$abc\r\n", "*x\r\n", "$5\r\nhi\r\n", "\r\n"} {
+		c := &redisConn{r: bufio.NewReader(strings.NewReader(raw))}
+		if _, err := c.reply(); err == nil {
+			t.Errorf("reply %q is valid, want an error", raw)
+		}
+	}
+}
+
+// fakeRedis is a Redis server keeping string values in memory and recording the commands it got.
+type fakeRedis struct {
+	ln net.Listener
+	// closeAfterReply closes every connection after its first reply to a command of the storage
+	closeAfterReply bool
+
+	mu       sync.Mutex
+	values   map[string]string
+	commands [][]string
+	conns    int
+}
+
+func startFakeRedis(t *testing.T, closeAfterReply bool) *fakeRedis {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRedis{ln: ln, closeAfterReply: closeAfterReply, values: make(map[string]string)}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.conns++
+			f.mu.Unlock()
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+
+	// Commands are arrays of bulk strings, which the client's reply parser reads too
+	c := &redisConn{conn: conn, r: bufio.NewReader(conn)}
+	for {
+		command, err := c.reply()
+		if err != nil {
+			return
+		}
+		items, _ := command.([]any)
+		args := make([]string, len(items))
+		for i, item := range items {
+			b, _ := item.([]byte)
+			args[i] = string(b)
+		}
+		if _, err := conn.Write([]byte(f.handle(args))); err != nil {
+			return
+		}
+		if f.closeAfterReply && args[0] != "AUTH" && args[0] != "SELECT" {
+			return
+		}
+	}
+}
+
+func (f *fakeRedis) handle(args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.commands = append(f.commands, args)
+
+	switch strings.ToUpper(args[0]) {
+	case "AUTH", "SELECT":
+		return "+OK\r\n"
+	case "GET":
+		value, ok := f.values[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		if value == "wrongtype" {
+			return "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	case "SET":
+		f.values[args[1]] = args[2]
+		return "+OK\r\n"
+	case "DEL":
+		_, ok := f.values[args[1]]
+		delete(f.values, args[1])
+		if ok {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
+
+func (f *fakeRedis) lastCommand() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.commands[len(f.commands)-1]
+}
+
+func (f *fakeRedis) connCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.conns
+}
+
+func newTestRedis(t *testing.T, f *fakeRedis, path string) *Redis {
+	t.Helper()
+
+	r, err := NewRedis("redis://" + f.ln.Addr().String() + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+func TestRedisGetSetDelete(t *testing.T) {
+	f := startFakeRedis(t, false)
+	r := newTestRedis(t, f, "")
+	ctx := context.Background()
+
+	if _, err := r.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a missing key returned %v, want ErrNotFound", err)
+	}
+	if err := r.Set(ctx, "key", []byte("value\r\nwith CRLF"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := r.Get(ctx, "key"); err != nil || string(value) != "value\r\nwith CRLF" {
+		t.Errorf("Get returned %q, %v, want the value set", value, err)
+	}
+	if err := r.Delete(ctx, "key"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Get(ctx, "key"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a deleted key returned %v, want ErrNotFound", err)
+	}
+
+	// An error reply fails the command, not the connection
+	if err := r.Set(ctx, "other", []byte("wrongtype"), 0); err != nil {
+		t.Fatal(err)
+	}
+	var replyErr redisError
+	if _, err := r.Get(ctx, "other"); !errors.As(err, &replyErr) {
+		t.Errorf("Get returned %v, want the error reply", err)
+	}
+	if _, err := r.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after an error reply returned %v, want ErrNotFound", err)
+	}
+	if n := f.connCount(); n != 1 {
+		t.Errorf("client opened %d connections, want 1 reused for every command", n)
+	}
+}
+
+func TestRedisSetTTL(t *testing.T) {
+	f := startFakeRedis(t, false)
+	r := newTestRedis(t, f, "")
+
+	tests := []struct {
+		ttl  time.Duration
+		want []string
+	}{
+		{0, []string{"SET", "key", "value"}},
+		{1500 * time.Millisecond, []string{"SET", "key", "value", "PX", "1500"}},
+		{90 * 24 * time.Hour, []string{"SET", "key", "value", "PX", "7776000000"}},
+		// TTLs under a millisecond do not round to 0, which Redis rejects
+		{time.Microsecond, []string{"SET", "key", "value", "PX", "1"}},
+	}
+	for _, tt := range tests {
+		if err := r.Set(context.Background(), "key", []byte("value"), tt.ttl); err != nil {
+			t.Fatal(err)
+		}
+		if got := f.lastCommand(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Set with a TTL of %s sent %q, want %q", tt.ttl, got, tt.want)
+		}
+	}
+}
+
+func TestRedisReconnect(t *testing.T) {
+	f := startFakeRedis(t, true)
+	r := newTestRedis(t, f, "/2")
+	ctx := context.Background()
+
+	if err := r.Set(ctx, "key", []byte("value"), 0); err != nil {
+		t.Fatal(err)
+	}
+	// The idle connection was closed by the server, the command is retried on a new one
+	for range 2 {
+		if value, err := r.Get(ctx, "key"); err != nil || string(value) != "value" {
+			t.Fatalf("Get returned %q, %v, want the value set", value, err)
+		}
+	}
+	if n := f.connCount(); n != 3 {
+		t.Errorf("client opened %d connections, want one per command", n)
+	}
+	// New connections select the database again
+	f.mu.Lock()
+	selects := 0
+	for _, command := range f.commands {
+		if command[0] == "SELECT" && command[1] == "2" {
+			selects++
+		}
+	}
+	f.mu.Unlock()
+	if selects != 3 {
+		t.Errorf("client selected the database %d times, want once per connection", selects)
+	}
+}
+
+func TestRedisAuth(t *testing.T) {
+	f := startFakeRedis(t, false)
+	r, err := NewRedis("redis://user:secret@" + f.ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if _, err := r.Get(context.Background(), "key"); !errors.Is(err, ErrNotFound) {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	first := f.commands[0]
+	f.mu.Unlock()
+	if want := []string{"AUTH", "user", "secret"}; !reflect.DeepEqual(first, want) {
+		t.Errorf("first command is %q, want %q", first, want)
+	}
+}
@@ -0,0 +1,41 @@
+// Package storage is the key value storage of the server state shared between tool calls, e.g.
+// sessions, caches and query history. The memory storage keeps it in the server process, the
+// Redis storage shares it between the replicas of the server and keeps it across restarts.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned by Get for keys that do not exist or have expired.
+var ErrNotFound = errors.New("storage: key not found")
+
+// Interface stores values by key. Implementations are safe for concurrent use.
+type Interface interface {
+	// Get returns the value of the key, or ErrNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores the value of the key. Values with a ttl expire after it, the others are kept
+	// until they are deleted.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes the key, deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// List returns the keys starting with prefix, sorted.
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// Open returns the storage of a URL: "memory" (or empty) for the memory storage,
+// redis://[user:password@]host[:port][/db] or rediss:// (TLS) for Redis.
+func Open(rawURL string) (Interface, error) {
+	switch {
+	case rawURL == "" || rawURL == "memory":
+		return NewMemory(), nil
+	case strings.HasPrefix(rawURL, "redis://"), strings.HasPrefix(rawURL, "rediss://"):
+		return NewRedis(rawURL)
+	default:
+		return nil, fmt.Errorf(`unsupported storage %q, expected "memory" or a redis:// URL`, rawURL)
+	}
+}
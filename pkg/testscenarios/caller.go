@@ -0,0 +1,59 @@
+package testscenarios
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Scenarios are the builtin scenarios, run against the demo API.
+//
+//go:embed scenarios/*.yaml
+var Scenarios embed.FS
+
+// ServerCaller calls tools through an MCP server, so calls go through the middlewares of the
+// server as they do for clients.
+type ServerCaller struct {
+	Server *server.MCPServer
+	id     atomic.Int64
+}
+
+func (c *ServerCaller) CallTool(ctx context.Context, name string, args map[string]any) (*mcp.CallToolResult, error) {
+	request, err := json.Marshal(map[string]any{
+		"jsonrpc": mcp.JSONRPC_VERSION,
+		"id":      c.id.Add(1),
+		"method":  string(mcp.MethodToolsCall),
+		"params":  map[string]any{"name": name, "arguments": args},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	response, err := json.Marshal(c.Server.HandleMessage(ctx, request))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal response: %w", err)
+	}
+
+	var decoded struct {
+		Result *json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(response, &decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if decoded.Error != nil {
+		return nil, fmt.Errorf("JSON-RPC error %d: %s", decoded.Error.Code, decoded.Error.Message)
+	}
+	if decoded.Result == nil {
+		return nil, fmt.Errorf("response has no result")
+	}
+	return mcp.ParseCallToolResult(decoded.Result)
+}
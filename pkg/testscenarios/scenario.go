@@ -0,0 +1,437 @@
+// Package testscenarios runs declarative scenarios of agent workflows against the tools: scripted
+// sequences of tool calls, each using values from the results of the previous ones, with
+// assertions on the results. They guard the ergonomics agents rely on across tools (the fields
+// one tool returns are the ones the next needs, guidance points to the right next step) rather
+// than individual handlers.
+//
+// A scenario is a YAML file:
+//
+//	name: investigate checkout errors
+//	steps:
+//	  - tool: discover_schema
+//	    args: {scope: log}
+//	    save: {service: 'common_fields["service.name"][1]'}
+//	  - tool: build_cql
+//	    args: {scope: log, filters: {service.name: "{{service}}"}}
+//	    expect:
+//	      - {path: valid, equals: true}
+//	    save: {query: query}
+//
+// Paths select a value of the first JSON object content of the result, e.g. data.items[0].body or
+// resource["service.name"]. "{{name}}" in string arguments and expected values is replaced with
+// the value saved under name; an argument that is only a reference keeps the type of the value.
+package testscenarios
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario is a scripted workflow of tool calls.
+type Scenario struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Steps       []Step `yaml:"steps"`
+}
+
+// Step is a tool call of a scenario with the assertions on its result.
+type Step struct {
+	Name string         `yaml:"name"`
+	Tool string         `yaml:"tool"`
+	Args map[string]any `yaml:"args"`
+	// IsError is whether the call is expected to fail
+	IsError bool     `yaml:"is_error"`
+	Expect  []Expect `yaml:"expect"`
+	// Save maps variable names to the paths of the values to save for the next steps
+	Save map[string]string `yaml:"save"`
+}
+
+// Expect is an assertion on the value at a path of a result. All the conditions set must hold.
+type Expect struct {
+	Path string `yaml:"path"`
+	// Equals compares the value with a YAML value, numbers compare by value
+	Equals any `yaml:"equals"`
+	// Contains is a substring of a string value, or an element of an array value
+	Contains any `yaml:"contains"`
+	// Matches is a regular expression a string value matches
+	Matches  string `yaml:"matches"`
+	NotEmpty bool   `yaml:"not_empty"`
+	// MinLen is the minimum length of an array, object or string value
+	MinLen *int `yaml:"min_len"`
+}
+
+// Caller calls tools, e.g. through an MCP server.
+type Caller interface {
+	CallTool(ctx context.Context, name string, args map[string]any) (*mcp.CallToolResult, error)
+}
+
+// StepResult is the outcome of a step.
+type StepResult struct {
+	Name     string
+	Tool     string
+	Duration time.Duration
+	Failures []string
+}
+
+// Result is the outcome of a scenario.
+type Result struct {
+	Name  string
+	Steps []StepResult
+}
+
+// Passed reports whether every step of the scenario passed.
+func (r Result) Passed() bool {
+	for _, step := range r.Steps {
+		if len(step.Failures) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Parse parses a YAML scenario.
+func Parse(data []byte) (*Scenario, error) {
+	var scenario Scenario
+	if err := yaml.Unmarshal(data, &scenario); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario: %w", err)
+	}
+	if scenario.Name == "" {
+		return nil, fmt.Errorf("scenario has no name")
+	}
+	if len(scenario.Steps) == 0 {
+		return nil, fmt.Errorf("scenario %q has no steps", scenario.Name)
+	}
+	for i, step := range scenario.Steps {
+		if step.Tool == "" {
+			return nil, fmt.Errorf("step %d of scenario %q has no tool", i+1, scenario.Name)
+		}
+	}
+	return &scenario, nil
+}
+
+// Load parses the scenarios, *.yaml files, of the directory of fsys.
+func Load(fsys fs.FS, dir string) ([]*Scenario, error) {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	scenarios := make([]*Scenario, 0, len(files))
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		scenario, err := Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		scenarios = append(scenarios, scenario)
+	}
+	return scenarios, nil
+}
+
+// Run runs the steps of the scenario in order. It stops at the first step that fails, since the
+// next steps depend on its result.
+func Run(ctx context.Context, caller Caller, scenario *Scenario) Result {
+	result := Result{Name: scenario.Name}
+	vars := make(map[string]any)
+
+	for i, step := range scenario.Steps {
+		name := step.Name
+		if name == "" {
+			name = fmt.Sprintf("%d. %s", i+1, step.Tool)
+		}
+		stepResult := StepResult{Name: name, Tool: step.Tool}
+
+		start := time.Now()
+		stepResult.Failures = runStep(ctx, caller, step, vars)
+		stepResult.Duration = time.Since(start)
+
+		result.Steps = append(result.Steps, stepResult)
+		if len(stepResult.Failures) > 0 {
+			break
+		}
+	}
+	return result
+}
+
+func runStep(ctx context.Context, caller Caller, step Step, vars map[string]any) []string {
+	args, err := substitute(step.Args, vars)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	argsMap, _ := args.(map[string]any)
+
+	toolResult, err := caller.CallTool(ctx, step.Tool, argsMap)
+	if err != nil {
+		return []string{fmt.Sprintf("call failed: %v", err)}
+	}
+	if toolResult.IsError != step.IsError {
+		return []string{fmt.Sprintf("is_error is %t, expected %t: %s", toolResult.IsError, step.IsError, firstText(toolResult))}
+	}
+
+	doc := resultDocument(toolResult)
+	var failures []string
+	for _, expect := range step.Expect {
+		if failure := check(doc, expect, vars); failure != "" {
+			failures = append(failures, failure)
+		}
+	}
+
+	for name, valuePath := range step.Save {
+		value, err := lookup(doc, valuePath)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("save %s: %v", name, err))
+			continue
+		}
+		vars[name] = value
+	}
+	return failures
+}
+
+// resultDocument returns the first content of the result that is a JSON object.
+func resultDocument(result *mcp.CallToolResult) any {
+	for _, content := range result.Content {
+		text, ok := content.(mcp.TextContent)
+		if !ok {
+			continue
+		}
+		var doc map[string]any
+		if json.Unmarshal([]byte(text.Text), &doc) == nil {
+			return doc
+		}
+	}
+	return nil
+}
+
+func firstText(result *mcp.CallToolResult) string {
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			return text.Text
+		}
+	}
+	return ""
+}
+
+func check(doc any, expect Expect, vars map[string]any) string {
+	value, err := lookup(doc, expect.Path)
+	if err != nil {
+		return fmt.Sprintf("%s: %v", expect.Path, err)
+	}
+
+	if expect.Equals != nil {
+		want, err := substitute(expect.Equals, vars)
+		if err != nil {
+			return fmt.Sprintf("%s: %v", expect.Path, err)
+		}
+		if !equal(value, want) {
+			return fmt.Sprintf("%s is %s, expected %s", expect.Path, show(value), show(want))
+		}
+	}
+
+	if expect.Contains != nil {
+		want, err := substitute(expect.Contains, vars)
+		if err != nil {
+			return fmt.Sprintf("%s: %v", expect.Path, err)
+		}
+		if !contains(value, want) {
+			return fmt.Sprintf("%s is %s, expected it to contain %s", expect.Path, show(value), show(want))
+		}
+	}
+
+	if expect.Matches != "" {
+		re, err := regexp.Compile(expect.Matches)
+		if err != nil {
+			return fmt.Sprintf("%s: invalid matches: %v", expect.Path, err)
+		}
+		if s, ok := value.(string); !ok || !re.MatchString(s) {
+			return fmt.Sprintf("%s is %s, expected it to match %s", expect.Path, show(value), expect.Matches)
+		}
+	}
+
+	length := valueLen(value)
+	if expect.NotEmpty && length == 0 {
+		return fmt.Sprintf("%s is empty", expect.Path)
+	}
+	if expect.MinLen != nil && length < *expect.MinLen {
+		return fmt.Sprintf("%s has length %d, expected at least %d", expect.Path, length, *expect.MinLen)
+	}
+	return ""
+}
+
+func valueLen(value any) int {
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case string:
+		return len(v)
+	case []any:
+		return len(v)
+	case map[string]any:
+		return len(v)
+	default:
+		return 1
+	}
+}
+
+func equal(got, want any) bool {
+	if g, ok := toFloat(got); ok {
+		if w, ok := toFloat(want); ok {
+			return g == w
+		}
+	}
+	return reflect.DeepEqual(normalize(got), normalize(want))
+}
+
+func contains(value, want any) bool {
+	switch v := value.(type) {
+	case string:
+		s, ok := want.(string)
+		return ok && strings.Contains(v, s)
+	case []any:
+		for _, item := range v {
+			if equal(item, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// normalize converts a YAML value to the types of the same value decoded from JSON.
+func normalize(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out any
+	if json.Unmarshal(data, &out) != nil {
+		return v
+	}
+	return out
+}
+
+func show(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	if len(data) > 200 {
+		return string(data[:200]) + "..."
+	}
+	return string(data)
+}
+
+var varPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+
+// substitute replaces the "{{name}}" references of the strings of v with the saved values.
+func substitute(v any, vars map[string]any) (any, error) {
+	switch value := v.(type) {
+	case string:
+		if m := varPattern.FindStringSubmatch(value); m != nil && m[0] == value {
+			saved, ok := vars[m[1]]
+			if !ok {
+				return nil, fmt.Errorf("variable %q is not saved by a previous step", m[1])
+			}
+			return saved, nil
+		}
+		var err error
+		out := varPattern.ReplaceAllStringFunc(value, func(ref string) string {
+			name := varPattern.FindStringSubmatch(ref)[1]
+			saved, ok := vars[name]
+			if !ok {
+				err = fmt.Errorf("variable %q is not saved by a previous step", name)
+				return ref
+			}
+			if s, ok := saved.(string); ok {
+				return s
+			}
+			return show(saved)
+		})
+		return out, err
+	case map[string]any:
+		out := make(map[string]any, len(value))
+		for key, item := range value {
+			substituted, err := substitute(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = substituted
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(value))
+		for i, item := range value {
+			substituted, err := substitute(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = substituted
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+// pathSegmentPattern matches the segments of a path: a key, ["quoted key"] or [index].
+var pathSegmentPattern = regexp.MustCompile(`^(?:\.?([A-Za-z0-9_@\-]+)|\["([^"]*)"\]|\[(\d+)\])`)
+
+// lookup returns the value at the path of doc.
+func lookup(doc any, p string) (any, error) {
+	value := doc
+	for rest := p; rest != ""; {
+		m := pathSegmentPattern.FindStringSubmatch(rest)
+		if m == nil {
+			return nil, fmt.Errorf("invalid path at %q", rest)
+		}
+		rest = rest[len(m[0]):]
+
+		if m[3] != "" {
+			index, _ := strconv.Atoi(m[3])
+			items, ok := value.([]any)
+			if !ok {
+				return nil, fmt.Errorf("not an array before [%d]", index)
+			}
+			if index >= len(items) {
+				return nil, fmt.Errorf("index %d out of range, array has %d items", index, len(items))
+			}
+			value = items[index]
+			continue
+		}
+
+		key := m[1] + m[2]
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("not an object before %q", key)
+		}
+		if value, ok = object[key]; !ok {
+			return nil, fmt.Errorf("no key %q", key)
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,200 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// OrgMember is a user of an organization.
+type OrgMember struct {
+	UserID      string     `json:"user_id"`
+	Email       string     `json:"email"`
+	Name        string     `json:"name,omitempty"`
+	Role        string     `json:"role"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// OrgMembersResponse mirrors the backend response from GET /v1/orgs/{org_id}/members
+type OrgMembersResponse struct {
+	Members []OrgMember `json:"members"`
+}
+
+// APIKey is the metadata of an API key. The key itself is never part of it: decoding the
+// backend response into it drops any other field.
+type APIKey struct {
+	KeyID       string     `json:"key_id"`
+	Name        string     `json:"name"`
+	Permissions []string   `json:"permissions,omitempty"`
+	CreatedBy   string     `json:"created_by,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// APIKeysResponse mirrors the backend response from GET /v1/orgs/{org_id}/api_keys
+type APIKeysResponse struct {
+	APIKeys []APIKey `json:"api_keys"`
+}
+
+// getOrgResource gets the path under the org and decodes the response into out.
+func getOrgResource(ctx context.Context, client Client, path, action string, out any, opts ...QueryParamOption) error {
+	keys, err := FetchContextKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	resourceURL, err := url.Parse(fmt.Sprintf("%s/v1/orgs/%s/%s", client.APIURL(), keys.OrgID, path))
+	if err != nil {
+		return err
+	}
+
+	req, err := createRequest(ctx, resourceURL, keys, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %v", path, err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to %s, status code %d: %s", action, resp.StatusCode, string(bodyBytes))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %v", path, err)
+	}
+	return nil
+}
+
+func ListOrgMembers(ctx context.Context, client Client) ([]OrgMember, error) {
+	var out OrgMembersResponse
+	if err := getOrgResource(ctx, client, "members", "list org members", &out); err != nil {
+		return nil, err
+	}
+	return out.Members, nil
+}
+
+func ListAPIKeys(ctx context.Context, client Client) ([]APIKey, error) {
+	var out APIKeysResponse
+	if err := getOrgResource(ctx, client, "api_keys", "list API keys", &out); err != nil {
+		return nil, err
+	}
+	return out.APIKeys, nil
+}
+
+// ListOrgMembersTool creates a tool to list the members of the organization
+func ListOrgMembersTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("list_org_members",
+			mcp.WithTitleAnnotation("List Org Members"),
+			mcp.WithDescription(`Lists the users of your organization with their role and last login, e.g. to answer "who has admin access?".`),
+			mcp.WithString("role",
+				mcp.Description("Only return members with this role (e.g., admin, editor, read_only)"),
+				mcp.DefaultString(""),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			members, err := ListOrgMembers(ctx, client)
+			if err != nil {
+				return nil, err
+			}
+
+			if role, _ := params.Optional[string](request, "role"); role != "" {
+				filtered := members[:0]
+				for _, m := range members {
+					if strings.EqualFold(m.Role, role) {
+						filtered = append(filtered, m)
+					}
+				}
+				members = filtered
+			}
+
+			response := map[string]any{"members": members, "count": len(members)}
+			if len(members) == 0 {
+				response["guidance"] = DiscoveryGuidance{
+					ResultStatus: "empty",
+					NextSteps:    []string{"No members match; call list_org_members without role to see the roles in use."},
+				}
+			}
+
+			r, err := json.Marshal(response)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
+
+// ListAPIKeysTool creates a tool to list the API keys of the organization
+func ListAPIKeysTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("list_api_keys",
+			mcp.WithTitleAnnotation("List API Keys"),
+			mcp.WithDescription(`Lists the API keys of your organization: name, permissions, creator, creation, last use and expiry. Only metadata is returned, never the keys themselves.
+
+Use unused_for to find stale keys, e.g. "which keys are unused for 90 days?" → unused_for=90d. Keys that were never used count as unused.`),
+			mcp.WithString("unused_for",
+				mcp.Description("Only return keys not used within this duration (e.g., 30d, 90d)"),
+				mcp.DefaultString(""),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			var cutoff time.Time
+			if unusedFor, _ := params.Optional[string](request, "unused_for"); unusedFor != "" {
+				d, err := ParseLookback(unusedFor)
+				if err != nil || d <= 0 {
+					return mcp.NewToolResultError(fmt.Sprintf("invalid unused_for %q, expected a duration such as 30d or 90d", unusedFor)), nil
+				}
+				cutoff = time.Now().Add(-d)
+			}
+
+			apiKeys, err := ListAPIKeys(ctx, client)
+			if err != nil {
+				return nil, err
+			}
+
+			if !cutoff.IsZero() {
+				filtered := apiKeys[:0]
+				for _, k := range apiKeys {
+					if k.LastUsedAt == nil || k.LastUsedAt.Before(cutoff) {
+						filtered = append(filtered, k)
+					}
+				}
+				apiKeys = filtered
+			}
+
+			response := map[string]any{"api_keys": apiKeys, "count": len(apiKeys)}
+			if len(apiKeys) == 0 {
+				response["guidance"] = DiscoveryGuidance{
+					ResultStatus: "empty",
+					NextSteps:    []string{"No API keys match."},
+				}
+			}
+
+			r, err := json.Marshal(response)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
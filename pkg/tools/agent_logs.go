@@ -0,0 +1,140 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// agentSelfLogsFilter matches the logs the Edge Delta agent emits about itself through the
+// ed_self_telemetry_input node, as opposed to the logs it collects from its sources.
+const agentSelfLogsFilter = `ed.source.type:"ed_self_telemetry_input"`
+
+// GetAgentSelfLogsTool creates a tool to fetch the logs emitted by the Edge Delta agent itself
+func GetAgentSelfLogsTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("get_agent_self_logs",
+			mcp.WithTitleAnnotation("Get Agent Self Logs"),
+			mcp.WithDescription(`Fetch logs emitted by the Edge Delta agent itself (self telemetry), not the logs it collects.
+
+WHEN TO USE:
+- An agent/pipeline is not shipping data and you need to know why
+- Checking for agent errors such as failed outputs, auth errors or dropped data
+
+The self telemetry filter is applied automatically; use the pipeline parameter (tag from get_pipelines tool)
+to narrow down to a single pipeline and severity to focus on errors.`),
+			mcp.WithString("pipeline",
+				mcp.Description("Pipeline tag (the 'tag' field from get_pipelines tool). Leave empty for all pipelines."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("severity",
+				mcp.Description(`Severity to filter on, e.g. "ERROR" or "WARN". Leave empty for all severities.`),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("query",
+				mcp.Description(`Additional CQL filter ANDed with the self telemetry filter. Examples:
+- host.name:"node-1"
+- timeout OR refused (full-text search)`),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("lookback",
+				mcp.Description("Lookback period in GOLANG duration format, or days and weeks. e.g. (1h, 15m, 24h, 7d, 1w). Either provide from/to or just lookback. Pass empty string to use from/to instead."),
+				mcp.DefaultString("1h"),
+			),
+			mcp.WithString("from",
+				mcp.Description("From datetime in ISO format 2006-01-02T15:04:05.000Z."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("to",
+				mcp.Description("To datetime in ISO format 2006-01-02T15:04:05.000Z."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description("Limits the number of logs in the response. Default is 50, max is 1000."),
+				mcp.DefaultNumber(50),
+			),
+			mcp.WithString("cursor",
+				mcp.Description("Cursor provided from previous response, pass it to next request to move the cursor with given limit."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithBoolean("normalize_severity",
+				mcp.Description(`Normalize severities to the OpenTelemetry set (TRACE, DEBUG, INFO, WARN, ERROR, FATAL). Severity filters such as severity_text:"WARN" also match synonyms ("warning", "40", ...) and returned items get a normalized severity_text, with the original in severity_text_original.`),
+				mcp.DefaultBool(false),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			pipeline, _ := params.Optional[string](request, "pipeline")
+			severity, _ := params.Optional[string](request, "severity")
+			extra, _ := params.Optional[string](request, "query")
+
+			query := buildAgentSelfLogsQuery(pipeline, severity, extra)
+
+			normalizeSeverity, _ := params.Optional[bool](request, "normalize_severity")
+			if normalizeSeverity {
+				query = ExpandSeverityQuery(query)
+			}
+
+			queryParams := url.Values{}
+			queryParams.Add("query", query)
+
+			if lookback, _ := params.Optional[string](request, "lookback"); lookback != "" {
+				queryParams.Add("lookback", lookback)
+			}
+
+			if from, _ := params.Optional[string](request, "from"); from != "" {
+				queryParams.Add("from", from)
+			}
+
+			if to, _ := params.Optional[string](request, "to"); to != "" {
+				queryParams.Add("to", to)
+			}
+
+			if limit, _ := params.Optional[float64](request, "limit"); limit > 0 {
+				queryParams.Add("limit", fmt.Sprintf("%.0f", limit))
+			} else {
+				queryParams.Add("limit", "50")
+			}
+
+			if cursor, _ := params.Optional[string](request, "cursor"); cursor != "" {
+				queryParams.Add("cursor", cursor)
+			}
+
+			queryParams.Add("order", "desc")
+
+			bodyBytes, err := searchLogs(ctx, client, queryParams)
+			if err != nil {
+				return nil, err
+			}
+
+			if normalizeSeverity {
+				bodyBytes = normalizeSeverityItems(bodyBytes)
+			}
+
+			return formatSearchResponse(bodyBytes, query, uiLink(ctx, client, UILogsPage, query, queryParams))
+		}
+}
+
+func buildAgentSelfLogsQuery(pipeline, severity, extra string) string {
+	parts := []string{agentSelfLogsFilter}
+	if pipeline != "" {
+		parts = append(parts, fmt.Sprintf(`ed.tag:"%s"`, escapeValue(pipeline)))
+	}
+
+	if severity != "" {
+		parts = append(parts, fmt.Sprintf(`severity_text:"%s"`, escapeValue(strings.ToUpper(severity))))
+	}
+
+	if extra = strings.TrimSpace(extra); extra != "" && extra != "*" {
+		parts = append(parts, "("+extra+")")
+	}
+
+	return strings.Join(parts, " AND ")
+}
@@ -0,0 +1,239 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// agentStaleAfter is how long after its last heartbeat an agent is considered not running.
+// Agents send a heartbeat every minute.
+const agentStaleAfter = 5 * time.Minute
+
+const (
+	AgentRunning      = "running"
+	AgentNotReporting = "not_reporting"
+)
+
+// Agent is the status an agent of a pipeline reports through its heartbeats.
+type Agent struct {
+	AgentID  string    `json:"agent_id"`
+	Host     string    `json:"host"`
+	Version  string    `json:"version"`
+	Health   string    `json:"health"`
+	LastSeen time.Time `json:"last_seen"`
+	Message  string    `json:"message,omitempty"`
+}
+
+// AgentsResponse mirrors the backend response from GET /v1/orgs/{org_id}/pipelines/{conf_id}/agents
+type AgentsResponse struct {
+	Agents []Agent `json:"agents"`
+}
+
+// AgentStatus is an agent with whether it is running, derived from its last heartbeat.
+type AgentStatus struct {
+	Agent
+	State         string `json:"state"`
+	SinceLastSeen string `json:"since_last_seen"`
+}
+
+// FleetSummary counts the agents of a pipeline by state, health and version.
+type FleetSummary struct {
+	Total     int            `json:"total"`
+	ByState   map[string]int `json:"by_state"`
+	ByHealth  map[string]int `json:"by_health"`
+	ByVersion map[string]int `json:"by_version"`
+}
+
+func ListPipelineAgents(ctx context.Context, client Client, confID string, opts ...QueryParamOption) ([]Agent, error) {
+	var out AgentsResponse
+	path := fmt.Sprintf("pipelines/%s/agents", url.PathEscape(confID))
+	if err := getOrgResource(ctx, client, path, "list pipeline agents", &out, opts...); err != nil {
+		return nil, err
+	}
+	return out.Agents, nil
+}
+
+func agentStatus(agent Agent, now time.Time) AgentStatus {
+	status := AgentStatus{Agent: agent, State: AgentRunning}
+	if agent.LastSeen.IsZero() {
+		status.State = AgentNotReporting
+		status.SinceLastSeen = "never"
+		return status
+	}
+
+	since := now.Sub(agent.LastSeen)
+	if since > agentStaleAfter {
+		status.State = AgentNotReporting
+	}
+	status.SinceLastSeen = since.Round(time.Second).String()
+	return status
+}
+
+// GetFleetAgentsTool creates a tool to list the agents running a pipeline
+func GetFleetAgentsTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("get_fleet_agents",
+			mcp.WithTitleAnnotation("Get Fleet Agents"),
+			mcp.WithDescription(`List the agents (collectors) running a pipeline with their host, agent version, health and last heartbeat, and a summary of the fleet by state, health and version.
+
+An agent whose last heartbeat is older than 5 minutes is reported with state "not_reporting": it is stopped, crashed or cannot reach Edge Delta.
+
+PREREQUISITE: Call get_pipelines tool first to obtain the conf_id.`),
+			mcp.WithString("conf_id",
+				mcp.Description("Pipeline configuration ID. Get this from get_pipelines response."),
+				mcp.Required(),
+			),
+			mcp.WithString("state",
+				mcp.Description("Only return agents in this state"),
+				mcp.Enum(AgentRunning, AgentNotReporting),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			confID, err := request.RequireString("conf_id")
+			if err != nil {
+				return mcp.NewToolResultError("missing required parameter: conf_id"), err
+			}
+			state, _ := params.Optional[string](request, "state")
+
+			agents, err := ListPipelineAgents(ctx, client, confID)
+			if err != nil {
+				return nil, err
+			}
+
+			now := time.Now()
+			summary := FleetSummary{
+				Total:     len(agents),
+				ByState:   make(map[string]int),
+				ByHealth:  make(map[string]int),
+				ByVersion: make(map[string]int),
+			}
+			statuses := make([]AgentStatus, 0, len(agents))
+			for _, agent := range agents {
+				status := agentStatus(agent, now)
+				summary.ByState[status.State]++
+				summary.ByHealth[agent.Health]++
+				summary.ByVersion[agent.Version]++
+				if state == "" || status.State == state {
+					statuses = append(statuses, status)
+				}
+			}
+			// Agents not reporting first, then by host
+			sort.SliceStable(statuses, func(i, j int) bool {
+				if statuses[i].State != statuses[j].State {
+					return statuses[i].State == AgentNotReporting
+				}
+				return statuses[i].Host < statuses[j].Host
+			})
+
+			guidance := DiscoveryGuidance{ResultStatus: "success"}
+			switch {
+			case len(agents) == 0:
+				guidance.ResultStatus = "empty"
+				guidance.NextSteps = []string{"No agent has ever reported for this pipeline; check that the agents are installed with the pipeline's API key."}
+			case summary.ByState[AgentNotReporting] > 0:
+				guidance.NextSteps = []string{"Use get_agent_self_logs tool with the pipeline tag and host.name of the agents not reporting to look for errors before they stopped."}
+			}
+			if len(summary.ByVersion) > 1 {
+				guidance.Suggestions = []string{"The fleet runs several agent versions; differences in behavior between hosts may come from them."}
+			}
+
+			r, err := json.Marshal(map[string]any{
+				"conf_id":  confID,
+				"summary":  summary,
+				"agents":   statuses,
+				"guidance": guidance,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
+
+// GetAgentStatusTool creates a tool to check whether the agent of a host is running
+func GetAgentStatusTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("get_agent_status",
+			mcp.WithTitleAnnotation("Get Agent Status"),
+			mcp.WithDescription(`Check whether the agent (collector) of a pipeline on a host is running: its version, health, last heartbeat and state ("running", or "not_reporting" if its last heartbeat is older than 5 minutes).
+
+Answers questions such as "is the collector on host X even running?". If no agent of the pipeline ever reported from the host, the state is "not_found".
+
+PREREQUISITE: Call get_pipelines tool first to obtain the conf_id.`),
+			mcp.WithString("conf_id",
+				mcp.Description("Pipeline configuration ID. Get this from get_pipelines response."),
+				mcp.Required(),
+			),
+			mcp.WithString("host",
+				mcp.Description("Host name of the agent, as in host.name of its logs"),
+				mcp.Required(),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			confID, err := request.RequireString("conf_id")
+			if err != nil {
+				return mcp.NewToolResultError("missing required parameter: conf_id"), err
+			}
+			host, err := request.RequireString("host")
+			if err != nil {
+				return mcp.NewToolResultError("missing required parameter: host"), err
+			}
+
+			agents, err := ListPipelineAgents(ctx, client, confID, func(v url.Values) {
+				v.Set("host", host)
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			now := time.Now()
+			statuses := make([]AgentStatus, 0, 1)
+			for _, agent := range agents {
+				if strings.EqualFold(agent.Host, host) {
+					statuses = append(statuses, agentStatus(agent, now))
+				}
+			}
+
+			response := map[string]any{"conf_id": confID, "host": host}
+			guidance := DiscoveryGuidance{ResultStatus: "success"}
+			switch {
+			case len(statuses) == 0:
+				response["state"] = "not_found"
+				guidance.ResultStatus = "empty"
+				guidance.NextSteps = []string{
+					"No agent of this pipeline ever reported from this host; it is not installed, uses another pipeline's API key or cannot reach Edge Delta.",
+					"Use get_fleet_agents tool to see the hosts the pipeline's agents report from.",
+				}
+			default:
+				// A host may run several agents, e.g. after a reinstall; the most recent one tells its state
+				sort.Slice(statuses, func(i, j int) bool { return statuses[i].LastSeen.After(statuses[j].LastSeen) })
+				response["state"] = statuses[0].State
+				response["agents"] = statuses
+				if statuses[0].State == AgentNotReporting {
+					guidance.NextSteps = []string{fmt.Sprintf("The agent last reported %s ago. Use get_agent_self_logs tool with query host.name:%q to look for errors before it stopped.", statuses[0].SinceLastSeen, host)}
+				}
+			}
+			response["guidance"] = guidance
+
+			r, err := json.Marshal(response)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
@@ -0,0 +1,364 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ServiceAliases maps the names teams use for their services, e.g. "checkout", to the
+// service.name values the services report, e.g. "svc-chk-prod-eu". Aliases are case-insensitive.
+type ServiceAliases map[string][]string
+
+// LoadServiceAliases reads service aliases from a JSON file of the form
+// {"checkout": ["svc-chk-prod-eu", "svc-chk-prod-us"]}.
+func LoadServiceAliases(path string) (ServiceAliases, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service aliases: %w", err)
+	}
+
+	var aliases ServiceAliases
+	if err := json.Unmarshal(data, &aliases); err != nil {
+		return nil, fmt.Errorf("failed to parse service aliases %s: %w", path, err)
+	}
+	return aliases.normalized(), nil
+}
+
+func (a ServiceAliases) normalized() ServiceAliases {
+	out := make(ServiceAliases, len(a))
+	for alias, services := range a {
+		alias = strings.ToLower(strings.TrimSpace(alias))
+		if alias == "" {
+			continue
+		}
+		for _, s := range services {
+			if s = strings.TrimSpace(s); s != "" {
+				out[alias] = append(out[alias], s)
+			}
+		}
+	}
+	return out
+}
+
+// Resolve returns the service names of an alias, or false if name is not an alias.
+func (a ServiceAliases) Resolve(name string) ([]string, bool) {
+	services, ok := a[strings.ToLower(strings.TrimSpace(name))]
+	return services, ok && len(services) > 0
+}
+
+// ServiceAliasesResponse mirrors the backend response from GET /v1/orgs/{org_id}/settings/service_aliases
+type ServiceAliasesResponse struct {
+	Aliases map[string][]string `json:"aliases"`
+}
+
+func GetOrgServiceAliases(ctx context.Context, client Client) (ServiceAliases, error) {
+	keys, err := FetchContextKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	aliasesURL, err := url.Parse(fmt.Sprintf("%s/v1/orgs/%s/settings/service_aliases", client.APIURL(), keys.OrgID))
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := createRequest(ctx, aliasesURL, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create service aliases request: %v", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to get service aliases, status code %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var out ServiceAliasesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode service aliases response: %v", err)
+	}
+	return ServiceAliases(out.Aliases).normalized(), nil
+}
+
+type orgServiceAliases struct {
+	aliases   ServiceAliases
+	fetchedAt time.Time
+}
+
+// ServiceAliasResolver resolves service aliases in tool arguments. Aliases come from a config
+// file and from the org settings, which are fetched per org, cached for the TTL and take
+// precedence over the file. If the org settings cannot be fetched only the file aliases apply.
+type ServiceAliasResolver struct {
+	client Client
+	static ServiceAliases
+	ttl    time.Duration
+
+	mu   sync.Mutex
+	orgs map[string]orgServiceAliases
+}
+
+func NewServiceAliasResolver(client Client, static ServiceAliases, ttl time.Duration) *ServiceAliasResolver {
+	return &ServiceAliasResolver{
+		client: client,
+		static: static.normalized(),
+		ttl:    ttl,
+		orgs:   make(map[string]orgServiceAliases),
+	}
+}
+
+// Aliases returns the service aliases of the caller's org.
+func (r *ServiceAliasResolver) Aliases(ctx context.Context) ServiceAliases {
+	aliases := make(ServiceAliases, len(r.static))
+	for alias, services := range r.static {
+		aliases[alias] = services
+	}
+
+	keys, err := FetchContextKeys(ctx)
+	if err != nil {
+		return aliases
+	}
+
+	r.mu.Lock()
+	cached, ok := r.orgs[keys.OrgID]
+	r.mu.Unlock()
+	if !ok || time.Since(cached.fetchedAt) >= r.ttl {
+		// A failed fetch is cached too, so an older backend without the endpoint is not asked on every call.
+		cached.aliases, _ = GetOrgServiceAliases(ctx, r.client)
+		cached.fetchedAt = time.Now()
+
+		r.mu.Lock()
+		r.orgs[keys.OrgID] = cached
+		r.mu.Unlock()
+	}
+
+	for alias, services := range cached.aliases {
+		aliases[alias] = services
+	}
+	return aliases
+}
+
+var (
+	serviceFilterPattern     = regexp.MustCompile(`\bservice\.name\s*:\s*"((?:[^"\\]|\\.)*)"`)
+	serviceFilterListPattern = regexp.MustCompile(`\bservice\.name\s*:\s*\(([^)]*)\)`)
+)
+
+// resolveQueryAliases replaces aliases in the service.name filters of a CQL query with the
+// service names they stand for. It returns the rewritten query and the aliases it resolved.
+func resolveQueryAliases(query string, aliases ServiceAliases) (string, map[string][]string) {
+	resolved := make(map[string][]string)
+
+	// Values are kept in their escaped query form.
+	expand := func(value string) []string {
+		services, ok := aliases.Resolve(value)
+		if !ok {
+			return []string{value}
+		}
+		resolved[value] = services
+		escaped := make([]string, len(services))
+		for i, service := range services {
+			escaped[i] = escapeValue(service)
+		}
+		return escaped
+	}
+
+	query = serviceFilterListPattern.ReplaceAllStringFunc(query, func(filter string) string {
+		list := serviceFilterListPattern.FindStringSubmatch(filter)[1]
+		var values []string
+		for _, match := range quotedValuePattern.FindAllStringSubmatch(list, -1) {
+			values = append(values, expand(match[1])...)
+		}
+		if len(values) == 0 {
+			return filter
+		}
+		return "service.name:" + cqlValues(values)
+	})
+
+	query = serviceFilterPattern.ReplaceAllStringFunc(query, func(filter string) string {
+		value := serviceFilterPattern.FindStringSubmatch(filter)[1]
+		services := expand(value)
+		if len(services) == 1 && services[0] == value {
+			return filter
+		}
+		return "service.name:" + cqlValues(services)
+	})
+
+	return query, resolved
+}
+
+// cqlValues formats escaped values as a CQL value or OR list.
+func cqlValues(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = fmt.Sprintf(`"%s"`, v)
+	}
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return "(" + strings.Join(quoted, " OR ") + ")"
+}
+
+// resolveArgumentAliases returns a copy of tool arguments with aliases resolved in the query,
+// in the service.name filter of build_cql and in a service argument that resolves to a single
+// service.
+func resolveArgumentAliases(args map[string]any, aliases ServiceAliases) (map[string]any, map[string][]string) {
+	resolved := make(map[string][]string)
+	out := make(map[string]any, len(args))
+	for k, v := range args {
+		out[k] = v
+	}
+
+	if query, ok := args["query"].(string); ok && query != "" {
+		var queryResolved map[string][]string
+		out["query"], queryResolved = resolveQueryAliases(query, aliases)
+		for alias, services := range queryResolved {
+			resolved[alias] = services
+		}
+	}
+
+	if service, ok := args["service"].(string); ok {
+		if services, ok := aliases.Resolve(service); ok && len(services) == 1 {
+			out["service"] = services[0]
+			resolved[service] = services
+		}
+	}
+
+	if filters, ok := args["filters"].(map[string]any); ok {
+		var values []any
+		switch v := filters["service.name"].(type) {
+		case string:
+			values = []any{v}
+		case []any:
+			values = v
+		}
+
+		var expanded []any
+		changed := false
+		for _, v := range values {
+			s, ok := v.(string)
+			services, isAlias := aliases.Resolve(s)
+			if !ok || !isAlias {
+				expanded = append(expanded, v)
+				continue
+			}
+			changed = true
+			resolved[s] = services
+			for _, service := range services {
+				expanded = append(expanded, service)
+			}
+		}
+
+		if changed {
+			outFilters := make(map[string]any, len(filters))
+			for k, v := range filters {
+				outFilters[k] = v
+			}
+			if len(expanded) == 1 {
+				outFilters["service.name"] = expanded[0]
+			} else {
+				outFilters["service.name"] = expanded
+			}
+			out["filters"] = outFilters
+		}
+	}
+
+	return out, resolved
+}
+
+// Middleware resolves service aliases in the arguments of tool calls and tells the agent which
+// aliases were resolved.
+func (r *ServiceAliasResolver) Middleware() ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			aliases := r.Aliases(ctx)
+			if len(aliases) == 0 {
+				return next(ctx, request)
+			}
+
+			args, resolved := resolveArgumentAliases(request.GetArguments(), aliases)
+			if len(resolved) == 0 {
+				return next(ctx, request)
+			}
+
+			request.Params.Arguments = args
+			result, err := next(ctx, request)
+			if err != nil || result == nil {
+				return result, err
+			}
+
+			noteBytes, err := json.Marshal(map[string]any{"resolved_service_aliases": resolved})
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal resolved aliases: %w", err)
+			}
+
+			result.Content = append(result.Content, mcp.NewTextContent(string(noteBytes)))
+			return result, nil
+		}
+	}
+}
+
+var ServiceAliasesResource = mcp.NewResource(
+	"service-aliases://org",
+	"Service Aliases",
+	mcp.WithResourceDescription(`Names your teams use for services mapped to the service.name values the services report.
+Aliases in service.name filters and service arguments are resolved automatically.`),
+	mcp.WithMIMEType("application/json"),
+)
+
+type ServiceAliasEntry struct {
+	Alias    string   `json:"alias"`
+	Services []string `json:"services"`
+}
+
+type ServiceAliasesResourceResponse struct {
+	Aliases    []ServiceAliasEntry `json:"aliases"`
+	UsageNotes string              `json:"usage_notes"`
+}
+
+func ServiceAliasesResourceHandler(resolver *ServiceAliasResolver) server.ResourceHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		aliases := resolver.Aliases(ctx)
+
+		response := ServiceAliasesResourceResponse{
+			Aliases: make([]ServiceAliasEntry, 0, len(aliases)),
+			UsageNotes: `When the user names a service by one of these aliases, you may filter on the alias, e.g. service.name:"checkout"; it is replaced with the service names before the query runs.
+Use the service names themselves when showing queries to the user.`,
+		}
+		for alias, services := range aliases {
+			response.Aliases = append(response.Aliases, ServiceAliasEntry{Alias: alias, Services: services})
+		}
+		sort.Slice(response.Aliases, func(i, j int) bool {
+			return response.Aliases[i].Alias < response.Aliases[j].Alias
+		})
+
+		result, err := json.Marshal(response)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal service aliases: %w", err)
+		}
+
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      request.Params.URI,
+				MIMEType: "application/json",
+				Text:     string(result),
+			},
+		}, nil
+	}
+}
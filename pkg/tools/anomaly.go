@@ -0,0 +1,147 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// anomalyEventsFilter matches the anomalies detected in log patterns.
+const anomalyEventsFilter = `event.type:"pattern_anomaly"`
+
+var (
+	// eventTypeClausePattern matches event.type clauses, which anomaly_search sets itself
+	eventTypeClausePattern = regexp.MustCompile(`-?event\.type:("[^"]*"|\S+)`)
+	// danglingOperatorPattern matches boolean operators left without an operand
+	danglingOperatorPattern = regexp.MustCompile(`^(\s*(AND|OR)\s+)+|(\s+(AND|OR|NOT))+\s*$`)
+	// repeatedOperatorPattern matches consecutive boolean operators, the first one is kept
+	repeatedOperatorPattern = regexp.MustCompile(`\b(AND|OR)(\s+(AND|OR)\b)+`)
+)
+
+// GetAnomalySearchTool creates a tool to search the anomalies detected in log patterns
+func GetAnomalySearchTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("anomaly_search",
+			mcp.WithTitleAnnotation("Search Anomalies"),
+			mcp.WithDescription(`Search the anomalies Edge Delta detected in log patterns, e.g. a pattern that suddenly spikes or a new error signature. Each anomaly has the pattern, its service and an anomaly score.
+
+Use it as the starting point of "what changed?" investigations, then:
+- get_log_patterns with the service to see the pattern in context
+- get_log_search with the pattern's words to see the raw logs
+
+The event.type filter is applied automatically; use service and query to narrow the search.`),
+			mcp.WithString("service",
+				mcp.Description("Service name to filter on. Leave empty for all services."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("query",
+				mcp.Description(`Additional CQL filter ANDed with the anomaly filter. Examples:
+- k8s.namespace.name:"checkout"
+- timeout OR refused (full-text search)`),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("lookback",
+				mcp.Description("Lookback period in golang duration format, or days and weeks. e.g. '1h' or '7d'. Either provide from/to or provide lookback/to or just lookback. Pass empty string to use from/to instead."),
+				mcp.DefaultString("24h"),
+			),
+			mcp.WithString("from",
+				mcp.Description("From datetime in ISO format 2006-01-02T15:04:05.000Z."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("to",
+				mcp.Description("To datetime in ISO format 2006-01-02T15:04:05.000Z."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description("Limits the number of anomalies in the response. Default is 20, max is 1000."),
+				mcp.DefaultNumber(20),
+			),
+			mcp.WithString("cursor",
+				mcp.Description("Cursor provided from previous response, pass it to next request to move the cursor with given limit."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			service, _ := params.Optional[string](request, "service")
+			extra, _ := params.Optional[string](request, "query")
+
+			query := buildAnomalyQuery(service, extra)
+
+			queryParams := url.Values{}
+			queryParams.Add("query", query)
+
+			if lookback, _ := params.Optional[string](request, "lookback"); lookback != "" {
+				queryParams.Add("lookback", lookback)
+			}
+
+			if from, _ := params.Optional[string](request, "from"); from != "" {
+				queryParams.Add("from", from)
+			}
+
+			if to, _ := params.Optional[string](request, "to"); to != "" {
+				queryParams.Add("to", to)
+			}
+
+			if limit, _ := params.Optional[float64](request, "limit"); limit > 0 {
+				queryParams.Add("limit", fmt.Sprintf("%.0f", limit))
+			} else {
+				queryParams.Add("limit", "20")
+			}
+
+			if cursor, _ := params.Optional[string](request, "cursor"); cursor != "" {
+				queryParams.Add("cursor", cursor)
+			}
+
+			queryParams.Add("order", "desc")
+
+			bodyBytes, err := searchEvents(ctx, client, queryParams)
+			if err != nil {
+				return nil, err
+			}
+			bodyBytes = withParsedMonitorEvents(bodyBytes)
+
+			return formatSearchResponse(bodyBytes, query, uiLink(ctx, client, UIEventsPage, query, queryParams))
+		}
+}
+
+func buildAnomalyQuery(service, extra string) string {
+	parts := []string{anomalyEventsFilter}
+	if service != "" {
+		parts = append(parts, fmt.Sprintf(`service.name:"%s"`, escapeValue(service)))
+	}
+
+	if extra = sanitizeAnomalyQuery(extra); extra != "" {
+		parts = append(parts, "("+extra+")")
+	}
+
+	return strings.Join(parts, " AND ")
+}
+
+// sanitizeAnomalyQuery removes what would break or contradict the anomaly filter from a caller's
+// query: match-all "*", event.type clauses and the boolean operators they leave dangling.
+func sanitizeAnomalyQuery(query string) string {
+	query = strings.TrimSpace(query)
+	if query == "*" {
+		return ""
+	}
+
+	query = eventTypeClausePattern.ReplaceAllString(query, "")
+	query = repeatedOperatorPattern.ReplaceAllString(query, "$1")
+	for {
+		cleaned := strings.TrimSpace(danglingOperatorPattern.ReplaceAllString(query, ""))
+		if cleaned == query {
+			break
+		}
+		query = cleaned
+	}
+	return strings.Join(strings.Fields(query), " ")
+}
@@ -0,0 +1,482 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	defaultDetectedAnomalies = 20
+	maxDetectedAnomalies     = 100
+	// monitorAlertsFetched is the number of monitor alert events fetched, they are grouped by monitor.
+	monitorAlertsFetched = 200
+	// negativeSpikeRatio is how many times more often a negative pattern must occur than in the
+	// baseline window to be a spike.
+	negativeSpikeRatio = 1.5
+
+	AnomalyNewPattern    = "new_pattern"
+	AnomalyGonePattern   = "gone_pattern"
+	AnomalyNegativeSpike = "negative_spike"
+	AnomalyMonitorAlert  = "monitor_alert"
+)
+
+// monitorAlertsFilter matches the alert events of the monitors whose body parseMonitorEvent decodes.
+var monitorAlertsFilter = fmt.Sprintf(`ed.monitor.type:(%q OR %q OR %q)`, MonitorTypeMetricThreshold, MonitorTypeLogThreshold, MonitorTypePatternAnomaly)
+
+type DetectAnomaliesResult struct {
+	Service  string        `json:"service,omitempty"`
+	Current  CompareWindow `json:"current"`
+	Baseline CompareWindow `json:"baseline"`
+	// Sources are the searches the anomalies are detected in, a failed one has Error set
+	Sources   []AnomalySource   `json:"sources"`
+	Total     int               `json:"total"`
+	Anomalies []DetectedAnomaly `json:"anomalies"`
+	Guidance  *SearchGuidance   `json:"guidance,omitempty"`
+}
+
+type AnomalySource struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+	Count int    `json:"count"`
+	Error string `json:"error,omitempty"`
+}
+
+// DetectedAnomaly is an anomaly of the current window, with the tool calls reproducing it.
+type DetectedAnomaly struct {
+	Kind    string `json:"kind"`
+	Summary string `json:"summary"`
+	Service string `json:"service,omitempty"`
+	// Score ranks the anomalies from 0 to 100, see anomalyScore
+	Score    float64           `json:"score"`
+	Current  float64           `json:"current,omitempty"`
+	Baseline float64           `json:"baseline,omitempty"`
+	Evidence []AnomalyEvidence `json:"evidence"`
+}
+
+// AnomalyEvidence is a tool call reproducing an anomaly, and the UI page of its data.
+type AnomalyEvidence struct {
+	Tool      string         `json:"tool"`
+	Arguments map[string]any `json:"arguments"`
+	UILink    string         `json:"ui_link,omitempty"`
+}
+
+// monitorAlertGroup is the alert events of a monitor for a service.
+type monitorAlertGroup struct {
+	monitorType, monitor, service, subject string
+	events                                 int
+	exceedsBy, score                       float64
+}
+
+// DetectAnomaliesTool creates a tool ranking the anomalies of a time window across patterns and monitor alerts
+func DetectAnomaliesTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("detect_anomalies",
+			mcp.WithTitleAnnotation("Detect Anomalies"),
+			mcp.WithDescription(fmt.Sprintf(`Detects the anomalies of the current window and returns them as one list ranked by score (0-100), each with the tool calls reproducing it.
+
+Anomalies:
+- %s / %s: log patterns only in the current or only in the baseline window
+- %s: negative sentiment patterns occurring at least %.1fx as often as in the baseline window
+- %s: alerts of metric threshold, log threshold and pattern anomaly monitors, grouped by monitor and service
+
+Use this tool to answer "is anything wrong?" across services, before drilling down with investigate_window, compare_windows or get_event_search.
+The searches run concurrently; a search that fails is reported in sources without failing the others.`,
+				AnomalyNewPattern, AnomalyGonePattern, AnomalyNegativeSpike, negativeSpikeRatio, AnomalyMonitorAlert)),
+			mcp.WithString("service",
+				mcp.Description("Service name to detect the anomalies of. Leave empty for all services."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("query",
+				mcp.Description(`Additional CQL filter of the logs the patterns are detected in, e.g. k8s.namespace.name:"shop". Does not apply to monitor alerts.`),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("window",
+				mcp.Description("Length of the current and baseline windows in Go duration format, or days and weeks (e.g., 15m, 1h, 24h)."),
+				mcp.DefaultString("1h"),
+			),
+			mcp.WithString("baseline_offset",
+				mcp.Description("How long before the current window the baseline window is, at least the window, e.g. 24h for the same time yesterday."),
+				mcp.DefaultString("24h"),
+			),
+			mcp.WithString("to",
+				mcp.Description("End of the current window in ISO format 2006-01-02T15:04:05.000Z. Defaults to now."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description(fmt.Sprintf("Number of anomalies to return, highest scores first. Default: %d, max %d", defaultDetectedAnomalies, maxDetectedAnomalies)),
+				mcp.DefaultNumber(defaultDetectedAnomalies),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			limit := defaultDetectedAnomalies
+			if v, _ := params.Optional[float64](request, "limit"); v > 0 {
+				limit = int(v)
+			}
+			if limit > maxDetectedAnomalies {
+				return mcp.NewToolResultError(fmt.Sprintf("limit must be at most %d", maxDetectedAnomalies)), nil
+			}
+
+			window, _ := params.Optional[string](request, "window")
+			if window == "" {
+				window = "1h"
+			}
+			windowLength, err := ParseLookback(window)
+			if err != nil || windowLength <= 0 {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid window %q, expected a duration such as 1h or 7d", window)), nil
+			}
+			offset, _ := params.Optional[string](request, "baseline_offset")
+			if offset == "" {
+				offset = "24h"
+			}
+			baselineOffset, err := ParseLookback(offset)
+			if err != nil || baselineOffset < windowLength {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid baseline_offset %q, expected a duration of at least the window %s so that the windows do not overlap", offset, window)), nil
+			}
+
+			end := time.Now().UTC()
+			if to, _ := params.Optional[string](request, "to"); to != "" {
+				if end, err = time.Parse(time.RFC3339, to); err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("invalid to %q, expected ISO format 2006-01-02T15:04:05.000Z", to)), nil
+				}
+			}
+			current := timeRange{from: end.Add(-windowLength), to: end}
+			baseline := timeRange{from: current.from.Add(-baselineOffset), to: current.to.Add(-baselineOffset)}
+
+			service, _ := params.Optional[string](request, "service")
+			service = strings.TrimSpace(service)
+			extra, _ := params.Optional[string](request, "query")
+			var filters []string
+			if service != "" {
+				filters = append(filters, fmt.Sprintf(`service.name:"%s"`, escapeValue(service)))
+			}
+			if extra = strings.TrimSpace(extra); extra != "" && extra != "*" {
+				filters = append(filters, "("+extra+")")
+			}
+			patternFilter := "*"
+			if len(filters) > 0 {
+				patternFilter = strings.Join(filters, " AND ")
+			}
+			alertFilter := monitorAlertsFilter
+			if service != "" {
+				alertFilter += fmt.Sprintf(` AND service.name:"%s"`, escapeValue(service))
+			}
+
+			result := DetectAnomaliesResult{
+				Service:  service,
+				Current:  CompareWindow{From: current.from.Format(isoTimeLayout), To: current.to.Format(isoTimeLayout)},
+				Baseline: CompareWindow{From: baseline.from.Format(isoTimeLayout), To: baseline.to.Format(isoTimeLayout)},
+				Sources: []AnomalySource{
+					{Name: "current patterns", Query: patternFilter},
+					{Name: "baseline patterns", Query: patternFilter},
+					{Name: "monitor alerts", Query: alertFilter},
+				},
+			}
+
+			var currentPatterns, baselinePatterns []ComparedPattern
+			var alerts []map[string]json.RawMessage
+			var wg sync.WaitGroup
+			wg.Add(3)
+			go func() {
+				defer wg.Done()
+				var err error
+				if currentPatterns, err = windowPatternStats(ctx, client, patternFilter, current); err != nil {
+					result.Sources[0].Error = err.Error()
+				}
+				result.Sources[0].Count = len(currentPatterns)
+			}()
+			go func() {
+				defer wg.Done()
+				var err error
+				if baselinePatterns, err = windowPatternStats(ctx, client, patternFilter, baseline); err != nil {
+					result.Sources[1].Error = err.Error()
+				}
+				result.Sources[1].Count = len(baselinePatterns)
+			}()
+			go func() {
+				defer wg.Done()
+				var err error
+				if alerts, err = monitorAlerts(ctx, client, alertFilter, current); err != nil {
+					result.Sources[2].Error = err.Error()
+				}
+				result.Sources[2].Count = len(alerts)
+			}()
+			wg.Wait()
+
+			comparison := map[string]any{
+				"scope":           "pattern",
+				"query":           patternFilter,
+				"window":          window,
+				"baseline_offset": offset,
+				"to":              current.to.Format(isoTimeLayout),
+			}
+			patternParams := url.Values{
+				"query": {patternFilter},
+				"from":  {current.from.Format(isoTimeLayout)},
+				"to":    {current.to.Format(isoTimeLayout)},
+			}
+			patternEvidence := AnomalyEvidence{
+				Tool:      "compare_windows",
+				Arguments: comparison,
+				UILink:    uiLink(ctx, client, UIPatternsPage, patternFilter, patternParams),
+			}
+			// Patterns are only compared when both windows were fetched, otherwise all would be new or gone
+			if result.Sources[0].Error == "" && result.Sources[1].Error == "" {
+				result.Anomalies = append(result.Anomalies, patternAnomalies(currentPatterns, baselinePatterns, patternEvidence)...)
+			}
+			result.Anomalies = append(result.Anomalies, monitorAlertAnomalies(ctx, client, alerts, current)...)
+
+			sort.SliceStable(result.Anomalies, func(i, j int) bool { return result.Anomalies[i].Score > result.Anomalies[j].Score })
+			result.Total = len(result.Anomalies)
+			if len(result.Anomalies) > limit {
+				result.Anomalies = result.Anomalies[:limit]
+			}
+			if result.Anomalies == nil {
+				result.Anomalies = []DetectedAnomaly{}
+			}
+			result.Guidance = detectAnomaliesGuidance(result, window, offset)
+
+			r, err := json.Marshal(result)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
+
+// monitorAlerts returns the monitor alert events matching filter over the time range.
+func monitorAlerts(ctx context.Context, client Client, filter string, r timeRange) ([]map[string]json.RawMessage, error) {
+	queryParams := url.Values{}
+	queryParams.Add("query", filter)
+	queryParams.Add("from", r.from.Format(isoTimeLayout))
+	queryParams.Add("to", r.to.Format(isoTimeLayout))
+	queryParams.Add("limit", strconv.Itoa(monitorAlertsFetched))
+	queryParams.Add("order", "desc")
+
+	bodyBytes, err := searchEvents(ctx, client, queryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Items []map[string]json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(bodyBytes, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode event search response: %v", err)
+	}
+	return resp.Items, nil
+}
+
+// patternAnomalies returns the new and gone patterns, and the negative patterns spiking compared
+// to the baseline window.
+func patternAnomalies(current, baseline []ComparedPattern, evidence AnomalyEvidence) []DetectedAnomaly {
+	sentiments := make(map[string]string)
+	currentCounts := make(map[string]float64)
+	baselineCounts := make(map[string]float64)
+	for _, p := range current {
+		currentCounts[p.Pattern] += float64(p.Count)
+		sentiments[p.Pattern] = p.Sentiment
+	}
+	for _, p := range baseline {
+		baselineCounts[p.Pattern] += float64(p.Count)
+		if _, ok := sentiments[p.Pattern]; !ok {
+			sentiments[p.Pattern] = p.Sentiment
+		}
+	}
+
+	var anomalies []DetectedAnomaly
+	for _, delta := range seriesDeltas(currentCounts, baselineCounts) {
+		negative := sentiments[delta.Series] == "negative"
+		anomaly := DetectedAnomaly{Current: delta.Current, Baseline: delta.Baseline, Evidence: []AnomalyEvidence{evidence}}
+		switch {
+		case delta.Status == "new":
+			anomaly.Kind = AnomalyNewPattern
+			anomaly.Summary = fmt.Sprintf("New pattern %q, %.0f times", delta.Series, delta.Current)
+		case delta.Status == "gone":
+			anomaly.Kind = AnomalyGonePattern
+			anomaly.Summary = fmt.Sprintf("Pattern %q stopped, %.0f times in the baseline window", delta.Series, delta.Baseline)
+		case negative && delta.Baseline > 0 && delta.Current >= negativeSpikeRatio*delta.Baseline:
+			anomaly.Kind = AnomalyNegativeSpike
+			anomaly.Summary = fmt.Sprintf("Negative pattern %q occurs %.1fx as often, %.0f times", delta.Series, delta.Current/delta.Baseline, delta.Current)
+		default:
+			continue
+		}
+		anomaly.Score = anomalyScore(anomaly.Kind, delta.Current, delta.Baseline, negative)
+		anomalies = append(anomalies, anomaly)
+	}
+	return anomalies
+}
+
+// monitorAlertAnomalies groups the monitor alert events by monitor and service, one anomaly per group.
+func monitorAlertAnomalies(ctx context.Context, client Client, alerts []map[string]json.RawMessage, r timeRange) []DetectedAnomaly {
+	var order []string
+	groups := make(map[string]*monitorAlertGroup)
+	for _, item := range alerts {
+		monitorType := eventMonitorType(item)
+		body := []byte(item["body"])
+		var text string
+		if json.Unmarshal(body, &text) == nil {
+			body = []byte(text)
+		}
+		parsed, ok := parseMonitorEvent(monitorType, body)
+		if !ok {
+			continue
+		}
+
+		var resource map[string]any
+		_ = json.Unmarshal(item["resource"], &resource)
+		group := &monitorAlertGroup{monitorType: monitorType, service: recordString(resource, "service.name")}
+		switch {
+		case parsed.MetricThreshold != nil:
+			group.monitor, group.subject = parsed.MetricThreshold.MonitorName, parsed.MetricThreshold.Metric
+		case parsed.LogThreshold != nil:
+			group.monitor, group.subject = parsed.LogThreshold.MonitorName, parsed.LogThreshold.Query
+		case parsed.PatternAnomaly != nil:
+			group.monitor, group.subject = parsed.PatternAnomaly.MonitorName, parsed.PatternAnomaly.Pattern
+		}
+		if group.monitor == "" {
+			group.monitor = group.subject
+		}
+
+		key := strings.Join([]string{group.monitorType, group.monitor, group.service}, "\x00")
+		if existing, ok := groups[key]; ok {
+			group = existing
+		} else {
+			groups[key] = group
+			order = append(order, key)
+		}
+		group.events++
+		if parsed.ExceedsBy != nil && (group.events == 1 || *parsed.ExceedsBy > group.exceedsBy) {
+			group.exceedsBy = *parsed.ExceedsBy
+		}
+		if parsed.PatternAnomaly != nil {
+			group.score = max(group.score, parsed.PatternAnomaly.Score)
+		}
+	}
+
+	anomalies := make([]DetectedAnomaly, 0, len(order))
+	for _, key := range order {
+		group := groups[key]
+		query := fmt.Sprintf(`ed.monitor.type:"%s"`, escapeValue(group.monitorType))
+		if group.service != "" {
+			query += fmt.Sprintf(` AND service.name:"%s"`, escapeValue(group.service))
+		}
+		queryParams := url.Values{
+			"query": {query},
+			"from":  {r.from.Format(isoTimeLayout)},
+			"to":    {r.to.Format(isoTimeLayout)},
+		}
+
+		summary := fmt.Sprintf("Monitor %q (%s) alerted", group.monitor, group.monitorType)
+		if group.events > 1 {
+			summary += fmt.Sprintf(" %d times", group.events)
+		}
+		value := group.score
+		if group.monitorType != MonitorTypePatternAnomaly {
+			if group.exceedsBy > 0 {
+				summary += fmt.Sprintf(", up to %.0f%% over its threshold", group.exceedsBy*100)
+			}
+			value = max(0, group.exceedsBy)
+		}
+		anomalies = append(anomalies, DetectedAnomaly{
+			Kind:    AnomalyMonitorAlert,
+			Summary: summary,
+			Service: group.service,
+			Score:   anomalyScore(AnomalyMonitorAlert, value, float64(group.events), group.monitorType == MonitorTypePatternAnomaly),
+			Current: float64(group.events),
+			Evidence: []AnomalyEvidence{{
+				Tool: "get_event_search",
+				Arguments: map[string]any{
+					"query": query,
+					"from":  r.from.Format(isoTimeLayout),
+					"to":    r.to.Format(isoTimeLayout),
+				},
+				UILink: uiLink(ctx, client, UIEventsPage, query, queryParams),
+			}},
+		})
+	}
+	return anomalies
+}
+
+// anomalyScore scores an anomaly from 0 to 100. Counts weigh logarithmically, so that a pattern
+// ten times as frequent scores 10 points higher rather than ten times higher:
+//   - new_pattern: 40, plus 10 per order of magnitude of current, plus 20 for negative patterns
+//   - gone_pattern: 20, plus 10 per order of magnitude of baseline
+//   - negative_spike: 30, plus 20 per doubling from baseline to current
+//   - monitor_alert: for pattern anomaly monitors (negative set), the score of the monitor; for
+//     threshold monitors 50, plus 50 per 100% over the threshold (current), plus 2 per repeated
+//     alert (baseline) up to 10
+func anomalyScore(kind string, current, baseline float64, negative bool) float64 {
+	var score float64
+	switch kind {
+	case AnomalyNewPattern:
+		score = 40 + 10*math.Log10(1+current)
+		if negative {
+			score += 20
+		}
+	case AnomalyGonePattern:
+		score = 20 + 10*math.Log10(1+baseline)
+	case AnomalyNegativeSpike:
+		score = 30 + 20*math.Log2(current/baseline)
+	case AnomalyMonitorAlert:
+		if negative {
+			score = current
+		} else {
+			score = 50 + 50*current + min(10, 2*(baseline-1))
+		}
+	}
+	return math.Round(min(100, max(0, score))*10) / 10
+}
+
+func detectAnomaliesGuidance(result DetectAnomaliesResult, window, offset string) *SearchGuidance {
+	guidance := &SearchGuidance{ResultStatus: "success"}
+
+	var failed []string
+	for _, source := range result.Sources {
+		if source.Error != "" {
+			failed = append(failed, source.Name)
+		}
+	}
+	switch {
+	case len(failed) == len(result.Sources):
+		guidance.ResultStatus = "failed"
+		guidance.NextSteps = append(guidance.NextSteps, "All the searches failed, see the error of every source.")
+		return guidance
+	case len(failed) > 0:
+		guidance.ResultStatus = "partial"
+		guidance.NextSteps = append(guidance.NextSteps, fmt.Sprintf("Searches failed for %s, see their error in sources.", strings.Join(failed, ", ")))
+	}
+
+	if result.Total == 0 {
+		if guidance.ResultStatus == "success" {
+			guidance.ResultStatus = "empty"
+		}
+		guidance.NextSteps = append(guidance.NextSteps, fmt.Sprintf("No anomaly in the last %s compared to %s before.", window, offset))
+		guidance.Suggestions = append(guidance.Suggestions, "Widen the window, or use investigate_window with a service to see its error signals.")
+		return guidance
+	}
+
+	top := result.Anomalies[0]
+	guidance.NextSteps = append(guidance.NextSteps, fmt.Sprintf("%d anomalies in the last %s; the top one scores %.1f: %s.", result.Total, window, top.Score, top.Summary))
+	if len(top.Evidence) > 0 {
+		guidance.Suggestions = append(guidance.Suggestions, fmt.Sprintf("Reproduce the top anomaly with %s using its evidence arguments.", top.Evidence[0].Tool))
+	}
+	if top.Service != "" && result.Service == "" {
+		guidance.Suggestions = append(guidance.Suggestions, fmt.Sprintf("Use investigate_window with service %q to see its error logs and failed traces.", top.Service))
+	}
+	return guidance
+}
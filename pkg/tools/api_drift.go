@@ -0,0 +1,247 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIEndpoint is an Edge Delta API endpoint a hand-written tool calls. Path parameters are in
+// braces, e.g. /v1/orgs/{org_id}/confs/{conf_id}.
+type APIEndpoint struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+}
+
+// ToolEndpoints lists the endpoints the hand-written tools call. Keep it in sync with the tools,
+// it is checked against the OpenAPI spec of the API to detect endpoints that were removed or
+// changed upstream.
+var ToolEndpoints = []APIEndpoint{
+	{http.MethodGet, "/v1/orgs"},
+	{http.MethodGet, "/v1/orgs/{org_id}/logs/log_search/search"},
+	{http.MethodGet, "/v1/orgs/{org_id}/logs/log_search/graph"},
+	{http.MethodGet, "/v1/orgs/{org_id}/events/search"},
+	{http.MethodGet, "/v1/orgs/{org_id}/clustering/stats"},
+	{http.MethodGet, "/v1/orgs/{org_id}/traces"},
+	{http.MethodPost, "/v1/orgs/{org_id}/graph"},
+	{http.MethodGet, "/v1/orgs/{org_id}/facets"},
+	{http.MethodPost, "/v1/orgs/{org_id}/facets"},
+	{http.MethodDelete, "/v1/orgs/{org_id}/facets"},
+	{http.MethodGet, "/v1/orgs/{org_id}/facet_options"},
+	{http.MethodGet, "/v1/orgs/{org_id}/facet_keys"},
+	{http.MethodGet, "/v1/orgs/{org_id}/pipelines"},
+	{http.MethodGet, "/v1/orgs/{org_id}/pipelines/{conf_id}/history"},
+	{http.MethodGet, "/v1/orgs/{org_id}/pipelines/{conf_id}/agents"},
+	{http.MethodPost, "/v1/orgs/{org_id}/pipelines/{conf_id}/deploy/{version}"},
+	{http.MethodPost, "/v1/orgs/{org_id}/pipelines/{conf_id}/add_source"},
+	{http.MethodPost, "/v1/orgs/{org_id}/pipelines/{conf_id}/remove_node"},
+	{http.MethodPost, "/v1/orgs/{org_id}/pipelines/{conf_id}/update_node"},
+	{http.MethodPost, "/v1/orgs/{org_id}/pipelines/{conf_id}/connect_nodes"},
+	{http.MethodPost, "/v1/orgs/{org_id}/pipelines/{conf_id}/disconnect_nodes"},
+	{http.MethodPost, "/v1/orgs/{org_id}/pipelines/{conf_id}/save"},
+	{http.MethodPost, "/v1/orgs/{org_id}/pipelines/{conf_id}/validate"},
+	{http.MethodGet, "/v1/orgs/{org_id}/confs"},
+	{http.MethodGet, "/v1/orgs/{org_id}/confs/{conf_id}"},
+	{http.MethodGet, "/v1/orgs/{org_id}/ingestion_endpoints"},
+	{http.MethodGet, "/v1/orgs/{org_id}/ingestion_token"},
+	{http.MethodGet, "/v1/orgs/{org_id}/features"},
+	{http.MethodGet, "/v1/orgs/{org_id}/settings/service_aliases"},
+	{http.MethodGet, "/v1/orgs/{org_id}/child_orgs"},
+	{http.MethodGet, "/v1/orgs/{org_id}/members"},
+	{http.MethodGet, "/v1/orgs/{org_id}/api_keys"},
+	{http.MethodGet, "/v1/orgs/{org_id}/dashboards"},
+	{http.MethodPost, "/v1/orgs/{org_id}/dashboards"},
+	{http.MethodGet, "/v1/orgs/{org_id}/dashboards/{dashboard_id}"},
+	{http.MethodPut, "/v1/orgs/{org_id}/dashboards/{dashboard_id}"},
+	{http.MethodGet, "/v1/orgs/{org_id}/monitors"},
+	{http.MethodPost, "/v1/orgs/{org_id}/monitors"},
+	{http.MethodGet, "/v1/orgs/{org_id}/monitors/{monitor_id}"},
+	{http.MethodPut, "/v1/orgs/{org_id}/monitors/{monitor_id}"},
+	{http.MethodPost, "/v1/orgs/{org_id}/monitors/{monitor_id}/mute"},
+	{http.MethodPost, "/v1/orgs/{org_id}/monitors/{monitor_id}/unmute"},
+	{http.MethodGet, "/v1/orgs/{org_id}/notifications/integrations"},
+	{http.MethodGet, "/v1/orgs/{org_id}/notifications/routing_rules"},
+	{http.MethodGet, "/v1/orgs/{org_id}/archives"},
+	{http.MethodGet, "/v1/orgs/{org_id}/archives/{destination_id}/objects"},
+}
+
+const (
+	// DriftPathMissing means the path of the endpoint is not in the spec.
+	DriftPathMissing = "path_missing"
+	// DriftMethodMissing means the path is in the spec but not with the method of the endpoint.
+	DriftMethodMissing = "method_missing"
+)
+
+// APIDrift is an endpoint of a tool that does not match the OpenAPI spec of the API.
+type APIDrift struct {
+	APIEndpoint
+	Kind string `json:"kind"`
+	// Methods are the methods the spec has for the path, for DriftMethodMissing
+	Methods []string `json:"spec_methods,omitempty"`
+}
+
+// APIDriftReport is the result of the last check of the tool endpoints against the spec.
+type APIDriftReport struct {
+	SpecURL string `json:"spec_url,omitempty"`
+	// SpecFile is set when the spec was read from the file, because the spec URL, if set, could
+	// not be fetched
+	SpecFile  string     `json:"spec_file,omitempty"`
+	CheckedAt time.Time  `json:"checked_at"`
+	Endpoints int        `json:"endpoints_checked"`
+	Drift     []APIDrift `json:"drift"`
+	// Error is set when the spec could not be fetched or parsed
+	Error string `json:"error,omitempty"`
+}
+
+// openAPISpec is the part of an OpenAPI 3 or Swagger 2 spec the check needs.
+type openAPISpec struct {
+	BasePath string                                `json:"basePath"`
+	Paths    map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+var pathParamPattern = regexp.MustCompile(`\{[^}/]*\}`)
+
+// normalizePath replaces the path parameters of the path with {}, since the spec may name them
+// differently, e.g. {orgID} for {org_id}.
+func normalizePath(path string) string {
+	return strings.TrimSuffix(pathParamPattern.ReplaceAllString(path, "{}"), "/")
+}
+
+// CheckAPIDrift returns the endpoints missing from the paths of the spec.
+func CheckAPIDrift(spec []byte, endpoints []APIEndpoint) ([]APIDrift, error) {
+	var parsed openAPISpec
+	if err := json.Unmarshal(spec, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI spec: %w", err)
+	}
+	if len(parsed.Paths) == 0 {
+		return nil, fmt.Errorf("OpenAPI spec has no paths")
+	}
+
+	specMethods := make(map[string][]string, len(parsed.Paths))
+	basePath := strings.TrimSuffix(parsed.BasePath, "/")
+	for path, operations := range parsed.Paths {
+		key := normalizePath(basePath + path)
+		for method := range operations {
+			switch method := strings.ToUpper(method); method {
+			case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodHead, http.MethodOptions:
+				specMethods[key] = append(specMethods[key], method)
+			}
+		}
+	}
+
+	drift := []APIDrift{}
+	for _, endpoint := range endpoints {
+		methods, ok := specMethods[normalizePath(endpoint.Path)]
+		switch {
+		case !ok:
+			drift = append(drift, APIDrift{APIEndpoint: endpoint, Kind: DriftPathMissing})
+		case !slices.Contains(methods, endpoint.Method):
+			sort.Strings(methods)
+			drift = append(drift, APIDrift{APIEndpoint: endpoint, Kind: DriftMethodMissing, Methods: methods})
+		}
+	}
+	return drift, nil
+}
+
+// APIDriftChecker checks the endpoints of the hand-written tools against the OpenAPI spec of the
+// API, fetched from a URL or read from a file, and keeps the report of the last check.
+type APIDriftChecker struct {
+	client   Client
+	specURL  string
+	specFile string
+
+	mu     sync.Mutex
+	report APIDriftReport
+}
+
+// NewAPIDriftChecker creates a checker fetching the spec from specURL, falling back to the file
+// at specFile when it cannot be fetched, e.g. in environments without network access to the API
+// spec. Either may be empty.
+func NewAPIDriftChecker(client Client, specURL, specFile string) *APIDriftChecker {
+	return &APIDriftChecker{
+		client:   client,
+		specURL:  specURL,
+		specFile: specFile,
+		report:   APIDriftReport{SpecURL: specURL, SpecFile: specFile, Drift: []APIDrift{}},
+	}
+}
+
+// Check fetches the spec and checks ToolEndpoints against it.
+func (c *APIDriftChecker) Check(ctx context.Context) (APIDriftReport, error) {
+	report := APIDriftReport{CheckedAt: time.Now().UTC(), Endpoints: len(ToolEndpoints), Drift: []APIDrift{}}
+
+	spec, err := c.loadSpec(ctx, &report)
+	if err == nil {
+		report.Drift, err = CheckAPIDrift(spec, ToolEndpoints)
+	}
+	if err != nil {
+		report.Error = err.Error()
+		report.Drift = []APIDrift{}
+	}
+
+	c.mu.Lock()
+	c.report = report
+	c.mu.Unlock()
+	return report, err
+}
+
+// Report returns the report of the last check.
+func (c *APIDriftChecker) Report() APIDriftReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.report
+}
+
+// loadSpec fetches the spec from the URL, or reads it from the file if there is no URL or it
+// cannot be fetched, and sets the source it was loaded from in the report.
+func (c *APIDriftChecker) loadSpec(ctx context.Context, report *APIDriftReport) ([]byte, error) {
+	var fetchErr error
+	if c.specURL != "" {
+		report.SpecURL = c.specURL
+		spec, err := c.fetchSpec(ctx)
+		if err == nil || c.specFile == "" {
+			return spec, err
+		}
+		fetchErr = err
+	}
+
+	report.SpecFile = c.specFile
+	spec, err := os.ReadFile(c.specFile)
+	if err != nil {
+		return nil, errors.Join(fetchErr, fmt.Errorf("failed to read OpenAPI spec: %w", err))
+	}
+	return spec, nil
+}
+
+func (c *APIDriftChecker) fetchSpec(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.specURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch OpenAPI spec, status code %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+	return bodyBytes, nil
+}
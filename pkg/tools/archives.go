@@ -0,0 +1,314 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	defaultArchiveObjects = 100
+	maxArchiveObjects     = 1000
+	// archiveGapTolerance is the largest hole between archive objects not reported as a gap.
+	archiveGapTolerance = time.Minute
+)
+
+// ArchiveDestination is an S3, GCS or Azure Blob destination data is archived to.
+type ArchiveDestination struct {
+	DestinationID string `json:"destination_id"`
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	Bucket        string `json:"bucket"`
+	Prefix        string `json:"prefix,omitempty"`
+	Region        string `json:"region,omitempty"`
+}
+
+// ArchiveDestinationsResponse mirrors the backend response from GET /v1/orgs/{org_id}/archives
+type ArchiveDestinationsResponse struct {
+	Destinations []ArchiveDestination `json:"destinations"`
+}
+
+// ArchiveObject is an object of an archive, holding the data of a time range.
+type ArchiveObject struct {
+	Key       string    `json:"key"`
+	SizeBytes int64     `json:"size_bytes"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Records   int64     `json:"records,omitempty"`
+}
+
+// ArchiveObjectsResponse mirrors the backend response from
+// GET /v1/orgs/{org_id}/archives/{destination_id}/objects
+type ArchiveObjectsResponse struct {
+	Objects    []ArchiveObject `json:"objects"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// ArchiveCoverage tells whether the objects listed cover a time range.
+type ArchiveCoverage struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// FullyArchived is true if the objects cover the whole range, up to archiveGapTolerance
+	FullyArchived bool         `json:"fully_archived"`
+	Gaps          []ArchiveGap `json:"gaps,omitempty"`
+	// Complete is false if there are more objects than listed, the gaps may be covered by them
+	Complete bool `json:"complete"`
+}
+
+type ArchiveGap struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Duration string `json:"duration"`
+}
+
+type ListArchiveObjectsResult struct {
+	Destination  *ArchiveDestination  `json:"destination,omitempty"`
+	Destinations []ArchiveDestination `json:"destinations,omitempty"`
+	Objects      []ArchiveObject      `json:"objects,omitempty"`
+	Count        int                  `json:"count"`
+	TotalBytes   int64                `json:"total_bytes,omitempty"`
+	NextCursor   string               `json:"next_cursor,omitempty"`
+	Coverage     *ArchiveCoverage     `json:"coverage,omitempty"`
+	Guidance     *SearchGuidance      `json:"guidance,omitempty"`
+}
+
+func ListArchiveDestinations(ctx context.Context, client Client) ([]ArchiveDestination, error) {
+	var out ArchiveDestinationsResponse
+	if err := getOrgResource(ctx, client, "archives", "list archive destinations", &out); err != nil {
+		return nil, err
+	}
+	return out.Destinations, nil
+}
+
+func ListArchiveObjects(ctx context.Context, client Client, destinationID string, opts ...QueryParamOption) (*ArchiveObjectsResponse, error) {
+	var out ArchiveObjectsResponse
+	path := "archives/" + url.PathEscape(destinationID) + "/objects"
+	if err := getOrgResource(ctx, client, path, "list archive objects", &out, opts...); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListArchiveObjectsTool creates a tool to browse the archive index of the organization
+func ListArchiveObjectsTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("list_archive_objects",
+			mcp.WithTitleAnnotation("List Archive Objects"),
+			mcp.WithDescription(`List the objects of an archive destination (S3, GCS or Azure Blob) holding data of a time range, and whether the range is fully archived.
+
+Use this tool to confirm data of a time window was archived before suggesting a rehydration, e.g. when the window is older than the retention of the search tools.
+The coverage block lists the gaps of the window without archived data.
+
+Leave destination empty to list the destinations when there are several.`),
+			mcp.WithString("destination",
+				mcp.Description("ID or name of the archive destination. Can be empty if the org has a single destination."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("prefix",
+				mcp.Description("Only list the objects whose key starts with this prefix, e.g. edgedelta/logs/"),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("lookback",
+				mcp.Description("Lookback period in GOLANG duration format, or days and weeks. e.g. (24h, 7d, 1w). Either provide from/to or just lookback. Pass empty string to use from/to instead."),
+				mcp.DefaultString("24h"),
+			),
+			mcp.WithString("from",
+				mcp.Description("From datetime in ISO format 2006-01-02T15:04:05.000Z."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("to",
+				mcp.Description("To datetime in ISO format 2006-01-02T15:04:05.000Z."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description(fmt.Sprintf("Maximum number of objects. Default: %d, max %d", defaultArchiveObjects, maxArchiveObjects)),
+				mcp.DefaultNumber(defaultArchiveObjects),
+			),
+			mcp.WithString("cursor",
+				mcp.Description("Pagination cursor. Use the next_cursor value from a previous response to fetch the next page of objects."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			limit := defaultArchiveObjects
+			if v, _ := params.Optional[float64](request, "limit"); v > 0 {
+				limit = int(v)
+			}
+			if limit > maxArchiveObjects {
+				return mcp.NewToolResultError(fmt.Sprintf("limit must be at most %d", maxArchiveObjects)), nil
+			}
+
+			timeParams := url.Values{}
+			lookback, _ := params.Optional[string](request, "lookback")
+			from, _ := params.Optional[string](request, "from")
+			to, _ := params.Optional[string](request, "to")
+			switch {
+			case from != "":
+				timeParams.Set("from", from)
+				timeParams.Set("to", to)
+			case lookback != "":
+				timeParams.Set("lookback", lookback)
+			default:
+				timeParams.Set("lookback", "24h")
+			}
+			start, end, err := resolveTimeRange(timeParams, time.Now().UTC())
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid time range: %v", err)), nil
+			}
+			if !start.Before(end) {
+				return mcp.NewToolResultError("invalid time range: from must be before to"), nil
+			}
+
+			destinations, err := ListArchiveDestinations(ctx, client)
+			if err != nil {
+				return nil, err
+			}
+			name, _ := params.Optional[string](request, "destination")
+			destination, result := archiveDestination(destinations, strings.TrimSpace(name))
+			if destination == nil {
+				return archiveResult(result)
+			}
+
+			prefix, _ := params.Optional[string](request, "prefix")
+			cursor, _ := params.Optional[string](request, "cursor")
+			objects, err := ListArchiveObjects(ctx, client, destination.DestinationID, func(v url.Values) {
+				v.Set("from", start.Format(isoTimeLayout))
+				v.Set("to", end.Format(isoTimeLayout))
+				v.Set("limit", strconv.Itoa(limit))
+				if prefix != "" {
+					v.Set("prefix", prefix)
+				}
+				if cursor != "" {
+					v.Set("cursor", cursor)
+				}
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			result = ListArchiveObjectsResult{
+				Destination: destination,
+				Objects:     objects.Objects,
+				Count:       len(objects.Objects),
+				NextCursor:  objects.NextCursor,
+			}
+			for _, object := range objects.Objects {
+				result.TotalBytes += object.SizeBytes
+			}
+			// A page after the first one does not start at the beginning of the range
+			if cursor == "" {
+				result.Coverage = archiveCoverage(objects.Objects, start, end, objects.NextCursor == "")
+			}
+			result.Guidance = archiveGuidance(result)
+			return archiveResult(result)
+		}
+}
+
+// archiveDestination returns the destination of the name, or the result to return without it.
+func archiveDestination(destinations []ArchiveDestination, name string) (*ArchiveDestination, ListArchiveObjectsResult) {
+	if len(destinations) == 0 {
+		return nil, ListArchiveObjectsResult{Guidance: &SearchGuidance{
+			ResultStatus: "no_destinations",
+			NextSteps:    []string{"The org has no archive destination, so data older than the retention of the search tools cannot be rehydrated."},
+		}}
+	}
+
+	if name == "" && len(destinations) == 1 {
+		return &destinations[0], ListArchiveObjectsResult{}
+	}
+	for i, d := range destinations {
+		if name != "" && (d.DestinationID == name || strings.EqualFold(d.Name, name)) {
+			return &destinations[i], ListArchiveObjectsResult{}
+		}
+	}
+
+	status, step := "choose_destination", "The org has several archive destinations, call list_archive_objects again with one of them as destination."
+	if name != "" {
+		status, step = "unknown_destination", fmt.Sprintf("No archive destination %q, use one of the destinations listed.", name)
+	}
+	return nil, ListArchiveObjectsResult{
+		Destinations: destinations,
+		Guidance:     &SearchGuidance{ResultStatus: status, NextSteps: []string{step}},
+	}
+}
+
+// archiveCoverage returns the gaps of [from, to) not covered by the time ranges of the objects.
+func archiveCoverage(objects []ArchiveObject, from, to time.Time, complete bool) *ArchiveCoverage {
+	ranges := make([]timeRange, 0, len(objects))
+	for _, object := range objects {
+		if object.End.After(from) && object.Start.Before(to) {
+			ranges = append(ranges, timeRange{from: object.Start, to: object.End})
+		}
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].from.Before(ranges[j].from) })
+
+	coverage := &ArchiveCoverage{From: from.Format(isoTimeLayout), To: to.Format(isoTimeLayout), Complete: complete}
+	addGap := func(start, end time.Time) {
+		if end.Sub(start) > archiveGapTolerance {
+			coverage.Gaps = append(coverage.Gaps, ArchiveGap{
+				From:     start.Format(isoTimeLayout),
+				To:       end.Format(isoTimeLayout),
+				Duration: formatDuration(end.Sub(start).Round(time.Second)),
+			})
+		}
+	}
+
+	covered := from
+	for _, r := range ranges {
+		if r.from.After(covered) {
+			addGap(covered, r.from)
+		}
+		if r.to.After(covered) {
+			covered = r.to
+		}
+	}
+	if covered.Before(to) {
+		addGap(covered, to)
+	}
+	coverage.FullyArchived = len(coverage.Gaps) == 0
+	return coverage
+}
+
+func archiveGuidance(result ListArchiveObjectsResult) *SearchGuidance {
+	guidance := &SearchGuidance{ResultStatus: "success"}
+	switch {
+	case result.Count == 0:
+		guidance.ResultStatus = "empty"
+		guidance.NextSteps = []string{"No archived data in this time range, it cannot be rehydrated from this destination."}
+		guidance.Suggestions = []string{"Check the prefix, and the other destinations with destination left empty"}
+	case result.Coverage == nil:
+		guidance.NextSteps = []string{"Objects of a later page, coverage is computed for the first page only."}
+	case result.Coverage.FullyArchived:
+		guidance.NextSteps = []string{"The time range is fully archived and can be rehydrated."}
+	case !result.Coverage.Complete:
+		guidance.ResultStatus = "partial"
+		guidance.NextSteps = []string{"There are more objects than listed, the gaps may be covered by them. Narrow the time range or the prefix, or raise limit."}
+	default:
+		guidance.ResultStatus = "gaps"
+		guidance.NextSteps = []string{fmt.Sprintf("%d gaps of the time range have no archived data, only the archived parts can be rehydrated.", len(result.Coverage.Gaps))}
+	}
+	if result.NextCursor != "" {
+		guidance.Suggestions = append(guidance.Suggestions, "Use next_cursor as cursor to list the next objects")
+	}
+	return guidance
+}
+
+func archiveResult(result ListArchiveObjectsResult) (*mcp.CallToolResult, error) {
+	r, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal response: %w", err)
+	}
+	return mcp.NewToolResultText(string(r)), nil
+}
@@ -0,0 +1,260 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	auditTimeout = 10 * time.Second
+
+	redacted = "[REDACTED]"
+)
+
+// sensitiveArgumentPattern matches the names of the arguments whose values are never audited.
+var sensitiveArgumentPattern = regexp.MustCompile(`(?i)token|secret|password|passwd|api_?key|authorization|credential|private_?key`)
+
+// AuditEvent records a tool call.
+type AuditEvent struct {
+	Time time.Time `json:"time"`
+	Tool string    `json:"tool"`
+	// Arguments are the canonical arguments of the caller, see ArgumentCanonicalizer, with the
+	// values of sensitive arguments and the tokens of the caller redacted
+	Arguments map[string]any `json:"arguments,omitempty"`
+	// OrgID is the org the call ran against, the org_id argument when the call overrides the org
+	OrgID string `json:"org_id,omitempty"`
+	// Caller identifies the caller across token refreshes, e.g. sub:<subject> for OAuth tokens and
+	// org:<org_id> for API tokens, see callerIdentity
+	Caller     string `json:"caller,omitempty"`
+	SessionID  string `json:"session_id,omitempty"`
+	Client     string `json:"client,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	// Status is "success" or "error"
+	Status      string    `json:"status"`
+	ErrorCode   ErrorCode `json:"error_code,omitempty"`
+	ResultBytes int       `json:"result_bytes"`
+}
+
+// AuditLogger records audit events.
+type AuditLogger interface {
+	Log(ctx context.Context, event AuditEvent) error
+}
+
+// FileAuditLogger appends audit events to a file, one JSON object per line.
+type FileAuditLogger struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileAuditLogger opens the audit file for appending, creating it if needed.
+func NewFileAuditLogger(path string) (*FileAuditLogger, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &FileAuditLogger{file: file}, nil
+}
+
+func (l *FileAuditLogger) Log(ctx context.Context, event AuditEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit event: %w", err)
+	}
+	return nil
+}
+
+func (l *FileAuditLogger) Close() error {
+	return l.file.Close()
+}
+
+// IngestionAuditLogger sends audit events to an Edge Delta HTTP ingestion endpoint, see
+// get_ingestion_endpoint. The URL carries the stream token of the endpoint.
+type IngestionAuditLogger struct {
+	url    string
+	client *http.Client
+}
+
+func NewIngestionAuditLogger(url string) *IngestionAuditLogger {
+	return &IngestionAuditLogger{url: url, client: &http.Client{Timeout: auditTimeout}}
+}
+
+// Log posts the event before the tool call returns, so calls are not audited after the fact. The
+// post is not canceled with the call.
+func (l *IngestionAuditLogger) Log(ctx context.Context, event AuditEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), auditTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to send audit event, status code %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+	return nil
+}
+
+// AuditToolCalls returns a tool middleware recording every tool call with the audit loggers, so
+// identical calls are audited with identical arguments. Failing to record a call is logged and
+// does not fail the call.
+func AuditToolCalls(auditLoggers []AuditLogger, canonicalizer *ArgumentCanonicalizer, logger *slog.Logger) ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			// The arguments are copied first, as the next middlewares may rewrite them
+			event := AuditEvent{
+				Time:      time.Now().UTC(),
+				Tool:      request.Params.Name,
+				Arguments: redactArguments(auditArguments(canonicalizer, request), callerTokens(ctx)),
+			}
+			event.OrgID, _ = ctx.Value(OrgIDKey).(string)
+			event.Caller, _ = callerIdentity(ctx)
+			// The middlewares running the call against another org record it here
+			effectiveOrg := new(string)
+			ctx = context.WithValue(ctx, auditOrgKey{}, effectiveOrg)
+			if session := server.ClientSessionFromContext(ctx); session != nil {
+				event.SessionID = session.SessionID()
+				if withInfo, ok := session.(server.SessionWithClientInfo); ok {
+					if info := withInfo.GetClientInfo(); info.Name != "" {
+						event.Client = strings.TrimSpace(info.Name + " " + info.Version)
+					}
+				}
+			}
+
+			result, err := next(ctx, request)
+
+			if *effectiveOrg != "" {
+				event.OrgID = *effectiveOrg
+			}
+			event.DurationMs = time.Since(event.Time).Milliseconds()
+			event.Status = "success"
+			switch {
+			case err != nil:
+				event.Status, event.ErrorCode = "error", ClassifyError(err).Code
+			case result == nil:
+				event.Status = "error"
+			case result.IsError:
+				event.Status, event.ErrorCode = "error", ErrorCode(toolErrorCode(result))
+			}
+			if result != nil {
+				for _, content := range result.Content {
+					if text, ok := content.(mcp.TextContent); ok {
+						event.ResultBytes += len(text.Text)
+					}
+				}
+			}
+
+			for _, auditLogger := range auditLoggers {
+				if logErr := auditLogger.Log(ctx, event); logErr != nil {
+					logger.Error("Failed to audit tool call", "tool", event.Tool, "error", logErr)
+				}
+			}
+			return result, err
+		}
+	}
+}
+
+// auditOrgKey holds where AuditToolCalls expects the org a call runs against, when it is not the
+// org of the caller.
+type auditOrgKey struct{}
+
+// recordAuditOrg records the org the call runs against for the audit of the call, if any.
+func recordAuditOrg(ctx context.Context, orgID string) {
+	if org, ok := ctx.Value(auditOrgKey{}).(*string); ok {
+		*org = orgID
+	}
+}
+
+// auditArguments returns the canonical arguments of the call, or its arguments as is if they
+// cannot be canonicalized.
+func auditArguments(canonicalizer *ArgumentCanonicalizer, request mcp.CallToolRequest) map[string]any {
+	canonical, err := canonicalizer.Canonical(request.Params.Name, request.GetArguments())
+	if err != nil {
+		return request.GetArguments()
+	}
+	var args map[string]any
+	if err := json.Unmarshal(canonical, &args); err != nil {
+		return request.GetArguments()
+	}
+	return args
+}
+
+// callerTokens returns the tokens of the caller, which are redacted wherever they appear in the
+// arguments.
+func callerTokens(ctx context.Context) []string {
+	keys := fetchTokens(ctx)
+	var tokens []string
+	for _, token := range []string{keys.EDToken, keys.BearerToken} {
+		if token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// redactArguments returns a copy of the arguments with the values of sensitive arguments, and the
+// tokens in the other values, redacted.
+func redactArguments(args map[string]any, tokens []string) map[string]any {
+	if args == nil {
+		return nil
+	}
+	redactedArgs := make(map[string]any, len(args))
+	for key, value := range args {
+		if sensitiveArgumentPattern.MatchString(key) {
+			redactedArgs[key] = redacted
+			continue
+		}
+		redactedArgs[key] = redactValue(value, tokens)
+	}
+	return redactedArgs
+}
+
+func redactValue(value any, tokens []string) any {
+	switch value := value.(type) {
+	case string:
+		for _, token := range tokens {
+			value = strings.ReplaceAll(value, token, redacted)
+		}
+		return value
+	case map[string]any:
+		return redactArguments(value, tokens)
+	case []any:
+		values := make([]any, len(value))
+		for i, v := range value {
+			values[i] = redactValue(v, tokens)
+		}
+		return values
+	default:
+		return value
+	}
+}
@@ -0,0 +1,25 @@
+package tools
+
+import (
+	"fmt"
+	"testing"
+)
+
+// The benchmarks of the tool handlers are in the server package, which runs them through the
+// middlewares against the demo API.
+
+const benchGraphQueries = 100
+
+func BenchmarkGraphQueryBuilder(b *testing.B) {
+	b.ReportAllocs()
+	for range b.N {
+		builder := NewGraphQueryBuilder()
+		for i := range benchGraphQueries {
+			builder.WithQuery(fmt.Sprintf("Q%d", i), GraphQuery{Scope: "log", Query: fmt.Sprintf(`service.name:"service-%d"`, i)})
+		}
+		builder.WithFormula("R1", "(Q1 + Q2) / Q3 * 100").WithFormula("R2", "abs(Q4 - Q5)")
+		if _, err := builder.Build(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/storage"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ToolBudget caps how often each caller may call a tool, e.g. 3 calls per hour.
+type ToolBudget struct {
+	Calls int
+	Per   time.Duration
+}
+
+func (b ToolBudget) String() string {
+	// 1h0m0s reads as 1h
+	per := b.Per.String()
+	if strings.HasSuffix(per, "m0s") {
+		per = strings.TrimSuffix(per, "0s")
+	}
+	if strings.HasSuffix(per, "h0m") {
+		per = strings.TrimSuffix(per, "0m")
+	}
+
+	if b.Calls == 1 {
+		return "1 call per " + per
+	}
+	return fmt.Sprintf("%d calls per %s", b.Calls, per)
+}
+
+// ParseToolBudgets parses budgets in the form "deploy_pipeline=3/1h,save_pipeline=10/1h".
+func ParseToolBudgets(s string) (map[string]ToolBudget, error) {
+	budgets := make(map[string]ToolBudget)
+	for _, entry := range strings.Split(s, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+
+		toolName, budget, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid tool budget entry %q, expected <tool>=<calls>/<duration>", entry)
+		}
+		calls, per, ok := strings.Cut(budget, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid tool budget entry %q, expected <tool>=<calls>/<duration>", entry)
+		}
+
+		n, err := strconv.Atoi(strings.TrimSpace(calls))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid number of calls for tool %s: %q", toolName, calls)
+		}
+		d, err := ParseLookback(strings.TrimSpace(per))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid budget period for tool %s: %q", toolName, per)
+		}
+		budgets[strings.TrimSpace(toolName)] = ToolBudget{Calls: n, Per: d}
+	}
+	return budgets, nil
+}
+
+// tokenBucket holds up to the budgeted calls and refills continuously over the budget period.
+type tokenBucket struct {
+	Tokens  float64   `json:"tokens"`
+	Updated time.Time `json:"updated"`
+}
+
+// take takes a token from the bucket, or returns how long until one is available.
+func (b *tokenBucket) take(budget ToolBudget, now time.Time) (bool, time.Duration) {
+	rate := float64(budget.Calls) / budget.Per.Seconds()
+	b.Tokens = min(float64(budget.Calls), b.Tokens+now.Sub(b.Updated).Seconds()*rate)
+	b.Updated = now
+
+	if b.Tokens >= 1 {
+		b.Tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.Tokens) / rate * float64(time.Second))
+}
+
+// budgetKey is the storage key of the bucket of a caller for a tool.
+func budgetKey(toolName, caller string) string {
+	return "tool-budgets/" + toolName + "/" + caller
+}
+
+// ToolBudgets returns a tool middleware that rejects calls of a budgeted tool once a caller
+// used up its budget, as a backstop against agents that call destructive tools in a loop.
+// Every call counts, whether or not it succeeds. Callers are identified by the subject of their
+// OAuth token, or by their org for API tokens, so refreshing a token does not reset the budget,
+// and calls without either are rejected. Buckets are kept in the storage for the budget period,
+// after which they refilled and a full bucket is the same as none. Replicas sharing the storage
+// share the budgets, though concurrent calls on different replicas may each take the last call.
+func ToolBudgets(budgets map[string]ToolBudget, store storage.Interface) ToolMiddleware {
+	var mu sync.Mutex
+
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			budget, ok := budgets[request.Params.Name]
+			if !ok || budget.Calls <= 0 || budget.Per <= 0 {
+				return next(ctx, request)
+			}
+
+			caller, err := callerIdentity(ctx)
+			if err != nil {
+				return NewToolResultErrorCode(ErrCodeAuthFailed, fmt.Sprintf("%s is budgeted per caller and the caller could not be identified", request.Params.Name)), nil
+			}
+
+			mu.Lock()
+			allowed, retryAfter, err := takeBudget(ctx, store, budgetKey(request.Params.Name, caller), budget)
+			mu.Unlock()
+			if err != nil {
+				return nil, fmt.Errorf("failed to check the budget of %s: %w", request.Params.Name, err)
+			}
+
+			if !allowed {
+				return NewToolResultErrorCode(ErrCodeRateLimited, fmt.Sprintf("%s is over budget: at most %s are allowed. Try again in %s, and ask the user before retrying; repeated calls may indicate a loop.",
+					request.Params.Name, budget, retryAfter.Round(time.Second))), nil
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// takeBudget takes a call from the bucket stored under key, or returns how long until one is
+// available.
+func takeBudget(ctx context.Context, store storage.Interface, key string, budget ToolBudget) (bool, time.Duration, error) {
+	now := time.Now()
+	bucket := tokenBucket{Tokens: float64(budget.Calls), Updated: now}
+	data, err := store.Get(ctx, key)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &bucket); err != nil {
+			return false, 0, fmt.Errorf("failed to decode budget: %w", err)
+		}
+	case !errors.Is(err, storage.ErrNotFound):
+		return false, 0, err
+	}
+
+	allowed, retryAfter := bucket.take(budget, now)
+	if !allowed {
+		return false, retryAfter, nil
+	}
+
+	data, err = json.Marshal(bucket)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to marshal budget: %w", err)
+	}
+	if err := store.Set(ctx, key, data, budget.Per); err != nil {
+		return false, 0, err
+	}
+	return true, 0, nil
+}
@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/storage"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestToolBudgets(t *testing.T) {
+	handler := ToolBudgets(map[string]ToolBudget{"deploy_pipeline": {Calls: 1, Per: time.Hour}}, storage.NewMemory())(
+		func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText("deployed"), nil
+		})
+	call := func(ctx context.Context) *mcp.CallToolResult {
+		var request mcp.CallToolRequest
+		request.Params.Name = "deploy_pipeline"
+		result, err := handler(ctx, request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	oauth := func(subject, token string) context.Context {
+		ctx := context.WithValue(context.Background(), OrgIDKey, "org-1")
+		ctx = context.WithValue(ctx, SubjectKey, subject)
+		return context.WithValue(ctx, BearerTokenKey, token)
+	}
+
+	if result := call(oauth("alice", "token-1")); result.IsError {
+		t.Fatalf("first call was rejected: %v", result.Content)
+	}
+	// A refreshed token of the same subject does not get a fresh budget
+	if result := call(oauth("alice", "token-2")); !result.IsError {
+		t.Error("refreshed token got a fresh budget")
+	}
+	if result := call(oauth("bob", "token-3")); result.IsError {
+		t.Errorf("other subject was rejected: %v", result.Content)
+	}
+	// Calls that cannot be attributed to a caller are rejected, not let through
+	if result := call(context.Background()); !result.IsError {
+		t.Error("call without a caller skipped the budget")
+	}
+}
+
+func TestToolBudgetsSharedStorage(t *testing.T) {
+	store := storage.NewMemory()
+	budgets := map[string]ToolBudget{"deploy_pipeline": {Calls: 1, Per: time.Hour}}
+	deploy := func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("deployed"), nil
+	}
+	// Two replicas of the server sharing the storage
+	replicas := []func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error){
+		ToolBudgets(budgets, store)(deploy),
+		ToolBudgets(budgets, store)(deploy),
+	}
+
+	ctx := context.WithValue(context.Background(), OrgIDKey, "org-1")
+	ctx = context.WithValue(ctx, EDTokenKey, "token-1")
+	var request mcp.CallToolRequest
+	request.Params.Name = "deploy_pipeline"
+	for i, handler := range replicas {
+		result, err := handler(ctx, request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if over := result.IsError; over != (i > 0) {
+			t.Errorf("call on replica %d over budget: %v, want the budget shared between replicas", i, over)
+		}
+	}
+
+	keys, err := store.List(ctx, "tool-budgets/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 {
+		t.Errorf("storage has the buckets %v, want the bucket of the caller", keys)
+	}
+}
@@ -0,0 +1,163 @@
+package tools
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ArgumentCanonicalizer maps semantically identical tool calls to the same canonical form, for
+// use as result cache, deduplication and audit keys. Canonical arguments have:
+//   - keys sorted
+//   - strings trimmed and integral numbers written without a fraction
+//   - nil, empty and default valued arguments of the registered tool removed
+type ArgumentCanonicalizer struct {
+	mu       sync.RWMutex
+	defaults map[string]map[string]any
+}
+
+func NewArgumentCanonicalizer() *ArgumentCanonicalizer {
+	return &ArgumentCanonicalizer{defaults: make(map[string]map[string]any)}
+}
+
+// Register records the default argument values of the tool's input schema.
+func (c *ArgumentCanonicalizer) Register(tool mcp.Tool) {
+	defaults := make(map[string]any)
+	for name, property := range tool.InputSchema.Properties {
+		schema, ok := property.(map[string]any)
+		if !ok {
+			continue
+		}
+		if d, ok := schema["default"]; ok {
+			defaults[name] = canonicalValue(d)
+		}
+	}
+
+	c.mu.Lock()
+	c.defaults[tool.Name] = defaults
+	c.mu.Unlock()
+}
+
+// Canonical returns the canonical JSON encoding of the arguments of a call of the tool.
+func (c *ArgumentCanonicalizer) Canonical(toolName string, args map[string]any) ([]byte, error) {
+	c.mu.RLock()
+	defaults := c.defaults[toolName]
+	c.mu.RUnlock()
+
+	canonical := make(map[string]any, len(args))
+	for k, v := range args {
+		value := canonicalValue(v)
+		if value == nil {
+			continue
+		}
+		if d, ok := defaults[k]; ok && sameCanonicalValue(value, d) {
+			continue
+		}
+		canonical[k] = value
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(canonical); err != nil {
+		return nil, fmt.Errorf("failed to encode canonical arguments: %w", err)
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// Key returns a stable key identifying the tool and its canonical arguments.
+func (c *ArgumentCanonicalizer) Key(request mcp.CallToolRequest) (string, error) {
+	canonical, err := c.Canonical(request.Params.Name, request.GetArguments())
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(append([]byte(request.Params.Name+"\x00"), canonical...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// canonicalValue normalizes a decoded JSON value, returning nil for values that carry no
+// information: nil, blank strings and empty arrays or objects.
+func canonicalValue(v any) any {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string:
+		if s := strings.TrimSpace(v); s != "" {
+			return s
+		}
+		return nil
+	case float64:
+		return canonicalNumber(v)
+	case float32:
+		return canonicalNumber(float64(v))
+	case int:
+		return json.Number(strconv.Itoa(v))
+	case int64:
+		return json.Number(strconv.FormatInt(v, 10))
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return canonicalNumber(f)
+		}
+		return v
+	case []any:
+		out := make([]any, 0, len(v))
+		for _, item := range v {
+			if item = canonicalValue(item); item != nil {
+				out = append(out, item)
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return out
+	case []string:
+		items := make([]any, len(v))
+		for i, s := range v {
+			items[i] = s
+		}
+		return canonicalValue(items)
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			if item = canonicalValue(item); item != nil {
+				out[k] = item
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func canonicalNumber(f float64) json.Number {
+	if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		return json.Number(strconv.FormatInt(int64(f), 10))
+	}
+	return json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+}
+
+// sameCanonicalValue reports whether two canonical values are equal, treating a number and its
+// string form, e.g. a limit of 10 and "10", as equal.
+func sameCanonicalValue(a, b any) bool {
+	aBytes, errA := json.Marshal(a)
+	bBytes, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	if bytes.Equal(aBytes, bBytes) {
+		return true
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
@@ -0,0 +1,159 @@
+package tools
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/storage"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func canonicalRequest(name string, args map[string]any) mcp.CallToolRequest {
+	var request mcp.CallToolRequest
+	request.Params.Name = name
+	request.Params.Arguments = args
+	return request
+}
+
+func TestArgumentCanonicalizerKey(t *testing.T) {
+	canonicalizer := NewArgumentCanonicalizer()
+	canonicalizer.Register(mcp.NewTool("get_log_search",
+		mcp.WithString("query"),
+		mcp.WithString("lookback", mcp.DefaultString("15m")),
+		mcp.WithNumber("limit", mcp.DefaultNumber(10)),
+	))
+
+	base, err := canonicalizer.Key(canonicalRequest("get_log_search", map[string]any{"query": "error"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	same := []map[string]any{
+		{"query": " error "},
+		{"query": "error", "lookback": "15m"},
+		{"query": "error", "limit": float64(10)},
+		{"query": "error", "cursor": "", "tags": []any{}},
+	}
+	for _, args := range same {
+		key, err := canonicalizer.Key(canonicalRequest("get_log_search", args))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != base {
+			t.Errorf("key of %v differs from the key of the same call", args)
+		}
+	}
+
+	other, err := canonicalizer.Key(canonicalRequest("get_log_search", map[string]any{"query": "error", "limit": float64(20)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == base {
+		t.Error("calls with different limits have the same key")
+	}
+}
+
+func TestRecordQueriesDeduplicatesCanonicalQueries(t *testing.T) {
+	canonicalizer := NewArgumentCanonicalizer()
+	canonicalizer.Register(mcp.NewTool("get_log_search", mcp.WithString("query"), mcp.WithString("scope", mcp.DefaultString("log"))))
+	history, err := NewQueryHistory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := RecordQueries(history, canonicalizer)(func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	})
+	ctx := context.WithValue(context.Background(), OrgIDKey, "org-1")
+	ctx = context.WithValue(ctx, EDTokenKey, "token-1")
+
+	for _, args := range []map[string]any{
+		{"query": "error", "lookback": "1h"},
+		{"query": " error", "scope": "log", "lookback": "24h"},
+	} {
+		if _, err := handler(ctx, canonicalRequest("get_log_search", args)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	userKey, err := callerIdentity(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	queries, err := history.Recent(ctx, userKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queries) != 1 {
+		t.Fatalf("history has %d queries, want the query once: %+v", len(queries), queries)
+	}
+	if queries[0].Query != "error" || queries[0].Lookback != "24h" {
+		t.Errorf("history has %+v, want the last run of the query", queries[0])
+	}
+}
+
+func TestSchemaCacheCanonicalQuery(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		_, _ = io.WriteString(w, `{"keys":[]}`)
+	}))
+	defer srv.Close()
+
+	cache := NewSchemaCache(NewHTTPClient(srv.URL, "X-ED-API-Token", WithRateLimits(RateLimits{})), time.Minute, NewArgumentCanonicalizer(), storage.NewMemory())
+	ctx := context.WithValue(context.Background(), OrgIDKey, "org-1")
+	ctx = schemaRequest(context.WithValue(ctx, EDTokenKey, "token-1"))
+
+	for _, query := range []string{"scope=log&lookback=1h", "lookback=1h&scope=log", "lookback=1h&scope=log&cursor="} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/v1/orgs/org-1/facet_keys?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := cache.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if !strings.Contains(string(body), "keys") {
+			t.Fatalf("response to %s is %q", query, body)
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("API got %d requests, want the equivalent requests served from the cache", n)
+	}
+}
+
+func TestRecordQueriesKeepsHistoryAcrossTokens(t *testing.T) {
+	history, err := NewQueryHistory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := RecordQueries(history, NewArgumentCanonicalizer())(func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	})
+	read := RecentQueriesResourceHandler(history)
+	oauth := func(token string) context.Context {
+		ctx := context.WithValue(context.Background(), OrgIDKey, "org-1")
+		ctx = context.WithValue(ctx, SubjectKey, "alice")
+		return context.WithValue(ctx, BearerTokenKey, token)
+	}
+
+	if _, err := handler(oauth("token-1"), canonicalRequest("get_log_search", map[string]any{"query": "error"})); err != nil {
+		t.Fatal(err)
+	}
+	// The refreshed token of the same subject reads the history
+	var request mcp.ReadResourceRequest
+	request.Params.URI = RecentQueriesResource.URI
+	contents, err := read(oauth("token-2"), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := contents[0].(mcp.TextResourceContents).Text; !strings.Contains(text, `"query":"error"`) {
+		t.Errorf("history after a token refresh is %s, want the query", text)
+	}
+}
@@ -0,0 +1,167 @@
+package tools
+
+import (
+	"maps"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ClassificationMetaKey is the _meta key of the tool definitions holding their ToolClassification.
+const ClassificationMetaKey = "com.edgedelta/classification"
+
+// Tool categories.
+const (
+	CategorySearch   = "search"
+	CategoryPipeline = "pipeline"
+	CategoryAdmin    = "admin"
+)
+
+// Data access of the tools.
+const (
+	DataAccessRead  = "read"
+	DataAccessWrite = "write"
+)
+
+// PII risk of the tool results.
+const (
+	PIIRiskLow  = "low"
+	PIIRiskHigh = "high"
+)
+
+// ToolClassification describes a tool for the policy engines of MCP gateways, e.g. to block the
+// write tools for some users, without keeping their own list of the tools.
+type ToolClassification struct {
+	// Category is search for the tools querying telemetry and its views, pipeline for the
+	// pipelines and agents, admin for the configuration of the organization.
+	Category string `json:"category"`
+	// DataAccess is write for the tools changing anything in Edge Delta.
+	DataAccess string `json:"data_access"`
+	// PIIRisk is high for the tools returning raw telemetry, members or credentials.
+	PIIRisk string `json:"pii_risk"`
+}
+
+// ToolCategories are the categories and PII risks of the tools. Their data access follows their
+// read only hint.
+var ToolCategories = map[string]ToolClassification{
+	// Discovery and query building
+	"discover_schema":    {Category: CategorySearch, PIIRisk: PIIRiskLow},
+	"search_metrics":     {Category: CategorySearch, PIIRisk: PIIRiskLow},
+	"validate_cql":       {Category: CategorySearch, PIIRisk: PIIRiskLow},
+	"build_cql":          {Category: CategorySearch, PIIRisk: PIIRiskLow},
+	"resolve_k8s_entity": {Category: CategorySearch, PIIRisk: PIIRiskLow},
+
+	// Pipelines and agents
+	"get_pipelines":               {Category: CategoryPipeline, PIIRisk: PIIRiskLow},
+	"get_pipeline_config":         {Category: CategoryPipeline, PIIRisk: PIIRiskLow},
+	"get_pipeline_history":        {Category: CategoryPipeline, PIIRisk: PIIRiskLow},
+	"deploy_pipeline":             {Category: CategoryPipeline, PIIRisk: PIIRiskLow},
+	"add_pipeline_source":         {Category: CategoryPipeline, PIIRisk: PIIRiskLow},
+	"remove_pipeline_node":        {Category: CategoryPipeline, PIIRisk: PIIRiskLow},
+	"update_pipeline_node":        {Category: CategoryPipeline, PIIRisk: PIIRiskLow},
+	"connect_pipeline_nodes":      {Category: CategoryPipeline, PIIRisk: PIIRiskLow},
+	"save_pipeline":               {Category: CategoryPipeline, PIIRisk: PIIRiskLow},
+	"validate_pipeline":           {Category: CategoryPipeline, PIIRisk: PIIRiskLow},
+	"analyze_pipeline_complexity": {Category: CategoryPipeline, PIIRisk: PIIRiskLow},
+	"send_test_logs":              {Category: CategoryPipeline, PIIRisk: PIIRiskLow},
+	"get_fleet_agents":            {Category: CategoryPipeline, PIIRisk: PIIRiskLow},
+	"get_agent_status":            {Category: CategoryPipeline, PIIRisk: PIIRiskLow},
+	"get_agent_self_logs":         {Category: CategoryPipeline, PIIRisk: PIIRiskLow},
+	"get_ingest_lag":              {Category: CategoryPipeline, PIIRisk: PIIRiskLow},
+	"list_archive_objects":        {Category: CategoryPipeline, PIIRisk: PIIRiskLow},
+	// The endpoint comes with its ingestion token
+	"get_ingestion_endpoint": {Category: CategoryPipeline, PIIRisk: PIIRiskHigh},
+
+	// Facets, facet options hold the values of the fields, e.g. user ids
+	"facets":        {Category: CategorySearch, PIIRisk: PIIRiskLow},
+	"facet_options": {Category: CategorySearch, PIIRisk: PIIRiskHigh},
+	"top_values":    {Category: CategorySearch, PIIRisk: PIIRiskHigh},
+	"create_facet":  {Category: CategorySearch, PIIRisk: PIIRiskLow},
+	"delete_facet":  {Category: CategorySearch, PIIRisk: PIIRiskLow},
+
+	// Searches returning the records
+	"get_log_search":        {Category: CategorySearch, PIIRisk: PIIRiskHigh},
+	"tail_logs":             {Category: CategorySearch, PIIRisk: PIIRiskHigh},
+	"refresh_search":        {Category: CategorySearch, PIIRisk: PIIRiskHigh},
+	"get_trace_timeline":    {Category: CategorySearch, PIIRisk: PIIRiskHigh},
+	"get_trace_error_chain": {Category: CategorySearch, PIIRisk: PIIRiskHigh},
+	"get_trace_by_id":       {Category: CategorySearch, PIIRisk: PIIRiskHigh},
+	"get_service_map":       {Category: CategorySearch, PIIRisk: PIIRiskLow},
+	"get_span_events":       {Category: CategorySearch, PIIRisk: PIIRiskHigh},
+	"get_event_search":      {Category: CategorySearch, PIIRisk: PIIRiskHigh},
+	"get_log_patterns":      {Category: CategorySearch, PIIRisk: PIIRiskHigh},
+	"anomaly_search":        {Category: CategorySearch, PIIRisk: PIIRiskHigh},
+	"detect_anomalies":      {Category: CategorySearch, PIIRisk: PIIRiskHigh},
+	"investigate_window":    {Category: CategorySearch, PIIRisk: PIIRiskHigh},
+	"compare_windows":       {Category: CategorySearch, PIIRisk: PIIRiskHigh},
+	// Searches returning aggregates
+	"get_metric_search":   {Category: CategorySearch, PIIRisk: PIIRiskLow},
+	"get_sentiment_trend": {Category: CategorySearch, PIIRisk: PIIRiskLow},
+	"compare_services":    {Category: CategorySearch, PIIRisk: PIIRiskLow},
+	"get_log_graph":       {Category: CategorySearch, PIIRisk: PIIRiskLow},
+	"get_metric_graph":    {Category: CategorySearch, PIIRisk: PIIRiskLow},
+	"get_trace_graph":     {Category: CategorySearch, PIIRisk: PIIRiskLow},
+	"get_pattern_graph":   {Category: CategorySearch, PIIRisk: PIIRiskLow},
+	"get_graph_multi":     {Category: CategorySearch, PIIRisk: PIIRiskLow},
+	"get_log_histogram":   {Category: CategorySearch, PIIRisk: PIIRiskLow},
+
+	// Dashboards, rendered widgets may list records
+	"get_all_dashboards":      {Category: CategorySearch, PIIRisk: PIIRiskLow},
+	"get_dashboard":           {Category: CategorySearch, PIIRisk: PIIRiskLow},
+	"render_dashboard":        {Category: CategorySearch, PIIRisk: PIIRiskHigh},
+	"create_dashboard":        {Category: CategorySearch, PIIRisk: PIIRiskLow},
+	"update_dashboard_widget": {Category: CategorySearch, PIIRisk: PIIRiskLow},
+
+	// Monitors and notifications
+	"list_monitors":                  {Category: CategoryAdmin, PIIRisk: PIIRiskLow},
+	"get_monitor":                    {Category: CategoryAdmin, PIIRisk: PIIRiskLow},
+	"create_monitor":                 {Category: CategoryAdmin, PIIRisk: PIIRiskLow},
+	"create_monitor_from_pattern":    {Category: CategoryAdmin, PIIRisk: PIIRiskLow},
+	"update_monitor":                 {Category: CategoryAdmin, PIIRisk: PIIRiskLow},
+	"mute_monitor":                   {Category: CategoryAdmin, PIIRisk: PIIRiskLow},
+	"unmute_monitor":                 {Category: CategoryAdmin, PIIRisk: PIIRiskLow},
+	"simulate_monitor":               {Category: CategoryAdmin, PIIRisk: PIIRiskLow},
+	"list_notification_integrations": {Category: CategoryAdmin, PIIRisk: PIIRiskLow},
+	"list_notification_routes":       {Category: CategoryAdmin, PIIRisk: PIIRiskLow},
+
+	// Organizations and access
+	"list_orgs":          {Category: CategoryAdmin, PIIRisk: PIIRiskLow},
+	"list_child_orgs":    {Category: CategoryAdmin, PIIRisk: PIIRiskLow},
+	"get_fleet_overview": {Category: CategoryAdmin, PIIRisk: PIIRiskLow},
+	"list_org_members":   {Category: CategoryAdmin, PIIRisk: PIIRiskHigh},
+	"list_api_keys":      {Category: CategoryAdmin, PIIRisk: PIIRiskHigh},
+	// Tickets are posted to the ticket system configured for the server
+	"create_incident_ticket": {Category: CategoryAdmin, PIIRisk: PIIRiskLow},
+
+	// Sessions hold the results of the calls
+	"export_session_snapshot": {Category: CategorySearch, PIIRisk: PIIRiskHigh},
+	"import_session_snapshot": {Category: CategorySearch, PIIRisk: PIIRiskHigh},
+	"resolve_pseudonym":       {Category: CategorySearch, PIIRisk: PIIRiskHigh},
+}
+
+// ClassifyTool returns the classification of the tool. Tools missing from ToolCategories are
+// classified as admin tools with a high PII risk, so policies fail closed; ok is false then.
+func ClassifyTool(tool mcp.Tool) (classification ToolClassification, ok bool) {
+	classification, ok = ToolCategories[tool.Name]
+	if !ok {
+		classification = ToolClassification{Category: CategoryAdmin, PIIRisk: PIIRiskHigh}
+	}
+
+	classification.DataAccess = DataAccessWrite
+	if hint := tool.Annotations.ReadOnlyHint; hint != nil && *hint {
+		classification.DataAccess = DataAccessRead
+	}
+	return classification, ok
+}
+
+// WithClassification returns the tool with its classification in the _meta of its definition,
+// under ClassificationMetaKey.
+func WithClassification(tool mcp.Tool, classification ToolClassification) mcp.Tool {
+	meta := &mcp.Meta{AdditionalFields: make(map[string]any)}
+	if tool.Meta != nil {
+		meta.ProgressToken = tool.Meta.ProgressToken
+		maps.Copy(meta.AdditionalFields, tool.Meta.AdditionalFields)
+	}
+	meta.AdditionalFields[ClassificationMetaKey] = classification
+	tool.Meta = meta
+	return tool
+}
@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/json"
@@ -42,13 +43,18 @@ var (
 			},
 		}
 
-		return &http.Client{Transport: t}
+		return &http.Client{Transport: t, Timeout: DefaultHTTPTimeout}
 	}
 )
 
+// DefaultHTTPTimeout bounds each attempt of the requests of HTTP clients. It is a backstop for
+// requests made without a deadline; tool calls have their own, see ToolDeadlines.
+const DefaultHTTPTimeout = 5 * time.Minute
+
 type authedTransport struct {
 	http.Transport
 	apiTokenHeader string
+	userAgent      string
 }
 
 func (t *authedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
@@ -59,6 +65,14 @@ func (t *authedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 		req.Header.Set("X-ED-API-Token", edToken)
 	}
 
+	if t.userAgent != "" && req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+
+	if locale, _ := ctx.Value(LocaleKey).(string); locale != "" && req.Header.Get("Accept-Language") == "" {
+		req.Header.Set("Accept-Language", locale)
+	}
+
 	return t.Transport.RoundTrip(req)
 }
 
@@ -75,22 +89,97 @@ type HTTPClient struct {
 	cl             *http.Client
 	apiTokenHeader string
 	apiURL         string
+	retryPolicy    RetryPolicy
+	limiter        *rateLimiter
+}
+
+// HTTPClientOption configures an HTTPClient
+type HTTPClientOption func(*HTTPClient)
+
+// WithUserAgent sets the User-Agent of requests that do not set one
+func WithUserAgent(userAgent string) HTTPClientOption {
+	return func(c *HTTPClient) {
+		if t, ok := c.cl.Transport.(*authedTransport); ok {
+			t.userAgent = userAgent
+		}
+	}
 }
 
-func NewHTTPClient(apiURL, apiTokenHeader string) *HTTPClient {
-	return &HTTPClient{
+// WithHTTPTimeout sets the timeout of each attempt of a request, DefaultHTTPTimeout by default.
+// Zero means no timeout.
+func WithHTTPTimeout(timeout time.Duration) HTTPClientOption {
+	return func(c *HTTPClient) {
+		c.cl.Timeout = timeout
+	}
+}
+
+// WithMaxIdleConns sets the maximum number of idle connections kept for reuse, 256 by default.
+// The idle connections per host are capped to it as well.
+func WithMaxIdleConns(n int) HTTPClientOption {
+	return WithTransport(func(t *http.Transport) {
+		t.MaxIdleConns = n
+		t.MaxIdleConnsPerHost = min(t.MaxIdleConnsPerHost, n)
+	})
+}
+
+// WithTransport tunes the transport of the client, e.g. its dial and TLS settings. The
+// authentication of the requests is kept. Apply it before WithMetrics and WithTracing.
+func WithTransport(tune func(*http.Transport)) HTTPClientOption {
+	return func(c *HTTPClient) {
+		if t, ok := c.cl.Transport.(*authedTransport); ok {
+			tune(&t.Transport)
+		}
+	}
+}
+
+// ParseTLSVersion parses a TLS version, e.g. 1.2 or 1.3.
+func ParseTLSVersion(s string) (uint16, error) {
+	switch strings.TrimSpace(s) {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("invalid TLS version %q, expected 1.2 or 1.3", s)
+	}
+}
+
+func NewHTTPClient(apiURL, apiTokenHeader string, opts ...HTTPClientOption) *HTTPClient {
+	c := &HTTPClient{
 		cl:             newHTTPClientFunc(apiTokenHeader),
 		apiURL:         apiURL,
 		apiTokenHeader: apiTokenHeader,
+		retryPolicy:    DefaultRetryPolicy,
+		limiter:        newRateLimiter(DefaultRateLimits),
+	}
+
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
 }
 
+// Do sends the request, retrying it on transient errors according to the retry policy. Every
+// attempt waits for the rate limits of the client.
 func (c *HTTPClient) Do(req *http.Request) (*http.Response, error) {
-	return c.cl.Do(req)
+	return c.retryPolicy.do(req, func(req *http.Request) (*http.Response, error) {
+		if err := c.limiter.wait(req); err != nil {
+			return nil, err
+		}
+		return c.cl.Do(req)
+	})
 }
 
 func (c *HTTPClient) Get(url string) (*http.Response, error) {
-	return c.cl.Get(url)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
 }
 
 func (c *HTTPClient) APIURL() string {
@@ -218,12 +307,39 @@ func GetPipelines(ctx context.Context, client Client, opts ...QueryParamOption)
 	return returnPipelines, nil
 }
 
-func SavePipeline(ctx context.Context, client Client, confID, description, pipeline, content string) (map[string]any, error) {
+// PipelineConflictError is returned when a pipeline was saved by someone else since the
+// version the caller based its changes on.
+type PipelineConflictError struct {
+	ConfID         string
+	BaseVersion    string
+	CurrentVersion string
+}
+
+func (e *PipelineConflictError) Error() string {
+	return fmt.Sprintf("pipeline %s was changed concurrently, base version %s, current version %s", e.ConfID, e.BaseVersion, e.CurrentVersion)
+}
+
+// SavePipeline saves a new pipeline version. When baseVersion is set, the save is rejected with a
+// *PipelineConflictError if the latest version of the pipeline is not baseVersion anymore.
+// The version is checked before saving and sent as If-Match so the backend can reject races
+// between the check and the save.
+func SavePipeline(ctx context.Context, client Client, confID, description, pipeline, content, baseVersion string) (map[string]any, error) {
 	keys, err := FetchContextKeys(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	if baseVersion != "" {
+		current, err := GetLatestPipelineVersion(ctx, client, confID)
+		if err != nil {
+			return nil, err
+		}
+
+		if current != baseVersion {
+			return nil, &PipelineConflictError{ConfID: confID, BaseVersion: baseVersion, CurrentVersion: current}
+		}
+	}
+
 	saveURL, err := url.Parse(fmt.Sprintf("%s/v1/orgs/%s/pipelines/%s/save", client.APIURL(), keys.OrgID, confID))
 	if err != nil {
 		return nil, err
@@ -256,6 +372,9 @@ func SavePipeline(ctx context.Context, client Client, confID, description, pipel
 	}
 
 	req.Header.Add("Content-Type", "application/json")
+	if baseVersion != "" {
+		req.Header.Add("If-Match", baseVersion)
+	}
 	applyAuthHeader(req, keys)
 
 	resp, err := client.Do(req)
@@ -264,6 +383,14 @@ func SavePipeline(ctx context.Context, client Client, confID, description, pipel
 	}
 
 	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusConflict || resp.StatusCode == http.StatusPreconditionFailed {
+		current, err := GetLatestPipelineVersion(ctx, client, confID)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &PipelineConflictError{ConfID: confID, BaseVersion: baseVersion, CurrentVersion: current}
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		return nil, fmt.Errorf("failed to save pipeline, status code %d: %s", resp.StatusCode, string(bodyBytes))
@@ -277,7 +404,163 @@ func SavePipeline(ctx context.Context, client Client, confID, description, pipel
 	return result, nil
 }
 
+// PipelineValidationIssue is an error or warning found in a pipeline configuration.
+type PipelineValidationIssue struct {
+	Message string `json:"message"`
+	Path    string `json:"path,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Node    string `json:"node,omitempty"`
+}
+
+// PipelineValidationResult mirrors the backend response from POST /v1/orgs/{org_id}/pipelines/{conf_id}/validate
+type PipelineValidationResult struct {
+	Valid    bool                      `json:"valid"`
+	Errors   []PipelineValidationIssue `json:"errors"`
+	Warnings []PipelineValidationIssue `json:"warnings"`
+}
+
+// ValidatePipeline validates a pipeline configuration, YAML or JSON, against the pipeline's
+// fleet without saving or deploying it.
+func ValidatePipeline(ctx context.Context, client Client, confID, content string) (*PipelineValidationResult, error) {
+	keys, err := FetchContextKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	validateURL, err := url.Parse(fmt.Sprintf("%s/v1/orgs/%s/pipelines/%s/validate", client.APIURL(), keys.OrgID, confID))
+	if err != nil {
+		return nil, err
+	}
+
+	payloadBytes, err := json.Marshal(map[string]any{"content": content})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, validateURL.String(), bytes.NewReader(payloadBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create validate pipeline request: %v", err)
+	}
+
+	req.Header.Add("Content-Type", "application/json")
+	applyAuthHeader(req, keys)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	// An invalid configuration is reported with 422 and the same body as a valid one
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusUnprocessableEntity {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to validate pipeline, status code %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var result PipelineValidationResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode validate pipeline response: %v", err)
+	}
+
+	return &result, nil
+}
+
+// pipelineHistoryEntry is a version of a pipeline. Content is the configuration saved in the
+// version, when the backend includes it.
+type pipelineHistoryEntry struct {
+	Timestamp json.Number `json:"timestamp"`
+	Content   string      `json:"content,omitempty"`
+}
+
+func getPipelineHistory(ctx context.Context, client Client, confID string) ([]pipelineHistoryEntry, error) {
+	keys, err := FetchContextKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	historyURL, err := url.Parse(fmt.Sprintf("%s/v1/orgs/%s/pipelines/%s/history", client.APIURL(), keys.OrgID, confID))
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := createRequest(ctx, historyURL, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pipeline history request: %v", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to get pipeline history, status code %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var history []pipelineHistoryEntry
+	if err := json.NewDecoder(resp.Body).Decode(&history); err != nil {
+		return nil, fmt.Errorf("failed to decode pipeline history response: %v", err)
+	}
+	return history, nil
+}
+
+// GetLatestPipelineVersion returns the timestamp of the most recent history entry of a pipeline,
+// which is the version deploy_pipeline and save conflict checks use.
+func GetLatestPipelineVersion(ctx context.Context, client Client, confID string) (string, error) {
+	history, err := getPipelineHistory(ctx, client, confID)
+	if err != nil {
+		return "", err
+	}
+
+	var latest int64
+	for _, entry := range history {
+		if ts, err := entry.Timestamp.Int64(); err == nil && ts > latest {
+			latest = ts
+		}
+	}
+
+	if latest == 0 {
+		return "", nil
+	}
+	return strconv.FormatInt(latest, 10), nil
+}
+
+// GetPipelineVersionContent returns the configuration saved in a version of a pipeline, from the
+// history entry whose timestamp is version.
+func GetPipelineVersionContent(ctx context.Context, client Client, confID, version string) (string, error) {
+	history, err := getPipelineHistory(ctx, client, confID)
+	if err != nil {
+		return "", err
+	}
+
+	for _, entry := range history {
+		if entry.Timestamp.String() != version {
+			continue
+		}
+		if entry.Content == "" {
+			return "", fmt.Errorf("pipeline history has no content for version %s", version)
+		}
+		return entry.Content, nil
+	}
+	return "", fmt.Errorf("version %s not found in the pipeline history", version)
+}
+
 func GetFacets(ctx context.Context, client Client, opts ...QueryParamOption) ([]Facet, error) {
+	response, err := getFacetsResponse(schemaRequest(ctx), client, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	facets := make([]Facet, 0, len(response.Builtin)+len(response.UserDefined))
+	facets = append(facets, response.Builtin...)
+	facets = append(facets, response.UserDefined...)
+
+	return facets, nil
+}
+
+func getFacetsResponse(ctx context.Context, client Client, opts ...QueryParamOption) (*FacetsResponse, error) {
 	keys, err := FetchContextKeys(ctx)
 	if err != nil {
 		return nil, err
@@ -309,11 +592,118 @@ func GetFacets(ctx context.Context, client Client, opts ...QueryParamOption) ([]
 		return nil, fmt.Errorf("failed to decode facets response: %v", err)
 	}
 
-	facets := make([]Facet, 0, len(response.Builtin)+len(response.UserDefined))
-	facets = append(facets, response.Builtin...)
-	facets = append(facets, response.UserDefined...)
+	return &response, nil
+}
 
-	return facets, nil
+// CreateFacet creates a user-defined facet. Facets that already exist, either builtin or user-defined, are rejected.
+func CreateFacet(ctx context.Context, client Client, facet Facet) (*Facet, error) {
+	existing, err := getFacetsResponse(ctx, client, WithScope(facet.Scope))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range append(existing.Builtin, existing.UserDefined...) {
+		if f.Path == facet.Path {
+			return nil, fmt.Errorf("facet %q already exists for scope %s", facet.Path, facet.Scope)
+		}
+	}
+
+	keys, err := FetchContextKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	facetsURL := fmt.Sprintf("%s/v1/orgs/%s/facets", client.APIURL(), keys.OrgID)
+	payloadBytes, err := json.Marshal(facet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, facetsURL, bytes.NewReader(payloadBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create facet request: %v", err)
+	}
+
+	req.Header.Add("Content-Type", "application/json")
+	applyAuthHeader(req, keys)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %v", err)
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to create facet, status code %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var created Facet
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return nil, fmt.Errorf("failed to decode create facet response: %v", err)
+	}
+
+	return &created, nil
+}
+
+// DeleteFacet deletes a user-defined facet. Builtin facets can not be deleted.
+func DeleteFacet(ctx context.Context, client Client, scope, path string) error {
+	existing, err := getFacetsResponse(ctx, client, WithScope(scope))
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, f := range existing.Builtin {
+		if f.Path == path {
+			return fmt.Errorf("facet %q is a builtin facet and can not be deleted", path)
+		}
+	}
+	for _, f := range existing.UserDefined {
+		if f.Path == path {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("user-defined facet %q not found for scope %s", path, scope)
+	}
+
+	keys, err := FetchContextKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	facetsURL, err := url.Parse(fmt.Sprintf("%s/v1/orgs/%s/facets", client.APIURL(), keys.OrgID))
+	if err != nil {
+		return err
+	}
+
+	queryValues := url.Values{}
+	WithScope(scope)(queryValues)
+	WithFacet(path)(queryValues)
+	facetsURL.RawQuery = queryValues.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, facetsURL.String(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create delete facet request: %v", err)
+	}
+
+	req.Header.Add("Content-Type", "application/json")
+	applyAuthHeader(req, keys)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %v", err)
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to delete facet, status code %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	return nil
 }
 
 func GetFacetOptions(ctx context.Context, client Client, opts ...QueryParamOption) (*Facet, error) {
@@ -327,7 +717,7 @@ func GetFacetOptions(ctx context.Context, client Client, opts ...QueryParamOptio
 		return nil, err
 	}
 
-	req, err := createRequest(ctx, facetURL, keys, opts...)
+	req, err := createRequest(schemaRequest(ctx), facetURL, keys, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create facet options request: %v", err)
 	}
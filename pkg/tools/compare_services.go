@@ -0,0 +1,330 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// comparePatternLimit is the number of patterns fetched per service to find the unique ones.
+	comparePatternLimit = "200"
+	// compareUniquePatterns is the number of unique patterns returned per service.
+	compareUniquePatterns = 5
+	// compareErrorQuery selects the error logs of a service.
+	compareErrorQuery = `severity_text:("ERROR" OR "FATAL")`
+)
+
+type CompareServicesResult struct {
+	Window   string                  `json:"window"`
+	ServiceA ServiceComparison       `json:"service_a"`
+	ServiceB ServiceComparison       `json:"service_b"`
+	Metrics  []ServiceMetricRelation `json:"comparison"`
+	Warnings []GraphWarning          `json:"warnings,omitempty"`
+	Stats    *QueryStats             `json:"stats,omitempty"`
+	Guidance *SearchGuidance         `json:"guidance,omitempty"`
+}
+
+// ServiceComparison is one side of a comparison of two services.
+type ServiceComparison struct {
+	Service   string  `json:"service"`
+	LogVolume int64   `json:"log_volume"`
+	Errors    int64   `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+	// Latency holds the span latency percentiles of the service, e.g. p50 and p95, if it has traces
+	Latency map[string]float64 `json:"latency,omitempty"`
+	// UniquePatterns are the top log patterns of the service the other service does not have
+	UniquePatterns []ComparedPattern `json:"unique_patterns"`
+}
+
+type ComparedPattern struct {
+	Pattern   string `json:"pattern"`
+	Count     int    `json:"count"`
+	Sentiment string `json:"sentiment,omitempty"`
+}
+
+// ServiceMetricRelation is how a metric of service B relates to service A.
+type ServiceMetricRelation struct {
+	Metric string  `json:"metric"`
+	A      float64 `json:"a"`
+	B      float64 `json:"b"`
+	// Ratio is B / A, omitted when A is zero
+	Ratio float64 `json:"ratio,omitempty"`
+}
+
+// compareFormulas are the graph formulas of a service comparison, by service side and metric.
+var compareFormulas = map[string]string{
+	"A_logs": "QA1", "A_errors": "QA2", "A_latency": "QA3",
+	"B_logs": "QB1", "B_errors": "QB2", "B_latency": "QB3",
+}
+
+// CompareServicesTool creates a tool comparing two services side by side
+func CompareServicesTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("compare_services",
+			mcp.WithTitleAnnotation("Compare Services"),
+			mcp.WithDescription(`Compares two services side by side over the same window: log volume, error count and rate, span latency percentiles, and the top log patterns each service has that the other does not.
+
+Use this tool when a canary, a new version or a new region behaves differently from its baseline, e.g. service_a: "checkout" and service_b: "checkout-canary", instead of running the same searches twice and comparing by hand.
+
+The comparison lists the ratio of each metric of service B to service A. Use get_log_search on the unique patterns to see their logs.`),
+			mcp.WithString("service_a",
+				mcp.Description("service.name of the baseline service."),
+				mcp.Required(),
+			),
+			mcp.WithString("service_b",
+				mcp.Description("service.name of the service compared to the baseline, e.g. the canary."),
+				mcp.Required(),
+			),
+			mcp.WithString("window",
+				mcp.Description("Time window ending now, in Go duration format, or days and weeks (e.g., 1h, 24h, 7d, 1w)."),
+				mcp.DefaultString("1h"),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			serviceA, err := request.RequireString("service_a")
+			if err != nil || strings.TrimSpace(serviceA) == "" {
+				return mcp.NewToolResultError("missing required parameter: service_a"), nil
+			}
+			serviceB, err := request.RequireString("service_b")
+			if err != nil || strings.TrimSpace(serviceB) == "" {
+				return mcp.NewToolResultError("missing required parameter: service_b"), nil
+			}
+			serviceA, serviceB = strings.TrimSpace(serviceA), strings.TrimSpace(serviceB)
+			if serviceA == serviceB {
+				return mcp.NewToolResultError("service_a and service_b must be different services"), nil
+			}
+
+			window, _ := params.Optional[string](request, "window")
+			if window == "" {
+				window = "1h"
+			}
+			if d, err := ParseLookback(window); err != nil || d <= 0 {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid window %q, expected a duration such as 1h or 7d", window)), nil
+			}
+
+			result := CompareServicesResult{
+				Window:   window,
+				ServiceA: ServiceComparison{Service: serviceA},
+				ServiceB: ServiceComparison{Service: serviceB},
+			}
+
+			if err := compareServiceGraphs(ctx, client, &result); err != nil {
+				return nil, err
+			}
+
+			patternsA, err := servicePatterns(ctx, client, serviceA, window)
+			if err != nil {
+				return nil, err
+			}
+			patternsB, err := servicePatterns(ctx, client, serviceB, window)
+			if err != nil {
+				return nil, err
+			}
+			result.ServiceA.UniquePatterns = uniquePatterns(patternsA, patternsB)
+			result.ServiceB.UniquePatterns = uniquePatterns(patternsB, patternsA)
+
+			result.Metrics = serviceMetricRelations(result.ServiceA, result.ServiceB)
+			result.Guidance = compareServicesGuidance(result)
+
+			r, err := json.Marshal(result)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
+
+// compareServiceGraphs fetches the log volumes, error counts and latencies of both services in
+// one graph request.
+func compareServiceGraphs(ctx context.Context, client Client, result *CompareServicesResult) error {
+	builder := NewGraphQueryBuilder()
+	for side, service := range map[string]string{"A": result.ServiceA.Service, "B": result.ServiceB.Service} {
+		filter := fmt.Sprintf(`service.name:"%s"`, escapeValue(service))
+		builder.
+			WithQuery("Q"+side+"1", GraphQuery{Scope: "log", Query: filter}).
+			WithQuery("Q"+side+"2", GraphQuery{Scope: "log", Query: filter + " AND " + compareErrorQuery}).
+			WithQuery("Q"+side+"3", GraphQuery{Scope: "trace", Query: filter, DataType: "latency", Options: map[string]any{"includeChildSpans": false}})
+	}
+	for name, formula := range compareFormulas {
+		builder.WithFormula(name, formula)
+	}
+	payload, err := builder.Build()
+	if err != nil {
+		return err
+	}
+
+	queryParams := url.Values{}
+	queryParams.Add("lookback", result.Window)
+	queryParams.Add("graph_type", "table")
+
+	statusCode, bodyBytes, warnings, err := postGraphWithRetry(ctx, client, payload, queryParams)
+	if err != nil {
+		return err
+	}
+	if statusCode != http.StatusMultiStatus {
+		return fmt.Errorf("failed to graph services, status code %d: %s", statusCode, string(bodyBytes))
+	}
+	result.Warnings = warnings
+	result.Stats = parseQueryStats(bodyBytes)
+
+	var resp map[string]struct {
+		Records []struct {
+			Values    []string `json:"values"`
+			Aggregate struct {
+				Value float64 `json:"value"`
+			} `json:"aggregate"`
+		} `json:"records"`
+	}
+	if err := json.Unmarshal(bodyBytes, &resp); err != nil {
+		return fmt.Errorf("failed to decode graph response: %v", err)
+	}
+
+	for side, comparison := range map[string]*ServiceComparison{"A": &result.ServiceA, "B": &result.ServiceB} {
+		for _, record := range resp[side+"_logs"].Records {
+			comparison.LogVolume += int64(record.Aggregate.Value)
+		}
+		for _, record := range resp[side+"_errors"].Records {
+			comparison.Errors += int64(record.Aggregate.Value)
+		}
+		if comparison.LogVolume > 0 {
+			comparison.ErrorRate = roundProportion(float64(comparison.Errors) / float64(comparison.LogVolume))
+		}
+
+		for _, record := range resp[side+"_latency"].Records {
+			if comparison.Latency == nil {
+				comparison.Latency = make(map[string]float64)
+			}
+			label := strings.ToLower(strings.Join(record.Values, ","))
+			if label == "" {
+				label = "value"
+			}
+			comparison.Latency[label] = record.Aggregate.Value
+		}
+	}
+	return nil
+}
+
+// servicePatterns returns the log patterns of the service in the window.
+func servicePatterns(ctx context.Context, client Client, service, window string) ([]ComparedPattern, error) {
+	queryParams := url.Values{}
+	queryParams.Add("query", fmt.Sprintf(`service.name:"%s"`, escapeValue(service)))
+	queryParams.Add("lookback", window)
+	queryParams.Add("limit", comparePatternLimit)
+
+	bodyBytes, err := getPatternStats(ctx, client, queryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Stats []ComparedPattern `json:"stats"`
+	}
+	if err := json.Unmarshal(bodyBytes, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode clustering stats response: %v", err)
+	}
+	return resp.Stats, nil
+}
+
+// uniquePatterns returns the most frequent patterns of patterns that are not in others.
+func uniquePatterns(patterns, others []ComparedPattern) []ComparedPattern {
+	seen := make(map[string]bool, len(others))
+	for _, p := range others {
+		seen[p.Pattern] = true
+	}
+
+	unique := []ComparedPattern{}
+	for _, p := range patterns {
+		if !seen[p.Pattern] {
+			unique = append(unique, p)
+		}
+	}
+	sort.SliceStable(unique, func(i, j int) bool { return unique[i].Count > unique[j].Count })
+	if len(unique) > compareUniquePatterns {
+		unique = unique[:compareUniquePatterns]
+	}
+	return unique
+}
+
+func serviceMetricRelations(a, b ServiceComparison) []ServiceMetricRelation {
+	relations := []ServiceMetricRelation{
+		newServiceMetricRelation("log_volume", float64(a.LogVolume), float64(b.LogVolume)),
+		newServiceMetricRelation("errors", float64(a.Errors), float64(b.Errors)),
+		newServiceMetricRelation("error_rate", a.ErrorRate, b.ErrorRate),
+	}
+
+	var labels []string
+	for label := range a.Latency {
+		if _, ok := b.Latency[label]; ok {
+			labels = append(labels, label)
+		}
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		relations = append(relations, newServiceMetricRelation("latency_"+label, a.Latency[label], b.Latency[label]))
+	}
+	return relations
+}
+
+func newServiceMetricRelation(metric string, a, b float64) ServiceMetricRelation {
+	relation := ServiceMetricRelation{Metric: metric, A: a, B: b}
+	if a != 0 {
+		relation.Ratio = roundProportion(b / a)
+	}
+	return relation
+}
+
+func compareServicesGuidance(result CompareServicesResult) *SearchGuidance {
+	a, b := result.ServiceA, result.ServiceB
+	if a.LogVolume == 0 && b.LogVolume == 0 && a.Latency == nil && b.Latency == nil {
+		return &SearchGuidance{
+			ResultStatus: "empty",
+			NextSteps: []string{
+				fmt.Sprintf("Neither %s nor %s has logs or traces in the last %s.", a.Service, b.Service, result.Window),
+				"Verify the service names with facet_options (facet_path: \"service.name\") or widen the window.",
+			},
+		}
+	}
+
+	guidance := &SearchGuidance{ResultStatus: "success"}
+	if len(result.Warnings) > 0 {
+		guidance.ResultStatus = "partial"
+		guidance.NextSteps = append(guidance.NextSteps, fmt.Sprintf("%d sub-queries failed, see warnings; the metrics they compute are missing or zero.", len(result.Warnings)))
+	}
+	for _, comparison := range []ServiceComparison{a, b} {
+		if comparison.LogVolume == 0 {
+			guidance.NextSteps = append(guidance.NextSteps, fmt.Sprintf("%s has no logs in the last %s; verify its name with facet_options.", comparison.Service, result.Window))
+		}
+	}
+
+	guidance.NextSteps = append(guidance.NextSteps, fmt.Sprintf("Error rate: %.2f%% for %s, %.2f%% for %s.",
+		a.ErrorRate*100, a.Service, b.ErrorRate*100, b.Service))
+	for _, relation := range result.Metrics {
+		if strings.HasPrefix(relation.Metric, "latency_") && relation.Ratio >= 1.5 {
+			guidance.NextSteps = append(guidance.NextSteps, fmt.Sprintf("%s %s is %.1fx that of %s.", b.Service, relation.Metric, relation.Ratio, a.Service))
+		}
+	}
+
+	for _, comparison := range []ServiceComparison{b, a} {
+		if len(comparison.UniquePatterns) > 0 {
+			guidance.Suggestions = append(guidance.Suggestions, fmt.Sprintf("%s has patterns the other service does not, e.g. %q; use get_log_search with query: service.name:\"%s\" to see their logs.",
+				comparison.Service, comparison.UniquePatterns[0].Pattern, comparison.Service))
+		}
+	}
+	if b.ErrorRate > a.ErrorRate {
+		guidance.Suggestions = append(guidance.Suggestions, fmt.Sprintf("Use get_trace_timeline with query: service.name:\"%s\" AND status.code:\"ERROR\" and get_trace_error_chain to find where its errors originate.", b.Service))
+	}
+	guidance.Suggestions = append(guidance.Suggestions, queryStatsSuggestions(result.Stats)...)
+	return guidance
+}
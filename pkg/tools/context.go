@@ -2,7 +2,6 @@ package tools
 
 import (
 	"context"
-	"fmt"
 )
 
 // ContextKey is a custom type for context keys to avoid collisions.
@@ -13,6 +12,10 @@ const (
 	BearerTokenKey ContextKey = "bearerToken"
 	EDTokenKey     ContextKey = "edToken"
 	APIURLKey      ContextKey = "apiURL"
+	// LocaleKey holds the Accept-Language of the session, forwarded to the Edge Delta API
+	LocaleKey ContextKey = "locale"
+	// SubjectKey holds the subject, the sub claim, of the OAuth token of the caller
+	SubjectKey ContextKey = "subject"
 )
 
 type ContextKeys struct {
@@ -24,9 +27,16 @@ type ContextKeys struct {
 func FetchContextKeys(ctx context.Context) (*ContextKeys, error) {
 	orgID, ok := ctx.Value(OrgIDKey).(string)
 	if !ok {
-		return nil, fmt.Errorf("orgID not found in context")
+		return nil, &ToolError{Code: ErrCodeAuthFailed, Message: "orgID not found in context"}
 	}
 
+	keys := fetchTokens(ctx)
+	keys.OrgID = orgID
+	return keys, nil
+}
+
+// fetchTokens returns the tokens in ctx, for the requests that do not need an org ID.
+func fetchTokens(ctx context.Context) *ContextKeys {
 	var edToken string
 	if val := ctx.Value(EDTokenKey); val != nil {
 		edToken = val.(string)
@@ -38,8 +48,19 @@ func FetchContextKeys(ctx context.Context) (*ContextKeys, error) {
 	}
 
 	return &ContextKeys{
-		OrgID:       orgID,
 		EDToken:     edToken,
 		BearerToken: bearerToken,
-	}, nil
+	}
+}
+
+// callerIdentity identifies the caller across token refreshes and rotations: by the subject of its
+// OAuth token, or by its org for API tokens, which identify no user.
+func callerIdentity(ctx context.Context) (string, error) {
+	if subject, _ := ctx.Value(SubjectKey).(string); subject != "" {
+		return "sub:" + subject, nil
+	}
+	if orgID, _ := ctx.Value(OrgIDKey).(string); orgID != "" {
+		return "org:" + orgID, nil
+	}
+	return "", &ToolError{Code: ErrCodeAuthFailed, Message: "caller not found in context"}
 }
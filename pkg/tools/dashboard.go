@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -33,6 +34,7 @@ func GetAllDashboardsTool(client Client) (tool mcp.Tool, handler server.ToolHand
 WORKFLOW: This is the entry point for dashboard operations.
 1. get_all_dashboards → list dashboards with their dashboard_id
 2. get_dashboard(dashboard_id) → get detailed dashboard configuration
+3. render_dashboard(dashboard_id) → get the data of the widgets for a time range
 
 Returns dashboard summaries. Use include_definitions:true for full widget definitions.`),
 			mcp.WithBoolean("include_definitions",
@@ -112,7 +114,7 @@ func GetDashboardTool(client Client) (tool mcp.Tool, handler server.ToolHandlerF
 
 PREREQUISITE: Call get_all_dashboards tool first to obtain the dashboard_id.
 
-Returns full dashboard configuration including widget definitions and layout.`),
+Returns full dashboard configuration including widget definitions and layout. Use render_dashboard tool for the data of the widgets.`),
 			mcp.WithString("dashboard_id",
 				mcp.Description("Dashboard ID"),
 				mcp.Required(),
@@ -123,40 +125,16 @@ Returns full dashboard configuration including widget definitions and layout.`),
 			mcp.WithOpenWorldHintAnnotation(false),
 		),
 		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			keys, err := FetchContextKeys(ctx)
-			if err != nil {
-				return nil, err
-			}
-
 			dashboardID, err := request.RequireString("dashboard_id")
 			if err != nil {
 				return mcp.NewToolResultError("missing required parameter: dashboard_id"), err
 			}
 
-			dashboardURL := fmt.Sprintf("%s/v1/orgs/%s/dashboards/%s", client.APIURL(), keys.OrgID, dashboardID)
-			req, err := http.NewRequestWithContext(ctx, http.MethodGet, dashboardURL, nil)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create request: %v", err)
-			}
-
-			req.Header.Add("Content-Type", "application/json")
-			applyAuthHeader(req, keys)
-
-			resp, err := client.Do(req)
+			bodyBytes, err := getDashboard(ctx, client, dashboardID)
 			if err != nil {
 				return nil, err
 			}
 
-			defer resp.Body.Close()
-			bodyBytes, err := io.ReadAll(resp.Body)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read response body: %v", err)
-			}
-
-			if resp.StatusCode != http.StatusOK {
-				return nil, fmt.Errorf("failed to get dashboard, status code %d: %s", resp.StatusCode, string(bodyBytes))
-			}
-
 			// Wrap with guidance
 			response := DashboardToolResponse{
 				Data: bodyBytes,
@@ -177,3 +155,51 @@ Returns full dashboard configuration including widget definitions and layout.`),
 			return mcp.NewToolResultText(string(r)), nil
 		}
 }
+
+// doDashboardRequest sends a request to the dashboards endpoint of the org, path being relative
+// to it, and returns the response body.
+func doDashboardRequest(ctx context.Context, client Client, method, path string, payload any, action string) ([]byte, error) {
+	keys, err := FetchContextKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var body io.Reader
+	if payload != nil {
+		payloadBytes, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal payload: %v", err)
+		}
+		body = bytes.NewReader(payloadBytes)
+	}
+
+	dashboardsURL := fmt.Sprintf("%s/v1/orgs/%s/dashboards%s", client.APIURL(), keys.OrgID, path)
+	req, err := http.NewRequestWithContext(ctx, method, dashboardsURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Add("Content-Type", "application/json")
+	applyAuthHeader(req, keys)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("failed to %s, status code %d: %s", action, resp.StatusCode, string(bodyBytes))
+	}
+	return bodyBytes, nil
+}
+
+// getDashboard returns the dashboard with its definition.
+func getDashboard(ctx context.Context, client Client, dashboardID string) ([]byte, error) {
+	return doDashboardRequest(ctx, client, http.MethodGet, "/"+url.PathEscape(dashboardID), nil, "get dashboard")
+}
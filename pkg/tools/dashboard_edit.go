@@ -0,0 +1,362 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// DashboardWidgetTypes are the widget types of dashboards, see tableWidgetTypes for the ones
+// showing one value per group.
+var DashboardWidgetTypes = []string{"timeseries", "bar", "table", "query_value", "value", "pie", "toplist", "text"}
+
+// GraphScopes are the scopes of graph queries.
+var GraphScopes = []string{"log", "metric", "trace", "pattern", "event"}
+
+const dashboardWidgetExample = `Example widget:
+{
+  "id": "errors",
+  "title": "Checkout errors by service",
+  "type": "timeseries",
+  "queries": [{"scope": "log", "query": "service.name:\"checkout\" AND severity_text:\"ERROR\""}]
+}
+Metric queries use the metric CQL, e.g. {"scope": "metric", "query": "avg:http.server.request.duration{service.name:\"checkout\"} by {host.name}"}. Widgets with several queries may combine them with "formulas", e.g. ["Q1/Q2*100"], the queries being Q1, Q2... in order. Text widgets have a "text" instead of queries.`
+
+// ValidateDashboardWidget checks a widget definition before it is sent to the API: its title,
+// its type, and its queries and formulas, which must be the ones render_dashboard can evaluate.
+func ValidateDashboardWidget(widget map[string]any) []string {
+	var errs []string
+	if title, _ := widget["title"].(string); strings.TrimSpace(title) == "" {
+		errs = append(errs, "widget must have a title")
+	}
+
+	widgetType, _ := widget["type"].(string)
+	switch {
+	case widgetType == "":
+		errs = append(errs, fmt.Sprintf("widget must have a type, one of %s", strings.Join(DashboardWidgetTypes, ", ")))
+	case !slices.Contains(DashboardWidgetTypes, widgetType):
+		errs = append(errs, fmt.Sprintf("invalid widget type %q, expected one of %s", widgetType, strings.Join(DashboardWidgetTypes, ", ")))
+	}
+
+	if widgetType == "text" {
+		if text, _ := widget["text"].(string); strings.TrimSpace(text) == "" {
+			errs = append(errs, "text widget must have a text")
+		}
+		return errs
+	}
+
+	var queries []any
+	switch q := widget["queries"].(type) {
+	case []any:
+		queries = q
+	case map[string]any:
+		names := make([]string, 0, len(q))
+		for name := range q {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			queries = append(queries, q[name])
+		}
+	case nil:
+		if q, ok := widget["query"]; ok {
+			queries = []any{q}
+		}
+	default:
+		errs = append(errs, "queries must be a list of query objects")
+	}
+	if len(queries) == 0 {
+		return append(errs, "widget must have at least one query with a scope and a query")
+	}
+
+	for i, v := range queries {
+		q, ok := v.(map[string]any)
+		if !ok {
+			errs = append(errs, fmt.Sprintf("query %d must be an object with a scope and a query", i+1))
+			continue
+		}
+		scope, _ := q["scope"].(string)
+		if !slices.Contains(GraphScopes, scope) {
+			errs = append(errs, fmt.Sprintf("query %d has invalid scope %q, expected one of %s", i+1, scope, strings.Join(GraphScopes, ", ")))
+		}
+		if query := firstRecordString(q, "query", "cql"); strings.TrimSpace(query) == "" {
+			errs = append(errs, fmt.Sprintf("query %d has no query, use \"*\" to match everything", i+1))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	// The formulas are checked the way render_dashboard builds the graph request
+	parsed := parseDashboardWidget(widget, 0)
+	builder := NewGraphQueryBuilder()
+	for name, query := range parsed.Queries {
+		builder.WithQuery(name, query)
+	}
+	for name, formula := range parsed.Formulas {
+		builder.WithFormula(name, formula)
+	}
+	if _, err := builder.Build(); err != nil {
+		errs = append(errs, err.Error())
+	}
+	return errs
+}
+
+// validateDashboardWidgets validates the widgets and gives the ones without an id one that is
+// unique within the dashboard.
+func validateDashboardWidgets(widgets []any) ([]any, []string) {
+	var errs []string
+	ids := make(map[string]bool)
+	for i, v := range widgets {
+		widget, ok := v.(map[string]any)
+		if !ok {
+			errs = append(errs, fmt.Sprintf("widget %d must be a JSON object", i+1))
+			continue
+		}
+		for _, err := range ValidateDashboardWidget(widget) {
+			errs = append(errs, fmt.Sprintf("widget %d: %s", i+1, err))
+		}
+		if id, _ := widget["id"].(string); id != "" {
+			if ids[id] {
+				errs = append(errs, fmt.Sprintf("widget %d: duplicate widget id %q", i+1, id))
+			}
+			ids[id] = true
+		}
+	}
+
+	for i, v := range widgets {
+		widget, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		if id, _ := widget["id"].(string); id == "" {
+			n := i + 1
+			for id = fmt.Sprintf("w%d", n); ids[id]; id = fmt.Sprintf("w%d", n) {
+				n++
+			}
+			widget["id"], ids[id] = id, true
+		}
+	}
+	return widgets, errs
+}
+
+// CreateDashboardTool creates a tool to create a dashboard
+func CreateDashboardTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("create_dashboard",
+			mcp.WithTitleAnnotation("Create Dashboard"),
+			mcp.WithDescription(`Create a dashboard from a list of widgets, e.g. to keep the graphs of an investigation.
+
+The widgets are validated before the dashboard is created: every widget needs a title, a type and queries with a scope and a query. Use render_dashboard with the returned dashboard_id to check the widgets show data.
+
+`+dashboardWidgetExample),
+			mcp.WithString("name",
+				mcp.Description("Name of the dashboard"),
+				mcp.Required(),
+			),
+			mcp.WithString("description",
+				mcp.Description("Description of the dashboard, e.g. the incident it was created for"),
+				mcp.DefaultString(""),
+			),
+			mcp.WithArray("tags",
+				mcp.Description("Tags of the dashboard"),
+				mcp.WithStringItems(),
+			),
+			mcp.WithArray("widgets",
+				mcp.Description("Widget definitions, in display order. Widgets without an id get one."),
+				mcp.Required(),
+			),
+			mcp.WithReadOnlyHintAnnotation(false),
+			mcp.WithIdempotentHintAnnotation(false),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			name, err := request.RequireString("name")
+			if err != nil || strings.TrimSpace(name) == "" {
+				return mcp.NewToolResultError("missing required parameter: name"), nil
+			}
+
+			widgets, ok := request.GetArguments()["widgets"].([]any)
+			if !ok || len(widgets) == 0 {
+				return mcp.NewToolResultError("missing required parameter: widgets, it must be a list of widget objects"), nil
+			}
+			widgets, errs := validateDashboardWidgets(widgets)
+			if len(errs) > 0 {
+				return mcp.NewToolResultError("invalid widgets:\n- " + strings.Join(errs, "\n- ")), nil
+			}
+
+			dashboard := map[string]any{
+				"name":       strings.TrimSpace(name),
+				"definition": map[string]any{"widgets": widgets},
+			}
+			if description, _ := params.Optional[string](request, "description"); description != "" {
+				dashboard["description"] = description
+			}
+			if tags := request.GetStringSlice("tags", nil); len(tags) > 0 {
+				dashboard["tags"] = tags
+			}
+
+			bodyBytes, err := doDashboardRequest(ctx, client, http.MethodPost, "", dashboard, "create dashboard")
+			if err != nil {
+				return nil, err
+			}
+
+			return dashboardResult(bodyBytes, &DashboardGuidance{
+				ResultStatus: "success",
+				NextSteps: []string{
+					"Dashboard created. Use render_dashboard tool with the returned dashboard_id to check its widgets show data.",
+				},
+			})
+		}
+}
+
+// UpdateDashboardWidgetTool creates a tool to add or replace a widget of a dashboard
+func UpdateDashboardWidgetTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("update_dashboard_widget",
+			mcp.WithTitleAnnotation("Update Dashboard Widget"),
+			mcp.WithDescription(`Replace a widget of a dashboard, or add it when the dashboard has no widget with its id. The other widgets are kept as they are.
+
+PREREQUISITE: Call get_dashboard tool first to see the widgets and their ids.
+
+The widget is validated before the dashboard is saved.
+
+`+dashboardWidgetExample),
+			mcp.WithString("dashboard_id",
+				mcp.Description("Dashboard ID"),
+				mcp.Required(),
+			),
+			mcp.WithObject("widget",
+				mcp.Description("Full widget definition. Must include 'id', the widget to replace or the id of the new widget."),
+				mcp.Required(),
+			),
+			mcp.WithReadOnlyHintAnnotation(false),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(true),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			dashboardID, err := request.RequireString("dashboard_id")
+			if err != nil || strings.TrimSpace(dashboardID) == "" {
+				return mcp.NewToolResultError("missing required parameter: dashboard_id"), nil
+			}
+			dashboardID = strings.TrimSpace(dashboardID)
+
+			widget, ok := request.GetArguments()["widget"].(map[string]any)
+			if !ok {
+				return mcp.NewToolResultError("missing required parameter: widget, it must be a JSON object"), nil
+			}
+			widgetID, _ := widget["id"].(string)
+			if widgetID == "" {
+				return mcp.NewToolResultError("widget must have an id"), nil
+			}
+			if errs := ValidateDashboardWidget(widget); len(errs) > 0 {
+				return mcp.NewToolResultError("invalid widget:\n- " + strings.Join(errs, "\n- ")), nil
+			}
+
+			bodyBytes, err := getDashboard(ctx, client, dashboardID)
+			if err != nil {
+				return nil, err
+			}
+			dashboard, added, err := replaceDashboardWidget(bodyBytes, widget)
+			if err != nil {
+				return nil, err
+			}
+
+			bodyBytes, err = doDashboardRequest(ctx, client, http.MethodPut, "/"+url.PathEscape(dashboardID), dashboard, "update dashboard")
+			if err != nil {
+				return nil, err
+			}
+
+			step := fmt.Sprintf("Widget %s replaced.", widgetID)
+			if added {
+				step = fmt.Sprintf("The dashboard had no widget %s, it was added after the others.", widgetID)
+			}
+			return dashboardResult(bodyBytes, &DashboardGuidance{
+				ResultStatus: "success",
+				NextSteps: []string{
+					step,
+					fmt.Sprintf("Use render_dashboard tool with widget_ids:[%q] to check the widget shows data.", widgetID),
+				},
+			})
+		}
+}
+
+// replaceDashboardWidget returns the dashboard with the widget of the same id, also within
+// groups, replaced, or with the widget appended when it has none. The definition keeps its form,
+// an object or a JSON string.
+func replaceDashboardWidget(bodyBytes []byte, widget map[string]any) (map[string]any, bool, error) {
+	var dashboard map[string]any
+	if err := json.Unmarshal(bodyBytes, &dashboard); err != nil {
+		return nil, false, fmt.Errorf("failed to decode dashboard: %v", err)
+	}
+
+	definition, _ := dashboard["definition"].(map[string]any)
+	encoded, isString := dashboard["definition"].(string)
+	if isString {
+		if err := json.Unmarshal([]byte(encoded), &definition); err != nil {
+			return nil, false, fmt.Errorf("failed to decode dashboard definition: %v", err)
+		}
+	}
+	if definition == nil {
+		definition = make(map[string]any)
+	}
+
+	var replace func(widgets []any) bool
+	replace = func(widgets []any) bool {
+		for i, v := range widgets {
+			w, ok := v.(map[string]any)
+			if !ok {
+				continue
+			}
+			if group, ok := w["widgets"].([]any); ok {
+				if replace(group) {
+					return true
+				}
+				continue
+			}
+			if w["id"] == widget["id"] || w["widget_id"] == widget["id"] {
+				widgets[i] = widget
+				return true
+			}
+		}
+		return false
+	}
+
+	widgets, _ := definition["widgets"].([]any)
+	added := !replace(widgets)
+	if added {
+		definition["widgets"] = append(widgets, widget)
+	}
+
+	if isString {
+		definitionBytes, err := json.Marshal(definition)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to marshal dashboard definition: %v", err)
+		}
+		dashboard["definition"] = string(definitionBytes)
+	} else {
+		dashboard["definition"] = definition
+	}
+	return dashboard, added, nil
+}
+
+func dashboardResult(bodyBytes []byte, guidance *DashboardGuidance) (*mcp.CallToolResult, error) {
+	if len(strings.TrimSpace(string(bodyBytes))) == 0 {
+		bodyBytes = []byte("null")
+	}
+
+	r, err := json.Marshal(DashboardToolResponse{Data: bodyBytes, Guidance: guidance})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal wrapped response, err: %w", err)
+	}
+	return mcp.NewToolResultText(string(r)), nil
+}
@@ -0,0 +1,497 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// dashboardConcurrency bounds the widgets queried at once by render_dashboard.
+	dashboardConcurrency = 4
+	// maxDashboardWidgets is the number of widgets render_dashboard evaluates, the others are
+	// listed as skipped.
+	maxDashboardWidgets = 30
+)
+
+// Statuses of rendered widgets.
+const (
+	WidgetSuccess = "success"
+	WidgetEmpty   = "empty"
+	WidgetPartial = "partial"
+	WidgetError   = "error"
+	WidgetSkipped = "skipped"
+)
+
+// tableWidgetTypes are the widget types showing one value per group rather than a timeseries.
+var tableWidgetTypes = map[string]bool{
+	"table":       true,
+	"query_value": true,
+	"value":       true,
+	"pie":         true,
+	"toplist":     true,
+}
+
+// DashboardWidget is a widget of a dashboard definition with the graph queries it shows.
+type DashboardWidget struct {
+	ID    string
+	Title string
+	Type  string
+	// Queries are the named graph queries of the widget, e.g. "Q1"
+	Queries map[string]GraphQuery
+	// Formulas are the named formulas over the queries, one per query when the widget has none
+	Formulas map[string]string
+}
+
+// GraphType returns the graph_type the widget is queried with.
+func (w DashboardWidget) GraphType() string {
+	if tableWidgetTypes[strings.ToLower(w.Type)] {
+		return "table"
+	}
+	return "timeseries"
+}
+
+type RenderDashboardResult struct {
+	DashboardID string           `json:"dashboard_id"`
+	Name        string           `json:"name,omitempty"`
+	Widgets     []RenderedWidget `json:"widgets"`
+	// Statuses counts the widgets per status
+	Statuses map[string]int     `json:"widget_statuses"`
+	Guidance *DashboardGuidance `json:"guidance,omitempty"`
+}
+
+// RenderedWidget is the data of a widget for the time range of render_dashboard.
+type RenderedWidget struct {
+	ID      string   `json:"widget_id,omitempty"`
+	Title   string   `json:"title,omitempty"`
+	Type    string   `json:"type,omitempty"`
+	Queries []string `json:"queries,omitempty"`
+	// Status is one of success, empty, partial, error or skipped
+	Status     string            `json:"status"`
+	Data       json.RawMessage   `json:"data,omitempty"`
+	Sparklines []SeriesSparkline `json:"sparklines,omitempty"`
+	Warnings   []GraphWarning    `json:"warnings,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// RenderDashboardTool creates a tool to evaluate the widgets of a dashboard
+func RenderDashboardTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("render_dashboard",
+			mcp.WithTitleAnnotation("Render Dashboard"),
+			mcp.WithDescription(`Evaluates the widgets of a dashboard for a time range and returns the data of every widget, instead of only the definition returned by get_dashboard.
+
+PREREQUISITE: Call get_all_dashboards tool first to obtain the dashboard_id.
+
+The queries of the widgets run in parallel through the graph API. Widgets without queries, e.g. text widgets, are listed as skipped; a widget whose query fails does not fail the others. By default each series is returned as a sparkline; use render:"raw" for the exact points, ideally with widget_ids to limit the widgets.`),
+			mcp.WithString("dashboard_id",
+				mcp.Description("Dashboard ID"),
+				mcp.Required(),
+			),
+			mcp.WithArray("widget_ids",
+				mcp.Description("IDs of the widgets to render. All widgets of the dashboard are rendered by default."),
+				mcp.WithStringItems(),
+			),
+			mcp.WithString("lookback",
+				mcp.Description("Lookback period in GOLANG duration format, or days and weeks. e.g. (1h, 15m, 24h, 7d, 1w). Either provide from/to or just lookback. Pass empty string to use from/to instead."),
+				mcp.DefaultString("1h"),
+			),
+			mcp.WithString("from",
+				mcp.Description("From datetime in ISO format 2006-01-02T15:04:05.000Z."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("to",
+				mcp.Description("To datetime in ISO format 2006-01-02T15:04:05.000Z."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("render",
+				mcp.Description(`How to return the series of the widgets. "sparkline" returns one compact unicode sparkline per series with its min, max and last values; "raw" returns the points of the API response.`),
+				mcp.Enum(RenderSparkline, RenderRaw),
+				mcp.DefaultString(RenderSparkline),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			dashboardID, err := request.RequireString("dashboard_id")
+			if err != nil || strings.TrimSpace(dashboardID) == "" {
+				return mcp.NewToolResultError("missing required parameter: dashboard_id"), nil
+			}
+			dashboardID = strings.TrimSpace(dashboardID)
+
+			render, _ := params.Optional[string](request, "render")
+			if render == "" {
+				render = RenderSparkline
+			}
+			if render != RenderRaw && render != RenderSparkline {
+				return mcp.NewToolResultError(fmt.Sprintf(`invalid render %q, expected "raw" or "sparkline"`, render)), nil
+			}
+
+			queryParams := url.Values{}
+			from, _ := params.Optional[string](request, "from")
+			to, _ := params.Optional[string](request, "to")
+			if from != "" {
+				queryParams.Add("from", from)
+				if to != "" {
+					queryParams.Add("to", to)
+				}
+			} else {
+				lookback, _ := params.Optional[string](request, "lookback")
+				if lookback == "" {
+					lookback = "1h"
+				}
+				if _, err := ParseLookback(lookback); err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("invalid lookback %q: %v", lookback, err)), nil
+				}
+				queryParams.Add("lookback", lookback)
+			}
+
+			bodyBytes, err := getDashboard(ctx, client, dashboardID)
+			if err != nil {
+				return nil, err
+			}
+
+			name, widgets, err := ParseDashboardWidgets(bodyBytes)
+			if err != nil {
+				return nil, err
+			}
+
+			if widgetIDs := request.GetStringSlice("widget_ids", nil); len(widgetIDs) > 0 {
+				var selected []DashboardWidget
+				for _, widget := range widgets {
+					for _, id := range widgetIDs {
+						if widget.ID == strings.TrimSpace(id) {
+							selected = append(selected, widget)
+							break
+						}
+					}
+				}
+				if len(selected) == 0 {
+					return mcp.NewToolResultError(fmt.Sprintf("none of the widget_ids %s are widgets of dashboard %s", strings.Join(widgetIDs, ", "), dashboardID)), nil
+				}
+				widgets = selected
+			}
+
+			result := RenderDashboardResult{
+				DashboardID: dashboardID,
+				Name:        name,
+				Widgets:     renderWidgets(ctx, client, widgets, queryParams, render),
+			}
+			result.Statuses = make(map[string]int)
+			for _, widget := range result.Widgets {
+				result.Statuses[widget.Status]++
+			}
+			result.Guidance = renderDashboardGuidance(result)
+
+			r, err := json.Marshal(result)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
+
+// renderWidgets queries the widgets, at most dashboardConcurrency at once, in the order of the
+// dashboard.
+func renderWidgets(ctx context.Context, client Client, widgets []DashboardWidget, queryParams url.Values, render string) []RenderedWidget {
+	rendered := make([]RenderedWidget, len(widgets))
+	sem := make(chan struct{}, dashboardConcurrency)
+	var wg sync.WaitGroup
+	for i, widget := range widgets {
+		if i >= maxDashboardWidgets {
+			rendered[i] = renderedWidget(widget)
+			rendered[i].Status = WidgetSkipped
+			rendered[i].Error = fmt.Sprintf("only the first %d widgets are rendered, pass widget_ids to render the others", maxDashboardWidgets)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			rendered[i] = renderWidget(ctx, client, widget, queryParams, render)
+		}()
+	}
+	wg.Wait()
+	return rendered
+}
+
+func renderWidget(ctx context.Context, client Client, widget DashboardWidget, queryParams url.Values, render string) RenderedWidget {
+	rendered := renderedWidget(widget)
+	if len(widget.Queries) == 0 {
+		rendered.Status = WidgetSkipped
+		rendered.Error = "widget has no queries, e.g. a text widget"
+		return rendered
+	}
+
+	builder := NewGraphQueryBuilder()
+	for name, query := range widget.Queries {
+		builder.WithQuery(name, query)
+	}
+	for name, formula := range widget.Formulas {
+		builder.WithFormula(name, formula)
+	}
+	payload, err := builder.Build()
+	if err != nil {
+		rendered.Status, rendered.Error = WidgetError, err.Error()
+		return rendered
+	}
+
+	widgetParams := url.Values{}
+	for k, v := range queryParams {
+		widgetParams[k] = v
+	}
+	widgetParams.Set("graph_type", widget.GraphType())
+
+	statusCode, bodyBytes, warnings, err := postGraphWithRetry(ctx, client, payload, widgetParams)
+	if err != nil {
+		rendered.Status, rendered.Error = WidgetError, err.Error()
+		return rendered
+	}
+	if statusCode != http.StatusMultiStatus {
+		rendered.Status = WidgetError
+		rendered.Error = fmt.Sprintf("failed to graph widget, status code %d: %s", statusCode, string(bodyBytes))
+		return rendered
+	}
+
+	rendered.Warnings = warnings
+	hasData := graphHasData(bodyBytes)
+	switch {
+	case !hasData && len(warnings) > 0:
+		rendered.Status = WidgetError
+		return rendered
+	case !hasData:
+		rendered.Status = WidgetEmpty
+		return rendered
+	case len(warnings) > 0:
+		rendered.Status = WidgetPartial
+	default:
+		rendered.Status = WidgetSuccess
+	}
+
+	rendered.Data = bodyBytes
+	if render == RenderSparkline {
+		if sparklines, err := graphSparklines(bodyBytes); err == nil && len(sparklines) > 0 {
+			rendered.Data, rendered.Sparklines = nil, sparklines
+		}
+	}
+	return rendered
+}
+
+func renderedWidget(widget DashboardWidget) RenderedWidget {
+	rendered := RenderedWidget{ID: widget.ID, Title: widget.Title, Type: widget.Type}
+	names := make([]string, 0, len(widget.Queries))
+	for name := range widget.Queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		query := widget.Queries[name]
+		rendered.Queries = append(rendered.Queries, fmt.Sprintf("%s (%s): %s", name, query.Scope, query.Query))
+	}
+	return rendered
+}
+
+// ParseDashboardWidgets returns the name of a dashboard and its widgets, in the order of its
+// definition. The widgets of groups are flattened. A widget holds either a single "query" or a
+// list or map of "queries", each with a scope and a CQL query, and optionally "formulas" over the
+// queries; other fields of a query, e.g. includeChildSpans, are passed to the graph API as is.
+func ParseDashboardWidgets(bodyBytes []byte) (string, []DashboardWidget, error) {
+	var dashboard struct {
+		Name       string          `json:"name"`
+		Definition json.RawMessage `json:"definition"`
+		Widgets    []any           `json:"widgets"`
+	}
+	if err := json.Unmarshal(bodyBytes, &dashboard); err != nil {
+		return "", nil, fmt.Errorf("failed to decode dashboard: %v", err)
+	}
+
+	widgets := dashboard.Widgets
+	if len(dashboard.Definition) > 0 {
+		definition := dashboard.Definition
+		// Some dashboards carry their definition as a JSON string
+		var encoded string
+		if err := json.Unmarshal(definition, &encoded); err == nil {
+			definition = json.RawMessage(encoded)
+		}
+
+		var def struct {
+			Widgets []any `json:"widgets"`
+		}
+		if err := json.Unmarshal(definition, &def); err != nil {
+			return "", nil, fmt.Errorf("failed to decode dashboard definition: %v", err)
+		}
+		widgets = append(widgets, def.Widgets...)
+	}
+
+	var parsed []DashboardWidget
+	var walk func(values []any)
+	walk = func(values []any) {
+		for _, v := range values {
+			w, ok := v.(map[string]any)
+			if !ok {
+				continue
+			}
+			if group, ok := w["widgets"].([]any); ok {
+				walk(group)
+				continue
+			}
+			parsed = append(parsed, parseDashboardWidget(w, len(parsed)))
+		}
+	}
+	walk(widgets)
+	return dashboard.Name, parsed, nil
+}
+
+func parseDashboardWidget(w map[string]any, index int) DashboardWidget {
+	widget := DashboardWidget{
+		ID:       firstRecordString(w, "id", "widget_id"),
+		Title:    firstRecordString(w, "title", "name"),
+		Type:     firstRecordString(w, "type", "graph_type", "visualization"),
+		Queries:  make(map[string]GraphQuery),
+		Formulas: make(map[string]string),
+	}
+	if widget.ID == "" {
+		widget.ID = fmt.Sprintf("widget-%d", index+1)
+	}
+
+	addQuery := func(name string, v any) {
+		q, ok := v.(map[string]any)
+		if !ok {
+			return
+		}
+		query := GraphQuery{
+			Scope:    firstRecordString(q, "scope"),
+			Query:    firstRecordString(q, "query", "cql"),
+			DataType: firstRecordString(q, "dataType", "data_type"),
+		}
+		if query.Scope == "" || query.Query == "" {
+			return
+		}
+		for k, v := range q {
+			switch k {
+			case "name", "scope", "query", "cql", "dataType", "data_type":
+			default:
+				if query.Options == nil {
+					query.Options = make(map[string]any)
+				}
+				query.Options[k] = v
+			}
+		}
+		if n := firstRecordString(q, "name"); n != "" {
+			name = n
+		}
+		widget.Queries[name] = query
+	}
+
+	switch queries := w["queries"].(type) {
+	case []any:
+		for i, q := range queries {
+			addQuery(fmt.Sprintf("Q%d", i+1), q)
+		}
+	case map[string]any:
+		for name, q := range queries {
+			addQuery(name, q)
+		}
+	}
+	if len(widget.Queries) == 0 {
+		addQuery("Q1", w["query"])
+	}
+
+	switch formulas := w["formulas"].(type) {
+	case []any:
+		for i, f := range formulas {
+			name := fmt.Sprintf("R%d", i+1)
+			switch f := f.(type) {
+			case string:
+				widget.Formulas[name] = f
+			case map[string]any:
+				if n := firstRecordString(f, "name"); n != "" {
+					name = n
+				}
+				if formula := firstRecordString(f, "formula"); formula != "" {
+					widget.Formulas[name] = formula
+				}
+			}
+		}
+	case map[string]any:
+		for name, f := range formulas {
+			switch f := f.(type) {
+			case string:
+				widget.Formulas[name] = f
+			case map[string]any:
+				if formula := firstRecordString(f, "formula"); formula != "" {
+					widget.Formulas[name] = formula
+				}
+			}
+		}
+	}
+	// Widgets without formulas show each of their queries
+	if len(widget.Formulas) == 0 {
+		names := make([]string, 0, len(widget.Queries))
+		for name := range widget.Queries {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for i, name := range names {
+			widget.Formulas[fmt.Sprintf("R%d", i+1)] = name
+		}
+	}
+	return widget
+}
+
+// firstRecordString returns the first non-empty string of the keys of the record.
+func firstRecordString(record map[string]any, keys ...string) string {
+	for _, key := range keys {
+		if s, ok := record[key].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+func renderDashboardGuidance(result RenderDashboardResult) *DashboardGuidance {
+	var failed, empty []string
+	for _, widget := range result.Widgets {
+		label := widget.ID
+		if widget.Title != "" {
+			label = fmt.Sprintf("%s (%s)", widget.ID, widget.Title)
+		}
+		switch widget.Status {
+		case WidgetError, WidgetPartial:
+			failed = append(failed, label)
+		case WidgetEmpty:
+			empty = append(empty, label)
+		}
+	}
+
+	guidance := &DashboardGuidance{ResultStatus: "success"}
+	if len(result.Widgets) == 0 {
+		guidance.ResultStatus = "empty"
+		guidance.NextSteps = []string{"The dashboard has no widgets. Use get_dashboard to inspect its definition."}
+		return guidance
+	}
+	if len(failed) > 0 {
+		guidance.ResultStatus = "partial"
+		guidance.NextSteps = append(guidance.NextSteps, fmt.Sprintf("Widgets %s failed, see their error and warnings.", strings.Join(failed, ", ")))
+		guidance.Suggestions = append(guidance.Suggestions, "Use validate_cql tool on the queries of the failed widgets, or search_metrics tool to verify metric names")
+	}
+	if len(empty) > 0 {
+		guidance.NextSteps = append(guidance.NextSteps, fmt.Sprintf("Widgets %s have no data for this time range; try a longer lookback.", strings.Join(empty, ", ")))
+	}
+	if len(failed) == 0 && len(empty) == 0 {
+		guidance.NextSteps = append(guidance.NextSteps, "All widgets were rendered successfully.")
+	}
+	guidance.Suggestions = append(guidance.Suggestions, "Use get_log_graph, get_metric_graph or get_trace_graph with the query of a widget to drill into it")
+	return guidance
+}
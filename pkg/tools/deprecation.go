@@ -0,0 +1,150 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ParameterDeprecation describes a parameter value that still works but will be rejected in a
+// future release.
+type ParameterDeprecation struct {
+	// Tool is the tool the parameter belongs to, or empty for every tool with the parameter.
+	Tool      string
+	Parameter string
+	// Replacement tells callers what to pass instead.
+	Replacement string
+	// migrate returns the value to use instead of a deprecated value, or nil to drop the
+	// parameter, and whether the value is deprecated at all.
+	migrate func(value any) (any, bool)
+}
+
+// DeprecationWarning is added to the result of a call that used a deprecated parameter value.
+type DeprecationWarning struct {
+	Tool        string `json:"tool"`
+	Parameter   string `json:"parameter"`
+	Value       any    `json:"value"`
+	MigratedTo  any    `json:"migrated_to,omitempty"`
+	Replacement string `json:"replacement"`
+}
+
+// Deprecations lists the deprecated parameter values still accepted by the tools.
+var Deprecations = []ParameterDeprecation{
+	{
+		Parameter:   "limit",
+		Replacement: "Omit limit to use the default, or pass a positive number. To page the other way, pass the cursor of the previous response or reverse order instead. Negative limits will be rejected.",
+		migrate: func(value any) (any, bool) {
+			limit, ok := value.(float64)
+			return nil, ok && limit < 0
+		},
+	},
+	{
+		Tool:        "get_log_patterns",
+		Parameter:   "offset",
+		Replacement: "Pass offsets in Go duration format, e.g. '24h' instead of '86400'. Offsets in seconds without a unit will be rejected.",
+		migrate:     migrateSecondOffsets,
+	},
+}
+
+// migrateSecondOffsets converts comma separated offsets given in seconds to durations.
+func migrateSecondOffsets(value any) (any, bool) {
+	offsets, ok := value.(string)
+	if !ok {
+		return value, false
+	}
+
+	parts := strings.Split(offsets, ",")
+	deprecated := false
+	for i, part := range parts {
+		seconds, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		parts[i] = (time.Duration(seconds) * time.Second).String()
+		deprecated = true
+	}
+	return strings.Join(parts, ","), deprecated
+}
+
+// DeprecationWarnings returns a tool middleware that migrates deprecated parameter values to their
+// replacement and appends a warning for each to the result, so callers can update their prompts
+// before the values are rejected.
+func DeprecationWarnings(deprecations []ParameterDeprecation) ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			args, warnings := migrateDeprecated(request.Params.Name, request.GetArguments(), deprecations)
+			if len(warnings) == 0 {
+				return next(ctx, request)
+			}
+
+			request.Params.Arguments = args
+			result, err := next(ctx, request)
+			if err != nil || result == nil {
+				return result, err
+			}
+
+			warningBytes, err := json.Marshal(map[string]any{"deprecation_warnings": warnings})
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal deprecation warnings: %w", err)
+			}
+
+			result.Content = append(result.Content, mcp.NewTextContent(string(warningBytes)))
+			return result, nil
+		}
+	}
+}
+
+// migrateDeprecated returns a copy of the arguments with the deprecated values migrated and a
+// warning for each, or the arguments untouched and no warnings.
+func migrateDeprecated(toolName string, args map[string]any, deprecations []ParameterDeprecation) (map[string]any, []DeprecationWarning) {
+	var (
+		migrated map[string]any
+		warnings []DeprecationWarning
+	)
+	for _, d := range deprecations {
+		if d.Tool != "" && d.Tool != toolName {
+			continue
+		}
+
+		value, ok := args[d.Parameter]
+		if !ok {
+			continue
+		}
+
+		replacement, deprecated := d.migrate(value)
+		if !deprecated {
+			continue
+		}
+
+		if migrated == nil {
+			migrated = make(map[string]any, len(args))
+			for k, v := range args {
+				migrated[k] = v
+			}
+		}
+		if replacement == nil {
+			delete(migrated, d.Parameter)
+		} else {
+			migrated[d.Parameter] = replacement
+		}
+
+		warnings = append(warnings, DeprecationWarning{
+			Tool:        toolName,
+			Parameter:   d.Parameter,
+			Value:       value,
+			MigratedTo:  replacement,
+			Replacement: d.Replacement,
+		})
+	}
+
+	if migrated == nil {
+		return args, nil
+	}
+	return migrated, warnings
+}
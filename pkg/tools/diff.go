@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	diffContextLines = 2
+	// maxDiffLines bounds the O(n*m) diff, larger inputs are reported as fully replaced.
+	maxDiffLines = 3000
+)
+
+// lineDiff returns a unified-style diff from a to b: removed lines are prefixed with "-",
+// added lines with "+" and unchanged context lines with " ". Hunks are separated by "@@".
+func lineDiff(a, b string) []string {
+	aLines := strings.Split(strings.TrimRight(a, "\n"), "\n")
+	bLines := strings.Split(strings.TrimRight(b, "\n"), "\n")
+
+	if len(aLines) > maxDiffLines || len(bLines) > maxDiffLines {
+		return []string{fmt.Sprintf("@@ too large to diff: %d lines -> %d lines", len(aLines), len(bLines))}
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of aLines[i:] and bLines[j:].
+	lcs := make([][]int, len(aLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bLines)+1)
+	}
+	for i := len(aLines) - 1; i >= 0; i-- {
+		for j := len(bLines) - 1; j >= 0; j-- {
+			if aLines[i] == bLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []string
+	i, j := 0, 0
+	for i < len(aLines) || j < len(bLines) {
+		switch {
+		case i < len(aLines) && j < len(bLines) && aLines[i] == bLines[j]:
+			ops = append(ops, " "+aLines[i])
+			i++
+			j++
+		case j < len(bLines) && (i == len(aLines) || lcs[i][j+1] >= lcs[i+1][j]):
+			ops = append(ops, "+"+bLines[j])
+			j++
+		default:
+			ops = append(ops, "-"+aLines[i])
+			i++
+		}
+	}
+
+	return trimDiffContext(ops)
+}
+
+// trimDiffContext keeps only changed lines and diffContextLines of context around them.
+func trimDiffContext(ops []string) []string {
+	keep := make([]bool, len(ops))
+	for i, op := range ops {
+		if op[0] == ' ' {
+			continue
+		}
+		for k := max(0, i-diffContextLines); k <= min(len(ops)-1, i+diffContextLines); k++ {
+			keep[k] = true
+		}
+	}
+
+	var out []string
+	for i, op := range ops {
+		if !keep[i] {
+			continue
+		}
+		if i == 0 || !keep[i-1] {
+			out = append(out, "@@")
+		}
+		out = append(out, op)
+	}
+	return out
+}
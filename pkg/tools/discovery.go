@@ -154,6 +154,9 @@ After calling this tool, use build_cql tool to construct queries from structured
 				mcp.Description("If true, fetch sample values for common fields. Default: true"),
 				mcp.DefaultBool(true),
 			),
+			mcp.WithBoolean("refresh",
+				mcp.Description("If true, fetch fresh field names and values instead of the ones cached from recent calls, e.g. right after new data started flowing. Default: false"),
+			),
 			mcp.WithReadOnlyHintAnnotation(true),
 			mcp.WithIdempotentHintAnnotation(true),
 			mcp.WithDestructiveHintAnnotation(false),
@@ -166,6 +169,9 @@ After calling this tool, use build_cql tool to construct queries from structured
 			}
 
 			includeSamples := request.GetBool("include_sample_values", true)
+			if request.GetBool("refresh", false) {
+				ctx = BypassSchemaCache(ctx)
+			}
 
 			result := SchemaDiscovery{
 				Scope:            scope,
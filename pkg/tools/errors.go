@@ -0,0 +1,186 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ErrorCode is a stable, machine-readable identifier of a class of tool errors. Clients can
+// implement retry and fallback policies on it instead of parsing error messages, which may
+// change between releases.
+type ErrorCode string
+
+const (
+	// ErrCodeAuthFailed means the credentials are missing or were rejected (401).
+	ErrCodeAuthFailed ErrorCode = "ED_MCP_AUTH_FAILED"
+	// ErrCodePermissionDenied means the credentials are valid but not allowed to do the call
+	// (403), e.g. for another org.
+	ErrCodePermissionDenied ErrorCode = "ED_MCP_PERMISSION_DENIED"
+	// ErrCodeRateLimited means the API (429) or a tool budget throttled the call. Retryable.
+	ErrCodeRateLimited ErrorCode = "ED_MCP_RATE_LIMITED"
+	// ErrCodeInvalidArgument means an argument of the tool call is missing or invalid.
+	ErrCodeInvalidArgument ErrorCode = "ED_MCP_INVALID_ARGUMENT"
+	// ErrCodeQueryInvalid means the API rejected the request, e.g. a CQL syntax error (400, 422).
+	ErrCodeQueryInvalid ErrorCode = "ED_MCP_QUERY_INVALID"
+	// ErrCodeNotFound means the requested resource does not exist (404).
+	ErrCodeNotFound ErrorCode = "ED_MCP_NOT_FOUND"
+	// ErrCodeConflict means the resource changed concurrently (409, 412).
+	ErrCodeConflict ErrorCode = "ED_MCP_CONFLICT"
+	// ErrCodeFeatureDisabled means the tool's feature is not enabled for the org.
+	ErrCodeFeatureDisabled ErrorCode = "ED_MCP_FEATURE_DISABLED"
+	// ErrCodeRejected means a hook of the server operator rejected the call.
+	ErrCodeRejected ErrorCode = "ED_MCP_REJECTED"
+	// ErrCodeUpstream5xx means the API failed (5xx). Retryable.
+	ErrCodeUpstream5xx ErrorCode = "ED_MCP_UPSTREAM_5XX"
+	// ErrCodeUpstreamUnreachable means the API could not be reached. Retryable.
+	ErrCodeUpstreamUnreachable ErrorCode = "ED_MCP_UPSTREAM_UNREACHABLE"
+	// ErrCodeTimeout means the call took too long. Retryable.
+	ErrCodeTimeout ErrorCode = "ED_MCP_TIMEOUT"
+	// ErrCodeCanceled means the client canceled the call.
+	ErrCodeCanceled ErrorCode = "ED_MCP_CANCELED"
+	// ErrCodeInternal is any other error.
+	ErrCodeInternal ErrorCode = "ED_MCP_INTERNAL"
+)
+
+// ToolError is the error payload of a failed tool call.
+type ToolError struct {
+	Code       ErrorCode `json:"code"`
+	Message    string    `json:"message"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Retryable  bool      `json:"retryable"`
+}
+
+func (e *ToolError) Error() string {
+	return e.Message
+}
+
+// statusCodePattern matches the status code of the API errors, which all read
+// "failed to <action>, status code <code>: <body>".
+var statusCodePattern = regexp.MustCompile(`status code (\d{3})`)
+
+// ClassifyError returns the tool error describing err.
+func ClassifyError(err error) *ToolError {
+	var toolErr *ToolError
+	if errors.As(err, &toolErr) {
+		return toolErr
+	}
+
+	toolErr = &ToolError{Code: ErrCodeInternal, Message: err.Error()}
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		toolErr.Code, toolErr.Retryable = ErrCodeTimeout, true
+	case errors.Is(err, context.Canceled):
+		toolErr.Code = ErrCodeCanceled
+	case errors.As(err, &netErr):
+		toolErr.Code, toolErr.Retryable = ErrCodeUpstreamUnreachable, true
+		if netErr.Timeout() {
+			toolErr.Code = ErrCodeTimeout
+		}
+	default:
+		if m := statusCodePattern.FindStringSubmatch(err.Error()); m != nil {
+			toolErr.StatusCode, _ = strconv.Atoi(m[1])
+			toolErr.Code, toolErr.Retryable = statusErrorCode(toolErr.StatusCode)
+		}
+	}
+	return toolErr
+}
+
+func statusErrorCode(statusCode int) (ErrorCode, bool) {
+	switch {
+	case statusCode == http.StatusUnauthorized:
+		return ErrCodeAuthFailed, false
+	case statusCode == http.StatusForbidden:
+		return ErrCodePermissionDenied, false
+	case statusCode == http.StatusNotFound:
+		return ErrCodeNotFound, false
+	case statusCode == http.StatusConflict || statusCode == http.StatusPreconditionFailed:
+		return ErrCodeConflict, false
+	case statusCode == http.StatusTooManyRequests:
+		return ErrCodeRateLimited, true
+	case statusCode == http.StatusRequestTimeout || statusCode == http.StatusGatewayTimeout:
+		return ErrCodeTimeout, true
+	case statusCode >= http.StatusInternalServerError:
+		return ErrCodeUpstream5xx, true
+	case statusCode >= http.StatusBadRequest:
+		return ErrCodeQueryInvalid, false
+	default:
+		return ErrCodeInternal, false
+	}
+}
+
+type errorPayload struct {
+	Error *ToolError `json:"error"`
+}
+
+// NewToolResultErrorCode returns an error result with the message and the error payload.
+func NewToolResultErrorCode(code ErrorCode, message string) *mcp.CallToolResult {
+	return newToolResultToolError(&ToolError{Code: code, Message: message, Retryable: code == ErrCodeRateLimited})
+}
+
+func newToolResultToolError(toolErr *ToolError) *mcp.CallToolResult {
+	result := mcp.NewToolResultError(toolErr.Message)
+	payload, err := json.Marshal(errorPayload{Error: toolErr})
+	if err == nil {
+		result.Content = append(result.Content, mcp.NewTextContent(string(payload)))
+	}
+	return result
+}
+
+// hasErrorPayload reports whether the result already carries an error payload.
+func hasErrorPayload(result *mcp.CallToolResult) bool {
+	for _, content := range result.Content {
+		text, ok := content.(mcp.TextContent)
+		if !ok {
+			continue
+		}
+		var payload errorPayload
+		if json.Unmarshal([]byte(text.Text), &payload) == nil && payload.Error != nil && payload.Error.Code != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrorCodes returns a tool middleware that turns handler errors into error results carrying a
+// ToolError payload, {"error": {"code": ..., "message": ..., "retryable": ...}}, after the
+// message. Error results without a payload, i.e. argument validation failures, get one with
+// ErrCodeInvalidArgument.
+func ErrorCodes() ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			result, err := next(ctx, request)
+			switch {
+			case err != nil && result != nil && result.IsError:
+				// Handlers return both for missing required parameters
+				return newToolResultToolError(&ToolError{Code: ErrCodeInvalidArgument, Message: resultText(result)}), nil
+			case err != nil:
+				return newToolResultToolError(ClassifyError(err)), nil
+			case result != nil && result.IsError && !hasErrorPayload(result):
+				payload, err := json.Marshal(errorPayload{Error: &ToolError{Code: ErrCodeInvalidArgument, Message: resultText(result)}})
+				if err == nil {
+					result.Content = append(result.Content, mcp.NewTextContent(string(payload)))
+				}
+			}
+			return result, nil
+		}
+	}
+}
+
+// resultText returns the first text content of the result.
+func resultText(result *mcp.CallToolResult) string {
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			return text.Text
+		}
+	}
+	return ""
+}
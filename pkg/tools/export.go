@@ -0,0 +1,223 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Export formats of the search tools.
+const (
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+)
+
+// preferredColumns lead the columns of exports without explicit columns, the others follow in
+// alphabetical order.
+var preferredColumns = []string{"timestamp", "service.name", "severity_text", "name", "body"}
+
+// formatParam is the format parameter of the search tools.
+func formatParam() mcp.ToolOption {
+	return mcp.WithString("format",
+		mcp.Description(`Format of the items. "json" returns the API response. "ndjson" returns one JSON object per line and "csv" a header and one row per item, e.g. to paste into a spreadsheet; nested fields are flattened to dotted columns such as attributes.user.id. The count, cursor and guidance come first in a separate JSON block.`),
+		mcp.Enum(FormatJSON, FormatNDJSON, FormatCSV),
+		mcp.DefaultString(FormatJSON),
+	)
+}
+
+// columnsParam is the columns parameter of the search tools, see formatParam.
+func columnsParam() mcp.ToolOption {
+	return mcp.WithArray("columns",
+		mcp.Description(`Columns of ndjson and csv exports, in order, e.g. ["timestamp", "service.name", "body"]. All fields of the items by default.`),
+		mcp.WithStringItems(),
+	)
+}
+
+// exportOptions returns the format and columns arguments of a search call.
+func exportOptions(request mcp.CallToolRequest) (string, []string, *mcp.CallToolResult) {
+	format, _ := params.Optional[string](request, "format")
+	switch format {
+	case "", FormatJSON:
+		return FormatJSON, nil, nil
+	case FormatNDJSON, FormatCSV:
+	default:
+		return "", nil, mcp.NewToolResultError(fmt.Sprintf(`invalid format %q, expected "json", "ndjson" or "csv"`, format))
+	}
+
+	var columns []string
+	for _, column := range request.GetStringSlice("columns", nil) {
+		if column = strings.TrimSpace(column); column != "" {
+			columns = append(columns, column)
+		}
+	}
+	return format, columns, nil
+}
+
+// exportSearchResponse returns the items of a search response in the format, followed by the
+// response without its data. Responses without items are returned as JSON.
+func exportSearchResponse(response SearchResponse, format string, columns []string) (*mcp.CallToolResult, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(response.Data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode search response: %v", err)
+	}
+	values, ok := doc["items"].([]any)
+	if !ok {
+		response.Guidance.Suggestions = append(response.Guidance.Suggestions, fmt.Sprintf("The response has no items to export as %s, it is returned as JSON", format))
+		result, _ := json.Marshal(response)
+		return mcp.NewToolResultText(string(result)), nil
+	}
+
+	items := make([]map[string]any, 0, len(values))
+	fields := make(map[string]bool)
+	for _, v := range values {
+		item := make(map[string]any)
+		flattenItem("", v, item)
+		for field := range item {
+			fields[field] = true
+		}
+		items = append(items, item)
+	}
+
+	if len(columns) == 0 {
+		columns = exportColumns(fields)
+	} else {
+		var missing []string
+		for _, column := range columns {
+			if !fields[column] {
+				missing = append(missing, column)
+			}
+		}
+		if len(missing) > 0 && len(items) > 0 {
+			response.Guidance.Suggestions = append(response.Guidance.Suggestions,
+				fmt.Sprintf("No item has the columns %s; the items have %s", strings.Join(missing, ", "), strings.Join(exportColumns(fields), ", ")))
+		}
+	}
+
+	var export string
+	var err error
+	if format == FormatCSV {
+		export, err = itemsCSV(items, columns)
+	} else {
+		export, err = itemsNDJSON(items, columns)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	response.Data = nil
+	response.Format, response.Columns = format, columns
+	response.NextCursor = findCursor(doc)
+	summary, err := json.Marshal(response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal response: %w", err)
+	}
+
+	// The summary comes first, so the cursor is found in the first JSON block of the result
+	result := mcp.NewToolResultText(string(summary))
+	if export != "" {
+		result.Content = append(result.Content, mcp.NewTextContent(export))
+	}
+	return result, nil
+}
+
+// flattenItem sets the fields of v in item, nested objects as dotted keys.
+func flattenItem(prefix string, v any, item map[string]any) {
+	object, ok := v.(map[string]any)
+	if !ok {
+		item[prefix] = v
+		return
+	}
+	if len(object) == 0 && prefix != "" {
+		item[prefix] = object
+		return
+	}
+	for key, value := range object {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		flattenItem(key, value, item)
+	}
+}
+
+func exportColumns(fields map[string]bool) []string {
+	var columns, others []string
+	for _, column := range preferredColumns {
+		if fields[column] {
+			columns = append(columns, column)
+		}
+	}
+	for field := range fields {
+		if !slices.Contains(preferredColumns, field) {
+			others = append(others, field)
+		}
+	}
+	sort.Strings(others)
+	return append(columns, others...)
+}
+
+func itemsCSV(items []map[string]any, columns []string) (string, error) {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	if err := w.Write(columns); err != nil {
+		return "", fmt.Errorf("failed to write csv: %w", err)
+	}
+	row := make([]string, len(columns))
+	for _, item := range items {
+		for i, column := range columns {
+			row[i] = csvValue(item[column])
+		}
+		if err := w.Write(row); err != nil {
+			return "", fmt.Errorf("failed to write csv: %w", err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", fmt.Errorf("failed to write csv: %w", err)
+	}
+	return b.String(), nil
+}
+
+func csvValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+}
+
+func itemsNDJSON(items []map[string]any, columns []string) (string, error) {
+	var b strings.Builder
+	for _, item := range items {
+		// Columns without a value are left out
+		object := make(map[string]any, len(columns))
+		for _, column := range columns {
+			if v, ok := item[column]; ok {
+				object[column] = v
+			}
+		}
+		line, err := json.Marshal(object)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal item: %w", err)
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/url"
 	"regexp"
+	"strconv"
 
 	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
 
@@ -47,6 +48,7 @@ type FacetOptionsResponse struct {
 	Scope       string         `json:"scope"`
 	TotalValues int            `json:"total_values"`
 	Options     []FacetOption  `json:"options"`
+	Sample      *FacetSample   `json:"sample,omitempty"`
 	Guidance    *FacetGuidance `json:"guidance,omitempty"`
 }
 
@@ -65,6 +67,9 @@ To get VALUES for a field, use facet_options tool.`),
 		mcp.Required(),
 		mcp.Enum("log", "metric", "trace", "pattern", "event"),
 	),
+	mcp.WithBoolean("refresh",
+		mcp.Description("If true, fetch fresh field names instead of the ones cached from recent calls, e.g. right after new data started flowing. Default: false"),
+	),
 	mcp.WithReadOnlyHintAnnotation(true),
 	mcp.WithIdempotentHintAnnotation(true),
 	mcp.WithDestructiveHintAnnotation(false),
@@ -101,6 +106,24 @@ Use build_cql tool to construct queries from structured parameters, or validate_
 		mcp.Description("The maximum number of facet options to return. Default is 100."),
 		mcp.DefaultString("100"),
 	),
+	mcp.WithString("strategy",
+		mcp.Description(`How to pick the returned options on high-cardinality facets:
+- top: most frequent values (default)
+- random: random sample of the values, to see past a skewed head
+- alphabetical: values sorted by name, paged with offset`),
+		mcp.DefaultString(FacetSampleTop),
+		mcp.Enum(FacetSampleTop, FacetSampleRandom, FacetSampleAlphabetical),
+	),
+	mcp.WithNumber("offset",
+		mcp.Description("Offset of the page for the alphabetical strategy. Default is 0."),
+		mcp.DefaultNumber(0),
+	),
+	mcp.WithBoolean("cardinality",
+		mcp.Description("If true, also count the distinct values of the field, up to 5000, with the top strategy. The random and alphabetical strategies always count them. Default: false"),
+	),
+	mcp.WithBoolean("refresh",
+		mcp.Description("If true, fetch fresh values instead of the ones cached from recent calls, e.g. right after new data started flowing. Default: false"),
+	),
 	mcp.WithReadOnlyHintAnnotation(true),
 	mcp.WithIdempotentHintAnnotation(true),
 	mcp.WithDestructiveHintAnnotation(false),
@@ -114,6 +137,55 @@ var FacetOptionsResource = mcp.NewResourceTemplate(
 	mcp.WithTemplateMIMEType("application/json"),
 )
 
+var CreateFacetTool = mcp.NewTool("create_facet",
+	mcp.WithTitleAnnotation("Create Facet"),
+	mcp.WithDescription(`Promotes a frequently used attribute into a user-defined facet for faster filtering.
+
+WHEN TO USE:
+- An attribute (e.g. @user.id) is filtered on repeatedly but is not listed by the facets tool
+- Builtin facets already exist and can not be created again
+
+After creation, the facet shows up under the facets tool and its values can be fetched with facet_options tool.`),
+	mcp.WithString("path",
+		mcp.Description("The field path to promote to a facet, e.g. '@user.id' or 'k8s.deployment.name'."),
+		mcp.Required(),
+	),
+	mcp.WithString("name",
+		mcp.Description("Display name of the facet. Defaults to the path."),
+		mcp.DefaultString(""),
+	),
+	mcp.WithString("scope",
+		mcp.Description("The scope the facet belongs to. Available scopes: 'log', 'metric', 'trace', 'pattern', 'event'"),
+		mcp.Required(),
+		mcp.Enum("log", "metric", "trace", "pattern", "event"),
+	),
+	mcp.WithReadOnlyHintAnnotation(false),
+	mcp.WithIdempotentHintAnnotation(false),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithOpenWorldHintAnnotation(false),
+)
+
+var DeleteFacetTool = mcp.NewTool("delete_facet",
+	mcp.WithTitleAnnotation("Delete Facet"),
+	mcp.WithDescription(`Deletes a user-defined facet.
+
+Only user-defined facets can be deleted, builtin facets are rejected.
+Use the facets tool first to see which facets exist for the scope.`),
+	mcp.WithString("path",
+		mcp.Description("The path of the user-defined facet to delete."),
+		mcp.Required(),
+	),
+	mcp.WithString("scope",
+		mcp.Description("The scope the facet belongs to. Available scopes: 'log', 'metric', 'trace', 'pattern', 'event'"),
+		mcp.Required(),
+		mcp.Enum("log", "metric", "trace", "pattern", "event"),
+	),
+	mcp.WithReadOnlyHintAnnotation(false),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithOpenWorldHintAnnotation(false),
+)
+
 func FacetsToolHandler(client Client) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		scope, err := request.RequireString("scope")
@@ -121,6 +193,10 @@ func FacetsToolHandler(client Client) server.ToolHandlerFunc {
 			return mcp.NewToolResultError("missing required parameter: scope"), err
 		}
 
+		if request.GetBool("refresh", false) {
+			ctx = BypassSchemaCache(ctx)
+		}
+
 		result, err := GetFacets(ctx, client, WithScope(scope))
 		if err != nil {
 			return nil, fmt.Errorf("failed to get facets, err: %w", err)
@@ -201,27 +277,54 @@ func FacetOptionsToolHandler(client Client) server.ToolHandlerFunc {
 			return mcp.NewToolResultError("missing required parameter: scope"), err
 		}
 
-		limit, err := params.Optional[string](request, "limit")
+		limitStr, err := params.Optional[string](request, "limit")
 		if err != nil {
 			return mcp.NewToolResultError("invalid parameter: limit"), err
 		}
 
-		result, err := GetFacetOptions(ctx, client, WithScope(scope), WithFacet(facet), WithLimit(limit))
+		limit := defaultFacetLimit
+		if limitStr != "" {
+			if limit, err = strconv.Atoi(limitStr); err != nil || limit <= 0 {
+				return mcp.NewToolResultError("invalid parameter: limit"), fmt.Errorf("limit must be a positive number, got %q", limitStr)
+			}
+		}
+
+		strategy, _ := params.Optional[string](request, "strategy")
+		if strategy == "" {
+			strategy = FacetSampleTop
+		}
+
+		offset, _ := params.Optional[float64](request, "offset")
+		if offset < 0 {
+			return mcp.NewToolResultError("invalid parameter: offset"), fmt.Errorf("offset must not be negative, got %v", offset)
+		}
+		if request.GetBool("refresh", false) {
+			ctx = BypassSchemaCache(ctx)
+		}
+
+		poolSize := facetOptionsPoolSize(strategy, limit, request.GetBool("cardinality", false))
+		result, err := GetFacetOptions(ctx, client, WithScope(scope), WithFacet(facet), WithLimit(strconv.Itoa(poolSize)))
 		if err != nil {
 			return nil, fmt.Errorf("failed to get facet options, err: %w", err)
 		}
 
-		// Wrap result with guidance
-		var options []FacetOption
+		var pool []FacetOption
 		if result != nil {
-			options = result.Options
+			pool = result.Options
+		}
+
+		options, sample, err := sampleFacetOptions(pool, poolSize, strategy, limit, int(offset))
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), err
 		}
 
+		// Wrap result with guidance
 		response := FacetOptionsResponse{
 			FacetPath:   facet,
 			Scope:       scope,
 			TotalValues: len(options),
 			Options:     options,
+			Sample:      &sample,
 		}
 
 		if len(options) == 0 {
@@ -245,6 +348,18 @@ func FacetOptionsToolHandler(client Client) server.ToolHandlerFunc {
 					"Use build_cql tool to construct queries from structured parameters, or validate_cql tool to check existing query syntax.",
 				},
 			}
+
+			if len(options) == sample.TotalCardinality && !sample.CardinalityExact {
+				response.Guidance.Suggestions = append(response.Guidance.Suggestions,
+					fmt.Sprintf("There may be more than these %d values. Use cardinality:true to count them, or strategy:\"random\" or strategy:\"alphabetical\" with offset to see other values.", len(options)))
+			} else if len(options) < sample.TotalCardinality {
+				cardinality := fmt.Sprintf("%d", sample.TotalCardinality)
+				if !sample.CardinalityExact {
+					cardinality = "at least " + cardinality
+				}
+				response.Guidance.Suggestions = append(response.Guidance.Suggestions,
+					fmt.Sprintf("Only %d of %s distinct values are shown. Use strategy:\"random\" or strategy:\"alphabetical\" with offset to see other values.", len(options), cardinality))
+			}
 		}
 
 		r, err := json.Marshal(response)
@@ -280,6 +395,85 @@ func FacetOptionsResourceHandler(client Client) server.ResourceTemplateHandlerFu
 	}
 }
 
+func CreateFacetToolHandler(client Client) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		path, err := request.RequireString("path")
+		if err != nil {
+			return mcp.NewToolResultError("missing required parameter: path"), err
+		}
+
+		scope, err := request.RequireString("scope")
+		if err != nil {
+			return mcp.NewToolResultError("missing required parameter: scope"), err
+		}
+
+		name, _ := params.Optional[string](request, "name")
+		if name == "" {
+			name = path
+		}
+
+		created, err := CreateFacet(ctx, client, Facet{Name: name, Path: path, Scope: scope})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create facet, err: %w", err)
+		}
+
+		response := FacetsToolResponse{
+			Scope:  scope,
+			Facets: []Facet{*created},
+			Guidance: &FacetGuidance{
+				ResultStatus: "success",
+				NextSteps: []string{
+					fmt.Sprintf("Facet '%s' created for scope '%s'.", path, scope),
+					fmt.Sprintf("Example: facet_options(scope:\"%s\", facet_path:\"%s\") to see values.", scope, path),
+				},
+			},
+		}
+
+		r, err := json.Marshal(response)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal response, err: %w", err)
+		}
+
+		return mcp.NewToolResultText(string(r)), nil
+	}
+}
+
+func DeleteFacetToolHandler(client Client) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		path, err := request.RequireString("path")
+		if err != nil {
+			return mcp.NewToolResultError("missing required parameter: path"), err
+		}
+
+		scope, err := request.RequireString("scope")
+		if err != nil {
+			return mcp.NewToolResultError("missing required parameter: scope"), err
+		}
+
+		if err := DeleteFacet(ctx, client, scope, path); err != nil {
+			return nil, fmt.Errorf("failed to delete facet, err: %w", err)
+		}
+
+		response := FacetsToolResponse{
+			Scope:  scope,
+			Facets: []Facet{},
+			Guidance: &FacetGuidance{
+				ResultStatus: "success",
+				NextSteps: []string{
+					fmt.Sprintf("Facet '%s' deleted from scope '%s'.", path, scope),
+				},
+			},
+		}
+
+		r, err := json.Marshal(response)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal response, err: %w", err)
+		}
+
+		return mcp.NewToolResultText(string(r)), nil
+	}
+}
+
 func WithScope(scope string) QueryParamOption {
 	return func(v url.Values) {
 		if scope != "" {
@@ -86,7 +86,7 @@ func GetFacetKeys(ctx context.Context, client Client, scope string, opts ...Quer
 	}
 
 	facetKeysURL.RawQuery = queryParams.Encode()
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, facetKeysURL.String(), nil)
+	req, err := http.NewRequestWithContext(schemaRequest(ctx), http.MethodGet, facetKeysURL.String(), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create facet keys request: %v", err)
 	}
@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Facet option sampling strategies of the facet_options tool.
+const (
+	FacetSampleTop          = "top"
+	FacetSampleRandom       = "random"
+	FacetSampleAlphabetical = "alphabetical"
+)
+
+const (
+	// facetOptionsPoolLimit is the maximum number of options fetched to sample from and to
+	// estimate cardinality with. Facets with more values only get a lower bound.
+	facetOptionsPoolLimit = 5000
+	defaultFacetLimit     = 100
+)
+
+// FacetSample describes which part of a facet's values a facet_options response contains.
+type FacetSample struct {
+	Strategy string `json:"strategy"`
+	Offset   int    `json:"offset,omitempty"`
+	// TotalCardinality is the number of distinct values seen. It is exact when CardinalityExact is
+	// true and a lower bound otherwise.
+	TotalCardinality int  `json:"total_cardinality"`
+	CardinalityExact bool `json:"cardinality_exact"`
+	// Coverage is the share of the total occurrence count of the seen values that the returned
+	// options account for, when counts are available.
+	Coverage float64 `json:"coverage,omitempty"`
+}
+
+// facetOptionsPoolSize returns how many options to fetch for a request of limit options. Only the
+// random and alphabetical strategies, and requests counting the distinct values, need more than
+// the most frequent limit options.
+func facetOptionsPoolSize(strategy string, limit int, cardinality bool) int {
+	if strategy == FacetSampleTop && (!cardinality || limit >= facetOptionsPoolLimit) {
+		return limit
+	}
+	return facetOptionsPoolLimit
+}
+
+// sampleFacetOptions selects limit options from the pool, which the API returns ordered by count.
+// The pool holds every value of the facet when it has less than the poolSize options fetched.
+func sampleFacetOptions(pool []FacetOption, poolSize int, strategy string, limit, offset int) ([]FacetOption, FacetSample, error) {
+	sample := FacetSample{
+		Strategy:         strategy,
+		TotalCardinality: len(pool),
+		CardinalityExact: len(pool) < poolSize,
+	}
+
+	var selected []FacetOption
+	switch strategy {
+	case FacetSampleTop:
+		selected = pool[:min(limit, len(pool))]
+	case FacetSampleRandom:
+		shuffled := append([]FacetOption(nil), pool...)
+		r := rand.New(rand.NewSource(time.Now().UnixNano()))
+		r.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+		selected = shuffled[:min(limit, len(shuffled))]
+	case FacetSampleAlphabetical:
+		sorted := append([]FacetOption(nil), pool...)
+		sort.Slice(sorted, func(i, j int) bool { return strings.ToLower(sorted[i].Name) < strings.ToLower(sorted[j].Name) })
+		sample.Offset = offset
+		if offset >= 0 && offset < len(sorted) {
+			selected = sorted[offset:min(offset+limit, len(sorted))]
+		}
+	default:
+		return nil, sample, fmt.Errorf("unknown strategy %q, expected one of: %s, %s, %s", strategy, FacetSampleTop, FacetSampleRandom, FacetSampleAlphabetical)
+	}
+
+	total, covered := 0, 0
+	for _, o := range pool {
+		total += o.Count
+	}
+	for _, o := range selected {
+		covered += o.Count
+	}
+	// The coverage of a pool of the selected options only is always 1
+	if total > 0 && (sample.CardinalityExact || len(selected) < len(pool)) {
+		sample.Coverage = float64(covered) / float64(total)
+	}
+
+	return selected, sample, nil
+}
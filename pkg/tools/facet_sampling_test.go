@@ -0,0 +1,64 @@
+package tools
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFacetOptionsPoolSize(t *testing.T) {
+	tests := []struct {
+		strategy    string
+		limit       int
+		cardinality bool
+		want        int
+	}{
+		{FacetSampleTop, 100, false, 100},
+		{FacetSampleTop, 100, true, facetOptionsPoolLimit},
+		{FacetSampleTop, 8000, true, 8000},
+		{FacetSampleRandom, 100, false, facetOptionsPoolLimit},
+		{FacetSampleAlphabetical, 100, false, facetOptionsPoolLimit},
+	}
+	for _, tt := range tests {
+		if got := facetOptionsPoolSize(tt.strategy, tt.limit, tt.cardinality); got != tt.want {
+			t.Errorf("facetOptionsPoolSize(%s, %d, %t) = %d, want %d", tt.strategy, tt.limit, tt.cardinality, got, tt.want)
+		}
+	}
+}
+
+func TestSampleFacetOptionsCardinality(t *testing.T) {
+	pool := func(n int) []FacetOption {
+		options := make([]FacetOption, n)
+		for i := range options {
+			options[i] = FacetOption{Name: fmt.Sprintf("value-%d", i), Count: n - i}
+		}
+		return options
+	}
+
+	tests := []struct {
+		name         string
+		pool         []FacetOption
+		poolSize     int
+		limit        int
+		wantExact    bool
+		wantCoverage bool
+	}{
+		// The API returned as many options as asked for, there may be more
+		{"top of a full pool", pool(10), 10, 10, false, false},
+		{"top of a partial pool", pool(4), 10, 10, true, true},
+		{"top of a counted pool", pool(50), facetOptionsPoolLimit, 10, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, sample, err := sampleFacetOptions(tt.pool, tt.poolSize, FacetSampleTop, tt.limit, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sample.CardinalityExact != tt.wantExact {
+				t.Errorf("cardinality exact is %t, want %t", sample.CardinalityExact, tt.wantExact)
+			}
+			if hasCoverage := sample.Coverage > 0; hasCoverage != tt.wantCoverage {
+				t.Errorf("coverage is %v, want a coverage: %t", sample.Coverage, tt.wantCoverage)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/storage"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Org level features of Edge Delta that tools depend on.
+const (
+	FeatureMetrics  = "metrics"
+	FeatureTraces   = "traces"
+	FeaturePatterns = "patterns"
+	FeatureEvents   = "events"
+)
+
+// toolFeatures maps tools to the feature they need. Tools not listed are always available.
+var toolFeatures = map[string]string{
+	"get_metric_search":     FeatureMetrics,
+	"get_metric_graph":      FeatureMetrics,
+	"search_metrics":        FeatureMetrics,
+	"get_trace_timeline":    FeatureTraces,
+	"get_trace_error_chain": FeatureTraces,
+	"get_trace_by_id":       FeatureTraces,
+	"get_service_map":       FeatureTraces,
+	"get_span_events":       FeatureTraces,
+	"get_trace_graph":       FeatureTraces,
+	"get_log_patterns":      FeaturePatterns,
+	"get_sentiment_trend":   FeaturePatterns,
+	"get_pattern_graph":     FeaturePatterns,
+	"get_event_search":      FeatureEvents,
+	"anomaly_search":        FeatureEvents,
+	"detect_anomalies":      FeatureEvents,
+}
+
+// OrgFeaturesResponse mirrors the backend response from GET /v1/orgs/{org_id}/features
+type OrgFeaturesResponse struct {
+	Features map[string]bool `json:"features"`
+}
+
+func GetOrgFeatures(ctx context.Context, client Client) (map[string]bool, error) {
+	keys, err := FetchContextKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	featuresURL, err := url.Parse(fmt.Sprintf("%s/v1/orgs/%s/features", client.APIURL(), keys.OrgID))
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := createRequest(ctx, featuresURL, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create features request: %v", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to get features, status code %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var out OrgFeaturesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode features response: %v", err)
+	}
+	return out.Features, nil
+}
+
+// FeatureFlags hides and rejects the tools of features that are disabled for the caller's org.
+// Features are fetched per org and cached in the storage for the TTL. If they cannot be fetched,
+// or a feature is not reported by the backend, its tools stay available.
+type FeatureFlags struct {
+	client Client
+	store  storage.Interface
+	ttl    time.Duration
+}
+
+func NewFeatureFlags(client Client, store storage.Interface, ttl time.Duration) *FeatureFlags {
+	return &FeatureFlags{
+		client: client,
+		store:  store,
+		ttl:    ttl,
+	}
+}
+
+// disabledFeature returns the feature the tool needs if it is disabled for the caller's org.
+func (f *FeatureFlags) disabledFeature(ctx context.Context, toolName string) (string, bool) {
+	feature, ok := toolFeatures[toolName]
+	if !ok {
+		return "", false
+	}
+
+	enabled, known := f.features(ctx)[feature]
+	return feature, known && !enabled
+}
+
+func (f *FeatureFlags) features(ctx context.Context) map[string]bool {
+	keys, err := FetchContextKeys(ctx)
+	if err != nil {
+		return nil
+	}
+
+	key := "features/" + keys.OrgID
+	var features map[string]bool
+	if data, err := f.store.Get(ctx, key); err == nil && json.Unmarshal(data, &features) == nil {
+		return features
+	}
+
+	// A failed fetch is cached too, so an older backend without the endpoint is not asked on every call.
+	features, _ = GetOrgFeatures(ctx, f.client)
+	if data, err := json.Marshal(features); err == nil {
+		_ = f.store.Set(ctx, key, data, f.ttl)
+	}
+	return features
+}
+
+// ToolFilter hides the tools of disabled features from tool listings.
+func (f *FeatureFlags) ToolFilter() server.ToolFilterFunc {
+	return func(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+		filtered := make([]mcp.Tool, 0, len(tools))
+		for _, tool := range tools {
+			if _, disabled := f.disabledFeature(ctx, tool.Name); !disabled {
+				filtered = append(filtered, tool)
+			}
+		}
+		return filtered
+	}
+}
+
+// Middleware rejects calls of tools whose feature is disabled, for clients that call tools
+// they did not list.
+func (f *FeatureFlags) Middleware() ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			if feature, disabled := f.disabledFeature(ctx, request.Params.Name); disabled {
+				return NewToolResultErrorCode(ErrCodeFeatureDisabled, fmt.Sprintf("%s is not available: the %s feature is not enabled for this organization", request.Params.Name, feature)), nil
+			}
+			return next(ctx, request)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// defaultFetchAllItems and maxFetchAllItems bound the items of a fetch_all call.
+	defaultFetchAllItems = 1000
+	maxFetchAllItems     = 5000
+	// maxFetchAllPages bounds the API calls of a fetch_all call.
+	maxFetchAllPages = 50
+	// fetchAllPageSize is the page size of fetch_all calls without a limit.
+	fetchAllPageSize = 500
+	// fetchAllDeadlineMargin is left of the deadline of the call to return the pages fetched.
+	fetchAllDeadlineMargin = 5 * time.Second
+)
+
+// FetchAllSummary describes the pages of a fetch_all call.
+type FetchAllSummary struct {
+	Pages    int  `json:"pages"`
+	Items    int  `json:"items"`
+	Complete bool `json:"complete"`
+	// StopReason tells why an incomplete call stopped: max_items, max_pages, deadline or error.
+	StopReason string `json:"stop_reason,omitempty"`
+	// Error is the error of the page that failed, the pages before it are returned.
+	Error string `json:"error,omitempty"`
+}
+
+// fetchAllParam is the fetch_all parameter of the cursor based search tools.
+func fetchAllParam() mcp.ToolOption {
+	return mcp.WithBoolean("fetch_all",
+		mcp.Description(fmt.Sprintf(`If true, follow next_cursor on the server and return the items of all pages at once, instead of one page per call. Stops at max_items, after %d pages or before the call times out; the fetch_all block of the result tells whether all pages were fetched, and next_cursor continues from there. Pages are of limit items, %d by default.`, maxFetchAllPages, fetchAllPageSize)),
+	)
+}
+
+// maxItemsParam is the max_items parameter of the cursor based search tools, see fetchAllParam.
+func maxItemsParam() mcp.ToolOption {
+	return mcp.WithNumber("max_items",
+		mcp.Description(fmt.Sprintf("Maximum number of items fetched with fetch_all. Default is %d, max is %d.", defaultFetchAllItems, maxFetchAllItems)),
+		mcp.Min(1),
+		mcp.Max(maxFetchAllItems),
+	)
+}
+
+// fetchAll are the options of a fetch_all call.
+type fetchAll struct {
+	MaxItems int
+	PageSize int
+}
+
+// fetchAllOptions returns the options of a fetch_all call, or nil if fetch_all is not set.
+func fetchAllOptions(request mcp.CallToolRequest) (*fetchAll, *mcp.CallToolResult) {
+	if enabled, _ := params.Optional[bool](request, "fetch_all"); !enabled {
+		return nil, nil
+	}
+
+	opts := &fetchAll{MaxItems: defaultFetchAllItems, PageSize: fetchAllPageSize}
+	if limit, _ := params.Optional[float64](request, "limit"); limit < 0 {
+		return nil, mcp.NewToolResultError("fetch_all pages forward, it cannot be used with a negative limit")
+	} else if limit > 0 {
+		opts.PageSize = int(limit)
+	}
+	if v, _ := params.Optional[float64](request, "max_items"); v > 0 {
+		opts.MaxItems = int(v)
+	}
+	if opts.MaxItems > maxFetchAllItems {
+		return nil, mcp.NewToolResultError(fmt.Sprintf("max_items must be at most %d", maxFetchAllItems))
+	}
+	return opts, nil
+}
+
+// searchPages calls search for one page, or for all pages if opts is set.
+func searchPages(ctx context.Context, queryParams url.Values, opts *fetchAll, search func(context.Context, url.Values) ([]byte, error)) ([]byte, *FetchAllSummary, error) {
+	if opts == nil {
+		bodyBytes, err := search(ctx, queryParams)
+		return bodyBytes, nil, err
+	}
+	bodyBytes, summary, err := fetchAllPages(ctx, queryParams, *opts, search)
+	if err != nil {
+		return nil, nil, err
+	}
+	return bodyBytes, &summary, nil
+}
+
+// fetchAllPages calls search for the pages of queryParams until there is no next cursor or a
+// limit is reached, and returns the last page with the items of all pages. The cursor of the last
+// page is kept, so the caller can continue from there. An error of the first page is returned, the
+// ones of the next pages are reported in the summary with the items fetched so far.
+func fetchAllPages(ctx context.Context, queryParams url.Values, opts fetchAll, search func(context.Context, url.Values) ([]byte, error)) ([]byte, FetchAllSummary, error) {
+	var summary FetchAllSummary
+
+	// The query parameters of the caller are left as they are
+	pageParams := url.Values{}
+	for k, v := range queryParams {
+		pageParams[k] = append([]string(nil), v...)
+	}
+
+	var (
+		last  map[string]any
+		items []any
+	)
+	for {
+		pageParams.Set("limit", strconv.Itoa(min(opts.PageSize, opts.MaxItems-len(items))))
+		bodyBytes, err := search(ctx, pageParams)
+		if err != nil {
+			if last == nil {
+				return nil, summary, err
+			}
+			summary.StopReason, summary.Error = "error", err.Error()
+			break
+		}
+
+		var page map[string]any
+		if err := json.Unmarshal(bodyBytes, &page); err != nil {
+			if last == nil {
+				// Not a page of items, e.g. an error message, returned as it is
+				return bodyBytes, summary, nil
+			}
+			summary.StopReason, summary.Error = "error", fmt.Sprintf("failed to decode page %d: %v", summary.Pages+1, err)
+			break
+		}
+		pageItems, _ := page["items"].([]any)
+		items = append(items, pageItems...)
+		last = page
+		summary.Pages++
+
+		cursor := findCursor(page)
+		if cursor == "" || cursor == pageParams.Get("cursor") || len(pageItems) == 0 {
+			summary.Complete = true
+			break
+		}
+		if len(items) >= opts.MaxItems {
+			summary.StopReason = "max_items"
+			break
+		}
+		if summary.Pages >= maxFetchAllPages {
+			summary.StopReason = "max_pages"
+			break
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < fetchAllDeadlineMargin {
+			summary.StopReason = "deadline"
+			break
+		}
+		pageParams.Set("cursor", cursor)
+	}
+
+	if items == nil {
+		items = []any{}
+	}
+	last["items"] = items
+	summary.Items = len(items)
+	bodyBytes, err := json.Marshal(last)
+	if err != nil {
+		return nil, summary, fmt.Errorf("failed to marshal pages: %w", err)
+	}
+	return bodyBytes, summary, nil
+}
+
+// withFetchAll sets the fetch_all summary of the response, with a suggestion if it is incomplete.
+func withFetchAll(response *SearchResponse, summary *FetchAllSummary) {
+	if response.FetchAll = summary; summary != nil {
+		response.Guidance.Suggestions = append(response.Guidance.Suggestions, fetchAllGuidance(*summary)...)
+	}
+}
+
+func fetchAllGuidance(summary FetchAllSummary) []string {
+	switch summary.StopReason {
+	case "":
+		return nil
+	case "error":
+		return []string{fmt.Sprintf("Page %d failed, only the items of the first %d pages are returned: %s. Retry with next_cursor as cursor to fetch the rest.", summary.Pages+1, summary.Pages, summary.Error)}
+	case "max_items":
+		return []string{fmt.Sprintf("Stopped at max_items=%d, there are more items. Narrow the query, or continue with next_cursor as cursor.", summary.Items)}
+	default:
+		return []string{fmt.Sprintf("Stopped after %d pages (%s), there are more items. Narrow the query or the time range, or continue with next_cursor as cursor.", summary.Pages, summary.StopReason)}
+	}
+}
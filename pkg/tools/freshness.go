@@ -0,0 +1,255 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ingestLagResolution is the bucket size of the freshness graphs and so the precision of the lag.
+const ingestLagResolution = time.Minute
+
+// Freshness statuses of a signal.
+const (
+	FreshnessFresh   = "fresh"
+	FreshnessDelayed = "delayed"
+	FreshnessNoData  = "no_data"
+	FreshnessUnknown = "unknown"
+	FreshnessSkipped = "skipped"
+)
+
+type IngestLagResponse struct {
+	Service        string            `json:"service"`
+	Lookback       string            `json:"lookback"`
+	Resolution     string            `json:"resolution"`
+	DelayThreshold string            `json:"delay_threshold"`
+	Signals        []SignalFreshness `json:"signals"`
+	Warnings       []GraphWarning    `json:"warnings,omitempty"`
+	Guidance       *GraphGuidance    `json:"guidance,omitempty"`
+}
+
+// SignalFreshness is how recent the newest data of one signal is.
+type SignalFreshness struct {
+	Signal     string `json:"signal"`
+	Status     string `json:"status"`
+	Query      string `json:"query_used,omitempty"`
+	NewestData string `json:"newest_data,omitempty"`
+	LagSeconds *int64 `json:"lag_seconds,omitempty"`
+	Note       string `json:"note,omitempty"`
+}
+
+// GetIngestLagTool creates a tool to measure how far behind the newest data of a service is
+func GetIngestLagTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("get_ingest_lag",
+			mcp.WithTitleAnnotation("Get Ingest Lag"),
+			mcp.WithDescription(`Measure data freshness of a service: the time between now and the newest log, trace and metric data.
+
+WHEN TO USE:
+- "Is my data delayed or missing?"
+- Before concluding that a service is down because a search returned nothing
+
+The lag is measured at 1 minute resolution. A signal is "delayed" when its lag exceeds delay_threshold
+and "no_data" when nothing arrived within the lookback.
+Metrics are only checked when metric_name is given, since metric queries need a metric name.`),
+			mcp.WithString("service",
+				mcp.Description(`Service name as in the service.name field. Use the services://list resource or facet_options tool to find it.`),
+				mcp.Required(),
+			),
+			mcp.WithString("metric_name",
+				mcp.Description(`Optional EXACT name of a metric the service emits, to also check metric freshness. Use search_metrics tool to find it.`),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("lookback",
+				mcp.Description("How far back to look for the newest data in GOLANG duration format, or days and weeks, e.g. 1h, 24h or 7d."),
+				mcp.DefaultString("1h"),
+			),
+			mcp.WithString("delay_threshold",
+				mcp.Description("Lag above which a signal is reported as delayed, in GOLANG duration format, or days and weeks, e.g. 5m, 1h or 1d."),
+				mcp.DefaultString("5m"),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			service, _ := params.Optional[string](request, "service")
+			if service == "" {
+				return nil, fmt.Errorf(`"service" is required`)
+			}
+
+			lookback, _ := params.Optional[string](request, "lookback")
+			if lookback == "" {
+				lookback = "1h"
+			}
+
+			thresholdStr, _ := params.Optional[string](request, "delay_threshold")
+			if thresholdStr == "" {
+				thresholdStr = "5m"
+			}
+
+			threshold, err := ParseLookback(thresholdStr)
+			if err != nil {
+				return nil, fmt.Errorf(`"delay_threshold" must be a duration, got %q`, thresholdStr)
+			}
+
+			metricName, _ := params.Optional[string](request, "metric_name")
+
+			filter := fmt.Sprintf("service.name:%q", service)
+			signals := []SignalFreshness{
+				{Signal: "logs", Query: fmt.Sprintf("{%s}", filter)},
+				{Signal: "traces", Query: fmt.Sprintf("{%s}", filter)},
+				{Signal: "metrics"},
+			}
+			scopes := []string{"log", "trace", "metric"}
+
+			if metricName != "" {
+				signals[2].Query = MetricQuery{
+					Aggregation: "count",
+					Name:        metricName,
+					Filter:      filter,
+					Rollup:      int(ingestLagResolution.Seconds()),
+				}.CQL()
+			}
+
+			builder := NewGraphQueryBuilder()
+			for i, signal := range signals {
+				if signal.Query == "" {
+					continue
+				}
+				builder.WithQuery(fmt.Sprintf("Q%d", i+1), GraphQuery{Scope: scopes[i], Query: signal.Query}).
+					WithFormula(fmt.Sprintf("R%d", i+1), fmt.Sprintf("Q%d", i+1))
+			}
+
+			payload, err := builder.Build()
+			if err != nil {
+				return nil, err
+			}
+
+			queryParams := url.Values{}
+			queryParams.Add("lookback", lookback)
+			queryParams.Add("window", ingestLagResolution.String())
+
+			statusCode, bodyBytes, warnings, err := postGraphWithRetry(ctx, client, payload, queryParams)
+			if err != nil {
+				return nil, err
+			}
+
+			if statusCode != http.StatusMultiStatus {
+				return nil, fmt.Errorf("failed to get ingest lag, status code %d: %s", statusCode, string(bodyBytes))
+			}
+
+			var graphResp map[string]struct {
+				Records []graphTimeseriesRecord `json:"records"`
+			}
+			if err := json.Unmarshal(bodyBytes, &graphResp); err != nil {
+				return nil, fmt.Errorf("failed to decode graph response: %w", err)
+			}
+
+			now := time.Now().UTC()
+			for i := range signals {
+				if signals[i].Query == "" {
+					signals[i].Status = FreshnessSkipped
+					signals[i].Note = "Pass metric_name to check metric freshness."
+					continue
+				}
+
+				result, ok := graphResp[fmt.Sprintf("R%d", i+1)]
+				if !ok {
+					signals[i].Status = FreshnessUnknown
+					signals[i].Note = "The query failed, see warnings."
+					continue
+				}
+
+				setFreshness(&signals[i], result.Records, now, threshold)
+			}
+
+			response := IngestLagResponse{
+				Service:        service,
+				Lookback:       lookback,
+				Resolution:     ingestLagResolution.String(),
+				DelayThreshold: thresholdStr,
+				Signals:        signals,
+				Warnings:       warnings,
+			}
+			response.Guidance = ingestLagGuidance(response)
+
+			jsonResponse, err := json.Marshal(response)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+
+			return mcp.NewToolResultText(string(jsonResponse)), nil
+		}
+}
+
+// setFreshness sets the newest data time and lag of the signal from the newest non-empty bucket.
+// A bucket covers [timestamp, timestamp+resolution), so data in it is at most as old as its end.
+func setFreshness(signal *SignalFreshness, records []graphTimeseriesRecord, now time.Time, threshold time.Duration) {
+	var newest int64
+	for _, record := range records {
+		for _, point := range record.Timeseries {
+			if point.Value > 0 && point.Timestamp > newest {
+				newest = point.Timestamp
+			}
+		}
+	}
+
+	if newest == 0 {
+		signal.Status = FreshnessNoData
+		return
+	}
+
+	newestData := time.UnixMilli(newest).Add(ingestLagResolution).UTC()
+	if newestData.After(now) {
+		newestData = now
+	}
+
+	lag := now.Sub(newestData)
+	lagSeconds := int64(lag.Seconds())
+	signal.NewestData = newestData.Format(time.RFC3339)
+	signal.LagSeconds = &lagSeconds
+
+	signal.Status = FreshnessFresh
+	if lag > threshold {
+		signal.Status = FreshnessDelayed
+	}
+}
+
+func ingestLagGuidance(response IngestLagResponse) *GraphGuidance {
+	guidance := &GraphGuidance{ResultStatus: "success"}
+
+	for _, signal := range response.Signals {
+		switch signal.Status {
+		case FreshnessFresh:
+			guidance.NextSteps = append(guidance.NextSteps, fmt.Sprintf("%s are up to date (lag %ds).", signal.Signal, *signal.LagSeconds))
+		case FreshnessDelayed:
+			guidance.NextSteps = append(guidance.NextSteps, fmt.Sprintf("%s are delayed: the newest data is from %s (lag %ds).", signal.Signal, signal.NewestData, *signal.LagSeconds))
+		case FreshnessNoData:
+			guidance.NextSteps = append(guidance.NextSteps, fmt.Sprintf("No %s found for service %q in the last %s.", signal.Signal, response.Service, response.Lookback))
+		}
+	}
+
+	for _, signal := range response.Signals {
+		if signal.Status == FreshnessDelayed || signal.Status == FreshnessNoData {
+			guidance.Suggestions = append(guidance.Suggestions,
+				"Check the agents and pipelines sending this data with get_agent_self_logs and get_pipelines tools.",
+				"Verify the service name with facet_options tool, a typo also results in no data.",
+			)
+			break
+		}
+	}
+
+	if len(response.Warnings) > 0 {
+		guidance.ResultStatus = "partial"
+	}
+
+	return guidance
+}
@@ -1,14 +1,11 @@
 package tools
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
-	"strings"
 
 	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
 	"github.com/mark3labs/mcp-go/mcp"
@@ -16,9 +13,13 @@ import (
 )
 
 type GraphToolResponse struct {
-	Data     json.RawMessage `json:"data"`
-	Query    string          `json:"query_used,omitempty"`
-	Guidance *GraphGuidance  `json:"guidance,omitempty"`
+	Data       json.RawMessage   `json:"data,omitempty"`
+	Sparklines []SeriesSparkline `json:"sparklines,omitempty"`
+	Query      string            `json:"query_used,omitempty"`
+	UILink     string            `json:"ui_link,omitempty"`
+	Warnings   []GraphWarning    `json:"warnings,omitempty"`
+	Stats      *QueryStats       `json:"stats,omitempty"`
+	Guidance   *GraphGuidance    `json:"guidance,omitempty"`
 }
 
 type GraphGuidance struct {
@@ -27,32 +28,36 @@ type GraphGuidance struct {
 	Suggestions  []string `json:"suggestions,omitempty"`
 }
 
-func formatGraphResponse(bodyBytes []byte, query string) (*mcp.CallToolResult, error) {
+// graphHasData reports whether a graph response has any records.
+func graphHasData(bodyBytes []byte) bool {
 	var graphResp GraphResponse
-	hasData := false
-
-	if err := json.Unmarshal(bodyBytes, &graphResp); err == nil {
-		hasData = len(graphResp.Records) > 0
+	if err := json.Unmarshal(bodyBytes, &graphResp); err == nil && len(graphResp.Records) > 0 {
+		return true
 	}
 
 	// Check for formula-based response structure: {"R1": {"records": [...]}}
-	if !hasData {
-		var genericResp map[string]any
-		if err := json.Unmarshal(bodyBytes, &genericResp); err == nil {
-			for _, v := range genericResp {
-				if formulaResp, ok := v.(map[string]any); ok {
-					if records, ok := formulaResp["records"].([]any); ok && len(records) > 0 {
-						hasData = true
-						break
-					}
+	var genericResp map[string]any
+	if err := json.Unmarshal(bodyBytes, &genericResp); err == nil {
+		for _, v := range genericResp {
+			if formulaResp, ok := v.(map[string]any); ok {
+				if records, ok := formulaResp["records"].([]any); ok && len(records) > 0 {
+					return true
 				}
 			}
 		}
 	}
+	return false
+}
+
+func formatGraphResponse(bodyBytes []byte, query, link string, warnings []GraphWarning, render string) (*mcp.CallToolResult, error) {
+	hasData := graphHasData(bodyBytes)
 
 	response := GraphToolResponse{
-		Data:  bodyBytes,
-		Query: query,
+		Data:     bodyBytes,
+		Query:    query,
+		UILink:   link,
+		Warnings: warnings,
+		Stats:    parseQueryStats(bodyBytes),
 	}
 
 	if !hasData {
@@ -69,6 +74,17 @@ func formatGraphResponse(bodyBytes []byte, query string) (*mcp.CallToolResult, e
 				"Use validate_cql tool to check your query syntax, or build_cql tool to reconstruct from structured parameters",
 			},
 		}
+	} else if len(warnings) > 0 {
+		response.Guidance = &GraphGuidance{
+			ResultStatus: "partial",
+			NextSteps: []string{
+				fmt.Sprintf("%d sub-queries failed, see warnings. The remaining graph data was retrieved successfully.", len(warnings)),
+				"Retryable failures were already retried; non-retryable ones (e.g. unknown metric name) need the query fixed.",
+			},
+			Suggestions: []string{
+				"Use search_metrics tool to verify metric names, or facet_options tool to verify field values",
+			},
+		}
 	} else {
 		response.Guidance = &GraphGuidance{
 			ResultStatus: "success",
@@ -78,6 +94,16 @@ func formatGraphResponse(bodyBytes []byte, query string) (*mcp.CallToolResult, e
 			},
 		}
 	}
+	response.Guidance.Suggestions = append(response.Guidance.Suggestions, queryStatsSuggestions(response.Stats)...)
+
+	if hasData && render == RenderSparkline {
+		if sparklines, err := graphSparklines(bodyBytes); err == nil && len(sparklines) > 0 {
+			response.Data, response.Sparklines = nil, sparklines
+			response.Guidance.Suggestions = append(response.Guidance.Suggestions, `Call again with render:"raw" for the exact values per timestamp`)
+		} else {
+			response.Guidance.Suggestions = append(response.Guidance.Suggestions, "The response has no timeseries to render as sparklines, the raw data is returned")
+		}
+	}
 
 	result, _ := json.Marshal(response)
 	return mcp.NewToolResultText(string(result)), nil
@@ -113,7 +139,7 @@ Use "*" for all logs. Verify fields with discover_schema tool first.`),
 				mcp.Required(),
 			),
 			mcp.WithString("lookback",
-				mcp.Description("Lookback period in GOLANG duration format. e.g. (1h, 15m, 24h). Either provide from/to or just lookback. Pass empty string to use from/to instead."),
+				mcp.Description("Lookback period in GOLANG duration format, or days and weeks. e.g. (1h, 15m, 24h, 7d, 1w). Either provide from/to or just lookback. Pass empty string to use from/to instead."),
 				mcp.DefaultString("1h"),
 			),
 			mcp.WithString("from",
@@ -131,50 +157,34 @@ Use "*" for all logs. Verify fields with discover_schema tool first.`),
 				mcp.Description("Order of the logs in the response, either 'ASC', 'asc', 'DESC' or 'desc'."),
 				mcp.DefaultString("desc"),
 			),
+			renderParam(),
 			mcp.WithReadOnlyHintAnnotation(true),
 			mcp.WithIdempotentHintAnnotation(true),
 			mcp.WithDestructiveHintAnnotation(false),
 			mcp.WithOpenWorldHintAnnotation(false),
 		),
 		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			keys, err := FetchContextKeys(ctx)
-			if err != nil {
-				return nil, err
-			}
-
-			// Build query parameters
-			searchURL, err := url.Parse(fmt.Sprintf("%s/v1/orgs/%s/graph", client.APIURL(), keys.OrgID))
-			if err != nil {
-				return nil, err
+			render, _ := params.Optional[string](request, "render")
+			if render != "" && render != RenderRaw && render != RenderSparkline {
+				return mcp.NewToolResultError(fmt.Sprintf(`invalid render %q, expected "raw" or "sparkline"`, render)), nil
 			}
 
 			var query string
 			if q, _ := params.Optional[string](request, "query"); q != "" {
 				query = q
 			} else {
-				return nil, fmt.Errorf(`"query" is required`)
+				query = "*"
 			}
 
-			payload := map[string]any{
-				"queries": map[string]any{
-					"Q1": map[string]any{
-						"scope": "log",
-						"query": query,
-					},
-				},
-				"formulas": map[string]any{
-					"R1": map[string]any{
-						"formula": "Q1",
-					},
-				},
-			}
-
-			buffer := bytes.NewBuffer(nil)
-			if err := json.NewEncoder(buffer).Encode(payload); err != nil {
-				return nil, fmt.Errorf("failed to encode request body: %w", err)
+			payload, err := NewGraphQueryBuilder().
+				WithQuery("Q1", GraphQuery{Scope: "log", Query: query}).
+				WithFormula("R1", "Q1").
+				Build()
+			if err != nil {
+				return nil, err
 			}
 
-			queryParams := searchURL.Query()
+			queryParams := url.Values{}
 			if lookback, _ := params.Optional[string](request, "lookback"); lookback != "" {
 				queryParams.Add("lookback", lookback)
 			}
@@ -195,31 +205,16 @@ Use "*" for all logs. Verify fields with discover_schema tool first.`),
 				queryParams.Add("order", order)
 			}
 
-			searchURL.RawQuery = queryParams.Encode()
-			req, err := http.NewRequestWithContext(ctx, http.MethodPost, searchURL.String(), buffer)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create request: %v", err)
-			}
-
-			req.Header.Add("Content-Type", "application/json")
-			applyAuthHeader(req, keys)
-
-			resp, err := client.Do(req)
+			statusCode, bodyBytes, warnings, err := postGraphWithRetry(ctx, client, payload, queryParams)
 			if err != nil {
 				return nil, err
 			}
 
-			defer resp.Body.Close()
-			bodyBytes, err := io.ReadAll(resp.Body)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read response body: %v", err)
+			if statusCode != http.StatusMultiStatus {
+				return nil, fmt.Errorf("failed to search logs, status code %d: %s", statusCode, string(bodyBytes))
 			}
 
-			if resp.StatusCode != http.StatusMultiStatus {
-				return nil, fmt.Errorf("failed to search logs, status code %d: %s", resp.StatusCode, string(bodyBytes))
-			}
-
-			return formatGraphResponse(bodyBytes, query)
+			return formatGraphResponse(bodyBytes, query, uiLink(ctx, client, UILogsPage, query, queryParams), warnings, render)
 		}
 }
 
@@ -269,7 +264,7 @@ Use "*" for no filter. Verify field values with facet_options.`),
 				mcp.Description("By default, rollup period will be handled according to the lookup period. However, one can specify it according to its own needs. This needs to be defined in seconds."),
 			),
 			mcp.WithString("lookback",
-				mcp.Description("Lookback period in GOLANG duration format. e.g. (1h, 15m, 24h). Either provide from/to or just lookback. Pass empty string to use from/to instead."),
+				mcp.Description("Lookback period in GOLANG duration format, or days and weeks. e.g. (1h, 15m, 24h, 7d, 1w). Either provide from/to or just lookback. Pass empty string to use from/to instead."),
 				mcp.DefaultString("1h"),
 			),
 			mcp.WithString("from",
@@ -287,21 +282,16 @@ Use "*" for no filter. Verify field values with facet_options.`),
 				mcp.Description("Order of the metrics in the response, either 'ASC', 'asc', 'DESC' or 'desc'."),
 				mcp.DefaultString("desc"),
 			),
+			renderParam(),
 			mcp.WithReadOnlyHintAnnotation(true),
 			mcp.WithIdempotentHintAnnotation(true),
 			mcp.WithDestructiveHintAnnotation(false),
 			mcp.WithOpenWorldHintAnnotation(false),
 		),
 		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			keys, err := FetchContextKeys(ctx)
-			if err != nil {
-				return nil, err
-			}
-
-			// Build query parameters
-			searchURL, err := url.Parse(fmt.Sprintf("%s/v1/orgs/%s/graph", client.APIURL(), keys.OrgID))
-			if err != nil {
-				return nil, err
+			render, _ := params.Optional[string](request, "render")
+			if render != "" && render != RenderRaw && render != RenderSparkline {
+				return mcp.NewToolResultError(fmt.Sprintf(`invalid render %q, expected "raw" or "sparkline"`, render)), nil
 			}
 
 			var metricName, aggregationMethod, filterQuery string
@@ -333,35 +323,23 @@ Use "*" for no filter. Verify field values with facet_options.`),
 				rollupPeriod = rollup
 			}
 
-			cql := fmt.Sprintf("%s:%s{%s}", aggregationMethod, metricName, filterQuery)
-			if len(groupByKeys) > 0 {
-				cql += fmt.Sprintf(" by {%s}", strings.Join(groupByKeys, ","))
-			}
-
-			if rollupPeriod > 0 {
-				cql += fmt.Sprintf(".rollup(%d)", rollupPeriod)
-			}
+			cql := MetricQuery{
+				Aggregation: aggregationMethod,
+				Name:        metricName,
+				Filter:      filterQuery,
+				GroupBy:     groupByKeys,
+				Rollup:      rollupPeriod,
+			}.CQL()
 
-			payload := map[string]any{
-				"queries": map[string]any{
-					"Q1": map[string]any{
-						"scope": "metric",
-						"query": cql,
-					},
-				},
-				"formulas": map[string]any{
-					"R1": map[string]any{
-						"formula": "Q1",
-					},
-				},
-			}
-
-			buffer := bytes.NewBuffer(nil)
-			if err := json.NewEncoder(buffer).Encode(payload); err != nil {
-				return nil, fmt.Errorf("failed to encode request body: %w", err)
+			payload, err := NewGraphQueryBuilder().
+				WithQuery("Q1", GraphQuery{Scope: "metric", Query: cql}).
+				WithFormula("R1", "Q1").
+				Build()
+			if err != nil {
+				return nil, err
 			}
 
-			queryParams := searchURL.Query()
+			queryParams := url.Values{}
 			queryParams.Add("graph_type", "timeseries")
 			if lookback, _ := params.Optional[string](request, "lookback"); lookback != "" {
 				queryParams.Add("lookback", lookback)
@@ -383,31 +361,16 @@ Use "*" for no filter. Verify field values with facet_options.`),
 				queryParams.Add("order", order)
 			}
 
-			searchURL.RawQuery = queryParams.Encode()
-			req, err := http.NewRequestWithContext(ctx, http.MethodPost, searchURL.String(), buffer)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create request: %w", err)
-			}
-
-			req.Header.Add("Content-Type", "application/json")
-			applyAuthHeader(req, keys)
-
-			resp, err := client.Do(req)
+			statusCode, bodyBytes, warnings, err := postGraphWithRetry(ctx, client, payload, queryParams)
 			if err != nil {
 				return nil, err
 			}
 
-			defer resp.Body.Close()
-			bodyBytes, err := io.ReadAll(resp.Body)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read response body: %w", err)
+			if statusCode != http.StatusMultiStatus {
+				return nil, fmt.Errorf("failed to search metrics, status code %d: %s", statusCode, string(bodyBytes))
 			}
 
-			if resp.StatusCode != http.StatusMultiStatus {
-				return nil, fmt.Errorf("failed to search metrics, status code %d: %s", resp.StatusCode, string(bodyBytes))
-			}
-
-			return formatGraphResponse(bodyBytes, cql)
+			return formatGraphResponse(bodyBytes, cql, uiLink(ctx, client, UIMetricsPage, cql, queryParams), warnings, render)
 		}
 }
 
@@ -451,7 +414,7 @@ NOTE: Full-text search is NOT supported for traces.`),
 				mcp.DefaultBool(false),
 			),
 			mcp.WithString("lookback",
-				mcp.Description("Lookback period in GOLANG duration format. e.g. (1h, 15m, 24h). Either provide from/to or just lookback. Pass empty string to use from/to instead."),
+				mcp.Description("Lookback period in GOLANG duration format, or days and weeks. e.g. (1h, 15m, 24h, 7d, 1w). Either provide from/to or just lookback. Pass empty string to use from/to instead."),
 				mcp.DefaultString("1h"),
 			),
 			mcp.WithString("from",
@@ -469,21 +432,16 @@ NOTE: Full-text search is NOT supported for traces.`),
 				mcp.Description("Order of the traces in the response, either 'ASC', 'asc', 'DESC' or 'desc'."),
 				mcp.DefaultString("desc"),
 			),
+			renderParam(),
 			mcp.WithReadOnlyHintAnnotation(true),
 			mcp.WithIdempotentHintAnnotation(true),
 			mcp.WithDestructiveHintAnnotation(false),
 			mcp.WithOpenWorldHintAnnotation(false),
 		),
 		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			keys, err := FetchContextKeys(ctx)
-			if err != nil {
-				return nil, err
-			}
-
-			// Build query parameters
-			searchURL, err := url.Parse(fmt.Sprintf("%s/v1/orgs/%s/graph", client.APIURL(), keys.OrgID))
-			if err != nil {
-				return nil, err
+			render, _ := params.Optional[string](request, "render")
+			if render != "" && render != RenderRaw && render != RenderSparkline {
+				return mcp.NewToolResultError(fmt.Sprintf(`invalid render %q, expected "raw" or "sparkline"`, render)), nil
 			}
 
 			var query, dataType string
@@ -491,7 +449,7 @@ NOTE: Full-text search is NOT supported for traces.`),
 			if q, _ := params.Optional[string](request, "query"); q != "" {
 				query = q
 			} else {
-				return nil, fmt.Errorf(`"query" is required`)
+				query = "*"
 			}
 
 			if dType, _ := params.Optional[string](request, "data_type"); dType != "" {
@@ -504,28 +462,22 @@ NOTE: Full-text search is NOT supported for traces.`),
 				includeChildSpans = true
 			}
 
-			payload := map[string]any{
-				"queries": map[string]any{
-					"Q1": map[string]any{
-						"scope":             "trace",
-						"query":             query,
-						"dataType":          dataType,
+			payload, err := NewGraphQueryBuilder().
+				WithQuery("Q1", GraphQuery{
+					Scope:    "trace",
+					Query:    query,
+					DataType: dataType,
+					Options: map[string]any{
 						"includeChildSpans": includeChildSpans,
 					},
-				},
-				"formulas": map[string]any{
-					"R1": map[string]string{
-						"formula": "Q1",
-					},
-				},
-			}
-
-			buffer := bytes.NewBuffer(nil)
-			if err := json.NewEncoder(buffer).Encode(payload); err != nil {
-				return nil, fmt.Errorf("failed to encode request body: %w", err)
+				}).
+				WithFormula("R1", "Q1").
+				Build()
+			if err != nil {
+				return nil, err
 			}
 
-			queryParams := searchURL.Query()
+			queryParams := url.Values{}
 			if lookback, _ := params.Optional[string](request, "lookback"); lookback != "" {
 				queryParams.Add("lookback", lookback)
 			}
@@ -546,31 +498,16 @@ NOTE: Full-text search is NOT supported for traces.`),
 				queryParams.Add("order", order)
 			}
 
-			searchURL.RawQuery = queryParams.Encode()
-			req, err := http.NewRequestWithContext(ctx, http.MethodPost, searchURL.String(), buffer)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create request: %v", err)
-			}
-
-			req.Header.Add("Content-Type", "application/json")
-			applyAuthHeader(req, keys)
-
-			resp, err := client.Do(req)
+			statusCode, bodyBytes, warnings, err := postGraphWithRetry(ctx, client, payload, queryParams)
 			if err != nil {
 				return nil, err
 			}
 
-			defer resp.Body.Close()
-			bodyBytes, err := io.ReadAll(resp.Body)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read response body: %v", err)
-			}
-
-			if resp.StatusCode != http.StatusMultiStatus {
-				return nil, fmt.Errorf("failed to graph traces, status code %d: %s", resp.StatusCode, string(bodyBytes))
+			if statusCode != http.StatusMultiStatus {
+				return nil, fmt.Errorf("failed to graph traces, status code %d: %s", statusCode, string(bodyBytes))
 			}
 
-			return formatGraphResponse(bodyBytes, query)
+			return formatGraphResponse(bodyBytes, query, uiLink(ctx, client, UITracesPage, query, queryParams), warnings, render)
 		}
 }
 
@@ -621,11 +558,11 @@ Use "*" for all patterns. Verify fields with discover_schema tool first.`),
 				mcp.DefaultString("all"),
 			),
 			mcp.WithString("volatility_offset",
-				mcp.Description(`Offset to be used by volatility parameter. Should be in GOLANG duration format. e.g. (1h, 15m, 24h)`),
+				mcp.Description(`Offset to be used by volatility parameter. Should be in GOLANG duration format, or days and weeks. e.g. (1h, 15m, 24h, 1w)`),
 				mcp.DefaultString("24h"),
 			),
 			mcp.WithString("lookback",
-				mcp.Description("Lookback period in GOLANG duration format. e.g. (1h, 15m, 24h). Either provide from/to or just lookback. Pass empty string to use from/to instead."),
+				mcp.Description("Lookback period in GOLANG duration format, or days and weeks. e.g. (1h, 15m, 24h, 7d, 1w). Either provide from/to or just lookback. Pass empty string to use from/to instead."),
 				mcp.DefaultString("1h"),
 			),
 			mcp.WithString("from",
@@ -643,21 +580,16 @@ Use "*" for all patterns. Verify fields with discover_schema tool first.`),
 				mcp.Description("Order of the patterns in the response, either 'ASC', 'asc', 'DESC' or 'desc'."),
 				mcp.DefaultString("desc"),
 			),
+			renderParam(),
 			mcp.WithReadOnlyHintAnnotation(true),
 			mcp.WithIdempotentHintAnnotation(true),
 			mcp.WithDestructiveHintAnnotation(false),
 			mcp.WithOpenWorldHintAnnotation(false),
 		),
 		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			keys, err := FetchContextKeys(ctx)
-			if err != nil {
-				return nil, err
-			}
-
-			// Build query parameters
-			searchURL, err := url.Parse(fmt.Sprintf("%s/v1/orgs/%s/graph", client.APIURL(), keys.OrgID))
-			if err != nil {
-				return nil, err
+			render, _ := params.Optional[string](request, "render")
+			if render != "" && render != RenderRaw && render != RenderSparkline {
+				return mcp.NewToolResultError(fmt.Sprintf(`invalid render %q, expected "raw" or "sparkline"`, render)), nil
 			}
 
 			var query, volatility, volatilityOffset string
@@ -665,7 +597,7 @@ Use "*" for all patterns. Verify fields with discover_schema tool first.`),
 			if q, _ := params.Optional[string](request, "query"); q != "" {
 				query = q
 			} else {
-				return nil, fmt.Errorf(`"query" is required`)
+				query = "*"
 			}
 
 			if omitZero, _ := params.Optional[bool](request, "omit_zero_patterns"); omitZero {
@@ -692,31 +624,25 @@ Use "*" for all patterns. Verify fields with discover_schema tool first.`),
 				volatilityOffset = "24h"
 			}
 
-			payload := map[string]any{
-				"queries": map[string]any{
-					"Q1": map[string]any{
-						"scope":        "pattern",
-						"query":        query,
+			payload, err := NewGraphQueryBuilder().
+				WithQuery("Q1", GraphQuery{
+					Scope: "pattern",
+					Query: query,
+					Options: map[string]any{
 						"omitZero":     omitZeroPatterns,
 						"negative":     includeNegativePatterns,
 						"includeOther": includeMissingUnderOther,
 						"volatility":   volatility,
 						"offset":       volatilityOffset,
 					},
-				},
-				"formulas": map[string]any{
-					"R1": map[string]string{
-						"formula": "Q1",
-					},
-				},
-			}
-
-			buffer := bytes.NewBuffer(nil)
-			if err := json.NewEncoder(buffer).Encode(payload); err != nil {
-				return nil, fmt.Errorf("failed to encode request body: %w", err)
+				}).
+				WithFormula("R1", "Q1").
+				Build()
+			if err != nil {
+				return nil, err
 			}
 
-			queryParams := searchURL.Query()
+			queryParams := url.Values{}
 			if lookback, _ := params.Optional[string](request, "lookback"); lookback != "" {
 				queryParams.Add("lookback", lookback)
 			}
@@ -737,30 +663,15 @@ Use "*" for all patterns. Verify fields with discover_schema tool first.`),
 				queryParams.Add("order", order)
 			}
 
-			searchURL.RawQuery = queryParams.Encode()
-			req, err := http.NewRequestWithContext(ctx, http.MethodPost, searchURL.String(), buffer)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create request: %v", err)
-			}
-
-			req.Header.Add("Content-Type", "application/json")
-			applyAuthHeader(req, keys)
-
-			resp, err := client.Do(req)
+			statusCode, bodyBytes, warnings, err := postGraphWithRetry(ctx, client, payload, queryParams)
 			if err != nil {
 				return nil, err
 			}
 
-			defer resp.Body.Close()
-			bodyBytes, err := io.ReadAll(resp.Body)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read response body: %v", err)
-			}
-
-			if resp.StatusCode != http.StatusMultiStatus {
-				return nil, fmt.Errorf("failed to graph patterns, status code %d: %s", resp.StatusCode, string(bodyBytes))
+			if statusCode != http.StatusMultiStatus {
+				return nil, fmt.Errorf("failed to graph patterns, status code %d: %s", statusCode, string(bodyBytes))
 			}
 
-			return formatGraphResponse(bodyBytes, query)
+			return formatGraphResponse(bodyBytes, query, uiLink(ctx, client, UIPatternsPage, query, queryParams), warnings, render)
 		}
 }
@@ -0,0 +1,257 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// maxGraphQueries bounds the queries of a get_graph_multi call.
+const maxGraphQueries = 5
+
+var graphQueryNamePattern = regexp.MustCompile(`^Q[1-9][0-9]*$`)
+
+// graphAggregations are the aggregations of every scope, the first is the default.
+var graphAggregations = map[string][]string{
+	"log":     {"count"},
+	"event":   {"count"},
+	"pattern": {"count"},
+	"metric":  {"sum", "avg", "median", "count", "max", "min"},
+	// Traces count requests or return the P50 and P95 latency
+	"trace": {"request", "latency"},
+}
+
+// GetGraphMultiTool creates a tool to graph formulas over several queries
+func GetGraphMultiTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("get_graph_multi",
+			mcp.WithTitleAnnotation("Get Multi-Query Graph"),
+			mcp.WithDescription(fmt.Sprintf(`Render a time series graph of a formula over up to %d named queries Q1..Qn, e.g. error rates and ratios.
+
+Examples:
+- Error rate of checkout in percent: Q1 = log service.name:"checkout" AND severity_text:"ERROR", Q2 = log service.name:"checkout", formula "Q1/Q2*100"
+- Share of a host in the CPU usage: Q1 = metric sum:system.cpu.usage{host.name:"web-1"}, Q2 = metric sum:system.cpu.usage{*}, formula "Q1/Q2"
+
+Every query has a scope, a CQL query and an aggregation:
+- log, event, pattern: count of the matching records
+- metric: sum, avg, median, count, max or min of metric_name, filtered by the query (use search_metrics for the exact name)
+- trace: request (count) or latency (P50 and P95)
+
+Without a formula every query is graphed as its own series.
+Use get_log_graph, get_metric_graph or get_trace_graph for a single query.`, maxGraphQueries)),
+			mcp.WithArray("queries",
+				mcp.Description(fmt.Sprintf("Up to %d queries, named Q1..Qn in order unless they have a name.", maxGraphQueries)),
+				mcp.Required(),
+				mcp.Items(map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"name":        map[string]any{"type": "string", "description": "Name of the query in the formula, e.g. Q1"},
+						"scope":       map[string]any{"type": "string", "enum": GraphScopes},
+						"query":       map[string]any{"type": "string", "description": `CQL filter, e.g. service.name:"checkout". "*" for all records.`},
+						"aggregation": map[string]any{"type": "string", "description": "count for log, event and pattern; sum, avg, median, count, max or min for metric; request or latency for trace"},
+						"metric_name": map[string]any{"type": "string", "description": "Exact metric name, for the metric scope"},
+						"group_by": map[string]any{
+							"type":        "array",
+							"items":       map[string]any{"type": "string"},
+							"description": "Grouping keys, for the metric scope",
+						},
+					},
+					"required": []string{"scope"},
+				}),
+			),
+			mcp.WithString("formula",
+				mcp.Description(`Formula over the queries, e.g. "Q1/Q2*100" or "Q1-Q2". Leave empty to graph every query.`),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("lookback",
+				mcp.Description("Lookback period in GOLANG duration format, or days and weeks. e.g. (1h, 15m, 24h, 7d, 1w). Either provide from/to or just lookback. Pass empty string to use from/to instead."),
+				mcp.DefaultString("1h"),
+			),
+			mcp.WithString("from",
+				mcp.Description("From datetime in ISO format 2006-01-02T15:04:05.000Z."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("to",
+				mcp.Description("To datetime in ISO format 2006-01-02T15:04:05.000Z."),
+				mcp.DefaultString(""),
+			),
+			renderParam(),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			render, _ := params.Optional[string](request, "render")
+			if render != "" && render != RenderRaw && render != RenderSparkline {
+				return mcp.NewToolResultError(fmt.Sprintf(`invalid render %q, expected "raw" or "sparkline"`, render)), nil
+			}
+
+			values, ok := request.GetArguments()["queries"].([]any)
+			if !ok || len(values) == 0 {
+				return mcp.NewToolResultError("missing required parameter: queries, it must be a list of query objects"), nil
+			}
+			if len(values) > maxGraphQueries {
+				return mcp.NewToolResultError(fmt.Sprintf("at most %d queries are supported, got %d", maxGraphQueries, len(values))), nil
+			}
+
+			builder := NewGraphQueryBuilder()
+			queries := make(map[string]GraphQuery, len(values))
+			var errs []string
+			for i, v := range values {
+				name, query, err := parseGraphMultiQuery(v, i)
+				if err != nil {
+					errs = append(errs, err.Error())
+					continue
+				}
+				if _, ok := queries[name]; ok {
+					errs = append(errs, fmt.Sprintf("query %d: duplicate name %s", i+1, name))
+					continue
+				}
+				queries[name] = query
+				builder.WithQuery(name, query)
+			}
+			if len(errs) > 0 {
+				return mcp.NewToolResultError("invalid queries:\n- " + strings.Join(errs, "\n- ")), nil
+			}
+
+			names := make([]string, 0, len(queries))
+			for name := range queries {
+				names = append(names, name)
+			}
+			sort.Slice(names, func(i, j int) bool { return graphQueryIndex(names[i]) < graphQueryIndex(names[j]) })
+
+			formula, _ := params.Optional[string](request, "formula")
+			if formula = strings.TrimSpace(formula); formula != "" {
+				builder.WithFormula("R1", formula)
+			} else {
+				for i, name := range names {
+					builder.WithFormula(fmt.Sprintf("R%d", i+1), name)
+				}
+			}
+			payload, err := builder.Build()
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+
+			queryParams := url.Values{}
+			queryParams.Add("graph_type", "timeseries")
+			if lookback, _ := params.Optional[string](request, "lookback"); lookback != "" {
+				queryParams.Add("lookback", lookback)
+			}
+
+			if from, _ := params.Optional[string](request, "from"); from != "" {
+				queryParams.Add("from", from)
+			}
+
+			if to, _ := params.Optional[string](request, "to"); to != "" {
+				queryParams.Add("to", to)
+			}
+
+			statusCode, bodyBytes, warnings, err := postGraphWithRetry(ctx, client, payload, queryParams)
+			if err != nil {
+				return nil, err
+			}
+
+			if statusCode != http.StatusMultiStatus {
+				return nil, fmt.Errorf("failed to graph queries, status code %d: %s", statusCode, string(bodyBytes))
+			}
+
+			// The queries are described in the order of their names, with the formula first
+			described := make([]string, 0, len(names)+1)
+			if formula != "" {
+				described = append(described, "R1 = "+formula)
+			}
+			for _, name := range names {
+				described = append(described, fmt.Sprintf("%s = %s:%s", name, queries[name].Scope, queries[name].Query))
+			}
+			first := queries[names[0]]
+			return formatGraphResponse(bodyBytes, strings.Join(described, "; "), uiLink(ctx, client, graphScopePage(first.Scope), first.Query, queryParams), warnings, render)
+		}
+}
+
+// parseGraphMultiQuery returns the name and the graph query of the i-th query argument.
+func parseGraphMultiQuery(v any, i int) (string, GraphQuery, error) {
+	q, ok := v.(map[string]any)
+	if !ok {
+		return "", GraphQuery{}, fmt.Errorf("query %d must be an object with a scope and a query", i+1)
+	}
+
+	name := fmt.Sprintf("Q%d", i+1)
+	if n := strings.TrimSpace(firstRecordString(q, "name")); n != "" {
+		if !graphQueryNamePattern.MatchString(n) {
+			return "", GraphQuery{}, fmt.Errorf("query %d: invalid name %q, expected Q1..Q%d", i+1, n, maxGraphQueries)
+		}
+		name = n
+	}
+
+	scope := firstRecordString(q, "scope")
+	aggregations, ok := graphAggregations[scope]
+	if !ok {
+		return "", GraphQuery{}, fmt.Errorf("%s: invalid scope %q, expected one of %s", name, scope, strings.Join(GraphScopes, ", "))
+	}
+	aggregation := firstRecordString(q, "aggregation")
+	if aggregation == "" {
+		aggregation = aggregations[0]
+	}
+	if !slices.Contains(aggregations, aggregation) {
+		return "", GraphQuery{}, fmt.Errorf("%s: invalid aggregation %q for scope %s, expected one of %s", name, aggregation, scope, strings.Join(aggregations, ", "))
+	}
+
+	filter := strings.TrimSpace(firstRecordString(q, "query", "cql"))
+	if filter == "" {
+		filter = "*"
+	}
+
+	query := GraphQuery{Scope: scope, Query: filter}
+	switch scope {
+	case "metric":
+		metricName := strings.TrimSpace(firstRecordString(q, "metric_name"))
+		if metricName == "" {
+			return "", GraphQuery{}, fmt.Errorf("%s: metric_name is required for the metric scope, use search_metrics to find it", name)
+		}
+		var groupBy []string
+		if keys, ok := q["group_by"].([]any); ok {
+			for _, key := range keys {
+				if s, ok := key.(string); ok && strings.TrimSpace(s) != "" {
+					groupBy = append(groupBy, strings.TrimSpace(s))
+				}
+			}
+		}
+		query.Query = MetricQuery{Aggregation: aggregation, Name: metricName, Filter: filter, GroupBy: groupBy}.CQL()
+	case "trace":
+		query.DataType = aggregation
+	}
+	return name, query, nil
+}
+
+// graphQueryIndex returns n of the query name Qn.
+func graphQueryIndex(name string) int {
+	var n int
+	fmt.Sscanf(name, "Q%d", &n)
+	return n
+}
+
+// graphScopePage returns the explorer page of the scope.
+func graphScopePage(scope string) UIPage {
+	switch scope {
+	case "metric":
+		return UIMetricsPage
+	case "trace":
+		return UITracesPage
+	case "pattern":
+		return UIPatternsPage
+	case "event":
+		return UIEventsPage
+	default:
+		return UILogsPage
+	}
+}
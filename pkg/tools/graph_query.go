@@ -0,0 +1,391 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// formulaIdentifierPattern matches the identifiers of a formula, and a following "(" for functions.
+// Numbers are matched first so that the exponent of a number, e.g. e3 in 2.5e3, is not an identifier.
+var formulaIdentifierPattern = regexp.MustCompile(`(?:\d+\.?\d*|\.\d+)(?:[eE][+-]?\d+)?|([A-Za-z_][A-Za-z0-9_]*)(\s*\()?`)
+
+// GraphQuery describes a single named sub-query of a /graph request.
+type GraphQuery struct {
+	Scope    string
+	Query    string
+	DataType string
+	// Options carries scope specific flags that are passed through as is,
+	// e.g. includeChildSpans for traces or volatility for patterns.
+	Options map[string]any
+}
+
+// MetricQuery describes the CQL of a metric graph query:
+// <aggregation>:<name>{<filter>} by {<group_by>}.rollup(<seconds>)
+type MetricQuery struct {
+	Aggregation string
+	Name        string
+	Filter      string
+	GroupBy     []string
+	Rollup      int
+}
+
+// CQL renders the metric query, defaulting the aggregation to "sum" and the filter to "*".
+func (m MetricQuery) CQL() string {
+	aggregation := m.Aggregation
+	if aggregation == "" {
+		aggregation = "sum"
+	}
+
+	filter := m.Filter
+	if filter == "" {
+		filter = "*"
+	}
+
+	cql := fmt.Sprintf("%s:%s{%s}", aggregation, m.Name, filter)
+	if len(m.GroupBy) > 0 {
+		cql += fmt.Sprintf(" by {%s}", strings.Join(m.GroupBy, ","))
+	}
+
+	if m.Rollup > 0 {
+		cql += fmt.Sprintf(".rollup(%d)", m.Rollup)
+	}
+
+	return cql
+}
+
+// GraphFormula is a formula evaluated over the named queries of a GraphPayload.
+type GraphFormula struct {
+	Formula string `json:"formula"`
+}
+
+// GraphPayload is the request body of POST /v1/orgs/{org_id}/graph.
+type GraphPayload struct {
+	Queries  map[string]map[string]any `json:"queries"`
+	Formulas map[string]GraphFormula   `json:"formulas"`
+}
+
+// GraphQueryBuilder assembles GraphPayloads and makes sure every formula only
+// references queries that are part of the payload.
+type GraphQueryBuilder struct {
+	queries  map[string]GraphQuery
+	formulas map[string]string
+}
+
+func NewGraphQueryBuilder() *GraphQueryBuilder {
+	return &GraphQueryBuilder{
+		queries:  make(map[string]GraphQuery),
+		formulas: make(map[string]string),
+	}
+}
+
+// WithQuery adds a named query, e.g. "Q1".
+func (b *GraphQueryBuilder) WithQuery(name string, q GraphQuery) *GraphQueryBuilder {
+	b.queries[name] = q
+	return b
+}
+
+// WithFormula adds a named formula over the queries, e.g. "R1" = "Q1/Q2*100".
+func (b *GraphQueryBuilder) WithFormula(name, formula string) *GraphQueryBuilder {
+	b.formulas[name] = formula
+	return b
+}
+
+// Build validates the queries and formulas and returns the request payload.
+func (b *GraphQueryBuilder) Build() (*GraphPayload, error) {
+	if len(b.queries) == 0 {
+		return nil, fmt.Errorf("graph payload requires at least one query")
+	}
+
+	if len(b.formulas) == 0 {
+		return nil, fmt.Errorf("graph payload requires at least one formula")
+	}
+
+	payload := &GraphPayload{
+		Queries:  make(map[string]map[string]any, len(b.queries)),
+		Formulas: make(map[string]GraphFormula, len(b.formulas)),
+	}
+
+	for name, q := range b.queries {
+		if q.Scope == "" {
+			return nil, fmt.Errorf("query %s has no scope", name)
+		}
+
+		entry := make(map[string]any, len(q.Options)+3)
+		for k, v := range q.Options {
+			entry[k] = v
+		}
+
+		entry["scope"] = q.Scope
+		entry["query"] = q.Query
+		if q.DataType != "" {
+			entry["dataType"] = q.DataType
+		}
+
+		payload.Queries[name] = entry
+	}
+
+	for name, formula := range b.formulas {
+		for _, ref := range formulaReferences(formula) {
+			if _, ok := b.queries[ref]; !ok {
+				return nil, fmt.Errorf("formula %s references unknown query %q, known queries: %s", name, ref, strings.Join(b.queryNames(), ", "))
+			}
+		}
+
+		payload.Formulas[name] = GraphFormula{Formula: formula}
+	}
+
+	return payload, nil
+}
+
+func (b *GraphQueryBuilder) queryNames() []string {
+	names := make([]string, 0, len(b.queries))
+	for name := range b.queries {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// formulaReferences returns the query names referenced by a formula, skipping function calls.
+func formulaReferences(formula string) []string {
+	var refs []string
+	for _, match := range formulaIdentifierPattern.FindAllStringSubmatch(formula, -1) {
+		if match[1] == "" || match[2] != "" {
+			// number, or function call, e.g. abs(Q1)
+			continue
+		}
+		refs = append(refs, match[1])
+	}
+	return refs
+}
+
+// postGraph sends the payload to the /graph endpoint and returns the response status code and body.
+func postGraph(ctx context.Context, client Client, payload *GraphPayload, queryParams url.Values) (int, []byte, error) {
+	keys, err := FetchContextKeys(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	graphURL, err := url.Parse(fmt.Sprintf("%s/v1/orgs/%s/graph", client.APIURL(), keys.OrgID))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	if err := json.NewEncoder(buffer).Encode(payload); err != nil {
+		return 0, nil, fmt.Errorf("failed to encode request body: %w", err)
+	}
+
+	graphURL.RawQuery = queryParams.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, graphURL.String(), buffer)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Add("Content-Type", "application/json")
+	applyAuthHeader(req, keys)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	return resp.StatusCode, bodyBytes, nil
+}
+
+const (
+	graphSubQueryRetries = 2
+	graphSubQueryBackoff = 500 * time.Millisecond
+)
+
+// GraphWarning describes a sub-query of a 207 Multi-Status graph response that failed.
+type GraphWarning struct {
+	Formula    string `json:"formula"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error"`
+	Retryable  bool   `json:"retryable"`
+	Attempts   int    `json:"attempts"`
+}
+
+// graphSubResult is the per-formula entry of a 207 Multi-Status graph response.
+type graphSubResult struct {
+	Records    json.RawMessage `json:"records,omitempty"`
+	StatusCode int             `json:"status_code,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+func (r graphSubResult) failed() bool {
+	return r.Error != "" || r.StatusCode >= http.StatusBadRequest
+}
+
+// retryable reports whether the failure is transient, e.g. throttling or upstream timeouts.
+// Failures such as an unknown metric name are not retried.
+func (r graphSubResult) retryable() bool {
+	switch r.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	case 0:
+		msg := strings.ToLower(r.Error)
+		return strings.Contains(msg, "timeout") || strings.Contains(msg, "deadline exceeded")
+	default:
+		return false
+	}
+}
+
+// Subset returns a payload containing only the given formulas and the queries they reference.
+func (p *GraphPayload) Subset(formulas []string) *GraphPayload {
+	subset := &GraphPayload{
+		Queries:  make(map[string]map[string]any),
+		Formulas: make(map[string]GraphFormula),
+	}
+
+	for _, name := range formulas {
+		formula, ok := p.Formulas[name]
+		if !ok {
+			continue
+		}
+
+		subset.Formulas[name] = formula
+		for _, ref := range formulaReferences(formula.Formula) {
+			if q, ok := p.Queries[ref]; ok {
+				subset.Queries[ref] = q
+			}
+		}
+	}
+
+	return subset
+}
+
+// postGraphWithRetry posts the payload and, on a 207 Multi-Status response, retries only the
+// sub-queries that failed with a retryable error. Results of successful retries are merged into
+// the response body; failures that remain are returned as warnings.
+func postGraphWithRetry(ctx context.Context, client Client, payload *GraphPayload, queryParams url.Values) (int, []byte, []GraphWarning, error) {
+	statusCode, bodyBytes, err := postGraph(ctx, client, payload, queryParams)
+	if err != nil || statusCode != http.StatusMultiStatus {
+		return statusCode, bodyBytes, nil, err
+	}
+
+	var merged map[string]json.RawMessage
+	if err := json.Unmarshal(bodyBytes, &merged); err != nil {
+		return statusCode, bodyBytes, nil, nil
+	}
+
+	failures := failedSubResults(merged, payload)
+	if len(failures) == 0 {
+		return statusCode, bodyBytes, nil, nil
+	}
+
+	attempts := make(map[string]int, len(failures))
+	for name := range failures {
+		attempts[name] = 1
+	}
+
+	for attempt := 1; attempt <= graphSubQueryRetries; attempt++ {
+		var retry []string
+		for name, failure := range failures {
+			if failure.retryable() {
+				retry = append(retry, name)
+			}
+		}
+
+		if len(retry) == 0 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, nil, nil, ctx.Err()
+		case <-time.After(time.Duration(attempt) * graphSubQueryBackoff):
+		}
+
+		sort.Strings(retry)
+		retryStatus, retryBody, err := postGraph(ctx, client, payload.Subset(retry), queryParams)
+		for _, name := range retry {
+			attempts[name]++
+		}
+
+		if err != nil || (retryStatus != http.StatusOK && retryStatus != http.StatusMultiStatus) {
+			continue
+		}
+
+		var retried map[string]json.RawMessage
+		if err := json.Unmarshal(retryBody, &retried); err != nil {
+			continue
+		}
+
+		retryFailures := failedSubResults(retried, payload.Subset(retry))
+		for _, name := range retry {
+			if failure, ok := retryFailures[name]; ok {
+				failures[name] = failure
+				continue
+			}
+
+			if result, ok := retried[name]; ok {
+				merged[name] = result
+				delete(failures, name)
+			}
+		}
+	}
+
+	warnings := make([]GraphWarning, 0, len(failures))
+	for name, failure := range failures {
+		warnings = append(warnings, GraphWarning{
+			Formula:    name,
+			StatusCode: failure.StatusCode,
+			Error:      failure.Error,
+			Retryable:  failure.retryable(),
+			Attempts:   attempts[name],
+		})
+	}
+
+	sort.Slice(warnings, func(i, j int) bool {
+		return warnings[i].Formula < warnings[j].Formula
+	})
+
+	mergedBody, err := json.Marshal(merged)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("failed to merge graph results: %w", err)
+	}
+
+	return statusCode, mergedBody, warnings, nil
+}
+
+func failedSubResults(results map[string]json.RawMessage, payload *GraphPayload) map[string]graphSubResult {
+	failures := make(map[string]graphSubResult)
+	for name := range payload.Formulas {
+		raw, ok := results[name]
+		if !ok {
+			continue
+		}
+
+		var result graphSubResult
+		if err := json.Unmarshal(raw, &result); err != nil {
+			continue
+		}
+
+		if result.failed() {
+			if result.Error == "" {
+				result.Error = http.StatusText(result.StatusCode)
+			}
+			failures[name] = result
+		}
+	}
+	return failures
+}
@@ -0,0 +1,272 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	defaultHistogramGroups = 10
+	maxHistogramGroups     = 50
+	minHistogramBucket     = time.Minute
+)
+
+var facetPathPattern = regexp.MustCompile(`^@?[A-Za-z0-9_.\-]+$`)
+
+type LogHistogramResult struct {
+	Query   string `json:"query_used"`
+	GroupBy string `json:"group_by,omitempty"`
+	// Bucket is the width of the buckets, chosen by the API unless requested
+	Bucket   string           `json:"bucket,omitempty"`
+	From     string           `json:"from,omitempty"`
+	To       string           `json:"to,omitempty"`
+	Total    int64            `json:"total"`
+	Groups   []HistogramGroup `json:"groups"`
+	UILink   string           `json:"ui_link,omitempty"`
+	Warnings []GraphWarning   `json:"warnings,omitempty"`
+	Stats    *QueryStats      `json:"stats,omitempty"`
+	Guidance *GraphGuidance   `json:"guidance,omitempty"`
+}
+
+// HistogramGroup is the log count over time of a value of the group_by facet.
+type HistogramGroup struct {
+	Value string `json:"value,omitempty"`
+	Total int64  `json:"total"`
+	// Buckets are the counts per bucket, or Sparkline draws them with render:"sparkline"
+	Buckets   []HistogramBucket `json:"buckets,omitempty"`
+	Sparkline string            `json:"sparkline,omitempty"`
+}
+
+type HistogramBucket struct {
+	Time  string `json:"time"`
+	Count int64  `json:"count"`
+}
+
+// GetLogHistogramTool creates a tool to count logs over time grouped by a facet
+func GetLogHistogramTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("get_log_histogram",
+			mcp.WithTitleAnnotation("Get Log Histogram"),
+			mcp.WithDescription(fmt.Sprintf(`Count logs over time in buckets, grouped by a facet, e.g. severity_text or service.name.
+
+Use this tool as the first step of an investigation: it shows when the volume changed and which group changed, e.g. "which service started logging errors at 14:05?".
+Groups are sorted by their total count, the top %d by default.
+
+Use facets tool to see the fields to group by, and get_log_search to read the logs of a bucket.`, defaultHistogramGroups)),
+			mcp.WithString("query",
+				mcp.Description(`CQL filter query, e.g. severity_text:"ERROR" or service.name:"api". Use "*" for all logs.`),
+				mcp.DefaultString("*"),
+			),
+			mcp.WithString("group_by",
+				mcp.Description("Facet to group the counts by, e.g. severity_text, service.name, host.name. Leave empty for a single count over time."),
+				mcp.DefaultString("severity_text"),
+			),
+			mcp.WithString("bucket",
+				mcp.Description("Width of the buckets in Go duration format, or days and weeks (e.g., 1m, 5m, 1h, 1d), at least 1m. Leave empty to let the API choose it from the time range."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description(fmt.Sprintf("Maximum number of groups. Default: %d, max %d", defaultHistogramGroups, maxHistogramGroups)),
+				mcp.DefaultNumber(defaultHistogramGroups),
+			),
+			mcp.WithString("lookback",
+				mcp.Description("Lookback period in GOLANG duration format, or days and weeks. e.g. (1h, 15m, 24h, 7d, 1w). Either provide from/to or just lookback. Pass empty string to use from/to instead."),
+				mcp.DefaultString("1h"),
+			),
+			mcp.WithString("from",
+				mcp.Description("From datetime in ISO format 2006-01-02T15:04:05.000Z."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("to",
+				mcp.Description("To datetime in ISO format 2006-01-02T15:04:05.000Z."),
+				mcp.DefaultString(""),
+			),
+			renderParam(),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			render, _ := params.Optional[string](request, "render")
+			if render != "" && render != RenderRaw && render != RenderSparkline {
+				return mcp.NewToolResultError(fmt.Sprintf(`invalid render %q, expected "raw" or "sparkline"`, render)), nil
+			}
+
+			filter, _ := params.Optional[string](request, "query")
+			if filter = strings.TrimSpace(filter); filter == "" {
+				filter = "*"
+			}
+			// group_by defaults to severity_text, an empty group_by counts all logs together
+			groupBy := "severity_text"
+			if _, ok := request.GetArguments()["group_by"]; ok {
+				groupBy, _ = params.Optional[string](request, "group_by")
+				groupBy = strings.TrimSpace(groupBy)
+			}
+			if groupBy != "" && !facetPathPattern.MatchString(groupBy) {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid group_by %q, expected a single facet path such as service.name", groupBy)), nil
+			}
+
+			limit := defaultHistogramGroups
+			if v, _ := params.Optional[float64](request, "limit"); v > 0 {
+				limit = int(v)
+			}
+			if limit > maxHistogramGroups {
+				return mcp.NewToolResultError(fmt.Sprintf("limit must be at most %d", maxHistogramGroups)), nil
+			}
+
+			query := filter
+			if groupBy != "" {
+				query = fmt.Sprintf("{%s} by {%s}", filter, groupBy)
+			}
+
+			payload, err := NewGraphQueryBuilder().
+				WithQuery("Q1", GraphQuery{Scope: "log", Query: query}).
+				WithFormula("R1", "Q1").
+				Build()
+			if err != nil {
+				return nil, err
+			}
+
+			queryParams := url.Values{}
+			queryParams.Add("graph_type", "timeseries")
+			queryParams.Add("limit", strconv.Itoa(limit))
+			if bucket, _ := params.Optional[string](request, "bucket"); bucket != "" {
+				d, err := ParseLookback(bucket)
+				if err != nil || d < minHistogramBucket {
+					return mcp.NewToolResultError(fmt.Sprintf("invalid bucket %q, expected a duration of at least 1m such as 5m or 1h", bucket)), nil
+				}
+				queryParams.Add("window", d.String())
+			}
+
+			if lookback, _ := params.Optional[string](request, "lookback"); lookback != "" {
+				queryParams.Add("lookback", lookback)
+			}
+
+			if from, _ := params.Optional[string](request, "from"); from != "" {
+				queryParams.Add("from", from)
+			}
+
+			if to, _ := params.Optional[string](request, "to"); to != "" {
+				queryParams.Add("to", to)
+			}
+
+			statusCode, bodyBytes, warnings, err := postGraphWithRetry(ctx, client, payload, queryParams)
+			if err != nil {
+				return nil, err
+			}
+
+			if statusCode != http.StatusMultiStatus {
+				return nil, fmt.Errorf("failed to count logs, status code %d: %s", statusCode, string(bodyBytes))
+			}
+
+			var graphResp map[string]struct {
+				From    string                  `json:"from"`
+				To      string                  `json:"to"`
+				Window  string                  `json:"window"`
+				Records []graphTimeseriesRecord `json:"records"`
+			}
+			if err := json.Unmarshal(bodyBytes, &graphResp); err != nil {
+				return nil, fmt.Errorf("failed to decode graph response: %w", err)
+			}
+
+			r1 := graphResp["R1"]
+			result := LogHistogramResult{
+				Query:    query,
+				GroupBy:  groupBy,
+				Bucket:   r1.Window,
+				From:     r1.From,
+				To:       r1.To,
+				Groups:   histogramGroups(r1.Records, render),
+				UILink:   uiLink(ctx, client, UILogsPage, query, queryParams),
+				Warnings: warnings,
+				Stats:    parseQueryStats(bodyBytes),
+			}
+			for _, group := range result.Groups {
+				result.Total += group.Total
+			}
+			result.Guidance = logHistogramGuidance(result, render)
+
+			r, err := json.Marshal(result)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
+
+// histogramGroups returns the groups of the graph records, largest first.
+func histogramGroups(records []graphTimeseriesRecord, render string) []HistogramGroup {
+	groups := make([]HistogramGroup, 0, len(records))
+	for _, record := range records {
+		group := HistogramGroup{Value: strings.Join(record.Values, ", ")}
+		sort.SliceStable(record.Timeseries, func(i, j int) bool {
+			return record.Timeseries[i].Timestamp < record.Timeseries[j].Timestamp
+		})
+		values := make([]float64, len(record.Timeseries))
+		for i, point := range record.Timeseries {
+			values[i] = point.Value
+			group.Total += int64(point.Value)
+			if render != RenderSparkline {
+				group.Buckets = append(group.Buckets, HistogramBucket{
+					Time:  time.UnixMilli(point.Timestamp).UTC().Format(isoTimeLayout),
+					Count: int64(point.Value),
+				})
+			}
+		}
+		if render == RenderSparkline && len(values) > 0 {
+			group.Sparkline = sparkline(values)
+		}
+		groups = append(groups, group)
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].Total > groups[j].Total })
+	return groups
+}
+
+func logHistogramGuidance(result LogHistogramResult, render string) *GraphGuidance {
+	if result.Total == 0 && len(result.Warnings) > 0 {
+		return &GraphGuidance{
+			ResultStatus: "failed",
+			NextSteps:    []string{"The query failed, see warnings. Check the query with validate_cql and the group_by field with facets."},
+		}
+	}
+	if result.Total == 0 {
+		return &GraphGuidance{
+			ResultStatus: "empty",
+			NextSteps: []string{
+				fmt.Sprintf("No logs found for query: %s", result.Query),
+				"This is a valid signal - no logs exist for this time range or filter.",
+			},
+			Suggestions: []string{
+				"Verify field values with facet_options tool to ensure the values exist in your data",
+				"Try a broader time range (e.g., lookback:\"24h\" or lookback:\"7d\")",
+			},
+		}
+	}
+
+	guidance := &GraphGuidance{
+		ResultStatus: "success",
+		NextSteps: []string{
+			"Look for the bucket where a group changed, then call get_log_search with that group in the query and the bucket as from/to.",
+		},
+	}
+	if len(result.Warnings) > 0 {
+		guidance.ResultStatus = "partial"
+	}
+	if render == RenderSparkline {
+		guidance.Suggestions = append(guidance.Suggestions, `Call again with render:"raw" for the count of every bucket`)
+	}
+	guidance.Suggestions = append(guidance.Suggestions, queryStatsSuggestions(result.Stats)...)
+	return guidance
+}
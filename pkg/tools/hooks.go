@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// PreToolHook runs before a tool executes, e.g. to ask a human for approval. Returning an error
+// denies the call: the tool does not run and the error message is returned to the agent.
+type PreToolHook func(ctx context.Context, request mcp.CallToolRequest) error
+
+// PostToolHook runs after a tool executed with its result and error, e.g. to notify a channel.
+type PostToolHook func(ctx context.Context, request mcp.CallToolRequest, result *mcp.CallToolResult, err error)
+
+// ToolHooks returns a tool middleware that runs the hooks around calls of the named tool.
+// Calls of other tools pass through untouched.
+func ToolHooks(toolName string, pre []PreToolHook, post []PostToolHook) ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			if request.Params.Name != toolName {
+				return next(ctx, request)
+			}
+
+			for _, hook := range pre {
+				if err := hook(ctx, request); err != nil {
+					var toolErr *ToolError
+					if errors.As(err, &toolErr) {
+						return newToolResultToolError(toolErr), nil
+					}
+					return NewToolResultErrorCode(ErrCodeRejected, err.Error()), nil
+				}
+			}
+
+			result, err := next(ctx, request)
+			for _, hook := range post {
+				hook(ctx, request, result, err)
+			}
+			return result, err
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	defaultInvestigationItems = 10
+	maxInvestigationItems     = 50
+)
+
+type InvestigateWindowResult struct {
+	Service string `json:"service"`
+	// Window is the lookback or the from/to range of the investigation
+	Window           string               `json:"window"`
+	ErrorLogs        InvestigationSection `json:"error_logs"`
+	Anomalies        InvestigationSection `json:"anomalies"`
+	NegativePatterns InvestigationSection `json:"negative_patterns"`
+	FailedTraces     InvestigationSection `json:"failed_traces"`
+	Guidance         *SearchGuidance      `json:"guidance,omitempty"`
+	sections         []*InvestigationSection
+}
+
+// InvestigationSection is the result of one of the searches of an investigation. A failed search
+// has Error set and no items, the other sections are still returned.
+type InvestigationSection struct {
+	Query  string          `json:"query"`
+	Count  int             `json:"count"`
+	Items  json.RawMessage `json:"items,omitempty"`
+	UILink string          `json:"ui_link,omitempty"`
+	Error  string          `json:"error,omitempty"`
+	name   string
+}
+
+// investigationSearch is one of the searches of an investigation, run concurrently with the others.
+type investigationSearch struct {
+	section *InvestigationSection
+	page    UIPage
+	// key is the field of the response holding the items
+	key    string
+	search func(ctx context.Context, client Client, queryParams url.Values) ([]byte, error)
+	params url.Values
+}
+
+// InvestigateWindowTool creates a tool fetching the error signals of a service over a time window at once
+func InvestigateWindowTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("investigate_window",
+			mcp.WithTitleAnnotation("Investigate Window"),
+			mcp.WithDescription(`Fetches the error signals of a service over an incident window in one call: its error logs, the anomalies detected in its log patterns, its negative sentiment log patterns and its failed traces.
+
+Use this tool as the first step of an incident investigation instead of calling get_log_search, anomaly_search, get_log_patterns and get_trace_timeline one after the other.
+The four searches run concurrently; a search that fails reports its error without failing the others.
+
+Then:
+- get_trace_error_chain with a trace_id of the failed traces to find where the errors originate
+- compare_windows with scope "pattern" to check the patterns are new compared to yesterday`),
+			mcp.WithString("service",
+				mcp.Description("service.name of the service to investigate."),
+				mcp.Required(),
+			),
+			mcp.WithString("lookback",
+				mcp.Description("Lookback period in GOLANG duration format, or days and weeks. e.g. (15m, 1h, 24h). Either provide from/to or just lookback. Pass empty string to use from/to instead."),
+				mcp.DefaultString("1h"),
+			),
+			mcp.WithString("from",
+				mcp.Description("From datetime in ISO format 2006-01-02T15:04:05.000Z."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("to",
+				mcp.Description("To datetime in ISO format 2006-01-02T15:04:05.000Z."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description(fmt.Sprintf("Maximum number of items of every section. Default: %d, max %d", defaultInvestigationItems, maxInvestigationItems)),
+				mcp.DefaultNumber(defaultInvestigationItems),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			service, err := request.RequireString("service")
+			if service = strings.TrimSpace(service); err != nil || service == "" {
+				return mcp.NewToolResultError("missing required parameter: service"), nil
+			}
+
+			limit := defaultInvestigationItems
+			if v, _ := params.Optional[float64](request, "limit"); v > 0 {
+				limit = int(v)
+			}
+			if limit > maxInvestigationItems {
+				return mcp.NewToolResultError(fmt.Sprintf("limit must be at most %d", maxInvestigationItems)), nil
+			}
+
+			timeParams := url.Values{}
+			lookback, _ := params.Optional[string](request, "lookback")
+			from, _ := params.Optional[string](request, "from")
+			to, _ := params.Optional[string](request, "to")
+			window := lookback
+			switch {
+			case from != "":
+				timeParams.Add("from", from)
+				if to != "" {
+					timeParams.Add("to", to)
+				}
+				window = from + " - " + to
+			case lookback != "":
+				timeParams.Add("lookback", lookback)
+			default:
+				timeParams.Add("lookback", "1h")
+				window = "1h"
+			}
+
+			serviceFilter := fmt.Sprintf(`service.name:"%s"`, escapeValue(service))
+			result := InvestigateWindowResult{
+				Service:          service,
+				Window:           window,
+				ErrorLogs:        InvestigationSection{Query: serviceFilter + " AND " + compareErrorQuery, name: "error logs"},
+				Anomalies:        InvestigationSection{Query: buildAnomalyQuery(service, ""), name: "anomalies"},
+				NegativePatterns: InvestigationSection{Query: serviceFilter, name: "negative patterns"},
+				FailedTraces:     InvestigationSection{Query: serviceFilter + ` AND status.code:"ERROR"`, name: "failed traces"},
+			}
+			searches := []investigationSearch{
+				{section: &result.ErrorLogs, page: UILogsPage, key: "items", search: searchLogs, params: url.Values{"order": {"desc"}}},
+				{section: &result.Anomalies, page: UIEventsPage, key: "items", search: searchEvents, params: url.Values{"order": {"desc"}}},
+				{section: &result.NegativePatterns, page: UIPatternsPage, key: "stats", search: getPatternStats, params: url.Values{"negative": {"true"}}},
+				{section: &result.FailedTraces, page: UITracesPage, key: "items", search: searchTraces, params: url.Values{"order": {"desc"}}},
+			}
+
+			var wg sync.WaitGroup
+			for _, s := range searches {
+				queryParams := url.Values{}
+				for k, v := range timeParams {
+					queryParams[k] = v
+				}
+				for k, v := range s.params {
+					queryParams[k] = v
+				}
+				queryParams.Set("query", s.section.Query)
+				queryParams.Set("limit", strconv.Itoa(limit))
+				s.section.UILink = uiLink(ctx, client, s.page, s.section.Query, queryParams)
+
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					runInvestigationSearch(ctx, client, s, queryParams)
+				}()
+			}
+			wg.Wait()
+
+			for _, s := range searches {
+				result.sections = append(result.sections, s.section)
+			}
+			result.Guidance = investigateWindowGuidance(result)
+
+			r, err := json.Marshal(result)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
+
+func runInvestigationSearch(ctx context.Context, client Client, s investigationSearch, queryParams url.Values) {
+	bodyBytes, err := s.search(ctx, client, queryParams)
+	if err != nil {
+		s.section.Error = err.Error()
+		return
+	}
+
+	var resp map[string]json.RawMessage
+	if err := json.Unmarshal(bodyBytes, &resp); err != nil {
+		s.section.Error = fmt.Sprintf("failed to decode %s response: %v", s.section.name, err)
+		return
+	}
+	var items []json.RawMessage
+	if raw, ok := resp[s.key]; ok {
+		if err := json.Unmarshal(raw, &items); err != nil {
+			s.section.Error = fmt.Sprintf("failed to decode %s: %v", s.section.name, err)
+			return
+		}
+		s.section.Items = raw
+	}
+	if s.section.Count = len(items); s.section.Count == 0 {
+		s.section.Items = json.RawMessage("[]")
+	}
+}
+
+func investigateWindowGuidance(result InvestigateWindowResult) *SearchGuidance {
+	guidance := &SearchGuidance{ResultStatus: "success"}
+
+	var failed, found []string
+	for _, section := range result.sections {
+		switch {
+		case section.Error != "":
+			failed = append(failed, section.name)
+		case section.Count > 0:
+			found = append(found, fmt.Sprintf("%d %s", section.Count, section.name))
+		}
+	}
+	switch {
+	case len(failed) == len(result.sections):
+		guidance.ResultStatus = "failed"
+		guidance.NextSteps = append(guidance.NextSteps, "All the searches failed, see the error of every section.")
+		return guidance
+	case len(failed) > 0:
+		guidance.ResultStatus = "partial"
+		guidance.NextSteps = append(guidance.NextSteps, fmt.Sprintf("Searches failed for %s, see their error.", strings.Join(failed, ", ")))
+	}
+	if len(found) == 0 {
+		if guidance.ResultStatus == "success" {
+			guidance.ResultStatus = "empty"
+		}
+		guidance.NextSteps = append(guidance.NextSteps,
+			fmt.Sprintf("No error signal for %s in %s.", result.Service, result.Window),
+			"Verify the service name with facet_options (facet_path: \"service.name\") or widen the window.")
+		return guidance
+	}
+
+	guidance.NextSteps = append(guidance.NextSteps, fmt.Sprintf("Found %s for %s.", strings.Join(found, ", "), result.Service))
+	if traceID := firstInvestigationItem(result.FailedTraces, "trace_id"); traceID != "" {
+		guidance.Suggestions = append(guidance.Suggestions, fmt.Sprintf("Use get_trace_error_chain with trace_id %q to find where the errors originate.", traceID))
+	}
+	if pattern := firstInvestigationItem(result.NegativePatterns, "pattern"); pattern != "" {
+		guidance.Suggestions = append(guidance.Suggestions,
+			fmt.Sprintf("Use compare_windows with scope \"pattern\" and query %s to check whether %q is new.", result.NegativePatterns.Query, pattern))
+	}
+	return guidance
+}
+
+// firstInvestigationItem returns a string field of the first item of the section, if any.
+func firstInvestigationItem(section InvestigationSection, key string) string {
+	var items []map[string]any
+	if section.Count == 0 || json.Unmarshal(section.Items, &items) != nil || len(items) == 0 {
+		return ""
+	}
+	return recordString(items[0], key)
+}
@@ -0,0 +1,233 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// k8sEntityFacets maps the entity kinds of resolve_k8s_entity to their facet paths.
+var k8sEntityFacets = map[string]string{
+	"namespace":  "k8s.namespace.name",
+	"pod":        "k8s.pod.name",
+	"deployment": "k8s.deployment.name",
+}
+
+// k8sHashSuffixPattern matches the generated suffixes of pod names, e.g. "-7d9f8b6c5d-x2kqp"
+// of deployment pods, "-x2kqp" of daemon set pods or "-0" of stateful set pods.
+var k8sHashSuffixPattern = regexp.MustCompile(`(-[a-z0-9]{8,10})?-[a-z0-9]{5}$|-[0-9]+$`)
+
+const k8sEntityPoolLimit = "1000"
+
+type K8sEntityResult struct {
+	Pattern    string             `json:"pattern"`
+	Kind       string             `json:"kind"`
+	FacetPath  string             `json:"facet_path"`
+	Candidates []K8sEntityMatch   `json:"candidates"`
+	Guidance   *DiscoveryGuidance `json:"guidance,omitempty"`
+}
+
+type K8sEntityMatch struct {
+	Name string `json:"name"`
+	// MatchType is how the pattern matched: exact, prefix, contains or fuzzy.
+	MatchType string  `json:"match_type"`
+	Count     int     `json:"count,omitempty"`
+	Score     float64 `json:"score"`
+}
+
+// GetResolveK8sEntityTool creates a tool to fuzzy-resolve Kubernetes entity names
+func GetResolveK8sEntityTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("resolve_k8s_entity",
+			mcp.WithTitleAnnotation("Resolve Kubernetes Entity"),
+			mcp.WithDescription(`Resolves a partial or misspelled Kubernetes namespace, pod or deployment name to the exact names seen in your data.
+
+Use this tool BEFORE filtering on k8s.namespace.name, k8s.pod.name or k8s.deployment.name when the user did not give the exact name.
+Pod names carry generated hash suffixes (e.g. "checkout-5c6b7f9d8-m4zlr"), so "checkout" resolves to all checkout pods.
+
+Examples:
+- resolve_k8s_entity(pattern: "checkout", kind: "pod")
+- resolve_k8s_entity(pattern: "kube sys", kind: "namespace")
+- resolve_k8s_entity(pattern: "paymnts", kind: "deployment") -> typos are matched as a subsequence
+
+Returns candidates ranked by match quality and volume. Use the exact name in your CQL filter.`),
+			mcp.WithString("pattern",
+				mcp.Description("Partial name, keywords or abbreviation to search for. Case-insensitive."),
+				mcp.Required(),
+			),
+			mcp.WithString("kind",
+				mcp.Description(`Kind of entity: "namespace", "pod" or "deployment".`),
+				mcp.DefaultString("pod"),
+				mcp.Enum("namespace", "pod", "deployment"),
+			),
+			mcp.WithString("scope",
+				mcp.Description(`Scope to take the names from: "log", "metric", "trace" or "event".`),
+				mcp.DefaultString("log"),
+				mcp.Enum("log", "metric", "trace", "event"),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description("Maximum number of candidates. Default: 10"),
+				mcp.DefaultNumber(10),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			pattern, err := request.RequireString("pattern")
+			if err != nil {
+				return mcp.NewToolResultError("missing required parameter: pattern"), nil
+			}
+
+			kind, _ := params.Optional[string](request, "kind")
+			if kind == "" {
+				kind = "pod"
+			}
+
+			facetPath, ok := k8sEntityFacets[kind]
+			if !ok {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid kind %q, expected namespace, pod or deployment", kind)), nil
+			}
+
+			scope, _ := params.Optional[string](request, "scope")
+			if scope == "" {
+				scope = "log"
+			}
+
+			limit := request.GetInt("limit", 10)
+			if limit <= 0 {
+				limit = 10
+			}
+			if limit > 100 {
+				limit = 100
+			}
+
+			facet, err := GetFacetOptions(ctx, client, WithScope(scope), WithFacet(facetPath), WithLimit(k8sEntityPoolLimit))
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch %s values: %w", facetPath, err)
+			}
+
+			var options []FacetOption
+			if facet != nil {
+				options = facet.Options
+			}
+
+			result := K8sEntityResult{
+				Pattern:    pattern,
+				Kind:       kind,
+				FacetPath:  facetPath,
+				Candidates: matchNames(pattern, options, limit, kind == "pod"),
+			}
+
+			if len(result.Candidates) > 0 {
+				best := result.Candidates[0].Name
+				result.Guidance = &DiscoveryGuidance{
+					ResultStatus: "success",
+					NextSteps: []string{
+						fmt.Sprintf("Filter on the exact name: %s:\"%s\"", facetPath, best),
+					},
+				}
+				if kind == "pod" {
+					result.Guidance.Suggestions = append(result.Guidance.Suggestions,
+						"Pods get new names when they are recreated; to follow a workload over time resolve and filter on its deployment (kind: \"deployment\") instead.")
+				}
+			} else {
+				result.Guidance = &DiscoveryGuidance{
+					ResultStatus: "empty",
+					NextSteps: []string{
+						fmt.Sprintf("No %s names matching %q found in scope %s.", kind, pattern, scope),
+					},
+					Suggestions: []string{
+						"Try a shorter pattern or another scope.",
+						fmt.Sprintf("Use facet_options tool with scope:'%s' and facet_path:'%s' to list the names.", scope, facetPath),
+					},
+				}
+			}
+
+			r, err := json.Marshal(result)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
+
+// matchNames ranks the names matching the pattern: an exact match of the name, or of a pod
+// name without its generated hash suffix, ranks first, then prefixes, then names containing all
+// the pattern's words, then names containing the pattern's characters in order.
+func matchNames(pattern string, options []FacetOption, limit int, pods bool) []K8sEntityMatch {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	words := strings.Fields(pattern)
+	compact := strings.Join(words, "")
+
+	var matches []K8sEntityMatch
+	for _, opt := range options {
+		name := strings.ToLower(opt.Name)
+		base := name
+		if pods {
+			base = k8sHashSuffixPattern.ReplaceAllString(name, "")
+		}
+
+		var match K8sEntityMatch
+		switch {
+		case name == pattern || base == pattern:
+			match = K8sEntityMatch{MatchType: "exact", Score: 4}
+		case strings.HasPrefix(name, pattern):
+			match = K8sEntityMatch{MatchType: "prefix", Score: 3}
+		case containsAll(name, words):
+			match = K8sEntityMatch{MatchType: "contains", Score: 2}
+		case isSubsequence(compact, name):
+			match = K8sEntityMatch{MatchType: "fuzzy", Score: 1}
+		default:
+			continue
+		}
+
+		// Within a match type, prefer names where the pattern covers more of the workload name.
+		match.Score += min(0.99, float64(len(compact))/float64(len(base)+1))
+		match.Name = opt.Name
+		match.Count = opt.Count
+		matches = append(matches, match)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Count > matches[j].Count
+	})
+
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+func containsAll(s string, words []string) bool {
+	for _, w := range words {
+		if !strings.Contains(s, w) {
+			return false
+		}
+	}
+	return len(words) > 0
+}
+
+// isSubsequence reports whether the characters of sub appear in s in order.
+func isSubsequence(sub, s string) bool {
+	if sub == "" {
+		return false
+	}
+	i := 0
+	for j := 0; j < len(s) && i < len(sub); j++ {
+		if s[j] == sub[i] {
+			i++
+		}
+	}
+	return i == len(sub)
+}
@@ -0,0 +1,249 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const isoTimeLayout = "2006-01-02T15:04:05.000Z"
+
+// LookbackLimits caps the time range tools may query. A zero limit means no cap.
+type LookbackLimits struct {
+	Default time.Duration
+	PerOrg  map[string]time.Duration
+}
+
+// For returns the limit that applies to the org.
+func (l LookbackLimits) For(orgID string) time.Duration {
+	if d, ok := l.PerOrg[orgID]; ok {
+		return d
+	}
+	return l.Default
+}
+
+// Enabled reports whether any limit is configured.
+func (l LookbackLimits) Enabled() bool {
+	return l.Default > 0 || len(l.PerOrg) > 0
+}
+
+// dayWeekPattern matches the day and week units of durations, e.g. 7d or 1.5w, which Go durations
+// do not have.
+var dayWeekPattern = regexp.MustCompile(`(\d+(?:\.\d*)?|\.\d+)([dw])`)
+
+// ParseLookback parses a duration in Go duration format that may also use days and weeks, such
+// as "15m", "24h", "7d", "1w" or "1d12h".
+func ParseLookback(s string) (time.Duration, error) {
+	expanded := dayWeekPattern.ReplaceAllStringFunc(strings.TrimSpace(s), func(m string) string {
+		n, _ := strconv.ParseFloat(m[:len(m)-1], 64)
+		if m[len(m)-1] == 'w' {
+			n *= 7
+		}
+		return strconv.FormatFloat(n*24, 'f', -1, 64) + "h"
+	})
+	d, err := time.ParseDuration(expanded)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q, expected e.g. 15m, 24h, 7d or 1w", s)
+	}
+	return d, nil
+}
+
+// formatDuration formats a duration in Go duration format without its zero trailing units, e.g.
+// 168h instead of 168h0m0s.
+func formatDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// DurationParameters are the tool parameters holding durations, or comma separated durations,
+// the API expects in Go duration format.
+var DurationParameters = []string{"lookback", "offset", "volatility_offset", "window"}
+
+// normalizeDurations converts the durations of the parameters using days or weeks to Go
+// durations. It returns the arguments untouched if none does.
+func normalizeDurations(args map[string]any, parameters []string) (map[string]any, error) {
+	var normalized map[string]any
+	for _, parameter := range parameters {
+		value, ok := args[parameter].(string)
+		if !ok || !dayWeekPattern.MatchString(value) {
+			continue
+		}
+
+		durations := strings.Split(value, ",")
+		for i, duration := range durations {
+			if !dayWeekPattern.MatchString(duration) {
+				continue
+			}
+			d, err := ParseLookback(duration)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", parameter, err)
+			}
+			durations[i] = formatDuration(d)
+		}
+
+		if normalized == nil {
+			normalized = make(map[string]any, len(args))
+			for k, v := range args {
+				normalized[k] = v
+			}
+		}
+		normalized[parameter] = strings.Join(durations, ",")
+	}
+	if normalized == nil {
+		return args, nil
+	}
+	return normalized, nil
+}
+
+// NormalizeDurations returns a tool middleware converting the durations of the parameters given
+// in days or weeks, e.g. lookback 7d or offset 1w, to Go durations before the tools send them to
+// the API, which only accepts Go durations. Invalid durations fail with ErrCodeInvalidArgument.
+func NormalizeDurations(parameters []string) ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			args, err := normalizeDurations(request.GetArguments(), parameters)
+			if err != nil {
+				return NewToolResultErrorCode(ErrCodeInvalidArgument, err.Error()), nil
+			}
+			request.Params.Arguments = args
+			return next(ctx, request)
+		}
+	}
+}
+
+// ParseLookbackLimits parses per org limits in the form "org1=24h,org2=7d".
+func ParseLookbackLimits(s string) (map[string]time.Duration, error) {
+	limits := make(map[string]time.Duration)
+	for _, entry := range strings.Split(s, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+
+		orgID, lookback, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid max lookback entry %q, expected <org_id>=<duration>", entry)
+		}
+
+		d, err := ParseLookback(strings.TrimSpace(lookback))
+		if err != nil {
+			return nil, fmt.Errorf("invalid max lookback for org %s: %w", orgID, err)
+		}
+		limits[strings.TrimSpace(orgID)] = d
+	}
+	return limits, nil
+}
+
+// nonTimeRangeTools take from and to arguments that are not a time range, e.g. the nodes an edge
+// of a pipeline connects, so the guardrail leaves them alone.
+var nonTimeRangeTools = map[string]bool{
+	"connect_pipeline_nodes": true,
+}
+
+// LookbackGuardrail returns a tool middleware that clamps the lookback, or the from/to range,
+// of every tool call to the configured maximum. Clamped calls still run and get a warning
+// appended to their result instead of failing, so the agent can adjust its next query. Calls
+// whose lookback or from/to do not parse fail with ErrCodeInvalidArgument, as the range cannot be
+// checked.
+func LookbackGuardrail(limits LookbackLimits) ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			if nonTimeRangeTools[request.Params.Name] {
+				return next(ctx, request)
+			}
+
+			// Calls without an org get the default limit
+			maxLookback := limits.Default
+			if keys, err := FetchContextKeys(ctx); err == nil {
+				maxLookback = limits.For(keys.OrgID)
+			}
+			if maxLookback <= 0 {
+				return next(ctx, request)
+			}
+
+			args, warning, err := clampTimeRange(request.GetArguments(), maxLookback, time.Now().UTC())
+			if err != nil {
+				return NewToolResultErrorCode(ErrCodeInvalidArgument, err.Error()), nil
+			}
+			if warning == "" {
+				return next(ctx, request)
+			}
+
+			request.Params.Arguments = args
+			result, err := next(ctx, request)
+			if err != nil || result == nil {
+				return result, err
+			}
+
+			warningBytes, err := json.Marshal(map[string]any{"warnings": []string{warning}})
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal warnings: %w", err)
+			}
+
+			result.Content = append(result.Content, mcp.NewTextContent(string(warningBytes)))
+			return result, nil
+		}
+	}
+}
+
+// clampTimeRange returns a copy of the arguments with the time range limited to limit and a
+// warning describing the change, or the arguments untouched and an empty warning. It fails if the
+// lookback or from/to do not parse, so other formats the API accepts cannot bypass the limit.
+func clampTimeRange(args map[string]any, limit time.Duration, now time.Time) (map[string]any, string, error) {
+	lookback, _ := args["lookback"].(string)
+	from, _ := args["from"].(string)
+	to, _ := args["to"].(string)
+
+	clamped := make(map[string]any, len(args))
+	for k, v := range args {
+		clamped[k] = v
+	}
+
+	if lookback != "" {
+		d, err := ParseLookback(lookback)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid lookback %q, expected a duration such as 1h or 7d", lookback)
+		}
+		if d <= limit {
+			return args, "", nil
+		}
+
+		clamped["lookback"] = limit.String()
+		return clamped, fmt.Sprintf("lookback %s exceeds the maximum of %s allowed by this server and was clamped to %s", lookback, limit, limit), nil
+	}
+
+	if from == "" {
+		return args, "", nil
+	}
+
+	fromTime, err := time.Parse(time.RFC3339, from)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid from %q, expected an ISO 8601 datetime such as %s", from, isoTimeLayout)
+	}
+
+	toTime := now
+	if to != "" {
+		if toTime, err = time.Parse(time.RFC3339, to); err != nil {
+			return nil, "", fmt.Errorf("invalid to %q, expected an ISO 8601 datetime such as %s", to, isoTimeLayout)
+		}
+	}
+
+	if toTime.Sub(fromTime) <= limit {
+		return args, "", nil
+	}
+
+	clamped["from"] = toTime.Add(-limit).Format(isoTimeLayout)
+	return clamped, fmt.Sprintf("time range %s - %s exceeds the maximum of %s allowed by this server, from was moved to %s", from, toTime.Format(isoTimeLayout), limit, clamped["from"]), nil
+}
@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestClampTimeRange(t *testing.T) {
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	limit := 48 * time.Hour
+
+	tests := []struct {
+		name     string
+		args     map[string]any
+		wantFrom string
+		wantWarn bool
+		wantErr  bool
+	}{
+		{"lookback within the limit", map[string]any{"lookback": "1d"}, "", false, false},
+		{"lookback over the limit", map[string]any{"lookback": "1w"}, "", true, false},
+		{"invalid lookback", map[string]any{"lookback": "bogus"}, "", false, true},
+		{"range within the limit", map[string]any{"from": "2026-01-09T00:00:00.000Z"}, "2026-01-09T00:00:00.000Z", false, false},
+		{"range over the limit", map[string]any{"from": "2026-01-01T00:00:00Z", "to": "2026-01-09T00:00:00Z"}, "2026-01-07T00:00:00.000Z", true, false},
+		{"epoch from", map[string]any{"from": "1767225600000"}, "", false, true},
+		{"from without offset", map[string]any{"from": "2026-01-01 00:00:00"}, "", false, true},
+		{"invalid to", map[string]any{"from": "2026-01-09T00:00:00Z", "to": "yesterday"}, "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, warning, err := clampTimeRange(tt.args, limit, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("clampTimeRange returned error %v, want error %v", err, tt.wantErr)
+			}
+			if (warning != "") != tt.wantWarn {
+				t.Errorf("clampTimeRange returned warning %q, want warning %v", warning, tt.wantWarn)
+			}
+			if from, _ := args["from"].(string); tt.wantFrom != "" && from != tt.wantFrom {
+				t.Errorf("from is %q, want %q", from, tt.wantFrom)
+			}
+		})
+	}
+}
+
+// TestDurationsAcceptDays checks that the duration parameters parsed outside
+// DurationParameters accept days and weeks like lookback does.
+func TestDurationsAcceptDays(t *testing.T) {
+	timeouts, err := ParseToolTimeouts("tail_logs=1d,get_log_graph=2m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timeouts["tail_logs"] != 24*time.Hour || timeouts["get_log_graph"] != 2*time.Minute {
+		t.Errorf("ParseToolTimeouts returned %v", timeouts)
+	}
+
+	var request mcp.CallToolRequest
+	request.Params.Arguments = map[string]any{"duration": "1d", "poll_interval": "0s"}
+	if d, err := tailDurationParam(request, "duration", time.Second); err != nil || d != 24*time.Hour {
+		t.Errorf("tailDurationParam returned %s, %v, want 24h", d, err)
+	}
+	if _, err := tailDurationParam(request, "poll_interval", time.Second); err == nil {
+		t.Error("tailDurationParam accepted a zero interval")
+	}
+}
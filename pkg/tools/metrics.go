@@ -0,0 +1,273 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Upper bounds of the buckets of the histograms, in seconds and bytes.
+var (
+	durationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+	sizeBuckets     = []float64{1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20, 16 << 20}
+)
+
+// otherEndpoint labels the API requests to paths that are not in ToolEndpoints.
+const otherEndpoint = "other"
+
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, bound := range h.buckets {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// Metrics collects the metrics of tool calls and of the requests to the Edge Delta API, and
+// serves them in the Prometheus text format. Endpoints are labeled with their path in
+// ToolEndpoints, e.g. /v1/orgs/{org_id}/graph, so org IDs do not become label values.
+type Metrics struct {
+	mu sync.Mutex
+	// toolCalls counts the calls by tool and outcome
+	toolCalls       map[[2]string]uint64
+	toolDuration    map[string]*histogram
+	toolResultBytes map[string]*histogram
+	// apiDuration is by method, endpoint and status code
+	apiDuration      map[[3]string]*histogram
+	apiResponseBytes map[[2]string]*histogram
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		toolCalls:        make(map[[2]string]uint64),
+		toolDuration:     make(map[string]*histogram),
+		toolResultBytes:  make(map[string]*histogram),
+		apiDuration:      make(map[[3]string]*histogram),
+		apiResponseBytes: make(map[[2]string]*histogram),
+	}
+}
+
+// Middleware counts tool calls by outcome, and observes their durations and result sizes.
+func (m *Metrics) Middleware() ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			start := time.Now()
+			result, err := next(ctx, request)
+			duration := time.Since(start)
+
+			outcome, size := "success", 0
+			if err != nil || result == nil || result.IsError {
+				outcome = "error"
+			}
+			if result != nil {
+				for _, content := range result.Content {
+					if text, ok := content.(mcp.TextContent); ok {
+						size += len(text.Text)
+					}
+				}
+			}
+
+			tool := request.Params.Name
+			m.mu.Lock()
+			m.toolCalls[[2]string{tool, outcome}]++
+			observe(m.toolDuration, tool, durationBuckets, duration.Seconds())
+			observe(m.toolResultBytes, tool, sizeBuckets, float64(size))
+			m.mu.Unlock()
+			return result, err
+		}
+	}
+}
+
+func observe[K comparable](histograms map[K]*histogram, key K, buckets []float64, v float64) {
+	h, ok := histograms[key]
+	if !ok {
+		h = newHistogram(buckets)
+		histograms[key] = h
+	}
+	h.observe(v)
+}
+
+// WithMetrics observes the duration and response size of every attempt of the requests of the
+// client. Apply it after the options configuring the transport, e.g. WithUserAgent.
+func WithMetrics(m *Metrics) HTTPClientOption {
+	return func(c *HTTPClient) {
+		next := c.cl.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		c.cl.Transport = &metricsTransport{metrics: m, next: next}
+	}
+}
+
+type metricsTransport struct {
+	metrics *Metrics
+	next    http.RoundTripper
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := endpointLabel(req.URL.Path)
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	duration := time.Since(start)
+
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	t.metrics.mu.Lock()
+	observe(t.metrics.apiDuration, [3]string{req.Method, endpoint, code}, durationBuckets, duration.Seconds())
+	t.metrics.mu.Unlock()
+
+	if err == nil {
+		resp.Body = &countingBody{ReadCloser: resp.Body, done: func(n int) {
+			t.metrics.mu.Lock()
+			observe(t.metrics.apiResponseBytes, [2]string{req.Method, endpoint}, sizeBuckets, float64(n))
+			t.metrics.mu.Unlock()
+		}}
+	}
+	return resp, err
+}
+
+// countingBody counts the bytes read from a response body, reporting them once it is closed.
+type countingBody struct {
+	io.ReadCloser
+	n    int
+	once sync.Once
+	done func(n int)
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += n
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	b.once.Do(func() { b.done(b.n) })
+	return b.ReadCloser.Close()
+}
+
+var endpointPatterns = func() []*regexp.Regexp {
+	patterns := make([]*regexp.Regexp, len(ToolEndpoints))
+	for i, endpoint := range ToolEndpoints {
+		parts := pathParamPattern.Split(endpoint.Path, -1)
+		for j, part := range parts {
+			parts[j] = regexp.QuoteMeta(part)
+		}
+		patterns[i] = regexp.MustCompile("^" + strings.Join(parts, "[^/]+") + "/?$")
+	}
+	return patterns
+}()
+
+// endpointLabel returns the path in ToolEndpoints the request path matches, or otherEndpoint.
+func endpointLabel(path string) string {
+	for i, pattern := range endpointPatterns {
+		if pattern.MatchString(path) {
+			return ToolEndpoints[i].Path
+		}
+	}
+	return otherEndpoint
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		bw := bufio.NewWriter(w)
+		m.write(bw)
+		_ = bw.Flush()
+	})
+}
+
+func (m *Metrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP edgedelta_mcp_tool_calls_total Tool calls by tool and outcome.")
+	fmt.Fprintln(w, "# TYPE edgedelta_mcp_tool_calls_total counter")
+	calls := make([][2]string, 0, len(m.toolCalls))
+	for key := range m.toolCalls {
+		calls = append(calls, key)
+	}
+	sort.Slice(calls, func(i, j int) bool { return calls[i][0]+"\x00"+calls[i][1] < calls[j][0]+"\x00"+calls[j][1] })
+	for _, key := range calls {
+		fmt.Fprintf(w, "edgedelta_mcp_tool_calls_total{%s} %d\n", labels("tool", key[0], "outcome", key[1]), m.toolCalls[key])
+	}
+
+	writeHistograms(w, "edgedelta_mcp_tool_call_duration_seconds", "Duration of tool calls by tool.", m.toolDuration, func(tool string) []string {
+		return []string{"tool", tool}
+	})
+	writeHistograms(w, "edgedelta_mcp_tool_result_bytes", "Size of the text of tool results by tool.", m.toolResultBytes, func(tool string) []string {
+		return []string{"tool", tool}
+	})
+	writeHistograms(w, "edgedelta_mcp_api_request_duration_seconds", "Duration of the requests to the Edge Delta API by method, endpoint and status code.", m.apiDuration, func(key [3]string) []string {
+		return []string{"method", key[0], "endpoint", key[1], "code", key[2]}
+	})
+	writeHistograms(w, "edgedelta_mcp_api_response_bytes", "Size of the responses of the Edge Delta API by method and endpoint.", m.apiResponseBytes, func(key [2]string) []string {
+		return []string{"method", key[0], "endpoint", key[1]}
+	})
+}
+
+func writeHistograms[K comparable](w io.Writer, name, help string, histograms map[K]*histogram, keyLabels func(K) []string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+
+	type series struct {
+		labels []string
+		h      *histogram
+	}
+	all := make([]series, 0, len(histograms))
+	for key, h := range histograms {
+		all = append(all, series{labels: keyLabels(key), h: h})
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return strings.Join(all[i].labels, "\x00") < strings.Join(all[j].labels, "\x00")
+	})
+
+	for _, s := range all {
+		for i, bound := range s.h.buckets {
+			fmt.Fprintf(w, "%s_bucket{%s} %d\n", name, labels(append(s.labels, "le", strconv.FormatFloat(bound, 'g', -1, 64))...), s.h.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{%s} %d\n", name, labels(append(s.labels, "le", "+Inf")...), s.h.count)
+		fmt.Fprintf(w, "%s_sum{%s} %s\n", name, labels(s.labels...), strconv.FormatFloat(s.h.sum, 'g', -1, 64))
+		fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels(s.labels...), s.h.count)
+	}
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labels formats label name and value pairs.
+func labels(pairs ...string) string {
+	var b strings.Builder
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"", pairs[i], labelValueReplacer.Replace(pairs[i+1]))
+	}
+	return b.String()
+}
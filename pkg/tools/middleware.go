@@ -0,0 +1,22 @@
+package tools
+
+import (
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ToolMiddleware wraps a tool handler to add cross-cutting behaviour such as logging,
+// auditing, rate limiting or truncation without touching the handlers themselves.
+type ToolMiddleware func(next server.ToolHandlerFunc) server.ToolHandlerFunc
+
+// Chain composes middlewares into one. The first middleware is the outermost, so it sees the
+// request first and the result last.
+func Chain(middlewares ...ToolMiddleware) ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			if middlewares[i] != nil {
+				next = middlewares[i](next)
+			}
+		}
+		return next
+	}
+}
@@ -0,0 +1,295 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// graphTimeseriesRecord is a grouped series of a timeseries graph response.
+type graphTimeseriesRecord struct {
+	Values     []string `json:"values"`
+	Timeseries []struct {
+		Timestamp int64   `json:"timestamp"`
+		Value     float64 `json:"value"`
+	} `json:"timeseries"`
+}
+
+type MonitorSimulationResponse struct {
+	Query      string                    `json:"query_used"`
+	Threshold  float64                   `json:"threshold"`
+	Comparison string                    `json:"comparison"`
+	Window     string                    `json:"window"`
+	Lookback   string                    `json:"lookback"`
+	TotalFires int                       `json:"total_fires"`
+	Series     []MonitorSeriesSimulation `json:"series"`
+	Warnings   []GraphWarning            `json:"warnings,omitempty"`
+	Guidance   *GraphGuidance            `json:"guidance,omitempty"`
+}
+
+// MonitorSeriesSimulation describes how a monitor would have behaved for one group.
+// A fire is a transition into the breaching state; consecutive breaching windows count once.
+type MonitorSeriesSimulation struct {
+	Group            []string `json:"group,omitempty"`
+	Fires            int      `json:"fires"`
+	BreachingWindows int      `json:"breaching_windows"`
+	TotalWindows     int      `json:"total_windows"`
+	FireTimes        []string `json:"fire_times,omitempty"`
+	Min              float64  `json:"min"`
+	Max              float64  `json:"max"`
+	P50              float64  `json:"p50"`
+	P95              float64  `json:"p95"`
+	P99              float64  `json:"p99"`
+}
+
+const maxFireTimes = 20
+
+// GetSimulateMonitorTool creates a tool to evaluate a hypothetical monitor against historical data
+func GetSimulateMonitorTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("simulate_monitor",
+			mcp.WithTitleAnnotation("Simulate Monitor"),
+			mcp.WithDescription(`Evaluate how many times a hypothetical threshold monitor would have fired over past data.
+
+WHEN TO USE:
+- Before recommending a monitor threshold, to back it with evidence
+- Comparing candidate thresholds to find one that is not too noisy
+
+The query is graphed in buckets of the given window and every bucket is compared to the threshold.
+A fire is counted when a series enters the breaching state; consecutive breaching windows count once.
+Percentiles (p50/p95/p99) of the bucket values are returned to help pick a threshold.
+
+Query examples:
+- log: {severity_text:"ERROR" AND service.name:"api"} by {service.name}
+- metric: avg:system.cpu.utilization{ed.tag:"prod"} by {host.name}
+- trace: {status.code:"ERROR"}`),
+			mcp.WithString("scope",
+				mcp.Description(`Scope of the monitor query: "log", "metric", "trace", "pattern" or "event".`),
+				mcp.DefaultString("log"),
+				mcp.Enum("log", "metric", "trace", "pattern", "event"),
+			),
+			mcp.WithString("query",
+				mcp.Description("Graph query the monitor evaluates, in the same syntax as the graph tools."),
+				mcp.Required(),
+			),
+			mcp.WithNumber("threshold",
+				mcp.Description("Threshold the bucket values are compared to."),
+				mcp.Required(),
+			),
+			mcp.WithString("comparison",
+				mcp.Description(`"above" fires when a value is greater than the threshold, "below" when it is lower.`),
+				mcp.DefaultString("above"),
+				mcp.Enum("above", "below"),
+			),
+			mcp.WithString("window",
+				mcp.Description("Evaluation window (bucket size) in GOLANG duration format, e.g. 1m, 5m, 1h."),
+				mcp.DefaultString("5m"),
+			),
+			mcp.WithString("lookback",
+				mcp.Description("How far back to simulate in GOLANG duration format, or days and weeks, e.g. 24h, 7d or 1w."),
+				mcp.DefaultString("168h"),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			query, _ := params.Optional[string](request, "query")
+			if query == "" {
+				return nil, fmt.Errorf(`"query" is required`)
+			}
+
+			if _, ok := request.GetArguments()["threshold"]; !ok {
+				return nil, fmt.Errorf(`"threshold" is required`)
+			}
+
+			threshold, err := params.Optional[float64](request, "threshold")
+			if err != nil {
+				return nil, fmt.Errorf(`"threshold" must be a number`)
+			}
+
+			scope, _ := params.Optional[string](request, "scope")
+			if scope == "" {
+				scope = "log"
+			}
+
+			comparison, _ := params.Optional[string](request, "comparison")
+			if comparison == "" {
+				comparison = "above"
+			}
+
+			if comparison != "above" && comparison != "below" {
+				return nil, fmt.Errorf(`"comparison" must be "above" or "below"`)
+			}
+
+			windowStr, _ := params.Optional[string](request, "window")
+			if windowStr == "" {
+				windowStr = "5m"
+			}
+
+			window, err := ParseLookback(windowStr)
+			if err != nil || window < time.Minute {
+				return nil, fmt.Errorf(`"window" must be a duration of at least 1m, got %q`, windowStr)
+			}
+
+			lookback, _ := params.Optional[string](request, "lookback")
+			if lookback == "" {
+				lookback = "168h"
+			}
+
+			if scope == "metric" && !strings.Contains(query, ".rollup(") {
+				query = fmt.Sprintf("%s.rollup(%d)", query, int(window.Seconds()))
+			}
+
+			payload, err := NewGraphQueryBuilder().
+				WithQuery("Q1", GraphQuery{Scope: scope, Query: query}).
+				WithFormula("R1", "Q1").
+				Build()
+			if err != nil {
+				return nil, err
+			}
+
+			queryParams := url.Values{}
+			queryParams.Add("lookback", lookback)
+			queryParams.Add("window", windowStr)
+
+			statusCode, bodyBytes, warnings, err := postGraphWithRetry(ctx, client, payload, queryParams)
+			if err != nil {
+				return nil, err
+			}
+
+			if statusCode != http.StatusMultiStatus {
+				return nil, fmt.Errorf("failed to simulate monitor, status code %d: %s", statusCode, string(bodyBytes))
+			}
+
+			var graphResp map[string]struct {
+				Records []graphTimeseriesRecord `json:"records"`
+			}
+			if err := json.Unmarshal(bodyBytes, &graphResp); err != nil {
+				return nil, fmt.Errorf("failed to decode graph response: %w", err)
+			}
+
+			response := MonitorSimulationResponse{
+				Query:      query,
+				Threshold:  threshold,
+				Comparison: comparison,
+				Window:     windowStr,
+				Lookback:   lookback,
+				Warnings:   warnings,
+			}
+
+			for _, record := range graphResp["R1"].Records {
+				sim := simulateSeries(record, threshold, comparison == "above")
+				response.TotalFires += sim.Fires
+				response.Series = append(response.Series, sim)
+			}
+
+			sort.Slice(response.Series, func(i, j int) bool {
+				return response.Series[i].Fires > response.Series[j].Fires
+			})
+
+			response.Guidance = monitorSimulationGuidance(response)
+
+			jsonResponse, err := json.Marshal(response)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+
+			return mcp.NewToolResultText(string(jsonResponse)), nil
+		}
+}
+
+func simulateSeries(record graphTimeseriesRecord, threshold float64, above bool) MonitorSeriesSimulation {
+	sim := MonitorSeriesSimulation{
+		Group:        record.Values,
+		TotalWindows: len(record.Timeseries),
+	}
+
+	values := make([]float64, 0, len(record.Timeseries))
+	breaching := false
+	for _, point := range record.Timeseries {
+		values = append(values, point.Value)
+
+		breach := point.Value > threshold
+		if !above {
+			breach = point.Value < threshold
+		}
+
+		if breach {
+			sim.BreachingWindows++
+			if !breaching {
+				sim.Fires++
+				if len(sim.FireTimes) < maxFireTimes {
+					sim.FireTimes = append(sim.FireTimes, time.UnixMilli(point.Timestamp).UTC().Format(time.RFC3339))
+				}
+			}
+		}
+		breaching = breach
+	}
+
+	if len(values) == 0 {
+		return sim
+	}
+
+	sort.Float64s(values)
+	sim.Min = values[0]
+	sim.Max = values[len(values)-1]
+	sim.P50 = percentile(values, 50)
+	sim.P95 = percentile(values, 95)
+	sim.P99 = percentile(values, 99)
+	return sim
+}
+
+// percentile returns the nearest-rank percentile of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+func monitorSimulationGuidance(response MonitorSimulationResponse) *GraphGuidance {
+	if len(response.Series) == 0 {
+		return &GraphGuidance{
+			ResultStatus: "empty",
+			NextSteps: []string{
+				fmt.Sprintf("No data found for query: %s", response.Query),
+				"A monitor on this query would not have had anything to evaluate over the lookback.",
+			},
+			Suggestions: []string{
+				"Verify field values with facet_options tool to ensure the values exist in your data",
+				"Use validate_cql tool to check your query syntax",
+			},
+		}
+	}
+
+	guidance := &GraphGuidance{
+		ResultStatus: "success",
+		NextSteps: []string{
+			fmt.Sprintf("The monitor would have fired %d times over %s.", response.TotalFires, response.Lookback),
+		},
+	}
+
+	if response.TotalFires == 0 {
+		guidance.Suggestions = append(guidance.Suggestions, "The threshold was never crossed; compare it to the p95/p99 values to check it is not too lax.")
+	} else {
+		guidance.Suggestions = append(guidance.Suggestions, "If this is too noisy, try a threshold near the p99 value or a longer window and simulate again.")
+	}
+
+	if len(response.Warnings) > 0 {
+		guidance.ResultStatus = "partial"
+	}
+
+	return guidance
+}
@@ -0,0 +1,151 @@
+package tools
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// Monitor types of the alert events monitors send, in ed.monitor.type.
+const (
+	MonitorTypeMetricThreshold = "metric_threshold"
+	MonitorTypeLogThreshold    = "log_threshold"
+	MonitorTypePatternAnomaly  = "pattern_anomaly"
+)
+
+// MetricThresholdAlert is the body of the events of metric threshold monitors.
+type MetricThresholdAlert struct {
+	MonitorName string  `json:"monitor_name,omitempty"`
+	Metric      string  `json:"metric"`
+	Value       float64 `json:"value"`
+	Threshold   float64 `json:"threshold"`
+}
+
+// LogThresholdAlert is the body of the events of log threshold monitors.
+type LogThresholdAlert struct {
+	MonitorName string  `json:"monitor_name,omitempty"`
+	Query       string  `json:"query"`
+	Count       float64 `json:"count"`
+	Threshold   float64 `json:"threshold"`
+}
+
+// PatternAnomalyAlert is the body of the events of pattern anomaly monitors.
+type PatternAnomalyAlert struct {
+	MonitorName string  `json:"monitor_name,omitempty"`
+	Pattern     string  `json:"pattern"`
+	Score       float64 `json:"score"`
+}
+
+// ParsedMonitorEvent is the parsed_attributes block added to monitor alert events: the monitor type
+// and the decoded body of the event, under the key of its type.
+type ParsedMonitorEvent struct {
+	MonitorType     string                `json:"monitor_type"`
+	MetricThreshold *MetricThresholdAlert `json:"metric_threshold,omitempty"`
+	LogThreshold    *LogThresholdAlert    `json:"log_threshold,omitempty"`
+	PatternAnomaly  *PatternAnomalyAlert  `json:"pattern_anomaly,omitempty"`
+	// ExceedsBy is how far over its threshold the value or count is, as a ratio, e.g. 0.2 for 20%
+	ExceedsBy *float64 `json:"exceeds_by,omitempty"`
+}
+
+// parseMonitorEvent decodes the body of a monitor alert event. It returns false if the event is
+// not a monitor alert or its body does not decode.
+func parseMonitorEvent(monitorType string, body []byte) (*ParsedMonitorEvent, bool) {
+	parsed := &ParsedMonitorEvent{MonitorType: monitorType}
+	var target any
+	switch monitorType {
+	case MonitorTypeMetricThreshold:
+		parsed.MetricThreshold = &MetricThresholdAlert{}
+		target = parsed.MetricThreshold
+	case MonitorTypeLogThreshold:
+		parsed.LogThreshold = &LogThresholdAlert{}
+		target = parsed.LogThreshold
+	case MonitorTypePatternAnomaly:
+		parsed.PatternAnomaly = &PatternAnomalyAlert{}
+		target = parsed.PatternAnomaly
+	default:
+		return nil, false
+	}
+	if err := json.Unmarshal(body, target); err != nil {
+		return nil, false
+	}
+
+	switch {
+	case parsed.MetricThreshold != nil && parsed.MetricThreshold.Threshold != 0:
+		parsed.ExceedsBy = exceedsBy(parsed.MetricThreshold.Value, parsed.MetricThreshold.Threshold)
+	case parsed.LogThreshold != nil && parsed.LogThreshold.Threshold != 0:
+		parsed.ExceedsBy = exceedsBy(parsed.LogThreshold.Count, parsed.LogThreshold.Threshold)
+	}
+	return parsed, true
+}
+
+func exceedsBy(value, threshold float64) *float64 {
+	ratio := (value - threshold) / threshold
+	// Two decimals are enough to compare alerts and keep the response small
+	ratio, _ = strconv.ParseFloat(strconv.FormatFloat(ratio, 'f', 2, 64), 64)
+	return &ratio
+}
+
+// withParsedMonitorEvents adds a parsed_attributes block to the monitor alert events of an event
+// search response, decoded from their body by monitor type. Bodies are kept as is, and the
+// response is returned unchanged if it cannot be decoded.
+func withParsedMonitorEvents(bodyBytes []byte) []byte {
+	var response map[string]json.RawMessage
+	if err := json.Unmarshal(bodyBytes, &response); err != nil {
+		return bodyBytes
+	}
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal(response["items"], &items); err != nil {
+		return bodyBytes
+	}
+
+	changed := false
+	for _, item := range items {
+		monitorType := eventMonitorType(item)
+		if monitorType == "" {
+			continue
+		}
+
+		// Bodies are JSON objects, or strings holding one
+		body := []byte(item["body"])
+		var text string
+		if json.Unmarshal(body, &text) == nil {
+			body = []byte(text)
+		}
+
+		parsed, ok := parseMonitorEvent(monitorType, body)
+		if !ok {
+			continue
+		}
+		if item["parsed_attributes"], ok = marshalRaw(parsed); ok {
+			changed = true
+		}
+	}
+	if !changed {
+		return bodyBytes
+	}
+
+	var ok bool
+	if response["items"], ok = marshalRaw(items); !ok {
+		return bodyBytes
+	}
+	result, err := json.Marshal(response)
+	if err != nil {
+		return bodyBytes
+	}
+	return result
+}
+
+// eventMonitorType returns ed.monitor.type of the event, falling back to event.type.
+func eventMonitorType(item map[string]json.RawMessage) string {
+	for _, key := range []string{"ed.monitor.type", "event.type"} {
+		var value string
+		if json.Unmarshal(item[key], &value) == nil && value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func marshalRaw(v any) (json.RawMessage, bool) {
+	data, err := json.Marshal(v)
+	return data, err == nil
+}
@@ -0,0 +1,199 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// minPatternFragment is the minimum number of letters and digits of a pattern fragment
+	// matched by the monitor query, shorter ones match too many logs to be worth it.
+	minPatternFragment = 3
+	maxMonitorNameLen  = 80
+)
+
+var monitorOperators = []string{">", ">="}
+
+// CreateMonitorFromPatternTool creates a tool to create a log threshold monitor from a log pattern
+func CreateMonitorFromPatternTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("create_monitor_from_pattern",
+			mcp.WithTitleAnnotation("Create Monitor From Pattern"),
+			mcp.WithDescription(`Create a log threshold monitor that fires when the logs of a pattern exceed a threshold, e.g. for a concerning pattern found with get_log_patterns during an investigation.
+
+The monitor query matches the literal parts of the pattern between its * wildcards, e.g. "connection refused to payments-db:5432 after * retries" becomes "connection refused to payments-db:5432 after" AND "retries", combined with the service of the pattern.
+
+Use dry_run first to check the generated monitor, and simulate_monitor with its query to pick a threshold that does not fire constantly.`),
+			mcp.WithString("pattern",
+				mcp.Description("Pattern (cluster) as returned by get_log_patterns, with * for its variable parts."),
+				mcp.Required(),
+			),
+			mcp.WithString("service",
+				mcp.Description("service.name of the pattern, as returned by get_log_patterns. Leave empty to match the pattern in all services."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithNumber("threshold",
+				mcp.Description("Number of logs of the pattern per window above which the monitor fires."),
+				mcp.Required(),
+			),
+			mcp.WithString("operator",
+				mcp.Description("Comparison of the log count to the threshold."),
+				mcp.Enum(monitorOperators...),
+				mcp.DefaultString(">"),
+			),
+			mcp.WithString("window",
+				mcp.Description("Evaluation window in Go duration format, e.g. 5m, 15m, 1h."),
+				mcp.DefaultString("5m"),
+			),
+			mcp.WithString("name",
+				mcp.Description("Name of the monitor. Defaults to the pattern."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithArray("notifications",
+				mcp.Description(`Notifications of the monitor, e.g. [{"type": "slack", "channel": "#oncall"}]. Use list_notification_integrations to see the integrations.`),
+			),
+			mcp.WithBoolean("dry_run",
+				mcp.Description("If true, return the monitor that would be created without creating it"),
+			),
+			mcp.WithReadOnlyHintAnnotation(false),
+			mcp.WithIdempotentHintAnnotation(false),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			pattern, err := request.RequireString("pattern")
+			if err != nil || strings.TrimSpace(pattern) == "" {
+				return mcp.NewToolResultError("missing required parameter: pattern"), nil
+			}
+			service, _ := params.Optional[string](request, "service")
+			service = strings.TrimSpace(service)
+			query, err := patternQuery(pattern, service)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+
+			if _, ok := request.GetArguments()["threshold"]; !ok {
+				return mcp.NewToolResultError("missing required parameter: threshold"), nil
+			}
+			threshold, err := params.Optional[float64](request, "threshold")
+			if err != nil || threshold < 0 {
+				return mcp.NewToolResultError("threshold must be a number of logs of at least 0"), nil
+			}
+			operator, _ := params.Optional[string](request, "operator")
+			if operator == "" {
+				operator = ">"
+			}
+			if !slices.Contains(monitorOperators, operator) {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid operator %q, expected one of %s", operator, strings.Join(monitorOperators, ", "))), nil
+			}
+			window, _ := params.Optional[string](request, "window")
+			if window == "" {
+				window = "5m"
+			}
+			if d, err := ParseLookback(window); err != nil || d < time.Minute {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid window %q, expected a duration of at least 1m such as 5m or 1h", window)), nil
+			}
+
+			name, _ := params.Optional[string](request, "name")
+			if name = strings.TrimSpace(name); name == "" {
+				name = patternMonitorName(pattern, service)
+			}
+			monitor := map[string]any{
+				"name":        name,
+				"type":        "log_threshold",
+				"query":       query,
+				"threshold":   map[string]any{"operator": operator, "value": threshold},
+				"window":      window,
+				"description": "Created from the log pattern: " + pattern,
+			}
+			if notifications, ok := request.GetArguments()["notifications"].([]any); ok && len(notifications) > 0 {
+				monitor["notifications"] = notifications
+			}
+
+			simulate := fmt.Sprintf(`Use simulate_monitor with query %q, threshold %g and window %q to see how often the monitor would have fired.`,
+				fmt.Sprintf("{%s}", query), threshold, window)
+			if dryRun, _ := params.Optional[bool](request, "dry_run"); dryRun {
+				definition, err := json.Marshal(monitor)
+				if err != nil {
+					return nil, fmt.Errorf("failed to marshal monitor: %w", err)
+				}
+				return monitorResult(definition, &MonitorGuidance{
+					ResultStatus: "dry_run",
+					NextSteps: []string{
+						"Check the monitor with the user, then call create_monitor_from_pattern again without dry_run to create it.",
+						simulate,
+					},
+					Suggestions: []string{
+						"Use get_log_search with the query to check it matches the logs of the pattern and no others.",
+					},
+				})
+			}
+
+			bodyBytes, err := doMonitorRequest(ctx, client, http.MethodPost, "", monitor, "create monitor")
+			if err != nil {
+				return nil, err
+			}
+
+			return monitorResult(bodyBytes, &MonitorGuidance{
+				ResultStatus: "success",
+				NextSteps: []string{
+					"Monitor created. Use get_monitor tool with the returned monitor_id to verify its definition.",
+					simulate,
+				},
+			})
+		}
+}
+
+// patternQuery returns the CQL query of the logs of a pattern: its literal fragments between the
+// * wildcards as quoted full-text terms, and the service if set.
+func patternQuery(pattern, service string) (string, error) {
+	var terms []string
+	if service != "" {
+		terms = append(terms, fmt.Sprintf(`service.name:"%s"`, escapeValue(service)))
+	}
+
+	fragments := 0
+	for _, fragment := range strings.Split(pattern, "*") {
+		// Brackets around a wildcard are left out, e.g. "retries (*ms)" matches "retries"
+		fragment = strings.Trim(fragment, " \t()[]{}<>\"'")
+		if significantChars(fragment) < minPatternFragment {
+			continue
+		}
+		terms = append(terms, fmt.Sprintf(`"%s"`, escapeValue(fragment)))
+		fragments++
+	}
+	if fragments == 0 {
+		return "", fmt.Errorf("pattern %q has no literal part of at least %d letters or digits to match its logs, use create_monitor with a query instead", pattern, minPatternFragment)
+	}
+	return strings.Join(terms, " AND "), nil
+}
+
+func significantChars(s string) int {
+	n := 0
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			n++
+		}
+	}
+	return n
+}
+
+func patternMonitorName(pattern, service string) string {
+	name := "Log pattern: " + strings.Join(strings.Fields(pattern), " ")
+	if service != "" {
+		name = service + " " + name
+	}
+	if runes := []rune(name); len(runes) > maxMonitorNameLen {
+		name = string(runes[:maxMonitorNameLen-1]) + "…"
+	}
+	return name
+}
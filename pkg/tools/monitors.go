@@ -0,0 +1,370 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type MonitorToolResponse struct {
+	Data     json.RawMessage  `json:"data"`
+	Guidance *MonitorGuidance `json:"guidance,omitempty"`
+}
+
+type MonitorGuidance struct {
+	ResultStatus string   `json:"result_status"`
+	NextSteps    []string `json:"next_steps,omitempty"`
+	Suggestions  []string `json:"suggestions,omitempty"`
+}
+
+// MonitorMuteRequest is the payload of POST /v1/orgs/{org_id}/monitors/{monitor_id}/mute
+type MonitorMuteRequest struct {
+	Until  string `json:"until,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+const monitorPayloadExample = `Example monitor:
+{
+  "name": "Checkout error rate",
+  "type": "log_threshold",
+  "query": "service.name:\"checkout\" AND severity_text:\"ERROR\"",
+  "threshold": {"operator": ">", "value": 50},
+  "window": "5m",
+  "notifications": [{"type": "slack", "channel": "#oncall"}]
+}`
+
+// doMonitorRequest sends a request to the monitors endpoint of the org, path being relative to
+// it, and returns the response body.
+func doMonitorRequest(ctx context.Context, client Client, method, path string, payload any, action string) ([]byte, error) {
+	keys, err := FetchContextKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var body io.Reader
+	if payload != nil {
+		payloadBytes, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal payload: %v", err)
+		}
+		body = bytes.NewReader(payloadBytes)
+	}
+
+	monitorsURL := fmt.Sprintf("%s/v1/orgs/%s/monitors%s", client.APIURL(), keys.OrgID, path)
+	req, err := http.NewRequestWithContext(ctx, method, monitorsURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Add("Content-Type", "application/json")
+	applyAuthHeader(req, keys)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("failed to %s, status code %d: %s", action, resp.StatusCode, string(bodyBytes))
+	}
+
+	return bodyBytes, nil
+}
+
+func monitorResult(bodyBytes []byte, guidance *MonitorGuidance) (*mcp.CallToolResult, error) {
+	if len(bytes.TrimSpace(bodyBytes)) == 0 {
+		bodyBytes = []byte("null")
+	}
+
+	r, err := json.Marshal(MonitorToolResponse{Data: bodyBytes, Guidance: guidance})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal wrapped response, err: %w", err)
+	}
+
+	return mcp.NewToolResultText(string(r)), nil
+}
+
+// monitorPayload returns the monitor object argument of a create or update call.
+func monitorPayload(request mcp.CallToolRequest) (map[string]any, *mcp.CallToolResult) {
+	monitor, ok := request.GetArguments()["monitor"].(map[string]any)
+	if !ok {
+		return nil, mcp.NewToolResultError("missing required parameter: monitor, it must be a JSON object")
+	}
+	if name, _ := monitor["name"].(string); name == "" {
+		return nil, mcp.NewToolResultError("monitor must have a name")
+	}
+	return monitor, nil
+}
+
+// ListMonitorsTool creates a tool to list the monitors of the organization
+func ListMonitorsTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("list_monitors",
+			mcp.WithTitleAnnotation("List Monitors"),
+			mcp.WithDescription(`List the monitors (alerts) of the organization with their monitor_id, name, type, state and whether they are muted.
+
+WORKFLOW: This is the entry point for monitor operations.
+1. list_monitors → find the monitor_id
+2. get_monitor(monitor_id) → full definition including query and thresholds
+3. update_monitor / mute_monitor / unmute_monitor to adjust it
+
+Use simulate_monitor to check how often a new threshold would have fired before changing it.`),
+			mcp.WithString("keyword",
+				mcp.Description("Only return monitors whose name contains the keyword"),
+				mcp.DefaultString(""),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			path := ""
+			if keyword, _ := params.Optional[string](request, "keyword"); keyword != "" {
+				path = "?" + url.Values{"keyword": {keyword}}.Encode()
+			}
+
+			bodyBytes, err := doMonitorRequest(ctx, client, http.MethodGet, path, nil, "list monitors")
+			if err != nil {
+				return nil, err
+			}
+
+			return monitorResult(bodyBytes, &MonitorGuidance{
+				ResultStatus: "success",
+				NextSteps: []string{
+					"Use get_monitor tool with monitor_id to see the full definition of a monitor.",
+				},
+			})
+		}
+}
+
+// GetMonitorTool creates a tool to get the definition of a monitor
+func GetMonitorTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("get_monitor",
+			mcp.WithTitleAnnotation("Get Monitor"),
+			mcp.WithDescription(`Get the full definition of a monitor: query, thresholds, evaluation window, notifications and mute state.
+
+PREREQUISITE: Call list_monitors tool first to obtain the monitor_id.
+
+To change the monitor, edit this definition and pass it whole to update_monitor.`),
+			mcp.WithString("monitor_id",
+				mcp.Description("Monitor ID. Get this from list_monitors response."),
+				mcp.Required(),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			monitorID, err := request.RequireString("monitor_id")
+			if err != nil {
+				return mcp.NewToolResultError("missing required parameter: monitor_id"), err
+			}
+
+			bodyBytes, err := doMonitorRequest(ctx, client, http.MethodGet, "/"+url.PathEscape(monitorID), nil, "get monitor")
+			if err != nil {
+				return nil, err
+			}
+
+			return monitorResult(bodyBytes, &MonitorGuidance{
+				ResultStatus: "success",
+				NextSteps: []string{
+					"Use simulate_monitor tool with the monitor query to see how a different threshold would have behaved.",
+					"Use update_monitor tool with the edited definition to change it, or mute_monitor tool to silence it temporarily.",
+				},
+			})
+		}
+}
+
+// CreateMonitorTool creates a tool to create a monitor
+func CreateMonitorTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("create_monitor",
+			mcp.WithTitleAnnotation("Create Monitor"),
+			mcp.WithDescription(`Create a monitor from a JSON definition.
+
+Use get_monitor on a similar monitor to see the fields of its type, and simulate_monitor to pick a threshold that does not fire constantly.
+
+`+monitorPayloadExample),
+			mcp.WithObject("monitor",
+				mcp.Description("Monitor definition. Must include 'name'."),
+				mcp.Required(),
+			),
+			mcp.WithReadOnlyHintAnnotation(false),
+			mcp.WithIdempotentHintAnnotation(false),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			monitor, errResult := monitorPayload(request)
+			if errResult != nil {
+				return errResult, nil
+			}
+
+			bodyBytes, err := doMonitorRequest(ctx, client, http.MethodPost, "", monitor, "create monitor")
+			if err != nil {
+				return nil, err
+			}
+
+			return monitorResult(bodyBytes, &MonitorGuidance{
+				ResultStatus: "success",
+				NextSteps: []string{
+					"Monitor created. Use get_monitor tool with the returned monitor_id to verify its definition.",
+				},
+			})
+		}
+}
+
+// UpdateMonitorTool creates a tool to update a monitor
+func UpdateMonitorTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("update_monitor",
+			mcp.WithTitleAnnotation("Update Monitor"),
+			mcp.WithDescription(`Replace the definition of a monitor, e.g. to adjust its threshold or window.
+
+PREREQUISITES:
+1. get_monitor(monitor_id) → current definition
+2. update_monitor(monitor_id, monitor) → pass the whole edited definition, fields left out are removed
+
+`+monitorPayloadExample),
+			mcp.WithString("monitor_id",
+				mcp.Description("Monitor ID"),
+				mcp.Required(),
+			),
+			mcp.WithObject("monitor",
+				mcp.Description("Full monitor definition. Must include 'name'."),
+				mcp.Required(),
+			),
+			mcp.WithReadOnlyHintAnnotation(false),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(true),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			monitorID, err := request.RequireString("monitor_id")
+			if err != nil {
+				return mcp.NewToolResultError("missing required parameter: monitor_id"), err
+			}
+
+			monitor, errResult := monitorPayload(request)
+			if errResult != nil {
+				return errResult, nil
+			}
+			if id, ok := monitor["monitor_id"].(string); ok && id != monitorID {
+				return mcp.NewToolResultError(fmt.Sprintf("monitor_id %q of the definition does not match monitor_id %q", id, monitorID)), nil
+			}
+
+			bodyBytes, err := doMonitorRequest(ctx, client, http.MethodPut, "/"+url.PathEscape(monitorID), monitor, "update monitor")
+			if err != nil {
+				return nil, err
+			}
+
+			return monitorResult(bodyBytes, &MonitorGuidance{
+				ResultStatus: "success",
+				NextSteps: []string{
+					"Monitor updated. The new definition applies from its next evaluation.",
+				},
+			})
+		}
+}
+
+// MuteMonitorTool creates a tool to mute a monitor
+func MuteMonitorTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("mute_monitor",
+			mcp.WithTitleAnnotation("Mute Monitor"),
+			mcp.WithDescription(`Mute a monitor so it stops notifying, e.g. while an incident it alerts on is being worked on. The monitor keeps evaluating.
+
+Prefer a duration so the monitor unmutes itself; use unmute_monitor to unmute it earlier.`),
+			mcp.WithString("monitor_id",
+				mcp.Description("Monitor ID"),
+				mcp.Required(),
+			),
+			mcp.WithString("duration",
+				mcp.Description("How long to mute the monitor, in Go duration format or days (e.g., 30m, 4h, 1d). Leave empty to mute until unmuted."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("reason",
+				mcp.Description("Why the monitor is muted, shown to other users"),
+				mcp.DefaultString(""),
+			),
+			mcp.WithReadOnlyHintAnnotation(false),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			monitorID, err := request.RequireString("monitor_id")
+			if err != nil {
+				return mcp.NewToolResultError("missing required parameter: monitor_id"), err
+			}
+
+			var payload MonitorMuteRequest
+			payload.Reason, _ = params.Optional[string](request, "reason")
+			if duration, _ := params.Optional[string](request, "duration"); duration != "" {
+				d, err := ParseLookback(duration)
+				if err != nil || d <= 0 {
+					return mcp.NewToolResultError(fmt.Sprintf("invalid duration %q, expected a duration such as 30m, 4h or 1d", duration)), nil
+				}
+				payload.Until = time.Now().UTC().Add(d).Format(isoTimeLayout)
+			}
+
+			bodyBytes, err := doMonitorRequest(ctx, client, http.MethodPost, "/"+url.PathEscape(monitorID)+"/mute", payload, "mute monitor")
+			if err != nil {
+				return nil, err
+			}
+
+			nextStep := "Monitor muted until unmuted with unmute_monitor tool."
+			if payload.Until != "" {
+				nextStep = fmt.Sprintf("Monitor muted until %s.", payload.Until)
+			}
+			return monitorResult(bodyBytes, &MonitorGuidance{
+				ResultStatus: "success",
+				NextSteps:    []string{nextStep},
+			})
+		}
+}
+
+// UnmuteMonitorTool creates a tool to unmute a monitor
+func UnmuteMonitorTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("unmute_monitor",
+			mcp.WithTitleAnnotation("Unmute Monitor"),
+			mcp.WithDescription(`Unmute a muted monitor so it notifies again.`),
+			mcp.WithString("monitor_id",
+				mcp.Description("Monitor ID"),
+				mcp.Required(),
+			),
+			mcp.WithReadOnlyHintAnnotation(false),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			monitorID, err := request.RequireString("monitor_id")
+			if err != nil {
+				return mcp.NewToolResultError("missing required parameter: monitor_id"), err
+			}
+
+			bodyBytes, err := doMonitorRequest(ctx, client, http.MethodPost, "/"+url.PathEscape(monitorID)+"/unmute", nil, "unmute monitor")
+			if err != nil {
+				return nil, err
+			}
+
+			return monitorResult(bodyBytes, &MonitorGuidance{
+				ResultStatus: "success",
+				NextSteps:    []string{"Monitor unmuted, it notifies again from its next evaluation."},
+			})
+		}
+}
@@ -0,0 +1,289 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// noDataProbeWindow is how far before and after an empty search window data is looked for.
+	noDataProbeWindow = 24 * time.Hour
+	// maxNoDataFacetChecks bounds the facet lookups of an empty search.
+	maxNoDataFacetChecks = 3
+	maxValueCandidates   = 5
+	// noDataProbeTimeout bounds the probes of an empty search, which run concurrently.
+	noDataProbeTimeout = 3 * time.Second
+)
+
+var queryFilterPattern = regexp.MustCompile(`(-|NOT\s+)?(@?[a-zA-Z_][a-zA-Z0-9_.-]*)\s*:\s*"([^"]*)"`)
+
+// NoDataResult explains an empty search result so the agent can decide what to try next
+// instead of giving up or guessing.
+type NoDataResult struct {
+	TimeRange        SearchTimeRange   `json:"time_range"`
+	ParsedQuery      ParsedQuery       `json:"parsed_query"`
+	NearestData      []NearestData     `json:"nearest_data,omitempty"`
+	FacetSuggestions []FacetSuggestion `json:"facet_suggestions,omitempty"`
+	// TimedOut is set when some probes did not complete in time, so their findings are missing
+	TimedOut bool `json:"timed_out,omitempty"`
+}
+
+type SearchTimeRange struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Lookback string `json:"lookback,omitempty"`
+}
+
+type ParsedQuery struct {
+	Filters  []QueryFilter `json:"filters,omitempty"`
+	FullText bool          `json:"full_text"`
+	Errors   []string      `json:"errors,omitempty"`
+}
+
+type QueryFilter struct {
+	Field   string `json:"field"`
+	Value   string `json:"value"`
+	Negated bool   `json:"negated,omitempty"`
+}
+
+// NearestData is the closest matching record outside the searched window.
+type NearestData struct {
+	Direction string `json:"direction"`
+	Timestamp string `json:"timestamp"`
+	// SuggestedFrom and SuggestedTo are a time range around the record to search again with.
+	SuggestedFrom string `json:"suggested_from"`
+	SuggestedTo   string `json:"suggested_to"`
+}
+
+// FacetSuggestion reports whether a filtered value exists and, if not, similar values that do.
+type FacetSuggestion struct {
+	Field      string   `json:"field"`
+	Value      string   `json:"value"`
+	Exists     bool     `json:"exists"`
+	Candidates []string `json:"candidates,omitempty"`
+}
+
+// resolveTimeRange returns the absolute time range of search query parameters.
+func resolveTimeRange(queryParams url.Values, now time.Time) (time.Time, time.Time, error) {
+	if lookback := queryParams.Get("lookback"); lookback != "" {
+		d, err := ParseLookback(lookback)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		return now.Add(-d), now, nil
+	}
+
+	from, err := time.Parse(time.RFC3339, queryParams.Get("from"))
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid from: %w", err)
+	}
+
+	to := now
+	if s := queryParams.Get("to"); s != "" {
+		if to, err = time.Parse(time.RFC3339, s); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid to: %w", err)
+		}
+	}
+	return from, to, nil
+}
+
+func parseQuery(query, scope string) ParsedQuery {
+	parsed := ParsedQuery{
+		FullText: hasFullTextSearch(queryFilterPattern.ReplaceAllString(query, "")),
+		Errors:   validateCQL(query, scope).Errors,
+	}
+
+	for _, match := range queryFilterPattern.FindAllStringSubmatch(query, -1) {
+		parsed.Filters = append(parsed.Filters, QueryFilter{
+			Negated: match[1] != "",
+			Field:   match[2],
+			Value:   match[3],
+		})
+	}
+	return parsed
+}
+
+// explainNoLogs probes the 24h before and after an empty log search window for the nearest
+// matching log and checks the filtered values against the facet values. The probes run
+// concurrently and share a deadline of noDataProbeTimeout, so an empty search is not delayed by
+// more than that.
+func explainNoLogs(ctx context.Context, client Client, query string, queryParams url.Values) *NoDataResult {
+	now := time.Now().UTC()
+	from, to, err := resolveTimeRange(queryParams, now)
+	if err != nil {
+		return nil
+	}
+
+	result := &NoDataResult{
+		TimeRange: SearchTimeRange{
+			From:     from.Format(isoTimeLayout),
+			To:       to.Format(isoTimeLayout),
+			Lookback: queryParams.Get("lookback"),
+		},
+		ParsedQuery: parseQuery(query, "log"),
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, noDataProbeTimeout)
+	defer cancel()
+
+	var (
+		wg                      sync.WaitGroup
+		before, after           time.Time
+		foundBefore, foundAfter bool
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		before, foundBefore = probeNearestLog(probeCtx, client, query, from.Add(-noDataProbeWindow), from, "desc")
+	}()
+
+	if to.Before(now) {
+		probeTo := to.Add(noDataProbeWindow)
+		if probeTo.After(now) {
+			probeTo = now
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			after, foundAfter = probeNearestLog(probeCtx, client, query, to, probeTo, "asc")
+		}()
+	}
+
+	var filters []QueryFilter
+	for _, filter := range result.ParsedQuery.Filters {
+		if len(filters) == maxNoDataFacetChecks {
+			break
+		}
+		if filter.Negated || filter.Value == "" || strings.Contains(filter.Value, "*") {
+			continue
+		}
+		filters = append(filters, filter)
+	}
+	suggestions := make([]*FacetSuggestion, len(filters))
+	for i, filter := range filters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			suggestions[i] = checkFacetValue(probeCtx, client, filter)
+		}()
+	}
+
+	wg.Wait()
+	result.TimedOut = probeCtx.Err() == context.DeadlineExceeded
+
+	if foundBefore {
+		result.NearestData = append(result.NearestData, nearestData("before", before, from, to))
+	}
+	if foundAfter {
+		result.NearestData = append(result.NearestData, nearestData("after", after, from, to))
+	}
+	for _, suggestion := range suggestions {
+		if suggestion != nil {
+			result.FacetSuggestions = append(result.FacetSuggestions, *suggestion)
+		}
+	}
+	return result
+}
+
+// checkFacetValue reports whether the filtered value exists and, if not, similar values that do,
+// or nil if the facet values cannot be fetched.
+func checkFacetValue(ctx context.Context, client Client, filter QueryFilter) *FacetSuggestion {
+	facet, err := GetFacetOptions(ctx, client, WithScope("log"), WithFacet(filter.Field), WithLimit("1000"))
+	if err != nil || facet == nil {
+		return nil
+	}
+
+	suggestion := &FacetSuggestion{Field: filter.Field, Value: filter.Value}
+	for _, option := range facet.Options {
+		if option.Name == filter.Value {
+			suggestion.Exists = true
+			return suggestion
+		}
+	}
+
+	for _, match := range matchNames(filter.Value, facet.Options, maxValueCandidates, false) {
+		suggestion.Candidates = append(suggestion.Candidates, match.Name)
+	}
+	return suggestion
+}
+
+// probeNearestLog returns the timestamp of the first log matching the query in [from, to) in the order.
+func probeNearestLog(ctx context.Context, client Client, query string, from, to time.Time, order string) (time.Time, bool) {
+	if !from.Before(to) {
+		return time.Time{}, false
+	}
+
+	queryParams := url.Values{}
+	if query != "" {
+		queryParams.Add("query", query)
+	}
+	queryParams.Add("from", from.Format(isoTimeLayout))
+	queryParams.Add("to", to.Format(isoTimeLayout))
+	queryParams.Add("limit", "1")
+	queryParams.Add("order", order)
+
+	bodyBytes, err := searchLogs(ctx, client, queryParams)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var resp struct {
+		Items []struct {
+			Timestamp string `json:"timestamp"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(bodyBytes, &resp); err != nil || len(resp.Items) == 0 {
+		return time.Time{}, false
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, resp.Items[0].Timestamp)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return timestamp, true
+}
+
+// nearestData suggests a window of the searched window's size, centered on the found record.
+func nearestData(direction string, timestamp, from, to time.Time) NearestData {
+	half := to.Sub(from) / 2
+	return NearestData{
+		Direction:     direction,
+		Timestamp:     timestamp.UTC().Format(isoTimeLayout),
+		SuggestedFrom: timestamp.Add(-half).UTC().Format(isoTimeLayout),
+		SuggestedTo:   timestamp.Add(half).UTC().Format(isoTimeLayout),
+	}
+}
+
+// noDataGuidance turns the findings of an empty search into next steps.
+func noDataGuidance(noData *NoDataResult) []string {
+	var steps []string
+	for _, nearest := range noData.NearestData {
+		steps = append(steps, fmt.Sprintf("Matching data exists %s the searched window at %s; search again with from:%q to:%q.",
+			nearest.Direction, nearest.Timestamp, nearest.SuggestedFrom, nearest.SuggestedTo))
+	}
+
+	for _, suggestion := range noData.FacetSuggestions {
+		if suggestion.Exists {
+			continue
+		}
+		if len(suggestion.Candidates) > 0 {
+			steps = append(steps, fmt.Sprintf("%s:%q does not exist; similar values: %q.", suggestion.Field, suggestion.Value, suggestion.Candidates))
+		} else {
+			steps = append(steps, fmt.Sprintf("%s:%q does not exist and no similar values were found.", suggestion.Field, suggestion.Value))
+		}
+	}
+
+	switch {
+	case noData.TimedOut:
+		steps = append(steps, "Looking for matching data around the searched window timed out; widen the time range to search it yourself.")
+	case len(noData.NearestData) == 0:
+		steps = append(steps, fmt.Sprintf("No matching data in the %.0fh before or after the searched window either.", noDataProbeWindow.Hours()))
+	}
+	return steps
+}
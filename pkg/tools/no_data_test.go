@@ -0,0 +1,79 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// slowProbeAPI answers every search and facet options request after the delay, with no logs and
+// the facet value "checkout".
+func slowProbeAPI(t *testing.T, delay time.Duration) Client {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/orgs/{org_id}/logs/log_search/search", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		_, _ = w.Write([]byte(`{"items":[]}`))
+	})
+	mux.HandleFunc("GET /v1/orgs/{org_id}/facet_options", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		_ = json.NewEncoder(w).Encode(Facet{Options: []FacetOption{{Name: "checkout", Count: 1}}})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return NewHTTPClient(srv.URL, "X-ED-API-Token", WithRateLimits(RateLimits{}))
+}
+
+func TestExplainNoLogsProbesConcurrently(t *testing.T) {
+	const delay = 300 * time.Millisecond
+	client := slowProbeAPI(t, delay)
+	ctx := context.WithValue(context.Background(), OrgIDKey, "org-1")
+	ctx = context.WithValue(ctx, EDTokenKey, "token-1")
+
+	// Two nearest data probes and three facet checks
+	query := `service.name:"checkout" host.name:"web-1" env:"prod" level:"error"`
+	window := url.Values{
+		"from": {time.Now().Add(-48 * time.Hour).UTC().Format(time.RFC3339)},
+		"to":   {time.Now().Add(-47 * time.Hour).UTC().Format(time.RFC3339)},
+	}
+
+	start := time.Now()
+	result := explainNoLogs(ctx, client, query, window)
+	if elapsed := time.Since(start); elapsed > 3*delay {
+		t.Errorf("probes took %s, want them to run concurrently in about %s", elapsed, delay)
+	}
+	if result.TimedOut {
+		t.Error("probes timed out")
+	}
+	if len(result.FacetSuggestions) != maxNoDataFacetChecks {
+		t.Errorf("checked %d facets, want %d", len(result.FacetSuggestions), maxNoDataFacetChecks)
+	}
+	if first := result.FacetSuggestions[0]; first.Field != "service.name" || !first.Exists {
+		t.Errorf("first facet check is %+v, want service.name in the order of the query", first)
+	}
+
+	// Probes outliving the deadline are abandoned
+	ctx, cancel := context.WithTimeout(ctx, delay/3)
+	defer cancel()
+	start = time.Now()
+	result = explainNoLogs(ctx, client, query, window)
+	if elapsed := time.Since(start); elapsed >= delay {
+		t.Errorf("probes took %s, want them canceled at the deadline", elapsed)
+	}
+	if !result.TimedOut {
+		t.Error("probes did not report the timeout")
+	}
+}
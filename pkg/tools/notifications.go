@@ -0,0 +1,154 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type NotificationToolResponse struct {
+	Data     json.RawMessage       `json:"data"`
+	Guidance *NotificationGuidance `json:"guidance,omitempty"`
+}
+
+type NotificationGuidance struct {
+	ResultStatus string   `json:"result_status"`
+	NextSteps    []string `json:"next_steps,omitempty"`
+	Suggestions  []string `json:"suggestions,omitempty"`
+}
+
+// getNotifications gets the path under the notifications API of the org, e.g. "/integrations".
+func getNotifications(ctx context.Context, client Client, path, action string, opts ...QueryParamOption) ([]byte, error) {
+	keys, err := FetchContextKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	notificationsURL, err := url.Parse(fmt.Sprintf("%s/v1/orgs/%s/notifications%s", client.APIURL(), keys.OrgID, path))
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := createRequest(ctx, notificationsURL, keys, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to %s, status code %d: %s", action, resp.StatusCode, string(bodyBytes))
+	}
+
+	return bodyBytes, nil
+}
+
+func notificationResult(bodyBytes []byte, guidance *NotificationGuidance) (*mcp.CallToolResult, error) {
+	r, err := json.Marshal(NotificationToolResponse{Data: bodyBytes, Guidance: guidance})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal wrapped response, err: %w", err)
+	}
+
+	return mcp.NewToolResultText(string(r)), nil
+}
+
+// ListNotificationIntegrationsTool creates a tool to list the notification integrations of the organization
+func ListNotificationIntegrationsTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("list_notification_integrations",
+			mcp.WithTitleAnnotation("List Notification Integrations"),
+			mcp.WithDescription(`List the notification integrations (Slack channels, PagerDuty services, webhooks, email) alerts can be delivered to, with their integration_id, type, target, whether they are enabled and the result of their last delivery.
+
+Use it with list_notification_routes to find out why an alert was not delivered:
+1. list_notification_routes(monitor_id) → which integrations the monitor routes to
+2. list_notification_integrations → whether those integrations are enabled and delivering`),
+			mcp.WithString("type",
+				mcp.Description("Only return integrations of this type"),
+				mcp.Enum("slack", "pagerduty", "webhook", "email"),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			integrationType, _ := params.Optional[string](request, "type")
+
+			bodyBytes, err := getNotifications(ctx, client, "/integrations", "list notification integrations", func(v url.Values) {
+				if integrationType != "" {
+					v.Set("type", integrationType)
+				}
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			return notificationResult(bodyBytes, &NotificationGuidance{
+				ResultStatus: "success",
+				NextSteps: []string{
+					"Use list_notification_routes tool to see which monitors deliver to these integrations.",
+				},
+				Suggestions: []string{
+					"A disabled integration or a failed last delivery explains alerts that fired but were not received.",
+				},
+			})
+		}
+}
+
+// ListNotificationRoutesTool creates a tool to list the routing rules connecting monitors to notification integrations
+func ListNotificationRoutesTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("list_notification_routes",
+			mcp.WithTitleAnnotation("List Notification Routes"),
+			mcp.WithDescription(`List the routing rules that connect monitors to notification integrations: which monitors (by monitor_id or matching tags and severities) notify which integration_ids, and the quiet hours or mute windows applying to them.
+
+Filter by monitor_id (from list_monitors) to trace where the alerts of one monitor are delivered. A monitor matched by no rule notifies nobody.`),
+			mcp.WithString("monitor_id",
+				mcp.Description("Only return the rules applying to this monitor"),
+				mcp.DefaultString(""),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			monitorID, _ := params.Optional[string](request, "monitor_id")
+
+			bodyBytes, err := getNotifications(ctx, client, "/routing_rules", "list notification routes", func(v url.Values) {
+				if monitorID != "" {
+					v.Set("monitor_id", monitorID)
+				}
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			guidance := &NotificationGuidance{
+				ResultStatus: "success",
+				NextSteps: []string{
+					"Use list_notification_integrations tool to check that the integrations of these rules are enabled and delivering.",
+				},
+			}
+			if monitorID != "" {
+				guidance.Suggestions = []string{
+					"Use get_monitor tool to check whether the monitor is muted, which suppresses every route.",
+				}
+			}
+			return notificationResult(bodyBytes, guidance)
+		}
+}
@@ -0,0 +1,530 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/storage"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	maxFleetOrgs = 50
+	// fleetConcurrency bounds the child orgs queried at once by get_fleet_overview.
+	fleetConcurrency = 5
+)
+
+// orgListingTools are the read-only tools listing or comparing the orgs of the caller, which do not
+// run against a single child org.
+var orgListingTools = map[string]bool{
+	"list_orgs":          true,
+	"list_child_orgs":    true,
+	"get_fleet_overview": true,
+}
+
+type Org struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Role is the role of the token in the org, e.g. "admin" or "read_only". Only set by /v1/orgs.
+	Role string `json:"role,omitempty"`
+}
+
+// ChildOrgsResponse mirrors the backend response from GET /v1/orgs/{org_id}/child_orgs
+type ChildOrgsResponse struct {
+	Orgs []Org `json:"orgs"`
+}
+
+func ListChildOrgs(ctx context.Context, client Client) ([]Org, error) {
+	keys, err := FetchContextKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	orgsURL, err := url.Parse(fmt.Sprintf("%s/v1/orgs/%s/child_orgs", client.APIURL(), keys.OrgID))
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := createRequest(ctx, orgsURL, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create child orgs request: %v", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to list child orgs, status code %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var out ChildOrgsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode child orgs response: %v", err)
+	}
+	return out.Orgs, nil
+}
+
+// OrgsResponse mirrors the backend response from GET /v1/orgs
+type OrgsResponse struct {
+	Orgs []Org `json:"orgs"`
+}
+
+// ListOrgs lists the orgs the token in ctx has access to. Unlike other requests it does not need
+// an org ID in ctx.
+func ListOrgs(ctx context.Context, client Client) ([]Org, error) {
+	keys := fetchTokens(ctx)
+
+	orgsURL, err := url.Parse(fmt.Sprintf("%s/v1/orgs", client.APIURL()))
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := createRequest(ctx, orgsURL, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create orgs request: %v", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to list orgs, status code %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var out OrgsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode orgs response: %v", err)
+	}
+	return out.Orgs, nil
+}
+
+// ListOrgsTool creates a tool to list the orgs the caller has access to
+func ListOrgsTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("list_orgs",
+			mcp.WithTitleAnnotation("List Orgs"),
+			mcp.WithDescription(`Lists the organizations your token has access to with their ID, name and your role in them, and the default org of this session if any.
+
+Use this tool to find the org_id to work with. When the other tools accept an org_id argument, pass the id of one of these orgs to run them against it; prefer orgs where your role allows the change you are about to make.`),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			orgs, err := ListOrgs(ctx, client)
+			if err != nil {
+				return nil, err
+			}
+
+			response := map[string]any{"orgs": orgs}
+			if current, _ := ctx.Value(OrgIDKey).(string); current != "" {
+				response["default_org_id"] = current
+			}
+			if len(orgs) == 0 {
+				response["guidance"] = DiscoveryGuidance{
+					ResultStatus: "empty",
+					NextSteps:    []string{"Your token has no access to any org; check that it is valid."},
+				}
+			}
+
+			r, err := json.Marshal(response)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
+
+// ListChildOrgsTool creates a tool to list the child orgs of a parent org
+func ListChildOrgsTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("list_child_orgs",
+			mcp.WithTitleAnnotation("List Child Orgs"),
+			mcp.WithDescription(`Lists the child organizations of your organization, e.g. the customers of a managed service provider.
+
+Pass a child org's id as org_id to the search, graph and facet tools to query that org, or use get_fleet_overview to compare all of them at once.`),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			orgs, err := ListChildOrgs(ctx, client)
+			if err != nil {
+				return nil, err
+			}
+
+			response := map[string]any{"orgs": orgs}
+			if len(orgs) == 0 {
+				response["guidance"] = DiscoveryGuidance{
+					ResultStatus: "empty",
+					NextSteps:    []string{"This organization has no child orgs; query it directly without org_id."},
+				}
+			}
+
+			r, err := json.Marshal(response)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
+
+// OrgOverride lets tools run against another org than the caller's through an org_id argument.
+// By default the read-only tools calling the API, but for orgListingTools, accept child orgs of
+// the caller's org. In multi-org mode every tool but list_orgs accepts any org the token has
+// access to. Child orgs and accessible orgs are cached in the storage for the TTL.
+type OrgOverride struct {
+	client   Client
+	store    storage.Interface
+	ttl      time.Duration
+	multiOrg bool
+	// tools are the names of the tools Tool added the org_id argument to
+	tools map[string]bool
+}
+
+func NewOrgOverride(client Client, store storage.Interface, ttl time.Duration, multiOrg bool) *OrgOverride {
+	return &OrgOverride{
+		client:   client,
+		store:    store,
+		ttl:      ttl,
+		multiOrg: multiOrg,
+		tools:    make(map[string]bool),
+	}
+}
+
+func (o *OrgOverride) supports(tool mcp.Tool) bool {
+	if o.multiOrg {
+		return tool.Name != "list_orgs"
+	}
+	readOnly := tool.Annotations.ReadOnlyHint
+	return readOnly != nil && *readOnly && !regionlessTools[tool.Name] && !orgListingTools[tool.Name]
+}
+
+// Tool adds the org_id argument to the tool if it supports running against another org. Tools are
+// registered before any call, so the names of the tools it adds the argument to need no lock.
+func (o *OrgOverride) Tool(tool mcp.Tool) mcp.Tool {
+	if !o.supports(tool) {
+		return tool
+	}
+	o.tools[tool.Name] = true
+
+	description := "ID of a child org to query instead of your own org. See list_child_orgs."
+	if o.multiOrg {
+		description = "ID of the org to run the tool against. Required unless the server has a default org. See list_orgs for the orgs you can access."
+	}
+
+	properties := make(map[string]any, len(tool.InputSchema.Properties)+1)
+	for k, v := range tool.InputSchema.Properties {
+		properties[k] = v
+	}
+	properties["org_id"] = map[string]any{
+		"type":        "string",
+		"description": description,
+	}
+	tool.InputSchema.Properties = properties
+	return tool
+}
+
+// cachedOrgIDs returns the IDs of the orgs cached under key, fetching them when missing or
+// expired. A storage error is a cache miss.
+func (o *OrgOverride) cachedOrgIDs(ctx context.Context, key string, fetch func() ([]Org, error)) (map[string]bool, error) {
+	var ids map[string]bool
+	if data, err := o.store.Get(ctx, key); err == nil && json.Unmarshal(data, &ids) == nil {
+		return ids, nil
+	}
+
+	orgs, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	ids = make(map[string]bool, len(orgs))
+	for _, org := range orgs {
+		ids[org.ID] = true
+	}
+	if data, err := json.Marshal(ids); err == nil {
+		_ = o.store.Set(ctx, key, data, o.ttl)
+	}
+	return ids, nil
+}
+
+// canAccess reports whether the caller may run tools against the org: in multi-org mode any org
+// the token has access to, and always the child orgs of the caller's org.
+func (o *OrgOverride) canAccess(ctx context.Context, orgID string) (bool, error) {
+	var listErr error
+	if o.multiOrg {
+		// The orgs a token has access to, by token
+		ids, err := o.cachedOrgIDs(ctx, "org-access/tokens/"+tokenKey(fetchTokens(ctx)), func() ([]Org, error) {
+			return ListOrgs(ctx, o.client)
+		})
+		if ids[orgID] {
+			return true, nil
+		}
+		listErr = err
+	}
+
+	keys, err := FetchContextKeys(ctx)
+	if err != nil {
+		// Without an org of its own the caller has no child orgs
+		return false, listErr
+	}
+
+	// The child orgs are cached per token, so the access of one caller never authorizes another
+	ids, err := o.cachedOrgIDs(ctx, "org-access/parents/"+tokenKey(keys)+"/"+keys.OrgID, func() ([]Org, error) {
+		return ListChildOrgs(ctx, o.client)
+	})
+	if err != nil {
+		return false, errors.Join(listErr, err)
+	}
+	return ids[orgID], nil
+}
+
+// Middleware runs calls with an org_id argument against that org.
+func (o *OrgOverride) Middleware() ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			args := request.GetArguments()
+			orgID, _ := args["org_id"].(string)
+			current, _ := ctx.Value(OrgIDKey).(string)
+
+			if orgID = strings.TrimSpace(orgID); orgID == "" {
+				if o.multiOrg && current == "" && o.tools[request.Params.Name] {
+					return mcp.NewToolResultError("org_id is required, this server has no default org; use list_orgs to see the orgs you can access"), nil
+				}
+				return next(ctx, request)
+			}
+
+			if !o.tools[request.Params.Name] {
+				return mcp.NewToolResultError(fmt.Sprintf("%s does not support org_id", request.Params.Name)), nil
+			}
+
+			if orgID != current {
+				allowed, err := o.canAccess(ctx, orgID)
+				if err != nil {
+					return nil, fmt.Errorf("failed to verify org_id: %w", err)
+				}
+				if !allowed {
+					if o.multiOrg {
+						return NewToolResultErrorCode(ErrCodePermissionDenied, fmt.Sprintf("your token has no access to org %s; use list_orgs to see the orgs you can access", orgID)), nil
+					}
+					return NewToolResultErrorCode(ErrCodePermissionDenied, fmt.Sprintf("org %s is not a child org of your org; use list_child_orgs to see the orgs you can query", orgID)), nil
+				}
+			}
+
+			overridden := make(map[string]any, len(args))
+			for k, v := range args {
+				if k != "org_id" {
+					overridden[k] = v
+				}
+			}
+			request.Params.Arguments = overridden
+
+			recordAuditOrg(ctx, orgID)
+			return next(context.WithValue(ctx, OrgIDKey, orgID), request)
+		}
+	}
+}
+
+// tokenKey identifies the token of the caller by a hash, so tokens are never kept.
+func tokenKey(keys *ContextKeys) string {
+	token := keys.BearerToken
+	if token == "" {
+		token = keys.EDToken
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:16])
+}
+
+type FleetOrgOverview struct {
+	OrgID      string  `json:"org_id"`
+	Name       string  `json:"name"`
+	LogCount   int64   `json:"log_count"`
+	ErrorCount int64   `json:"error_count"`
+	ErrorRate  float64 `json:"error_rate"`
+	Error      string  `json:"error,omitempty"`
+}
+
+type FleetOverviewResponse struct {
+	Lookback  string             `json:"lookback"`
+	Orgs      []FleetOrgOverview `json:"orgs"`
+	Truncated bool               `json:"truncated,omitempty"`
+	Guidance  *SearchGuidance    `json:"guidance,omitempty"`
+}
+
+// GetFleetOverviewTool creates a tool to compare the log volume and errors of all child orgs
+func GetFleetOverviewTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("get_fleet_overview",
+			mcp.WithTitleAnnotation("Get Fleet Overview"),
+			mcp.WithDescription(`Compares the log volume, error logs and error rate of every child org of your organization, worst first.
+
+Use this tool to triage many customers from one session, then query the orgs that stand out by passing their org_id to the search and graph tools.`),
+			mcp.WithString("lookback",
+				mcp.Description("Lookback period in Go duration format, or days and weeks (e.g., 15m, 1h, 24h, 7d)."),
+				mcp.DefaultString("1h"),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			lookback, _ := params.Optional[string](request, "lookback")
+			if lookback == "" {
+				lookback = "1h"
+			}
+			if _, err := ParseLookback(lookback); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid lookback %q: %v", lookback, err)), nil
+			}
+
+			orgs, err := ListChildOrgs(ctx, client)
+			if err != nil {
+				return nil, err
+			}
+
+			response := FleetOverviewResponse{Lookback: lookback, Orgs: make([]FleetOrgOverview, 0, len(orgs))}
+			if len(orgs) > maxFleetOrgs {
+				orgs = orgs[:maxFleetOrgs]
+				response.Truncated = true
+			}
+
+			overviews := make([]FleetOrgOverview, len(orgs))
+			sem := make(chan struct{}, fleetConcurrency)
+			var wg sync.WaitGroup
+			for i, org := range orgs {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					sem <- struct{}{}
+					defer func() { <-sem }()
+					overviews[i] = fleetOrgOverview(context.WithValue(ctx, OrgIDKey, org.ID), client, org, lookback)
+				}()
+			}
+			wg.Wait()
+
+			response.Orgs = append(response.Orgs, overviews...)
+			sort.SliceStable(response.Orgs, func(i, j int) bool {
+				if response.Orgs[i].ErrorRate != response.Orgs[j].ErrorRate {
+					return response.Orgs[i].ErrorRate > response.Orgs[j].ErrorRate
+				}
+				return response.Orgs[i].ErrorCount > response.Orgs[j].ErrorCount
+			})
+			response.Guidance = fleetOverviewGuidance(response)
+
+			r, err := json.Marshal(response)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
+
+// fleetOrgOverview counts the logs and error logs of the org in ctx.
+func fleetOrgOverview(ctx context.Context, client Client, org Org, lookback string) FleetOrgOverview {
+	overview := FleetOrgOverview{OrgID: org.ID, Name: org.Name}
+
+	payload, err := NewGraphQueryBuilder().
+		WithQuery("Q1", GraphQuery{Scope: "log", Query: "*"}).
+		WithQuery("Q2", GraphQuery{Scope: "log", Query: `severity_text:("ERROR" OR "FATAL")`}).
+		WithFormula("R1", "Q1").
+		WithFormula("R2", "Q2").
+		Build()
+	if err != nil {
+		overview.Error = err.Error()
+		return overview
+	}
+
+	queryParams := url.Values{}
+	queryParams.Add("lookback", lookback)
+
+	statusCode, bodyBytes, _, err := postGraphWithRetry(ctx, client, payload, queryParams)
+	if err != nil {
+		overview.Error = err.Error()
+		return overview
+	}
+	if statusCode != http.StatusMultiStatus {
+		overview.Error = fmt.Sprintf("failed to graph logs, status code %d: %s", statusCode, string(bodyBytes))
+		return overview
+	}
+
+	var graphResp map[string]struct {
+		Records []graphTimeseriesRecord `json:"records"`
+	}
+	if err := json.Unmarshal(bodyBytes, &graphResp); err != nil {
+		overview.Error = fmt.Sprintf("failed to decode graph response: %v", err)
+		return overview
+	}
+
+	overview.LogCount = sumRecords(graphResp["R1"].Records)
+	overview.ErrorCount = sumRecords(graphResp["R2"].Records)
+	if overview.LogCount > 0 {
+		overview.ErrorRate = roundProportion(float64(overview.ErrorCount) / float64(overview.LogCount))
+	}
+	return overview
+}
+
+func sumRecords(records []graphTimeseriesRecord) int64 {
+	var sum float64
+	for _, record := range records {
+		for _, point := range record.Timeseries {
+			sum += point.Value
+		}
+	}
+	return int64(sum)
+}
+
+func fleetOverviewGuidance(response FleetOverviewResponse) *SearchGuidance {
+	if len(response.Orgs) == 0 {
+		return &SearchGuidance{
+			ResultStatus: "empty",
+			NextSteps:    []string{"This organization has no child orgs."},
+		}
+	}
+
+	guidance := &SearchGuidance{ResultStatus: "success"}
+	var failed, silent []string
+	for _, org := range response.Orgs {
+		switch {
+		case org.Error != "":
+			failed = append(failed, org.OrgID)
+		case org.LogCount == 0:
+			silent = append(silent, org.OrgID)
+		}
+	}
+
+	if worst := response.Orgs[0]; worst.ErrorCount > 0 {
+		guidance.NextSteps = append(guidance.NextSteps, fmt.Sprintf("%s (%s) has the highest error rate, %.1f%%; search its errors with get_log_search, org_id:%q and query: severity_text:\"ERROR\".",
+			worst.Name, worst.OrgID, worst.ErrorRate*100, worst.OrgID))
+	}
+	if len(silent) > 0 {
+		guidance.NextSteps = append(guidance.NextSteps, fmt.Sprintf("No logs in the last %s from: %s; check their ingestion.", response.Lookback, strings.Join(silent, ", ")))
+	}
+	if len(failed) > 0 {
+		guidance.NextSteps = append(guidance.NextSteps, fmt.Sprintf("The overview failed for: %s; see their error.", strings.Join(failed, ", ")))
+	}
+	if response.Truncated {
+		guidance.Suggestions = append(guidance.Suggestions, fmt.Sprintf("Only the first %d child orgs are included.", maxFleetOrgs))
+	}
+	return guidance
+}
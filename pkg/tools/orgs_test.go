@@ -0,0 +1,59 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/storage"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// childOrgsAPI serves the child orgs of the parent org to the token of its admin only.
+func childOrgsAPI(t *testing.T) Client {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/orgs/parent/child_orgs", func(w http.ResponseWriter, r *http.Request) {
+		out := ChildOrgsResponse{Orgs: []Org{}}
+		if r.Header.Get("X-ED-API-Token") == "admin-token" {
+			out.Orgs = []Org{{ID: "child", Name: "Child"}}
+		}
+		_ = json.NewEncoder(w).Encode(out)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return NewHTTPClient(srv.URL, "X-ED-API-Token", WithRateLimits(RateLimits{}))
+}
+
+func TestOrgOverrideCachesChildOrgsPerToken(t *testing.T) {
+	override := NewOrgOverride(childOrgsAPI(t), storage.NewMemory(), time.Minute, false)
+	handler := override.Middleware()(func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	})
+	override.Tool(mcp.NewTool("get_log_search", mcp.WithReadOnlyHintAnnotation(true)))
+
+	call := func(token string) *mcp.CallToolResult {
+		ctx := context.WithValue(context.Background(), OrgIDKey, "parent")
+		ctx = context.WithValue(ctx, EDTokenKey, token)
+		var request mcp.CallToolRequest
+		request.Params.Name = "get_log_search"
+		request.Params.Arguments = map[string]any{"org_id": "child"}
+		result, err := handler(ctx, request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	if result := call("admin-token"); result.IsError {
+		t.Fatalf("admin of the parent org was denied the child org: %v", result.Content)
+	}
+	// Another caller of the same org is not authorized by the cached access of the admin
+	if result := call("member-token"); !result.IsError {
+		t.Error("member of the parent org was allowed the child org from the cache of the admin")
+	}
+}
@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// NextCall is a tool call to make next, with its arguments filled in.
+type NextCall struct {
+	Tool      string         `json:"tool"`
+	Arguments map[string]any `json:"arguments"`
+}
+
+// Pagination is the content block added to results with more pages.
+type Pagination struct {
+	HasMore  bool     `json:"has_more"`
+	NextCall NextCall `json:"next_call"`
+	Hint     string   `json:"hint"`
+}
+
+// PaginationHints returns a tool middleware that adds a pagination content block to the results
+// of the paginated tools, the ones taking a cursor argument, that have a next cursor. The block
+// holds the exact call for the next page, the arguments of the call with the cursor, so models do
+// not have to rebuild the arguments to continue. paginated is read on every call, so tools may be
+// added to it as they are registered.
+func PaginationHints(paginated map[string]bool) ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			if !paginated[request.Params.Name] {
+				return next(ctx, request)
+			}
+
+			// The arguments are copied before the next middlewares rewrite them, e.g. drop org_id
+			args := request.GetArguments()
+			nextArgs := make(map[string]any, len(args)+1)
+			for k, v := range args {
+				nextArgs[k] = v
+			}
+
+			result, err := next(ctx, request)
+			if err != nil || result == nil || result.IsError {
+				return result, err
+			}
+
+			cursor := resultCursor(result)
+			if cursor == "" || cursor == nextArgs["cursor"] {
+				return result, nil
+			}
+			nextArgs["cursor"] = cursor
+
+			block, err := json.Marshal(map[string]any{"pagination": Pagination{
+				HasMore:  true,
+				NextCall: NextCall{Tool: request.Params.Name, Arguments: nextArgs},
+				Hint:     fmt.Sprintf("More results are available. To get the next page, call %s with exactly these arguments.", request.Params.Name),
+			}})
+			if err != nil {
+				return result, nil
+			}
+			result.Content = append(result.Content, mcp.NewTextContent(string(block)))
+			return result, nil
+		}
+	}
+}
+
+// resultCursor returns the next cursor of the first JSON content of the result, if any.
+func resultCursor(result *mcp.CallToolResult) string {
+	for _, content := range result.Content {
+		text, ok := content.(mcp.TextContent)
+		if !ok {
+			continue
+		}
+		var doc any
+		if json.Unmarshal([]byte(text.Text), &doc) == nil {
+			return findCursor(doc)
+		}
+	}
+	return ""
+}
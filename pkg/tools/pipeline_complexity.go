@@ -0,0 +1,331 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	NodeRoleSource      = "source"
+	NodeRoleProcessor   = "processor"
+	NodeRoleDestination = "destination"
+
+	CostLow    = "low"
+	CostMedium = "medium"
+	CostHigh   = "high"
+
+	// regexHeavyThreshold is the number of regexes from which a processor is regex heavy.
+	regexHeavyThreshold = 3
+)
+
+// regexProcessorTypes are the processors that apply a regex to every item they process.
+var regexProcessorTypes = map[string]bool{
+	"regex_filter":          true,
+	"mask":                  true,
+	"generic_mask":          true,
+	"log_transform":         true,
+	"grok":                  true,
+	"parse_regex":           true,
+	"extract_metric":        true,
+	"regex_capture":         true,
+	"regex_capture_to_json": true,
+}
+
+// statefulProcessorTypes are the processors that keep state over many items, their cost grows with
+// cardinality rather than throughput.
+var statefulProcessorTypes = map[string]bool{
+	"log_to_pattern":   true,
+	"log_to_metric":    true,
+	"aggregate":        true,
+	"aggregate_metric": true,
+	"dedup":            true,
+	"deduplicate_logs": true,
+	"lookup":           true,
+	"enrichment":       true,
+	"sample":           true,
+	"tail_sample":      true,
+}
+
+var (
+	// regexKeyPattern matches the config keys holding regexes
+	regexKeyPattern = regexp.MustCompile(`(?i)(regex|pattern|grok|include|exclude)`)
+	// ottlRegexFuncPattern matches the OTTL functions taking a regex
+	ottlRegexFuncPattern = regexp.MustCompile(`\b(IsMatch|replace_pattern|replace_all_patterns|replace_match|ExtractPatterns|ExtractGrokPatterns)\(`)
+)
+
+// PipelineNodeComplexity is the complexity of a pipeline node and a hint of its relative cost.
+type PipelineNodeComplexity struct {
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	Role       string   `json:"role"`
+	Regexes    int      `json:"regexes,omitempty"`
+	RegexHeavy bool     `json:"regex_heavy,omitempty"`
+	FanOut     int      `json:"fan_out"`
+	Copies     int      `json:"copies"`
+	Cost       string   `json:"cost"`
+	CostHints  []string `json:"cost_hints,omitempty"`
+}
+
+// PipelineComplexity is the analysis of the size and shape of a pipeline configuration.
+type PipelineComplexity struct {
+	ConfID       string                   `json:"conf_id"`
+	SizeBytes    int                      `json:"size_bytes"`
+	Lines        int                      `json:"lines"`
+	NodeCount    int                      `json:"node_count"`
+	LinkCount    int                      `json:"link_count"`
+	NodesByRole  map[string]int           `json:"nodes_by_role"`
+	NodesByType  map[string]int           `json:"nodes_by_type"`
+	RegexHeavy   []string                 `json:"regex_heavy_processors"`
+	MaxFanOut    int                      `json:"max_fan_out"`
+	FanOutFactor float64                  `json:"fan_out_factor"`
+	Unlinked     []string                 `json:"unlinked_nodes,omitempty"`
+	Nodes        []PipelineNodeComplexity `json:"nodes"`
+}
+
+type pipelineGraphYAML struct {
+	Nodes []map[string]any `yaml:"nodes"`
+	Links []struct {
+		From string `yaml:"from"`
+		To   string `yaml:"to"`
+	} `yaml:"links"`
+}
+
+// nodeRole returns the role of a node from its type, e.g. kubernetes_input is a source.
+func nodeRole(nodeType string) string {
+	switch {
+	case strings.HasSuffix(nodeType, "_input"):
+		return NodeRoleSource
+	case strings.HasSuffix(nodeType, "_output"):
+		return NodeRoleDestination
+	default:
+		return NodeRoleProcessor
+	}
+}
+
+// countRegexes counts the regexes in a node config: the string values of regex-like keys and
+// the OTTL calls taking a regex.
+func countRegexes(value any, regexKey bool) int {
+	switch v := value.(type) {
+	case map[string]any:
+		count := 0
+		for key, item := range v {
+			count += countRegexes(item, regexKey || regexKeyPattern.MatchString(key))
+		}
+		return count
+	case []any:
+		count := 0
+		for _, item := range v {
+			count += countRegexes(item, regexKey)
+		}
+		return count
+	case string:
+		if calls := len(ottlRegexFuncPattern.FindAllString(v, -1)); calls > 0 {
+			return calls
+		}
+		if regexKey && v != "" {
+			return 1
+		}
+	}
+	return 0
+}
+
+// AnalyzePipelineComplexity analyzes the size and shape of a pipeline configuration (YAML or JSON).
+func AnalyzePipelineComplexity(confID, content string) (*PipelineComplexity, error) {
+	var cfg pipelineGraphYAML
+	if err := yaml.Unmarshal([]byte(content), &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse pipeline configuration: %w", err)
+	}
+
+	analysis := &PipelineComplexity{
+		ConfID:      confID,
+		SizeBytes:   len(content),
+		Lines:       strings.Count(strings.TrimRight(content, "\n"), "\n") + 1,
+		NodeCount:   len(cfg.Nodes),
+		LinkCount:   len(cfg.Links),
+		NodesByRole: make(map[string]int),
+		NodesByType: make(map[string]int),
+		RegexHeavy:  []string{},
+	}
+
+	out := make(map[string][]string)
+	linked := make(map[string]bool)
+	for _, link := range cfg.Links {
+		out[link.From] = append(out[link.From], link.To)
+		linked[link.From], linked[link.To] = true, true
+	}
+
+	// copies counts the paths from the sources to each node: how many times an item of the sources
+	// is processed by the node
+	copies := make(map[string]int)
+	var visit func(name string, depth int)
+	visit = func(name string, depth int) {
+		// Links form a DAG; the depth guard stops on invalid configurations with cycles
+		if depth > len(cfg.Nodes) {
+			return
+		}
+		copies[name]++
+		for _, to := range out[name] {
+			visit(to, depth+1)
+		}
+	}
+	for _, node := range cfg.Nodes {
+		nodeType, _ := node["type"].(string)
+		if name, _ := node["name"].(string); nodeRole(nodeType) == NodeRoleSource {
+			visit(name, 0)
+		}
+	}
+
+	fanOutNodes, fanOutTotal := 0, 0
+	for _, node := range cfg.Nodes {
+		name, _ := node["name"].(string)
+		nodeType, _ := node["type"].(string)
+		n := PipelineNodeComplexity{
+			Name:    name,
+			Type:    nodeType,
+			Role:    nodeRole(nodeType),
+			Regexes: countRegexes(node, false),
+			FanOut:  len(out[name]),
+			Copies:  copies[name],
+		}
+		n.RegexHeavy = n.Role == NodeRoleProcessor && (regexProcessorTypes[nodeType] || n.Regexes >= regexHeavyThreshold)
+		n.Cost, n.CostHints = nodeCost(n)
+
+		analysis.NodesByRole[n.Role]++
+		analysis.NodesByType[nodeType]++
+		if n.RegexHeavy {
+			analysis.RegexHeavy = append(analysis.RegexHeavy, name)
+		}
+		if n.FanOut > 0 {
+			fanOutNodes++
+			fanOutTotal += n.FanOut
+		}
+		analysis.MaxFanOut = max(analysis.MaxFanOut, n.FanOut)
+		if !linked[name] {
+			analysis.Unlinked = append(analysis.Unlinked, name)
+		}
+		analysis.Nodes = append(analysis.Nodes, n)
+	}
+	if fanOutNodes > 0 {
+		analysis.FanOutFactor = float64(int(float64(fanOutTotal)/float64(fanOutNodes)*100)) / 100
+	}
+
+	// Most expensive nodes first
+	rank := map[string]int{CostHigh: 0, CostMedium: 1, CostLow: 2}
+	sort.SliceStable(analysis.Nodes, func(i, j int) bool {
+		return rank[analysis.Nodes[i].Cost] < rank[analysis.Nodes[j].Cost]
+	})
+	return analysis, nil
+}
+
+// nodeCost returns a relative cost estimate of the node with its reasons. It is a hint from the
+// configuration only: actual costs depend on the throughput of the node.
+func nodeCost(n PipelineNodeComplexity) (string, []string) {
+	var hints []string
+	score := 0
+	if n.RegexHeavy {
+		score += 2
+		hints = append(hints, fmt.Sprintf("applies %s to every item; anchor them and filter items out before this node", regexCount(max(n.Regexes, 1))))
+	} else if n.Role == NodeRoleProcessor && n.Regexes > 0 {
+		score++
+		hints = append(hints, fmt.Sprintf("applies %s to every item", regexCount(n.Regexes)))
+	}
+	if statefulProcessorTypes[n.Type] {
+		score++
+		hints = append(hints, "keeps state per key; its memory grows with the cardinality of the keys")
+	}
+	if n.Copies > 1 {
+		score++
+		if n.Role == NodeRoleDestination {
+			hints = append(hints, fmt.Sprintf("receives %d copies of each source item through fan-out; data is sent and billed once per copy", n.Copies))
+		} else {
+			hints = append(hints, fmt.Sprintf("processes %d copies of each source item through fan-out; move it before the fan-out if possible", n.Copies))
+		}
+	}
+
+	switch {
+	case score >= 3:
+		return CostHigh, hints
+	case score >= 1:
+		return CostMedium, hints
+	default:
+		return CostLow, hints
+	}
+}
+
+func regexCount(n int) string {
+	if n == 1 {
+		return "1 regex"
+	}
+	return fmt.Sprintf("%d regexes", n)
+}
+
+// AnalyzePipelineComplexityTool creates a tool to analyze the complexity of a pipeline configuration
+func AnalyzePipelineComplexityTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("analyze_pipeline_complexity",
+			mcp.WithTitleAnnotation("Analyze Pipeline Complexity"),
+			mcp.WithDescription(`Analyzes the size and shape of a pipeline configuration to guide optimization of slow or expensive pipelines.
+
+Reports:
+- configuration size, node and link counts, node counts per role (source, processor, destination) and type
+- regex heavy processors, which apply regexes to every item
+- fan-out: how many nodes each node sends to, and how many copies of each source item every node processes
+- a relative cost hint (low, medium, high) per node with its reasons, most expensive first
+
+Cost hints come from the configuration only; confirm them with the throughput of the pipeline before changing it.
+
+PREREQUISITE: Call get_pipelines tool first to obtain the conf_id.`),
+			mcp.WithString("conf_id",
+				mcp.Description("Pipeline configuration ID. Get this from get_pipelines response."),
+				mcp.Required(),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			confID, err := request.RequireString("conf_id")
+			if err != nil {
+				return mcp.NewToolResultError("missing required parameter: conf_id"), err
+			}
+
+			conf, err := GetConf(ctx, client, confID)
+			if err != nil {
+				return nil, err
+			}
+
+			analysis, err := AnalyzePipelineComplexity(confID, conf.Content)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+
+			guidance := DiscoveryGuidance{ResultStatus: "success"}
+			if len(analysis.RegexHeavy) > 0 {
+				guidance.NextSteps = append(guidance.NextSteps, fmt.Sprintf("Review the regexes of %s: anchor them, and drop or route items before these processors so fewer items reach them.", strings.Join(analysis.RegexHeavy, ", ")))
+			}
+			if analysis.MaxFanOut > 1 {
+				guidance.NextSteps = append(guidance.NextSteps, "Nodes after a fan-out process each item once per path; move shared processing before the fan-out.")
+			}
+			if len(analysis.Unlinked) > 0 {
+				guidance.Suggestions = append(guidance.Suggestions, fmt.Sprintf("Nodes %s are not linked and do nothing; remove them with remove_pipeline_node tool.", strings.Join(analysis.Unlinked, ", ")))
+			}
+			guidance.Suggestions = append(guidance.Suggestions, "Use get_fleet_agents tool to check the health of the agents running the pipeline.")
+
+			r, err := json.Marshal(map[string]any{
+				"analysis": analysis,
+				"guidance": guidance,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
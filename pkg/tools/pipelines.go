@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
 
@@ -16,6 +18,25 @@ import (
 	"github.com/mark3labs/mcp-go/server"
 )
 
+const (
+	OutputFormatJSON = "json"
+	OutputFormatYAML = "yaml"
+)
+
+// withOutputFormat adds the output_format parameter of tools returning pipeline configurations.
+func withOutputFormat() mcp.ToolOption {
+	return mcp.WithString("output_format",
+		mcp.Description(`How to return the pipeline configuration: "json" embeds it as an escaped string in the JSON response, "yaml" returns it as a separate fenced YAML block, which is easier to read and edit.`),
+		mcp.DefaultString(OutputFormatJSON),
+		mcp.Enum(OutputFormatJSON, OutputFormatYAML),
+	)
+}
+
+// yamlBlock fences pipeline configuration content as a YAML code block.
+func yamlBlock(content string) string {
+	return "```yaml\n" + strings.TrimRight(content, "\n") + "\n```"
+}
+
 type PipelineToolResponse struct {
 	Data     json.RawMessage   `json:"data"`
 	Guidance *PipelineGuidance `json:"guidance,omitempty"`
@@ -63,6 +84,7 @@ WORKFLOW: This is the starting point for pipeline operations.
 3. get_pipeline_history(conf_id) → see version history
 4. deploy_pipeline(conf_id, version) → deploy a specific version
 5. add_pipeline_source(conf_id, source) → add data source
+6. update_pipeline_node, remove_pipeline_node, connect_pipeline_nodes → edit nodes and links
 
 Returns recently updated pipelines with their conf_id (configuration ID) which is required for all other pipeline operations.`),
 			mcp.WithNumber("limit",
@@ -155,11 +177,13 @@ Returns the full pipeline configuration including:
 After viewing config, you can:
 - Use get_pipeline_history tool to see version history
 - Use add_pipeline_source tool to add data sources
+- Use update_pipeline_node, remove_pipeline_node and connect_pipeline_nodes tools to edit nodes and links
 - Use deploy_pipeline tool to deploy changes`),
 			mcp.WithString("conf_id",
 				mcp.Description("Config ID of the pipeline. Get this from get_pipelines response."),
 				mcp.Required(),
 			),
+			withOutputFormat(),
 			mcp.WithReadOnlyHintAnnotation(true),
 			mcp.WithIdempotentHintAnnotation(true),
 			mcp.WithDestructiveHintAnnotation(false),
@@ -209,15 +233,36 @@ After viewing config, you can:
 						"Use get_pipeline_history tool to see the configuration change history.",
 						"Use deploy_pipeline tool to deploy the pipeline after making changes.",
 						"Use add_pipeline_source tool to add new data sources to the pipeline.",
+						"Use update_pipeline_node, remove_pipeline_node and connect_pipeline_nodes tools to edit nodes and links.",
 					},
 				},
 			}
 
+			var content string
+			if format, _ := params.Optional[string](request, "output_format"); format == OutputFormatYAML {
+				// Move the content out of the JSON into its own block, so it is not escaped
+				var conf map[string]any
+				if err := json.Unmarshal(bodyBytes, &conf); err == nil {
+					content, _ = conf["content"].(string)
+					delete(conf, "content")
+					if response.Data, err = json.Marshal(conf); err != nil {
+						return nil, fmt.Errorf("failed to marshal pipeline, err: %w", err)
+					}
+					response.Guidance.Suggestions = append(response.Guidance.Suggestions,
+						"The configuration content is the YAML block; pass it without the fences as content to save_pipeline.")
+				}
+			}
+
 			r, err := json.Marshal(response)
 			if err != nil {
 				return nil, fmt.Errorf("failed to marshal wrapped response, err: %w", err)
 			}
 
+			if content != "" {
+				return &mcp.CallToolResult{
+					Content: []mcp.Content{mcp.NewTextContent(yamlBlock(content)), mcp.NewTextContent(string(r))},
+				}, nil
+			}
 			return mcp.NewToolResultText(string(r)), nil
 		}
 }
@@ -315,6 +360,8 @@ PREREQUISITES (must be called in order):
 The version parameter is the timestamp from get_pipeline_history response.
 This is a DESTRUCTIVE operation that will apply configuration changes.
 
+Validate the configuration with validate_pipeline first; do not deploy a configuration with errors.
+
 Workflow example:
 1. get_pipelines → find pipeline with conf_id:"abc123"
 2. get_pipeline_history(conf_id:"abc123") → get version:"1752190141312"
@@ -466,11 +513,6 @@ Example node configurations:
 			mcp.WithOpenWorldHintAnnotation(false),
 		),
 		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			keys, err := FetchContextKeys(ctx)
-			if err != nil {
-				return nil, err
-			}
-
 			confID, err := request.RequireString("conf_id")
 			if err != nil {
 				return mcp.NewToolResultError("missing required parameter: conf_id"), err
@@ -487,49 +529,344 @@ Example node configurations:
 				return mcp.NewToolResultError("node parameter must be an object"), fmt.Errorf("node parameter is not a map")
 			}
 
-			// Prepare request payload
-			payload := map[string]any{
-				"node": node,
+			bodyBytes, err := mutatePipeline(ctx, client, confID, "add_source", map[string]any{"node": node}, "add pipeline source")
+			if err != nil {
+				return nil, err
+			}
+
+			return pipelineMutationResult(bodyBytes, "Source added and configuration saved (not yet deployed).")
+		}
+}
+
+// RemovePipelineNodeTool creates a tool to remove a node from a pipeline
+func RemovePipelineNodeTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("remove_pipeline_node",
+			mcp.WithTitleAnnotation("Remove Pipeline Node"),
+			mcp.WithDescription(`Removes a node (source, processor or destination) and all its links from a pipeline configuration.
+
+PREREQUISITE: Call get_pipeline_config tool first to see the node names and links.
+
+This tool SAVES the configuration but does NOT deploy it. Nodes that were only fed by the removed
+node are left unlinked: use connect_pipeline_nodes tool to reconnect them, then validate_pipeline
+and deploy_pipeline tools.`),
+			mcp.WithString("conf_id",
+				mcp.Description("Config ID of the pipeline"),
+				mcp.Required(),
+			),
+			mcp.WithString("node_name",
+				mcp.Description("Name of the node to remove"),
+				mcp.Required(),
+			),
+			mcp.WithReadOnlyHintAnnotation(false),
+			mcp.WithIdempotentHintAnnotation(false),
+			mcp.WithDestructiveHintAnnotation(true),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			confID, err := request.RequireString("conf_id")
+			if err != nil {
+				return mcp.NewToolResultError("missing required parameter: conf_id"), err
 			}
 
-			payloadBytes, err := json.Marshal(payload)
+			nodeName, err := request.RequireString("node_name")
 			if err != nil {
-				return nil, fmt.Errorf("failed to marshal payload: %v", err)
+				return mcp.NewToolResultError("missing required parameter: node_name"), err
 			}
 
-			addSourceURL := fmt.Sprintf("%s/v1/orgs/%s/pipelines/%s/add_source", client.APIURL(), keys.OrgID, confID)
-			req, err := http.NewRequestWithContext(ctx, http.MethodPost, addSourceURL, bytes.NewReader(payloadBytes))
+			bodyBytes, err := mutatePipeline(ctx, client, confID, "remove_node", map[string]any{"node_name": nodeName}, "remove pipeline node")
 			if err != nil {
-				return nil, fmt.Errorf("failed to create request: %v", err)
+				return nil, err
 			}
 
-			req.Header.Add("Content-Type", "application/json")
-			applyAuthHeader(req, keys)
+			return pipelineMutationResult(bodyBytes, "Node and its links removed and configuration saved (not yet deployed).",
+				"Use get_pipeline_config tool to check for nodes left unlinked and connect_pipeline_nodes tool to reconnect them.")
+		}
+}
 
-			resp, err := client.Do(req)
+// UpdatePipelineNodeTool creates a tool to change the configuration of a pipeline node
+func UpdatePipelineNodeTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("update_pipeline_node",
+			mcp.WithTitleAnnotation("Update Pipeline Node"),
+			mcp.WithDescription(`Replaces the configuration of a node of a pipeline, keeping its links.
+
+PREREQUISITE: Call get_pipeline_config tool first to get the current node configuration, then pass
+the full modified node: fields left out are removed from the node. The type of a node cannot be
+changed; remove it and add a new node instead.
+
+If the node is renamed (a different name in node), its links are renamed too.
+
+This tool SAVES the configuration but does NOT deploy it.`),
+			mcp.WithString("conf_id",
+				mcp.Description("Config ID of the pipeline"),
+				mcp.Required(),
+			),
+			mcp.WithString("node_name",
+				mcp.Description("Current name of the node to update"),
+				mcp.Required(),
+			),
+			mcp.WithObject("node",
+				mcp.Description("Full new node configuration. Must include 'name' and 'type' fields."),
+				mcp.Required(),
+			),
+			mcp.WithReadOnlyHintAnnotation(false),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(true),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			confID, err := request.RequireString("conf_id")
+			if err != nil {
+				return mcp.NewToolResultError("missing required parameter: conf_id"), err
+			}
+
+			nodeName, err := request.RequireString("node_name")
+			if err != nil {
+				return mcp.NewToolResultError("missing required parameter: node_name"), err
+			}
+
+			node, ok := request.GetArguments()["node"].(map[string]any)
+			if !ok {
+				return mcp.NewToolResultError("node parameter must be an object"), fmt.Errorf("node parameter is not a map")
+			}
+			if name, _ := node["name"].(string); name == "" {
+				return mcp.NewToolResultError("node must have a name"), fmt.Errorf("node must have a name")
+			}
+			if nodeType, _ := node["type"].(string); nodeType == "" {
+				return mcp.NewToolResultError("node must have a type"), fmt.Errorf("node must have a type")
+			}
+
+			payload := map[string]any{"node_name": nodeName, "node": node}
+			bodyBytes, err := mutatePipeline(ctx, client, confID, "update_node", payload, "update pipeline node")
 			if err != nil {
 				return nil, err
 			}
 
-			defer resp.Body.Close()
-			bodyBytes, err := io.ReadAll(resp.Body)
+			return pipelineMutationResult(bodyBytes, "Node updated and configuration saved (not yet deployed).")
+		}
+}
+
+// ConnectPipelineNodesTool creates a tool to add or remove a link between pipeline nodes
+func ConnectPipelineNodesTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("connect_pipeline_nodes",
+			mcp.WithTitleAnnotation("Connect Pipeline Nodes"),
+			mcp.WithDescription(`Adds a link from a node to another in a pipeline configuration, so the data of the first node flows to the second. Set disconnect to remove the link instead.
+
+PREREQUISITE: Call get_pipeline_config tool first to see the node names and links.
+
+To move a node in the flow, disconnect its old links and connect the new ones.
+
+This tool SAVES the configuration but does NOT deploy it.`),
+			mcp.WithString("conf_id",
+				mcp.Description("Config ID of the pipeline"),
+				mcp.Required(),
+			),
+			mcp.WithString("from",
+				mcp.Description("Name of the node the data flows from"),
+				mcp.Required(),
+			),
+			mcp.WithString("to",
+				mcp.Description("Name of the node the data flows to"),
+				mcp.Required(),
+			),
+			mcp.WithBoolean("disconnect",
+				mcp.Description("Remove the link instead of adding it"),
+				mcp.DefaultBool(false),
+			),
+			mcp.WithReadOnlyHintAnnotation(false),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(true),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			confID, err := request.RequireString("conf_id")
 			if err != nil {
-				return nil, fmt.Errorf("failed to read response body: %v", err)
+				return mcp.NewToolResultError("missing required parameter: conf_id"), err
 			}
 
-			if resp.StatusCode != http.StatusOK {
-				return nil, fmt.Errorf("failed to add pipeline source, status code %d: %s", resp.StatusCode, string(bodyBytes))
+			from, err := request.RequireString("from")
+			if err != nil {
+				return mcp.NewToolResultError("missing required parameter: from"), err
+			}
+
+			to, err := request.RequireString("to")
+			if err != nil {
+				return mcp.NewToolResultError("missing required parameter: to"), err
+			}
+
+			if from == to {
+				return mcp.NewToolResultError("from and to must be different nodes"), fmt.Errorf("from and to must be different nodes")
+			}
+
+			endpoint, action, done := "connect_nodes", "connect pipeline nodes", "Link added"
+			if disconnect, _ := params.Optional[bool](request, "disconnect"); disconnect {
+				endpoint, action, done = "disconnect_nodes", "disconnect pipeline nodes", "Link removed"
+			}
+
+			bodyBytes, err := mutatePipeline(ctx, client, confID, endpoint, map[string]any{"from": from, "to": to}, action)
+			if err != nil {
+				return nil, err
+			}
+
+			return pipelineMutationResult(bodyBytes, done+" and configuration saved (not yet deployed).")
+		}
+}
+
+// mutatePipeline posts a change to a pipeline mutation endpoint, which saves a new version of the
+// configuration, and returns the response body.
+func mutatePipeline(ctx context.Context, client Client, confID, endpoint string, payload map[string]any, action string) ([]byte, error) {
+	keys, err := FetchContextKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %v", err)
+	}
+
+	mutationURL := fmt.Sprintf("%s/v1/orgs/%s/pipelines/%s/%s", client.APIURL(), keys.OrgID, url.PathEscape(confID), endpoint)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, mutationURL, bytes.NewReader(payloadBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Add("Content-Type", "application/json")
+	applyAuthHeader(req, keys)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to %s, status code %d: %s", action, resp.StatusCode, string(bodyBytes))
+	}
+
+	return bodyBytes, nil
+}
+
+// pipelineMutationResult wraps the response of a pipeline mutation with the steps to deploy it.
+func pipelineMutationResult(bodyBytes []byte, done string, nextSteps ...string) (*mcp.CallToolResult, error) {
+	response := PipelineToolResponse{
+		Data: bodyBytes,
+		Guidance: &PipelineGuidance{
+			ResultStatus: "success",
+			NextSteps: append(append([]string{done}, nextSteps...),
+				"Use get_pipeline_history tool to get the latest version timestamp.",
+				"Use deploy_pipeline tool with the version to deploy the updated configuration.",
+			),
+		},
+	}
+
+	r, err := json.Marshal(response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal wrapped response, err: %w", err)
+	}
+
+	return mcp.NewToolResultText(string(r)), nil
+}
+
+// PipelineConflictResponse is returned by save_pipeline instead of saving when the pipeline was
+// changed by someone else since base_version.
+type PipelineConflictResponse struct {
+	Conflict       bool   `json:"conflict"`
+	ConfID         string `json:"conf_id"`
+	BaseVersion    string `json:"base_version"`
+	CurrentVersion string `json:"current_version"`
+	// BaseToCurrent is what others changed since base_version, BaseToYours what the rejected
+	// content changes. Diff is only set when base_version could not be fetched.
+	BaseToCurrent []string          `json:"diff_base_to_current,omitempty"`
+	BaseToYours   []string          `json:"diff_base_to_yours,omitempty"`
+	Diff          []string          `json:"diff_to_current,omitempty"`
+	Guidance      *PipelineGuidance `json:"guidance,omitempty"`
+}
+
+// SavePipelineTool creates a tool to save a new pipeline configuration version
+func SavePipelineTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("save_pipeline",
+			mcp.WithTitleAnnotation("Save Pipeline"),
+			mcp.WithDescription(`Saves a new version of a pipeline configuration (not deployed).
+
+PREREQUISITES (must be called in order):
+1. get_pipeline_history(conf_id) → note the latest version (timestamp field) as base_version
+2. get_pipeline_config(conf_id) → get the YAML content to edit
+3. save_pipeline(conf_id, content, base_version) → save the edited YAML
+
+If someone else saved the pipeline after base_version, nothing is saved and a conflict result is
+returned with the current version, a diff from base_version to the current content (what others
+changed) and a diff from base_version to your content (what you changed). Apply your changes to the
+current content and save again with the current version.`),
+			mcp.WithString("conf_id",
+				mcp.Description("Config ID of the pipeline"),
+				mcp.Required(),
+			),
+			mcp.WithString("content",
+				mcp.Description("Full pipeline configuration YAML to save"),
+				mcp.Required(),
+			),
+			mcp.WithString("base_version",
+				mcp.Description("Latest version (timestamp field from get_pipeline_history) at the time the configuration was read. Used to detect concurrent edits."),
+				mcp.Required(),
+			),
+			mcp.WithString("description",
+				mcp.Description("Short description of the change"),
+				mcp.DefaultString(""),
+			),
+			withOutputFormat(),
+			mcp.WithReadOnlyHintAnnotation(false),
+			mcp.WithIdempotentHintAnnotation(false),
+			mcp.WithDestructiveHintAnnotation(true),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			confID, err := request.RequireString("conf_id")
+			if err != nil {
+				return mcp.NewToolResultError("missing required parameter: conf_id"), err
+			}
+
+			content, err := request.RequireString("content")
+			if err != nil {
+				return mcp.NewToolResultError("missing required parameter: content"), err
+			}
+
+			baseVersion, err := request.RequireString("base_version")
+			if err != nil {
+				return mcp.NewToolResultError("missing required parameter: base_version"), err
+			}
+
+			description, _ := params.Optional[string](request, "description")
+
+			result, err := SavePipeline(ctx, client, confID, description, "", content, baseVersion)
+			var conflictErr *PipelineConflictError
+			if errors.As(err, &conflictErr) {
+				format, _ := params.Optional[string](request, "output_format")
+				return pipelineConflictResult(ctx, client, conflictErr, content, format)
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			rawData, err := json.Marshal(result)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response, err: %w", err)
 			}
 
-			// Wrap with guidance
 			response := PipelineToolResponse{
-				Data: bodyBytes,
+				Data: rawData,
 				Guidance: &PipelineGuidance{
 					ResultStatus: "success",
 					NextSteps: []string{
-						"Source added and configuration saved (not yet deployed).",
-						"Use get_pipeline_history tool to get the latest version timestamp.",
-						"Use deploy_pipeline tool with the version to deploy the updated configuration.",
+						"Configuration saved (not yet deployed).",
+						"Use validate_pipeline tool to check the saved configuration before deploying it.",
+						"Use get_pipeline_history tool to get the new version timestamp.",
+						"Use deploy_pipeline tool with the version to deploy the saved configuration.",
 					},
 				},
 			}
@@ -542,3 +879,131 @@ Example node configurations:
 			return mcp.NewToolResultText(string(r)), nil
 		}
 }
+
+// ValidatePipelineTool creates a tool to validate a pipeline configuration before deploying it
+func ValidatePipelineTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("validate_pipeline",
+			mcp.WithTitleAnnotation("Validate Pipeline"),
+			mcp.WithDescription(`Validates a pipeline configuration (YAML or JSON) without saving or deploying it, and returns its errors and warnings with their location.
+
+Call it before deploying a change:
+1. save_pipeline(conf_id, content, base_version) → save the edited configuration
+2. validate_pipeline(conf_id) → validate the saved configuration
+3. deploy_pipeline(conf_id, version) → only if valid is true
+
+Pass content to validate a configuration before saving it instead. Do not deploy a configuration with errors; warnings do not block deploys but should be reviewed.`),
+			mcp.WithString("conf_id",
+				mcp.Description("Config ID of the pipeline, its fleet type decides which nodes are valid"),
+				mcp.Required(),
+			),
+			mcp.WithString("content",
+				mcp.Description("Pipeline configuration YAML or JSON to validate. Leave empty to validate the saved configuration of the pipeline."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			confID, err := request.RequireString("conf_id")
+			if err != nil {
+				return mcp.NewToolResultError("missing required parameter: conf_id"), err
+			}
+
+			content, _ := params.Optional[string](request, "content")
+			if strings.TrimSpace(content) == "" {
+				conf, err := GetConf(ctx, client, confID)
+				if err != nil {
+					return nil, err
+				}
+				content = conf.Content
+			}
+
+			result, err := ValidatePipeline(ctx, client, confID, content)
+			if err != nil {
+				return nil, err
+			}
+
+			rawData, err := json.Marshal(result)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response, err: %w", err)
+			}
+
+			guidance := &PipelineGuidance{ResultStatus: "valid"}
+			switch {
+			case !result.Valid:
+				guidance.ResultStatus = "invalid"
+				guidance.NextSteps = []string{
+					"Do not deploy this configuration.",
+					"Fix the errors (path and line locate them), save the configuration with save_pipeline tool and validate it again.",
+				}
+			case len(result.Warnings) > 0:
+				guidance.NextSteps = []string{"The configuration is valid. Review the warnings with the user before deploying it with deploy_pipeline tool."}
+			default:
+				guidance.NextSteps = []string{"The configuration is valid. Use get_pipeline_history tool for its version and deploy_pipeline tool to deploy it."}
+			}
+
+			r, err := json.Marshal(PipelineToolResponse{Data: rawData, Guidance: guidance})
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal wrapped response, err: %w", err)
+			}
+
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
+
+// pipelineConflictResult explains a save conflict with the changes made on both sides since the
+// base version. With the yaml output format the current configuration is also returned as a YAML
+// block, to merge the changes into.
+func pipelineConflictResult(ctx context.Context, client Client, conflictErr *PipelineConflictError, content, format string) (*mcp.CallToolResult, error) {
+	response := PipelineConflictResponse{
+		Conflict:       true,
+		ConfID:         conflictErr.ConfID,
+		BaseVersion:    conflictErr.BaseVersion,
+		CurrentVersion: conflictErr.CurrentVersion,
+		Guidance: &PipelineGuidance{
+			ResultStatus: "conflict",
+			NextSteps: []string{
+				"Nothing was saved: the pipeline was changed by someone else after base_version.",
+			},
+		},
+	}
+
+	current, err := GetConf(ctx, client, conflictErr.ConfID)
+	if err != nil {
+		response.Guidance.Suggestions = []string{"Use get_pipeline_config tool to fetch the current configuration, the diff could not be computed: " + err.Error()}
+	} else if base, err := GetPipelineVersionContent(ctx, client, conflictErr.ConfID, conflictErr.BaseVersion); err == nil {
+		response.BaseToCurrent = lineDiff(base, current.Content)
+		response.BaseToYours = lineDiff(base, content)
+		response.Guidance.NextSteps = append(response.Guidance.NextSteps,
+			"diff_base_to_current shows what others changed since base_version, diff_base_to_yours what your content changes ('-' base lines, '+' new lines).",
+			"Apply your changes from diff_base_to_yours to the current configuration, then call save_pipeline again with base_version set to current_version.",
+		)
+	} else {
+		response.Diff = lineDiff(content, current.Content)
+		response.Guidance.NextSteps = append(response.Guidance.NextSteps,
+			"diff_to_current shows how the current configuration differs from your content ('-' your lines, '+' current lines).",
+			"Merge the changes you intend to keep into the current configuration, then call save_pipeline again with base_version set to current_version.",
+		)
+		response.Guidance.Suggestions = []string{"base_version could not be fetched, so the diff cannot tell your changes from the changes of others: " + err.Error()}
+	}
+
+	var currentContent string
+	if current != nil && format == OutputFormatYAML {
+		currentContent = current.Content
+		response.Guidance.NextSteps = append(response.Guidance.NextSteps, "The YAML block is the current configuration.")
+	}
+
+	r, err := json.Marshal(response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal conflict response, err: %w", err)
+	}
+
+	if currentContent != "" {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.NewTextContent(yamlBlock(currentContent)), mcp.NewTextContent(string(r))},
+		}, nil
+	}
+	return mcp.NewToolResultText(string(r)), nil
+}
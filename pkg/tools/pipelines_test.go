@@ -0,0 +1,85 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// conflictingPipelineAPI serves a pipeline whose second version was saved by someone else, with the
+// content of each version in its history. withContent false leaves the content out of the history.
+func conflictingPipelineAPI(t *testing.T, withContent bool) Client {
+	t.Helper()
+
+	const (
+		base    = "inputs:\n  - name: k8s\noutputs:\n  - name: s3\n"
+		current = "inputs:\n  - name: k8s\n  - name: otlp\noutputs:\n  - name: s3\n"
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/orgs/org-1/pipelines/conf-1/history", func(w http.ResponseWriter, r *http.Request) {
+		history := []map[string]any{{"timestamp": 2000}, {"timestamp": 1000}}
+		if withContent {
+			history[0]["content"] = current
+			history[1]["content"] = base
+		}
+		_ = json.NewEncoder(w).Encode(history)
+	})
+	mux.HandleFunc("GET /v1/orgs/org-1/confs/conf-1", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(ConfSummary{ID: "conf-1", Content: current})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return NewHTTPClient(srv.URL, "X-ED-API-Token", WithRateLimits(RateLimits{}))
+}
+
+func TestSavePipelineConflictDiffsFromBase(t *testing.T) {
+	save := func(client Client) PipelineConflictResponse {
+		_, handler := SavePipelineTool(client)
+		ctx := context.WithValue(context.Background(), OrgIDKey, "org-1")
+		ctx = context.WithValue(ctx, EDTokenKey, "token-1")
+		var request mcp.CallToolRequest
+		request.Params.Arguments = map[string]any{
+			"conf_id":      "conf-1",
+			"content":      "inputs:\n  - name: k8s\noutputs:\n  - name: s3\n  - name: splunk\n",
+			"base_version": "1000",
+			"description":  "Add splunk output",
+		}
+		result, err := handler(ctx, request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var response PipelineConflictResponse
+		if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &response); err != nil {
+			t.Fatal(err)
+		}
+		if !response.Conflict || response.CurrentVersion != "2000" {
+			t.Fatalf("response is %+v, want a conflict with version 2000", response)
+		}
+		return response
+	}
+
+	response := save(conflictingPipelineAPI(t, true))
+	if !slices.Contains(response.BaseToCurrent, "+  - name: otlp") || slices.Contains(response.BaseToCurrent, "+  - name: splunk") {
+		t.Errorf("diff from base to current is %q, want only the otlp input added by someone else", response.BaseToCurrent)
+	}
+	if !slices.Contains(response.BaseToYours, "+  - name: splunk") || slices.Contains(response.BaseToYours, "-  - name: otlp") {
+		t.Errorf("diff from base to yours is %q, want only the splunk output added", response.BaseToYours)
+	}
+	if response.Diff != nil {
+		t.Errorf("diff to current is %q, want it left out when the base is known", response.Diff)
+	}
+
+	// Without the base version, the content is diffed against the current configuration
+	response = save(conflictingPipelineAPI(t, false))
+	if response.BaseToCurrent != nil || response.BaseToYours != nil {
+		t.Errorf("response has diffs from the base %+v, want none without the base content", response)
+	}
+	if !slices.Contains(response.Diff, "+  - name: otlp") || len(response.Guidance.Suggestions) == 0 {
+		t.Errorf("response is %+v, want the diff to current and a suggestion", response)
+	}
+}
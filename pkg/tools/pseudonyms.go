@@ -0,0 +1,328 @@
+package tools
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/storage"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Kinds of the identifiers replaced with pseudonyms.
+const (
+	PseudonymOrg  = "org"
+	PseudonymConf = "conf"
+	PseudonymHost = "host"
+)
+
+// pseudonymKeys are the JSON keys whose string values are identifiers, by kind.
+var pseudonymKeys = map[string]string{
+	"org_id":      PseudonymOrg,
+	"orgID":       PseudonymOrg,
+	"conf_id":     PseudonymConf,
+	"confID":      PseudonymConf,
+	"pipeline_id": PseudonymConf,
+	"host.name":   PseudonymHost,
+	"host":        PseudonymHost,
+	"hostname":    PseudonymHost,
+	"host_name":   PseudonymHost,
+}
+
+// minPseudonymized is the length under which values are not replaced, as they would match inside
+// unrelated words.
+const minPseudonymized = 4
+
+var pseudonymPattern = regexp.MustCompile(`\b(?:org|conf|host)-[0-9a-f]{12}\b`)
+
+// pseudonymsTTL is how long the storage keeps the pseudonyms of a caller after they were last
+// shown a new one.
+const pseudonymsTTL = 90 * 24 * time.Hour
+
+type pseudonymEntry struct {
+	Value string `json:"value"`
+	Kind  string `json:"kind"`
+}
+
+// callerPseudonyms are the pseudonyms a caller was shown, mapped to their values.
+type callerPseudonyms map[string]pseudonymEntry
+
+// Pseudonymizer replaces org IDs, pipeline (conf) IDs and host names in tool results with stable
+// pseudonyms such as host-3f2a9c01b7de, for teams pasting transcripts into public tickets. The
+// pseudonyms are HMACs of the values, and the mapping stays on the server, in the storage:
+// pseudonyms in tool arguments are replaced back with the values, so agents keep working with
+// them, and resolve_pseudonym reveals a value to the callers that were shown its pseudonym.
+type Pseudonymizer struct {
+	key   []byte
+	store storage.Interface
+
+	// mu serializes the updates of the pseudonyms of callers in this process
+	mu sync.Mutex
+}
+
+// NewPseudonymizer creates a pseudonymizer deriving pseudonyms with the key and keeping them in the
+// storage. Without a key a random one is used, and pseudonyms change when the server restarts.
+func NewPseudonymizer(key []byte, store storage.Interface) *Pseudonymizer {
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		_, _ = rand.Read(key)
+	}
+	return &Pseudonymizer{key: key, store: store}
+}
+
+func pseudonymsKey(caller string) string {
+	return "pseudonyms/" + caller
+}
+
+// pseudonyms returns the pseudonyms the caller was shown.
+func (p *Pseudonymizer) pseudonyms(ctx context.Context, caller string) (callerPseudonyms, error) {
+	pseudonyms := make(callerPseudonyms)
+	data, err := p.store.Get(ctx, pseudonymsKey(caller))
+	if errors.Is(err, storage.ErrNotFound) {
+		return pseudonyms, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pseudonyms: %w", err)
+	}
+	if err := json.Unmarshal(data, &pseudonyms); err != nil {
+		return nil, fmt.Errorf("failed to decode pseudonyms: %w", err)
+	}
+	return pseudonyms, nil
+}
+
+// pseudonym returns the pseudonym of the value.
+func (p *Pseudonymizer) pseudonym(kind, value string) string {
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(kind + "\x00" + value))
+	return kind + "-" + hex.EncodeToString(mac.Sum(nil))[:12]
+}
+
+// record adds the pseudonyms of the identifiers, by value, to the pseudonyms the caller was
+// shown and returns all of them. Replicas sharing the storage may drop each other's concurrent
+// additions, whose pseudonyms then no longer resolve.
+func (p *Pseudonymizer) record(ctx context.Context, caller string, ids map[string]string) (callerPseudonyms, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pseudonyms, err := p.pseudonyms(ctx, caller)
+	if err != nil {
+		return nil, err
+	}
+
+	added := false
+	for value, kind := range ids {
+		if len(value) < minPseudonymized {
+			continue
+		}
+		pseudonym := p.pseudonym(kind, value)
+		if _, ok := pseudonyms[pseudonym]; !ok {
+			pseudonyms[pseudonym] = pseudonymEntry{Value: value, Kind: kind}
+			added = true
+		}
+	}
+	if !added {
+		return pseudonyms, nil
+	}
+
+	data, err := json.Marshal(pseudonyms)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal pseudonyms: %w", err)
+	}
+	if err := p.store.Set(ctx, pseudonymsKey(caller), data, pseudonymsTTL); err != nil {
+		return nil, fmt.Errorf("failed to save pseudonyms: %w", err)
+	}
+	return pseudonyms, nil
+}
+
+// replacer returns a replacer of the values the caller was shown with their pseudonyms.
+func (pseudonyms callerPseudonyms) replacer() *strings.Replacer {
+	values := make([]string, 0, len(pseudonyms))
+	byValue := make(map[string]string, len(pseudonyms))
+	for pseudonym, entry := range pseudonyms {
+		values = append(values, entry.Value)
+		byValue[entry.Value] = pseudonym
+	}
+	// Longer values first, so a value containing another one is replaced as a whole
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	pairs := make([]string, 0, 2*len(values))
+	for _, value := range values {
+		pairs = append(pairs, value, byValue[value])
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// restore replaces the pseudonyms the caller was shown in the strings of v with their values.
+func (pseudonyms callerPseudonyms) restore(v any) any {
+	switch value := v.(type) {
+	case string:
+		return pseudonymPattern.ReplaceAllStringFunc(value, func(pseudonym string) string {
+			if entry, ok := pseudonyms[pseudonym]; ok {
+				return entry.Value
+			}
+			return pseudonym
+		})
+	case map[string]any:
+		out := make(map[string]any, len(value))
+		for k, item := range value {
+			out[k] = pseudonyms.restore(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(value))
+		for i, item := range value {
+			out[i] = pseudonyms.restore(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// collectIdentifiers adds the identifiers of a decoded JSON document to ids, by value.
+func collectIdentifiers(doc any, ids map[string]string) {
+	switch v := doc.(type) {
+	case map[string]any:
+		// Pipelines are listed by id with their tag and fleet type
+		if id, ok := v["id"].(string); ok && v["tag"] != nil && v["fleet_type"] != nil {
+			ids[id] = PseudonymConf
+		}
+		for key, child := range v {
+			if s, ok := child.(string); ok {
+				if kind, ok := pseudonymKeys[key]; ok {
+					ids[s] = kind
+				}
+				continue
+			}
+			if orgs, ok := child.([]any); ok && key == "orgs" {
+				for _, org := range orgs {
+					if id, ok := org.(map[string]any)["id"].(string); ok {
+						ids[id] = PseudonymOrg
+					}
+				}
+			}
+			collectIdentifiers(child, ids)
+		}
+	case []any:
+		for _, child := range v {
+			collectIdentifiers(child, ids)
+		}
+	}
+}
+
+// Middleware replaces the pseudonyms in the arguments of calls with their values, and the
+// identifiers in the results with pseudonyms: the org of the caller, the values of identifier
+// fields, and every value the caller was shown a pseudonym for before, wherever it appears. Calls
+// fail when the pseudonyms cannot be read or saved, rather than returning the identifiers.
+func (p *Pseudonymizer) Middleware() ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			caller, err := callerKey(ctx)
+			if err != nil || request.Params.Name == "resolve_pseudonym" {
+				return next(ctx, request)
+			}
+
+			pseudonyms, err := p.pseudonyms(ctx, caller)
+			if err != nil {
+				return nil, err
+			}
+			if args, ok := request.Params.Arguments.(map[string]any); ok {
+				request.Params.Arguments = pseudonyms.restore(args)
+			}
+
+			result, err := next(ctx, request)
+			if err != nil || result == nil {
+				return result, err
+			}
+
+			ids := make(map[string]string)
+			if keys, err := FetchContextKeys(ctx); err == nil {
+				ids[keys.OrgID] = PseudonymOrg
+			}
+			for _, content := range result.Content {
+				if text, ok := content.(mcp.TextContent); ok {
+					var doc any
+					if json.Unmarshal([]byte(text.Text), &doc) == nil {
+						collectIdentifiers(doc, ids)
+					}
+				}
+			}
+			pseudonyms, err = p.record(ctx, caller, ids)
+			if err != nil {
+				return nil, err
+			}
+
+			replacer := pseudonyms.replacer()
+			for i, content := range result.Content {
+				if text, ok := content.(mcp.TextContent); ok {
+					text.Text = replacer.Replace(text.Text)
+					result.Content[i] = text
+				}
+			}
+			return result, nil
+		}
+	}
+}
+
+// ResolvedPseudonym is the value of a pseudonym.
+type ResolvedPseudonym struct {
+	Pseudonym string `json:"pseudonym"`
+	Kind      string `json:"kind"`
+	Value     string `json:"value"`
+}
+
+// ResolvePseudonymTool creates a tool revealing the values of pseudonyms
+func ResolvePseudonymTool(p *Pseudonymizer) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("resolve_pseudonym",
+			mcp.WithTitleAnnotation("Resolve Pseudonym"),
+			mcp.WithDescription(`Reveal the org ID, pipeline ID or host name behind a pseudonym such as host-3f2a9c01b7de.
+
+This server replaces these identifiers with pseudonyms in tool results so transcripts can be shared publicly. Pseudonyms can be passed to other tools as is. Only call this tool when the user explicitly asks for the real value, and do not include the value in content meant to be shared.`),
+			mcp.WithString("pseudonym",
+				mcp.Description("Pseudonym to resolve, e.g. host-3f2a9c01b7de"),
+				mcp.Required(),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			pseudonym, err := request.RequireString("pseudonym")
+			if err != nil {
+				return mcp.NewToolResultError("missing required parameter: pseudonym"), nil
+			}
+
+			caller, err := callerKey(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			pseudonyms, err := p.pseudonyms(ctx, caller)
+			if err != nil {
+				return nil, err
+			}
+
+			pseudonym = strings.TrimSpace(pseudonym)
+			entry, ok := pseudonyms[pseudonym]
+			if !ok {
+				return NewToolResultErrorCode(ErrCodeNotFound, fmt.Sprintf("unknown pseudonym %s; only pseudonyms shown to you by this server can be resolved", pseudonym)), nil
+			}
+
+			r, err := json.Marshal(ResolvedPseudonym{Pseudonym: pseudonym, Kind: entry.Kind, Value: entry.Value})
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response, err: %w", err)
+			}
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
@@ -0,0 +1,88 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/storage"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// failingStorage fails every operation, like an unreachable Redis.
+type failingStorage struct{}
+
+var errStorageDown = errors.New("storage is down")
+
+func (failingStorage) Get(context.Context, string) ([]byte, error) { return nil, errStorageDown }
+func (failingStorage) Set(context.Context, string, []byte, time.Duration) error {
+	return errStorageDown
+}
+func (failingStorage) Delete(context.Context, string) error { return errStorageDown }
+func (failingStorage) List(context.Context, string) ([]string, error) {
+	return nil, errStorageDown
+}
+
+func pseudonymContext() context.Context {
+	ctx := context.WithValue(context.Background(), OrgIDKey, "org-1234")
+	return context.WithValue(ctx, EDTokenKey, "token-1")
+}
+
+func TestPseudonymizerSharedStorage(t *testing.T) {
+	store := storage.NewMemory()
+	key := []byte("pseudonym-key")
+	var seen string
+	handler := NewPseudonymizer(key, store).Middleware()(func(_ context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		seen, _ = request.GetArguments()["host"].(string)
+		return mcp.NewToolResultText(`{"host.name":"web-server-01"}`), nil
+	})
+
+	var request mcp.CallToolRequest
+	request.Params.Name = "get_log_search"
+	result, err := handler(pseudonymContext(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if strings.Contains(text, "web-server-01") {
+		t.Fatalf("result %s has the host name", text)
+	}
+	pseudonym := pseudonymPattern.FindString(text)
+
+	// Another replica sharing the storage resolves the pseudonym
+	_, resolve := ResolvePseudonymTool(NewPseudonymizer(key, store))
+	request.Params.Name = "resolve_pseudonym"
+	request.Params.Arguments = map[string]any{"pseudonym": pseudonym}
+	result, err = resolve(pseudonymContext(), request)
+	if err != nil || result.IsError {
+		t.Fatalf("resolve_pseudonym failed: %v %v", err, result)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "web-server-01") {
+		t.Errorf("resolve_pseudonym returned %s, want the host name", text)
+	}
+
+	// and restores it in the arguments of calls
+	request.Params.Name = "get_log_search"
+	request.Params.Arguments = map[string]any{"host": pseudonym}
+	if _, err := handler(pseudonymContext(), request); err != nil {
+		t.Fatal(err)
+	}
+	if seen != "web-server-01" {
+		t.Errorf("tool got the host %q, want the host name", seen)
+	}
+}
+
+func TestPseudonymizerFailsClosed(t *testing.T) {
+	handler := NewPseudonymizer(nil, failingStorage{}).Middleware()(func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText(`{"host.name":"web-server-01"}`), nil
+	})
+
+	var request mcp.CallToolRequest
+	request.Params.Name = "get_log_search"
+	result, err := handler(pseudonymContext(), request)
+	if err == nil {
+		t.Errorf("call returned %v without its pseudonyms, want an error", result.Content)
+	}
+}
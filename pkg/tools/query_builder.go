@@ -5,20 +5,27 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
 type CQLValidationResult struct {
-	Valid           bool                `json:"valid"`
-	NormalizedQuery string              `json:"normalized_query,omitempty"`
-	Errors          []string            `json:"errors,omitempty"`
-	Warnings        []string            `json:"warnings,omitempty"`
-	Suggestions     []string            `json:"suggestions,omitempty"`
-	SyntaxReference string              `json:"syntax_reference,omitempty"`
-	Guidance        *ValidationGuidance `json:"guidance,omitempty"`
+	Valid           bool     `json:"valid"`
+	NormalizedQuery string   `json:"normalized_query,omitempty"`
+	Errors          []string `json:"errors,omitempty"`
+	Warnings        []string `json:"warnings,omitempty"`
+	Suggestions     []string `json:"suggestions,omitempty"`
+	// UnknownFields are the fields of the query that are not common facet keys of the scope
+	UnknownFields []string `json:"unknown_fields,omitempty"`
+	// FieldSuggestions are the closest facet keys of the scope to the unknown fields that do not exist
+	FieldSuggestions map[string][]string `json:"field_suggestions,omitempty"`
+	SyntaxReference  string              `json:"syntax_reference,omitempty"`
+	Guidance         *ValidationGuidance `json:"guidance,omitempty"`
 }
 
 type CQLBuildResult struct {
@@ -46,6 +53,14 @@ var (
 
 const AttributeLabelPrefix = "@"
 
+const (
+	// maxFieldSuggestions is the number of facet keys suggested for an unknown field
+	maxFieldSuggestions = 5
+	// fieldSuggestionTimeout bounds the facet keys lookup of validate_cql, the validation is
+	// returned without suggestions when it takes longer
+	fieldSuggestionTimeout = 5 * time.Second
+)
+
 // CommonFacetKeys contains known facet keys for each scope.
 // Keep this list MINIMAL for progressive discovery.
 // LLMs should use facet_options to discover other fields.
@@ -58,7 +73,7 @@ var CommonFacetKeys = map[string][]string{
 }
 
 // GetValidateCQLTool creates a tool to validate CQL queries before execution
-func GetValidateCQLTool() (tool mcp.Tool, handler server.ToolHandlerFunc) {
+func GetValidateCQLTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
 	return mcp.NewTool("validate_cql",
 			mcp.WithTitleAnnotation("Validate CQL Query"),
 			mcp.WithDescription(`Validates a CQL (Common Query Language) query BEFORE executing search.
@@ -82,7 +97,7 @@ NOT SUPPORTED:
 - Regular expressions (e.g., /pattern/)
 - Wildcards in middle of strings (e.g., "err*or")
 
-Returns validation result with errors, warnings, and suggestions for fixes.`),
+Returns validation result with errors, warnings, and suggestions for fixes. Fields that are not common facet keys are checked against the facet keys of the scope: field_suggestions lists the closest existing keys of the fields that do not exist.`),
 			mcp.WithString("query",
 				mcp.Description("The CQL query to validate"),
 				mcp.Required(),
@@ -109,6 +124,15 @@ Returns validation result with errors, warnings, and suggestions for fixes.`),
 			}
 
 			result := validateCQL(query, scope)
+			if len(result.UnknownFields) > 0 {
+				keysCtx, cancel := context.WithTimeout(ctx, fieldSuggestionTimeout)
+				facetKeys, err := GetFacetKeys(keysCtx, client, scope)
+				cancel()
+				// Without the facet keys the warnings stay as they are
+				if err == nil {
+					suggestFields(&result, scope, facetKeys)
+				}
+			}
 			r, _ := json.Marshal(result)
 			return mcp.NewToolResultText(string(r)), nil
 		}
@@ -260,8 +284,8 @@ func validateCQL(query, scope string) CQLValidationResult {
 					}
 				}
 				if !isKnown && !strings.HasPrefix(fieldName, "@") {
-					result.Warnings = append(result.Warnings,
-						fmt.Sprintf("Field '%s' is not a commonly known facet for scope '%s'. Use facet_options tool to verify this field exists.", fieldName, scope))
+					result.UnknownFields = append(result.UnknownFields, fieldName)
+					result.Warnings = append(result.Warnings, unknownFieldWarning(fieldName, scope))
 				}
 			}
 		}
@@ -390,6 +414,103 @@ func buildCQL(scope string, filters map[string]any) CQLBuildResult {
 	return result
 }
 
+func unknownFieldWarning(field, scope string) string {
+	return fmt.Sprintf("Field '%s' is not a commonly known facet for scope '%s'. Use facet_options tool to verify this field exists.", field, scope)
+}
+
+// suggestFields checks the unknown fields of a validation against the facet keys of the scope:
+// the warnings of the fields that exist are dropped, and the warnings of the others name the
+// closest keys.
+func suggestFields(result *CQLValidationResult, scope string, facetKeys []FacetKey) {
+	keys := make([]string, 0, len(facetKeys))
+	for _, facetKey := range facetKeys {
+		keys = append(keys, facetKey.Key)
+	}
+
+	replacements := make(map[string]string, len(result.UnknownFields))
+	for _, field := range result.UnknownFields {
+		if slices.ContainsFunc(keys, func(key string) bool { return strings.EqualFold(key, field) }) {
+			replacements[unknownFieldWarning(field, scope)] = ""
+			continue
+		}
+
+		closest := closestKeys(field, keys, maxFieldSuggestions)
+		if len(closest) == 0 {
+			replacements[unknownFieldWarning(field, scope)] = fmt.Sprintf("Field '%s' does not exist for scope '%s'. Use facet_options tool to list the available fields.", field, scope)
+			continue
+		}
+		if result.FieldSuggestions == nil {
+			result.FieldSuggestions = make(map[string][]string)
+		}
+		result.FieldSuggestions[field] = closest
+		replacements[unknownFieldWarning(field, scope)] = fmt.Sprintf("Field '%s' does not exist for scope '%s'. Did you mean %s?", field, scope, strings.Join(closest, ", "))
+	}
+
+	warnings := result.Warnings[:0]
+	for _, warning := range result.Warnings {
+		if replacement, ok := replacements[warning]; ok {
+			if replacement == "" {
+				continue
+			}
+			warning = replacement
+		}
+		warnings = append(warnings, warning)
+	}
+	result.Warnings = warnings
+}
+
+// closestKeys returns up to limit keys ranked by their edit distance to the field, ignoring case.
+// Keys that share nothing with the field, i.e. that are at least as far as the field is long,
+// are left out.
+func closestKeys(field string, keys []string, limit int) []string {
+	type candidate struct {
+		key      string
+		distance int
+	}
+
+	field = strings.ToLower(field)
+	var candidates []candidate
+	for _, key := range keys {
+		if d := editDistance(field, strings.ToLower(key)); d < len(field) {
+			candidates = append(candidates, candidate{key: key, distance: d})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].key < candidates[j].key
+	})
+
+	closest := make([]string, 0, min(limit, len(candidates)))
+	for _, c := range candidates[:min(limit, len(candidates))] {
+		closest = append(closest, c.key)
+	}
+	return closest
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
 func escapeValue(s string) string {
 	s = strings.ReplaceAll(s, "\\", "\\\\")
 	s = strings.ReplaceAll(s, "\"", "\\\"")
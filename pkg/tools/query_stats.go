@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Queries over these are slow enough to suggest narrowing them.
+const (
+	slowQueryExecution = 10 * time.Second
+	largeQueryScan     = 10 << 30
+)
+
+// QueryStats are the execution statistics the API reports for a query, in query_stats of its
+// response, when available.
+type QueryStats struct {
+	ScannedBytes     int64 `json:"scanned_bytes,omitempty"`
+	EvaluatedRecords int64 `json:"evaluated_records,omitempty"`
+	ExecutionMS      int64 `json:"execution_ms,omitempty"`
+	Shards           int   `json:"shards,omitempty"`
+}
+
+// add adds the stats of another query run in parallel for the same result, e.g. another shard.
+func (s *QueryStats) add(other QueryStats) {
+	s.ScannedBytes += other.ScannedBytes
+	s.EvaluatedRecords += other.EvaluatedRecords
+	s.ExecutionMS = max(s.ExecutionMS, other.ExecutionMS)
+	s.Shards += other.Shards
+}
+
+// parseQueryStats returns the query stats of a response, summed over the sub-queries of formula
+// responses, e.g. {"A": {"records": [...], "query_stats": {...}}}. It returns nil if the response
+// has none.
+func parseQueryStats(bodyBytes []byte) *QueryStats {
+	var response map[string]json.RawMessage
+	if err := json.Unmarshal(bodyBytes, &response); err != nil {
+		return nil
+	}
+
+	var stats QueryStats
+	if json.Unmarshal(response["query_stats"], &stats) == nil && stats != (QueryStats{}) {
+		return &stats
+	}
+
+	found := false
+	for key, value := range response {
+		if key == "query_stats" {
+			continue
+		}
+		var sub struct {
+			Stats *QueryStats `json:"query_stats"`
+		}
+		if json.Unmarshal(value, &sub) == nil && sub.Stats != nil {
+			stats.add(*sub.Stats)
+			found = true
+		}
+	}
+	if !found {
+		return nil
+	}
+	return &stats
+}
+
+// queryStatsSuggestions returns how to speed up a query the stats show to be slow or large.
+func queryStatsSuggestions(stats *QueryStats) []string {
+	if stats == nil {
+		return nil
+	}
+
+	execution := time.Duration(stats.ExecutionMS) * time.Millisecond
+	if execution < slowQueryExecution && stats.ScannedBytes < largeQueryScan {
+		return nil
+	}
+	return []string{fmt.Sprintf("The query scanned %s in %s; narrow the time range or add field filters such as service.name to make it faster.",
+		formatBytes(stats.ScannedBytes), execution.Round(100*time.Millisecond))}
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,165 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit is a rate of requests with bursts, e.g. 10 requests per second with bursts of 20.
+type RateLimit struct {
+	RPS   float64
+	Burst int
+}
+
+// Enabled reports whether the limit is set.
+func (l RateLimit) Enabled() bool {
+	return l.RPS > 0 && l.Burst > 0
+}
+
+// budget returns the limit as a token bucket budget, Burst calls refilled at RPS.
+func (l RateLimit) budget() ToolBudget {
+	return ToolBudget{Calls: l.Burst, Per: time.Duration(float64(l.Burst) / l.RPS * float64(time.Second))}
+}
+
+// RateLimits configures how HTTPClient limits the rate of its requests per org, so that agents
+// making many parallel tool calls stay under the org-level rate limits of the API.
+type RateLimits struct {
+	// Default limits every request of an org
+	Default RateLimit
+	// Endpoints limits the requests to expensive endpoints of an org on top of Default, by the
+	// path after /v1/orgs/{org_id}, e.g. /graph
+	Endpoints map[string]RateLimit
+}
+
+// DefaultRateLimits are the rate limits of HTTP clients.
+var DefaultRateLimits = RateLimits{
+	Default: RateLimit{RPS: 20, Burst: 40},
+	Endpoints: map[string]RateLimit{
+		"/graph":                 {RPS: 2, Burst: 5},
+		"/logs/log_search/graph": {RPS: 2, Burst: 5},
+	},
+}
+
+// WithRateLimits sets the rate limits of the client, DefaultRateLimits by default
+func WithRateLimits(limits RateLimits) HTTPClientOption {
+	return func(c *HTTPClient) {
+		c.limiter = newRateLimiter(limits)
+	}
+}
+
+// SetRateLimits changes the rate limits of the client while it is in use.
+func (c *HTTPClient) SetRateLimits(limits RateLimits) {
+	c.limiter.setLimits(limits)
+}
+
+// ParseEndpointRateLimits parses per endpoint limits in the form "/graph=2/5,/traces=5/10", a
+// rate in requests per second and a burst.
+func ParseEndpointRateLimits(s string) (map[string]RateLimit, error) {
+	limits := make(map[string]RateLimit)
+	for _, entry := range strings.Split(s, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+
+		endpoint, limit, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate limit entry %q, expected <path>=<rps>/<burst>", entry)
+		}
+		rps, burst, ok := strings.Cut(limit, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate limit entry %q, expected <path>=<rps>/<burst>", entry)
+		}
+
+		r, err := strconv.ParseFloat(strings.TrimSpace(rps), 64)
+		if err != nil || r <= 0 {
+			return nil, fmt.Errorf("invalid rate for endpoint %s: %q", endpoint, rps)
+		}
+		b, err := strconv.Atoi(strings.TrimSpace(burst))
+		if err != nil || b <= 0 {
+			return nil, fmt.Errorf("invalid burst for endpoint %s: %q", endpoint, burst)
+		}
+		limits[strings.TrimSpace(endpoint)] = RateLimit{RPS: r, Burst: b}
+	}
+	return limits, nil
+}
+
+// orgPathPattern splits the path of an API request into the org and the endpoint.
+var orgPathPattern = regexp.MustCompile(`^/v1/orgs/([^/]+)(/.*)?$`)
+
+// rateLimiter holds a token bucket per org, and per org and expensive endpoint.
+type rateLimiter struct {
+	mu      sync.Mutex
+	limits  RateLimits
+	buckets map[string]*tokenBucket
+}
+
+func newRateLimiter(limits RateLimits) *rateLimiter {
+	return &rateLimiter{limits: limits, buckets: make(map[string]*tokenBucket)}
+}
+
+// wait blocks until the request may be sent under the limits of its org and endpoint, or the
+// context of the request is done.
+func (l *rateLimiter) wait(req *http.Request) error {
+	if l == nil {
+		return nil
+	}
+	m := orgPathPattern.FindStringSubmatch(req.URL.Path)
+	if m == nil {
+		return nil
+	}
+	orgID, endpoint := m[1], strings.TrimSuffix(m[2], "/")
+
+	l.mu.Lock()
+	limits := l.limits
+	l.mu.Unlock()
+
+	if limit, ok := limits.Endpoints[endpoint]; ok && limit.Enabled() {
+		if err := l.take(req.Context(), orgID+"\x00"+endpoint, limit); err != nil {
+			return err
+		}
+	}
+	if limits.Default.Enabled() {
+		return l.take(req.Context(), orgID, limits.Default)
+	}
+	return nil
+}
+
+// setLimits replaces the limits. The buckets are dropped, so they start full at the new bursts.
+func (l *rateLimiter) setLimits(limits RateLimits) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limits = limits
+	clear(l.buckets)
+}
+
+func (l *rateLimiter) take(ctx context.Context, key string, limit RateLimit) error {
+	budget := limit.budget()
+	for {
+		now := time.Now()
+		l.mu.Lock()
+		bucket, ok := l.buckets[key]
+		if !ok {
+			bucket = &tokenBucket{Tokens: float64(limit.Burst), Updated: now}
+			l.buckets[key] = bucket
+		}
+		allowed, delay := bucket.take(budget, now)
+		l.mu.Unlock()
+		if allowed {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("failed to wait for the client rate limit of the Edge Delta API, err: %w", ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/storage"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	maxRecentQueries = 20
+	// queryHistoryTTL is how long the storage keeps the history of a user after their last query.
+	queryHistoryTTL = 90 * 24 * time.Hour
+)
+
+// RecentQuery is a query a user ran successfully.
+type RecentQuery struct {
+	Tool     string    `json:"tool"`
+	Query    string    `json:"query"`
+	Scope    string    `json:"scope,omitempty"`
+	Lookback string    `json:"lookback,omitempty"`
+	LastUsed time.Time `json:"last_used"`
+	// Key identifies the tool, query and scope of the query, see ArgumentCanonicalizer.Key, so
+	// that queries differing only in whitespace or a default scope are recorded once
+	Key string `json:"key,omitempty"`
+}
+
+// sameQuery reports whether two recorded queries are the same query.
+func (q RecentQuery) sameQuery(other RecentQuery) bool {
+	if q.Key != "" && other.Key != "" {
+		return q.Key == other.Key
+	}
+	return q.Tool == other.Tool && q.Query == other.Query && q.Scope == other.Scope
+}
+
+type RecentQueriesResourceResponse struct {
+	Queries    []RecentQuery `json:"queries"`
+	UsageNotes string        `json:"usage_notes"`
+}
+
+var RecentQueriesResource = mcp.NewResource(
+	"recent-queries://mine",
+	"Recent Queries",
+	mcp.WithResourceDescription(`Queries you ran successfully in previous sessions, most recent first.
+Read it at the start of a session to pick up where you left off.`),
+	mcp.WithMIMEType("application/json"),
+)
+
+// QueryHistory keeps the last successful queries of every user, keyed by their caller identity,
+// see callerIdentity, so tokens are never stored and a refreshed OAuth token keeps its history.
+// It is optionally persisted to a JSON file, or kept in a storage.
+type QueryHistory struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string][]RecentQuery
+	// store keeps the history instead of entries if set
+	store storage.Interface
+}
+
+// NewQueryHistory creates a query history. If path is not empty the history is loaded from and
+// saved to that file.
+func NewQueryHistory(path string) (*QueryHistory, error) {
+	h := &QueryHistory{
+		path:    path,
+		entries: make(map[string][]RecentQuery),
+	}
+
+	if path == "" {
+		return h, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read query history: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &h.entries); err != nil {
+		return nil, fmt.Errorf("failed to decode query history %s: %w", path, err)
+	}
+	return h, nil
+}
+
+// NewStoredQueryHistory creates a query history kept in the storage, e.g. to share it between the
+// replicas of the server.
+func NewStoredQueryHistory(store storage.Interface) *QueryHistory {
+	return &QueryHistory{store: store}
+}
+
+// Record adds a query to the front of the user's history, moving it there if it was already present.
+func (h *QueryHistory) Record(ctx context.Context, userKey string, query RecentQuery) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	previous, err := h.recent(ctx, userKey)
+	if err != nil {
+		return err
+	}
+
+	queries := []RecentQuery{query}
+	for _, q := range previous {
+		if q.sameQuery(query) {
+			continue
+		}
+		if len(queries) == maxRecentQueries {
+			break
+		}
+		queries = append(queries, q)
+	}
+
+	if h.store != nil {
+		data, err := json.Marshal(queries)
+		if err != nil {
+			return fmt.Errorf("failed to marshal query history: %w", err)
+		}
+		if err := h.store.Set(ctx, queryHistoryKey(userKey), data, queryHistoryTTL); err != nil {
+			return fmt.Errorf("failed to save query history: %w", err)
+		}
+		return nil
+	}
+
+	h.entries[userKey] = queries
+	return h.save()
+}
+
+// Recent returns the user's history, most recent first.
+func (h *QueryHistory) Recent(ctx context.Context, userKey string) ([]RecentQuery, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.recent(ctx, userKey)
+}
+
+// recent returns the user's history. Callers must hold h.mu.
+func (h *QueryHistory) recent(ctx context.Context, userKey string) ([]RecentQuery, error) {
+	if h.store == nil {
+		return append([]RecentQuery{}, h.entries[userKey]...), nil
+	}
+
+	data, err := h.store.Get(ctx, queryHistoryKey(userKey))
+	if errors.Is(err, storage.ErrNotFound) {
+		return []RecentQuery{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read query history: %w", err)
+	}
+
+	queries := []RecentQuery{}
+	if err := json.Unmarshal(data, &queries); err != nil {
+		return nil, fmt.Errorf("failed to decode query history: %w", err)
+	}
+	return queries, nil
+}
+
+func queryHistoryKey(userKey string) string {
+	return "recent-queries/" + userKey
+}
+
+// save writes the history to its file. Callers must hold h.mu.
+func (h *QueryHistory) save() error {
+	if h.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(h.entries)
+	if err != nil {
+		return fmt.Errorf("failed to marshal query history: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(h.path), filepath.Base(h.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to save query history: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save query history: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save query history: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), h.path)
+}
+
+// callerKey identifies the caller by a hash of their org and token, so tokens are never kept.
+func callerKey(ctx context.Context) (string, error) {
+	keys, err := FetchContextKeys(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	token := keys.BearerToken
+	if token == "" {
+		token = keys.EDToken
+	}
+
+	sum := sha256.Sum256([]byte(keys.OrgID + ":" + token))
+	return hex.EncodeToString(sum[:16]), nil
+}
+
+// RecordQueries returns a tool middleware that adds the query of every successful tool call to
+// the caller's history.
+func RecordQueries(history *QueryHistory, canonicalizer *ArgumentCanonicalizer) ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			result, err := next(ctx, request)
+			if err != nil || result == nil || result.IsError {
+				return result, err
+			}
+
+			args := request.GetArguments()
+			query, _ := args["query"].(string)
+			if query == "" {
+				return result, err
+			}
+
+			userKey, keyErr := callerIdentity(ctx)
+			if keyErr != nil {
+				return result, err
+			}
+
+			scope, _ := args["scope"].(string)
+			lookback, _ := args["lookback"].(string)
+
+			// The lookback is left out of the key, running a query again over another period
+			// moves it to the front
+			keyRequest := mcp.CallToolRequest{}
+			keyRequest.Params.Name = request.Params.Name
+			keyRequest.Params.Arguments = map[string]any{"query": query, "scope": scope}
+			key, _ := canonicalizer.Key(keyRequest)
+
+			// Failing to persist the history must not fail the tool call itself.
+			_ = history.Record(ctx, userKey, RecentQuery{
+				Tool:     request.Params.Name,
+				Query:    strings.TrimSpace(query),
+				Scope:    scope,
+				Lookback: lookback,
+				LastUsed: time.Now().UTC(),
+				Key:      key,
+			})
+
+			return result, err
+		}
+	}
+}
+
+func RecentQueriesResourceHandler(history *QueryHistory) server.ResourceHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		userKey, err := callerIdentity(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		queries, err := history.Recent(ctx, userKey)
+		if err != nil {
+			return nil, err
+		}
+
+		response := RecentQueriesResourceResponse{
+			Queries: queries,
+			UsageNotes: `Re-run a query with the tool it was used with, or offer the most recent ones to the user as a starting point.
+Queries are recorded only when the tool call succeeded.`,
+		}
+
+		result, err := json.Marshal(response)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal recent queries: %w", err)
+		}
+
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      request.Params.URI,
+				MIMEType: "application/json",
+				Text:     string(result),
+			},
+		}, nil
+	}
+}
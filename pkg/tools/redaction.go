@@ -0,0 +1,199 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// DefaultRedactionPatterns match common credentials. Patterns with a group redact only what the
+// first group matches, e.g. the value of password=..., and the whole match otherwise.
+var DefaultRedactionPatterns = []string{
+	// AWS access key IDs
+	`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+	// AWS secret access keys
+	`(?i)aws_?secret_?access_?key\\?["']?\s*[:=]\s*\\?["']?([A-Za-z0-9/+=]{40})`,
+	// Bearer tokens, e.g. of Authorization headers
+	`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]{16,}=*)`,
+	// Values of secret keys, also in JSON documents and in logs embedded in JSON strings
+	`(?i)\b(?:api[_-]?key|api[_-]?token|access[_-]?token|auth[_-]?token|token|client[_-]?secret|secret|password|passwd|pwd)\\?["']?\s*[:=]\s*\\?["']?([^\s"'\\,;&]{4,})`,
+	// GitHub tokens
+	`\bgh[pousr]_[A-Za-z0-9]{36,}\b`,
+	// Slack tokens
+	`\bxox[abprs]-[A-Za-z0-9-]{10,}`,
+	// JSON web tokens
+	`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`,
+	// PEM private keys
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`,
+}
+
+// RedactionExemptTools return credentials on purpose, their results are not redacted.
+var RedactionExemptTools = map[string]bool{
+	"get_ingestion_endpoint": true,
+}
+
+// Redactor masks the values matching its patterns.
+type Redactor struct {
+	patterns []*regexp.Regexp
+}
+
+func NewRedactor(patterns []string) (*Redactor, error) {
+	r := &Redactor{patterns: make([]*regexp.Regexp, 0, len(patterns))}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// LoadRedactionPatterns reads patterns from a file, one per line. Empty lines and lines starting
+// with # are skipped.
+func LoadRedactionPatterns(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redaction patterns: %w", err)
+	}
+	defer file.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			patterns = append(patterns, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read redaction patterns: %w", err)
+	}
+	return patterns, nil
+}
+
+// Redact returns s with the matches of the patterns replaced with [REDACTED].
+func (r *Redactor) Redact(s string) string {
+	for _, re := range r.patterns {
+		matches := re.FindAllStringSubmatchIndex(s, -1)
+		if matches == nil {
+			continue
+		}
+
+		var b strings.Builder
+		last := 0
+		for _, m := range matches {
+			start, end := m[0], m[1]
+			if len(m) >= 4 && m[2] >= 0 {
+				start, end = m[2], m[3]
+			}
+			b.WriteString(s[last:start])
+			b.WriteString(redacted)
+			last = end
+		}
+		b.WriteString(s[last:])
+		s = b.String()
+	}
+	return s
+}
+
+// Middleware redacts the text of tool results, including error results, except for the
+// RedactionExemptTools.
+func (r *Redactor) Middleware() ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			result, err := next(ctx, request)
+			if RedactionExemptTools[request.Params.Name] {
+				return result, err
+			}
+			if result != nil {
+				for i, content := range result.Content {
+					if text, ok := content.(mcp.TextContent); ok {
+						text.Text = r.Redact(text.Text)
+						result.Content[i] = text
+					}
+				}
+			}
+			if err != nil {
+				err = redactedError{err: err, message: r.Redact(err.Error())}
+			}
+			return result, err
+		}
+	}
+}
+
+// redactedError keeps the wrapped error for errors.Is and errors.As, with a redacted message.
+type redactedError struct {
+	err     error
+	message string
+}
+
+func (e redactedError) Error() string { return e.message }
+
+func (e redactedError) Unwrap() error { return e.err }
+
+// NewRedactingHandler returns a log handler redacting the messages and the attribute values of
+// the records before passing them to h.
+func NewRedactingHandler(h slog.Handler, r *Redactor) slog.Handler {
+	return &redactingHandler{next: h, redactor: r}
+}
+
+type redactingHandler struct {
+	next     slog.Handler
+	redactor *Redactor
+}
+
+func (h *redactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *redactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	redactedRecord := slog.NewRecord(record.Time, record.Level, h.redactor.Redact(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redactedRecord.AddAttrs(h.redactAttr(attr))
+		return true
+	})
+	return h.next.Handle(ctx, redactedRecord)
+}
+
+func (h *redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redactedAttrs := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redactedAttrs[i] = h.redactAttr(attr)
+	}
+	return &redactingHandler{next: h.next.WithAttrs(redactedAttrs), redactor: h.redactor}
+}
+
+func (h *redactingHandler) WithGroup(name string) slog.Handler {
+	return &redactingHandler{next: h.next.WithGroup(name), redactor: h.redactor}
+}
+
+func (h *redactingHandler) redactAttr(attr slog.Attr) slog.Attr {
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, h.redactor.Redact(value.String()))
+	case slog.KindGroup:
+		group := value.Group()
+		attrs := make([]any, len(group))
+		for i, groupAttr := range group {
+			attrs[i] = h.redactAttr(groupAttr)
+		}
+		return slog.Group(attr.Key, attrs...)
+	case slog.KindAny:
+		// Errors and other values are logged with their string form
+		if err, ok := value.Any().(error); ok {
+			return slog.String(attr.Key, h.redactor.Redact(err.Error()))
+		}
+		if stringer, ok := value.Any().(fmt.Stringer); ok {
+			return slog.String(attr.Key, h.redactor.Redact(stringer.String()))
+		}
+	}
+	return slog.Attr{Key: attr.Key, Value: value}
+}
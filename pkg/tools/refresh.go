@@ -0,0 +1,324 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	refreshQueryIDPrefix   = "refresh."
+	defaultRefreshLookback = 5 * time.Minute
+	defaultRefreshLimit    = 100
+	maxRefreshLimit        = 1000
+	// refreshOverlap is searched again by every refresh, to pick up records ingested late.
+	refreshOverlap = 30 * time.Second
+	// maxRefreshSeen bounds the records of the overlap window kept in a query id.
+	maxRefreshSeen = 200
+)
+
+// refreshSources are the searches refresh_search can follow.
+var refreshSources = map[string]func(context.Context, Client, url.Values) ([]byte, error){
+	"logs":   searchLogs,
+	"events": searchEvents,
+	"traces": searchTraces,
+}
+
+// RefreshState is the position of a refreshed search, returned as its query id: the timestamp of
+// the newest record returned and the hashes of the records of the overlap window before it. Start
+// is the start of the first search, the overlap window does not reach before it.
+type RefreshState struct {
+	Source string    `json:"source"`
+	Query  string    `json:"query,omitempty"`
+	Start  time.Time `json:"start"`
+	Since  time.Time `json:"since"`
+	Seen   []string  `json:"seen,omitempty"`
+}
+
+func (s RefreshState) encode() string {
+	data, _ := json.Marshal(s)
+	return refreshQueryIDPrefix + base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeRefreshState(queryID string) (RefreshState, error) {
+	var s RefreshState
+	if !strings.HasPrefix(queryID, refreshQueryIDPrefix) {
+		return s, fmt.Errorf("invalid query_id, pass the query_id of a previous refresh_search result")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(queryID, refreshQueryIDPrefix))
+	if err != nil {
+		return s, fmt.Errorf("invalid query_id: %w", err)
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("invalid query_id: %w", err)
+	}
+	return s, nil
+}
+
+type RefreshSearchResult struct {
+	Source     string          `json:"source"`
+	Query      string          `json:"query_used,omitempty"`
+	From       string          `json:"from"`
+	To         string          `json:"to"`
+	Items      []any           `json:"items"`
+	TotalCount int             `json:"total_count"`
+	HasMore    bool            `json:"has_more,omitempty"`
+	QueryID    string          `json:"query_id"`
+	Guidance   *SearchGuidance `json:"guidance,omitempty"`
+}
+
+// RefreshSearchTool creates a tool returning the records newer than the ones already seen
+func RefreshSearchTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("refresh_search",
+			mcp.WithTitleAnnotation("Refresh Search"),
+			mcp.WithDescription(`Returns only the logs, events or spans matching a query that are newer than the ones already seen, for cheap "anything new?" follow-ups during an incident.
+
+The first call returns the records of the lookback (5m by default), or the ones after since, e.g. the timestamp of the newest record of a previous get_log_search result.
+Every result has a query_id; pass it to the next call to get the records that arrived in between, without repeating the ones already returned.
+Records ingested late, up to 30s before the newest record returned, are picked up by the next refresh.`),
+			mcp.WithString("source",
+				mcp.Description("Records to search."),
+				mcp.Enum("logs", "events", "traces"),
+				mcp.DefaultString("logs"),
+			),
+			mcp.WithString("query",
+				mcp.Description(`CQL query of the search, e.g. service.name:"checkout" AND severity_text:"ERROR". Defaults to the query of query_id.`),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("query_id",
+				mcp.Description("query_id of the previous refresh_search result, to get the records newer than the ones it returned."),
+			),
+			mcp.WithString("since",
+				mcp.Description("Return the records after this ISO-8601 timestamp, for the first call, e.g. 2024-06-01T10:00:00.000Z."),
+			),
+			mcp.WithString("lookback",
+				mcp.Description("Time range of the first call when since is not set, e.g. 5m, 15m."),
+				mcp.DefaultString("5m"),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description(fmt.Sprintf("Maximum number of new records returned, oldest first. Default is %d, max is %d.", defaultRefreshLimit, maxRefreshLimit)),
+				mcp.DefaultNumber(defaultRefreshLimit),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(false),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			source, _ := params.Optional[string](request, "source")
+			if source == "" {
+				source = "logs"
+			}
+			search, ok := refreshSources[source]
+			if !ok {
+				return mcp.NewToolResultError(fmt.Sprintf(`invalid source %q, expected "logs", "events" or "traces"`, source)), nil
+			}
+			query, _ := params.Optional[string](request, "query")
+
+			limit := request.GetInt("limit", defaultRefreshLimit)
+			if limit <= 0 {
+				limit = defaultRefreshLimit
+			}
+			limit = min(limit, maxRefreshLimit)
+
+			now := time.Now().UTC()
+			state := RefreshState{Source: source, Query: query}
+			from := now.Add(-defaultRefreshLookback)
+			// after is set when the records at since were seen already, but are not in the state
+			var after time.Time
+			if queryID, _ := params.Optional[string](request, "query_id"); queryID != "" {
+				previous, err := decodeRefreshState(queryID)
+				if err != nil {
+					return mcp.NewToolResultError(err.Error()), nil
+				}
+				if query == "" {
+					state.Query = previous.Query
+				}
+				if previous.Source != state.Source || previous.Query != state.Query {
+					return mcp.NewToolResultError(fmt.Sprintf("query_id was returned for the %s query %q, not for the %s query %q; pass the same source and query, or leave query_id out to start over",
+						previous.Source, previous.Query, state.Source, state.Query)), nil
+				}
+				state.Start, state.Since, state.Seen = previous.Start, previous.Since, previous.Seen
+				from = previous.Since.Add(-refreshOverlap)
+				if from.Before(previous.Start) {
+					from = previous.Start
+				}
+			} else if since, _ := params.Optional[string](request, "since"); since != "" {
+				t, err := time.Parse(time.RFC3339Nano, since)
+				if err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("invalid since %q, expected an ISO-8601 timestamp such as 2024-06-01T10:00:00.000Z", since)), nil
+				}
+				state.Since, from, after = t.UTC(), t.UTC(), t.UTC()
+			} else if lookback, _ := params.Optional[string](request, "lookback"); lookback != "" {
+				d, err := ParseLookback(lookback)
+				if err != nil {
+					return mcp.NewToolResultError(err.Error()), nil
+				}
+				from = now.Add(-d)
+			}
+			if state.Since.IsZero() {
+				state.Start, state.Since = from, from
+			} else if state.Start.IsZero() {
+				state.Start = state.Since
+			}
+
+			queryParams := url.Values{}
+			if state.Query != "" {
+				queryParams.Add("query", state.Query)
+			}
+			queryParams.Add("to", now.Format(isoTimeLayout))
+			queryParams.Add("limit", strconv.Itoa(limit))
+			queryParams.Add("order", "asc")
+
+			var items []map[string]any
+			for _, start := range []time.Time{from, state.Since} {
+				queryParams.Set("from", start.Format(isoTimeLayout))
+				bodyBytes, err := search(ctx, client, queryParams)
+				if err != nil {
+					return nil, err
+				}
+				var resp struct {
+					Items []map[string]any `json:"items"`
+				}
+				if err := json.Unmarshal(bodyBytes, &resp); err != nil {
+					return nil, fmt.Errorf("failed to decode %s search response: %v", source, err)
+				}
+				items = resp.Items
+
+				// A page full of records seen in the overlap window is searched again from since,
+				// giving up the records ingested late
+				if len(items) < limit || !start.Before(state.Since) || refreshHasNew(state, items, after) {
+					from = start
+					break
+				}
+			}
+
+			result := RefreshSearchResult{
+				Source:  source,
+				Query:   state.Query,
+				From:    from.Format(isoTimeLayout),
+				To:      now.Format(isoTimeLayout),
+				Items:   []any{},
+				HasMore: len(items) >= limit,
+			}
+			state = refreshItems(state, items, after, &result)
+			result.TotalCount = len(result.Items)
+			result.QueryID = state.encode()
+			result.Guidance = refreshSearchGuidance(result, state)
+
+			r, err := json.Marshal(result)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
+
+// refreshHasNew reports whether any of the items was not seen yet.
+func refreshHasNew(state RefreshState, items []map[string]any, after time.Time) bool {
+	var result RefreshSearchResult
+	refreshItems(state, items, after, &result)
+	return len(result.Items) > 0
+}
+
+// refreshItems adds the items that were not seen yet, and are after after if set, to the result and
+// returns the state after them.
+func refreshItems(state RefreshState, items []map[string]any, after time.Time, result *RefreshSearchResult) RefreshState {
+	seen := make(map[string]bool, len(state.Seen))
+	for _, hash := range state.Seen {
+		seen[hash] = true
+	}
+
+	type seenRecord struct {
+		hash string
+		at   time.Time
+	}
+	var returned []seenRecord
+	for _, item := range items {
+		at, ok := recordTime(item)
+		if !ok || (!after.IsZero() && !at.After(after)) {
+			continue
+		}
+		hash := recordHash(item)
+		if seen[hash] {
+			continue
+		}
+		seen[hash] = true
+		result.Items = append(result.Items, item)
+		returned = append(returned, seenRecord{hash: hash, at: at})
+	}
+	if len(returned) == 0 {
+		return state
+	}
+
+	sort.SliceStable(returned, func(i, j int) bool { return returned[i].at.Before(returned[j].at) })
+	since := returned[len(returned)-1].at
+	next := RefreshState{Source: state.Source, Query: state.Query, Start: state.Start, Since: since}
+	// The records seen before stay relevant as long as their window overlaps the new one
+	if !state.Since.Before(since.Add(-refreshOverlap)) {
+		next.Seen = append(next.Seen, state.Seen...)
+	}
+	for _, record := range returned {
+		if !record.at.Before(since.Add(-refreshOverlap)) {
+			next.Seen = append(next.Seen, record.hash)
+		}
+	}
+	if len(next.Seen) > maxRefreshSeen {
+		next.Seen = next.Seen[len(next.Seen)-maxRefreshSeen:]
+	}
+	return next
+}
+
+// recordTime returns the timestamp of a search record, an ISO-8601 string or epoch milliseconds.
+func recordTime(item map[string]any) (time.Time, bool) {
+	switch v := item["timestamp"].(type) {
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		return t.UTC(), err == nil
+	case float64:
+		return time.UnixMilli(int64(v)).UTC(), true
+	}
+	return time.Time{}, false
+}
+
+func recordHash(item map[string]any) string {
+	// Maps are encoded with sorted keys, so equal records have equal hashes
+	data, _ := json.Marshal(item)
+	h := fnv.New64a()
+	h.Write(data)
+	return strconv.FormatUint(h.Sum64(), 36)
+}
+
+func refreshSearchGuidance(result RefreshSearchResult, state RefreshState) *SearchGuidance {
+	if result.TotalCount == 0 {
+		return &SearchGuidance{
+			ResultStatus: "empty",
+			NextSteps: []string{
+				fmt.Sprintf("No new %s since %s.", result.Source, state.Since.Format(isoTimeLayout)),
+				"Call refresh_search again later with query_id to check again.",
+			},
+		}
+	}
+
+	guidance := &SearchGuidance{
+		ResultStatus: "success",
+		NextSteps:    []string{fmt.Sprintf("Found %d new %s up to %s.", result.TotalCount, result.Source, state.Since.Format(isoTimeLayout))},
+	}
+	if result.HasMore {
+		guidance.NextSteps = append(guidance.NextSteps, "There are more new records than the limit; call refresh_search again with query_id right away to get the next ones.")
+	} else {
+		guidance.NextSteps = append(guidance.NextSteps, "Call refresh_search again later with query_id to get the records that arrive in between.")
+	}
+	return guidance
+}
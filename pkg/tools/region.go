@@ -0,0 +1,171 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// regionlessTools are the read-only tools that do not call the API, so a region means nothing to
+// them.
+var regionlessTools = map[string]bool{
+	"validate_cql":            true,
+	"export_session_snapshot": true,
+}
+
+// ParseRegions parses the API URLs of regions in the form
+// "us=https://api.edgedelta.com,eu=https://api.eu.example.com".
+func ParseRegions(s string) (map[string]string, error) {
+	regions := make(map[string]string)
+	for _, entry := range strings.Split(s, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+
+		region, apiURL, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid region entry %q, expected <region>=<api_url>", entry)
+		}
+
+		apiURL = strings.TrimSuffix(strings.TrimSpace(apiURL), "/")
+		if u, err := url.Parse(apiURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid API URL for region %s: %q", region, apiURL)
+		}
+		regions[strings.TrimSpace(region)] = apiURL
+	}
+	return regions, nil
+}
+
+// RegionRouter is a client sending the requests of calls with a region, see RegionOverride, to
+// the client of the region, and the others to the default client.
+type RegionRouter struct {
+	client  Client
+	regions map[string]Client
+}
+
+// NewRegionRouter creates a router sending requests to client by default and to the clients of
+// regions for calls with a region.
+func NewRegionRouter(client Client, regions map[string]Client) *RegionRouter {
+	return &RegionRouter{client: client, regions: regions}
+}
+
+func (r *RegionRouter) Do(req *http.Request) (*http.Response, error) {
+	apiURL, _ := req.Context().Value(APIURLKey).(string)
+	if apiURL == "" || apiURL == r.client.APIURL() {
+		return r.client.Do(req)
+	}
+
+	for _, client := range r.regions {
+		if client.APIURL() != apiURL {
+			continue
+		}
+		// Tools build their URLs from the default API URL
+		rawURL, ok := strings.CutPrefix(req.URL.String(), r.client.APIURL())
+		if !ok {
+			return r.client.Do(req)
+		}
+		regional, err := url.Parse(apiURL + rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to route request to %s: %v", apiURL, err)
+		}
+		req = req.Clone(req.Context())
+		req.URL, req.Host = regional, ""
+		return client.Do(req)
+	}
+	return nil, fmt.Errorf("no client for API URL %s", apiURL)
+}
+
+func (r *RegionRouter) Get(url string) (*http.Response, error) {
+	return r.client.Get(url)
+}
+
+func (r *RegionRouter) APIURL() string {
+	return r.client.APIURL()
+}
+
+// RegionOverride lets read-only tools query another region of the Edge Delta API than the default
+// one through a region argument, for orgs with data in several regions. Only the regions it is
+// created with are accepted.
+type RegionOverride struct {
+	regions map[string]string
+	// tools are the names of the tools the region argument was added to
+	tools map[string]bool
+}
+
+// NewRegionOverride creates the override of the API URLs of regions, e.g. {"eu": "https://..."}.
+func NewRegionOverride(regions map[string]string) *RegionOverride {
+	return &RegionOverride{regions: regions, tools: make(map[string]bool)}
+}
+
+func (o *RegionOverride) supports(tool mcp.Tool) bool {
+	readOnly := tool.Annotations.ReadOnlyHint
+	return readOnly != nil && *readOnly && !regionlessTools[tool.Name]
+}
+
+func (o *RegionOverride) names() []string {
+	names := make([]string, 0, len(o.regions))
+	for name := range o.regions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Tool adds the region argument to the tool if it is read-only. Tools are registered before any
+// call, so the names of the tools it adds the argument to need no lock.
+func (o *RegionOverride) Tool(tool mcp.Tool) mcp.Tool {
+	if len(o.regions) == 0 || !o.supports(tool) {
+		return tool
+	}
+
+	properties := make(map[string]any, len(tool.InputSchema.Properties)+1)
+	for k, v := range tool.InputSchema.Properties {
+		properties[k] = v
+	}
+	properties["region"] = map[string]any{
+		"type":        "string",
+		"description": "Region of the Edge Delta API to query instead of the default one, for orgs with data in several regions.",
+		"enum":        o.names(),
+	}
+	tool.InputSchema.Properties = properties
+	o.tools[tool.Name] = true
+	return tool
+}
+
+// Middleware runs calls with a region argument against the API URL of that region.
+func (o *RegionOverride) Middleware() ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			args := request.GetArguments()
+			region, _ := args["region"].(string)
+			if region = strings.TrimSpace(region); region == "" {
+				return next(ctx, request)
+			}
+
+			if !o.tools[request.Params.Name] {
+				return mcp.NewToolResultError(fmt.Sprintf("%s does not support region", request.Params.Name)), nil
+			}
+
+			apiURL, ok := o.regions[region]
+			if !ok {
+				return mcp.NewToolResultError(fmt.Sprintf("unknown region %q, expected one of %s", region, strings.Join(o.names(), ", "))), nil
+			}
+
+			overridden := make(map[string]any, len(args))
+			for k, v := range args {
+				if k != "region" {
+					overridden[k] = v
+				}
+			}
+			request.Params.Arguments = overridden
+
+			return next(context.WithValue(ctx, APIURLKey, apiURL), request)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures how HTTPClient retries requests failing with a transient error: 429,
+// 502, 503 and 504 responses and network errors.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt. Zero disables retries.
+	MaxRetries int
+	// BaseDelay is the delay before the first retry, doubled for every next retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between attempts. A Retry-After longer than MaxDelay is not
+	// waited for: the response is returned so the caller learns it is throttled.
+	MaxDelay time.Duration
+	// Jitter randomizes delays by up to this fraction, e.g. 0.2 for ±20%, so that clients
+	// throttled together do not retry together.
+	Jitter float64
+}
+
+// DefaultRetryPolicy is the retry policy of HTTP clients.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   10 * time.Second,
+	Jitter:     0.2,
+}
+
+// WithRetryPolicy sets the retry policy of the client, DefaultRetryPolicy by default
+func WithRetryPolicy(policy RetryPolicy) HTTPClientOption {
+	return func(c *HTTPClient) {
+		c.retryPolicy = policy
+	}
+}
+
+// retryableStatus reports whether a response with the status code may succeed if retried. Only
+// 429 and 503, which mean the request was not processed, are retried for requests that are not
+// idempotent, e.g. a pipeline save.
+func retryableStatus(statusCode int, idempotent bool) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent
+	default:
+		return false
+	}
+}
+
+// retryableError reports whether a request failing with err may succeed if retried: network
+// errors, and for requests that are not idempotent only the ones before the request was sent.
+func retryableError(err error, idempotent bool) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	var netErr net.Error
+	return idempotent && errors.As(err, &netErr)
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryAfter returns the delay of the Retry-After header of the response, in seconds or as an
+// HTTP date, and false if it has none.
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// backoff returns the delay before the retry, starting at 1.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.BaseDelay << (retry - 1)
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		delay = time.Duration(float64(delay) * (1 + p.Jitter*(2*rand.Float64()-1)))
+	}
+	return delay
+}
+
+// do sends the request with send, retrying it according to the policy.
+func (p RetryPolicy) do(req *http.Request, send func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	ctx := req.Context()
+	idempotent := isIdempotent(req.Method)
+	// Requests with a body can only be retried if it can be read again
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+	for retry := 1; ; retry++ {
+		resp, err := send(req)
+		if retry > p.MaxRetries || !replayable {
+			return resp, err
+		}
+
+		var delay time.Duration
+		switch {
+		case err != nil:
+			if !retryableError(err, idempotent) {
+				return resp, err
+			}
+			delay = p.backoff(retry)
+		case retryableStatus(resp.StatusCode, idempotent):
+			var ok bool
+			if delay, ok = retryAfter(resp, time.Now()); !ok {
+				delay = p.backoff(retry)
+			} else if delay > p.MaxDelay {
+				return resp, nil
+			}
+		default:
+			return resp, nil
+		}
+
+		// Do not wait for a retry the deadline would cancel
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package tools
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// NormalizeSchema resolves conflicts in the input schema of the tool that some clients reject:
+// a parameter that is required and has a default is made optional, as its default applies when
+// it is left out. It returns the tool and a description of every adjustment.
+func NormalizeSchema(tool mcp.Tool) (mcp.Tool, []string) {
+	var (
+		required    = make([]string, 0, len(tool.InputSchema.Required))
+		adjustments []string
+	)
+	for _, name := range tool.InputSchema.Required {
+		property, _ := tool.InputSchema.Properties[name].(map[string]any)
+		if d, ok := property["default"]; ok {
+			adjustments = append(adjustments, fmt.Sprintf("parameter %q has default %v, no longer required", name, d))
+			continue
+		}
+		required = append(required, name)
+	}
+
+	if len(adjustments) > 0 {
+		// Required is replaced, not modified, as it may be shared with the caller's tool
+		tool.InputSchema.Required = slices.Clip(required)
+	}
+	return tool, adjustments
+}
@@ -0,0 +1,178 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/storage"
+)
+
+// DefaultSchemaCacheTTL is how long the schema responses are cached by default.
+const DefaultSchemaCacheTTL = time.Minute
+
+type schemaRequestKey struct{}
+
+type schemaBypassKey struct{}
+
+// schemaRequest marks the requests of ctx as schema requests, i.e. facet keys, facets, facet
+// options and services, which SchemaCache caches.
+func schemaRequest(ctx context.Context) context.Context {
+	return context.WithValue(ctx, schemaRequestKey{}, true)
+}
+
+// BypassSchemaCache returns a context whose schema requests skip the cached responses. Their fresh
+// responses are still cached for the next calls.
+func BypassSchemaCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, schemaBypassKey{}, true)
+}
+
+type schemaEntry struct {
+	Status    int         `json:"status"`
+	Header    http.Header `json:"header"`
+	Body      []byte      `json:"body"`
+	FetchedAt time.Time   `json:"fetched_at"`
+}
+
+// SchemaCache is a Client caching the responses of schema requests, i.e. facet keys, facets,
+// facet options and services, which discover_schema and agents make again and again in a
+// conversation. Responses are cached in the storage per caller, org and request, so a cached
+// response is only served to the credentials that fetched it. Requests differing only in the
+// order of their query parameters or in blank ones share a response. Creating or deleting a facet
+// drops the responses cached for the caller.
+type SchemaCache struct {
+	client        Client
+	canonicalizer *ArgumentCanonicalizer
+	store         storage.Interface
+
+	mu  sync.Mutex
+	ttl time.Duration
+}
+
+func NewSchemaCache(client Client, ttl time.Duration, canonicalizer *ArgumentCanonicalizer, store storage.Interface) *SchemaCache {
+	return &SchemaCache{
+		client:        client,
+		canonicalizer: canonicalizer,
+		store:         store,
+		ttl:           ttl,
+	}
+}
+
+func (c *SchemaCache) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	caller, err := callerKey(ctx)
+	if err != nil {
+		return c.client.Do(req)
+	}
+
+	_, schema := ctx.Value(schemaRequestKey{}).(bool)
+	if !schema || req.Method != http.MethodGet {
+		resp, err := c.client.Do(req)
+		if err == nil && resp.StatusCode < http.StatusBadRequest && strings.HasSuffix(req.URL.Path, "/facets") {
+			c.invalidate(ctx, caller)
+		}
+		return resp, err
+	}
+
+	query, err := c.canonicalQuery(req.URL)
+	if err != nil {
+		return c.client.Do(req)
+	}
+	// The region of the call is part of the key, the URL always has the default API URL
+	apiURL, _ := ctx.Value(APIURLKey).(string)
+	sum := sha256.Sum256([]byte(apiURL + "\x00" + req.URL.Path + "\x00" + query))
+	key := schemaCacheKey(caller) + hex.EncodeToString(sum[:])
+
+	c.mu.Lock()
+	ttl := c.ttl
+	c.mu.Unlock()
+
+	if _, bypass := ctx.Value(schemaBypassKey{}).(bool); !bypass {
+		// A storage error is a cache miss
+		if data, err := c.store.Get(ctx, key); err == nil {
+			var entry schemaEntry
+			if json.Unmarshal(data, &entry) == nil && time.Since(entry.FetchedAt) < ttl {
+				return &http.Response{
+					Status:     fmt.Sprintf("%d %s", entry.Status, http.StatusText(entry.Status)),
+					StatusCode: entry.Status,
+					Header:     entry.Header,
+					Body:       io.NopCloser(bytes.NewReader(entry.Body)),
+					Request:    req,
+				}, nil
+			}
+		}
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	// Failing to cache the response must not fail the request
+	if data, err := json.Marshal(schemaEntry{Status: resp.StatusCode, Header: resp.Header, Body: body, FetchedAt: time.Now()}); err == nil {
+		_ = c.store.Set(ctx, key, data, ttl)
+	}
+	return resp, nil
+}
+
+// schemaCacheKey is the prefix of the storage keys of the responses cached for the caller.
+func schemaCacheKey(caller string) string {
+	return "schema-cache/" + caller + "/"
+}
+
+// canonicalQuery returns the canonical form of the query parameters of the URL.
+func (c *SchemaCache) canonicalQuery(u *url.URL) (string, error) {
+	params := make(map[string]any)
+	for name, values := range u.Query() {
+		if len(values) == 1 {
+			params[name] = values[0]
+		} else {
+			params[name] = values
+		}
+	}
+	canonical, err := c.canonicalizer.Canonical("", params)
+	if err != nil {
+		return "", err
+	}
+	return string(canonical), nil
+}
+
+// invalidate drops the responses cached for the caller.
+func (c *SchemaCache) invalidate(ctx context.Context, caller string) {
+	keys, _ := c.store.List(ctx, schemaCacheKey(caller))
+	for _, key := range keys {
+		_ = c.store.Delete(ctx, key)
+	}
+}
+
+// SetTTL changes how long responses are cached, including the responses already cached, which are
+// kept in the storage at most for the TTL they were cached with.
+func (c *SchemaCache) SetTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttl = ttl
+}
+
+func (c *SchemaCache) Get(url string) (*http.Response, error) {
+	return c.client.Get(url)
+}
+
+func (c *SchemaCache) APIURL() string {
+	return c.client.APIURL()
+}
@@ -1,14 +1,14 @@
 package tools
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"strings"
+	"strconv"
+	"time"
 
 	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
 	"github.com/mark3labs/mcp-go/mcp"
@@ -16,10 +16,20 @@ import (
 )
 
 type SearchResponse struct {
-	Data       json.RawMessage `json:"data"`
+	// Data is the API response, left out when its items are exported in another format
+	Data       json.RawMessage `json:"data,omitempty"`
 	TotalCount int             `json:"total_count"`
-	Query      string          `json:"query_used,omitempty"`
-	Guidance   *SearchGuidance `json:"guidance,omitempty"`
+	// Format, Columns and NextCursor describe the items exported in another format than JSON
+	Format     string           `json:"format,omitempty"`
+	Columns    []string         `json:"columns,omitempty"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+	Query      string           `json:"query_used,omitempty"`
+	UILink     string           `json:"ui_link,omitempty"`
+	NoData     *NoDataResult    `json:"no_data,omitempty"`
+	Shards     *ShardSummary    `json:"shards,omitempty"`
+	FetchAll   *FetchAllSummary `json:"fetch_all,omitempty"`
+	Stats      *QueryStats      `json:"stats,omitempty"`
+	Guidance   *SearchGuidance  `json:"guidance,omitempty"`
 }
 
 type SearchGuidance struct {
@@ -46,6 +56,8 @@ NOT SUPPORTED: Regular expressions (/pattern/)
 
 Common fields: service.name, severity_text, host.name, ed.tag
 
+Time ranges over 3 days are split into daily shards searched in parallel; page through them with next_cursor as usual.
+
 If empty results: verify field values with facet_options`),
 			mcp.WithString("query",
 				mcp.Description(`CQL query string. Examples:
@@ -57,7 +69,7 @@ Use discover_schema tool or facet_options tool first to verify field names.`),
 				mcp.DefaultString(""),
 			),
 			mcp.WithString("lookback",
-				mcp.Description("Lookback period in GOLANG duration format. e.g. (1h, 15m, 24h). Either provide from/to or just lookback. Pass empty string to use from/to instead."),
+				mcp.Description("Lookback period in GOLANG duration format, or days and weeks. e.g. (1h, 15m, 24h, 7d, 1w). Either provide from/to or just lookback. Pass empty string to use from/to instead."),
 				mcp.DefaultString("1h"),
 			),
 			mcp.WithString("from",
@@ -69,36 +81,48 @@ Use discover_schema tool or facet_options tool first to verify field names.`),
 				mcp.DefaultString(""),
 			),
 			mcp.WithNumber("limit",
-				mcp.Description("Limits the number of logs in the response. Default is 20 for AI search, max is 1000. Can be negative to move cursor in prev direction."),
+				mcp.Description("Limits the number of logs in the response, a positive number. Default is 20 for AI search, max is 1000. To page through older or newer logs, pass the cursor of the previous response, or reverse order."),
 				mcp.DefaultNumber(20),
 			),
 			mcp.WithString("cursor",
 				mcp.Description("Cursor provided from previous response, pass it to next request to move the cursor with given limit."),
 				mcp.DefaultString(""),
 			),
+			fetchAllParam(),
+			maxItemsParam(),
 			mcp.WithString("order",
 				mcp.Description("Order of the logs in the response, either 'ASC', 'asc', 'DESC' or 'desc'."),
 				mcp.DefaultString("desc"),
 			),
+			mcp.WithBoolean("normalize_severity",
+				mcp.Description(`Normalize severities to the OpenTelemetry set (TRACE, DEBUG, INFO, WARN, ERROR, FATAL). Severity filters such as severity_text:"WARN" also match synonyms ("warning", "40", ...) and returned items get a normalized severity_text, with the original in severity_text_original.`),
+				mcp.DefaultBool(false),
+			),
+			formatParam(),
+			columnsParam(),
 			mcp.WithReadOnlyHintAnnotation(true),
 			mcp.WithIdempotentHintAnnotation(true),
 			mcp.WithDestructiveHintAnnotation(false),
 			mcp.WithOpenWorldHintAnnotation(false),
 		),
 		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			keys, err := FetchContextKeys(ctx)
-			if err != nil {
-				return nil, err
+			format, columns, errResult := exportOptions(request)
+			if errResult != nil {
+				return errResult, nil
+			}
+			fetch, errResult := fetchAllOptions(request)
+			if errResult != nil {
+				return errResult, nil
 			}
+			normalizeSeverity, _ := params.Optional[bool](request, "normalize_severity")
 
-			// Build query parameters
-			searchURL, err := url.Parse(fmt.Sprintf("%s/v1/orgs/%s/logs/log_search/search", client.APIURL(), keys.OrgID))
-			if err != nil {
-				return nil, err
+			query, _ := params.Optional[string](request, "query")
+			if normalizeSeverity {
+				query = ExpandSeverityQuery(query)
 			}
 
-			queryParams := searchURL.Query()
-			if query, _ := params.Optional[string](request, "query"); query != "" {
+			queryParams := url.Values{}
+			if query != "" {
 				queryParams.Add("query", query)
 			}
 
@@ -129,39 +153,157 @@ Use discover_schema tool or facet_options tool first to verify field names.`),
 				queryParams.Add("order", order)
 			}
 
-			searchURL.RawQuery = queryParams.Encode()
-			req, err := http.NewRequestWithContext(ctx, http.MethodGet, searchURL.String(), nil)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create request: %v", err)
+			// Searches over more than a few days are split into daily shards queried in parallel,
+			// so they do not time out upstream
+			if _, _, err := logSearchShardCursor(queryParams, time.Now().UTC()); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
 			}
 
-			req.Header.Add("Content-Type", "application/json")
-			applyAuthHeader(req, keys)
+			var shards *ShardSummary
+			search := func(ctx context.Context, queryParams url.Values) ([]byte, error) {
+				shardCursor, sharded, err := logSearchShardCursor(queryParams, time.Now().UTC())
+				if err != nil {
+					return nil, err
+				}
+				limit, _ := strconv.Atoi(queryParams.Get("limit"))
+				if !sharded || limit <= 0 {
+					return searchLogs(ctx, client, queryParams)
+				}
+				bodyBytes, summary, err := searchLogShards(ctx, client, queryParams, shardCursor, limit)
+				if err != nil {
+					return nil, err
+				}
+				shards = &summary
+				return bodyBytes, nil
+			}
 
-			resp, err := client.Do(req)
+			bodyBytes, fetched, err := searchPages(ctx, queryParams, fetch, search)
 			if err != nil {
 				return nil, err
 			}
 
-			defer resp.Body.Close()
-			bodyBytes, err := io.ReadAll(resp.Body)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read response body: %v", err)
+			if normalizeSeverity {
+				bodyBytes = normalizeSeverityItems(bodyBytes)
 			}
 
-			if resp.StatusCode != http.StatusOK {
-				return nil, fmt.Errorf("failed to search logs, status code %d: %s", resp.StatusCode, string(bodyBytes))
+			response, ok := newSearchResponse(bodyBytes, query, uiLink(ctx, client, UILogsPage, query, queryParams))
+			if !ok {
+				return mcp.NewToolResultText(string(bodyBytes)), nil
 			}
 
-			query, _ := params.Optional[string](request, "query")
-			return formatSearchResponse(bodyBytes, query)
+			withFetchAll(&response, fetched)
+			if response.Shards = shards; shards != nil {
+				response.Guidance.Suggestions = append(response.Guidance.Suggestions,
+					fmt.Sprintf("The time range was split into %d shards of %s, %d of them queried; pass next_cursor as cursor to continue in time order.", shards.Shards, shards.Window, shards.Queried))
+			}
+
+			if response.TotalCount == 0 && queryParams.Get("cursor") == "" {
+				if response.NoData = explainNoLogs(ctx, client, query, queryParams); response.NoData != nil {
+					response.Guidance.NextSteps = append(response.Guidance.NextSteps, noDataGuidance(response.NoData)...)
+				}
+			}
+
+			if format != FormatJSON {
+				return exportSearchResponse(response, format, columns)
+			}
+			result, _ := json.Marshal(response)
+			return mcp.NewToolResultText(string(result)), nil
 		}
 }
 
-func formatSearchResponse(bodyBytes []byte, query string) (*mcp.CallToolResult, error) {
+// searchLogs calls the log search endpoint with the given query parameters and returns the raw response body.
+func searchLogs(ctx context.Context, client Client, queryParams url.Values) ([]byte, error) {
+	keys, err := FetchContextKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	searchURL, err := url.Parse(fmt.Sprintf("%s/v1/orgs/%s/logs/log_search/search", client.APIURL(), keys.OrgID))
+	if err != nil {
+		return nil, err
+	}
+
+	searchURL.RawQuery = queryParams.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, searchURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Add("Content-Type", "application/json")
+	applyAuthHeader(req, keys)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to search logs, status code %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	return bodyBytes, nil
+}
+
+func searchEvents(ctx context.Context, client Client, queryParams url.Values) ([]byte, error) {
+	keys, err := FetchContextKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	eventsURL, err := url.Parse(fmt.Sprintf("%s/v1/orgs/%s/events/search", client.APIURL(), keys.OrgID))
+	if err != nil {
+		return nil, err
+	}
+
+	eventsURL.RawQuery = queryParams.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, eventsURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Add("Content-Type", "application/json")
+	applyAuthHeader(req, keys)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to search events, status code %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	return bodyBytes, nil
+}
+
+func formatSearchResponse(bodyBytes []byte, query, link string) (*mcp.CallToolResult, error) {
+	response, ok := newSearchResponse(bodyBytes, query, link)
+	if !ok {
+		return mcp.NewToolResultText(string(bodyBytes)), nil
+	}
+
+	result, _ := json.Marshal(response)
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+// newSearchResponse wraps a search response body with its result count and guidance. It returns
+// false if the body is not JSON.
+func newSearchResponse(bodyBytes []byte, query, link string) (SearchResponse, bool) {
 	var genericResp map[string]any
 	if err := json.Unmarshal(bodyBytes, &genericResp); err != nil {
-		return mcp.NewToolResultText(string(bodyBytes)), nil
+		return SearchResponse{}, false
 	}
 
 	totalCount := 0
@@ -189,6 +331,8 @@ func formatSearchResponse(bodyBytes []byte, query string) (*mcp.CallToolResult,
 		Data:       bodyBytes,
 		TotalCount: totalCount,
 		Query:      query,
+		UILink:     link,
+		Stats:      parseQueryStats(bodyBytes),
 	}
 
 	if totalCount == 0 {
@@ -213,9 +357,9 @@ func formatSearchResponse(bodyBytes []byte, query string) (*mcp.CallToolResult,
 			},
 		}
 	}
+	response.Guidance.Suggestions = append(response.Guidance.Suggestions, queryStatsSuggestions(response.Stats)...)
 
-	result, _ := json.Marshal(response)
-	return mcp.NewToolResultText(string(result)), nil
+	return response, true
 }
 
 // GetMetricSearchTool creates a tool to search metrics
@@ -264,7 +408,7 @@ Use "*" for no filter (default). Always verify field values with facet_options f
 				mcp.Description("By default, rollup period will be handled according to the lookup period. However, one can specify it according to its own needs. This needs to be defined in seconds."),
 			),
 			mcp.WithString("lookback",
-				mcp.Description("Lookback period in GOLANG duration format. e.g. (1h, 15m, 24h). Either provide from/to or just lookback. Pass empty string to use from/to instead."),
+				mcp.Description("Lookback period in GOLANG duration format, or days and weeks. e.g. (1h, 15m, 24h, 7d, 1w). Either provide from/to or just lookback. Pass empty string to use from/to instead."),
 				mcp.DefaultString("1h"),
 			),
 			mcp.WithString("from",
@@ -292,17 +436,6 @@ Use "*" for no filter (default). Always verify field values with facet_options f
 			mcp.WithOpenWorldHintAnnotation(false),
 		),
 		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			keys, err := FetchContextKeys(ctx)
-			if err != nil {
-				return nil, err
-			}
-
-			// Build query parameters
-			searchURL, err := url.Parse(fmt.Sprintf("%s/v1/orgs/%s/graph", client.APIURL(), keys.OrgID))
-			if err != nil {
-				return nil, err
-			}
-
 			var metricName, aggregationMethod, filterQuery string
 			var groupByKeys []string
 			var rollupPeriod int
@@ -332,35 +465,23 @@ Use "*" for no filter (default). Always verify field values with facet_options f
 				rollupPeriod = rollup
 			}
 
-			cql := fmt.Sprintf("%s:%s{%s}", aggregationMethod, metricName, filterQuery)
-			if len(groupByKeys) > 0 {
-				cql += fmt.Sprintf(" by {%s}", strings.Join(groupByKeys, ","))
-			}
-
-			if rollupPeriod > 0 {
-				cql += fmt.Sprintf(".rollup(%d)", rollupPeriod)
-			}
-
-			payload := map[string]any{
-				"queries": map[string]any{
-					"A": map[string]any{
-						"scope": "metric",
-						"query": cql,
-					},
-				},
-				"formulas": map[string]any{
-					"A": map[string]any{
-						"formula": "A",
-					},
-				},
-			}
+			cql := MetricQuery{
+				Aggregation: aggregationMethod,
+				Name:        metricName,
+				Filter:      filterQuery,
+				GroupBy:     groupByKeys,
+				Rollup:      rollupPeriod,
+			}.CQL()
 
-			buffer := bytes.NewBuffer(nil)
-			if err := json.NewEncoder(buffer).Encode(payload); err != nil {
-				return nil, fmt.Errorf("failed to encode request body: %w", err)
+			payload, err := NewGraphQueryBuilder().
+				WithQuery("A", GraphQuery{Scope: "metric", Query: cql}).
+				WithFormula("A", "A").
+				Build()
+			if err != nil {
+				return nil, err
 			}
 
-			queryParams := searchURL.Query()
+			queryParams := url.Values{}
 			if lookback, _ := params.Optional[string](request, "lookback"); lookback != "" {
 				queryParams.Add("lookback", lookback)
 			}
@@ -385,32 +506,28 @@ Use "*" for no filter (default). Always verify field values with facet_options f
 				queryParams.Add("graph_type", graphType)
 			}
 
-			searchURL.RawQuery = queryParams.Encode()
-			req, err := http.NewRequestWithContext(ctx, http.MethodPost, searchURL.String(), buffer)
+			statusCode, bodyBytes, warnings, err := postGraphWithRetry(ctx, client, payload, queryParams)
 			if err != nil {
-				return nil, fmt.Errorf("failed to create request: %w", err)
+				return nil, err
 			}
 
-			req.Header.Add("Content-Type", "application/json")
-			applyAuthHeader(req, keys)
-
-			resp, err := client.Do(req)
-			if err != nil {
-				return nil, err
+			if statusCode != http.StatusMultiStatus {
+				return nil, fmt.Errorf("failed to search metrics, status code %d: %s", statusCode, string(bodyBytes))
 			}
 
-			defer resp.Body.Close()
-			bodyBytes, err := io.ReadAll(resp.Body)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read response body: %w", err)
+			queryDesc := fmt.Sprintf("metric:%s filter:%s", metricName, filterQuery)
+			result, err := formatSearchResponse(bodyBytes, queryDesc, uiLink(ctx, client, UIMetricsPage, cql, queryParams))
+			if err != nil || len(warnings) == 0 {
+				return result, err
 			}
 
-			if resp.StatusCode != http.StatusMultiStatus {
-				return nil, fmt.Errorf("failed to search metrics, status code %d: %s", resp.StatusCode, string(bodyBytes))
+			warningBytes, err := json.Marshal(map[string]any{"warnings": warnings})
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal warnings: %w", err)
 			}
 
-			queryDesc := fmt.Sprintf("metric:%s filter:%s", metricName, filterQuery)
-			return formatSearchResponse(bodyBytes, queryDesc)
+			result.Content = append(result.Content, mcp.NewTextContent(string(warningBytes)))
+			return result, nil
 		}
 }
 
@@ -437,6 +554,10 @@ CQL Syntax:
 
 NOT SUPPORTED: Regular expressions (/pattern/)
 
+Monitor alert events (metric_threshold, log_threshold, pattern_anomaly) have a parsed_attributes
+block with their body decoded: the metric, value and threshold, the log query, count and threshold,
+or the anomalous pattern and its score. The raw body is kept.
+
 If empty results: verify event.type/event.domain values with facet_options`),
 			mcp.WithString("query",
 				mcp.Description(`CQL query for events. Examples:
@@ -447,7 +568,7 @@ Use discover_schema tool or facet_options tool to verify field values.`),
 				mcp.DefaultString(""),
 			),
 			mcp.WithString("lookback",
-				mcp.Description("Lookback period in golang duration format. e.g. '1h'. Either provide from/to or provide lookback/to or just lookback. Pass empty string to use from/to instead."),
+				mcp.Description("Lookback period in golang duration format, or days and weeks. e.g. '1h' or '7d'. Either provide from/to or provide lookback/to or just lookback. Pass empty string to use from/to instead."),
 				mcp.DefaultString("1h"),
 			),
 			mcp.WithString("from",
@@ -459,35 +580,37 @@ Use discover_schema tool or facet_options tool to verify field values.`),
 				mcp.DefaultString(""),
 			),
 			mcp.WithNumber("limit",
-				mcp.Description("Limits the number of events in the response. Default is 20 for AI search, max is 1000. Can be negative to move cursor in prev direction."),
+				mcp.Description("Limits the number of events in the response, a positive number. Default is 20 for AI search, max is 1000. To page through older or newer events, pass the cursor of the previous response, or reverse order."),
 				mcp.DefaultNumber(20),
 			),
 			mcp.WithString("cursor",
 				mcp.Description("Cursor provided from previous response, pass it to next request to move the cursor with given limit."),
 				mcp.DefaultString(""),
 			),
+			fetchAllParam(),
+			maxItemsParam(),
 			mcp.WithString("order",
 				mcp.Description("Order of the events in the response, either 'ASC', 'asc', 'DESC' or 'desc'."),
 				mcp.DefaultString("desc"),
 			),
+			formatParam(),
+			columnsParam(),
 			mcp.WithReadOnlyHintAnnotation(true),
 			mcp.WithIdempotentHintAnnotation(true),
 			mcp.WithDestructiveHintAnnotation(false),
 			mcp.WithOpenWorldHintAnnotation(false),
 		),
 		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			keys, err := FetchContextKeys(ctx)
-			if err != nil {
-				return nil, err
+			format, columns, errResult := exportOptions(request)
+			if errResult != nil {
+				return errResult, nil
 			}
-
-			// Build query parameters
-			eventsURL, err := url.Parse(fmt.Sprintf("%s/v1/orgs/%s/events/search", client.APIURL(), keys.OrgID))
-			if err != nil {
-				return nil, err
+			fetch, errResult := fetchAllOptions(request)
+			if errResult != nil {
+				return errResult, nil
 			}
 
-			queryParams := eventsURL.Query()
+			queryParams := url.Values{}
 			if query, _ := params.Optional[string](request, "query"); query != "" {
 				queryParams.Add("query", query)
 			}
@@ -519,32 +642,26 @@ Use discover_schema tool or facet_options tool to verify field values.`),
 				queryParams.Add("order", order)
 			}
 
-			eventsURL.RawQuery = queryParams.Encode()
-			req, err := http.NewRequestWithContext(ctx, http.MethodGet, eventsURL.String(), nil)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create request: %v", err)
-			}
-
-			req.Header.Add("Content-Type", "application/json")
-			applyAuthHeader(req, keys)
-
-			resp, err := client.Do(req)
+			bodyBytes, fetched, err := searchPages(ctx, queryParams, fetch, func(ctx context.Context, queryParams url.Values) ([]byte, error) {
+				return searchEvents(ctx, client, queryParams)
+			})
 			if err != nil {
 				return nil, err
 			}
+			bodyBytes = withParsedMonitorEvents(bodyBytes)
 
-			defer resp.Body.Close()
-			bodyBytes, err := io.ReadAll(resp.Body)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read response body: %v", err)
+			query, _ := params.Optional[string](request, "query")
+			response, ok := newSearchResponse(bodyBytes, query, uiLink(ctx, client, UIEventsPage, query, queryParams))
+			if !ok {
+				return mcp.NewToolResultText(string(bodyBytes)), nil
 			}
+			withFetchAll(&response, fetched)
 
-			if resp.StatusCode != http.StatusOK {
-				return nil, fmt.Errorf("failed to search events, status code %d: %s", resp.StatusCode, string(bodyBytes))
+			if format != FormatJSON {
+				return exportSearchResponse(response, format, columns)
 			}
-
-			query, _ := params.Optional[string](request, "query")
-			return formatSearchResponse(bodyBytes, query)
+			result, _ := json.Marshal(response)
+			return mcp.NewToolResultText(string(result)), nil
 		}
 }
 
@@ -579,7 +696,7 @@ Use discover_schema tool or facet_options tool to verify field values.`),
 				mcp.DefaultString(""),
 			),
 			mcp.WithString("lookback",
-				mcp.Description("Lookback period in golang duration format. e.g. '1h'. Either provide from/to or provide lookback/to or just lookback. Pass empty string to use from/to instead."),
+				mcp.Description("Lookback period in golang duration format, or days and weeks. e.g. '1h' or '7d'. Either provide from/to or provide lookback/to or just lookback. Pass empty string to use from/to instead."),
 				mcp.DefaultString("1h"),
 			),
 			mcp.WithString("from",
@@ -598,7 +715,7 @@ Use discover_schema tool or facet_options tool to verify field values.`),
 				mcp.DefaultNumber(20),
 			),
 			mcp.WithString("offset",
-				mcp.Description("Comma separated offsets for delta stat calculation. Each offset is in golang duration format. Default value is lookback duration. e.g. '24h'."),
+				mcp.Description("Comma separated offsets for delta stat calculation. Each offset is in golang duration format, or days and weeks. Default value is lookback duration. e.g. '24h' or '1w'."),
 				mcp.DefaultString(""),
 			),
 			mcp.WithBoolean("negative",
@@ -610,18 +727,8 @@ Use discover_schema tool or facet_options tool to verify field values.`),
 			mcp.WithOpenWorldHintAnnotation(false),
 		),
 		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			keys, err := FetchContextKeys(ctx)
-			if err != nil {
-				return nil, err
-			}
-
 			// Build query parameters
-			statsURL, err := url.Parse(fmt.Sprintf("%s/v1/orgs/%s/clustering/stats", client.APIURL(), keys.OrgID))
-			if err != nil {
-				return nil, err
-			}
-
-			queryParams := statsURL.Query()
+			queryParams := url.Values{}
 			if query, _ := params.Optional[string](request, "query"); query != "" {
 				queryParams.Add("query", query)
 			}
@@ -656,33 +763,53 @@ Use discover_schema tool or facet_options tool to verify field values.`),
 				queryParams.Add("negative", "true")
 			}
 
-			statsURL.RawQuery = queryParams.Encode()
-			req, err := http.NewRequestWithContext(ctx, http.MethodGet, statsURL.String(), nil)
+			bodyBytes, err := getPatternStats(ctx, client, queryParams)
 			if err != nil {
-				return nil, fmt.Errorf("failed to create request: %v", err)
+				return nil, err
 			}
 
-			req.Header.Add("Content-Type", "application/json")
-			applyAuthHeader(req, keys)
+			query, _ := params.Optional[string](request, "query")
+			return formatSearchResponse(bodyBytes, query, uiLink(ctx, client, UIPatternsPage, query, queryParams))
+		}
+}
 
-			resp, err := client.Do(req)
-			if err != nil {
-				return nil, err
-			}
+// getPatternStats calls the clustering stats endpoint with the given query parameters and returns the raw response body.
+func getPatternStats(ctx context.Context, client Client, queryParams url.Values) ([]byte, error) {
+	keys, err := FetchContextKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-			defer resp.Body.Close()
-			bodyBytes, err := io.ReadAll(resp.Body)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read response body: %v", err)
-			}
+	statsURL, err := url.Parse(fmt.Sprintf("%s/v1/orgs/%s/clustering/stats", client.APIURL(), keys.OrgID))
+	if err != nil {
+		return nil, err
+	}
 
-			if resp.StatusCode != http.StatusOK {
-				return nil, fmt.Errorf("failed to get clustering stats, status code %d: %s", resp.StatusCode, string(bodyBytes))
-			}
+	statsURL.RawQuery = queryParams.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, statsURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
 
-			query, _ := params.Optional[string](request, "query")
-			return formatSearchResponse(bodyBytes, query)
-		}
+	req.Header.Add("Content-Type", "application/json")
+	applyAuthHeader(req, keys)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get clustering stats, status code %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	return bodyBytes, nil
 }
 
 // GetTraceTimelineTool creates a tool to fetch spans suitable for the TraceTimeline component
@@ -704,6 +831,8 @@ NOT SUPPORTED for traces:
 
 Common fields: service.name, status.code, span.kind, ed.tag
 
+Spans with events (e.g. exceptions) or links are outlined in a span_events block; use get_span_events for all their attributes, such as exception stack traces.
+
 If empty results: verify field values with facet_options`),
 			mcp.WithString("query",
 				mcp.Description(`CQL filter query (field:value syntax required). Examples:
@@ -715,7 +844,7 @@ NOTE: Full-text search is NOT supported for traces.`),
 				mcp.DefaultString(""),
 			),
 			mcp.WithString("lookback",
-				mcp.Description("Lookback period in Go duration format (e.g., 1h, 15m, 24h). Provide either lookback or from/to. Pass empty string to use from/to instead."),
+				mcp.Description("Lookback period in Go duration format, or days and weeks (e.g., 1h, 15m, 24h, 7d). Provide either lookback or from/to. Pass empty string to use from/to instead."),
 				mcp.DefaultString("1h"),
 			),
 			mcp.WithString("from",
@@ -734,6 +863,8 @@ NOTE: Full-text search is NOT supported for traces.`),
 				mcp.Description("Pagination cursor from a previous response (use next_cursor/previous_cursor)."),
 				mcp.DefaultString(""),
 			),
+			fetchAllParam(),
+			maxItemsParam(),
 			mcp.WithString("order",
 				mcp.Description("Sort order: 'ASC' or 'DESC' (case-insensitive)."),
 				mcp.DefaultString("desc"),
@@ -741,24 +872,25 @@ NOTE: Full-text search is NOT supported for traces.`),
 			mcp.WithBoolean("include_child_spans",
 				mcp.Description("If true, include child spans for matched spans to provide full trace context."),
 			),
+			formatParam(),
+			columnsParam(),
 			mcp.WithReadOnlyHintAnnotation(true),
 			mcp.WithIdempotentHintAnnotation(true),
 			mcp.WithDestructiveHintAnnotation(false),
 			mcp.WithOpenWorldHintAnnotation(false),
 		),
 		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			keys, err := FetchContextKeys(ctx)
-			if err != nil {
-				return nil, err
+			format, columns, errResult := exportOptions(request)
+			if errResult != nil {
+				return errResult, nil
 			}
-
-			// Build query parameters for traces search
-			tracesURL, err := url.Parse(fmt.Sprintf("%s/v1/orgs/%s/traces", client.APIURL(), keys.OrgID))
-			if err != nil {
-				return nil, err
+			fetch, errResult := fetchAllOptions(request)
+			if errResult != nil {
+				return errResult, nil
 			}
 
-			queryParams := tracesURL.Query()
+			// Build query parameters for traces search
+			queryParams := url.Values{}
 			var query string
 			if q, _ := params.Optional[string](request, "query"); q != "" {
 				query = q
@@ -795,30 +927,77 @@ NOTE: Full-text search is NOT supported for traces.`),
 				queryParams.Add("include_child_spans", "true")
 			}
 
-			tracesURL.RawQuery = queryParams.Encode()
-			req, err := http.NewRequestWithContext(ctx, http.MethodGet, tracesURL.String(), nil)
+			bodyBytes, fetched, err := searchPages(ctx, queryParams, fetch, func(ctx context.Context, queryParams url.Values) ([]byte, error) {
+				return searchTraces(ctx, client, queryParams)
+			})
 			if err != nil {
-				return nil, fmt.Errorf("failed to create request: %v", err)
+				return nil, err
 			}
 
-			req.Header.Add("Content-Type", "application/json")
-			applyAuthHeader(req, keys)
-
-			resp, err := client.Do(req)
-			if err != nil {
-				return nil, err
+			response, ok := newSearchResponse(bodyBytes, query, uiLink(ctx, client, UITracesPage, query, queryParams))
+			if !ok {
+				return mcp.NewToolResultText(string(bodyBytes)), nil
 			}
+			withFetchAll(&response, fetched)
 
-			defer resp.Body.Close()
-			bodyBytes, err := io.ReadAll(resp.Body)
-			if err != nil {
-				return nil, fmt.Errorf("failed to read response body: %v", err)
+			var result *mcp.CallToolResult
+			if format != FormatJSON {
+				result, err = exportSearchResponse(response, format, columns)
+			} else {
+				encoded, _ := json.Marshal(response)
+				result = mcp.NewToolResultText(string(encoded))
+			}
+			summaries := spanEventSummaries(bodyBytes)
+			if err != nil || len(summaries) == 0 {
+				return result, err
 			}
 
-			if resp.StatusCode != http.StatusOK {
-				return nil, fmt.Errorf("failed to search traces, status code %d: %s", resp.StatusCode, string(bodyBytes))
+			// Outline the events and links of the spans, they are easy to miss in the spans
+			eventBytes, err := json.Marshal(map[string]any{"span_events": summaries})
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal span events: %w", err)
 			}
 
-			return formatSearchResponse(bodyBytes, query)
+			result.Content = append(result.Content, mcp.NewTextContent(string(eventBytes)))
+			return result, nil
 		}
 }
+
+// searchTraces calls the trace search endpoint with the given query parameters and returns the raw response body.
+func searchTraces(ctx context.Context, client Client, queryParams url.Values) ([]byte, error) {
+	keys, err := FetchContextKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tracesURL, err := url.Parse(fmt.Sprintf("%s/v1/orgs/%s/traces", client.APIURL(), keys.OrgID))
+	if err != nil {
+		return nil, err
+	}
+
+	tracesURL.RawQuery = queryParams.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tracesURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Add("Content-Type", "application/json")
+	applyAuthHeader(req, keys)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to search traces, status code %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	return bodyBytes, nil
+}
@@ -0,0 +1,260 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	maxSentimentBuckets = 24
+	// sentimentPatternLimit is the number of patterns counted per bucket.
+	sentimentPatternLimit = "1000"
+	// sentimentStableDelta is the change of the negative proportion below which the trend is
+	// considered stable, i.e. 1 percentage point.
+	sentimentStableDelta = 0.01
+)
+
+type SentimentTrendResult struct {
+	Service string            `json:"service,omitempty"`
+	Query   string            `json:"query"`
+	Window  string            `json:"window"`
+	Buckets []SentimentBucket `json:"buckets"`
+	// Trend is "improving", "worsening", "stable" or "no_data", comparing the negative proportion
+	// of the later half of the buckets to the earlier half.
+	Trend    string          `json:"trend"`
+	Change   float64         `json:"change"`
+	Guidance *SearchGuidance `json:"guidance,omitempty"`
+}
+
+type SentimentBucket struct {
+	From               string  `json:"from"`
+	To                 string  `json:"to"`
+	TotalCount         int     `json:"total_count"`
+	NegativeCount      int     `json:"negative_count"`
+	NegativeProportion float64 `json:"negative_proportion"`
+	TopNegativePattern string  `json:"top_negative_pattern,omitempty"`
+	NegativePatterns   int     `json:"negative_patterns"`
+}
+
+// GetSentimentTrendTool creates a tool to track the share of negative log patterns over time
+func GetSentimentTrendTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("get_sentiment_trend",
+			mcp.WithTitleAnnotation("Get Sentiment Trend"),
+			mcp.WithDescription(`Returns the proportion of logs matching negative-sentiment patterns over consecutive time buckets, and whether it is improving, worsening or stable.
+
+Use this tool to answer "is the system getting healthier since the fix?" without pulling full pattern lists for every time range.
+The window is split into equal buckets ending now; each bucket reports total and negative pattern counts and the top negative pattern.
+
+For the patterns themselves use get_log_patterns with negative: true.`),
+			mcp.WithString("service",
+				mcp.Description("service.name to analyze. Leave empty for all services."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("query",
+				mcp.Description(`Additional CQL filter, e.g. ed.tag:"prod". Combined with service.`),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("window",
+				mcp.Description("Time window ending now, in Go duration format, or days and weeks (e.g., 6h, 24h, 7d, 1w)."),
+				mcp.DefaultString("24h"),
+			),
+			mcp.WithNumber("buckets",
+				mcp.Description("Number of equal time buckets to split the window into. Default: 6, max 24"),
+				mcp.DefaultNumber(6),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			service, _ := params.Optional[string](request, "service")
+			extra, _ := params.Optional[string](request, "query")
+
+			window, _ := params.Optional[string](request, "window")
+			if window == "" {
+				window = "24h"
+			}
+			windowDuration, err := ParseLookback(window)
+			if err != nil || windowDuration <= 0 {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid window %q, expected a duration such as 24h or 7d", window)), nil
+			}
+
+			buckets := request.GetInt("buckets", 6)
+			if buckets < 2 {
+				buckets = 2
+			}
+			if buckets > maxSentimentBuckets {
+				buckets = maxSentimentBuckets
+			}
+
+			var filters []string
+			if service != "" {
+				filters = append(filters, fmt.Sprintf(`service.name:"%s"`, escapeValue(service)))
+			}
+			if extra = strings.TrimSpace(extra); extra != "" {
+				filters = append(filters, extra)
+			}
+			query := strings.Join(filters, " AND ")
+
+			result := SentimentTrendResult{
+				Service: service,
+				Query:   query,
+				Window:  window,
+				Buckets: make([]SentimentBucket, 0, buckets),
+			}
+
+			to := time.Now().UTC()
+			from := to.Add(-windowDuration)
+			step := windowDuration / time.Duration(buckets)
+			for i := 0; i < buckets; i++ {
+				bucketFrom := from.Add(time.Duration(i) * step)
+				bucketTo := bucketFrom.Add(step)
+				if i == buckets-1 {
+					bucketTo = to
+				}
+
+				bucket, err := sentimentBucket(ctx, client, query, bucketFrom, bucketTo)
+				if err != nil {
+					return nil, err
+				}
+				result.Buckets = append(result.Buckets, bucket)
+			}
+
+			result.Trend, result.Change = sentimentTrend(result.Buckets)
+			result.Guidance = sentimentTrendGuidance(result)
+
+			r, err := json.Marshal(result)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
+
+func sentimentBucket(ctx context.Context, client Client, query string, from, to time.Time) (SentimentBucket, error) {
+	bucket := SentimentBucket{
+		From: from.Format(isoTimeLayout),
+		To:   to.Format(isoTimeLayout),
+	}
+
+	queryParams := url.Values{}
+	if query != "" {
+		queryParams.Add("query", query)
+	}
+	queryParams.Add("from", bucket.From)
+	queryParams.Add("to", bucket.To)
+	queryParams.Add("limit", sentimentPatternLimit)
+
+	bodyBytes, err := getPatternStats(ctx, client, queryParams)
+	if err != nil {
+		return bucket, err
+	}
+
+	var resp struct {
+		Stats []struct {
+			Pattern   string `json:"pattern"`
+			Count     int    `json:"count"`
+			Sentiment string `json:"sentiment"`
+		} `json:"stats"`
+	}
+	if err := json.Unmarshal(bodyBytes, &resp); err != nil {
+		return bucket, fmt.Errorf("failed to decode clustering stats response: %v", err)
+	}
+
+	topCount := 0
+	for _, stat := range resp.Stats {
+		bucket.TotalCount += stat.Count
+		if !strings.EqualFold(stat.Sentiment, "negative") {
+			continue
+		}
+		bucket.NegativeCount += stat.Count
+		bucket.NegativePatterns++
+		if stat.Count > topCount {
+			bucket.TopNegativePattern, topCount = stat.Pattern, stat.Count
+		}
+	}
+
+	if bucket.TotalCount > 0 {
+		bucket.NegativeProportion = roundProportion(float64(bucket.NegativeCount) / float64(bucket.TotalCount))
+	}
+	return bucket, nil
+}
+
+// sentimentTrend compares the negative proportion of the later half of the buckets with data to
+// the earlier half.
+func sentimentTrend(buckets []SentimentBucket) (string, float64) {
+	var proportions []float64
+	for _, b := range buckets {
+		if b.TotalCount > 0 {
+			proportions = append(proportions, b.NegativeProportion)
+		}
+	}
+	if len(proportions) < 2 {
+		return "no_data", 0
+	}
+
+	half := len(proportions) / 2
+	change := roundProportion(mean(proportions[len(proportions)-half:]) - mean(proportions[:half]))
+	switch {
+	case math.Abs(change) < sentimentStableDelta:
+		return "stable", change
+	case change < 0:
+		return "improving", change
+	default:
+		return "worsening", change
+	}
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func roundProportion(p float64) float64 {
+	return math.Round(p*10000) / 10000
+}
+
+func sentimentTrendGuidance(result SentimentTrendResult) *SearchGuidance {
+	first, last := result.Buckets[0], result.Buckets[len(result.Buckets)-1]
+
+	switch result.Trend {
+	case "no_data":
+		return &SearchGuidance{
+			ResultStatus: "empty",
+			NextSteps: []string{
+				"Not enough buckets have log patterns to compute a trend.",
+				"Verify the service name with facet_options (scope: \"pattern\", facet_path: \"service.name\") or widen the window.",
+			},
+		}
+	case "stable":
+		return &SearchGuidance{
+			ResultStatus: "success",
+			NextSteps: []string{fmt.Sprintf("The negative pattern proportion is stable at about %.1f%% over %s.",
+				last.NegativeProportion*100, result.Window)},
+		}
+	}
+
+	guidance := &SearchGuidance{
+		ResultStatus: "success",
+		NextSteps: []string{fmt.Sprintf("The negative pattern proportion is %s: %.1f%% in the first bucket, %.1f%% in the last (%+.1f percentage points between the halves of the window).",
+			result.Trend, first.NegativeProportion*100, last.NegativeProportion*100, result.Change*100)},
+	}
+	if result.Trend == "worsening" && last.TopNegativePattern != "" {
+		guidance.Suggestions = append(guidance.Suggestions, fmt.Sprintf("The top negative pattern of the last bucket is %q; use get_log_search to find its logs.", last.TopNegativePattern))
+	}
+	guidance.Suggestions = append(guidance.Suggestions, "Use get_log_patterns with negative: true and the from/to of a bucket to see its negative patterns.")
+	return guidance
+}
@@ -0,0 +1,270 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const defaultServiceMapSpans = 500
+
+type ServiceMapResult struct {
+	Query string `json:"query,omitempty"`
+	// SpanCount and TraceCount are the spans and traces of the sample the map is derived from
+	SpanCount  int              `json:"span_count"`
+	TraceCount int              `json:"trace_count"`
+	Services   []ServiceMapNode `json:"services"`
+	EdgeCount  int              `json:"edge_count"`
+	Guidance   *SearchGuidance  `json:"guidance,omitempty"`
+}
+
+// ServiceMapNode is a service of the map with the services it calls.
+type ServiceMapNode struct {
+	Service    string  `json:"service"`
+	Spans      int     `json:"spans"`
+	ErrorSpans int     `json:"error_spans"`
+	ErrorRate  float64 `json:"error_rate"`
+	// Entrypoint is true if the service has spans without parent, e.g. it receives external requests
+	Entrypoint bool          `json:"entrypoint,omitempty"`
+	Calls      []ServiceCall `json:"calls"`
+}
+
+// ServiceCall is an edge of the service map: the spans of Service whose parent span is in the
+// calling service.
+type ServiceCall struct {
+	Service      string  `json:"service"`
+	Requests     int     `json:"requests"`
+	Errors       int     `json:"errors"`
+	ErrorRate    float64 `json:"error_rate"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	latencyMs    float64
+}
+
+// GetServiceMapTool creates a tool returning the service dependency map derived from trace spans
+func GetServiceMapTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("get_service_map",
+			mcp.WithTitleAnnotation("Get Service Map"),
+			mcp.WithDescription(fmt.Sprintf(`Returns the service dependency map derived from the parent/child relationships of trace spans over a time window: every service with the services it calls, and the request count, error count, error rate and average latency of every call.
+
+Use this tool to find the upstream and downstream services of a service, e.g. the blast radius of a failing service or the dependencies a slow service waits on.
+The map is derived from a sample of up to %d spans of the most recent traces, the counts are those of the sample.
+A call from a service to itself is not an edge; spans whose parent is not in the sample only count for their service.
+
+Filter the traces with query, e.g. service.name:"checkout" for the traces going through checkout.`, traceSpanLimit)),
+			mcp.WithString("query",
+				mcp.Description(`CQL filter query of the traces (field:value syntax required), e.g. service.name:"frontend". Default: all traces.`),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("lookback",
+				mcp.Description("Lookback period in Go duration format, or days and weeks (e.g., 1h, 15m, 24h, 7d). Provide either lookback or from/to. Pass empty string to use from/to instead."),
+				mcp.DefaultString("1h"),
+			),
+			mcp.WithString("from",
+				mcp.Description("From datetime (ISO 8601: 2006-01-02T15:04:05.000Z). Use with 'to' when not using lookback."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("to",
+				mcp.Description("To datetime (ISO 8601: 2006-01-02T15:04:05.000Z). Use with 'from' when not using lookback."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description(fmt.Sprintf("Maximum number of spans sampled (default %d, max %d). More spans give more accurate counts.", defaultServiceMapSpans, traceSpanLimit)),
+				mcp.DefaultNumber(defaultServiceMapSpans),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			limit := defaultServiceMapSpans
+			if v, _ := params.Optional[float64](request, "limit"); v > 0 {
+				limit = int(v)
+			}
+			if limit > traceSpanLimit {
+				return mcp.NewToolResultError(fmt.Sprintf("limit must be at most %d", traceSpanLimit)), nil
+			}
+
+			query, _ := params.Optional[string](request, "query")
+			query = strings.TrimSpace(query)
+
+			queryParams := url.Values{}
+			if query != "" {
+				queryParams.Add("query", query)
+			}
+			queryParams.Add("limit", strconv.Itoa(limit))
+			queryParams.Add("order", "desc")
+			queryParams.Add("include_child_spans", "true")
+
+			from, _ := params.Optional[string](request, "from")
+			to, _ := params.Optional[string](request, "to")
+			lookback, _ := params.Optional[string](request, "lookback")
+			if from != "" {
+				queryParams.Add("from", from)
+				if to != "" {
+					queryParams.Add("to", to)
+				}
+			} else {
+				if lookback == "" {
+					lookback = "1h"
+				}
+				queryParams.Add("lookback", lookback)
+			}
+
+			items, err := fetchTraceSpans(ctx, client, queryParams)
+			if err != nil {
+				return nil, err
+			}
+
+			result := serviceMap(items)
+			result.Query = query
+			result.Guidance = serviceMapGuidance(result, len(items) >= limit)
+
+			r, err := json.Marshal(result)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
+
+// serviceMap groups the spans by trace, links them to their parents and aggregates the
+// cross-service parent/child links into the calls of the services.
+func serviceMap(items []map[string]any) ServiceMapResult {
+	result := ServiceMapResult{Services: []ServiceMapNode{}}
+
+	var traceIDs []string
+	traces := make(map[string][]map[string]any)
+	for _, item := range items {
+		traceID := recordString(item, "trace_id")
+		if _, ok := traces[traceID]; !ok {
+			traceIDs = append(traceIDs, traceID)
+		}
+		traces[traceID] = append(traces[traceID], item)
+	}
+	result.TraceCount = len(traceIDs)
+
+	services := make(map[string]*ServiceMapNode)
+	calls := make(map[[2]string]*ServiceCall)
+	service := func(name string) *ServiceMapNode {
+		if name == "" {
+			name = "unknown"
+		}
+		node, ok := services[name]
+		if !ok {
+			node = &ServiceMapNode{Service: name, Calls: []ServiceCall{}}
+			services[name] = node
+		}
+		return node
+	}
+
+	for _, traceID := range traceIDs {
+		nodes, order := buildTraceTree(traces[traceID])
+		result.SpanCount += len(order)
+		for _, span := range order {
+			callee := service(span.span.Service)
+			callee.Spans++
+			if span.failed() {
+				callee.ErrorSpans++
+			}
+
+			parent, ok := nodes[span.parentID]
+			if !ok || parent == span {
+				if span.parentID == "" {
+					callee.Entrypoint = true
+				}
+				continue
+			}
+			caller := service(parent.span.Service)
+			if caller == callee {
+				continue
+			}
+			key := [2]string{caller.Service, callee.Service}
+			call, ok := calls[key]
+			if !ok {
+				call = &ServiceCall{Service: callee.Service}
+				calls[key] = call
+			}
+			call.Requests++
+			call.latencyMs += span.span.DurationMs
+			if span.failed() {
+				call.Errors++
+			}
+		}
+	}
+
+	for key, call := range calls {
+		call.ErrorRate = serviceMapRate(call.Errors, call.Requests)
+		call.AvgLatencyMs = math.Round(call.latencyMs/float64(call.Requests)*1e3) / 1e3
+		caller := services[key[0]]
+		caller.Calls = append(caller.Calls, *call)
+	}
+	result.EdgeCount = len(calls)
+
+	for _, node := range services {
+		node.ErrorRate = serviceMapRate(node.ErrorSpans, node.Spans)
+		sort.Slice(node.Calls, func(i, j int) bool {
+			if node.Calls[i].Requests != node.Calls[j].Requests {
+				return node.Calls[i].Requests > node.Calls[j].Requests
+			}
+			return node.Calls[i].Service < node.Calls[j].Service
+		})
+		result.Services = append(result.Services, *node)
+	}
+	sort.Slice(result.Services, func(i, j int) bool { return result.Services[i].Service < result.Services[j].Service })
+	return result
+}
+
+// serviceMapRate returns the ratio of errors to requests, rounded to 4 decimals.
+func serviceMapRate(errors, requests int) float64 {
+	if requests == 0 {
+		return 0
+	}
+	return math.Round(float64(errors)/float64(requests)*1e4) / 1e4
+}
+
+func serviceMapGuidance(result ServiceMapResult, truncated bool) *SearchGuidance {
+	if result.SpanCount == 0 {
+		return &SearchGuidance{
+			ResultStatus: "empty",
+			NextSteps: []string{
+				"No spans found in the time window; widen the lookback or relax the query.",
+				"Check the services sending traces with facet_options (scope: \"trace\", facet_path: \"service.name\").",
+			},
+		}
+	}
+
+	guidance := &SearchGuidance{ResultStatus: "success"}
+	guidance.NextSteps = append(guidance.NextSteps, fmt.Sprintf("%d services and %d service-to-service calls in %d spans of %d traces.",
+		len(result.Services), result.EdgeCount, result.SpanCount, result.TraceCount))
+
+	var worst *ServiceCall
+	var worstCaller string
+	for _, node := range result.Services {
+		for i, call := range node.Calls {
+			if call.Errors > 0 && (worst == nil || call.ErrorRate > worst.ErrorRate) {
+				worst, worstCaller = &node.Calls[i], node.Service
+			}
+		}
+	}
+	if worst != nil {
+		guidance.Suggestions = append(guidance.Suggestions, fmt.Sprintf("Calls from %s to %s fail the most (error rate %.2f%%); use investigate_window with service %q to find why.",
+			worstCaller, worst.Service, worst.ErrorRate*100, worst.Service))
+	}
+	if result.EdgeCount == 0 {
+		guidance.Suggestions = append(guidance.Suggestions, "No calls between services were found; the parent spans may be in traces outside the sample, try a larger limit.")
+	}
+	if truncated {
+		guidance.Suggestions = append(guidance.Suggestions, "The sample hit the span limit, rarely called services may be missing; raise limit or narrow the window for a more complete map.")
+	}
+	return guidance
+}
@@ -72,7 +72,7 @@ func GetServices(ctx context.Context, client Client, opts ...QueryParamOption) (
 	}
 
 	graphURL.RawQuery = queryParams.Encode()
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, graphURL.String(), nil)
+	req, err := http.NewRequestWithContext(schemaRequest(ctx), http.MethodGet, graphURL.String(), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create services request: %v", err)
 	}
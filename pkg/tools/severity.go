@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// severitySynonyms maps the OpenTelemetry severity texts to the spellings sources commonly emit,
+// including the numeric levels of bunyan/pino style loggers (10 trace ... 60 fatal).
+var severitySynonyms = map[string][]string{
+	"TRACE": {"TRACE", "trace", "Trace", "TRC", "10"},
+	"DEBUG": {"DEBUG", "debug", "Debug", "DBG", "20"},
+	"INFO":  {"INFO", "info", "Info", "INFORMATION", "information", "Information", "NOTICE", "notice", "30"},
+	"WARN":  {"WARN", "warn", "Warn", "WARNING", "warning", "Warning", "WRN", "40"},
+	"ERROR": {"ERROR", "error", "Error", "ERR", "err", "50"},
+	"FATAL": {"FATAL", "fatal", "Fatal", "CRITICAL", "critical", "Critical", "CRIT", "crit", "PANIC", "panic", "EMERGENCY", "emergency", "60"},
+}
+
+var (
+	severityCanonical = func() map[string]string {
+		m := make(map[string]string)
+		for canonical, synonyms := range severitySynonyms {
+			for _, s := range synonyms {
+				m[strings.ToUpper(s)] = canonical
+			}
+		}
+		return m
+	}()
+
+	severityFilterPattern = regexp.MustCompile(`severity_text\s*:\s*"([^"]*)"`)
+)
+
+// NormalizeSeverity maps a severity to its OpenTelemetry severity text, e.g. "warning" and "40"
+// become "WARN". Unknown severities are returned unchanged.
+func NormalizeSeverity(severity string) string {
+	if canonical, ok := severityCanonical[strings.ToUpper(strings.TrimSpace(severity))]; ok {
+		return canonical
+	}
+	return severity
+}
+
+// ExpandSeverityQuery rewrites every severity_text:"X" filter of a CQL query to match all
+// spellings of X, e.g. severity_text:"WARN" becomes severity_text:("WARN" OR "warning" OR ...).
+func ExpandSeverityQuery(query string) string {
+	return severityFilterPattern.ReplaceAllStringFunc(query, func(filter string) string {
+		value := severityFilterPattern.FindStringSubmatch(filter)[1]
+		synonyms, ok := severitySynonyms[NormalizeSeverity(value)]
+		if !ok {
+			return filter
+		}
+
+		quoted := make([]string, 0, len(synonyms))
+		for _, s := range synonyms {
+			quoted = append(quoted, fmt.Sprintf("%q", s))
+		}
+		return fmt.Sprintf("severity_text:(%s)", strings.Join(quoted, " OR "))
+	})
+}
+
+// normalizeSeverityItems rewrites the severity_text of every item of a search response to its
+// OpenTelemetry severity text, keeping the original value in severity_text_original when it differs.
+// The body is returned unchanged if it is not a search response.
+func normalizeSeverityItems(bodyBytes []byte) []byte {
+	var resp map[string]json.RawMessage
+	if err := json.Unmarshal(bodyBytes, &resp); err != nil {
+		return bodyBytes
+	}
+
+	// Items stay raw but for severity_text, decoding them would round the numbers above 2^53, e.g.
+	// nanosecond timestamps
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal(resp["items"], &items); err != nil {
+		return bodyBytes
+	}
+
+	for _, item := range items {
+		var original string
+		if json.Unmarshal(item["severity_text"], &original) != nil {
+			continue
+		}
+
+		if normalized := NormalizeSeverity(original); normalized != original {
+			var ok bool
+			if item["severity_text"], ok = marshalRaw(normalized); !ok {
+				continue
+			}
+			item["severity_text_original"], _ = marshalRaw(original)
+		}
+	}
+
+	itemBytes, err := json.Marshal(items)
+	if err != nil {
+		return bodyBytes
+	}
+
+	resp["items"] = itemBytes
+	normalized, err := json.Marshal(resp)
+	if err != nil {
+		return bodyBytes
+	}
+	return normalized
+}
@@ -0,0 +1,239 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// shardThreshold is the time range above which log searches are split into shards.
+	shardThreshold = 3 * 24 * time.Hour
+	// shardWindow is the time range of every shard.
+	shardWindow = 24 * time.Hour
+	// shardConcurrency bounds the shards queried at once.
+	shardConcurrency = 4
+	// shardCursorPrefix marks cursors of sharded searches, which are passed back as the cursor
+	// argument like any other.
+	shardCursorPrefix = "shards."
+)
+
+type timeRange struct {
+	from, to time.Time
+}
+
+// ShardCursor is the position of a sharded search: the shard to continue in and the cursor of
+// the search of that shard.
+type ShardCursor struct {
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	Desc   bool      `json:"desc"`
+	Shard  int       `json:"shard"`
+	Cursor string    `json:"cursor,omitempty"`
+}
+
+func (c ShardCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return shardCursorPrefix + base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeShardCursor(cursor string) (ShardCursor, error) {
+	var c ShardCursor
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(cursor, shardCursorPrefix))
+	if err != nil {
+		return c, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, fmt.Errorf("invalid cursor: %w", err)
+	}
+	return c, nil
+}
+
+// ShardSummary tells how a search was split.
+type ShardSummary struct {
+	Shards  int    `json:"shards"`
+	Queried int    `json:"shards_queried"`
+	Window  string `json:"shard_window"`
+}
+
+// splitTimeRange splits the range into shards of shardWindow, ordered newest first if desc.
+func splitTimeRange(from, to time.Time, desc bool) []timeRange {
+	var shards []timeRange
+	for start := from; start.Before(to); start = start.Add(shardWindow) {
+		end := start.Add(shardWindow)
+		if end.After(to) {
+			end = to
+		}
+		shards = append(shards, timeRange{from: start, to: end})
+	}
+	if desc {
+		for i, j := 0, len(shards)-1; i < j; i, j = i+1, j-1 {
+			shards[i], shards[j] = shards[j], shards[i]
+		}
+	}
+	return shards
+}
+
+// logSearchShardCursor returns the cursor to start a sharded search with, if the search spans
+// more than shardThreshold or continues a sharded search.
+func logSearchShardCursor(queryParams url.Values, now time.Time) (ShardCursor, bool, error) {
+	if cursor := queryParams.Get("cursor"); strings.HasPrefix(cursor, shardCursorPrefix) {
+		c, err := decodeShardCursor(cursor)
+		return c, err == nil, err
+	}
+	if queryParams.Get("cursor") != "" {
+		return ShardCursor{}, false, nil
+	}
+
+	to := now
+	if t, err := time.Parse(time.RFC3339, queryParams.Get("to")); err == nil {
+		to = t
+	}
+
+	var from time.Time
+	if lookback := queryParams.Get("lookback"); lookback != "" {
+		d, err := ParseLookback(lookback)
+		if err != nil {
+			return ShardCursor{}, false, nil
+		}
+		from = to.Add(-d)
+	} else if t, err := time.Parse(time.RFC3339, queryParams.Get("from")); err == nil {
+		from = t
+	} else {
+		return ShardCursor{}, false, nil
+	}
+
+	if to.Sub(from) <= shardThreshold {
+		return ShardCursor{}, false, nil
+	}
+	return ShardCursor{From: from, To: to, Desc: !strings.EqualFold(queryParams.Get("order"), "asc")}, true, nil
+}
+
+type shardPage struct {
+	Items      []json.RawMessage `json:"items"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+	Stats      *QueryStats       `json:"query_stats,omitempty"`
+}
+
+// searchLogShards runs a log search over daily shards, starting at the cursor. Shards are queried
+// in waves of shardConcurrency until limit items are found; as the shards do not overlap, joining
+// their pages in shard order keeps the global order. It returns a search response body with a
+// next_cursor continuing the sharded search.
+func searchLogShards(ctx context.Context, client Client, queryParams url.Values, start ShardCursor, limit int) ([]byte, ShardSummary, error) {
+	shards := splitTimeRange(start.From, start.To, start.Desc)
+	summary := ShardSummary{Shards: len(shards), Window: shardWindow.String()}
+
+	fetch := func(i int, cursor string, limit int) (shardPage, error) {
+		shardParams := url.Values{}
+		for k, v := range queryParams {
+			shardParams[k] = v
+		}
+		shardParams.Del("lookback")
+		shardParams.Del("cursor")
+		shardParams.Set("from", shards[i].from.Format(isoTimeLayout))
+		shardParams.Set("to", shards[i].to.Format(isoTimeLayout))
+		shardParams.Set("limit", strconv.Itoa(limit))
+		if cursor != "" {
+			shardParams.Set("cursor", cursor)
+		}
+
+		var page shardPage
+		bodyBytes, err := searchLogs(ctx, client, shardParams)
+		if err != nil {
+			return page, err
+		}
+		if err := json.Unmarshal(bodyBytes, &page); err != nil {
+			return page, fmt.Errorf("failed to decode log search response: %w", err)
+		}
+		return page, nil
+	}
+	startCursor := func(i int) string {
+		if i == start.Shard {
+			return start.Cursor
+		}
+		return ""
+	}
+
+	var (
+		pages = make(map[int]shardPage)
+		found int
+		// stats sums the stats of every shard query, including the ones fetched again
+		stats QueryStats
+	)
+	addStats := func(page shardPage) {
+		if page.Stats != nil {
+			stats.add(*page.Stats)
+		}
+	}
+	for next := start.Shard; next < len(shards) && found < limit; next += shardConcurrency {
+		wave := min(shardConcurrency, len(shards)-next)
+		results := make([]shardPage, wave)
+		errs := make([]error, wave)
+
+		var wg sync.WaitGroup
+		for j := 0; j < wave; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[j], errs[j] = fetch(next+j, startCursor(next+j), limit)
+			}()
+		}
+		wg.Wait()
+
+		for j := 0; j < wave; j++ {
+			if errs[j] != nil {
+				return nil, summary, errs[j]
+			}
+			pages[next+j] = results[j]
+			found += len(results[j].Items)
+			addStats(results[j])
+		}
+		summary.Queried += wave
+	}
+
+	response := shardPage{Items: make([]json.RawMessage, 0, limit)}
+	for i := start.Shard; i < len(shards); i++ {
+		page, ok := pages[i]
+		if !ok {
+			break
+		}
+
+		need := limit - len(response.Items)
+		if len(page.Items) > need {
+			// Fetch exactly what is needed from this shard again, for a cursor right after it
+			var err error
+			if page, err = fetch(i, startCursor(i), need); err != nil {
+				return nil, summary, err
+			}
+			addStats(page)
+			page.Items = page.Items[:min(need, len(page.Items))]
+		}
+		response.Items = append(response.Items, page.Items...)
+
+		if len(response.Items) < limit && page.NextCursor == "" {
+			continue
+		}
+		if page.NextCursor != "" {
+			response.NextCursor = ShardCursor{From: start.From, To: start.To, Desc: start.Desc, Shard: i, Cursor: page.NextCursor}.encode()
+		} else if i+1 < len(shards) {
+			response.NextCursor = ShardCursor{From: start.From, To: start.To, Desc: start.Desc, Shard: i + 1}.encode()
+		}
+		break
+	}
+
+	if stats != (QueryStats{}) {
+		response.Stats = &stats
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		return nil, summary, fmt.Errorf("failed to marshal log search response: %w", err)
+	}
+	return body, summary, nil
+}
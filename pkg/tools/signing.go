@@ -0,0 +1,163 @@
+package tools
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// SignatureMetaKey is the key of the signature in the _meta of signed tool results.
+	SignatureMetaKey = "com.edgedelta/signature"
+	// SignatureAlgorithm is the only signing algorithm.
+	SignatureAlgorithm = "HMAC-SHA256"
+	// MinSigningKeyBytes is the minimum length of signing keys, the output size of SHA-256.
+	MinSigningKeyBytes = 32
+)
+
+// ResultSignature is the signature of a tool result.
+type ResultSignature struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"key_id"`
+	SignedAt  string `json:"signed_at"`
+	Signature string `json:"signature"`
+}
+
+// ResultSigner signs tool results with HMAC-SHA256, so that systems archiving transcripts can
+// verify results were not changed after the server returned them. The signed message is made of
+// newline separated lines:
+//
+//	tool name
+//	signed_at, RFC 3339
+//	is_error, "true" or "false"
+//	one line per content item: the hex SHA-256 of its text, or of its data for images and audio
+//
+// The signature is the base64 encoded HMAC of the message.
+type ResultSigner struct {
+	keyID string
+	key   []byte
+}
+
+func NewResultSigner(keyID string, key []byte) (*ResultSigner, error) {
+	if len(key) < MinSigningKeyBytes {
+		return nil, fmt.Errorf("signing key must be at least %d bytes, got %d", MinSigningKeyBytes, len(key))
+	}
+	if keyID == "" {
+		return nil, errors.New("signing key ID must not be empty")
+	}
+	return &ResultSigner{keyID: keyID, key: key}, nil
+}
+
+// Sign adds the signature of the result to its _meta.
+func (s *ResultSigner) Sign(toolName string, result *mcp.CallToolResult, now time.Time) error {
+	signedAt := now.UTC().Format(time.RFC3339)
+	signature, err := s.signature(toolName, signedAt, result)
+	if err != nil {
+		return err
+	}
+
+	if result.Meta == nil {
+		result.Meta = &mcp.Meta{}
+	}
+	if result.Meta.AdditionalFields == nil {
+		result.Meta.AdditionalFields = make(map[string]any)
+	}
+	result.Meta.AdditionalFields[SignatureMetaKey] = ResultSignature{
+		Algorithm: SignatureAlgorithm,
+		KeyID:     s.keyID,
+		SignedAt:  signedAt,
+		Signature: signature,
+	}
+	return nil
+}
+
+// Verify checks the signature of a result of the tool, as decoded from a transcript.
+func (s *ResultSigner) Verify(toolName string, result *mcp.CallToolResult) error {
+	if result.Meta == nil {
+		return errors.New("result is not signed")
+	}
+	raw, ok := result.Meta.AdditionalFields[SignatureMetaKey]
+	if !ok {
+		return errors.New("result is not signed")
+	}
+
+	// The signature is a ResultSignature when signed in process and a map when decoded
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	var signature ResultSignature
+	if err := json.Unmarshal(data, &signature); err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	if signature.Algorithm != SignatureAlgorithm {
+		return fmt.Errorf("unsupported signature algorithm %q", signature.Algorithm)
+	}
+	if signature.KeyID != s.keyID {
+		return fmt.Errorf("result is signed with key %q, not %q", signature.KeyID, s.keyID)
+	}
+
+	expected, err := s.signature(toolName, signature.SignedAt, result)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(expected), []byte(signature.Signature)) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func (s *ResultSigner) signature(toolName, signedAt string, result *mcp.CallToolResult) (string, error) {
+	lines := []string{toolName, signedAt, strconv.FormatBool(result.IsError)}
+	for _, content := range result.Content {
+		var data []byte
+		switch c := content.(type) {
+		case mcp.TextContent:
+			data = []byte(c.Text)
+		case mcp.ImageContent:
+			data = []byte(c.Data)
+		case mcp.AudioContent:
+			data = []byte(c.Data)
+		default:
+			var err error
+			if data, err = json.Marshal(c); err != nil {
+				return "", fmt.Errorf("failed to marshal content to sign: %w", err)
+			}
+		}
+		sum := sha256.Sum256(data)
+		lines = append(lines, hex.EncodeToString(sum[:]))
+	}
+
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// SignResults returns a tool middleware that signs every tool result. It must be the outermost
+// middleware, so that no other middleware changes results after they are signed.
+func SignResults(signer *ResultSigner) ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			result, err := next(ctx, request)
+			if err != nil || result == nil {
+				return result, err
+			}
+
+			if err := signer.Sign(request.Params.Name, result, time.Now()); err != nil {
+				return nil, fmt.Errorf("failed to sign result: %w", err)
+			}
+			return result, nil
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// SessionSnapshotVersion is the version of the snapshot format.
+	SessionSnapshotVersion = 1
+	// maxSessionEvents caps the timeline of a session, older calls are dropped first.
+	maxSessionEvents = 200
+	// sessionIdleTTL is how long the timeline of an idle session is kept.
+	sessionIdleTTL = 24 * time.Hour
+)
+
+// snapshotTools are not recorded in timelines, exporting or importing is not a step of the
+// investigation.
+var snapshotTools = map[string]bool{
+	"export_session_snapshot": true,
+	"import_session_snapshot": true,
+}
+
+// SessionEvent is a tool call of an investigation. The result is kept as a digest only, so a
+// snapshot does not carry the data the calls returned.
+type SessionEvent struct {
+	At           time.Time      `json:"at"`
+	Tool         string         `json:"tool"`
+	Arguments    map[string]any `json:"arguments,omitempty"`
+	IsError      bool           `json:"is_error,omitempty"`
+	ResultSHA256 string         `json:"result_sha256"`
+	ResultBytes  int            `json:"result_bytes"`
+	// Imported marks calls made in the session the snapshot was imported from
+	Imported bool `json:"imported,omitempty"`
+}
+
+// SnapshotQuery is a distinct query of an investigation, to re-run it.
+type SnapshotQuery struct {
+	Tool      string         `json:"tool"`
+	Arguments map[string]any `json:"arguments"`
+}
+
+// SessionSnapshot is a shareable bundle of an investigation.
+type SessionSnapshot struct {
+	Version     int             `json:"version"`
+	ExportedAt  time.Time       `json:"exported_at"`
+	OrgID       string          `json:"org_id,omitempty"`
+	Title       string          `json:"title,omitempty"`
+	Summary     string          `json:"summary,omitempty"`
+	Conclusions []string        `json:"conclusions,omitempty"`
+	Queries     []SnapshotQuery `json:"queries"`
+	Timeline    []SessionEvent  `json:"timeline"`
+}
+
+type sessionTimeline struct {
+	events      []SessionEvent
+	conclusions []string
+	lastSeen    time.Time
+}
+
+// SessionRecorder records the tool calls of every session, for export_session_snapshot.
+// Sessions are identified by their MCP session ID, or by caller when the transport has no
+// sessions, e.g. stateless HTTP.
+type SessionRecorder struct {
+	mu       sync.Mutex
+	sessions map[string]*sessionTimeline
+}
+
+func NewSessionRecorder() *SessionRecorder {
+	return &SessionRecorder{sessions: make(map[string]*sessionTimeline)}
+}
+
+func sessionKey(ctx context.Context) (string, error) {
+	if session := server.ClientSessionFromContext(ctx); session != nil && session.SessionID() != "" {
+		return "session:" + session.SessionID(), nil
+	}
+	key, err := callerKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	return "caller:" + key, nil
+}
+
+// timeline returns the timeline of the session, dropping idle ones. Callers must hold r.mu.
+func (r *SessionRecorder) timeline(key string, now time.Time) *sessionTimeline {
+	for k, t := range r.sessions {
+		if now.Sub(t.lastSeen) > sessionIdleTTL {
+			delete(r.sessions, k)
+		}
+	}
+
+	t, ok := r.sessions[key]
+	if !ok {
+		t = &sessionTimeline{}
+		r.sessions[key] = t
+	}
+	t.lastSeen = now
+	return t
+}
+
+func (t *sessionTimeline) add(events ...SessionEvent) {
+	t.events = append(t.events, events...)
+	if over := len(t.events) - maxSessionEvents; over > 0 {
+		t.events = append([]SessionEvent{}, t.events[over:]...)
+	}
+}
+
+// Record adds a call to the timeline of the session in ctx.
+func (r *SessionRecorder) Record(ctx context.Context, event SessionEvent) {
+	key, err := sessionKey(ctx)
+	if err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.timeline(key, event.At).add(event)
+}
+
+// Snapshot returns the snapshot of the session in ctx.
+func (r *SessionRecorder) Snapshot(ctx context.Context, title, summary string, conclusions []string) (*SessionSnapshot, error) {
+	key, err := sessionKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	r.mu.Lock()
+	t := r.timeline(key, now)
+	events := append([]SessionEvent{}, t.events...)
+	allConclusions := append(append([]string{}, t.conclusions...), conclusions...)
+	r.mu.Unlock()
+
+	snapshot := &SessionSnapshot{
+		Version:     SessionSnapshotVersion,
+		ExportedAt:  now,
+		Title:       title,
+		Summary:     summary,
+		Conclusions: allConclusions,
+		Queries:     []SnapshotQuery{},
+		Timeline:    events,
+	}
+	if keys, err := FetchContextKeys(ctx); err == nil {
+		snapshot.OrgID = keys.OrgID
+	}
+
+	seen := make(map[string]bool)
+	for _, event := range events {
+		if event.IsError {
+			continue
+		}
+		query := SnapshotQuery{Tool: event.Tool, Arguments: event.Arguments}
+		if query.Arguments == nil {
+			query.Arguments = map[string]any{}
+		}
+		key, err := json.Marshal(query)
+		if err != nil || seen[string(key)] {
+			continue
+		}
+		seen[string(key)] = true
+		snapshot.Queries = append(snapshot.Queries, query)
+	}
+	return snapshot, nil
+}
+
+// Import adds the timeline and conclusions of the snapshot to the session in ctx, so a later
+// export carries the whole investigation.
+func (r *SessionRecorder) Import(ctx context.Context, snapshot *SessionSnapshot) error {
+	key, err := sessionKey(ctx)
+	if err != nil {
+		return err
+	}
+
+	imported := make([]SessionEvent, len(snapshot.Timeline))
+	for i, event := range snapshot.Timeline {
+		event.Imported = true
+		imported[i] = event
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := r.timeline(key, time.Now().UTC())
+	events := t.events
+	t.events = nil
+	t.add(append(imported, events...)...)
+	t.conclusions = append(t.conclusions, snapshot.Conclusions...)
+	return nil
+}
+
+// resultDigest returns the SHA-256 and the size of the text of the result.
+func resultDigest(result *mcp.CallToolResult) (string, int) {
+	h := sha256.New()
+	size := 0
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			h.Write([]byte(text.Text))
+			size += len(text.Text)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), size
+}
+
+// RecordSession returns a tool middleware that records every tool call in the timeline of the
+// caller's session.
+func RecordSession(recorder *SessionRecorder) ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			result, err := next(ctx, request)
+			if snapshotTools[request.Params.Name] {
+				return result, err
+			}
+
+			event := SessionEvent{
+				At:        time.Now().UTC(),
+				Tool:      request.Params.Name,
+				Arguments: maps.Clone(request.GetArguments()),
+				IsError:   err != nil || result == nil || result.IsError,
+			}
+			if result != nil {
+				event.ResultSHA256, event.ResultBytes = resultDigest(result)
+			}
+			recorder.Record(ctx, event)
+
+			return result, err
+		}
+	}
+}
+
+// ExportSessionSnapshotTool creates a tool to export the investigation of the session
+func ExportSessionSnapshotTool(recorder *SessionRecorder) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("export_session_snapshot",
+			mcp.WithTitleAnnotation("Export Session Snapshot"),
+			mcp.WithDescription(`Export the investigation of this session as a single JSON snapshot, to hand it off to a teammate who continues it in another session or MCP client with import_session_snapshot.
+
+The snapshot contains the distinct queries run (to re-run them), the timeline of tool calls with a digest of each result (results themselves are not included, re-run the queries for fresh data), and your summary and conclusions.
+
+Write the summary and conclusions for a reader who has not seen this conversation: what was investigated, what was found, what is still open.`),
+			mcp.WithString("title",
+				mcp.Description("Short title of the investigation, e.g. 'Checkout 5xx spike 2024-05-02'"),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("summary",
+				mcp.Description("Summary of the investigation so far"),
+				mcp.DefaultString(""),
+			),
+			mcp.WithArray("conclusions",
+				mcp.Description("Findings and open questions, one per item"),
+				mcp.WithStringItems(),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			title, _ := params.Optional[string](request, "title")
+			summary, _ := params.Optional[string](request, "summary")
+			conclusions := request.GetStringSlice("conclusions", nil)
+
+			snapshot, err := recorder.Snapshot(ctx, title, summary, conclusions)
+			if err != nil {
+				return nil, err
+			}
+
+			r, err := json.Marshal(snapshot)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal snapshot: %w", err)
+			}
+
+			guidance, err := json.Marshal(DiscoveryGuidance{
+				ResultStatus: "success",
+				NextSteps: []string{
+					"Give the snapshot JSON (the first content item) to the user to share; it is imported with import_session_snapshot tool.",
+				},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal guidance: %w", err)
+			}
+
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{mcp.NewTextContent(string(r)), mcp.NewTextContent(string(guidance))},
+			}, nil
+		}
+}
+
+// ImportSessionSnapshotTool creates a tool to continue an investigation exported from another session
+func ImportSessionSnapshotTool(recorder *SessionRecorder) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("import_session_snapshot",
+			mcp.WithTitleAnnotation("Import Session Snapshot"),
+			mcp.WithDescription(`Import an investigation snapshot exported with export_session_snapshot, e.g. by a teammate, to continue it in this session.
+
+Returns the snapshot's summary, conclusions and queries. The snapshot does not contain results: re-run the queries relevant to the next step for fresh data. The imported timeline and conclusions are carried over into the next export of this session.`),
+			mcp.WithString("snapshot",
+				mcp.Description("Snapshot JSON as returned by export_session_snapshot"),
+				mcp.Required(),
+			),
+			mcp.WithReadOnlyHintAnnotation(false),
+			mcp.WithIdempotentHintAnnotation(false),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			raw, err := request.RequireString("snapshot")
+			if err != nil {
+				return mcp.NewToolResultError("missing required parameter: snapshot"), err
+			}
+
+			var snapshot SessionSnapshot
+			if err := json.Unmarshal([]byte(raw), &snapshot); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid snapshot, expected the JSON returned by export_session_snapshot: %v", err)), nil
+			}
+			if snapshot.Version != SessionSnapshotVersion {
+				return mcp.NewToolResultError(fmt.Sprintf("unsupported snapshot version %d, this server supports version %d", snapshot.Version, SessionSnapshotVersion)), nil
+			}
+
+			if err := recorder.Import(ctx, &snapshot); err != nil {
+				return nil, err
+			}
+
+			guidance := DiscoveryGuidance{
+				ResultStatus: "success",
+				NextSteps: []string{
+					"Summarize the imported investigation to the user and ask how to continue.",
+					"Re-run the queries relevant to the next step with their tool and arguments for fresh data.",
+				},
+			}
+			if keys, err := FetchContextKeys(ctx); err == nil && snapshot.OrgID != "" && snapshot.OrgID != keys.OrgID {
+				guidance.Suggestions = []string{fmt.Sprintf("The snapshot was exported for org %s, this session uses org %s; queries may return different data.", snapshot.OrgID, keys.OrgID)}
+			}
+
+			r, err := json.Marshal(map[string]any{
+				"title":       snapshot.Title,
+				"exported_at": snapshot.ExportedAt,
+				"org_id":      snapshot.OrgID,
+				"summary":     snapshot.Summary,
+				"conclusions": snapshot.Conclusions,
+				"queries":     snapshot.Queries,
+				"tool_calls":  len(snapshot.Timeline),
+				"tools_used":  toolsUsed(snapshot.Timeline),
+				"guidance":    guidance,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
+
+// toolsUsed lists the tools of the timeline with their call counts, e.g. "get_log_search x3".
+func toolsUsed(events []SessionEvent) string {
+	var (
+		order  []string
+		counts = make(map[string]int)
+	)
+	for _, event := range events {
+		if counts[event.Tool] == 0 {
+			order = append(order, event.Tool)
+		}
+		counts[event.Tool]++
+	}
+
+	used := make([]string, len(order))
+	for i, tool := range order {
+		used[i] = fmt.Sprintf("%s x%d", tool, counts[tool])
+	}
+	return strings.Join(used, ", ")
+}
@@ -0,0 +1,258 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// SpanEvent is an event of a span, e.g. an exception or a log message.
+type SpanEvent struct {
+	Name       string         `json:"name"`
+	Timestamp  string         `json:"timestamp,omitempty"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// SpanLink links a span to a span of another trace, e.g. of the message it consumed.
+type SpanLink struct {
+	TraceID    string         `json:"trace_id"`
+	SpanID     string         `json:"span_id"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+type SpanEventsResult struct {
+	TraceID  string          `json:"trace_id,omitempty"`
+	SpanID   string          `json:"span_id"`
+	Service  string          `json:"service,omitempty"`
+	Name     string          `json:"name,omitempty"`
+	Status   string          `json:"status,omitempty"`
+	Events   []SpanEvent     `json:"events"`
+	Links    []SpanLink      `json:"links"`
+	Guidance *SearchGuidance `json:"guidance,omitempty"`
+}
+
+// SpanEventSummary lists the events and links of a span of trace search results, without the
+// attributes of the events but the exception type and message.
+type SpanEventSummary struct {
+	SpanID  string             `json:"span_id"`
+	Service string             `json:"service,omitempty"`
+	Name    string             `json:"name,omitempty"`
+	Events  []SpanEventOutline `json:"events,omitempty"`
+	Links   []SpanLink         `json:"links,omitempty"`
+}
+
+type SpanEventOutline struct {
+	Name      string `json:"name"`
+	Timestamp string `json:"timestamp,omitempty"`
+	Type      string `json:"exception_type,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+// GetSpanEventsTool creates a tool to get the events and links of a span
+func GetSpanEventsTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("get_span_events",
+			mcp.WithTitleAnnotation("Get Span Events"),
+			mcp.WithDescription(`Returns the events and links of a span with all their attributes, e.g. the exception.stacktrace of exception events.
+
+Use it after get_trace_timeline or get_trace_error_chain to read the stack trace of a failing span. get_trace_timeline results only outline the events of their spans.`),
+			mcp.WithString("span_id",
+				mcp.Description("ID of the span."),
+				mcp.Required(),
+			),
+			mcp.WithString("trace_id",
+				mcp.Description("ID of the trace of the span, narrows the search when known."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("lookback",
+				mcp.Description("Lookback period in Go duration format, or days and weeks (e.g., 1h, 24h, 7d) the span is searched in. Provide either lookback or from/to."),
+				mcp.DefaultString("24h"),
+			),
+			mcp.WithString("from",
+				mcp.Description("From datetime (ISO 8601: 2006-01-02T15:04:05.000Z). Use with 'to' when not using lookback."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("to",
+				mcp.Description("To datetime (ISO 8601: 2006-01-02T15:04:05.000Z). Use with 'from' when not using lookback."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			spanID, err := request.RequireString("span_id")
+			if err != nil || strings.TrimSpace(spanID) == "" {
+				return mcp.NewToolResultError("missing required parameter: span_id"), nil
+			}
+			spanID = strings.TrimSpace(spanID)
+
+			query := fmt.Sprintf("span_id:%q", spanID)
+			if traceID, _ := params.Optional[string](request, "trace_id"); strings.TrimSpace(traceID) != "" {
+				query = fmt.Sprintf("trace_id:%q AND %s", strings.TrimSpace(traceID), query)
+			}
+
+			queryParams := url.Values{}
+			queryParams.Add("query", query)
+			queryParams.Add("limit", "1")
+
+			from, _ := params.Optional[string](request, "from")
+			to, _ := params.Optional[string](request, "to")
+			lookback, _ := params.Optional[string](request, "lookback")
+			if from != "" {
+				queryParams.Add("from", from)
+				if to != "" {
+					queryParams.Add("to", to)
+				}
+			} else {
+				if lookback == "" {
+					lookback = "24h"
+				}
+				queryParams.Add("lookback", lookback)
+			}
+
+			bodyBytes, err := searchTraces(ctx, client, queryParams)
+			if err != nil {
+				return nil, err
+			}
+
+			var resp struct {
+				Items []map[string]any `json:"items"`
+			}
+			if err := json.Unmarshal(bodyBytes, &resp); err != nil {
+				return nil, fmt.Errorf("failed to decode trace search response: %v", err)
+			}
+
+			result := SpanEventsResult{SpanID: spanID, Events: []SpanEvent{}, Links: []SpanLink{}}
+			for _, item := range resp.Items {
+				if recordString(item, "span_id") != spanID {
+					continue
+				}
+				result.TraceID = recordString(item, "trace_id")
+				result.Service = recordString(item, "service.name")
+				result.Name = recordString(item, "name")
+				result.Status = strings.ToUpper(recordString(item, "status.code"))
+				result.Events = spanEvents(item)
+				result.Links = spanLinks(item)
+				break
+			}
+			result.Guidance = spanEventsGuidance(result, queryParams.Get("lookback"))
+
+			r, err := json.Marshal(result)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
+
+// spanEvents returns the events of a span of trace search results.
+func spanEvents(item map[string]any) []SpanEvent {
+	events := []SpanEvent{}
+	values, _ := item["events"].([]any)
+	for _, v := range values {
+		event, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		attributes, _ := event["attributes"].(map[string]any)
+		events = append(events, SpanEvent{
+			Name:       recordString(event, "name"),
+			Timestamp:  recordString(event, "timestamp"),
+			Attributes: attributes,
+		})
+	}
+	return events
+}
+
+// spanLinks returns the links of a span of trace search results.
+func spanLinks(item map[string]any) []SpanLink {
+	links := []SpanLink{}
+	values, _ := item["links"].([]any)
+	for _, v := range values {
+		link, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		attributes, _ := link["attributes"].(map[string]any)
+		links = append(links, SpanLink{
+			TraceID:    recordString(link, "trace_id"),
+			SpanID:     recordString(link, "span_id"),
+			Attributes: attributes,
+		})
+	}
+	return links
+}
+
+// spanEventSummaries outlines the events and links of the spans of trace search results that have
+// any.
+func spanEventSummaries(bodyBytes []byte) []SpanEventSummary {
+	var resp struct {
+		Items []map[string]any `json:"items"`
+	}
+	if err := json.Unmarshal(bodyBytes, &resp); err != nil {
+		return nil
+	}
+
+	var summaries []SpanEventSummary
+	for _, item := range resp.Items {
+		events, links := spanEvents(item), spanLinks(item)
+		if len(events) == 0 && len(links) == 0 {
+			continue
+		}
+
+		summary := SpanEventSummary{
+			SpanID:  recordString(item, "span_id"),
+			Service: recordString(item, "service.name"),
+			Name:    recordString(item, "name"),
+			Links:   links,
+		}
+		for _, event := range events {
+			outline := SpanEventOutline{Name: event.Name, Timestamp: event.Timestamp}
+			if event.Name == "exception" {
+				outline.Type = recordString(event.Attributes, "exception.type")
+				outline.Message = recordString(event.Attributes, "exception.message")
+			} else {
+				outline.Message = recordString(event.Attributes, "message")
+			}
+			summary.Events = append(summary.Events, outline)
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+func spanEventsGuidance(result SpanEventsResult, lookback string) *SearchGuidance {
+	if result.TraceID == "" && result.Service == "" && result.Name == "" {
+		steps := []string{"Verify the span_id, e.g. with get_trace_timeline."}
+		if lookback != "" {
+			steps = append(steps, fmt.Sprintf("The span was searched in the last %s; pass a longer lookback or from/to if it is older.", lookback))
+		}
+		return &SearchGuidance{ResultStatus: "empty", NextSteps: steps}
+	}
+
+	guidance := &SearchGuidance{ResultStatus: "success"}
+	if len(result.Events) == 0 && len(result.Links) == 0 {
+		guidance.NextSteps = []string{"The span has no events or links; search the logs of its service around the span instead."}
+		return guidance
+	}
+	for _, event := range result.Events {
+		if event.Name == "exception" {
+			guidance.NextSteps = append(guidance.NextSteps, "Read exception.stacktrace of the exception events to find the failing code.")
+			break
+		}
+	}
+	if len(result.Links) > 0 {
+		guidance.Suggestions = append(guidance.Suggestions, "Follow the links with get_trace_error_chain on their trace_id, e.g. to the producer of a consumed message.")
+	}
+	if result.TraceID != "" {
+		guidance.Suggestions = append(guidance.Suggestions, fmt.Sprintf("Use get_trace_error_chain with trace_id: %s to see how the error propagated.", result.TraceID))
+	}
+	return guidance
+}
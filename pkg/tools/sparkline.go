@@ -0,0 +1,147 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Render modes of the graph tools.
+const (
+	RenderRaw       = "raw"
+	RenderSparkline = "sparkline"
+)
+
+// sparklineWidth is the maximum number of characters of a sparkline, longer series are averaged
+// into that many buckets.
+const sparklineWidth = 60
+
+var sparklineBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// renderParam is the render parameter of the graph tools.
+func renderParam() mcp.ToolOption {
+	return mcp.WithString("render",
+		mcp.Description(`How to return the series. "raw" returns the points of the API response. "sparkline" returns one compact unicode sparkline per series with its min, max and last values, far fewer tokens than the points; use it to spot trends and spikes, and "raw" when exact values per timestamp are needed.`),
+		mcp.Enum(RenderRaw, RenderSparkline),
+		mcp.DefaultString(RenderRaw),
+	)
+}
+
+// SeriesSparkline summarizes a series of a timeseries graph.
+type SeriesSparkline struct {
+	Formula   string   `json:"formula,omitempty"`
+	Group     []string `json:"group,omitempty"`
+	Sparkline string   `json:"sparkline"`
+	Points    int      `json:"points"`
+	From      string   `json:"from"`
+	To        string   `json:"to"`
+	Min       float64  `json:"min"`
+	Max       float64  `json:"max"`
+	Last      float64  `json:"last"`
+}
+
+// graphSparklines returns the sparklines of the series of a graph response, in the order of the
+// formulas and of their records. Records without timeseries, e.g. of table graphs, are skipped.
+func graphSparklines(bodyBytes []byte) ([]SeriesSparkline, error) {
+	type recordsResponse struct {
+		Records []graphTimeseriesRecord `json:"records"`
+	}
+
+	var formulas map[string]json.RawMessage
+	if err := json.Unmarshal(bodyBytes, &formulas); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal graph response: %w", err)
+	}
+	// Single query responses have the records at the top level
+	if _, ok := formulas["records"]; ok {
+		formulas = map[string]json.RawMessage{"": bodyBytes}
+	}
+
+	names := make([]string, 0, len(formulas))
+	for name := range formulas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sparklines []SeriesSparkline
+	for _, name := range names {
+		var resp recordsResponse
+		if err := json.Unmarshal(formulas[name], &resp); err != nil {
+			continue
+		}
+		for _, record := range resp.Records {
+			if len(record.Timeseries) == 0 {
+				continue
+			}
+			sparkline := seriesSparkline(record)
+			sparkline.Formula = name
+			sparklines = append(sparklines, sparkline)
+		}
+	}
+	return sparklines, nil
+}
+
+func seriesSparkline(record graphTimeseriesRecord) SeriesSparkline {
+	points := record.Timeseries
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Timestamp < points[j].Timestamp })
+
+	values := make([]float64, len(points))
+	for i, point := range points {
+		values[i] = point.Value
+	}
+
+	s := SeriesSparkline{
+		Group:     record.Values,
+		Sparkline: sparkline(values),
+		Points:    len(points),
+		From:      time.UnixMilli(points[0].Timestamp).UTC().Format(isoTimeLayout),
+		To:        time.UnixMilli(points[len(points)-1].Timestamp).UTC().Format(isoTimeLayout),
+		Min:       values[0],
+		Max:       values[0],
+		Last:      values[len(values)-1],
+	}
+	for _, v := range values {
+		s.Min = min(s.Min, v)
+		s.Max = max(s.Max, v)
+	}
+	return s
+}
+
+// sparkline draws the values with block characters scaled between their min and max.
+func sparkline(values []float64) string {
+	if len(values) > sparklineWidth {
+		values = downsample(values, sparklineWidth)
+	}
+
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo, hi = min(lo, v), max(hi, v)
+	}
+
+	var b strings.Builder
+	for _, v := range values {
+		level := 0
+		if hi > lo {
+			level = int((v - lo) / (hi - lo) * float64(len(sparklineBlocks)-1))
+		}
+		b.WriteRune(sparklineBlocks[level])
+	}
+	return b.String()
+}
+
+// downsample averages the values into n buckets.
+func downsample(values []float64, n int) []float64 {
+	buckets := make([]float64, n)
+	for i := range buckets {
+		start, end := i*len(values)/n, (i+1)*len(values)/n
+		var sum float64
+		for _, v := range values[start:end] {
+			sum += v
+		}
+		buckets[i] = sum / float64(end-start)
+	}
+	return buckets
+}
@@ -0,0 +1,45 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ResultStream sends the partial results of a long-running tool call to the client as progress
+// notifications while the call is in flight. Partial results are only sent to clients that asked
+// for progress with a progress token, so the final tool result must still hold the full result.
+//
+// Over the HTTP transport the response to the call is upgraded to an SSE stream on the first
+// notification; over stdio notifications are written to stdout in between responses.
+type ResultStream struct {
+	server *server.MCPServer
+	token  mcp.ProgressToken
+}
+
+func NewResultStream(ctx context.Context, request mcp.CallToolRequest) *ResultStream {
+	s := &ResultStream{server: server.ServerFromContext(ctx)}
+	if request.Params.Meta != nil {
+		s.token = request.Params.Meta.ProgressToken
+	}
+	return s
+}
+
+// Enabled reports whether the client receives partial results.
+func (s *ResultStream) Enabled() bool {
+	return s.server != nil && s.token != nil
+}
+
+// Send sends a partial result. progress must increase with every call, e.g. the number of items
+// produced so far.
+func (s *ResultStream) Send(ctx context.Context, progress int, message string) error {
+	if !s.Enabled() {
+		return nil
+	}
+	return s.server.SendNotificationToClient(ctx, "notifications/progress", map[string]any{
+		"progressToken": s.token,
+		"progress":      progress,
+		"message":       message,
+	})
+}
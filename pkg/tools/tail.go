@@ -0,0 +1,269 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	maxTailDuration    = 2 * time.Minute
+	minTailInterval    = time.Second
+	maxTailLines       = 1000
+	tailSearchPageSize = "1000"
+	// tailOverlap is how far back each poll searches again, to pick up logs that were ingested late.
+	tailOverlap = 30 * time.Second
+)
+
+type TailLogsResult struct {
+	Query     string          `json:"query,omitempty"`
+	From      string          `json:"from"`
+	To        string          `json:"to"`
+	Lines     []TailLogLine   `json:"lines"`
+	Streamed  bool            `json:"streamed"`
+	Truncated bool            `json:"truncated,omitempty"`
+	Guidance  *SearchGuidance `json:"guidance,omitempty"`
+}
+
+type TailLogLine struct {
+	Timestamp string `json:"timestamp"`
+	Severity  string `json:"severity,omitempty"`
+	Service   string `json:"service,omitempty"`
+	Body      string `json:"body"`
+}
+
+func (l TailLogLine) String() string {
+	return strings.TrimSpace(fmt.Sprintf("%s %s %s %s", l.Timestamp, l.Severity, l.Service, l.Body))
+}
+
+// TailLogsTool creates a tool to follow new logs matching a query for a while
+func TailLogsTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("tail_logs",
+			mcp.WithTitleAnnotation("Tail Logs"),
+			mcp.WithDescription(`Follows new logs matching a CQL query, like "tail -f", for up to 2 minutes.
+
+Use this tool to watch logs live, e.g. while reproducing an issue or right after a deployment.
+Clients that pass a progress token receive new lines as progress notifications as they arrive;
+the result always holds all lines seen.
+
+For past logs use get_log_search instead.`),
+			mcp.WithString("query",
+				mcp.Description(`CQL query to filter logs, e.g. service.name:"checkout" AND severity_text:"ERROR". Leave empty to follow all logs.`),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("duration",
+				mcp.Description("How long to follow logs in Go duration format (e.g., 30s, 1m). Max 2m."),
+				mcp.DefaultString("30s"),
+			),
+			mcp.WithString("poll_interval",
+				mcp.Description("How often to check for new logs in Go duration format. Min 1s."),
+				mcp.DefaultString("5s"),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description("Stop after this many lines. Default: 200, max 1000"),
+				mcp.DefaultNumber(200),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(false),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			query, _ := params.Optional[string](request, "query")
+
+			duration, err := tailDurationParam(request, "duration", 30*time.Second)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			if duration > maxTailDuration {
+				duration = maxTailDuration
+			}
+
+			interval, err := tailDurationParam(request, "poll_interval", 5*time.Second)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			if interval < minTailInterval {
+				interval = minTailInterval
+			}
+
+			limit := request.GetInt("limit", 200)
+			if limit <= 0 {
+				limit = 200
+			}
+			if limit > maxTailLines {
+				limit = maxTailLines
+			}
+
+			stream := NewResultStream(ctx, request)
+			start := time.Now().UTC()
+			result := TailLogsResult{
+				Query:    query,
+				From:     start.Format(isoTimeLayout),
+				Lines:    []TailLogLine{},
+				Streamed: stream.Enabled(),
+			}
+
+			tail := logTail{client: client, query: query, started: start, since: start, seen: make(map[string]time.Time)}
+			deadline := time.NewTimer(duration)
+			defer deadline.Stop()
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+		poll:
+			for {
+				select {
+				case <-ctx.Done():
+					break poll
+				case <-deadline.C:
+					break poll
+				case <-ticker.C:
+				}
+
+				lines, err := tail.poll(ctx)
+				if err != nil {
+					if ctx.Err() != nil {
+						break poll
+					}
+					return nil, err
+				}
+				if len(lines) == 0 {
+					continue
+				}
+
+				if remaining := limit - len(result.Lines); len(lines) >= remaining {
+					lines = lines[:remaining]
+					result.Truncated = true
+				}
+				result.Lines = append(result.Lines, lines...)
+
+				text := make([]string, len(lines))
+				for i, line := range lines {
+					text[i] = line.String()
+				}
+				// A failed notification only loses the partial result, the final result has every line.
+				_ = stream.Send(ctx, len(result.Lines), strings.Join(text, "\n"))
+
+				if result.Truncated {
+					break poll
+				}
+			}
+
+			result.To = time.Now().UTC().Format(isoTimeLayout)
+			result.Guidance = tailLogsGuidance(result, duration)
+
+			r, err := json.Marshal(result)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
+
+func tailDurationParam(request mcp.CallToolRequest, name string, fallback time.Duration) (time.Duration, error) {
+	s, _ := params.Optional[string](request, name)
+	if s == "" {
+		return fallback, nil
+	}
+	d, err := ParseLookback(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid %s %q, expected a duration such as 30s", name, s)
+	}
+	return d, nil
+}
+
+// logTail polls log search for logs newer than the last poll.
+type logTail struct {
+	client  Client
+	query   string
+	started time.Time
+	since   time.Time
+	// seen holds the logs of the overlap window that were already returned.
+	seen map[string]time.Time
+}
+
+func (t *logTail) poll(ctx context.Context) ([]TailLogLine, error) {
+	now := time.Now().UTC()
+	from := t.since.Add(-tailOverlap)
+
+	queryParams := url.Values{}
+	if t.query != "" {
+		queryParams.Add("query", t.query)
+	}
+	queryParams.Add("from", from.Format(isoTimeLayout))
+	queryParams.Add("to", now.Format(isoTimeLayout))
+	queryParams.Add("limit", tailSearchPageSize)
+	queryParams.Add("order", "asc")
+
+	bodyBytes, err := searchLogs(ctx, t.client, queryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Items []map[string]any `json:"items"`
+	}
+	if err := json.Unmarshal(bodyBytes, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode log search response: %v", err)
+	}
+
+	var lines []TailLogLine
+	for _, item := range resp.Items {
+		line := TailLogLine{
+			Timestamp: recordString(item, "timestamp"),
+			Severity:  recordString(item, "severity_text"),
+			Service:   recordString(item, "service.name"),
+			Body:      recordString(item, "body"),
+		}
+
+		timestamp, err := time.Parse(time.RFC3339Nano, line.Timestamp)
+		if err != nil {
+			continue
+		}
+		// Logs from before the tail started are history, not new lines.
+		if timestamp.Before(t.started) {
+			continue
+		}
+
+		key := line.Timestamp + "\x00" + line.Service + "\x00" + line.Body
+		if _, ok := t.seen[key]; ok {
+			continue
+		}
+		t.seen[key] = timestamp
+		lines = append(lines, line)
+	}
+
+	for key, timestamp := range t.seen {
+		if timestamp.Before(from) {
+			delete(t.seen, key)
+		}
+	}
+	t.since = now
+	return lines, nil
+}
+
+func tailLogsGuidance(result TailLogsResult, duration time.Duration) *SearchGuidance {
+	if len(result.Lines) == 0 {
+		return &SearchGuidance{
+			ResultStatus: "empty",
+			NextSteps: []string{
+				fmt.Sprintf("No new logs matched in %s; logs can take a while to be ingested, so tail again for longer.", duration),
+				"Check that the query matches past logs with get_log_search and a lookback of 15m.",
+			},
+		}
+	}
+
+	guidance := &SearchGuidance{ResultStatus: "success"}
+	if result.Truncated {
+		guidance.NextSteps = append(guidance.NextSteps, "The line limit was reached before the duration ended; narrow the query or raise the limit to see every line.")
+	}
+	guidance.Suggestions = append(guidance.Suggestions, fmt.Sprintf("Search the followed time range again with get_log_search from:%q to:%q.", result.From, result.To))
+	return guidance
+}
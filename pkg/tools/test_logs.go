@@ -0,0 +1,232 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	maxTestLogLines = 100
+	// testLogMarkerField is the attribute carrying the debug marker of every test log.
+	testLogMarkerField = "ed_debug_marker"
+)
+
+type SendTestLogsResponse struct {
+	Marker      string            `json:"marker"`
+	ConfID      string            `json:"conf_id"`
+	Pipeline    string            `json:"pipeline"`
+	NodeName    string            `json:"node_name"`
+	Sent        int               `json:"sent"`
+	SearchQuery string            `json:"search_query"`
+	Guidance    *PipelineGuidance `json:"guidance,omitempty"`
+}
+
+// testLog is the JSON line posted to the HTTP intake for every test log line.
+type testLog struct {
+	Body   string `json:"body"`
+	Marker string `json:"ed_debug_marker"`
+}
+
+// SendTestLogsTool creates a tool to send ad-hoc log lines through an ingestion pipeline
+func SendTestLogsTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("send_test_logs",
+			mcp.WithTitleAnnotation("Send Test Logs"),
+			mcp.WithDescription(fmt.Sprintf(`Sends a handful of log lines to the HTTP intake of an ingestion pipeline to verify end-to-end processing.
+
+WHEN TO USE:
+- After adding a processor or pattern to a pipeline, to check that sample lines are parsed, masked or routed as expected
+- To check that an ingestion pipeline accepts data at all
+
+Every line is sent as {"body": <line>, "%s": <marker>}. The marker is returned so the lines can be found with
+get_log_search tool within a couple of minutes.
+
+WORKFLOW:
+1. send_test_logs(lines: ["..."]) → note the marker
+2. get_log_search(query: "\"<marker>\"", lookback: "15m") → inspect how the lines were processed
+
+Uses the default ingestion pipeline ("%s") unless conf_id is given.`, testLogMarkerField, ingestionPipelineTag)),
+			mcp.WithArray("lines",
+				mcp.Description(fmt.Sprintf("Log lines to send, at most %d.", maxTestLogLines)),
+				mcp.WithStringItems(),
+				mcp.Required(),
+			),
+			mcp.WithString("conf_id",
+				mcp.Description("Configuration ID of the ingestion pipeline to send to. Use get_ingestion_endpoint tool to list them."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("node_name",
+				mcp.Description("Name of the http_ingestion_input node, if the pipeline has several."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("marker",
+				mcp.Description("Debug marker to tag the lines with. A unique one is generated by default."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithReadOnlyHintAnnotation(false),
+			mcp.WithIdempotentHintAnnotation(false),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(true),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			lines := request.GetStringSlice("lines", nil)
+			if len(lines) == 0 {
+				return mcp.NewToolResultError("missing required parameter: lines"), nil
+			}
+
+			if len(lines) > maxTestLogLines {
+				return mcp.NewToolResultError(fmt.Sprintf("too many lines: %d, at most %d can be sent", len(lines), maxTestLogLines)), nil
+			}
+
+			confID, _ := params.Optional[string](request, "conf_id")
+			nodeName, _ := params.Optional[string](request, "node_name")
+
+			marker, _ := params.Optional[string](request, "marker")
+			if marker == "" {
+				marker = "mcp-test-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+			}
+
+			target, err := resolveTestLogTarget(ctx, client, confID, nodeName)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+
+			var body bytes.Buffer
+			encoder := json.NewEncoder(&body)
+			for _, line := range lines {
+				if err := encoder.Encode(testLog{Body: line, Marker: marker}); err != nil {
+					return nil, fmt.Errorf("failed to encode test log: %w", err)
+				}
+			}
+
+			if err := postTestLogs(ctx, client, target.url, body.Bytes()); err != nil {
+				return nil, err
+			}
+
+			searchQuery := fmt.Sprintf("%q", marker)
+			response := SendTestLogsResponse{
+				Marker:      marker,
+				ConfID:      target.pipeline.ConfID,
+				Pipeline:    target.pipeline.Name,
+				NodeName:    target.pipeline.NodeName,
+				Sent:        len(lines),
+				SearchQuery: searchQuery,
+				Guidance: &PipelineGuidance{
+					ResultStatus: "success",
+					NextSteps: []string{
+						fmt.Sprintf("Wait a minute or two, then use get_log_search tool with query %s and lookback 15m to find the lines.", searchQuery),
+						"Compare the stored lines with the ones sent to verify the pipeline's processors.",
+					},
+					Suggestions: []string{
+						"If the lines do not show up after a few minutes, check the pipeline with get_pipeline_config tool and the agent with get_agent_self_logs tool.",
+					},
+				},
+			}
+
+			r, err := json.Marshal(response)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
+
+type testLogTarget struct {
+	pipeline ingestionPipelineOut
+	url      string
+}
+
+// resolveTestLogTarget finds the logs intake URL of the requested ingestion pipeline and node,
+// defaulting to the auto-provisioned pipeline and its first HTTP ingest node.
+func resolveTestLogTarget(ctx context.Context, client Client, confID, nodeName string) (*testLogTarget, error) {
+	confs, err := ListConfs(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list configurations: %w", err)
+	}
+
+	var conf *ConfSummary
+	for _, c := range filterIngestionConfs(confs) {
+		switch {
+		case confID != "":
+			if c.ID == confID {
+				conf = c
+			}
+		case conf == nil, c.Tag == ingestionPipelineTag:
+			conf = c
+		}
+	}
+
+	if conf == nil {
+		if confID != "" {
+			return nil, fmt.Errorf("ingestion pipeline %q not found, use get_ingestion_endpoint tool to list them", confID)
+		}
+		return nil, fmt.Errorf("no ingestion pipelines (fleet_type=%q) visible to this caller", IngestionPipelineFleetType)
+	}
+
+	endpoints, err := GetIngestionEndpoints(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ingestion endpoints: %w", err)
+	}
+	if endpoints.HTTPS == nil {
+		return nil, fmt.Errorf("backend did not return HTTPS ingestion endpoints")
+	}
+
+	base, err := url.Parse(endpoints.HTTPS.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base_url %q: %v", endpoints.HTTPS.BaseURL, err)
+	}
+
+	for _, p := range resolvePipeline(ctx, client, conf, base, endpoints.HTTPS) {
+		if nodeName != "" && p.NodeName != nodeName {
+			continue
+		}
+		if p.Error != "" {
+			return nil, fmt.Errorf("ingestion pipeline %s: %s", conf.ID, p.Error)
+		}
+		for _, e := range p.Endpoints {
+			if e.DataType == "logs" {
+				return &testLogTarget{pipeline: p, url: e.URL}, nil
+			}
+		}
+	}
+
+	if nodeName != "" {
+		return nil, fmt.Errorf("no logs endpoint for node %q of ingestion pipeline %s", nodeName, conf.ID)
+	}
+	return nil, fmt.Errorf("no logs endpoint for ingestion pipeline %s", conf.ID)
+}
+
+// postTestLogs posts the body to the intake URL, which authenticates with the token in its query.
+func postTestLogs(ctx context.Context, client Client, intakeURL string, body []byte) error {
+	// Do not forward the caller's API credentials to the intake.
+	ctx = context.WithValue(ctx, BearerTokenKey, "")
+	ctx = context.WithValue(ctx, EDTokenKey, "")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, intakeURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send test logs: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to send test logs, status code %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+	return nil
+}
@@ -0,0 +1,325 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const ticketTimeout = 15 * time.Second
+
+// TicketSeverities are the severities of incident tickets, lowest first.
+var TicketSeverities = []string{"low", "medium", "high", "critical"}
+
+// IncidentTicket is the incident summary posted by create_incident_ticket, and the data of ticket
+// templates.
+type IncidentTicket struct {
+	Title    string    `json:"title"`
+	Summary  string    `json:"summary"`
+	Severity string    `json:"severity"`
+	Services []string  `json:"services,omitempty"`
+	Findings []string  `json:"findings,omitempty"`
+	Links    []string  `json:"links,omitempty"`
+	OrgID    string    `json:"org_id,omitempty"`
+	Created  time.Time `json:"created"`
+	// Queries are the distinct queries of the investigation of the session, to re-run them
+	Queries []SnapshotQuery `json:"queries,omitempty"`
+	// Description renders the fields above as plain text, for ticket systems with a single
+	// description field
+	Description string `json:"description"`
+}
+
+// TicketWebhook posts incident tickets to a webhook, e.g. the REST API of Jira or ServiceNow.
+type TicketWebhook struct {
+	url     string
+	headers map[string]string
+	// body renders the request body, the ticket as JSON if nil
+	body   *template.Template
+	client *http.Client
+}
+
+// NewTicketWebhook creates a webhook posting to url with the headers, e.g. Authorization. The
+// request body is rendered with bodyTemplate, a text/template of the IncidentTicket, or is the
+// ticket as JSON if bodyTemplate is empty. The json function of templates encodes a value as
+// JSON, e.g. {"fields": {"summary": {{json .Title}}}}.
+func NewTicketWebhook(url string, headers map[string]string, bodyTemplate string) (*TicketWebhook, error) {
+	w := &TicketWebhook{url: url, headers: headers, client: &http.Client{Timeout: ticketTimeout}}
+	if bodyTemplate != "" {
+		t, err := template.New("ticket").Funcs(template.FuncMap{"json": templateJSON, "join": strings.Join}).Parse(bodyTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ticket template: %w", err)
+		}
+		w.body = t
+	}
+	return w, nil
+}
+
+// LoadTicketTemplate reads a ticket body template from a file.
+func LoadTicketTemplate(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read ticket template: %w", err)
+	}
+	return string(data), nil
+}
+
+func templateJSON(v any) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// Body renders the request body of the ticket.
+func (w *TicketWebhook) Body(ticket IncidentTicket) ([]byte, error) {
+	if w.body == nil {
+		body, err := json.Marshal(ticket)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal ticket: %w", err)
+		}
+		return body, nil
+	}
+
+	var b bytes.Buffer
+	if err := w.body.Execute(&b, ticket); err != nil {
+		return nil, fmt.Errorf("failed to render ticket template: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+// Post posts the body and returns the status code and body of the response.
+func (w *TicketWebhook) Post(ctx context.Context, body []byte) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	for key, value := range w.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+	return resp.StatusCode, respBody, nil
+}
+
+type CreateIncidentTicketResult struct {
+	Created    bool   `json:"created"`
+	DryRun     bool   `json:"dry_run,omitempty"`
+	StatusCode int    `json:"status_code,omitempty"`
+	TicketID   string `json:"ticket_id,omitempty"`
+	TicketURL  string `json:"ticket_url,omitempty"`
+	// Body is the request body of dry runs, and the response body when no ticket id is found
+	Body     json.RawMessage    `json:"body,omitempty"`
+	Guidance *DashboardGuidance `json:"guidance,omitempty"`
+}
+
+// CreateIncidentTicketTool creates a tool to open a ticket with the findings of the investigation
+func CreateIncidentTicketTool(webhook *TicketWebhook, recorder *SessionRecorder) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("create_incident_ticket",
+			mcp.WithTitleAnnotation("Create Incident Ticket"),
+			mcp.WithDescription(`Open an incident ticket in the ticketing system configured for this server (e.g. Jira or ServiceNow), as the final step of an investigation.
+
+The ticket holds the title, summary, severity, affected services, findings and links you pass, plus the findings and distinct queries recorded in this session (see export_session_snapshot).
+Write it for an on-call engineer who has not seen this conversation: what is broken, since when, the evidence and what is still open.
+Use dry_run first to check the ticket, then call again without it to create it; every call without dry_run opens a new ticket.`),
+			mcp.WithString("title",
+				mcp.Required(),
+				mcp.Description("Short title of the incident, e.g. 'Checkout 5xx spike after payments deploy'"),
+			),
+			mcp.WithString("summary",
+				mcp.Required(),
+				mcp.Description("What happened, the impact and the likely cause"),
+			),
+			mcp.WithString("severity",
+				mcp.Description("Severity of the incident"),
+				mcp.Enum(TicketSeverities...),
+				mcp.DefaultString("medium"),
+			),
+			mcp.WithArray("services",
+				mcp.Description("Affected services"),
+				mcp.WithStringItems(),
+			),
+			mcp.WithArray("findings",
+				mcp.Description("Evidence and open questions, one per item"),
+				mcp.WithStringItems(),
+			),
+			mcp.WithArray("links",
+				mcp.Description("Links to the evidence, e.g. the ui_link of search results"),
+				mcp.WithStringItems(),
+			),
+			mcp.WithBoolean("dry_run",
+				mcp.Description("If true, return the request that would be sent without creating the ticket"),
+			),
+			mcp.WithReadOnlyHintAnnotation(false),
+			mcp.WithIdempotentHintAnnotation(false),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(true),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			title, err := request.RequireString("title")
+			if err != nil || strings.TrimSpace(title) == "" {
+				return mcp.NewToolResultError("title is required"), nil
+			}
+			summary, err := request.RequireString("summary")
+			if err != nil || strings.TrimSpace(summary) == "" {
+				return mcp.NewToolResultError("summary is required"), nil
+			}
+			severity, _ := params.Optional[string](request, "severity")
+			if severity == "" {
+				severity = "medium"
+			}
+			if !slices.Contains(TicketSeverities, severity) {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid severity %q, expected one of %s", severity, strings.Join(TicketSeverities, ", "))), nil
+			}
+
+			ticket := IncidentTicket{
+				Title:    title,
+				Summary:  summary,
+				Severity: severity,
+				Services: request.GetStringSlice("services", nil),
+				Findings: request.GetStringSlice("findings", nil),
+				Links:    request.GetStringSlice("links", nil),
+				Created:  time.Now().UTC(),
+			}
+			// The findings and queries of the session complete the ones passed
+			if snapshot, err := recorder.Snapshot(ctx, title, summary, nil); err == nil {
+				ticket.OrgID = snapshot.OrgID
+				for _, finding := range snapshot.Conclusions {
+					if !slices.Contains(ticket.Findings, finding) {
+						ticket.Findings = append(ticket.Findings, finding)
+					}
+				}
+				ticket.Queries = snapshot.Queries
+			}
+			ticket.Description = ticketDescription(ticket)
+
+			body, err := webhook.Body(ticket)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+
+			result := CreateIncidentTicketResult{}
+			if dryRun, _ := params.Optional[bool](request, "dry_run"); dryRun {
+				result.DryRun = true
+				result.Body = jsonOrString(body)
+				result.Guidance = &DashboardGuidance{
+					ResultStatus: "dry_run",
+					NextSteps:    []string{"Check the ticket with the user, then call create_incident_ticket again without dry_run to create it."},
+				}
+				return ticketResult(result)
+			}
+
+			statusCode, respBody, err := webhook.Post(ctx, body)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create incident ticket: %w", err)
+			}
+			if statusCode >= http.StatusBadRequest {
+				return nil, fmt.Errorf("failed to create incident ticket, status code %d: %s", statusCode, string(respBody))
+			}
+
+			result.Created, result.StatusCode = true, statusCode
+			var doc any
+			if json.Unmarshal(respBody, &doc) == nil {
+				result.TicketID, result.TicketURL = ticketRef(doc)
+			}
+			if result.TicketID == "" && len(respBody) > 0 {
+				result.Body = jsonOrString(respBody)
+			}
+			result.Guidance = &DashboardGuidance{
+				ResultStatus: "success",
+				NextSteps:    []string{"Share the ticket with the user. Do not call create_incident_ticket again for this incident, it would open a duplicate ticket."},
+			}
+			return ticketResult(result)
+		}
+}
+
+func ticketResult(result CreateIncidentTicketResult) (*mcp.CallToolResult, error) {
+	r, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal response: %w", err)
+	}
+	return mcp.NewToolResultText(string(r)), nil
+}
+
+// ticketDescription renders the ticket as plain text.
+func ticketDescription(ticket IncidentTicket) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\nSeverity: %s\n", ticket.Summary, ticket.Severity)
+	if len(ticket.Services) > 0 {
+		fmt.Fprintf(&b, "Services: %s\n", strings.Join(ticket.Services, ", "))
+	}
+	if len(ticket.Findings) > 0 {
+		b.WriteString("\nFindings:\n")
+		for _, finding := range ticket.Findings {
+			fmt.Fprintf(&b, "- %s\n", finding)
+		}
+	}
+	if len(ticket.Links) > 0 {
+		b.WriteString("\nLinks:\n")
+		for _, link := range ticket.Links {
+			fmt.Fprintf(&b, "- %s\n", link)
+		}
+	}
+	if len(ticket.Queries) > 0 {
+		b.WriteString("\nQueries:\n")
+		for _, query := range ticket.Queries {
+			args, _ := json.Marshal(query.Arguments)
+			fmt.Fprintf(&b, "- %s %s\n", query.Tool, args)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// ticketRef returns the id and the URL of the ticket in the response of the ticket system, e.g.
+// {"key": "OPS-12", "self": "..."} for Jira or {"result": {"number": "INC001", "sys_id": "..."}}
+// for ServiceNow.
+func ticketRef(doc any) (id, url string) {
+	object, ok := doc.(map[string]any)
+	if !ok {
+		return "", ""
+	}
+	if result, ok := object["result"].(map[string]any); ok {
+		object = result
+	}
+	for _, key := range []string{"key", "number", "id", "sys_id"} {
+		if v, ok := object[key]; ok && v != nil {
+			id = fmt.Sprint(v)
+			break
+		}
+	}
+	for _, key := range []string{"url", "html_url", "link", "self"} {
+		if v, ok := object[key].(string); ok && v != "" {
+			url = v
+			break
+		}
+	}
+	return id, url
+}
+
+// jsonOrString returns data as is if it is JSON, or as a JSON string.
+func jsonOrString(data []byte) json.RawMessage {
+	if json.Valid(data) {
+		return data
+	}
+	encoded, _ := json.Marshal(string(data))
+	return encoded
+}
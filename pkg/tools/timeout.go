@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// DefaultRequestTimeout is the deadline of tool calls when none is configured.
+const DefaultRequestTimeout = time.Minute
+
+// ToolTimeouts bounds how long tool calls may run. A zero timeout means no deadline.
+type ToolTimeouts struct {
+	Default time.Duration
+	PerTool map[string]time.Duration
+}
+
+// DefaultToolTimeouts are the deadlines of tools that run longer than a request by design.
+var DefaultToolTimeouts = map[string]time.Duration{
+	// tail_logs follows logs for up to maxTailDuration, then searches once more
+	"tail_logs": maxTailDuration + 30*time.Second,
+	// render_dashboard runs the queries of up to maxDashboardWidgets widgets
+	"render_dashboard": 2 * time.Minute,
+}
+
+// For returns the timeout that applies to the tool.
+func (t ToolTimeouts) For(toolName string) time.Duration {
+	if d, ok := t.PerTool[toolName]; ok {
+		return d
+	}
+	return t.Default
+}
+
+// Enabled reports whether any timeout is configured.
+func (t ToolTimeouts) Enabled() bool {
+	return t.Default > 0 || len(t.PerTool) > 0
+}
+
+// ParseToolTimeouts parses per tool timeouts in the form "get_metric_graph=2m,tail_logs=5m".
+func ParseToolTimeouts(s string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration)
+	for _, entry := range strings.Split(s, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+
+		toolName, timeout, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid tool timeout entry %q, expected <tool>=<duration>", entry)
+		}
+
+		d, err := ParseLookback(timeout)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid timeout for tool %s: %q", toolName, timeout)
+		}
+		timeouts[strings.TrimSpace(toolName)] = d
+	}
+	return timeouts, nil
+}
+
+// ToolDeadlines returns a tool middleware that runs every tool call with the deadline of its
+// timeout. API requests made with the context of the call are canceled at the deadline, and the
+// call returns a retryable ED_MCP_TIMEOUT error then even if the handler has not returned yet, so a
+// slow query cannot hang the MCP call.
+func ToolDeadlines(timeouts ToolTimeouts) ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			timeout := timeouts.For(request.Params.Name)
+			if timeout <= 0 {
+				return next(ctx, request)
+			}
+
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			type callResult struct {
+				result *mcp.CallToolResult
+				err    error
+			}
+			// Buffered so the handler does not block once the call has returned
+			done := make(chan callResult, 1)
+			go func() {
+				result, err := next(ctx, request)
+				done <- callResult{result: result, err: err}
+			}()
+
+			select {
+			case call := <-done:
+				// Handlers report the canceled requests in their own words
+				failed := call.err != nil || (call.result != nil && call.result.IsError)
+				if failed && ctx.Err() == context.DeadlineExceeded {
+					return nil, timeoutError(request.Params.Name, timeout)
+				}
+				return call.result, call.err
+			case <-ctx.Done():
+				if ctx.Err() == context.DeadlineExceeded {
+					return nil, timeoutError(request.Params.Name, timeout)
+				}
+				return nil, ctx.Err()
+			}
+		}
+	}
+}
+
+func timeoutError(toolName string, timeout time.Duration) *ToolError {
+	return &ToolError{
+		Code:      ErrCodeTimeout,
+		Message:   fmt.Sprintf("%s did not complete within %s; narrow the time range or the query and retry", toolName, timeout),
+		Retryable: true,
+	}
+}
@@ -0,0 +1,134 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const toolDocsURIPrefix = "docs://tool/"
+
+// ToolDocsResource serves the full documentation of the tools whose descriptions were compacted,
+// see ToolDocs.
+var ToolDocsResource = mcp.NewResourceTemplate(
+	toolDocsURIPrefix+"{name}",
+	"Tool Documentation",
+	mcp.WithTemplateDescription(`Full documentation of a tool: its workflow, query syntax, examples and parameters.
+Read it before calling a tool for the first time, e.g. docs://tool/get_log_search.`),
+	mcp.WithTemplateMIMEType("text/markdown"),
+)
+
+// ToolDocs compacts the descriptions of tools and of their parameters to their first line, keeping
+// the full descriptions to serve them with ToolDocsResource. It saves the context of
+// clients listing many tools.
+type ToolDocs struct {
+	mu   sync.RWMutex
+	docs map[string]string
+}
+
+func NewToolDocs() *ToolDocs {
+	return &ToolDocs{docs: make(map[string]string)}
+}
+
+// Compact records the documentation of the tool and returns it with a one line description
+// pointing to the documentation.
+func (d *ToolDocs) Compact(tool mcp.Tool) mcp.Tool {
+	d.mu.Lock()
+	d.docs[tool.Name] = toolDoc(tool)
+	d.mu.Unlock()
+
+	tool.Description = fmt.Sprintf("%s Full documentation: %s%s", firstLine(tool.Description), toolDocsURIPrefix, tool.Name)
+
+	// The properties are copied, tools may share them, e.g. FacetOptionsTool
+	properties := make(map[string]any, len(tool.InputSchema.Properties))
+	for name, v := range tool.InputSchema.Properties {
+		if property, ok := v.(map[string]any); ok {
+			if description, _ := property["description"].(string); strings.Contains(strings.TrimSpace(description), "\n") {
+				compacted := make(map[string]any, len(property))
+				for k, v := range property {
+					compacted[k] = v
+				}
+				compacted["description"] = firstLine(description)
+				v = compacted
+			}
+		}
+		properties[name] = v
+	}
+	tool.InputSchema.Properties = properties
+	return tool
+}
+
+// Doc returns the documentation of the tool.
+func (d *ToolDocs) Doc(name string) (string, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	doc, ok := d.docs[name]
+	return doc, ok
+}
+
+func (d *ToolDocs) ResourceHandler() server.ResourceTemplateHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		name := strings.TrimPrefix(request.Params.URI, toolDocsURIPrefix)
+		doc, ok := d.Doc(name)
+		if !ok {
+			return nil, fmt.Errorf("no documentation for tool %q", name)
+		}
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      request.Params.URI,
+				MIMEType: "text/markdown",
+				Text:     doc,
+			},
+		}, nil
+	}
+}
+
+// toolDoc renders the description and the parameters of the tool as markdown.
+func toolDoc(tool mcp.Tool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n%s\n", tool.Name, strings.TrimSpace(tool.Description))
+
+	if len(tool.InputSchema.Properties) == 0 {
+		return b.String()
+	}
+	names := make([]string, 0, len(tool.InputSchema.Properties))
+	for name := range tool.InputSchema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	b.WriteString("\n## Parameters\n")
+	for _, name := range names {
+		property, _ := tool.InputSchema.Properties[name].(map[string]any)
+		line := "- `" + name + "`"
+		if propertyType, _ := property["type"].(string); propertyType != "" {
+			line += " (" + propertyType
+			if slices.Contains(tool.InputSchema.Required, name) {
+				line += ", required"
+			}
+			line += ")"
+		}
+		if description, _ := property["description"].(string); description != "" {
+			// Multi-line descriptions stay in the list item
+			line += ": " + strings.ReplaceAll(strings.TrimSpace(description), "\n", "\n  ")
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+// firstLine returns the first non-empty line of s.
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
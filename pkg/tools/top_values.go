@@ -0,0 +1,253 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	defaultTopValues = 10
+	maxTopValues     = 100
+)
+
+type TopValuesResult struct {
+	Scope string `json:"scope"`
+	Facet string `json:"facet"`
+	Query string `json:"query_used"`
+	// Total is the count of all the matching records, Other the count of the values not listed
+	Total    int64          `json:"total"`
+	Values   []TopValue     `json:"values"`
+	Other    int64          `json:"other,omitempty"`
+	UILink   string         `json:"ui_link,omitempty"`
+	Warnings []GraphWarning `json:"warnings,omitempty"`
+	Stats    *QueryStats    `json:"stats,omitempty"`
+	Guidance *GraphGuidance `json:"guidance,omitempty"`
+}
+
+type TopValue struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+	// Share is the percentage of the total count
+	Share float64 `json:"share"`
+}
+
+// TopValuesTool creates a tool to list the most frequent values of a facet
+func TopValuesTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("top_values",
+			mcp.WithTitleAnnotation("Top Values"),
+			mcp.WithDescription(fmt.Sprintf(`Return the most frequent values of a facet among the records matching a query over a time range, with their count and percentage of all the matching records.
+
+Use this tool to answer "which services log the most errors?" or "which endpoints have the most failing requests?".
+Unlike facet_options, the counts honor the query and the time range.
+
+Scopes: log, trace, event and pattern count records; metric counts the data points of metric_name.
+Returns the top %d values by default, up to %d. Use facets tool to see the facets of a scope.`, defaultTopValues, maxTopValues)),
+			mcp.WithString("scope",
+				mcp.Description("Scope of the records"),
+				mcp.Enum(GraphScopes...),
+				mcp.DefaultString("log"),
+			),
+			mcp.WithString("facet",
+				mcp.Description("Facet to count the values of, e.g. service.name, host.name, severity_text, @http.status_code"),
+				mcp.Required(),
+			),
+			mcp.WithString("query",
+				mcp.Description(`CQL filter query, e.g. severity_text:"ERROR". Use "*" for all records.`),
+				mcp.DefaultString("*"),
+			),
+			mcp.WithString("metric_name",
+				mcp.Description("Exact metric name, required for the metric scope. Use search_metrics to find it."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description(fmt.Sprintf("Number of values to return. Default: %d, max %d", defaultTopValues, maxTopValues)),
+				mcp.DefaultNumber(defaultTopValues),
+			),
+			mcp.WithString("lookback",
+				mcp.Description("Lookback period in GOLANG duration format, or days and weeks. e.g. (1h, 15m, 24h, 7d, 1w). Either provide from/to or just lookback. Pass empty string to use from/to instead."),
+				mcp.DefaultString("1h"),
+			),
+			mcp.WithString("from",
+				mcp.Description("From datetime in ISO format 2006-01-02T15:04:05.000Z."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("to",
+				mcp.Description("To datetime in ISO format 2006-01-02T15:04:05.000Z."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			scope, _ := params.Optional[string](request, "scope")
+			if scope == "" {
+				scope = "log"
+			}
+			if !slices.Contains(GraphScopes, scope) {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid scope %q, expected one of %s", scope, strings.Join(GraphScopes, ", "))), nil
+			}
+
+			facet, err := request.RequireString("facet")
+			if facet = strings.TrimSpace(facet); err != nil || facet == "" {
+				return mcp.NewToolResultError("missing required parameter: facet"), nil
+			}
+			if !facetPathPattern.MatchString(facet) {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid facet %q, expected a single facet path such as service.name", facet)), nil
+			}
+
+			filter, _ := params.Optional[string](request, "query")
+			if filter = strings.TrimSpace(filter); filter == "" {
+				filter = "*"
+			}
+
+			limit := defaultTopValues
+			if v, _ := params.Optional[float64](request, "limit"); v > 0 {
+				limit = int(v)
+			}
+			if limit > maxTopValues {
+				return mcp.NewToolResultError(fmt.Sprintf("limit must be at most %d", maxTopValues)), nil
+			}
+
+			// Q1 counts the records by value and Q2 all of them, for the shares
+			grouped := GraphQuery{Scope: scope, Query: fmt.Sprintf("{%s} by {%s}", filter, facet)}
+			total := GraphQuery{Scope: scope, Query: filter}
+			switch scope {
+			case "metric":
+				metricName, _ := params.Optional[string](request, "metric_name")
+				if metricName = strings.TrimSpace(metricName); metricName == "" {
+					return mcp.NewToolResultError("metric_name is required for the metric scope, use search_metrics to find it"), nil
+				}
+				grouped.Query = MetricQuery{Aggregation: "count", Name: metricName, Filter: filter, GroupBy: []string{facet}}.CQL()
+				total.Query = MetricQuery{Aggregation: "count", Name: metricName, Filter: filter}.CQL()
+			case "trace":
+				grouped.DataType, total.DataType = "request", "request"
+			}
+
+			payload, err := NewGraphQueryBuilder().
+				WithQuery("Q1", grouped).
+				WithQuery("Q2", total).
+				WithFormula("R1", "Q1").
+				WithFormula("R2", "Q2").
+				Build()
+			if err != nil {
+				return nil, err
+			}
+
+			queryParams := url.Values{}
+			queryParams.Add("graph_type", "table")
+			queryParams.Add("limit", strconv.Itoa(limit))
+			if lookback, _ := params.Optional[string](request, "lookback"); lookback != "" {
+				queryParams.Add("lookback", lookback)
+			}
+
+			if from, _ := params.Optional[string](request, "from"); from != "" {
+				queryParams.Add("from", from)
+			}
+
+			if to, _ := params.Optional[string](request, "to"); to != "" {
+				queryParams.Add("to", to)
+			}
+
+			statusCode, bodyBytes, warnings, err := postGraphWithRetry(ctx, client, payload, queryParams)
+			if err != nil {
+				return nil, err
+			}
+
+			if statusCode != http.StatusMultiStatus {
+				return nil, fmt.Errorf("failed to get top values, status code %d: %s", statusCode, string(bodyBytes))
+			}
+
+			var graphResp map[string]struct {
+				Records []struct {
+					Values    []string `json:"values"`
+					Aggregate struct {
+						Value float64 `json:"value"`
+					} `json:"aggregate"`
+				} `json:"records"`
+			}
+			if err := json.Unmarshal(bodyBytes, &graphResp); err != nil {
+				return nil, fmt.Errorf("failed to decode graph response: %w", err)
+			}
+
+			result := TopValuesResult{
+				Scope:    scope,
+				Facet:    facet,
+				Query:    grouped.Query,
+				Values:   []TopValue{},
+				UILink:   uiLink(ctx, client, graphScopePage(scope), grouped.Query, queryParams),
+				Warnings: warnings,
+				Stats:    parseQueryStats(bodyBytes),
+			}
+			var listed int64
+			for _, record := range graphResp["R1"].Records {
+				value := TopValue{Value: strings.Join(record.Values, ", "), Count: int64(record.Aggregate.Value)}
+				listed += value.Count
+				result.Values = append(result.Values, value)
+			}
+			sort.SliceStable(result.Values, func(i, j int) bool { return result.Values[i].Count > result.Values[j].Count })
+			if len(result.Values) > limit {
+				result.Values = result.Values[:limit]
+			}
+
+			for _, record := range graphResp["R2"].Records {
+				result.Total += int64(record.Aggregate.Value)
+			}
+			// The shares are of the listed values if the total failed
+			result.Total = max(result.Total, listed)
+			for i := range result.Values {
+				if result.Total > 0 {
+					result.Values[i].Share = math.Round(float64(result.Values[i].Count)/float64(result.Total)*10000) / 100
+				}
+			}
+			result.Other = result.Total - listed
+			result.Guidance = topValuesGuidance(result)
+
+			r, err := json.Marshal(result)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
+
+func topValuesGuidance(result TopValuesResult) *GraphGuidance {
+	if len(result.Values) == 0 {
+		return &GraphGuidance{
+			ResultStatus: "empty",
+			NextSteps: []string{
+				fmt.Sprintf("No %s records with a %s value found for query: %s", result.Scope, result.Facet, result.Query),
+			},
+			Suggestions: []string{
+				fmt.Sprintf("Use facets tool with scope:%q to check the facet name", result.Scope),
+				"Try a broader time range (e.g., lookback:\"24h\" or lookback:\"7d\")",
+			},
+		}
+	}
+
+	guidance := &GraphGuidance{
+		ResultStatus: "success",
+		NextSteps: []string{
+			fmt.Sprintf("Add %s:\"<value>\" to the query of the search or graph tools to drill into a value.", result.Facet),
+		},
+	}
+	if len(result.Warnings) > 0 {
+		guidance.ResultStatus = "partial"
+		guidance.NextSteps = append(guidance.NextSteps, "A sub-query failed, see warnings. The shares may be of the listed values only.")
+	}
+	guidance.Suggestions = append(guidance.Suggestions, queryStatsSuggestions(result.Stats)...)
+	return guidance
+}
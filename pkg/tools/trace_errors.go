@@ -0,0 +1,335 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// traceSpanLimit is the maximum number of spans fetched for a trace.
+const traceSpanLimit = 1000
+
+type TraceErrorChainResult struct {
+	TraceID        string            `json:"trace_id"`
+	SpanCount      int               `json:"span_count"`
+	ErrorSpanCount int               `json:"error_span_count"`
+	ErrorChains    []TraceErrorChain `json:"error_chains"`
+	Guidance       *SearchGuidance   `json:"guidance,omitempty"`
+}
+
+// TraceErrorChain is the path from the root span to an error span that has no failing
+// descendants, i.e. the span the error originated in.
+type TraceErrorChain struct {
+	FailingService string           `json:"failing_service"`
+	FailingSpan    string           `json:"failing_span"`
+	Chain          []TraceChainSpan `json:"chain"`
+}
+
+type TraceChainSpan struct {
+	SpanID        string           `json:"span_id"`
+	Service       string           `json:"service"`
+	Name          string           `json:"name"`
+	Kind          string           `json:"kind,omitempty"`
+	Status        string           `json:"status"`
+	StatusMessage string           `json:"status_message,omitempty"`
+	Timestamp     string           `json:"timestamp,omitempty"`
+	DurationMs    float64          `json:"duration_ms"`
+	Exceptions    []TraceSpanEvent `json:"exceptions,omitempty"`
+}
+
+type TraceSpanEvent struct {
+	Type    string `json:"type,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type traceSpanNode struct {
+	span     TraceChainSpan
+	parentID string
+	// start is the zero time if the span has no valid timestamp
+	start    time.Time
+	children []*traceSpanNode
+}
+
+// GetTraceErrorChainTool creates a tool to find where the error of a failed trace originated
+func GetTraceErrorChainTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("get_trace_error_chain",
+			mcp.WithTitleAnnotation("Get Trace Error Chain"),
+			mcp.WithDescription(`Finds the span(s) a failed trace's error originated in and returns the propagation chain from the root span to each of them.
+
+Use this tool to answer "which downstream dependency actually failed?" for a trace in one call instead of reading every span with get_trace_timeline.
+Each chain lists the spans root → ... → failing span with their service, status message and exception events.
+The failing span is the deepest error span, an error span none of whose descendants failed.
+
+Get trace ids from get_trace_timeline with query: status.code:"ERROR".`),
+			mcp.WithString("trace_id",
+				mcp.Description("ID of the trace to analyze."),
+				mcp.Required(),
+			),
+			mcp.WithString("lookback",
+				mcp.Description("Lookback period in Go duration format, or days and weeks (e.g., 1h, 24h, 7d) the trace is searched in. Provide either lookback or from/to."),
+				mcp.DefaultString("24h"),
+			),
+			mcp.WithString("from",
+				mcp.Description("From datetime (ISO 8601: 2006-01-02T15:04:05.000Z). Use with 'to' when not using lookback."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("to",
+				mcp.Description("To datetime (ISO 8601: 2006-01-02T15:04:05.000Z). Use with 'from' when not using lookback."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			traceID, err := request.RequireString("trace_id")
+			if err != nil || strings.TrimSpace(traceID) == "" {
+				return mcp.NewToolResultError("missing required parameter: trace_id"), nil
+			}
+			traceID = strings.TrimSpace(traceID)
+
+			queryParams := traceSpanParams(request, traceID)
+			items, err := fetchTraceSpans(ctx, client, queryParams)
+			if err != nil {
+				return nil, err
+			}
+
+			result := traceErrorChains(traceID, items)
+			result.Guidance = traceErrorChainGuidance(result, queryParams.Get("lookback"))
+
+			r, err := json.Marshal(result)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
+
+// traceSpanParams returns the trace search parameters of all the spans of a trace, in the time
+// range of the request.
+func traceSpanParams(request mcp.CallToolRequest, traceID string) url.Values {
+	queryParams := url.Values{}
+	queryParams.Add("query", fmt.Sprintf("trace_id:%q", traceID))
+	queryParams.Add("limit", strconv.Itoa(traceSpanLimit))
+	queryParams.Add("order", "asc")
+	queryParams.Add("include_child_spans", "true")
+
+	from, _ := params.Optional[string](request, "from")
+	to, _ := params.Optional[string](request, "to")
+	lookback, _ := params.Optional[string](request, "lookback")
+	if from != "" {
+		queryParams.Add("from", from)
+		if to != "" {
+			queryParams.Add("to", to)
+		}
+	} else {
+		if lookback == "" {
+			lookback = "24h"
+		}
+		queryParams.Add("lookback", lookback)
+	}
+	return queryParams
+}
+
+func fetchTraceSpans(ctx context.Context, client Client, queryParams url.Values) ([]map[string]any, error) {
+	bodyBytes, err := searchTraces(ctx, client, queryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Items []map[string]any `json:"items"`
+	}
+	if err := json.Unmarshal(bodyBytes, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode trace search response: %v", err)
+	}
+	return resp.Items, nil
+}
+
+// buildTraceTree returns the spans of a trace by span ID and in the order of items, linked to
+// their children. Spans without ID and duplicates are left out.
+func buildTraceTree(items []map[string]any) (map[string]*traceSpanNode, []*traceSpanNode) {
+	nodes := make(map[string]*traceSpanNode, len(items))
+	order := make([]*traceSpanNode, 0, len(items))
+	for _, item := range items {
+		node := newTraceSpanNode(item)
+		if node.span.SpanID == "" {
+			continue
+		}
+		if _, ok := nodes[node.span.SpanID]; ok {
+			continue
+		}
+		nodes[node.span.SpanID] = node
+		order = append(order, node)
+	}
+
+	for _, node := range order {
+		if parent, ok := nodes[node.parentID]; ok && parent != node {
+			parent.children = append(parent.children, node)
+		}
+	}
+	return nodes, order
+}
+
+// traceErrorChains builds the span tree of a trace and returns the chains to its deepest error spans.
+func traceErrorChains(traceID string, items []map[string]any) TraceErrorChainResult {
+	result := TraceErrorChainResult{TraceID: traceID, ErrorChains: []TraceErrorChain{}}
+
+	nodes, order := buildTraceTree(items)
+	result.SpanCount = len(order)
+
+	var failing []*traceSpanNode
+	for _, node := range order {
+		if !node.failed() {
+			continue
+		}
+		result.ErrorSpanCount++
+		if !node.hasFailedDescendant() {
+			failing = append(failing, node)
+		}
+	}
+
+	for _, node := range failing {
+		// Walk up to the root, guarding against parent cycles in malformed traces.
+		var chain []TraceChainSpan
+		seen := make(map[*traceSpanNode]bool)
+		for n := node; n != nil && !seen[n]; n = nodes[n.parentID] {
+			seen[n] = true
+			chain = append(chain, n.span)
+		}
+		for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+			chain[i], chain[j] = chain[j], chain[i]
+		}
+
+		result.ErrorChains = append(result.ErrorChains, TraceErrorChain{
+			FailingService: node.span.Service,
+			FailingSpan:    node.span.Name,
+			Chain:          chain,
+		})
+	}
+
+	// The longest chains first, they are the most likely root causes.
+	sort.SliceStable(result.ErrorChains, func(i, j int) bool {
+		return len(result.ErrorChains[i].Chain) > len(result.ErrorChains[j].Chain)
+	})
+	return result
+}
+
+func newTraceSpanNode(item map[string]any) *traceSpanNode {
+	span := TraceChainSpan{
+		SpanID:        recordString(item, "span_id"),
+		Service:       recordString(item, "service.name"),
+		Name:          recordString(item, "name"),
+		Kind:          recordString(item, "span.kind"),
+		Status:        strings.ToUpper(recordString(item, "status.code")),
+		StatusMessage: recordString(item, "status.message"),
+		Timestamp:     recordString(item, "timestamp"),
+	}
+	if span.Status == "" {
+		span.Status = "UNSET"
+	}
+
+	if duration, ok := item["duration_ns"].(float64); ok {
+		span.DurationMs = duration / 1e6
+	}
+
+	events, _ := item["events"].([]any)
+	for _, e := range events {
+		event, ok := e.(map[string]any)
+		if !ok || event["name"] != "exception" {
+			continue
+		}
+		attributes, _ := event["attributes"].(map[string]any)
+		span.Exceptions = append(span.Exceptions, TraceSpanEvent{
+			Type:    recordString(attributes, "exception.type"),
+			Message: recordString(attributes, "exception.message"),
+		})
+	}
+	node := &traceSpanNode{span: span, parentID: recordString(item, "parent_span_id")}
+	node.start, _ = time.Parse(time.RFC3339Nano, span.Timestamp)
+	return node
+}
+
+// recordString returns a string field of a span or log, looking in its resource and attributes for
+// fields that are not top level.
+func recordString(item map[string]any, key string) string {
+	if v, ok := item[key].(string); ok {
+		return v
+	}
+	for _, nested := range []string{"resource", "attributes"} {
+		if m, ok := item[nested].(map[string]any); ok {
+			if v, ok := m[key].(string); ok {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+func (n *traceSpanNode) failed() bool {
+	return n.span.Status == "ERROR" || n.span.Status == "STATUS_CODE_ERROR"
+}
+
+func (n *traceSpanNode) hasFailedDescendant() bool {
+	seen := map[*traceSpanNode]bool{n: true}
+	stack := append([]*traceSpanNode{}, n.children...)
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[node] {
+			continue
+		}
+		seen[node] = true
+		if node.failed() {
+			return true
+		}
+		stack = append(stack, node.children...)
+	}
+	return false
+}
+
+func traceErrorChainGuidance(result TraceErrorChainResult, lookback string) *SearchGuidance {
+	switch {
+	case result.SpanCount == 0:
+		steps := []string{"Verify the trace_id, e.g. with get_trace_timeline and query: status.code:\"ERROR\"."}
+		if lookback != "" {
+			steps = append(steps, fmt.Sprintf("The trace was searched in the last %s; pass a longer lookback or from/to if it is older.", lookback))
+		}
+		return &SearchGuidance{ResultStatus: "empty", NextSteps: steps}
+	case len(result.ErrorChains) == 0:
+		return &SearchGuidance{
+			ResultStatus: "success",
+			NextSteps:    []string{"No span of the trace has an error status; use get_trace_timeline to look for slow spans instead."},
+		}
+	}
+
+	guidance := &SearchGuidance{ResultStatus: "success"}
+	for _, chain := range result.ErrorChains {
+		services := make([]string, 0, len(chain.Chain))
+		for _, span := range chain.Chain {
+			services = append(services, span.Service)
+		}
+		guidance.NextSteps = append(guidance.NextSteps, fmt.Sprintf("The error originated in %s (%s) and propagated through %s.",
+			chain.FailingService, chain.FailingSpan, strings.Join(services, " → ")))
+	}
+
+	failing := result.ErrorChains[0].FailingService
+	guidance.Suggestions = []string{
+		fmt.Sprintf("Search the logs of the failing service around the span: get_log_search with query: service.name:\"%s\" AND severity_text:\"ERROR\".", failing),
+		fmt.Sprintf("Check whether %s fails in other traces too: get_trace_timeline with query: service.name:\"%s\" AND status.code:\"ERROR\".", failing, failing),
+	}
+	if result.SpanCount >= traceSpanLimit {
+		guidance.Suggestions = append(guidance.Suggestions, fmt.Sprintf("The trace has %d or more spans; spans beyond the limit were not analyzed.", traceSpanLimit))
+	}
+	return guidance
+}
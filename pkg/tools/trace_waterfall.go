@@ -0,0 +1,287 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type TraceWaterfallResult struct {
+	TraceID string `json:"trace_id"`
+	// Start is the timestamp of the earliest span, the offsets of the spans are relative to it
+	Start      string   `json:"start,omitempty"`
+	DurationMs float64  `json:"duration_ms"`
+	SpanCount  int      `json:"span_count"`
+	ErrorCount int      `json:"error_span_count"`
+	Services   []string `json:"services"`
+	// Spans are in depth-first order, the children of a span by start time after it
+	Spans    []WaterfallSpan `json:"spans"`
+	Guidance *SearchGuidance `json:"guidance,omitempty"`
+}
+
+type WaterfallSpan struct {
+	SpanID       string `json:"span_id"`
+	ParentSpanID string `json:"parent_span_id,omitempty"`
+	Depth        int    `json:"depth"`
+	Service      string `json:"service"`
+	Name         string `json:"name"`
+	Kind         string `json:"kind,omitempty"`
+	Status       string `json:"status"`
+	// StatusMessage and Exceptions are only set for error spans
+	StatusMessage string  `json:"status_message,omitempty"`
+	OffsetMs      float64 `json:"offset_ms"`
+	DurationMs    float64 `json:"duration_ms"`
+	// SelfTimeMs is the time of the span not covered by its children
+	SelfTimeMs float64          `json:"self_time_ms"`
+	Exceptions []TraceSpanEvent `json:"exceptions,omitempty"`
+	// Orphan is true if the parent span of the span is not in the trace, e.g. it was not sampled
+	Orphan bool `json:"orphan,omitempty"`
+}
+
+// GetTraceByIDTool creates a tool returning the spans of a trace as a waterfall
+func GetTraceByIDTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("get_trace_by_id",
+			mcp.WithTitleAnnotation("Get Trace By ID"),
+			mcp.WithDescription(`Returns all the spans of a trace as a waterfall: the spans in parent/child order with their depth, offset from the start of the trace, duration and self time.
+
+Use this tool to see where the time of a slow request goes, or how a request flows through the services, instead of rebuilding the tree from the flat spans of get_trace_timeline.
+Spans whose parent is missing from the trace are marked orphan and listed as roots.
+
+Use get_trace_error_chain for failed traces, it returns the chain of spans the error propagated through.
+Get trace ids from get_trace_timeline.`),
+			mcp.WithString("trace_id",
+				mcp.Description("ID of the trace."),
+				mcp.Required(),
+			),
+			mcp.WithString("lookback",
+				mcp.Description("Lookback period in Go duration format, or days and weeks (e.g., 1h, 24h, 7d) the trace is searched in. Provide either lookback or from/to."),
+				mcp.DefaultString("24h"),
+			),
+			mcp.WithString("from",
+				mcp.Description("From datetime (ISO 8601: 2006-01-02T15:04:05.000Z). Use with 'to' when not using lookback."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("to",
+				mcp.Description("To datetime (ISO 8601: 2006-01-02T15:04:05.000Z). Use with 'from' when not using lookback."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			traceID, err := request.RequireString("trace_id")
+			if err != nil || strings.TrimSpace(traceID) == "" {
+				return mcp.NewToolResultError("missing required parameter: trace_id"), nil
+			}
+			traceID = strings.TrimSpace(traceID)
+
+			queryParams := traceSpanParams(request, traceID)
+			items, err := fetchTraceSpans(ctx, client, queryParams)
+			if err != nil {
+				return nil, err
+			}
+
+			result := traceWaterfall(traceID, items)
+			result.Guidance = traceWaterfallGuidance(result, queryParams.Get("lookback"))
+
+			r, err := json.Marshal(result)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
+
+// traceWaterfall builds the span tree of a trace and lists its spans depth first.
+func traceWaterfall(traceID string, items []map[string]any) TraceWaterfallResult {
+	result := TraceWaterfallResult{TraceID: traceID, Services: []string{}, Spans: []WaterfallSpan{}}
+
+	nodes, order := buildTraceTree(items)
+	result.SpanCount = len(order)
+	if len(order) == 0 {
+		return result
+	}
+
+	var start, end time.Time
+	services := make(map[string]bool)
+	var roots []*traceSpanNode
+	for _, node := range order {
+		if parent, ok := nodes[node.parentID]; !ok || parent == node {
+			roots = append(roots, node)
+		}
+		if node.failed() {
+			result.ErrorCount++
+		}
+		if node.span.Service != "" && !services[node.span.Service] {
+			services[node.span.Service] = true
+			result.Services = append(result.Services, node.span.Service)
+		}
+		if node.start.IsZero() {
+			continue
+		}
+		if start.IsZero() || node.start.Before(start) {
+			start = node.start
+		}
+		if e := node.end(); e.After(end) {
+			end = e
+		}
+	}
+	sort.Strings(result.Services)
+	if !start.IsZero() {
+		result.Start = start.Format(isoTimeLayout)
+		result.DurationMs = roundMs(end.Sub(start))
+	}
+
+	// Every span is listed once, even in malformed traces with parent cycles
+	seen := make(map[*traceSpanNode]bool, len(order))
+	var walk func(node *traceSpanNode, depth int)
+	walk = func(node *traceSpanNode, depth int) {
+		if seen[node] {
+			return
+		}
+		seen[node] = true
+		result.Spans = append(result.Spans, waterfallSpan(node, depth, start, node.parentID != "" && depth == 0))
+		sortSpansByStart(node.children)
+		for _, child := range node.children {
+			walk(child, depth+1)
+		}
+	}
+	sortSpansByStart(roots)
+	for _, root := range roots {
+		walk(root, 0)
+	}
+	for _, node := range order {
+		walk(node, 0)
+	}
+	return result
+}
+
+func waterfallSpan(node *traceSpanNode, depth int, traceStart time.Time, orphan bool) WaterfallSpan {
+	span := WaterfallSpan{
+		SpanID:       node.span.SpanID,
+		ParentSpanID: node.parentID,
+		Depth:        depth,
+		Service:      node.span.Service,
+		Name:         node.span.Name,
+		Kind:         node.span.Kind,
+		Status:       node.span.Status,
+		DurationMs:   node.span.DurationMs,
+		SelfTimeMs:   roundMs(node.selfTime()),
+		Orphan:       orphan,
+	}
+	if !node.start.IsZero() && !traceStart.IsZero() {
+		span.OffsetMs = roundMs(node.start.Sub(traceStart))
+	}
+	if node.failed() {
+		span.StatusMessage = node.span.StatusMessage
+		span.Exceptions = node.span.Exceptions
+	}
+	return span
+}
+
+func (n *traceSpanNode) duration() time.Duration {
+	return time.Duration(n.span.DurationMs * float64(time.Millisecond))
+}
+
+func (n *traceSpanNode) end() time.Time {
+	return n.start.Add(n.duration())
+}
+
+// selfTime returns the time of the span not covered by any of its children, which may overlap.
+func (n *traceSpanNode) selfTime() time.Duration {
+	if n.start.IsZero() {
+		return n.duration()
+	}
+
+	var children []timeRange
+	for _, child := range n.children {
+		if child.start.IsZero() {
+			continue
+		}
+		// Children are clipped to the span, their clocks may be skewed
+		from, to := maxTime(child.start, n.start), minTime(child.end(), n.end())
+		if from.Before(to) {
+			children = append(children, timeRange{from: from, to: to})
+		}
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].from.Before(children[j].from) })
+
+	var covered time.Duration
+	var last time.Time
+	for _, c := range children {
+		if c.from.Before(last) {
+			c.from = last
+		}
+		if c.from.Before(c.to) {
+			covered += c.to.Sub(c.from)
+			last = c.to
+		}
+	}
+	return max(n.duration()-covered, 0)
+}
+
+func sortSpansByStart(nodes []*traceSpanNode) {
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].start.Before(nodes[j].start) })
+}
+
+// roundMs returns a duration in milliseconds, rounded to the microsecond.
+func roundMs(d time.Duration) float64 {
+	return math.Round(float64(d)/1e3) / 1e3
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func traceWaterfallGuidance(result TraceWaterfallResult, lookback string) *SearchGuidance {
+	if result.SpanCount == 0 {
+		steps := []string{"Verify the trace_id, e.g. with get_trace_timeline."}
+		if lookback != "" {
+			steps = append(steps, fmt.Sprintf("The trace was searched in the last %s; pass a longer lookback or from/to if it is older.", lookback))
+		}
+		return &SearchGuidance{ResultStatus: "empty", NextSteps: steps}
+	}
+
+	guidance := &SearchGuidance{ResultStatus: "success"}
+	slowest := result.Spans[0]
+	for _, span := range result.Spans {
+		if span.SelfTimeMs > slowest.SelfTimeMs {
+			slowest = span
+		}
+	}
+	guidance.NextSteps = append(guidance.NextSteps, fmt.Sprintf("%d spans across %d services over %.1fms; %s (%s) spends the most time itself, %.1fms.",
+		result.SpanCount, len(result.Services), result.DurationMs, slowest.Name, slowest.Service, slowest.SelfTimeMs))
+
+	if result.ErrorCount > 0 {
+		guidance.Suggestions = append(guidance.Suggestions, fmt.Sprintf("%d spans failed; use get_trace_error_chain with trace_id %q to find where the error originated.", result.ErrorCount, result.TraceID))
+	}
+	for _, span := range result.Spans {
+		if span.Orphan {
+			guidance.Suggestions = append(guidance.Suggestions, "Some spans are orphans, their parents are missing from the trace, e.g. not sampled or still being ingested.")
+			break
+		}
+	}
+	if result.SpanCount >= traceSpanLimit {
+		guidance.Suggestions = append(guidance.Suggestions, fmt.Sprintf("The trace has %d or more spans; spans beyond the limit are missing.", traceSpanLimit))
+	}
+	return guidance
+}
@@ -0,0 +1,367 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// traceParentHeader propagates the trace context, see https://www.w3.org/TR/trace-context/
+	traceParentHeader = "traceparent"
+
+	traceQueueSize      = 2048
+	traceBatchSize      = 512
+	traceExportInterval = 5 * time.Second
+	traceExportTimeout  = 10 * time.Second
+)
+
+// Kinds and status codes of OTLP spans.
+const (
+	spanKindServer = 2
+	spanKindClient = 3
+
+	spanStatusError = 2
+)
+
+type traceParentKey struct{}
+
+type spanContextKey struct{}
+
+// spanContext identifies a span of a trace.
+type spanContext struct {
+	traceID [16]byte
+	spanID  [8]byte
+}
+
+func (s spanContext) traceParent() string {
+	return fmt.Sprintf("00-%s-%s-01", hex.EncodeToString(s.traceID[:]), hex.EncodeToString(s.spanID[:]))
+}
+
+// parseTraceParent parses a traceparent header value.
+func parseTraceParent(value string) (spanContext, bool) {
+	var sc spanContext
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return sc, false
+	}
+	traceID, err := hex.DecodeString(parts[1])
+	if err != nil || len(traceID) != len(sc.traceID) {
+		return sc, false
+	}
+	spanID, err := hex.DecodeString(parts[2])
+	if err != nil || len(spanID) != len(sc.spanID) {
+		return sc, false
+	}
+	copy(sc.traceID[:], traceID)
+	copy(sc.spanID[:], spanID)
+	if sc.traceID == [16]byte{} || sc.spanID == [8]byte{} {
+		return sc, false
+	}
+	return sc, true
+}
+
+// ContextWithTraceParent returns a context whose tool calls continue the trace of the traceparent
+// header value, e.g. of the HTTP request of the call.
+func ContextWithTraceParent(ctx context.Context, traceParent string) context.Context {
+	if sc, ok := parseTraceParent(traceParent); ok {
+		return context.WithValue(ctx, traceParentKey{}, sc)
+	}
+	return ctx
+}
+
+type span struct {
+	spanContext
+	parentID   [8]byte
+	name       string
+	kind       int
+	start, end time.Time
+	attributes map[string]any
+	statusCode int
+	statusMsg  string
+}
+
+// TracerConfig configures the OTLP exporter of a Tracer.
+type TracerConfig struct {
+	// Endpoint is the URL spans are posted to, e.g. http://localhost:4318/v1/traces
+	Endpoint string
+	// Headers are added to the export requests, e.g. for authentication
+	Headers     map[string]string
+	ServiceName string
+}
+
+// Tracer records a span for every tool call, with child spans for the requests to the Edge
+// Delta API, and exports them to an OpenTelemetry collector with OTLP over HTTP in JSON. Calls
+// continue the trace of the traceparent of their HTTP request or of the _meta of the call, and
+// API requests carry the traceparent of their span.
+type Tracer struct {
+	config TracerConfig
+	client *http.Client
+	queue  chan span
+}
+
+func NewTracer(config TracerConfig) *Tracer {
+	return &Tracer{
+		config: config,
+		client: &http.Client{Timeout: traceExportTimeout},
+		queue:  make(chan span, traceQueueSize),
+	}
+}
+
+// ParseOTLPHeaders parses the headers of OTEL_EXPORTER_OTLP_HEADERS, e.g. "api-key=abc,x-tenant=t1".
+func ParseOTLPHeaders(s string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, entry := range strings.Split(s, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid header entry %q, expected <key>=<value>", entry)
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return headers, nil
+}
+
+// start starts a span, a child of the span of ctx, or of the trace parent of ctx, if any.
+func (t *Tracer) start(ctx context.Context, name string, kind int) (context.Context, *span) {
+	s := &span{name: name, kind: kind, start: time.Now(), attributes: make(map[string]any)}
+	if parent, ok := ctx.Value(spanContextKey{}).(spanContext); ok {
+		s.traceID, s.parentID = parent.traceID, parent.spanID
+	} else if parent, ok := ctx.Value(traceParentKey{}).(spanContext); ok {
+		s.traceID, s.parentID = parent.traceID, parent.spanID
+	} else {
+		_, _ = rand.Read(s.traceID[:])
+	}
+	_, _ = rand.Read(s.spanID[:])
+	return context.WithValue(ctx, spanContextKey{}, s.spanContext), s
+}
+
+// end ends the span and queues it for export, dropping it if the queue is full.
+func (t *Tracer) end(s *span) {
+	s.end = time.Now()
+	select {
+	case t.queue <- *s:
+	default:
+	}
+}
+
+// Middleware records a span for every tool call.
+func (t *Tracer) Middleware() ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			// MCP clients may pass the trace context in the _meta of the call
+			if meta := request.Params.Meta; meta != nil {
+				if traceParent, ok := meta.AdditionalFields[traceParentHeader].(string); ok {
+					ctx = ContextWithTraceParent(ctx, traceParent)
+				}
+			}
+
+			ctx, s := t.start(ctx, "tools/call "+request.Params.Name, spanKindServer)
+			s.attributes["mcp.method.name"] = "tools/call"
+			s.attributes["gen_ai.tool.name"] = request.Params.Name
+			defer t.end(s)
+
+			result, err := next(ctx, request)
+			switch {
+			case err != nil:
+				s.statusCode, s.statusMsg = spanStatusError, err.Error()
+			case result != nil && result.IsError:
+				s.statusCode = spanStatusError
+				if code := toolErrorCode(result); code != "" {
+					s.attributes["error.type"] = code
+				}
+			}
+			return result, err
+		}
+	}
+}
+
+// toolErrorCode returns the error code of an error result, if it has one.
+func toolErrorCode(result *mcp.CallToolResult) string {
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			var payload errorPayload
+			if json.Unmarshal([]byte(text.Text), &payload) == nil && payload.Error != nil {
+				return string(payload.Error.Code)
+			}
+		}
+	}
+	return ""
+}
+
+// WithTracing records a span for every attempt of the requests of the client, and propagates its
+// trace context to the API. Apply it after the options configuring the transport, e.g.
+// WithUserAgent.
+func WithTracing(t *Tracer) HTTPClientOption {
+	return func(c *HTTPClient) {
+		next := c.cl.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		c.cl.Transport = &tracingTransport{tracer: t, next: next}
+	}
+}
+
+type tracingTransport struct {
+	tracer *Tracer
+	next   http.RoundTripper
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := endpointLabel(req.URL.Path)
+	ctx, s := t.tracer.start(req.Context(), req.Method+" "+endpoint, spanKindClient)
+	s.attributes["http.request.method"] = req.Method
+	s.attributes["url.template"] = endpoint
+	s.attributes["server.address"] = req.URL.Hostname()
+	defer t.tracer.end(s)
+
+	req = req.Clone(ctx)
+	req.Header.Set(traceParentHeader, s.spanContext.traceParent())
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		s.statusCode, s.statusMsg = spanStatusError, err.Error()
+		return resp, err
+	}
+	s.attributes["http.response.status_code"] = resp.StatusCode
+	if resp.StatusCode >= http.StatusBadRequest {
+		s.statusCode = spanStatusError
+		s.attributes["error.type"] = strconv.Itoa(resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// Run exports the recorded spans in batches until ctx is done, then exports the remaining ones.
+// Failed exports are logged and their spans dropped, tracing must not affect tool calls.
+func (t *Tracer) Run(ctx context.Context, logger *slog.Logger) {
+	ticker := time.NewTicker(traceExportInterval)
+	defer ticker.Stop()
+
+	batch := make([]span, 0, traceBatchSize)
+	flush := func(ctx context.Context) {
+		if len(batch) == 0 {
+			return
+		}
+		if err := t.export(ctx, batch); err != nil {
+			logger.Warn("Failed to export spans", "endpoint", t.config.Endpoint, "spans", len(batch), "error", err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case s := <-t.queue:
+			batch = append(batch, s)
+			if len(batch) >= traceBatchSize {
+				flush(ctx)
+			}
+		case <-ticker.C:
+			flush(ctx)
+		case <-ctx.Done():
+			for pending := true; pending; {
+				select {
+				case s := <-t.queue:
+					batch = append(batch, s)
+				default:
+					pending = false
+				}
+			}
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), traceExportTimeout)
+			flush(shutdownCtx)
+			cancel()
+			return
+		}
+	}
+}
+
+// export posts the spans to the OTLP endpoint, see
+// https://opentelemetry.io/docs/specs/otlp/#otlphttp
+func (t *Tracer) export(ctx context.Context, spans []span) error {
+	otlpSpans := make([]map[string]any, 0, len(spans))
+	for _, s := range spans {
+		otlpSpan := map[string]any{
+			"traceId":           hex.EncodeToString(s.traceID[:]),
+			"spanId":            hex.EncodeToString(s.spanID[:]),
+			"name":              s.name,
+			"kind":              s.kind,
+			"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(s.end.UnixNano(), 10),
+			"attributes":        otlpAttributes(s.attributes),
+		}
+		if s.parentID != [8]byte{} {
+			otlpSpan["parentSpanId"] = hex.EncodeToString(s.parentID[:])
+		}
+		if s.statusCode != 0 {
+			otlpSpan["status"] = map[string]any{"code": s.statusCode, "message": s.statusMsg}
+		}
+		otlpSpans = append(otlpSpans, otlpSpan)
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"resourceSpans": []map[string]any{{
+			"resource": map[string]any{
+				"attributes": otlpAttributes(map[string]any{"service.name": t.config.ServiceName}),
+			},
+			"scopeSpans": []map[string]any{{
+				"scope": map[string]any{"name": "github.com/edgedelta/edgedelta-mcp-server"},
+				"spans": otlpSpans,
+			}},
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal spans: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range t.config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to export spans, status code %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+	return nil
+}
+
+// otlpAttributes converts attributes to OTLP key values.
+func otlpAttributes(attributes map[string]any) []map[string]any {
+	keyValues := make([]map[string]any, 0, len(attributes))
+	for key, value := range attributes {
+		var v map[string]any
+		switch value := value.(type) {
+		case int:
+			// OTLP JSON encodes 64 bit integers as strings
+			v = map[string]any{"intValue": strconv.Itoa(value)}
+		case bool:
+			v = map[string]any{"boolValue": value}
+		default:
+			v = map[string]any{"stringValue": fmt.Sprint(value)}
+		}
+		keyValues = append(keyValues, map[string]any{"key": key, "value": v})
+	}
+	return keyValues
+}
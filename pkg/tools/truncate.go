@@ -0,0 +1,204 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// truncationFooterBytes is reserved from the budget for the truncation footer.
+const truncationFooterBytes = 512
+
+// Truncation is the footer added to a result that was cut to the size budget.
+type Truncation struct {
+	Truncated     bool   `json:"truncated"`
+	TotalItems    int    `json:"total_items,omitempty"`
+	ReturnedItems int    `json:"returned_items,omitempty"`
+	TotalBytes    int    `json:"total_bytes"`
+	Hint          string `json:"hint"`
+}
+
+// TruncateResults limits the text of tool results to maxBytes so large log, trace or graph
+// responses do not exhaust the context window of the agent. JSON results are cut by dropping
+// items from the end of their largest array, so they stay valid JSON; other text is cut at the
+// budget. A truncated result gets a footer with the item counts. The cursor of the response points
+// past the items cut, so it is removed along with the pagination block, and agents page by
+// repeating the call with a lower limit instead of silently skipping items.
+func TruncateResults(maxBytes int) ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			result, err := next(ctx, request)
+			if err != nil || result == nil || maxBytes <= 0 {
+				return result, err
+			}
+
+			total, largest := 0, -1
+			for i, content := range result.Content {
+				text, ok := content.(mcp.TextContent)
+				if !ok {
+					continue
+				}
+				total += len(text.Text)
+				if largest < 0 || len(text.Text) > len(result.Content[largest].(mcp.TextContent).Text) {
+					largest = i
+				}
+			}
+			if total <= maxBytes || largest < 0 {
+				return result, nil
+			}
+
+			text := result.Content[largest].(mcp.TextContent)
+			budget := maxBytes - (total - len(text.Text)) - truncationFooterBytes
+			if budget < 0 {
+				budget = 0
+			}
+
+			truncated, truncation := truncateText(text.Text, budget)
+			text.Text = truncated
+			result.Content[largest] = text
+
+			kept := result.Content[:0]
+			for _, content := range result.Content {
+				if !isPaginationBlock(content) {
+					kept = append(kept, content)
+				}
+			}
+			result.Content = kept
+
+			footer, err := json.Marshal(map[string]any{"truncation": truncation})
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal truncation: %w", err)
+			}
+			result.Content = append(result.Content, mcp.NewTextContent(string(footer)))
+			return result, nil
+		}
+	}
+}
+
+// truncateText cuts text to budget bytes, keeping JSON valid where possible.
+func truncateText(text string, budget int) (string, Truncation) {
+	truncation := Truncation{Truncated: true, TotalBytes: len(text)}
+
+	var doc any
+	if err := json.Unmarshal([]byte(text), &doc); err == nil {
+		dropCursors(doc)
+		if items, set := largestArray(&doc); items != nil {
+			truncation.TotalItems = len(items)
+
+			// Find the most items that fit the budget.
+			lo, hi := 0, len(items)
+			var fitted []byte
+			for lo <= hi {
+				mid := (lo + hi) / 2
+				set(items[:mid])
+				encoded, err := json.Marshal(doc)
+				if err == nil && len(encoded) <= budget {
+					fitted, truncation.ReturnedItems = encoded, mid
+					lo = mid + 1
+				} else {
+					hi = mid - 1
+				}
+			}
+
+			if truncation.ReturnedItems > 0 {
+				truncation.Hint = fmt.Sprintf("Only the first %d of %d items are returned and the cursor of the response was removed, as it would skip the rest. Repeat the call with the limit lowered to %d and page with its cursor, or narrow the query or time range, to see the rest.",
+					truncation.ReturnedItems, truncation.TotalItems, truncation.ReturnedItems)
+				if object, ok := doc.(map[string]any); ok && object["fetch_all"] != nil {
+					truncation.Hint = fmt.Sprintf("Only the first %d of %d items fetched with fetch_all are returned. Lower max_items to %d, or narrow the query or time range, to see the rest.",
+						truncation.ReturnedItems, truncation.TotalItems, truncation.ReturnedItems)
+				}
+				return string(fitted), truncation
+			}
+			truncation.TotalItems = 0
+		}
+	}
+
+	cut := min(budget, len(text))
+	for cut > 0 && cut < len(text) && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	// Line based exports, e.g. csv or ndjson, are cut after their last whole line
+	if i := strings.LastIndexByte(text[:cut], '\n'); i > 0 && cut < len(text) {
+		cut = i + 1
+	}
+	truncation.Hint = fmt.Sprintf("Only the first %d of %d bytes are returned. Narrow the query, time range or limit to see the rest.", cut, len(text))
+	return text[:cut], truncation
+}
+
+// largestArray returns the array with the most encoded bytes in a decoded JSON document and a
+// function replacing it in the document.
+func largestArray(doc *any) ([]any, func([]any)) {
+	var (
+		best     []any
+		bestSize int
+		bestSet  func([]any)
+	)
+
+	var walk func(v any, set func([]any))
+	walk = func(v any, set func([]any)) {
+		switch v := v.(type) {
+		case map[string]any:
+			for k, child := range v {
+				walk(child, func(items []any) { v[k] = items })
+			}
+		case []any:
+			if encoded, err := json.Marshal(v); err == nil && len(encoded) > bestSize {
+				best, bestSize, bestSet = v, len(encoded), set
+			}
+			for i, child := range v {
+				walk(child, func(items []any) { v[i] = items })
+			}
+		}
+	}
+	walk(*doc, func(items []any) { *doc = items })
+	return best, bestSet
+}
+
+// cursorKeys are the keys of the pagination cursors of responses.
+var cursorKeys = []string{"next_cursor", "nextCursor", "cursor"}
+
+// dropCursors removes the pagination cursors of a decoded JSON response, where findCursor finds
+// them.
+func dropCursors(doc any) {
+	if v, ok := doc.(map[string]any); ok {
+		for _, key := range cursorKeys {
+			delete(v, key)
+		}
+		for _, child := range v {
+			dropCursors(child)
+		}
+	}
+}
+
+// isPaginationBlock reports whether the content is the block PaginationHints adds.
+func isPaginationBlock(content mcp.Content) bool {
+	text, ok := content.(mcp.TextContent)
+	if !ok || !strings.HasPrefix(text.Text, `{"pagination":`) {
+		return false
+	}
+	var block map[string]json.RawMessage
+	return json.Unmarshal([]byte(text.Text), &block) == nil && len(block) == 1
+}
+
+// findCursor returns the pagination cursor of a decoded JSON response, if any.
+func findCursor(doc any) string {
+	switch v := doc.(type) {
+	case map[string]any:
+		for _, key := range cursorKeys {
+			if cursor, ok := v[key].(string); ok && cursor != "" {
+				return cursor
+			}
+		}
+		for _, child := range v {
+			if cursor := findCursor(child); cursor != "" {
+				return cursor
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,50 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestTruncateResultsDropsCursors(t *testing.T) {
+	items := make([]map[string]any, 200)
+	for i := range items {
+		items[i] = map[string]any{"body": fmt.Sprintf("log line %d", i)}
+	}
+	page, err := json.Marshal(map[string]any{"items": items, "next_cursor": "page-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := TruncateResults(2048)(PaginationHints(map[string]bool{"get_log_search": true})(
+		func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText(string(page)), nil
+		}))
+	var request mcp.CallToolRequest
+	request.Params.Name = "get_log_search"
+	result, err := handler(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var footer struct {
+		Truncation Truncation `json:"truncation"`
+	}
+	last := result.Content[len(result.Content)-1].(mcp.TextContent).Text
+	if err := json.Unmarshal([]byte(last), &footer); err != nil || !footer.Truncation.Truncated {
+		t.Fatalf("last content is %q, want the truncation footer", last)
+	}
+	if footer.Truncation.ReturnedItems == 0 || footer.Truncation.ReturnedItems >= len(items) {
+		t.Fatalf("returned %d of %d items, want some dropped", footer.Truncation.ReturnedItems, len(items))
+	}
+	// The cursor of the page would skip the items dropped
+	for _, content := range result.Content {
+		if text := content.(mcp.TextContent).Text; strings.Contains(text, "page-2") {
+			t.Errorf("result still offers the cursor of the page: %s", text)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package tools
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// UIPage is a page of the Edge Delta web UI that tool results can be explored in.
+type UIPage string
+
+const (
+	UILogsPage     UIPage = "logs/log-search"
+	UIMetricsPage  UIPage = "metrics/explorer"
+	UITracesPage   UIPage = "traces/explorer"
+	UIPatternsPage UIPage = "logs/patterns"
+	UIEventsPage   UIPage = "events"
+)
+
+// uiTimeParams are the request parameters that carry over to the UI time picker.
+var uiTimeParams = []string{"lookback", "from", "to"}
+
+// uiBaseURL derives the web UI URL from the API URL, e.g. https://api.edgedelta.com becomes
+// https://app.edgedelta.com. It returns "" when the API URL does not follow that convention.
+func uiBaseURL(apiURL string) string {
+	u, err := url.Parse(apiURL)
+	if err != nil || !strings.HasPrefix(u.Host, "api.") {
+		return ""
+	}
+
+	u.Host = "app." + strings.TrimPrefix(u.Host, "api.")
+	u.Path = ""
+	u.RawQuery = ""
+	return u.String()
+}
+
+// uiLink returns the web UI URL that shows the query over the same time range as the request,
+// so users can click through from a tool result to the product. It returns "" when no link can
+// be built.
+func uiLink(ctx context.Context, client Client, page UIPage, query string, queryParams url.Values) string {
+	base := uiBaseURL(client.APIURL())
+	if base == "" {
+		return ""
+	}
+
+	keys, err := FetchContextKeys(ctx)
+	if err != nil {
+		return ""
+	}
+
+	values := url.Values{}
+	values.Set("org_id", keys.OrgID)
+	if query != "" {
+		values.Set("query", query)
+	}
+
+	for _, name := range uiTimeParams {
+		if v := queryParams.Get(name); v != "" {
+			values.Set(name, v)
+		}
+	}
+
+	return base + "/" + string(page) + "?" + values.Encode()
+}
@@ -0,0 +1,149 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// warmupScopes are the scopes whose facet keys are prefetched.
+var warmupScopes = []string{"log", "metric", "trace", "pattern", "event"}
+
+type warmingKey struct{}
+
+type warmEntry struct {
+	status    int
+	header    http.Header
+	body      []byte
+	fetchedAt time.Time
+}
+
+// WarmCache is a Client caching the responses of the requests made by Warm, i.e. the services and
+// facet keys of an org, so the first interaction of a session does not wait for them. A cached
+// response is only served to callers whose credentials the API accepted for the org within the
+// TTL, so it never stands in for authentication.
+type WarmCache struct {
+	client Client
+	ttl    time.Duration
+
+	mu       sync.Mutex
+	entries  map[string]warmEntry
+	verified map[string]time.Time
+}
+
+func NewWarmCache(client Client, ttl time.Duration) *WarmCache {
+	return &WarmCache{
+		client:   client,
+		ttl:      ttl,
+		entries:  make(map[string]warmEntry),
+		verified: make(map[string]time.Time),
+	}
+}
+
+// TTL returns how long responses are cached.
+func (c *WarmCache) TTL() time.Duration {
+	return c.ttl
+}
+
+func (c *WarmCache) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	key := req.URL.String()
+	caller, _ := callerKey(ctx)
+	_, warming := ctx.Value(warmingKey{}).(bool)
+
+	cacheable := req.Method == http.MethodGet
+	if cacheable && !warming {
+		c.mu.Lock()
+		entry, ok := c.entries[key]
+		fresh := ok && time.Since(entry.fetchedAt) < c.ttl && time.Since(c.verified[caller]) < c.ttl
+		c.mu.Unlock()
+
+		if fresh {
+			return &http.Response{
+				Status:     fmt.Sprintf("%d %s", entry.status, http.StatusText(entry.status)),
+				StatusCode: entry.status,
+				Header:     entry.header.Clone(),
+				Body:       io.NopCloser(bytes.NewReader(entry.body)),
+				Request:    req,
+			}, nil
+		}
+		cacheable = ok
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.StatusCode < http.StatusBadRequest && caller != "" {
+		c.mu.Lock()
+		c.verified[caller] = time.Now()
+		c.mu.Unlock()
+	}
+
+	if !cacheable || resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	c.mu.Lock()
+	c.entries[key] = warmEntry{status: resp.StatusCode, header: resp.Header.Clone(), body: body, fetchedAt: time.Now()}
+	c.mu.Unlock()
+	return resp, nil
+}
+
+func (c *WarmCache) Get(url string) (*http.Response, error) {
+	return c.client.Get(url)
+}
+
+func (c *WarmCache) APIURL() string {
+	return c.client.APIURL()
+}
+
+// Warm fetches the services and the facet keys of every scope of the org in ctx concurrently and
+// caches them.
+func (c *WarmCache) Warm(ctx context.Context) error {
+	ctx = context.WithValue(ctx, warmingKey{}, true)
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	fetch := func(name string, f func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := f(); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("failed to warm up %s: %w", name, err))
+				mu.Unlock()
+			}
+		}()
+	}
+
+	fetch("services", func() error {
+		_, err := GetServices(ctx, c)
+		return err
+	})
+	for _, scope := range warmupScopes {
+		fetch(scope+" facet keys", func() error {
+			_, err := GetFacetKeys(ctx, c, scope)
+			return err
+		})
+	}
+
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,418 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/params"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	defaultCompareSeries = 20
+	maxCompareSeries     = 100
+	// compareSeriesFetched is the number of series fetched per window, more than returned so that
+	// series only in one window are found.
+	compareSeriesFetched = 200
+	// compareAllSeries is the label of the series of a query without grouping.
+	compareAllSeries = "all"
+)
+
+var compareWindowScopes = []string{"log", "metric", "pattern"}
+
+type CompareWindowsResult struct {
+	Scope    string        `json:"scope"`
+	Query    string        `json:"query_used"`
+	Current  CompareWindow `json:"current"`
+	Baseline CompareWindow `json:"baseline"`
+	// Total is the sum of all the series, omitted for aggregations that cannot be summed, e.g. avg
+	Total    *SeriesDelta    `json:"total,omitempty"`
+	Series   []SeriesDelta   `json:"series"`
+	Warnings []GraphWarning  `json:"warnings,omitempty"`
+	Stats    *QueryStats     `json:"stats,omitempty"`
+	Guidance *SearchGuidance `json:"guidance,omitempty"`
+}
+
+type CompareWindow struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// SeriesDelta is the change of a series from the baseline window to the current one.
+type SeriesDelta struct {
+	Series   string  `json:"series"`
+	Current  float64 `json:"current"`
+	Baseline float64 `json:"baseline"`
+	Delta    float64 `json:"delta"`
+	// PercentChange is omitted when the series is zero in the baseline window
+	PercentChange *float64 `json:"percent_change,omitempty"`
+	// Status is "new" for a series only in the current window, "gone" for one only in the baseline
+	Status string `json:"status,omitempty"`
+}
+
+// CompareWindowsTool creates a tool comparing a query over a current and a baseline time window
+func CompareWindowsTool(client Client) (tool mcp.Tool, handler server.ToolHandlerFunc) {
+	return mcp.NewTool("compare_windows",
+			mcp.WithTitleAnnotation("Compare Time Windows"),
+			mcp.WithDescription(fmt.Sprintf(`Runs the same query over a current window and a baseline window, e.g. the last 1h and the same 1h yesterday, and returns the delta and percent change of every series.
+
+Use this tool to answer "is this normal?" or "what changed since yesterday?" instead of running two searches or graphs and comparing by hand.
+
+Scopes:
+- log: count of the matching logs, per value of group_by if set
+- metric: aggregation of metric_name, per value of group_by if set (use search_metrics for the exact name)
+- pattern: count of every log pattern of the matching logs, new patterns have status "new"
+
+Series are sorted by absolute delta, biggest changes first. Returns %d series by default, up to %d.`, defaultCompareSeries, maxCompareSeries)),
+			mcp.WithString("scope",
+				mcp.Description("Scope of the query"),
+				mcp.Enum(compareWindowScopes...),
+				mcp.DefaultString("log"),
+			),
+			mcp.WithString("query",
+				mcp.Description(`CQL filter query, e.g. service.name:"checkout" AND severity_text:"ERROR". Use "*" for all records.`),
+				mcp.DefaultString("*"),
+			),
+			mcp.WithString("group_by",
+				mcp.Description("Facet to split the log or metric series by, e.g. service.name. Ignored for patterns."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("metric_name",
+				mcp.Description("Exact metric name, required for the metric scope."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithString("aggregation",
+				mcp.Description("Aggregation of the metric scope."),
+				mcp.Enum(graphAggregations["metric"]...),
+				mcp.DefaultString("sum"),
+			),
+			mcp.WithString("window",
+				mcp.Description("Length of both windows in Go duration format, or days and weeks (e.g., 15m, 1h, 24h, 7d)."),
+				mcp.DefaultString("1h"),
+			),
+			mcp.WithString("baseline_offset",
+				mcp.Description("How long before the current window the baseline window is, at least the window, e.g. 24h for the same time yesterday, 1w for last week, or the window for the previous one."),
+				mcp.DefaultString("24h"),
+			),
+			mcp.WithString("to",
+				mcp.Description("End of the current window in ISO format 2006-01-02T15:04:05.000Z. Defaults to now."),
+				mcp.DefaultString(""),
+			),
+			mcp.WithNumber("limit",
+				mcp.Description(fmt.Sprintf("Number of series to return. Default: %d, max %d", defaultCompareSeries, maxCompareSeries)),
+				mcp.DefaultNumber(defaultCompareSeries),
+			),
+			mcp.WithReadOnlyHintAnnotation(true),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithOpenWorldHintAnnotation(false),
+		),
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			scope, _ := params.Optional[string](request, "scope")
+			if scope == "" {
+				scope = "log"
+			}
+			if !slices.Contains(compareWindowScopes, scope) {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid scope %q, expected one of %s", scope, strings.Join(compareWindowScopes, ", "))), nil
+			}
+
+			filter, _ := params.Optional[string](request, "query")
+			if filter = strings.TrimSpace(filter); filter == "" {
+				filter = "*"
+			}
+			groupBy, _ := params.Optional[string](request, "group_by")
+			if groupBy = strings.TrimSpace(groupBy); groupBy != "" && !facetPathPattern.MatchString(groupBy) {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid group_by %q, expected a single facet path such as service.name", groupBy)), nil
+			}
+
+			limit := defaultCompareSeries
+			if v, _ := params.Optional[float64](request, "limit"); v > 0 {
+				limit = int(v)
+			}
+			if limit > maxCompareSeries {
+				return mcp.NewToolResultError(fmt.Sprintf("limit must be at most %d", maxCompareSeries)), nil
+			}
+
+			window, _ := params.Optional[string](request, "window")
+			if window == "" {
+				window = "1h"
+			}
+			windowLength, err := ParseLookback(window)
+			if err != nil || windowLength <= 0 {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid window %q, expected a duration such as 1h or 7d", window)), nil
+			}
+			offset, _ := params.Optional[string](request, "baseline_offset")
+			if offset == "" {
+				offset = "24h"
+			}
+			baselineOffset, err := ParseLookback(offset)
+			if err != nil || baselineOffset < windowLength {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid baseline_offset %q, expected a duration of at least the window %s so that the windows do not overlap", offset, window)), nil
+			}
+
+			end := time.Now().UTC()
+			if to, _ := params.Optional[string](request, "to"); to != "" {
+				if end, err = time.Parse(time.RFC3339, to); err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("invalid to %q, expected ISO format 2006-01-02T15:04:05.000Z", to)), nil
+				}
+			}
+			current := timeRange{from: end.Add(-windowLength), to: end}
+			baseline := timeRange{from: current.from.Add(-baselineOffset), to: current.to.Add(-baselineOffset)}
+
+			result := CompareWindowsResult{
+				Scope:    scope,
+				Query:    filter,
+				Current:  CompareWindow{From: current.from.Format(isoTimeLayout), To: current.to.Format(isoTimeLayout)},
+				Baseline: CompareWindow{From: baseline.from.Format(isoTimeLayout), To: baseline.to.Format(isoTimeLayout)},
+			}
+
+			var fetch func(timeRange) (map[string]float64, error)
+			additive := true
+			if scope == "pattern" {
+				fetch = func(r timeRange) (map[string]float64, error) {
+					return windowPatterns(ctx, client, filter, r)
+				}
+			} else {
+				query := GraphQuery{Scope: scope, Query: filter}
+				if groupBy != "" {
+					query.Query = fmt.Sprintf("{%s} by {%s}", filter, groupBy)
+				}
+				if scope == "metric" {
+					metricName, _ := params.Optional[string](request, "metric_name")
+					if metricName = strings.TrimSpace(metricName); metricName == "" {
+						return mcp.NewToolResultError("metric_name is required for the metric scope, use search_metrics to find it"), nil
+					}
+					aggregation, _ := params.Optional[string](request, "aggregation")
+					if aggregation == "" {
+						aggregation = graphAggregations["metric"][0]
+					}
+					if !slices.Contains(graphAggregations["metric"], aggregation) {
+						return mcp.NewToolResultError(fmt.Sprintf("invalid aggregation %q, expected one of %s", aggregation, strings.Join(graphAggregations["metric"], ", "))), nil
+					}
+					metric := MetricQuery{Aggregation: aggregation, Name: metricName, Filter: filter}
+					if groupBy != "" {
+						metric.GroupBy = []string{groupBy}
+					}
+					query.Query = metric.CQL()
+					additive = aggregation == "sum" || aggregation == "count"
+				}
+				result.Query = query.Query
+				fetch = func(r timeRange) (map[string]float64, error) {
+					return windowSeries(ctx, client, query, r, &result)
+				}
+			}
+
+			currentSeries, err := fetch(current)
+			if err != nil {
+				return nil, err
+			}
+			baselineSeries, err := fetch(baseline)
+			if err != nil {
+				return nil, err
+			}
+
+			result.Series = seriesDeltas(currentSeries, baselineSeries)
+			if additive {
+				var total SeriesDelta
+				for _, s := range result.Series {
+					total.Current += s.Current
+					total.Baseline += s.Baseline
+				}
+				total = newSeriesDelta(compareAllSeries, total.Current, total.Baseline)
+				result.Total = &total
+			}
+			if len(result.Series) > limit {
+				result.Series = result.Series[:limit]
+			}
+			result.Guidance = compareWindowsGuidance(result, window, offset)
+
+			r, err := json.Marshal(result)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal response: %w", err)
+			}
+			return mcp.NewToolResultText(string(r)), nil
+		}
+}
+
+// windowSeries returns the value of every series of the graph query over the time range. The
+// warnings and stats of the request are added to result.
+func windowSeries(ctx context.Context, client Client, query GraphQuery, r timeRange, result *CompareWindowsResult) (map[string]float64, error) {
+	payload, err := NewGraphQueryBuilder().
+		WithQuery("Q1", query).
+		WithFormula("R1", "Q1").
+		Build()
+	if err != nil {
+		return nil, err
+	}
+
+	queryParams := url.Values{}
+	queryParams.Add("graph_type", "table")
+	queryParams.Add("from", r.from.Format(isoTimeLayout))
+	queryParams.Add("to", r.to.Format(isoTimeLayout))
+	queryParams.Add("limit", strconv.Itoa(compareSeriesFetched))
+
+	statusCode, bodyBytes, warnings, err := postGraphWithRetry(ctx, client, payload, queryParams)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusMultiStatus {
+		return nil, fmt.Errorf("failed to graph window, status code %d: %s", statusCode, string(bodyBytes))
+	}
+	result.Warnings = append(result.Warnings, warnings...)
+	if stats := parseQueryStats(bodyBytes); stats != nil {
+		result.Stats = stats
+	}
+
+	var resp map[string]struct {
+		Records []struct {
+			Values    []string `json:"values"`
+			Aggregate struct {
+				Value float64 `json:"value"`
+			} `json:"aggregate"`
+		} `json:"records"`
+	}
+	if err := json.Unmarshal(bodyBytes, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode graph response: %w", err)
+	}
+
+	series := make(map[string]float64)
+	for _, record := range resp["R1"].Records {
+		label := strings.Join(record.Values, ", ")
+		if label == "" {
+			label = compareAllSeries
+		}
+		series[label] += record.Aggregate.Value
+	}
+	return series, nil
+}
+
+// windowPatterns returns the count of every log pattern of the logs matching filter over the time range.
+func windowPatterns(ctx context.Context, client Client, filter string, r timeRange) (map[string]float64, error) {
+	stats, err := windowPatternStats(ctx, client, filter, r)
+	if err != nil {
+		return nil, err
+	}
+
+	series := make(map[string]float64, len(stats))
+	for _, p := range stats {
+		series[p.Pattern] += float64(p.Count)
+	}
+	return series, nil
+}
+
+// windowPatternStats returns the log patterns of the logs matching filter over the time range.
+func windowPatternStats(ctx context.Context, client Client, filter string, r timeRange) ([]ComparedPattern, error) {
+	queryParams := url.Values{}
+	queryParams.Add("query", filter)
+	queryParams.Add("from", r.from.Format(isoTimeLayout))
+	queryParams.Add("to", r.to.Format(isoTimeLayout))
+	queryParams.Add("limit", strconv.Itoa(compareSeriesFetched))
+
+	bodyBytes, err := getPatternStats(ctx, client, queryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Stats []ComparedPattern `json:"stats"`
+	}
+	if err := json.Unmarshal(bodyBytes, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode clustering stats response: %v", err)
+	}
+	return resp.Stats, nil
+}
+
+// seriesDeltas returns the deltas of the series of both windows, biggest absolute delta first.
+func seriesDeltas(current, baseline map[string]float64) []SeriesDelta {
+	deltas := make([]SeriesDelta, 0, len(current))
+	for label, value := range current {
+		deltas = append(deltas, newSeriesDelta(label, value, baseline[label]))
+	}
+	for label, value := range baseline {
+		if _, ok := current[label]; !ok {
+			deltas = append(deltas, newSeriesDelta(label, 0, value))
+		}
+	}
+	sort.Slice(deltas, func(i, j int) bool {
+		if a, b := math.Abs(deltas[i].Delta), math.Abs(deltas[j].Delta); a != b {
+			return a > b
+		}
+		return deltas[i].Series < deltas[j].Series
+	})
+	return deltas
+}
+
+func newSeriesDelta(label string, current, baseline float64) SeriesDelta {
+	delta := SeriesDelta{Series: label, Current: current, Baseline: baseline, Delta: current - baseline}
+	if baseline != 0 {
+		change := math.Round((current-baseline)/math.Abs(baseline)*10000) / 100
+		delta.PercentChange = &change
+	}
+	switch {
+	case baseline == 0 && current != 0:
+		delta.Status = "new"
+	case current == 0 && baseline != 0:
+		delta.Status = "gone"
+	}
+	return delta
+}
+
+func compareWindowsGuidance(result CompareWindowsResult, window, offset string) *SearchGuidance {
+	if len(result.Series) == 0 && len(result.Warnings) > 0 {
+		return &SearchGuidance{
+			ResultStatus: "failed",
+			NextSteps:    []string{"The window queries failed, see warnings."},
+			Suggestions:  []string{"Check the metric name with search_metrics and the query with validate_cql"},
+		}
+	}
+	if len(result.Series) == 0 {
+		return &SearchGuidance{
+			ResultStatus: "empty",
+			NextSteps: []string{
+				fmt.Sprintf("No %s data for query %s in either window.", result.Scope, result.Query),
+			},
+			Suggestions: []string{
+				"Check the query with validate_cql, or widen the window",
+			},
+		}
+	}
+
+	guidance := &SearchGuidance{ResultStatus: "success"}
+	if len(result.Warnings) > 0 {
+		guidance.ResultStatus = "partial"
+		guidance.NextSteps = append(guidance.NextSteps, "A window query failed, see warnings; its series are missing or zero.")
+	}
+	if t := result.Total; t != nil && t.PercentChange != nil {
+		guidance.NextSteps = append(guidance.NextSteps, fmt.Sprintf("Total changed by %+.2f%% over the last %s compared to %s before.", *t.PercentChange, window, offset))
+	}
+
+	var added []string
+	for _, s := range result.Series {
+		if s.Status == "new" {
+			added = append(added, s.Series)
+		}
+	}
+	if len(added) > 0 {
+		guidance.NextSteps = append(guidance.NextSteps, fmt.Sprintf("%d series are new in the current window, e.g. %q.", len(added), added[0]))
+	}
+
+	top := result.Series[0]
+	switch result.Scope {
+	case "pattern":
+		guidance.Suggestions = append(guidance.Suggestions, fmt.Sprintf("Use get_log_search with the pattern %q to see its logs.", top.Series))
+	default:
+		guidance.Suggestions = append(guidance.Suggestions, fmt.Sprintf("Series %q changed the most; graph it with get_log_graph or get_metric_graph over both windows to see when it changed.", top.Series))
+	}
+	guidance.Suggestions = append(guidance.Suggestions, queryStatsSuggestions(result.Stats)...)
+	return guidance
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/demo"
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/tools"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+type recordingAuditLogger struct {
+	mu     sync.Mutex
+	events []tools.AuditEvent
+}
+
+func (l *recordingAuditLogger) Log(_ context.Context, event tools.AuditEvent) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, event)
+	return nil
+}
+
+func TestAuditRecordsEffectiveOrgAndSubject(t *testing.T) {
+	auditLogger := &recordingAuditLogger{}
+	config := defaultServerConfig
+	for _, opt := range []ServerOption{
+		WithClient(demo.NewClient()),
+		WithAuditLogger(auditLogger),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	} {
+		opt(&config)
+	}
+	s := config.newMCPServer(config.newClient())
+
+	ctx := context.WithValue(context.Background(), tools.OrgIDKey, demo.OrgID)
+	ctx = context.WithValue(ctx, tools.BearerTokenKey, "oauth-token")
+	ctx = context.WithValue(ctx, tools.SubjectKey, "alice")
+	var request mcp.CallToolRequest
+	request.Params.Name = "get_log_search"
+	request.Params.Arguments = map[string]any{"org_id": "demo-acme", "query": " error ", "cursor": "", "lookback": "1h", "limit": 1}
+	result, err := s.GetTool("get_log_search").Handler(ctx, request)
+	if err != nil || result.IsError {
+		t.Fatalf("get_log_search failed: %v %v", err, result)
+	}
+
+	if len(auditLogger.events) != 1 {
+		t.Fatalf("audited %d events, want 1", len(auditLogger.events))
+	}
+	event := auditLogger.events[0]
+	if event.OrgID != "demo-acme" {
+		t.Errorf("audited org %q, want the child org the call ran against", event.OrgID)
+	}
+	if event.Caller != "sub:alice" {
+		t.Errorf("audited caller %q, want the subject of the token", event.Caller)
+	}
+	if _, ok := event.Arguments["cursor"]; ok || event.Arguments["query"] != "error" {
+		t.Errorf("audited arguments %v, want the canonical arguments", event.Arguments)
+	}
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/demo"
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/tools"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	benchSearchItems = 1000
+	benchGraphSeries = 100
+	// benchMaxResultBytes cuts the search results but not the graph, so truncation is measured too
+	benchMaxResultBytes = 256 << 10
+)
+
+// benchCalls are the calls the benchmarks make: a search returning benchSearchItems logs and a graph
+// grouped by enough dimensions for benchGraphSeries series.
+var benchCalls = map[string]map[string]any{
+	"get_log_search": {"lookback": "1h", "limit": float64(benchSearchItems)},
+	"get_log_graph":  {"query": "* by {service.name,host.name,severity_text,ed.tag}", "lookback": "1h", "limit": float64(benchGraphSeries)},
+}
+
+// handlerLatencyBudgets are the durations per call the handlers must stay under in the benchmarks,
+// several times what they take today so that slower CI runners do not fail them.
+var handlerLatencyBudgets = map[string]time.Duration{
+	"get_log_search": time.Second,
+	"get_log_graph":  500 * time.Millisecond,
+}
+
+// benchServer is a server against the demo API with the middlewares of a deployment: secret
+// redaction, result truncation and pagination hints.
+func benchServer(tb testing.TB) *server.MCPServer {
+	tb.Helper()
+
+	redactor, err := tools.NewRedactor(tools.DefaultRedactionPatterns)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	config := defaultServerConfig
+	for _, opt := range []ServerOption{
+		WithClient(demo.NewClient()),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithSecretRedaction(redactor),
+		WithMaxResultBytes(benchMaxResultBytes),
+	} {
+		opt(&config)
+	}
+	return config.newMCPServer(config.newClient())
+}
+
+func runBenchCall(tb testing.TB, s *server.MCPServer, name string) {
+	ctx := context.WithValue(context.Background(), tools.OrgIDKey, demo.OrgID)
+	ctx = context.WithValue(ctx, tools.EDTokenKey, demo.APIToken)
+	var request mcp.CallToolRequest
+	request.Params.Name = name
+	request.Params.Arguments = benchCalls[name]
+
+	result, err := s.GetTool(name).Handler(ctx, request)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if result.IsError {
+		tb.Fatalf("%s failed: %v", name, result.Content)
+	}
+}
+
+func benchmarkTool(b *testing.B, name string) {
+	s := benchServer(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		runBenchCall(b, s, name)
+	}
+	if perCall := b.Elapsed() / time.Duration(b.N); perCall > handlerLatencyBudgets[name] {
+		b.Errorf("%s takes %s per call, budget is %s", name, perCall, handlerLatencyBudgets[name])
+	}
+}
+
+func BenchmarkSearchLogs(b *testing.B) {
+	benchmarkTool(b, "get_log_search")
+}
+
+func BenchmarkLogGraph(b *testing.B) {
+	benchmarkTool(b, "get_log_graph")
+}
+
+// handlerAllocLimits are the allocations per call the handlers must stay under, roughly twice
+// what they make today, so that CI catches regressions without being flaky across Go versions.
+var handlerAllocLimits = map[string]float64{
+	"get_log_search": 350000,
+	"get_log_graph":  150000,
+}
+
+func TestHandlerAllocations(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping allocation thresholds in short mode")
+	}
+
+	s := benchServer(t)
+	for _, name := range []string{"get_log_search", "get_log_graph"} {
+		t.Run(name, func(t *testing.T) {
+			allocs := testing.AllocsPerRun(5, func() { runBenchCall(t, s, name) })
+			t.Logf("%s: %.0f allocs per call", name, allocs)
+			if limit := handlerAllocLimits[name]; allocs > limit {
+				t.Errorf("%s makes %.0f allocs per call, limit is %.0f", name, allocs, limit)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// Set at build time with
+// -ldflags "-X github.com/edgedelta/edgedelta-mcp-server/server.version=..."
+var (
+	version = ""
+	commit  = ""
+	date    = ""
+)
+
+// Build identifies the running build of the server.
+type Build struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+	Date    string `json:"date"`
+}
+
+// BuildInfo returns the version, commit and date the server was built with. Values not set at
+// build time are taken from the Go module and VCS information embedded in the binary.
+func BuildInfo() Build {
+	b := Build{Version: version, Commit: commit, Date: date}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if b.Version == "" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			b.Version = info.Main.Version
+		}
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && b.Commit == "":
+				b.Commit = setting.Value
+			case setting.Key == "vcs.time" && b.Date == "":
+				b.Date = setting.Value
+			}
+		}
+	}
+
+	if b.Version == "" {
+		b.Version = "dev"
+	}
+	if b.Commit == "" {
+		b.Commit = "unknown"
+	}
+	if b.Date == "" {
+		b.Date = "unknown"
+	}
+	return b
+}
+
+func (b Build) String() string {
+	return fmt.Sprintf("%s (%s) %s", b.Version, b.Commit, b.Date)
+}
+
+// userAgent is the User-Agent of requests to the Edge Delta API.
+func (b Build) userAgent() string {
+	commit := b.Commit
+	if len(commit) > 12 {
+		commit = commit[:12]
+	}
+	return fmt.Sprintf("edgedelta-mcp-server/%s (%s)", b.Version, commit)
+}
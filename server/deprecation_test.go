@@ -0,0 +1,30 @@
+package server
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/demo"
+)
+
+// TestDeprecatedLimitsUndocumented checks that no tool documents the negative limits
+// DeprecationWarnings drops.
+func TestDeprecatedLimitsUndocumented(t *testing.T) {
+	config := defaultServerConfig
+	WithClient(demo.NewClient())(&config)
+	WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))(&config)
+	s := config.newMCPServer(config.newClient())
+
+	for name, tool := range s.ListTools() {
+		property, ok := tool.Tool.InputSchema.Properties["limit"].(map[string]any)
+		if !ok {
+			continue
+		}
+		description, _ := property["description"].(string)
+		if strings.Contains(strings.ToLower(description), "negative") {
+			t.Errorf("%s documents negative limits, which are dropped: %q", name, description)
+		}
+	}
+}
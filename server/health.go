@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// healthPath answers as long as the process serves HTTP, for liveness probes
+	healthPath = "/healthz"
+	// readyPath answers with 200 once the server can serve tool calls, for readiness probes and
+	// load balancers
+	readyPath = "/readyz"
+	// metricsPath serves the metrics of WithMetrics
+	metricsPath = "/metrics"
+
+	// readinessTimeout bounds the request checking the Edge Delta API is reachable
+	readinessTimeout = 5 * time.Second
+)
+
+// ReadinessCheck is the outcome of a check of /readyz.
+type ReadinessCheck struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Readiness is the response of /readyz.
+type Readiness struct {
+	Status string                    `json:"status"`
+	Checks map[string]ReadinessCheck `json:"checks"`
+}
+
+func (c *serverConfig) healthHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	}
+}
+
+// readyHandler checks the OpenAPI spec of the API drift check was loaded, when the check is
+// enabled, and the Edge Delta API is reachable, unless the tools use a client set with WithClient.
+func (c *serverConfig) readyHandler() http.HandlerFunc {
+	httpClient := &http.Client{Timeout: readinessTimeout}
+	return func(w http.ResponseWriter, r *http.Request) {
+		readiness := Readiness{Status: "ok", Checks: make(map[string]ReadinessCheck)}
+
+		if c.apiDrift != nil {
+			readiness.Checks["openapi_spec"] = c.checkSpecLoaded()
+		}
+		if c.client == nil {
+			readiness.Checks["edgedelta_api"] = c.checkAPIReachable(r.Context(), httpClient)
+		}
+
+		status := http.StatusOK
+		for _, check := range readiness.Checks {
+			if check.Status != "ok" {
+				readiness.Status = "unavailable"
+				status = http.StatusServiceUnavailable
+			}
+		}
+		writeJSON(w, status, readiness)
+	}
+}
+
+func (c *serverConfig) checkSpecLoaded() ReadinessCheck {
+	report := c.apiDrift.Report()
+	switch {
+	case report.CheckedAt.IsZero():
+		return ReadinessCheck{Status: "unavailable", Error: "OpenAPI spec not loaded yet"}
+	case report.Error != "":
+		return ReadinessCheck{Status: "unavailable", Error: report.Error}
+	}
+	return ReadinessCheck{Status: "ok"}
+}
+
+// checkAPIReachable checks the Edge Delta API answers. Any response but a server error counts,
+// the request has no credentials.
+func (c *serverConfig) checkAPIReachable(ctx context.Context, httpClient *http.Client) ReadinessCheck {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.apiURL, nil)
+	if err != nil {
+		return ReadinessCheck{Status: "unavailable", Error: err.Error()}
+	}
+	req.Header.Set("User-Agent", BuildInfo().userAgent())
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return ReadinessCheck{Status: "unavailable", Error: err.Error()}
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return ReadinessCheck{Status: "unavailable", Error: fmt.Sprintf("status code %d", resp.StatusCode)}
+	}
+	return ReadinessCheck{Status: "ok"}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -3,8 +3,10 @@ package server
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/edgedelta/edgedelta-mcp-server/pkg/tools"
 
@@ -33,10 +35,33 @@ func WithDisableStreaming(disableStreaming bool) ServerOption {
 	}
 }
 
+// warmupConfig holds the service account the warmup fetches with
+type warmupConfig struct {
+	orgID    string
+	apiToken string
+}
+
+// WithWarmup prefetches the services and facet keys of the org on startup with the API token of
+// a service account, and keeps them cached, so the first interaction of a session is fast
+func WithWarmup(orgID, apiToken string) ServerOption {
+	return func(c *serverConfig) {
+		c.warmup = &warmupConfig{orgID: orgID, apiToken: apiToken}
+	}
+}
+
+// WithDefaultOrgID sets the org ID used when a request does not carry one
+func WithDefaultOrgID(orgID string) ServerOption {
+	return func(c *serverConfig) {
+		c.defaultOrgID = orgID
+	}
+}
+
 // MCPHTTPServer wraps the HTTP server and its dependencies
 type MCPHTTPServer struct {
 	httpServer *server.StreamableHTTPServer
-	config     *serverConfig
+	// handler serves the MCP endpoint, requiring a bearer token with OAuth
+	handler http.Handler
+	config  *serverConfig
 }
 
 // New creates a new Edge Delta MCP HTTP server
@@ -48,16 +73,33 @@ func NewHTTPServer(opts ...ServerOption) (*MCPHTTPServer, error) {
 	for _, opt := range opts {
 		opt(&config)
 	}
+	if config.redactor != nil {
+		config.logger = slog.New(tools.NewRedactingHandler(config.logger.Handler(), config.redactor))
+	}
 
-	httpClient := tools.NewHTTPClient(config.apiURL, config.apiTokenHeader)
-
-	s := server.NewMCPServer(config.serverName, config.serverVersion)
+	client := config.newClient()
 
-	AddCustomTools(s, httpClient)
-	AddCustomResources(s, httpClient)
+	s := config.newMCPServer(client)
 
 	// Create auth middleware that uses the configured header
 	authMiddleware := func(ctx context.Context, r *http.Request) context.Context {
+		// Forward the client's locale so API error messages match the language of the session
+		if locale := r.Header.Get("Accept-Language"); locale != "" {
+			ctx = addToContext(ctx, tools.LocaleKey, locale)
+		} else if config.locale != "" {
+			ctx = addToContext(ctx, tools.LocaleKey, config.locale)
+		}
+
+		// Tool calls continue the trace of the HTTP request
+		if traceParent := r.Header.Get("traceparent"); traceParent != "" {
+			ctx = tools.ContextWithTraceParent(ctx, traceParent)
+		}
+
+		// With OAuth the token and org ID were already taken from the verified token
+		if config.oauth != nil {
+			return ctx
+		}
+
 		// Check for Bearer token in Authorization header
 		if authHeader := r.Header.Get("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
 			ctx = addToContext(ctx, tools.BearerTokenKey, strings.TrimPrefix(authHeader, "Bearer "))
@@ -79,26 +121,71 @@ func NewHTTPServer(opts ...ServerOption) (*MCPHTTPServer, error) {
 		orgID, ok := mux.Vars(r)["org_id"]
 		if ok && orgID != "" {
 			ctx = addToContext(ctx, tools.OrgIDKey, orgID)
+		} else if config.defaultOrgID != "" {
+			ctx = addToContext(ctx, tools.OrgIDKey, config.defaultOrgID)
 		}
 
 		return ctx
 	}
 
-	httpServer := server.NewStreamableHTTPServer(
-		s,
+	httpOpts := []server.StreamableHTTPOption{
+		server.WithEndpointPath(mcpEndpointPath),
 		server.WithHTTPContextFunc(authMiddleware),
 		server.WithStateLess(config.stateless),
 		server.WithDisableStreaming(config.disableStreaming),
-	)
+	}
 
-	return &MCPHTTPServer{
+	var verifier *jwtVerifier
+	if config.oauth != nil {
+		if config.oauth.issuerURL == "" {
+			return nil, fmt.Errorf("OAuth issuer URL not set")
+		}
+		// Without an audience the server would accept tokens the issuer minted for any client
+		if config.oauth.audience == "" {
+			return nil, fmt.Errorf("OAuth audience not set")
+		}
+		verifier = newJWTVerifier(*config.oauth)
+	}
+
+	// The MCP endpoint shares the listener with the health endpoints
+	serveMux := http.NewServeMux()
+	httpOpts = append(httpOpts, server.WithStreamableHTTPServer(&http.Server{Handler: serveMux}))
+
+	httpServer := server.NewStreamableHTTPServer(s, httpOpts...)
+
+	m := &MCPHTTPServer{
 		httpServer: httpServer,
+		handler:    httpServer,
 		config:     &config,
-	}, nil
+	}
+	if verifier != nil {
+		m.handler = config.oauthHandler(verifier, httpServer)
+		serveMux.Handle(protectedResourcePath, config.protectedResourceHandler())
+	}
+	serveMux.Handle(mcpEndpointPath, m.handler)
+	serveMux.Handle(healthPath, config.healthHandler())
+	serveMux.Handle(readyPath, config.readyHandler())
+	if config.metrics != nil {
+		serveMux.Handle(metricsPath, config.metrics.Handler())
+	}
+	return m, nil
 }
 
 // Start starts the HTTP server and blocks until shutdown
-func (m *MCPHTTPServer) Start(_ context.Context) error {
+func (m *MCPHTTPServer) Start(ctx context.Context) error {
+	if m.config.warmCache != nil {
+		go m.config.keepWarm(ctx)
+	}
+	if m.config.apiDrift != nil {
+		go m.config.watchAPIDrift(ctx)
+	}
+	if m.config.reloads != nil {
+		m.config.watchReloads(ctx)
+	}
+	if m.config.tracer != nil {
+		go m.config.tracer.Run(ctx, m.config.logger)
+	}
+
 	addr := fmt.Sprintf(":%d", m.config.port)
 	m.config.logger.Info("Starting MCP server", "addr", addr)
 	return m.httpServer.Start(addr)
@@ -109,6 +196,30 @@ func (m *MCPHTTPServer) Port() int {
 	return m.config.port
 }
 
+// keepWarm runs the warmup now and again before the cached responses expire, until ctx is done
+func (c *serverConfig) keepWarm(ctx context.Context) {
+	ctx = addToContext(ctx, tools.OrgIDKey, c.warmup.orgID)
+	ctx = addToContext(ctx, tools.EDTokenKey, c.warmup.apiToken)
+
+	ticker := time.NewTicker(c.warmCache.TTL() * 3 / 4)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		if err := c.warmCache.Warm(ctx); err != nil {
+			c.logger.Warn("Warmup failed", "org_id", c.warmup.orgID, "error", err)
+		} else {
+			c.logger.Info("Warmup completed", "org_id", c.warmup.orgID, "duration", time.Since(start))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 func addToContext(ctx context.Context, key tools.ContextKey, value string) context.Context {
 	return context.WithValue(ctx, key, value)
 }
@@ -116,3 +227,9 @@ func addToContext(ctx context.Context, key tools.ContextKey, value string) conte
 func (m *MCPHTTPServer) HTTPServer() *server.StreamableHTTPServer {
 	return m.httpServer
 }
+
+// Handler returns the handler of the MCP endpoint, to mount it on another router. With OAuth it
+// rejects requests without a valid bearer token.
+func (m *MCPHTTPServer) Handler() http.Handler {
+	return m.handler
+}
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/demo"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// recordingClient is the demo API recording the auth headers of the requests the tools make.
+type recordingClient struct {
+	*demo.Client
+
+	mu      sync.Mutex
+	headers []http.Header
+}
+
+func (c *recordingClient) Do(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	c.headers = append(c.headers, req.Header.Clone())
+	c.mu.Unlock()
+	return c.Client.Do(req)
+}
+
+func (c *recordingClient) lastHeader(key string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.headers) == 0 {
+		return ""
+	}
+	return c.headers[len(c.headers)-1].Get(key)
+}
+
+// serveHTTP serves the MCP endpoint over HTTP against the demo API and returns its URL.
+func serveHTTP(t *testing.T, opts ...ServerOption) (string, *recordingClient) {
+	t.Helper()
+
+	api := &recordingClient{Client: demo.NewClient()}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	opts = append([]ServerOption{WithClient(api), WithDefaultOrgID(demo.OrgID), WithLogger(logger)}, opts...)
+	m, err := NewHTTPServer(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(m.Handler())
+	t.Cleanup(srv.Close)
+	return srv.URL + mcpEndpointPath, api
+}
+
+// startHTTPServer serves the MCP endpoint like serveHTTP and returns a client initialized with
+// headers.
+func startHTTPServer(t *testing.T, headers map[string]string, opts ...ServerOption) (*client.Client, *recordingClient) {
+	t.Helper()
+
+	endpoint, api := serveHTTP(t, opts...)
+	c, err := client.NewStreamableHttpClient(endpoint, transport.WithHTTPHeaders(headers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+
+	ctx := context.Background()
+	if err := c.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var initialize mcp.InitializeRequest
+	initialize.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initialize.Params.ClientInfo = mcp.Implementation{Name: "http-test", Version: "1.0.0"}
+	result, err := c.Initialize(ctx, initialize)
+	if err != nil {
+		t.Fatalf("initialize: %v", err)
+	}
+	if result.ServerInfo.Name == "" || result.Capabilities.Tools == nil {
+		t.Fatalf("initialize returned %+v, want server info and the tools capability", result)
+	}
+	return c, api
+}
+
+func callTool(t *testing.T, c *client.Client, name string, args map[string]any) *mcp.CallToolResult {
+	t.Helper()
+
+	var request mcp.CallToolRequest
+	request.Params.Name = name
+	request.Params.Arguments = args
+	result, err := c.CallTool(context.Background(), request)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if result.IsError {
+		t.Fatalf("%s failed: %s", name, toolResultText(result))
+	}
+	return result
+}
+
+func TestHTTPServerListTools(t *testing.T) {
+	c, _ := startHTTPServer(t, map[string]string{"X-ED-API-Token": demo.APIToken})
+
+	result, err := c.ListTools(context.Background(), mcp.ListToolsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]bool, len(result.Tools))
+	for _, tool := range result.Tools {
+		names[tool.Name] = true
+	}
+	for _, name := range []string{"get_log_search", "get_log_graph", "facet_options", "deploy_pipeline"} {
+		if !names[name] {
+			t.Errorf("tools/list is missing %s", name)
+		}
+	}
+}
+
+func TestHTTPServerLargeResults(t *testing.T) {
+	args := map[string]any{"lookback": "24h", "limit": 1000}
+
+	t.Run("intact", func(t *testing.T) {
+		c, _ := startHTTPServer(t, map[string]string{"X-ED-API-Token": demo.APIToken})
+
+		text := callTool(t, c, "get_log_search", args).Content[0].(mcp.TextContent).Text
+		if len(text) < 100_000 {
+			t.Fatalf("result is %d bytes, want a result over 100kB", len(text))
+		}
+		var response struct {
+			Data struct {
+				Items []json.RawMessage `json:"items"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(text), &response); err != nil {
+			t.Fatalf("result is not valid JSON: %v", err)
+		}
+		if len(response.Data.Items) != 1000 {
+			t.Errorf("result has %d items, want 1000", len(response.Data.Items))
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		const maxBytes = 16 << 10
+		c, _ := startHTTPServer(t, map[string]string{"X-ED-API-Token": demo.APIToken}, WithMaxResultBytes(maxBytes))
+
+		result := callTool(t, c, "get_log_search", args)
+		if text := toolResultText(result); len(text) > maxBytes {
+			t.Errorf("result is %d bytes, want at most %d", len(text), maxBytes)
+		}
+		if footer := result.Content[len(result.Content)-1].(mcp.TextContent).Text; !strings.Contains(footer, `"truncation"`) {
+			t.Errorf("last content is %q, want the truncation footer", footer)
+		}
+	})
+}
+
+func TestHTTPServerAuthHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		key     string
+		want    string
+	}{
+		{"api token", map[string]string{"X-ED-API-Token": "header-token"}, "X-ED-API-Token", "header-token"},
+		{"bearer token", map[string]string{"Authorization": "Bearer oauth-token"}, "Authorization", "Bearer oauth-token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, api := startHTTPServer(t, tt.headers)
+
+			callTool(t, c, "get_log_search", map[string]any{"lookback": "1h", "limit": 1})
+			if got := api.lastHeader(tt.key); got != tt.want {
+				t.Errorf("API request has %s %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
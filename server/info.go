@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/tools"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ServerInfoResource describes the running server: its build and, when enabled, the drift of the
+// Edge Delta API from what the tools expect.
+var ServerInfoResource = mcp.NewResource(
+	"server://info",
+	"Server Info",
+	mcp.WithResourceDescription(`Version of the Edge Delta MCP server and the result of its check of the Edge Delta API endpoints the tools call.
+If api_drift lists endpoints, the tools calling them may fail: tell the user and suggest updating the server.`),
+	mcp.WithMIMEType("application/json"),
+)
+
+// ServerInfo is the content of the server://info resource.
+type ServerInfo struct {
+	Name     string                `json:"name"`
+	Build    Build                 `json:"build"`
+	APIURL   string                `json:"api_url"`
+	APIDrift *tools.APIDriftReport `json:"api_drift,omitempty"`
+}
+
+// WithAPISpecCheck checks on startup that the Edge Delta API endpoints the tools call are in the
+// OpenAPI spec at specURL, logging a warning for each one that is missing and reporting them in
+// the server://info resource.
+func WithAPISpecCheck(specURL string) ServerOption {
+	return func(c *serverConfig) {
+		c.apiSpecURL = specURL
+	}
+}
+
+func (c *serverConfig) serverInfoHandler() server.ResourceHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		info := ServerInfo{Name: c.serverName, Build: BuildInfo(), APIURL: c.apiURL}
+		if c.apiDrift != nil {
+			report := c.apiDrift.Report()
+			info.APIDrift = &report
+		}
+
+		result, err := json.Marshal(info)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal server info: %w", err)
+		}
+
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      request.Params.URI,
+				MIMEType: "application/json",
+				Text:     string(result),
+			},
+		}, nil
+	}
+}
+
+// WithOpenAPISpecFile reads the OpenAPI spec of the API drift check from the file at path, for
+// air-gapped environments or ones where the spec URL is unreliable. With WithAPISpecCheck the file
+// is only read when the spec cannot be fetched.
+func WithOpenAPISpecFile(path string) ServerOption {
+	return func(c *serverConfig) {
+		c.apiSpecFile = path
+	}
+}
+
+// WithAPISpecCheckInterval re-fetches the spec of WithAPISpecCheck and checks the tool endpoints
+// against it again at the interval, so API changes are noticed without a restart. Zero, the
+// default, checks only on startup.
+func WithAPISpecCheckInterval(interval time.Duration) ServerOption {
+	return func(c *serverConfig) {
+		c.apiSpecInterval = interval
+	}
+}
+
+// watchAPIDrift checks the API drift now and then at the check interval, if any, until ctx is
+// done. Checks after the first are only logged when their outcome changed.
+func (c *serverConfig) watchAPIDrift(ctx context.Context) {
+	last := c.checkAPIDrift(ctx, "")
+	if c.apiSpecInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(c.apiSpecInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			last = c.checkAPIDrift(ctx, last)
+		}
+	}
+}
+
+// checkAPIDrift checks the tool endpoints against the OpenAPI spec of the API and warns about the
+// ones that are missing, before users hit them as 404s. It returns the outcome of the check, and
+// logs nothing if it is the last one.
+func (c *serverConfig) checkAPIDrift(ctx context.Context, last string) string {
+	report, err := c.apiDrift.Check(ctx)
+	outcome := driftOutcome(report)
+	if outcome == last {
+		return outcome
+	}
+	if err != nil {
+		c.logger.Warn("API drift check failed", "spec_url", c.apiSpecURL, "spec_file", c.apiSpecFile, "error", err)
+		return outcome
+	}
+	if len(report.Drift) == 0 {
+		c.logger.Info("API drift check passed", "spec_url", c.apiSpecURL, "spec_file", c.apiSpecFile, "endpoints", report.Endpoints)
+		return outcome
+	}
+
+	c.logger.Warn("API DRIFT: Edge Delta API endpoints used by tools are missing from the OpenAPI spec; the tools calling them will fail",
+		"spec_url", c.apiSpecURL, "spec_file", c.apiSpecFile, "missing", len(report.Drift), "endpoints", report.Endpoints)
+	for _, drift := range report.Drift {
+		c.logger.Warn("API drift", "method", drift.Method, "path", drift.Path, "kind", drift.Kind, "spec_methods", drift.Methods)
+	}
+	return outcome
+}
+
+// driftOutcome summarizes the report to compare checks: its error or its drifted endpoints.
+func driftOutcome(report tools.APIDriftReport) string {
+	if report.Error != "" {
+		return "error: " + report.Error
+	}
+	outcome := make([]string, 0, len(report.Drift)+1)
+	outcome = append(outcome, "drift:")
+	for _, drift := range report.Drift {
+		outcome = append(outcome, drift.Kind+" "+drift.Method+" "+drift.Path)
+	}
+	return strings.Join(outcome, "\n")
+}
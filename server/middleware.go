@@ -0,0 +1,38 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/tools"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ToolMiddleware wraps the handler of every registered tool, see WithToolMiddleware. It is the
+// same type as tools.ToolMiddleware, so the builtin middlewares of pkg/tools can be passed too.
+type ToolMiddleware = tools.ToolMiddleware
+
+// loggingMiddleware logs every tool call with its duration and outcome
+func loggingMiddleware(logger *slog.Logger) tools.ToolMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			start := time.Now()
+			result, err := next(ctx, request)
+
+			attrs := []any{"tool", request.Params.Name, "duration", time.Since(start)}
+			switch {
+			case err != nil:
+				logger.Warn("Tool call failed", append(attrs, "error", err)...)
+			case result != nil && result.IsError:
+				logger.Warn("Tool call returned an error result", attrs...)
+			default:
+				logger.Debug("Tool call completed", attrs...)
+			}
+
+			return result, err
+		}
+	}
+}
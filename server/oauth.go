@@ -0,0 +1,470 @@
+package server
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/tools"
+)
+
+const (
+	// defaultOrgClaim is the JWT claim holding the Edge Delta org ID
+	defaultOrgClaim = "org_id"
+	// jwksRefreshInterval is the minimum time between fetches of the signing keys of the issuer
+	jwksRefreshInterval = time.Minute
+	// clockSkew is the leeway allowed when checking the exp and nbf claims
+	clockSkew = time.Minute
+	// protectedResourcePath serves the OAuth protected resource metadata (RFC 9728)
+	protectedResourcePath = "/.well-known/oauth-protected-resource"
+	// mcpEndpointPath is the path the MCP endpoint is served on
+	mcpEndpointPath = "/mcp"
+)
+
+var errInvalidToken = errors.New("invalid token")
+
+// oauthConfig holds the identity provider the HTTP server trusts
+type oauthConfig struct {
+	issuerURL string
+	audience  string
+	orgClaim  string
+}
+
+// WithOAuth makes the HTTP server require OAuth 2.0 bearer tokens: JWTs issued by issuerURL for
+// audience, validated against the keys the issuer publishes through OpenID Connect discovery.
+// The audience is required, NewHTTPServer fails without it.
+// The org ID is read from the org_id claim, see WithOAuthOrgClaim, and the token is forwarded to
+// the Edge Delta API.
+func WithOAuth(issuerURL, audience string) ServerOption {
+	return func(c *serverConfig) {
+		orgClaim := defaultOrgClaim
+		if c.oauth != nil {
+			orgClaim = c.oauth.orgClaim
+		}
+		c.oauth = &oauthConfig{
+			issuerURL: strings.TrimSuffix(issuerURL, "/"),
+			audience:  audience,
+			orgClaim:  orgClaim,
+		}
+	}
+}
+
+// WithOAuthOrgClaim sets the JWT claim holding the Edge Delta org ID. Defaults to org_id.
+func WithOAuthOrgClaim(claim string) ServerOption {
+	return func(c *serverConfig) {
+		if c.oauth == nil {
+			c.oauth = &oauthConfig{}
+		}
+		c.oauth.orgClaim = claim
+	}
+}
+
+// jwtVerifier validates JWTs of an OpenID Connect issuer.
+type jwtVerifier struct {
+	config oauthConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	// fetching is closed when the fetch of the keys in progress, if any, completes
+	fetching chan struct{}
+}
+
+func newJWTVerifier(config oauthConfig) *jwtVerifier {
+	return &jwtVerifier{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Verify checks the signature, issuer, audience and validity period of the token and returns
+// its claims.
+func (v *jwtVerifier) Verify(ctx context.Context, token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed JWT", errInvalidToken)
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode header: %v", errInvalidToken, err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to decode signature: %v", errInvalidToken, err)
+	}
+
+	key, err := v.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidToken, err)
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode claims: %v", errInvalidToken, err)
+	}
+	if err := v.validateClaims(claims, time.Now()); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidToken, err)
+	}
+	return claims, nil
+}
+
+func (v *jwtVerifier) validateClaims(claims map[string]any, now time.Time) error {
+	if iss, _ := claims["iss"].(string); strings.TrimSuffix(iss, "/") != v.config.issuerURL {
+		return fmt.Errorf("unexpected issuer %q", iss)
+	}
+
+	// The audience is always checked, tokens the issuer minted for other clients are rejected
+	var audiences []any
+	switch aud := claims["aud"].(type) {
+	case string:
+		audiences = []any{aud}
+	case []any:
+		audiences = aud
+	}
+
+	found := false
+	for _, aud := range audiences {
+		if v.config.audience != "" && aud == v.config.audience {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("token is not issued for audience %q", v.config.audience)
+	}
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("token has no expiry")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(clockSkew)) {
+		return errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(clockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("token is not valid yet")
+	}
+	return nil
+}
+
+// key returns the signing key with the given ID, fetching the keys of the issuer when the key
+// is unknown, e.g. after a key rotation. The keys are fetched without holding v.mu, so a slow
+// issuer only delays the requests waiting for a new key, and concurrent requests share one fetch.
+func (v *jwtVerifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	for {
+		v.mu.Lock()
+		if key, ok := v.lookupKey(kid); ok {
+			v.mu.Unlock()
+			return key, nil
+		}
+		if fetching := v.fetching; fetching != nil {
+			v.mu.Unlock()
+			select {
+			case <-fetching:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		if time.Since(v.fetchedAt) < jwksRefreshInterval {
+			v.mu.Unlock()
+			return nil, fmt.Errorf("%w: unknown signing key %q", errInvalidToken, kid)
+		}
+		fetching := make(chan struct{})
+		v.fetching = fetching
+		v.mu.Unlock()
+
+		keys, err := v.fetchKeys(ctx)
+
+		v.mu.Lock()
+		if err == nil {
+			v.keys, v.fetchedAt = keys, time.Now()
+		}
+		v.fetching = nil
+		close(fetching)
+		key, ok := v.lookupKey(kid)
+		v.mu.Unlock()
+
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("%w: unknown signing key %q", errInvalidToken, kid)
+	}
+}
+
+// lookupKey returns the key with the ID, or the only key of the issuer for tokens without a key
+// ID. Callers must hold v.mu.
+func (v *jwtVerifier) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, true
+		}
+	}
+	key, ok := v.keys[kid]
+	return key, ok
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (v *jwtVerifier) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := v.getJSON(ctx, v.config.issuerURL+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("failed to discover OpenID configuration: %w", err)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != v.config.issuerURL {
+		return nil, fmt.Errorf("OpenID configuration of %s is for issuer %q", v.config.issuerURL, discovery.Issuer)
+	}
+	if discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("OpenID configuration of %s has no jwks_uri", v.config.issuerURL)
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := v.getJSON(ctx, discovery.JWKSURI, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// Keys of unsupported types are skipped, tokens signed with them are rejected
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+func (v *jwtVerifier) getJSON(ctx context.Context, url string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status code %d from %s", resp.StatusCode, url)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	if len(alg) != 5 {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+
+	var hash crypto.Hash
+	switch alg[2:] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	switch alg[:2] {
+	case "RS", "PS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("algorithm %s does not match the signing key", alg)
+		}
+		if alg[0] == 'P' {
+			return rsa.VerifyPSS(pub, hash, digest, signature, nil)
+		}
+		return rsa.VerifyPKCS1v15(pub, hash, digest, signature)
+	case "ES":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("algorithm %s does not match the signing key", alg)
+		}
+		// The signature is R and S, each padded to the byte size of the curve (RFC 7518 3.4)
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return fmt.Errorf("invalid signature length %d for %s", len(signature), alg)
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+}
+
+func decodeSegment(segment string, out any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// oauthHandler rejects requests without a valid bearer token and adds the token, the org ID and
+// the subject of its claims to the request context.
+func (c *serverConfig) oauthHandler(verifier *jwtVerifier, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			writeUnauthorized(w, r, "missing bearer token")
+			return
+		}
+
+		claims, err := verifier.Verify(r.Context(), token)
+		if err != nil {
+			if errors.Is(err, errInvalidToken) {
+				c.logger.Debug("Rejected OAuth token", "error", err)
+				writeUnauthorized(w, r, "invalid bearer token")
+			} else {
+				c.logger.Error("Failed to verify OAuth token", "error", err)
+				http.Error(w, "failed to verify token", http.StatusServiceUnavailable)
+			}
+			return
+		}
+
+		orgID, _ := claims[c.oauth.orgClaim].(string)
+		if orgID == "" {
+			orgID = c.defaultOrgID
+		}
+		if orgID == "" {
+			http.Error(w, fmt.Sprintf("token has no %s claim", c.oauth.orgClaim), http.StatusForbidden)
+			return
+		}
+
+		ctx := addToContext(r.Context(), tools.BearerTokenKey, token)
+		ctx = addToContext(ctx, tools.OrgIDKey, orgID)
+		// The subject identifies the caller across token refreshes, e.g. for budgets and audits
+		if subject, _ := claims["sub"].(string); subject != "" {
+			ctx = addToContext(ctx, tools.SubjectKey, subject)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// protectedResourceHandler serves the OAuth protected resource metadata, which tells MCP clients
+// where to get a token.
+func (c *serverConfig) protectedResourceHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		metadata := map[string]any{
+			"resource":                 resourceURL(r) + mcpEndpointPath,
+			"authorization_servers":    []string{c.oauth.issuerURL},
+			"bearer_methods_supported": []string{"header"},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(metadata)
+	})
+}
+
+func writeUnauthorized(w http.ResponseWriter, r *http.Request, message string) {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer resource_metadata=%q`, resourceURL(r)+protectedResourcePath))
+	http.Error(w, message, http.StatusUnauthorized)
+}
+
+// resourceURL returns the base URL the request was sent to.
+func resourceURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if forwarded := r.Header.Get("X-Forwarded-Proto"); forwarded != "" {
+		scheme = forwarded
+	}
+	return scheme + "://" + r.Host
+}
@@ -0,0 +1,324 @@
+package server
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testAudience = "edgedelta-mcp"
+
+// testIssuer is an OpenID Connect issuer serving the public keys of its signing keys.
+type testIssuer struct {
+	server *httptest.Server
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+
+	mu sync.Mutex
+	// kids are the key IDs the JWKS serves, by the key they identify
+	kids map[string]crypto.Signer
+	// block, when set, holds JWKS requests until it is closed
+	block   chan struct{}
+	fetches atomic.Int32
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := &testIssuer{rsaKey: rsaKey, ecKey: ecKey, kids: map[string]crypto.Signer{"rsa": rsaKey, "ec": ecKey}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{"issuer": issuer.server.URL, "jwks_uri": issuer.server.URL + "/jwks"})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		issuer.fetches.Add(1)
+		issuer.mu.Lock()
+		block := issuer.block
+		issuer.mu.Unlock()
+		if block != nil {
+			select {
+			case <-block:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": issuer.jwks()})
+	})
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+func (i *testIssuer) jwks() []map[string]string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	encode := func(n *big.Int) string { return base64.RawURLEncoding.EncodeToString(n.Bytes()) }
+	var keys []map[string]string
+	for kid, key := range i.kids {
+		switch key := key.(type) {
+		case *rsa.PrivateKey:
+			keys = append(keys, map[string]string{"kty": "RSA", "kid": kid, "use": "sig", "n": encode(key.N), "e": encode(big.NewInt(int64(key.E)))})
+		case *ecdsa.PrivateKey:
+			keys = append(keys, map[string]string{"kty": "EC", "kid": kid, "crv": "P-256", "x": encode(key.X), "y": encode(key.Y)})
+		}
+	}
+	return keys
+}
+
+func (i *testIssuer) addKey(kid string, key crypto.Signer) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.kids[kid] = key
+}
+
+func (i *testIssuer) claims() map[string]any {
+	now := time.Now()
+	return map[string]any{
+		"iss":    i.server.URL,
+		"aud":    testAudience,
+		"sub":    "user-1",
+		"exp":    now.Add(time.Hour).Unix(),
+		"iat":    now.Unix(),
+		"org_id": "org-1",
+	}
+}
+
+func encodeSegment(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// signToken signs the claims with key, with the raw ECDSA signature of JWS for EC keys.
+func signToken(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]any) string {
+	t.Helper()
+
+	signed := encodeSegment(t, map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func withClaim(claims map[string]any, key string, value any) map[string]any {
+	if value == nil {
+		delete(claims, key)
+	} else {
+		claims[key] = value
+	}
+	return claims
+}
+
+func TestJWTVerifier(t *testing.T) {
+	issuer := newTestIssuer(t)
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		token   func() string
+		wantErr bool
+	}{
+		{"valid RS256", func() string { return signToken(t, "RS256", "rsa", issuer.rsaKey, issuer.claims()) }, false},
+		{"valid ES256", func() string { return signToken(t, "ES256", "ec", issuer.ecKey, issuer.claims()) }, false},
+		{"audience in a list", func() string {
+			return signToken(t, "RS256", "rsa", issuer.rsaKey, withClaim(issuer.claims(), "aud", []any{"other", testAudience}))
+		}, false},
+		{"bad signature", func() string {
+			token := signToken(t, "RS256", "rsa", issuer.rsaKey, issuer.claims())
+			other := signToken(t, "RS256", "rsa", issuer.rsaKey, withClaim(issuer.claims(), "org_id", "org-2"))
+			return token[:len(token)-10] + other[len(other)-10:]
+		}, true},
+		{"alg none", func() string {
+			return encodeSegment(t, map[string]string{"alg": "none", "kid": "rsa"}) + "." + encodeSegment(t, issuer.claims()) + "."
+		}, true},
+		{"HS256 with the RSA public key", func() string {
+			signed := encodeSegment(t, map[string]string{"alg": "HS256", "kid": "rsa"}) + "." + encodeSegment(t, issuer.claims())
+			mac := hmac.New(sha256.New, issuer.rsaKey.N.Bytes())
+			mac.Write([]byte(signed))
+			return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+		}, true},
+		{"RS256 with the EC key", func() string { return signToken(t, "RS256", "ec", issuer.ecKey, issuer.claims()) }, true},
+		{"ES256 signature of the wrong length", func() string {
+			token := signToken(t, "ES256", "ec", issuer.ecKey, issuer.claims())
+			// R and S padded with a zero byte each keep their values, not the length of the signature
+			signature, _ := base64.RawURLEncoding.DecodeString(token[len(token)-86:])
+			padded := append(append([]byte{0}, signature[:32]...), append([]byte{0}, signature[32:]...)...)
+			return token[:len(token)-86] + base64.RawURLEncoding.EncodeToString(padded)
+		}, true},
+		{"expired", func() string {
+			return signToken(t, "RS256", "rsa", issuer.rsaKey, withClaim(issuer.claims(), "exp", now.Add(-time.Hour).Unix()))
+		}, true},
+		{"no expiry", func() string {
+			return signToken(t, "RS256", "rsa", issuer.rsaKey, withClaim(issuer.claims(), "exp", nil))
+		}, true},
+		{"not valid yet", func() string {
+			return signToken(t, "RS256", "rsa", issuer.rsaKey, withClaim(issuer.claims(), "nbf", now.Add(time.Hour).Unix()))
+		}, true},
+		{"wrong issuer", func() string {
+			return signToken(t, "RS256", "rsa", issuer.rsaKey, withClaim(issuer.claims(), "iss", "https://other.example.com"))
+		}, true},
+		{"wrong audience", func() string {
+			return signToken(t, "RS256", "rsa", issuer.rsaKey, withClaim(issuer.claims(), "aud", "other-client"))
+		}, true},
+		{"no audience", func() string {
+			return signToken(t, "RS256", "rsa", issuer.rsaKey, withClaim(issuer.claims(), "aud", nil))
+		}, true},
+		{"malformed", func() string { return "not-a-jwt" }, true},
+	}
+
+	verifier := newJWTVerifier(oauthConfig{issuerURL: issuer.server.URL, audience: testAudience, orgClaim: defaultOrgClaim})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifier.Verify(context.Background(), tt.token())
+			if tt.wantErr {
+				if !errors.Is(err, errInvalidToken) {
+					t.Fatalf("Verify returned %v, want an invalid token error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify returned %v", err)
+			}
+			if claims["sub"] != "user-1" {
+				t.Errorf("claims are %v, want the claims of the token", claims)
+			}
+		})
+	}
+}
+
+func TestJWTVerifierRefetchesUnknownKeys(t *testing.T) {
+	issuer := newTestIssuer(t)
+	verifier := newJWTVerifier(oauthConfig{issuerURL: issuer.server.URL, audience: testAudience, orgClaim: defaultOrgClaim})
+	ctx := context.Background()
+
+	if _, err := verifier.Verify(ctx, signToken(t, "RS256", "rsa", issuer.rsaKey, issuer.claims())); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer.addKey("rotated", rotated)
+	token := signToken(t, "RS256", "rotated", rotated, issuer.claims())
+
+	// Unknown keys are not fetched again within the refresh interval
+	if _, err := verifier.Verify(ctx, token); !errors.Is(err, errInvalidToken) {
+		t.Fatalf("Verify returned %v, want an unknown key error", err)
+	}
+	if got := issuer.fetches.Load(); got != 1 {
+		t.Fatalf("JWKS fetched %d times, want 1", got)
+	}
+
+	verifier.mu.Lock()
+	verifier.fetchedAt = time.Now().Add(-jwksRefreshInterval)
+	verifier.mu.Unlock()
+	if _, err := verifier.Verify(ctx, token); err != nil {
+		t.Fatalf("Verify returned %v after the refresh interval, want the rotated key to be fetched", err)
+	}
+	if got := issuer.fetches.Load(); got != 2 {
+		t.Errorf("JWKS fetched %d times, want 2", got)
+	}
+}
+
+func TestJWTVerifierFetchDoesNotBlockKnownKeys(t *testing.T) {
+	issuer := newTestIssuer(t)
+	verifier := newJWTVerifier(oauthConfig{issuerURL: issuer.server.URL, audience: testAudience, orgClaim: defaultOrgClaim})
+	ctx := context.Background()
+
+	known := signToken(t, "RS256", "rsa", issuer.rsaKey, issuer.claims())
+	if _, err := verifier.Verify(ctx, known); err != nil {
+		t.Fatal(err)
+	}
+
+	block := make(chan struct{})
+	issuer.mu.Lock()
+	issuer.block = block
+	issuer.mu.Unlock()
+	verifier.mu.Lock()
+	verifier.fetchedAt = time.Now().Add(-jwksRefreshInterval)
+	verifier.mu.Unlock()
+
+	unknown := signToken(t, "RS256", "unknown", issuer.rsaKey, issuer.claims())
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = verifier.Verify(ctx, unknown)
+		}()
+	}
+	for issuer.fetches.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := verifier.Verify(ctx, known)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Verify returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Verify of a known key waited for the fetch of the keys")
+	}
+
+	close(block)
+	wg.Wait()
+	// The concurrent requests for the unknown key share one fetch
+	if got := issuer.fetches.Load(); got != 2 {
+		t.Errorf("JWKS fetched %d times, want 2", got)
+	}
+}
+
+func TestNewHTTPServerRequiresOAuthAudience(t *testing.T) {
+	if _, err := NewHTTPServer(WithOAuth("https://issuer.example.com", "")); err == nil {
+		t.Error("NewHTTPServer accepted OAuth without an audience")
+	}
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/tools"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// RuntimeConfig holds the settings that can change while the server runs, see
+// WithConfigReloads. Nil fields keep their current value.
+type RuntimeConfig struct {
+	SchemaCacheTTL *time.Duration
+	RateLimits     *tools.RateLimits
+	// DisabledTools are removed from the server, and added back once they are no longer listed.
+	// An empty list enables every tool again.
+	DisabledTools []string
+}
+
+// WithConfigReloads applies the runtime configs received on reloads while the server runs, e.g.
+// when its config file changes, so long-lived deployments do not need a restart. Configs received
+// before the server starts are applied before it serves. Clients are notified that the tool list
+// changed when tools are disabled or enabled again.
+func WithConfigReloads(reloads <-chan RuntimeConfig) ServerOption {
+	return func(c *serverConfig) {
+		c.reloads = reloads
+	}
+}
+
+// watchReloads applies the pending runtime configs, then the next ones until ctx is done.
+func (c *serverConfig) watchReloads(ctx context.Context) {
+	for pending := true; pending; {
+		select {
+		case rc := <-c.reloads:
+			c.applyRuntimeConfig(rc)
+		default:
+			pending = false
+		}
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case rc := <-c.reloads:
+				c.applyRuntimeConfig(rc)
+			}
+		}
+	}()
+}
+
+func (c *serverConfig) applyRuntimeConfig(rc RuntimeConfig) {
+	if rc.SchemaCacheTTL != nil {
+		if c.schemaCache != nil {
+			c.schemaCache.SetTTL(*rc.SchemaCacheTTL)
+			c.logger.Info("Schema cache TTL changed", "ttl", *rc.SchemaCacheTTL)
+		} else if *rc.SchemaCacheTTL > 0 {
+			c.logger.Warn("Schema cache was disabled on startup, restart the server to enable it")
+		}
+	}
+
+	if rc.RateLimits != nil {
+		for _, client := range c.httpClients {
+			client.SetRateLimits(*rc.RateLimits)
+		}
+		c.logger.Info("Rate limits changed", "rps", rc.RateLimits.Default.RPS, "burst", rc.RateLimits.Default.Burst)
+	}
+
+	if rc.DisabledTools != nil {
+		c.setDisabledTools(rc.DisabledTools)
+	}
+}
+
+// setDisabledTools removes the listed tools from the server and adds back the tools it removed
+// before that are no longer listed.
+func (c *serverConfig) setDisabledTools(names []string) {
+	disabled := make(map[string]bool, len(names))
+	for _, name := range names {
+		disabled[name] = true
+	}
+
+	var removed []string
+	for name := range disabled {
+		if _, ok := c.disabledTools[name]; ok {
+			continue
+		}
+		tool := c.mcpServer.GetTool(name)
+		if tool == nil {
+			c.logger.Warn("Unknown tool in disabled tools", "tool", name)
+			continue
+		}
+		c.disabledTools[name] = *tool
+		removed = append(removed, name)
+	}
+
+	var restored []server.ServerTool
+	var restoredNames []string
+	for name, tool := range c.disabledTools {
+		if !disabled[name] {
+			restored = append(restored, tool)
+			restoredNames = append(restoredNames, name)
+			delete(c.disabledTools, name)
+		}
+	}
+
+	// Both notify the clients that the tool list changed
+	if len(removed) > 0 {
+		sort.Strings(removed)
+		c.mcpServer.DeleteTools(removed...)
+		c.logger.Info("Tools disabled", "tools", removed)
+	}
+	if len(restored) > 0 {
+		sort.Strings(restoredNames)
+		c.mcpServer.AddTools(restored...)
+		c.logger.Info("Tools enabled", "tools", restoredNames)
+	}
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// sdkClients are the commands running the scripts of test/sdk with the official MCP client SDKs,
+// by the names ED_MCP_SDK_CLIENTS selects them with. The scripts connect to the URL they are given
+// with one header, then initialize, list the tools, call get_log_search for 1000 logs of the last
+// 24 hours and print an sdkReport.
+var sdkClients = map[string][]string{
+	"typescript": {"node", "../test/sdk/typescript/client.mjs"},
+	"python":     {"python3", "../test/sdk/python/client.py"},
+}
+
+// sdkReport is what the SDK client scripts print.
+type sdkReport struct {
+	ServerName string   `json:"server_name"`
+	Tools      []string `json:"tools"`
+	IsError    bool     `json:"is_error"`
+	Content    []string `json:"content"`
+}
+
+func runSDKClient(t *testing.T, command []string, endpoint, header, value string) sdkReport {
+	t.Helper()
+
+	cmd := exec.Command(command[0], append(command[1:], endpoint, header, value)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("%s: %v\n%s", strings.Join(command, " "), err, stderr.String())
+	}
+
+	var report sdkReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("client printed %q, want a report: %v", stdout.String(), err)
+	}
+	if report.ServerName == "" {
+		t.Error("initialize returned no server info")
+	}
+	for _, name := range []string{"get_log_search", "get_log_graph", "facet_options", "deploy_pipeline"} {
+		if !slices.Contains(report.Tools, name) {
+			t.Errorf("tools/list is missing %s", name)
+		}
+	}
+	if report.IsError || len(report.Content) == 0 {
+		t.Fatalf("get_log_search failed: %v", report.Content)
+	}
+	return report
+}
+
+// TestSDKClients drives the HTTP server with the official MCP client SDKs listed in
+// ED_MCP_SDK_CLIENTS, comma separated, which CI runs with the SDKs installed.
+func TestSDKClients(t *testing.T) {
+	selected := os.Getenv("ED_MCP_SDK_CLIENTS")
+	if selected == "" {
+		t.Skip("set ED_MCP_SDK_CLIENTS to run the SDK clients")
+	}
+
+	for _, name := range strings.Split(selected, ",") {
+		command, ok := sdkClients[strings.TrimSpace(name)]
+		if !ok {
+			t.Fatalf("unknown SDK client %q", name)
+		}
+
+		t.Run(name, func(t *testing.T) {
+			t.Run("api token", func(t *testing.T) {
+				endpoint, api := serveHTTP(t)
+
+				report := runSDKClient(t, command, endpoint, "X-ED-API-Token", "header-token")
+				var response struct {
+					Data struct {
+						Items []json.RawMessage `json:"items"`
+					} `json:"data"`
+				}
+				if err := json.Unmarshal([]byte(report.Content[0]), &response); err != nil {
+					t.Fatalf("result is not valid JSON: %v", err)
+				}
+				if len(response.Data.Items) != 1000 {
+					t.Errorf("result has %d items, want 1000", len(response.Data.Items))
+				}
+				if got := api.lastHeader("X-ED-API-Token"); got != "header-token" {
+					t.Errorf("API request has X-ED-API-Token %q, want the header of the client", got)
+				}
+			})
+
+			t.Run("bearer token and truncation", func(t *testing.T) {
+				const maxBytes = 16 << 10
+				endpoint, api := serveHTTP(t, WithMaxResultBytes(maxBytes))
+
+				report := runSDKClient(t, command, endpoint, "Authorization", "Bearer oauth-token")
+				if text := strings.Join(report.Content, "\n"); len(text) > maxBytes {
+					t.Errorf("result is %d bytes, want at most %d", len(text), maxBytes)
+				}
+				if footer := report.Content[len(report.Content)-1]; !strings.Contains(footer, `"truncation"`) {
+					t.Errorf("last content is %q, want the truncation footer", footer)
+				}
+				if got := api.lastHeader("Authorization"); got != "Bearer oauth-token" {
+					t.Errorf("API request has Authorization %q, want the header of the client", got)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/tools"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	SelfTestPassed  = "pass"
+	SelfTestFailed  = "fail"
+	SelfTestSkipped = "skip"
+
+	// selfTestTimeout bounds every tool call of the self test.
+	selfTestTimeout = 30 * time.Second
+	// selfTestLookback keeps the queries of the self test cheap.
+	selfTestLookback = "15m"
+)
+
+// selfTestArguments are the arguments of the read-only tools whose required arguments have no
+// safe default. The services need not exist, the calls only have to succeed. The tools requiring
+// the ID of an object, e.g. a trace or a dashboard, are skipped.
+var selfTestArguments = map[string]map[string]any{
+	"validate_cql":       {"query": "*"},
+	"build_cql":          {"filters": map[string]any{}},
+	"facet_options":      {"facet_path": "service.name"},
+	"top_values":         {"facet": "service.name"},
+	"search_metrics":     {"pattern": "cpu"},
+	"resolve_k8s_entity": {"pattern": "selftest"},
+	"simulate_monitor":   {"query": "*", "threshold": float64(1)},
+	"tail_logs":          {"duration": "2s", "poll_interval": "1s"},
+	"get_ingest_lag":     {"service": "selftest"},
+	"compare_services":   {"service_a": "selftest-a", "service_b": "selftest-b"},
+	"investigate_window": {"service": "selftest"},
+}
+
+// SelfTestResult is the outcome of the self test of a tool.
+type SelfTestResult struct {
+	Tool       string `json:"tool"`
+	Status     string `json:"status"`
+	DurationMs int64  `json:"duration_ms"`
+	// Error is the error of a failed tool, or why a tool was skipped
+	Error string `json:"error,omitempty"`
+}
+
+// SelfTest calls every read-only tool of the server with minimal safe arguments against the org,
+// to verify the token scopes, the org access and the API compatibility of a deployment. The tools
+// are called one at a time through the same middleware as the tool calls of a server, in the order
+// of their names.
+func SelfTest(ctx context.Context, orgID, apiToken string, opts ...ServerOption) ([]SelfTestResult, error) {
+	config := defaultServerConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if orgID == "" {
+		return nil, fmt.Errorf("ED_ORG_ID not set")
+	}
+	if apiToken == "" {
+		return nil, fmt.Errorf("ED_API_TOKEN not set")
+	}
+
+	s := config.newMCPServer(config.newClient())
+	ctx = context.WithValue(ctx, tools.OrgIDKey, orgID)
+	ctx = context.WithValue(ctx, tools.EDTokenKey, apiToken)
+	if config.locale != "" {
+		ctx = context.WithValue(ctx, tools.LocaleKey, config.locale)
+	}
+
+	serverTools := s.ListTools()
+	names := make([]string, 0, len(serverTools))
+	for name := range serverTools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]SelfTestResult, 0, len(names))
+	for _, name := range names {
+		t := serverTools[name]
+		result := SelfTestResult{Tool: name, Status: SelfTestSkipped}
+		args, reason := selfTestRequestArguments(t.Tool)
+		if reason != "" {
+			result.Error = reason
+			results = append(results, result)
+			continue
+		}
+
+		request := mcp.CallToolRequest{}
+		request.Params.Name = name
+		request.Params.Arguments = args
+
+		callCtx, cancel := context.WithTimeout(ctx, selfTestTimeout)
+		start := time.Now()
+		res, err := t.Handler(callCtx, request)
+		duration := time.Since(start)
+		result.DurationMs = duration.Milliseconds()
+		cancel()
+
+		result.Status = SelfTestPassed
+		switch {
+		case err != nil:
+			result.Status, result.Error = SelfTestFailed, err.Error()
+		case res == nil:
+			result.Status, result.Error = SelfTestFailed, "no result"
+		case res.IsError:
+			result.Status, result.Error = SelfTestFailed, toolResultText(res)
+		}
+		config.logger.Debug("Self test", "tool", name, "status", result.Status, "duration", duration, "error", result.Error)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// selfTestRequestArguments returns the arguments of the self test of the tool, or why it is
+// skipped.
+func selfTestRequestArguments(tool mcp.Tool) (map[string]any, string) {
+	if readOnly := tool.Annotations.ReadOnlyHint; readOnly == nil || !*readOnly {
+		return nil, "not read-only"
+	}
+
+	args := make(map[string]any)
+	if selfTestPropertyType(tool, "lookback") == "string" {
+		args["lookback"] = selfTestLookback
+	}
+	if selfTestPropertyType(tool, "limit") == "number" {
+		args["limit"] = float64(1)
+	}
+	for key, value := range selfTestArguments[tool.Name] {
+		args[key] = value
+	}
+
+	var missing []string
+	for _, key := range tool.InputSchema.Required {
+		if _, ok := args[key]; ok {
+			continue
+		}
+		if property, ok := tool.InputSchema.Properties[key].(map[string]any); ok {
+			if value, ok := property["default"]; ok {
+				args[key] = value
+				continue
+			}
+			if values, ok := property["enum"].([]string); ok && len(values) > 0 {
+				args[key] = values[0]
+				continue
+			}
+		}
+		missing = append(missing, key)
+	}
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		return nil, "requires " + strings.Join(missing, ", ")
+	}
+	return args, ""
+}
+
+func selfTestPropertyType(tool mcp.Tool, key string) string {
+	property, _ := tool.InputSchema.Properties[key].(map[string]any)
+	t, _ := property["type"].(string)
+	return t
+}
+
+func toolResultText(res *mcp.CallToolResult) string {
+	var texts []string
+	for _, content := range res.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			texts = append(texts, text.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
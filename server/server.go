@@ -4,19 +4,35 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"time"
 
+	"github.com/edgedelta/edgedelta-mcp-server/pkg/storage"
 	"github.com/edgedelta/edgedelta-mcp-server/pkg/tools"
 
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
+const (
+	// featureFlagsTTL is how long the enabled features of an org are cached
+	featureFlagsTTL = 10 * time.Minute
+	// serviceAliasesTTL is how long the service aliases of an org are cached
+	serviceAliasesTTL = 10 * time.Minute
+	// childOrgsTTL is how long the child orgs of a parent org are cached
+	childOrgsTTL = 10 * time.Minute
+	// warmCacheTTL is how long the responses prefetched by the warmup are cached
+	warmCacheTTL = 5 * time.Minute
+)
+
 var (
 	defaultServerConfig = serverConfig{
 		apiURL:         "https://api.edgedelta.com",
 		serverName:     "edgedelta-mcp-server",
-		serverVersion:  "0.0.1",
+		serverVersion:  BuildInfo().Version,
 		apiTokenHeader: "X-ED-API-Token",
 		logger:         slog.Default(),
+		requestTimeout: tools.DefaultRequestTimeout,
+		schemaCacheTTL: tools.DefaultSchemaCacheTTL,
 		// HTTP server options
 		port:             8080,
 		stateless:        true,
@@ -46,43 +62,159 @@ func CreateServer(serverType ServerType, orgID, apiToken string, opts ...ServerO
 	}
 }
 
-func AddCustomTools(s *server.MCPServer, client tools.Client) {
+// toolRegistry registers tools on the server with their handlers wrapped by the middleware.
+type toolRegistry struct {
+	s             *server.MCPServer
+	middleware    tools.ToolMiddleware
+	canonicalizer *tools.ArgumentCanonicalizer
+	orgOverride   *tools.OrgOverride
+	regions       *tools.RegionOverride
+	// docs compacts the descriptions of the tools when set
+	docs *tools.ToolDocs
+	// paginated collects the names of the tools taking a cursor, for the pagination hints
+	paginated map[string]bool
+	logger    *slog.Logger
+}
+
+func (r toolRegistry) AddTool(tool mcp.Tool, handler server.ToolHandlerFunc) {
+	if r.orgOverride != nil {
+		tool = r.orgOverride.Tool(tool)
+	}
+	if r.regions != nil {
+		tool = r.regions.Tool(tool)
+	}
+
+	tool, adjustments := tools.NormalizeSchema(tool)
+	for _, adjustment := range adjustments {
+		r.log().Info("Adjusted tool schema", "tool", tool.Name, "adjustment", adjustment)
+	}
+
+	classification, ok := tools.ClassifyTool(tool)
+	if !ok {
+		r.log().Warn("Tool has no classification, classified as admin with a high PII risk", "tool", tool.Name)
+	}
+	tool = tools.WithClassification(tool, classification)
+
+	if r.canonicalizer != nil {
+		r.canonicalizer.Register(tool)
+	}
+	if r.docs != nil {
+		tool = r.docs.Compact(tool)
+	}
+	if _, ok := tool.InputSchema.Properties["cursor"]; ok && r.paginated != nil {
+		r.paginated[tool.Name] = true
+	}
+	r.s.AddTool(tool, r.middleware(handler))
+}
+
+func (r toolRegistry) log() *slog.Logger {
+	if r.logger == nil {
+		return slog.Default()
+	}
+	return r.logger
+}
+
+// AddCustomTools registers the Edge Delta tools, wrapping every handler with the middlewares.
+// The first middleware is the outermost.
+func AddCustomTools(s *server.MCPServer, client tools.Client, middlewares ...tools.ToolMiddleware) {
+	addCustomTools(toolRegistry{s: s, middleware: tools.Chain(middlewares...)}, client)
+}
+
+func addCustomTools(r toolRegistry, client tools.Client) {
 	// Discovery and query building tools
-	s.AddTool(tools.GetDiscoverSchemaTool(client))
-	s.AddTool(tools.GetSearchMetricsTool(client))
-	s.AddTool(tools.GetValidateCQLTool())
-	s.AddTool(tools.GetBuildCQLTool(client))
+	r.AddTool(tools.GetDiscoverSchemaTool(client))
+	r.AddTool(tools.GetSearchMetricsTool(client))
+	r.AddTool(tools.GetValidateCQLTool(client))
+	r.AddTool(tools.GetBuildCQLTool(client))
+	r.AddTool(tools.GetResolveK8sEntityTool(client))
 
 	// Pipeline management tools
-	s.AddTool(tools.GetPipelinesTool(client))
-	s.AddTool(tools.GetPipelineConfigTool(client))
-	s.AddTool(tools.GetPipelineHistoryTool(client))
-	s.AddTool(tools.DeployPipelineTool(client))
-	s.AddTool(tools.AddPipelineSourceTool(client))
+	r.AddTool(tools.GetPipelinesTool(client))
+	r.AddTool(tools.GetPipelineConfigTool(client))
+	r.AddTool(tools.GetPipelineHistoryTool(client))
+	r.AddTool(tools.DeployPipelineTool(client))
+	r.AddTool(tools.AddPipelineSourceTool(client))
+	r.AddTool(tools.RemovePipelineNodeTool(client))
+	r.AddTool(tools.UpdatePipelineNodeTool(client))
+	r.AddTool(tools.ConnectPipelineNodesTool(client))
+	r.AddTool(tools.SavePipelineTool(client))
+	r.AddTool(tools.ValidatePipelineTool(client))
+	r.AddTool(tools.AnalyzePipelineComplexityTool(client))
+	r.AddTool(tools.SendTestLogsTool(client))
+	r.AddTool(tools.GetFleetAgentsTool(client))
+	r.AddTool(tools.GetAgentStatusTool(client))
 
 	// Ingestion tools
-	s.AddTool(tools.GetIngestionEndpointTool(client))
+	r.AddTool(tools.GetIngestionEndpointTool(client))
 
 	// Facet tools
-	s.AddTool(tools.FacetsTool, tools.FacetsToolHandler(client))
-	s.AddTool(tools.FacetOptionsTool, tools.FacetOptionsToolHandler(client))
+	r.AddTool(tools.FacetsTool, tools.FacetsToolHandler(client))
+	r.AddTool(tools.FacetOptionsTool, tools.FacetOptionsToolHandler(client))
+	r.AddTool(tools.TopValuesTool(client))
+	r.AddTool(tools.CreateFacetTool, tools.CreateFacetToolHandler(client))
+	r.AddTool(tools.DeleteFacetTool, tools.DeleteFacetToolHandler(client))
 
 	// Search tools
-	s.AddTool(tools.GetLogSearchTool(client))
-	s.AddTool(tools.GetTraceTimelineTool(client))
-	s.AddTool(tools.GetMetricSearchTool(client))
-	s.AddTool(tools.GetEventSearchTool(client))
-	s.AddTool(tools.GetLogPatternsTool(client))
+	r.AddTool(tools.GetLogSearchTool(client))
+	r.AddTool(tools.TailLogsTool(client))
+	r.AddTool(tools.RefreshSearchTool(client))
+	r.AddTool(tools.GetTraceTimelineTool(client))
+	r.AddTool(tools.GetTraceErrorChainTool(client))
+	r.AddTool(tools.GetTraceByIDTool(client))
+	r.AddTool(tools.GetServiceMapTool(client))
+	r.AddTool(tools.GetSpanEventsTool(client))
+	r.AddTool(tools.GetMetricSearchTool(client))
+	r.AddTool(tools.GetEventSearchTool(client))
+	r.AddTool(tools.GetLogPatternsTool(client))
+	r.AddTool(tools.GetSentimentTrendTool(client))
+	r.AddTool(tools.CompareServicesTool(client))
+	r.AddTool(tools.CompareWindowsTool(client))
+	r.AddTool(tools.GetAgentSelfLogsTool(client))
+	r.AddTool(tools.GetAnomalySearchTool(client))
+	r.AddTool(tools.DetectAnomaliesTool(client))
+	r.AddTool(tools.InvestigateWindowTool(client))
 
 	// Dashboard tools
-	s.AddTool(tools.GetAllDashboardsTool(client))
-	s.AddTool(tools.GetDashboardTool(client))
+	r.AddTool(tools.GetAllDashboardsTool(client))
+	r.AddTool(tools.GetDashboardTool(client))
+	r.AddTool(tools.RenderDashboardTool(client))
+	r.AddTool(tools.CreateDashboardTool(client))
+	r.AddTool(tools.UpdateDashboardWidgetTool(client))
 
 	// Graph/visualization tools
-	s.AddTool(tools.GetLogGraphTool(client))
-	s.AddTool(tools.GetMetricGraphTool(client))
-	s.AddTool(tools.GetTraceGraphTool(client))
-	s.AddTool(tools.GetPatternGraphTool(client))
+	r.AddTool(tools.GetLogGraphTool(client))
+	r.AddTool(tools.GetMetricGraphTool(client))
+	r.AddTool(tools.GetTraceGraphTool(client))
+	r.AddTool(tools.GetPatternGraphTool(client))
+	r.AddTool(tools.GetGraphMultiTool(client))
+	r.AddTool(tools.GetLogHistogramTool(client))
+
+	// Monitor tools
+	r.AddTool(tools.ListMonitorsTool(client))
+	r.AddTool(tools.GetMonitorTool(client))
+	r.AddTool(tools.CreateMonitorTool(client))
+	r.AddTool(tools.CreateMonitorFromPatternTool(client))
+	r.AddTool(tools.UpdateMonitorTool(client))
+	r.AddTool(tools.MuteMonitorTool(client))
+	r.AddTool(tools.UnmuteMonitorTool(client))
+	r.AddTool(tools.GetSimulateMonitorTool(client))
+
+	// Notification tools
+	r.AddTool(tools.ListNotificationIntegrationsTool(client))
+	r.AddTool(tools.ListNotificationRoutesTool(client))
+
+	// Data health tools
+	r.AddTool(tools.GetIngestLagTool(client))
+	r.AddTool(tools.ListArchiveObjectsTool(client))
+
+	// Multi-org tools
+	r.AddTool(tools.ListOrgsTool(client))
+	r.AddTool(tools.ListChildOrgsTool(client))
+	r.AddTool(tools.GetFleetOverviewTool(client))
+
+	// Access audit tools
+	r.AddTool(tools.ListOrgMembersTool(client))
+	r.AddTool(tools.ListAPIKeysTool(client))
 }
 
 func AddCustomResources(s *server.MCPServer, client tools.Client) {
@@ -99,6 +231,75 @@ func AddCustomResources(s *server.MCPServer, client tools.Client) {
 	s.AddResource(tools.EventFacetKeysResource, tools.EventFacetKeysResourceHandler(client))
 }
 
+// newMCPServer creates the MCP server with the Edge Delta tools and resources
+func (c *serverConfig) newMCPServer(client tools.Client) *server.MCPServer {
+	// Tools are registered below, before any call can use the canonicalizer
+	if c.canonicalizer == nil {
+		c.canonicalizer = tools.NewArgumentCanonicalizer()
+	}
+	if c.storage != nil && c.queryHistory == nil {
+		c.queryHistory = tools.NewStoredQueryHistory(c.storage)
+	}
+	if c.pseudonymize {
+		c.pseudonyms = tools.NewPseudonymizer(c.pseudonymKey, c.stateStorage())
+	}
+	c.aliases = tools.NewServiceAliasResolver(client, c.serviceAliases, serviceAliasesTTL)
+	c.orgOverride = tools.NewOrgOverride(client, c.stateStorage(), childOrgsTTL, c.multiOrg)
+	c.sessions = tools.NewSessionRecorder()
+	c.paginated = make(map[string]bool)
+	if len(c.regions) > 0 {
+		c.regionOverride = tools.NewRegionOverride(c.regions)
+	}
+	if c.apiSpecURL != "" || c.apiSpecFile != "" {
+		c.apiDrift = tools.NewAPIDriftChecker(client, c.apiSpecURL, c.apiSpecFile)
+	}
+
+	var opts []server.ServerOption
+	if c.featureFlags {
+		c.features = tools.NewFeatureFlags(client, c.stateStorage(), featureFlagsTTL)
+		opts = append(opts, server.WithToolFilter(c.features.ToolFilter()))
+	}
+
+	s := server.NewMCPServer(c.serverName, c.serverVersion, opts...)
+	c.mcpServer = s
+	c.disabledTools = make(map[string]server.ServerTool)
+
+	registry := toolRegistry{
+		s:             s,
+		middleware:    tools.Chain(c.middlewares()...),
+		canonicalizer: c.canonicalizer,
+		orgOverride:   c.orgOverride,
+		regions:       c.regionOverride,
+		paginated:     c.paginated,
+		logger:        c.logger,
+	}
+	if c.compactDescriptions {
+		registry.docs = tools.NewToolDocs()
+	}
+	addCustomTools(registry, client)
+	// Session snapshot tools
+	registry.AddTool(tools.ExportSessionSnapshotTool(c.sessions))
+	registry.AddTool(tools.ImportSessionSnapshotTool(c.sessions))
+	if c.pseudonyms != nil {
+		registry.AddTool(tools.ResolvePseudonymTool(c.pseudonyms))
+	}
+	if c.ticketWebhook != nil {
+		registry.AddTool(tools.CreateIncidentTicketTool(c.ticketWebhook, c.sessions))
+	}
+	AddCustomResources(s, client)
+	if registry.docs != nil {
+		s.AddResourceTemplate(tools.ToolDocsResource, registry.docs.ResourceHandler())
+	}
+	s.AddResource(tools.ServiceAliasesResource, tools.ServiceAliasesResourceHandler(c.aliases))
+	s.AddResource(ServerInfoResource, c.serverInfoHandler())
+
+	if c.queryHistory != nil {
+		s.AddResource(tools.RecentQueriesResource, tools.RecentQueriesResourceHandler(c.queryHistory))
+	}
+
+	return s
+}
+
 // serverConfig holds internal configuration
 type serverConfig struct {
 	apiURL         string
@@ -106,11 +307,66 @@ type serverConfig struct {
 	serverVersion  string
 	apiTokenHeader string
 	logger         *slog.Logger
+	// client overrides the Edge Delta API client, e.g. with the demo client
+	client          tools.Client
+	lookbackLimits  tools.LookbackLimits
+	toolMiddlewares []tools.ToolMiddleware
+	queryHistory    *tools.QueryHistory
+	// storage keeps the state shared between tool calls, see WithStorage
+	storage       storage.Interface
+	preToolHooks  map[string][]tools.PreToolHook
+	postToolHooks map[string][]tools.PostToolHook
+	featureFlags  bool
+	// compactDescriptions trims the tool descriptions to one line, see tools.ToolDocs
+	compactDescriptions bool
+	features            *tools.FeatureFlags
+	serviceAliases      tools.ServiceAliases
+	maxResultBytes      int
+	toolBudgets         map[string]tools.ToolBudget
+	aliases             *tools.ServiceAliasResolver
+	orgOverride         *tools.OrgOverride
+	regions             map[string]string
+	regionOverride      *tools.RegionOverride
+	paginated           map[string]bool
+	locale              string
+	oauth               *oauthConfig
+	multiOrg            bool
+	warmup              *warmupConfig
+	warmCache           *tools.WarmCache
+	resultSigner        *tools.ResultSigner
+	pseudonymize        bool
+	pseudonymKey        []byte
+	pseudonyms          *tools.Pseudonymizer
+	ticketWebhook       *tools.TicketWebhook
+	metrics             *tools.Metrics
+	tracer              *tools.Tracer
+	auditLoggers        []tools.AuditLogger
+	redactor            *tools.Redactor
+	retryPolicy         *tools.RetryPolicy
+	rateLimits          *tools.RateLimits
+	httpOptions         []tools.HTTPClientOption
+	apiSpecURL          string
+	apiSpecFile         string
+	apiDrift            *tools.APIDriftChecker
+	apiSpecInterval     time.Duration
+	sessions            *tools.SessionRecorder
+	requestTimeout      time.Duration
+	toolTimeouts        map[string]time.Duration
+	schemaCacheTTL      time.Duration
+	schemaCache         *tools.SchemaCache
+	// httpClients are the Edge Delta API clients created by the server, for the reloads
+	httpClients   []*tools.HTTPClient
+	reloads       <-chan RuntimeConfig
+	mcpServer     *server.MCPServer
+	disabledTools map[string]server.ServerTool
+	// canonicalizer derives cache, dedup and audit keys from tool arguments
+	canonicalizer *tools.ArgumentCanonicalizer
 
 	// HTTP server options
 	port             int
 	stateless        bool
 	disableStreaming bool
+	defaultOrgID     string
 }
 
 // ServerOption configures the MCP server
@@ -149,3 +405,445 @@ func WithLogger(logger *slog.Logger) ServerOption {
 		c.logger = logger
 	}
 }
+
+// WithClient replaces the Edge Delta API client the tools use
+func WithClient(client tools.Client) ServerOption {
+	return func(c *serverConfig) {
+		c.client = client
+	}
+}
+
+// newClient returns the configured client or an HTTP client for the configured API URL
+func (c *serverConfig) newClient() tools.Client {
+	// The schema cache shares the canonicalizer with the tool middlewares
+	if c.canonicalizer == nil {
+		c.canonicalizer = tools.NewArgumentCanonicalizer()
+	}
+	client := c.client
+	if client == nil {
+		client = c.newHTTPClient(c.apiURL)
+	}
+	if c.warmup != nil {
+		c.warmCache = tools.NewWarmCache(client, warmCacheTTL)
+		client = c.warmCache
+	}
+	// Regional requests bypass the warm cache, which only holds the default region
+	if len(c.regions) > 0 {
+		regions := make(map[string]tools.Client, len(c.regions))
+		for region, apiURL := range c.regions {
+			regions[region] = c.newHTTPClient(apiURL)
+		}
+		client = tools.NewRegionRouter(client, regions)
+	}
+	if c.schemaCacheTTL > 0 {
+		c.schemaCache = tools.NewSchemaCache(client, c.schemaCacheTTL, c.canonicalizer, c.stateStorage())
+		client = c.schemaCache
+	}
+	return client
+}
+
+// WithSchemaCacheTTL sets how long the facet keys, facets, facet options and services fetched by
+// tools and resources are cached, tools.DefaultSchemaCacheTTL by default. Zero disables the cache.
+func WithSchemaCacheTTL(ttl time.Duration) ServerOption {
+	return func(c *serverConfig) {
+		c.schemaCacheTTL = ttl
+	}
+}
+
+func (c *serverConfig) newHTTPClient(apiURL string) *tools.HTTPClient {
+	opts := []tools.HTTPClientOption{tools.WithUserAgent(BuildInfo().userAgent())}
+	if c.retryPolicy != nil {
+		opts = append(opts, tools.WithRetryPolicy(*c.retryPolicy))
+	}
+	if c.rateLimits != nil {
+		opts = append(opts, tools.WithRateLimits(*c.rateLimits))
+	}
+	opts = append(opts, c.httpOptions...)
+	// Metrics and tracing wrap the transport configured by the options above
+	if c.metrics != nil {
+		opts = append(opts, tools.WithMetrics(c.metrics))
+	}
+	if c.tracer != nil {
+		opts = append(opts, tools.WithTracing(c.tracer))
+	}
+	client := tools.NewHTTPClient(apiURL, c.apiTokenHeader, opts...)
+	c.httpClients = append(c.httpClients, client)
+	return client
+}
+
+// WithRegions lets read-only tools query other regions of the Edge Delta API through a region
+// argument, e.g. {"eu": "https://..."}, for orgs with data in several regions. Only these API URLs
+// are accepted; requests without a region go to the API URL of WithAPIURL.
+func WithRegions(regions map[string]string) ServerOption {
+	return func(c *serverConfig) {
+		c.regions = regions
+	}
+}
+
+// WithRetryPolicy sets how the Edge Delta API client retries requests failing with a transient
+// error, tools.DefaultRetryPolicy by default. It does not apply to clients set with WithClient.
+func WithRetryPolicy(policy tools.RetryPolicy) ServerOption {
+	return func(c *serverConfig) {
+		c.retryPolicy = &policy
+	}
+}
+
+// WithRateLimits sets the rate limits of the requests to the Edge Delta API per org,
+// tools.DefaultRateLimits by default. Requests over the limits wait for their turn. It does not
+// apply to clients set with WithClient.
+func WithRateLimits(limits tools.RateLimits) ServerOption {
+	return func(c *serverConfig) {
+		c.rateLimits = &limits
+	}
+}
+
+// WithHTTPClientOptions configures the Edge Delta API clients, e.g. their timeouts with
+// tools.WithHTTPTimeout or their TLS settings with tools.WithTransport. It does not apply to
+// clients set with WithClient.
+func WithHTTPClientOptions(opts ...tools.HTTPClientOption) ServerOption {
+	return func(c *serverConfig) {
+		c.httpOptions = append(c.httpOptions, opts...)
+	}
+}
+
+// WithLookbackLimits caps the time range tool calls may query, clamping longer ranges
+func WithLookbackLimits(limits tools.LookbackLimits) ServerOption {
+	return func(c *serverConfig) {
+		c.lookbackLimits = limits
+	}
+}
+
+// WithQueryHistory records the successful queries of every user and exposes them as the
+// recent-queries://mine resource
+func WithQueryHistory(history *tools.QueryHistory) ServerOption {
+	return func(c *serverConfig) {
+		c.queryHistory = history
+	}
+}
+
+// WithStorage keeps the state shared between tool calls in the storage, e.g. Redis to share it
+// between the replicas of the server: the schema cache, tool budgets, pseudonyms, org access and
+// feature caches, and the query history unless WithQueryHistory sets another one. The state is
+// kept in memory by default.
+func WithStorage(store storage.Interface) ServerOption {
+	return func(c *serverConfig) {
+		c.storage = store
+	}
+}
+
+// stateStorage returns the storage of the state shared between tool calls, the memory storage
+// unless WithStorage sets one.
+func (c *serverConfig) stateStorage() storage.Interface {
+	if c.storage == nil {
+		c.storage = storage.NewMemory()
+	}
+	return c.storage
+}
+
+// WithPreDeployHook adds a hook that runs before deploy_pipeline, e.g. to wait for an approval.
+// An error returned by the hook denies the deployment and its message is returned to the agent.
+func WithPreDeployHook(hook tools.PreToolHook) ServerOption {
+	return withPreToolHook("deploy_pipeline", hook)
+}
+
+// pipelineSaveTools are the tools saving a pipeline configuration. The save hooks run for all of
+// them, so that an approval required by a hook cannot be bypassed by editing a single node.
+var pipelineSaveTools = []string{
+	"save_pipeline",
+	"add_pipeline_source",
+	"remove_pipeline_node",
+	"update_pipeline_node",
+	"connect_pipeline_nodes",
+}
+
+// WithPreSaveHook adds a hook that runs before every tool saving a pipeline configuration:
+// save_pipeline, add_pipeline_source, remove_pipeline_node, update_pipeline_node and
+// connect_pipeline_nodes. The hook gets the name of the tool in the request. See WithPreDeployHook.
+func WithPreSaveHook(hook tools.PreToolHook) ServerOption {
+	return func(c *serverConfig) {
+		for _, toolName := range pipelineSaveTools {
+			withPreToolHook(toolName, hook)(c)
+		}
+	}
+}
+
+// WithPostDeployHook adds a hook that runs after deploy_pipeline with its outcome
+func WithPostDeployHook(hook tools.PostToolHook) ServerOption {
+	return withPostToolHook("deploy_pipeline", hook)
+}
+
+// WithPostSaveHook adds a hook that runs after every tool saving a pipeline configuration with its
+// outcome, see WithPreSaveHook for the tools
+func WithPostSaveHook(hook tools.PostToolHook) ServerOption {
+	return func(c *serverConfig) {
+		for _, toolName := range pipelineSaveTools {
+			withPostToolHook(toolName, hook)(c)
+		}
+	}
+}
+
+func withPreToolHook(toolName string, hook tools.PreToolHook) ServerOption {
+	return func(c *serverConfig) {
+		if c.preToolHooks == nil {
+			c.preToolHooks = make(map[string][]tools.PreToolHook)
+		}
+		c.preToolHooks[toolName] = append(c.preToolHooks[toolName], hook)
+	}
+}
+
+func withPostToolHook(toolName string, hook tools.PostToolHook) ServerOption {
+	return func(c *serverConfig) {
+		if c.postToolHooks == nil {
+			c.postToolHooks = make(map[string][]tools.PostToolHook)
+		}
+		c.postToolHooks[toolName] = append(c.postToolHooks[toolName], hook)
+	}
+}
+
+// WithFeatureFlags sets whether tools of features disabled for the org are hidden and rejected.
+// Disabled by default, the features endpoint it relies on, GET /v1/orgs/{org_id}/features, is not
+// part of the documented Edge Delta API yet.
+func WithFeatureFlags(enabled bool) ServerOption {
+	return func(c *serverConfig) {
+		c.featureFlags = enabled
+	}
+}
+
+// WithCompactDescriptions trims the description of every tool to its first line, saving the
+// context of clients, and serves the full descriptions as docs://tool/{name} resources.
+func WithCompactDescriptions(enabled bool) ServerOption {
+	return func(c *serverConfig) {
+		c.compactDescriptions = enabled
+	}
+}
+
+// WithMultiOrg adds an org_id argument to every tool, so one session can work with all the orgs
+// its token has access to. Calls without org_id use the default org of the session, if any.
+func WithMultiOrg(enabled bool) ServerOption {
+	return func(c *serverConfig) {
+		c.multiOrg = enabled
+	}
+}
+
+// WithServiceAliases sets service aliases, e.g. from a config file. Aliases from the org
+// settings take precedence.
+func WithServiceAliases(aliases tools.ServiceAliases) ServerOption {
+	return func(c *serverConfig) {
+		c.serviceAliases = aliases
+	}
+}
+
+// WithLocale sets the Accept-Language sent to the Edge Delta API, e.g. "de-DE". Over HTTP the
+// Accept-Language of the client request takes precedence.
+func WithLocale(locale string) ServerOption {
+	return func(c *serverConfig) {
+		c.locale = locale
+	}
+}
+
+// WithResultSigner signs every tool result with the signer, adding the signature and key ID to
+// the _meta of the result, see tools.ResultSigner.
+func WithResultSigner(signer *tools.ResultSigner) ServerOption {
+	return func(c *serverConfig) {
+		c.resultSigner = signer
+	}
+}
+
+// WithPseudonyms replaces org IDs, pipeline IDs and host names in tool results with stable
+// pseudonyms derived with the key, and adds the resolve_pseudonym tool, see tools.Pseudonymizer.
+// An empty key uses a random one.
+func WithPseudonyms(key []byte) ServerOption {
+	return func(c *serverConfig) {
+		c.pseudonymize, c.pseudonymKey = true, key
+	}
+}
+
+// WithTicketWebhook adds the create_incident_ticket tool, opening tickets with the webhook.
+func WithTicketWebhook(webhook *tools.TicketWebhook) ServerOption {
+	return func(c *serverConfig) {
+		c.ticketWebhook = webhook
+	}
+}
+
+// WithMetrics collects Prometheus metrics of tool calls and of the requests to the Edge Delta API,
+// served at /metrics by the HTTP server.
+func WithMetrics() ServerOption {
+	return func(c *serverConfig) {
+		c.metrics = tools.NewMetrics()
+	}
+}
+
+// WithSecretRedaction masks the values matching the patterns of the redactor, e.g. credentials
+// found in logs, in tool results and in the server logs.
+func WithSecretRedaction(redactor *tools.Redactor) ServerOption {
+	return func(c *serverConfig) {
+		c.redactor = redactor
+	}
+}
+
+// WithAuditLogger records every tool call with the audit loggers: tool, redacted arguments, org,
+// caller, duration, status and result size, see tools.AuditEvent.
+func WithAuditLogger(auditLoggers ...tools.AuditLogger) ServerOption {
+	return func(c *serverConfig) {
+		c.auditLoggers = append(c.auditLoggers, auditLoggers...)
+	}
+}
+
+// WithTracing records a span for every tool call, with child spans for the requests to the Edge
+// Delta API, exported by the tracer while the server runs, see tools.Tracer.
+func WithTracing(tracer *tools.Tracer) ServerOption {
+	return func(c *serverConfig) {
+		c.tracer = tracer
+	}
+}
+
+// WithMaxResultBytes caps the size of tool results, cutting larger results and marking them as
+// truncated. Zero, the default, disables the cap.
+func WithMaxResultBytes(maxBytes int) ServerOption {
+	return func(c *serverConfig) {
+		c.maxResultBytes = maxBytes
+	}
+}
+
+// WithToolBudget caps how often each caller may call the tool, e.g. deploy_pipeline 3 times per
+// hour. Calls over budget are rejected with an explanation.
+func WithToolBudget(toolName string, calls int, per time.Duration) ServerOption {
+	return func(c *serverConfig) {
+		if c.toolBudgets == nil {
+			c.toolBudgets = make(map[string]tools.ToolBudget)
+		}
+		c.toolBudgets[toolName] = tools.ToolBudget{Calls: calls, Per: per}
+	}
+}
+
+// WithRequestTimeout sets the deadline of tool calls, tools.DefaultRequestTimeout by default.
+// Calls running longer are canceled and fail with a retryable ED_MCP_TIMEOUT error. Zero disables
+// the deadline.
+func WithRequestTimeout(timeout time.Duration) ServerOption {
+	return func(c *serverConfig) {
+		c.requestTimeout = timeout
+	}
+}
+
+// WithToolTimeout sets the deadline of calls of the tool, overriding the request timeout, e.g.
+// for graph queries over long ranges. Zero disables the deadline of the tool.
+func WithToolTimeout(toolName string, timeout time.Duration) ServerOption {
+	return func(c *serverConfig) {
+		if c.toolTimeouts == nil {
+			c.toolTimeouts = make(map[string]time.Duration)
+		}
+		c.toolTimeouts[toolName] = timeout
+	}
+}
+
+// timeouts returns the deadlines of tool calls: the request timeout, and per tool the defaults of
+// the tools running longer by design overridden by the configured ones.
+func (c *serverConfig) timeouts() tools.ToolTimeouts {
+	timeouts := tools.ToolTimeouts{Default: c.requestTimeout, PerTool: make(map[string]time.Duration)}
+	if c.requestTimeout > 0 {
+		for toolName, timeout := range tools.DefaultToolTimeouts {
+			timeouts.PerTool[toolName] = max(timeout, c.requestTimeout)
+		}
+	}
+	for toolName, timeout := range c.toolTimeouts {
+		timeouts.PerTool[toolName] = timeout
+	}
+	return timeouts
+}
+
+// WithToolMiddleware appends middlewares that wrap every tool handler
+func WithToolMiddleware(middlewares ...ToolMiddleware) ServerOption {
+	return func(c *serverConfig) {
+		c.toolMiddlewares = append(c.toolMiddlewares, middlewares...)
+	}
+}
+
+// middlewares returns the tool middleware chain, outermost first: tracing, signing, pseudonyms,
+// auditing and metrics, then error codes and logging, then truncation and redaction of results,
+// then the middlewares rewriting arguments and the builtin guardrails, then the configured
+// middlewares, then the tool hooks, so approvals happen right before a tool executes, and last the
+// deadlines, so they bound the tool and not approvals. The comments of the steps explain their
+// positions.
+func (c *serverConfig) middlewares() []tools.ToolMiddleware {
+	var middlewares []tools.ToolMiddleware
+	// Tracing wraps everything, so the spans of calls cover all the middlewares
+	if c.tracer != nil {
+		middlewares = append(middlewares, c.tracer.Middleware())
+	}
+	// Signing is outermost so nothing changes results after they are signed
+	if c.resultSigner != nil {
+		middlewares = append(middlewares, tools.SignResults(c.resultSigner))
+	}
+	// Pseudonyms come right after, so they also cover the errors and the blocks the other
+	// middlewares add to results
+	if c.pseudonyms != nil {
+		middlewares = append(middlewares, c.pseudonyms.Middleware())
+	}
+	// Auditing comes after pseudonyms, so it records the real arguments and result sizes, and
+	// before ErrorCodes, so it records the codes of the errors of the handlers
+	if len(c.auditLoggers) > 0 {
+		middlewares = append(middlewares, tools.AuditToolCalls(c.auditLoggers, c.canonicalizer, c.logger))
+	}
+	// Metrics come before ErrorCodes so they see the errors of the handlers too
+	if c.metrics != nil {
+		middlewares = append(middlewares, c.metrics.Middleware())
+	}
+	// ErrorCodes comes before logging so the logging middleware still sees the errors of the handlers
+	middlewares = append(middlewares, tools.ErrorCodes(), loggingMiddleware(c.logger))
+	// Truncation comes right after logging, so it also covers what later middlewares add to results
+	if c.maxResultBytes > 0 {
+		middlewares = append(middlewares, tools.TruncateResults(c.maxResultBytes))
+	}
+	// Redaction comes right after, so secrets are redacted in the full result before it is cut at
+	// the byte budget, where a secret cut in two would no longer match, and signatures cover the
+	// redacted results
+	if c.redactor != nil {
+		middlewares = append(middlewares, c.redactor.Middleware())
+	}
+	// Pagination hints come before the middlewares rewriting arguments, so the next call has the
+	// arguments of the call
+	if c.paginated != nil {
+		middlewares = append(middlewares, tools.PaginationHints(c.paginated))
+	}
+	// The region comes before the org override, so the org is checked in the region
+	if c.regionOverride != nil {
+		middlewares = append(middlewares, c.regionOverride.Middleware())
+	}
+	// The org override comes before the other guardrails, so they apply to the child org
+	if c.orgOverride != nil {
+		middlewares = append(middlewares, c.orgOverride.Middleware())
+	}
+	if c.features != nil {
+		middlewares = append(middlewares, c.features.Middleware())
+	}
+	if c.aliases != nil {
+		middlewares = append(middlewares, c.aliases.Middleware())
+	}
+	middlewares = append(middlewares, tools.DeprecationWarnings(tools.Deprecations))
+	// Durations are normalized after the deprecated arguments are migrated, and before the
+	// guardrails parse them
+	middlewares = append(middlewares, tools.NormalizeDurations(tools.DurationParameters))
+	if len(c.toolBudgets) > 0 {
+		middlewares = append(middlewares, tools.ToolBudgets(c.toolBudgets, c.stateStorage()))
+	}
+	if c.lookbackLimits.Enabled() {
+		middlewares = append(middlewares, tools.LookbackGuardrail(c.lookbackLimits))
+	}
+	if c.queryHistory != nil {
+		middlewares = append(middlewares, tools.RecordQueries(c.queryHistory, c.canonicalizer))
+	}
+	if c.sessions != nil {
+		middlewares = append(middlewares, tools.RecordSession(c.sessions))
+	}
+	middlewares = append(middlewares, c.toolMiddlewares...)
+
+	for _, toolName := range append([]string{"deploy_pipeline"}, pipelineSaveTools...) {
+		if len(c.preToolHooks[toolName]) > 0 || len(c.postToolHooks[toolName]) > 0 {
+			middlewares = append(middlewares, tools.ToolHooks(toolName, c.preToolHooks[toolName], c.postToolHooks[toolName]))
+		}
+	}
+	if timeouts := c.timeouts(); timeouts.Enabled() {
+		middlewares = append(middlewares, tools.ToolDeadlines(timeouts))
+	}
+	return middlewares
+}
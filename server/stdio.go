@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
@@ -22,13 +23,6 @@ type MCPServer struct {
 
 // NewStdioServer creates a new Edge Delta MCP server for stdin/stdout
 func NewStdioServer(orgID, apiToken string, opts ...ServerOption) (*MCPServer, error) {
-	if orgID == "" {
-		return nil, fmt.Errorf("ED_ORG_ID not set")
-	}
-	if apiToken == "" {
-		return nil, fmt.Errorf("ED_API_TOKEN not set")
-	}
-
 	// Set defaults
 	config := defaultServerConfig
 
@@ -36,18 +30,31 @@ func NewStdioServer(orgID, apiToken string, opts ...ServerOption) (*MCPServer, e
 	for _, opt := range opts {
 		opt(&config)
 	}
+	if config.redactor != nil {
+		config.logger = slog.New(tools.NewRedactingHandler(config.logger.Handler(), config.redactor))
+	}
 
-	httpClient := tools.NewHTTPClient(config.apiURL, config.apiTokenHeader)
+	// In multi-org mode the org can be picked per call from list_orgs instead
+	if orgID == "" && !config.multiOrg {
+		return nil, fmt.Errorf("ED_ORG_ID not set")
+	}
+	if apiToken == "" {
+		return nil, fmt.Errorf("ED_API_TOKEN not set")
+	}
 
-	s := server.NewMCPServer(config.serverName, config.serverVersion)
+	client := config.newClient()
 
-	AddCustomTools(s, httpClient)
-	AddCustomResources(s, httpClient)
+	s := config.newMCPServer(client)
 
 	stdioServer := server.NewStdioServer(s)
 	stdioServer.SetContextFunc(func(ctx context.Context) context.Context {
-		ctx = context.WithValue(ctx, tools.OrgIDKey, orgID)
+		if orgID != "" {
+			ctx = context.WithValue(ctx, tools.OrgIDKey, orgID)
+		}
 		ctx = context.WithValue(ctx, tools.EDTokenKey, apiToken)
+		if config.locale != "" {
+			ctx = context.WithValue(ctx, tools.LocaleKey, config.locale)
+		}
 		return ctx
 	})
 
@@ -63,6 +70,16 @@ func (m *MCPServer) Start(ctx context.Context) error {
 	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
+	if m.config.apiDrift != nil {
+		go m.config.watchAPIDrift(ctx)
+	}
+	if m.config.reloads != nil {
+		m.config.watchReloads(ctx)
+	}
+	if m.config.tracer != nil {
+		go m.config.tracer.Run(ctx, m.config.logger)
+	}
+
 	errC := make(chan error, 1)
 	go func() {
 		in, out := io.Reader(os.Stdin), io.Writer(os.Stdout)
@@ -0,0 +1,35 @@
+"""Drives the MCP server with the official Python SDK client and prints a report for
+TestSDKClients in the server package.
+
+Usage: python3 client.py <url> <header> <value>
+"""
+
+import asyncio
+import json
+import sys
+
+from mcp import ClientSession
+from mcp.client.streamable_http import streamablehttp_client
+
+
+async def main(url: str, header: str, value: str) -> None:
+    async with streamablehttp_client(url, headers={header: value}) as (read, write, _):
+        async with ClientSession(read, write) as session:
+            initialized = await session.initialize()
+            tools = await session.list_tools()
+            result = await session.call_tool("get_log_search", {"lookback": "24h", "limit": 1000})
+
+    print(
+        json.dumps(
+            {
+                "server_name": initialized.serverInfo.name,
+                "tools": [tool.name for tool in tools.tools],
+                "is_error": result.isError,
+                "content": [c.text for c in result.content if c.type == "text"],
+            }
+        )
+    )
+
+
+if __name__ == "__main__":
+    asyncio.run(main(*sys.argv[1:4]))
@@ -0,0 +1,30 @@
+// Drives the MCP server with the official TypeScript SDK client and prints a report for
+// TestSDKClients in the server package.
+//
+// Usage: node client.mjs <url> <header> <value>
+import { Client } from "@modelcontextprotocol/sdk/client/index.js";
+import { StreamableHTTPClientTransport } from "@modelcontextprotocol/sdk/client/streamableHttp.js";
+
+const [url, header, value] = process.argv.slice(2);
+
+const transport = new StreamableHTTPClientTransport(new URL(url), {
+  requestInit: { headers: { [header]: value } },
+});
+const client = new Client({ name: "sdk-test-typescript", version: "1.0.0" });
+await client.connect(transport);
+
+const { tools } = await client.listTools();
+const result = await client.callTool({
+  name: "get_log_search",
+  arguments: { lookback: "24h", limit: 1000 },
+});
+
+console.log(
+  JSON.stringify({
+    server_name: client.getServerVersion()?.name ?? "",
+    tools: tools.map((tool) => tool.name),
+    is_error: result.isError ?? false,
+    content: result.content.filter((c) => c.type === "text").map((c) => c.text),
+  }),
+);
+await client.close();